@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/api"
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metadata"
+)
+
+// configProblem is a setting the server can't start with: the flags it comes
+// from, what's wrong, and how to fix it when the error doesn't say.
+type configProblem struct {
+	flag string
+	err  error
+	help string
+}
+
+// configReport collects the problems with the configuration, so startup can
+// check everything it's given and report it all at once, rather than stopping
+// at the first problem or failing later, at first use.
+type configReport struct {
+	problems []configProblem
+}
+
+// add records a problem with the setting of flag, such as "db-url" or
+// "rate-limit/search-rate-limit".
+func (c *configReport) add(flag string, err error, help string) {
+	c.problems = append(c.problems, configProblem{flag: flag, err: err, help: help})
+}
+
+// ok reports whether there are no problems.
+func (c *configReport) ok() bool {
+	return len(c.problems) == 0
+}
+
+// write writes the problems to w, one to a line with its help below it.
+func (c *configReport) write(w io.Writer) {
+	noun := "problems"
+	if len(c.problems) == 1 {
+		noun = "problem"
+	}
+	fmt.Fprintf(w, "Error: can't start with this configuration (%d %s):\n", len(c.problems), noun)
+	for _, p := range c.problems {
+		fmt.Fprintf(w, "  --%s: %v\n", p.flag, p.err)
+		if p.help != "" {
+			fmt.Fprintf(w, "      %s\n", p.help)
+		}
+	}
+	fmt.Fprintf(w, "Run with --help to see every flag.\n")
+}
+
+// checkWritable returns an error unless files can be created in dir, which is
+// created if it doesn't exist yet.
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("could not create directory '%s': %v", dir, err)
+	}
+	f, err := os.CreateTemp(dir, ".bookshelf-check-*")
+	if err != nil {
+		return fmt.Errorf("directory '%s' is not writable: %v", dir, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
+
+// reloadable are the settings a reload of the configuration file applies
+// while the server runs; changes to the others wait for a restart.
+var reloadable = map[string]bool{
+	"verbose":              true,
+	"log-level":            true,
+	"log-format":           true,
+	"log-levels":           true,
+	"log-sample":           true,
+	"slow-query-threshold": true,
+	"rate-limit":           true,
+	"search-rate-limit":    true,
+	"metadata-providers":   true,
+	"google-books-key":     true,
+}
+
+// configSetting is a line of a configuration file.
+type configSetting struct {
+	name, value string
+	line        int
+}
+
+// readConfigFile reads the settings of a configuration file: one flag to a
+// line, as "name = value" without the dashes, with blank lines and lines
+// starting with # ignored.
+func readConfigFile(path string) ([]configSetting, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open configuration file: %v", err)
+	}
+	defer f.Close()
+
+	var settings []configSetting
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(text, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%s, line %d: expected 'name = value', got '%s'", path, line, text)
+		}
+		if name == "config" {
+			return nil, fmt.Errorf("%s, line %d: the configuration file can't name another", path, line)
+		}
+		settings = append(settings, configSetting{name: name, value: strings.TrimSpace(value), line: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read configuration file: %v", err)
+	}
+	return settings, nil
+}
+
+// configFile sets flags from a configuration file, at startup and again when
+// it's reloaded. Flags given on the command line take precedence over the
+// file, and the file over the flags' defaults.
+type configFile struct {
+	path     string
+	flags    *flag.FlagSet
+	explicit map[string]bool   // Flags given on the command line
+	values   map[string]string // Values of the flags in effect, as given
+}
+
+// newConfigFile returns the configuration file at path for flags, which have
+// been parsed from the command line.
+func newConfigFile(path string, flags *flag.FlagSet) *configFile {
+	c := &configFile{path: path, flags: flags, explicit: map[string]bool{}}
+	flags.Visit(func(f *flag.Flag) { c.explicit[f.Name] = true })
+	return c
+}
+
+// resolve returns the value of every flag with the file's settings: that of
+// the command line, the file or the default, in that order. Unknown settings
+// are left out.
+func (c *configFile) resolve(settings []configSetting) map[string]string {
+	values := map[string]string{}
+	c.flags.VisitAll(func(f *flag.Flag) {
+		if c.explicit[f.Name] {
+			values[f.Name] = f.Value.String()
+		} else {
+			values[f.Name] = f.DefValue
+		}
+	})
+	for _, s := range settings {
+		if c.flags.Lookup(s.name) != nil && !c.explicit[s.name] {
+			values[s.name] = s.value
+		}
+	}
+	return values
+}
+
+// load sets the flags the file has settings for, adding what's wrong with
+// them to report.
+func (c *configFile) load(report *configReport) {
+	settings, err := readConfigFile(c.path)
+	if err != nil {
+		report.add("config", err, "")
+		return
+	}
+	c.values = c.resolve(settings)
+	for _, s := range settings {
+		if c.flags.Lookup(s.name) == nil {
+			report.add("config", fmt.Errorf("%s, line %d: unknown setting '%s'", c.path, s.line, s.name), "Settings are named like the flags, without the dashes.")
+		} else if !c.explicit[s.name] {
+			if err := c.flags.Set(s.name, s.value); err != nil {
+				report.add(s.name, fmt.Errorf("%s, line %d: %v", c.path, s.line, err), "")
+			}
+		}
+	}
+}
+
+// reload reads the file again and, when a reloadable setting changed, hands
+// the new value of every flag to apply. Other changes are logged as waiting
+// for a restart. When the file or apply fails, nothing changes.
+func (c *configFile) reload(apply func(value func(name string) string) error) error {
+	settings, err := readConfigFile(c.path)
+	if err != nil {
+		return err
+	}
+	for _, s := range settings {
+		if c.flags.Lookup(s.name) == nil {
+			return fmt.Errorf("%s, line %d: unknown setting '%s'", c.path, s.line, s.name)
+		}
+	}
+	values := c.resolve(settings)
+
+	var changed []string
+	c.flags.VisitAll(func(f *flag.Flag) {
+		if values[f.Name] == c.values[f.Name] {
+			return
+		}
+		if reloadable[f.Name] {
+			changed = append(changed, f.Name)
+		} else {
+			slog.Warn("Changed setting needs a restart to apply", "setting", f.Name, "file", c.path)
+		}
+	})
+	if len(changed) == 0 {
+		slog.Info("Configuration reloaded; nothing to apply", "file", c.path)
+		return nil
+	}
+	if err := apply(func(name string) string { return values[name] }); err != nil {
+		return err
+	}
+	for _, name := range changed {
+		c.values[name] = values[name]
+	}
+	slog.Info("Configuration reloaded", "file", c.path, "changed", changed)
+	return nil
+}
+
+// liveSettings are the parts of the running server a reload changes, as
+// named by reloadable.
+type liveSettings struct {
+	logging     *logging.Controller
+	rateLimits  *api.RateLimits
+	metadata    *metadata.Reloadable
+	client      *http.Client // Sends the metadata providers' requests
+	slowQueries *db.SlowQueryLog
+}
+
+// apply applies the reloadable settings of value, or returns an error having
+// changed nothing. Imports and other requests under way aren't interrupted.
+func (l *liveSettings) apply(value func(name string) string) error {
+	verbose, err := strconv.ParseBool(value("verbose"))
+	if err != nil {
+		return fmt.Errorf("invalid verbose: %v", err)
+	}
+	sample, err := strconv.Atoi(value("log-sample"))
+	if err != nil {
+		return fmt.Errorf("invalid log-sample: %v", err)
+	}
+	logSettings, err := logSettings(verbose, value("log-level"), value("log-format"), value("log-levels"), sample)
+	if err != nil {
+		return err
+	}
+	threshold, err := slowQueryThreshold(value("slow-query-threshold"))
+	if err != nil {
+		return err
+	}
+	writes, err := strconv.Atoi(value("rate-limit"))
+	if err != nil {
+		return fmt.Errorf("invalid rate-limit: %v", err)
+	}
+	searches, err := strconv.Atoi(value("search-rate-limit"))
+	if err != nil {
+		return fmt.Errorf("invalid search-rate-limit: %v", err)
+	}
+	chain, err := metadata.NewChain(strings.Split(value("metadata-providers"), ","), l.client, value("google-books-key"))
+	if err != nil {
+		return fmt.Errorf("invalid metadata-providers: %v", err)
+	}
+	if err := l.rateLimits.Update(writes, searches); err != nil {
+		return fmt.Errorf("invalid rate-limit or search-rate-limit: %v", err)
+	}
+	l.metadata.Set(chain)
+	l.logging.Set(logSettings)
+	l.slowQueries.SetThreshold(threshold)
+	return nil
+}
+
+// slowQueryThreshold parses the duration from which queries are logged as
+// slow, 0 turning the slow query log off.
+func slowQueryThreshold(value string) (time.Duration, error) {
+	threshold, err := time.ParseDuration(value)
+	if err == nil && threshold < 0 {
+		err = fmt.Errorf("%v is negative; use 0 to turn the slow query log off", threshold)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid slow-query-threshold: %v", err)
+	}
+	return threshold, nil
+}
+
+// logSettings returns the log settings of the flags: verbose meaning the
+// debug level, or else level, the subsystem levels of subsystems and the SQL
+// lines sampled a second.
+func logSettings(verbose bool, level, format, subsystems string, sample int) (logging.Settings, error) {
+	settings := logging.Settings{Level: slog.LevelDebug, Format: logging.Format(format), Sample: sample}
+	if !verbose {
+		if err := settings.Level.UnmarshalText([]byte(level)); err != nil {
+			return settings, fmt.Errorf("invalid log-level: %v", err)
+		}
+	}
+	var err error
+	if settings.Subsystems, err = logging.ParseSubsystems(subsystems); err != nil {
+		return settings, fmt.Errorf("invalid log-levels: %v", err)
+	}
+	return settings, settings.Validate()
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+)
+
+func TestConfigReport(t *testing.T) {
+	var report configReport
+	if !report.ok() {
+		t.Error("Expected an empty report to be ok")
+	}
+	report.add("db-url", errors.New("failed to connect to database"), "Check that PostgreSQL is running.")
+	report.add("web-dir", errors.New("web directory './web' does not exist"), "")
+	if report.ok() {
+		t.Error("Expected a report with problems not to be ok")
+	}
+
+	var out strings.Builder
+	report.write(&out)
+	want := "Error: can't start with this configuration (2 problems):\n" +
+		"  --db-url: failed to connect to database\n" +
+		"      Check that PostgreSQL is running.\n" +
+		"  --web-dir: web directory './web' does not exist\n" +
+		"Run with --help to see every flag.\n"
+	if out.String() != want {
+		t.Errorf("Unexpected report:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "covers")
+	if err := checkWritable(dir); err != nil {
+		t.Fatalf("checkWritable(%s) failed: %v", dir, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected the check to leave nothing behind, got %v", entries)
+	}
+
+	// A file where the directory should be
+	file := filepath.Join(t.TempDir(), "bookshelf.db")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkWritable(file); err == nil {
+		t.Errorf("checkWritable(%s) did not return an error for a file", file)
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookshelf.conf")
+	write := func(contents string) {
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	flags := flag.NewFlagSet("bookshelf", flag.ContinueOnError)
+	port := flags.Int("port", 8080, "")
+	rateLimit := flags.Int("rate-limit", 0, "")
+	flags.Int("search-rate-limit", 0, "")
+	webDir := flags.String("web-dir", "./web", "")
+	if err := flags.Parse([]string{"--web-dir", "/srv/web"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The command line wins over the file, and the file over the defaults
+	write("# Bookshelf\n\nport = 9090\nrate-limit=30\nweb-dir = /tmp/web\n")
+	config := newConfigFile(path, flags)
+	var report configReport
+	config.load(&report)
+	if !report.ok() || *port != 9090 || *rateLimit != 30 || *webDir != "/srv/web" {
+		t.Fatalf("After loading: port %d, rate-limit %d, web-dir %q, problems %v", *port, *rateLimit, *webDir, report.problems)
+	}
+
+	var applied map[string]string
+	apply := func(value func(string) string) error {
+		applied = map[string]string{"rate-limit": value("rate-limit"), "search-rate-limit": value("search-rate-limit"), "web-dir": value("web-dir")}
+		return nil
+	}
+
+	// Reloadable settings are applied; removed ones go back to their default
+	write("port = 9090\nsearch-rate-limit = 10\n")
+	if err := config.reload(apply); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	want := map[string]string{"rate-limit": "0", "search-rate-limit": "10", "web-dir": "/srv/web"}
+	if !reflect.DeepEqual(applied, want) {
+		t.Errorf("Applied %v, want %v", applied, want)
+	}
+
+	// Nothing is applied without a reloadable change, or from a broken file
+	for _, contents := range []string{"port = 9191\nsearch-rate-limit = 10\n", "search-rate-limit = 5\nspeed = 11\n", "search-rate-limit 5\n"} {
+		applied = nil
+		write(contents)
+		if err := config.reload(apply); applied != nil {
+			t.Errorf("Reloading %q applied %v (error %v)", contents, applied, err)
+		}
+	}
+
+	// A failed apply is tried again on the next reload
+	write("search-rate-limit = -1\n")
+	if err := config.reload(func(func(string) string) error { return errors.New("rate limits can't be negative") }); err == nil {
+		t.Error("Expected the failed apply to be returned")
+	}
+	if config.values["search-rate-limit"] != "10" {
+		t.Errorf("Expected the failed setting not to be recorded, got %q", config.values["search-rate-limit"])
+	}
+
+	// Problems at startup are reported by line
+	flags = flag.NewFlagSet("bookshelf", flag.ContinueOnError)
+	flags.Int("port", 8080, "")
+	write("port = many\nspeed = 11\nconfig = other.conf\n")
+	report = configReport{}
+	newConfigFile(path, flags).load(&report)
+	if len(report.problems) != 1 || !strings.Contains(report.problems[0].err.Error(), "line 3") {
+		t.Errorf("Expected the nested configuration file to be reported, got %v", report.problems)
+	}
+	write("port = many\nspeed = 11\n")
+	report = configReport{}
+	newConfigFile(path, flags).load(&report)
+	if len(report.problems) != 2 || report.problems[0].flag != "port" || !strings.Contains(report.problems[1].err.Error(), "line 2: unknown setting 'speed'") {
+		t.Errorf("Unexpected problems: %v", report.problems)
+	}
+}
+
+func TestLogSettings(t *testing.T) {
+	settings, err := logSettings(false, "warn", "json", "db=error", 5)
+	if err != nil || settings.Level != slog.LevelWarn || settings.Format != logging.JSON || settings.Subsystems["db"] != slog.LevelError || settings.Sample != 5 {
+		t.Errorf("logSettings = %+v, %v", settings, err)
+	}
+	if settings, err := logSettings(true, "warn", "text", "", 0); err != nil || settings.Level != slog.LevelDebug {
+		t.Errorf("Expected verbose to mean the debug level, got %+v, %v", settings, err)
+	}
+	if _, err := logSettings(false, "info", "text", "", -1); err == nil {
+		t.Error("logSettings: expected a negative sample to be rejected")
+	}
+	for _, bad := range [][3]string{{"loud", "text", ""}, {"info", "xml", ""}, {"info", "text", "db"}} {
+		if _, err := logSettings(false, bad[0], bad[1], bad[2], 0); err == nil {
+			t.Errorf("logSettings(%q, %q, %q): expected an error", bad[0], bad[1], bad[2])
+		}
+	}
+}
+
+func TestSlowQueryThreshold(t *testing.T) {
+	for value, want := range map[string]time.Duration{"200ms": 200 * time.Millisecond, "0s": 0, "1s": time.Second} {
+		if got, err := slowQueryThreshold(value); err != nil || got != want {
+			t.Errorf("slowQueryThreshold(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"-1s", "soon", ""} {
+		if _, err := slowQueryThreshold(value); err == nil {
+			t.Errorf("slowQueryThreshold(%q): expected an error", value)
+		}
+	}
+}
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"context"
+	"io"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -25,4 +30,53 @@ func TestCheckWebDir(t *testing.T) {
 	if err == nil {
 		t.Errorf("checkWebDir(%s) did not return error for non-existent directory", nonExistentDir)
 	}
-}
\ No newline at end of file
+}
+func TestListen(t *testing.T) {
+	// A port alone binds localhost only for the admin listener
+	listener, err := listen(":0", true)
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	if host, _, _ := net.SplitHostPort(listener.Addr().String()); host != "127.0.0.1" {
+		t.Errorf("Expected a loopback address, got %s", listener.Addr())
+	}
+	listener.Close()
+
+	path := filepath.Join(t.TempDir(), "bookshelf.sock")
+	listener, err = listen(unixPrefix+path, false)
+	if err != nil {
+		t.Fatalf("listen on a socket failed: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o660 {
+		t.Errorf("Expected a socket with mode 0660, got %v, %v", info, err)
+	}
+	go serveOn(listener, unixPrefix+path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	}))
+	client := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "unix", path)
+	}}}
+	resp, err := client.Get("http://bookshelf/")
+	if err != nil {
+		t.Fatalf("Request over the socket failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "127.0.0.1:0" {
+		t.Errorf("Expected requests over the socket to be local, got address %q", body)
+	}
+
+	// A socket left behind is replaced, but nothing else is
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	if listener, err = listen(unixPrefix+path, false); err != nil {
+		t.Errorf("listen on an old socket failed: %v", err)
+	} else {
+		listener.Close()
+	}
+	file := filepath.Join(t.TempDir(), "data.db")
+	os.WriteFile(file, []byte("books"), 0o600)
+	if _, err := listen(unixPrefix+file, false); err == nil {
+		t.Error("Expected listen to refuse replacing a file that isn't a socket")
+	}
+}
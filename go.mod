@@ -6,7 +6,9 @@ toolchain go1.24.2
 
 require (
 	github.com/gorilla/mux v1.8.1
+	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.22
+	golang.org/x/crypto v0.31.0
 )
 
-require github.com/klauspost/compress v1.18.0 // indirect
+require github.com/klauspost/compress v1.18.0
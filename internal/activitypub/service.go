@@ -0,0 +1,631 @@
+// Package activitypub publishes finished books to the fediverse through built-in
+// ActivityPub actors: one per user, at /ap/users/{username}, and the library's at
+// /ap/actor for the books without an owner, such as those of single-user mode.
+// Remote servers can follow an actor; each time one of its books is finished a
+// Create(Note) is delivered to every follower inbox.
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/safehttp"
+)
+
+// ContentType is the media type for ActivityPub documents.
+const ContentType = "application/activity+json"
+
+// privateKeySetting is the settings key holding the library's actor's PEM
+// private key. Users' actors' keys are stored with GetActivityPubKey.
+const privateKeySetting = "activitypub_private_key"
+
+const publicAddress = "https://www.w3.org/ns/activitystreams#Public"
+
+// ErrUnauthorized is returned by HandleInbox when the request signature is invalid.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Store is the subset of the book store used by the ActivityPub service.
+type Store interface {
+	GetBookByID(ctx context.Context, id int64) (*model.Book, error)
+	ListActivities(ctx context.Context, limit int, localOnly bool) ([]model.Activity, error)
+	GetActivityByID(ctx context.Context, id int64) (*model.Activity, error)
+	GetUserByID(ctx context.Context, id int64) (*model.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*model.User, error)
+	FirstUserID(ctx context.Context) (int64, error)
+	db.FederationStore
+}
+
+// Service serves the built-in ActivityPub actors.
+type Service struct {
+	Store      Store
+	BaseURL    string // Public base URL of this instance, without trailing slash
+	Username   string // The library's actor's preferred username, used for WebFinger (acct:username@host)
+	HTTPClient *http.Client
+
+	key *rsa.PrivateKey // The library's actor's
+
+	mu   sync.Mutex
+	keys map[int64]*rsa.PrivateKey // Users' actors', by user ID, once loaded
+}
+
+// actor is one of the service's actors.
+type actor struct {
+	userID    *int64 // Whose books it publishes; nil for the library's before there are users
+	library   bool   // Whether it is the library's, which the first user adopts with its books
+	username  string
+	id        string
+	endpoints string // Prefix of its inbox, outbox and followers URLs
+	key       *rsa.PrivateKey
+}
+
+// endpoint returns the URL of the actor's endpoint name, such as "inbox".
+func (a *actor) endpoint(name string) string { return a.endpoints + "/" + name }
+
+func (a *actor) keyID() string { return a.id + "#main-key" }
+
+// follows returns the user a's followers are stored under, nil for the library's.
+func (a *actor) follows() *int64 {
+	if a.library {
+		return nil
+	}
+	return a.userID
+}
+
+// NewService creates the ActivityPub actors, loading the library's signing key
+// from the store or generating and persisting a new one on first use. Actors
+// and inboxes are wherever remote servers say, so its client can't reach the
+// server's own networks.
+func NewService(store Store, baseURL, username string) (*Service, error) {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		return nil, fmt.Errorf("public base URL must be an absolute http(s) URL, got %q", baseURL)
+	}
+	if username == "" {
+		return nil, fmt.Errorf("username is required")
+	}
+
+	ctx := context.Background()
+	key, err := loadOrCreateKey(func() (string, bool, error) {
+		return store.GetSetting(ctx, privateKeySetting)
+	}, func(pemData string) error {
+		return store.SetSetting(ctx, privateKeySetting, pemData)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		Store:      store,
+		BaseURL:    baseURL,
+		Username:   username,
+		HTTPClient: safehttp.NewClient(15 * time.Second),
+		key:        key,
+		keys:       make(map[int64]*rsa.PrivateKey),
+	}, nil
+}
+
+// loadOrCreateKey returns the key load finds, or generates one and saves it.
+func loadOrCreateKey(load func() (string, bool, error), save func(pemData string) error) (*rsa.PrivateKey, error) {
+	pemData, ok, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		key, err := decodePrivateKey(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode stored ActivityPub key: %w", err)
+		}
+		return key, nil
+	}
+
+	slog.Info("Generating ActivityPub signing key")
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ActivityPub key: %w", err)
+	}
+	encoded, err := encodePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := save(encoded); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ActorID returns the canonical ID of the library's actor.
+func (s *Service) ActorID() string { return s.BaseURL + "/ap/actor" }
+
+// library returns the library's actor, which publishes the books without an
+// owner, or once there are users those of the first user, who was given them.
+func (s *Service) library(ctx context.Context) (*actor, error) {
+	a := &actor{library: true, username: s.Username, id: s.ActorID(), endpoints: s.BaseURL + "/ap", key: s.key}
+	first, err := s.Store.FirstUserID(ctx)
+	if err == nil {
+		a.userID = &first
+	} else if !errors.Is(err, db.ErrNotFound) {
+		return nil, err
+	}
+	return a, nil
+}
+
+// lookup returns the actor of the user named username, or the library's for "".
+// It returns an error wrapping db.ErrNotFound for unknown users.
+func (s *Service) lookup(ctx context.Context, username string) (*actor, error) {
+	if username == "" {
+		return s.library(ctx)
+	}
+	user, err := s.Store.GetUserByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return s.userActor(ctx, user)
+}
+
+// actorsOf returns the actors publishing the books of userID: theirs, and the
+// library's too for the first user, or only the library's for nil.
+func (s *Service) actorsOf(ctx context.Context, userID *int64) ([]*actor, error) {
+	library, err := s.library(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if userID == nil {
+		return []*actor{library}, nil
+	}
+	user, err := s.Store.GetUserByID(ctx, *userID)
+	if err != nil {
+		return nil, err
+	}
+	a, err := s.userActor(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if library.userID != nil && *library.userID == user.ID {
+		return []*actor{a, library}, nil
+	}
+	return []*actor{a}, nil
+}
+
+// userActor returns the actor of user, generating and storing its signing key
+// on first use.
+func (s *Service) userActor(ctx context.Context, user *model.User) (*actor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[user.ID]
+	if !ok {
+		var err error
+		key, err = loadOrCreateKey(func() (string, bool, error) {
+			return s.Store.GetActivityPubKey(ctx, user.ID)
+		}, func(pemData string) error {
+			return s.Store.SetActivityPubKey(ctx, user.ID, pemData)
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.keys[user.ID] = key
+	}
+	id := s.BaseURL + "/ap/users/" + url.PathEscape(user.Username)
+	return &actor{userID: &user.ID, username: user.Username, id: id, endpoints: id, key: key}, nil
+}
+
+// Host returns the host part of the base URL, used in acct: URIs.
+func (s *Service) Host() string {
+	host := strings.TrimPrefix(strings.TrimPrefix(s.BaseURL, "https://"), "http://")
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+	return host
+}
+
+// Actor returns the actor document of the user named username, or of the
+// library's actor for "".
+func (s *Service) Actor(ctx context.Context, username string) (map[string]interface{}, error) {
+	a, err := s.lookup(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := encodePublicKey(&a.key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"@context":          []string{"https://www.w3.org/ns/activitystreams", "https://w3id.org/security/v1"},
+		"id":                a.id,
+		"type":              "Person",
+		"preferredUsername": a.username,
+		"name":              a.username + "'s bookshelf",
+		"summary":           "Books finished on this bookshelf.",
+		"url":               s.BaseURL + "/",
+		"inbox":             a.endpoint("inbox"),
+		"outbox":            a.endpoint("outbox"),
+		"followers":         a.endpoint("followers"),
+		"publicKey": map[string]string{
+			"id":           a.keyID(),
+			"owner":        a.id,
+			"publicKeyPem": pub,
+		},
+	}, nil
+}
+
+// WebFinger returns the JRD document for resource, an acct: URI or an actor ID,
+// or false if it isn't one of the actors. A user's actor comes before the
+// library's of the same name.
+func (s *Service) WebFinger(ctx context.Context, resource string) (map[string]interface{}, bool, error) {
+	var username string
+	switch users := s.BaseURL + "/ap/users/"; {
+	case resource == s.ActorID():
+		return s.jrd(s.Username, s.ActorID()), true, nil
+	case strings.HasPrefix(resource, "acct:"):
+		name, ok := strings.CutSuffix(strings.TrimPrefix(resource, "acct:"), "@"+s.Host())
+		if !ok {
+			return nil, false, nil
+		}
+		username = name
+	case strings.HasPrefix(resource, users):
+		name, err := url.PathUnescape(strings.TrimPrefix(resource, users))
+		if err != nil {
+			return nil, false, nil
+		}
+		username = name
+	}
+	if username == "" {
+		return nil, false, nil
+	}
+
+	a, err := s.lookup(ctx, username)
+	switch {
+	case err == nil:
+		return s.jrd(a.username, a.id), true, nil
+	case !errors.Is(err, db.ErrNotFound):
+		return nil, false, err
+	case username == s.Username && strings.HasPrefix(resource, "acct:"):
+		return s.jrd(s.Username, s.ActorID()), true, nil
+	}
+	return nil, false, nil
+}
+
+// jrd returns the WebFinger document of the actor id, named username.
+func (s *Service) jrd(username, id string) map[string]interface{} {
+	return map[string]interface{}{
+		"subject": "acct:" + username + "@" + s.Host(),
+		"links": []map[string]string{
+			{"rel": "self", "type": ContentType, "href": id},
+		},
+	}
+}
+
+// stars renders a 1-10 rating as up to five stars, with a half star for odd ratings.
+func stars(rating int) string {
+	return strings.Repeat("★", rating/2) + strings.Repeat("½", rating%2)
+}
+
+// note builds the Note by which a announces that book was finished. It returns
+// nil if the book has opted out of publishing. Comments flagged as spoilers are
+// hidden behind a content warning.
+func (s *Service) note(a *actor, book *model.Book, activityID int64, published time.Time) map[string]interface{} {
+	if book.PublishOptOut {
+		return nil
+	}
+
+	var content strings.Builder
+	fmt.Fprintf(&content, "<p>Finished reading <em>%s</em>", html.EscapeString(book.Title))
+	if book.Author != "" {
+		fmt.Fprintf(&content, " by %s", html.EscapeString(book.Author))
+	}
+	if book.Rating != nil {
+		fmt.Fprintf(&content, " %s", stars(*book.Rating))
+	}
+	content.WriteString("</p>")
+
+	note := map[string]interface{}{
+		"id":           a.endpoint(fmt.Sprintf("notes/%d", activityID)),
+		"type":         "Note",
+		"attributedTo": a.id,
+		"published":    published.UTC().Format(time.RFC3339),
+		"to":           []string{publicAddress},
+		"cc":           []string{a.endpoint("followers")},
+	}
+
+	if book.Comments != nil && *book.Comments != "" {
+		fmt.Fprintf(&content, "<p>%s</p>", html.EscapeString(*book.Comments))
+		if book.CommentsSpoiler {
+			note["sensitive"] = true
+			note["summary"] = "Spoilers: " + book.Title
+		}
+	}
+	note["content"] = content.String()
+	return note
+}
+
+// create wraps a note of a in a Create activity.
+func (s *Service) create(a *actor, note map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"@context":  "https://www.w3.org/ns/activitystreams",
+		"id":        note["id"].(string) + "/activity",
+		"type":      "Create",
+		"actor":     a.id,
+		"published": note["published"],
+		"to":        note["to"],
+		"cc":        note["cc"],
+		"object":    note,
+	}
+}
+
+// Outbox returns the outbox of the actor of the user named username, or of the
+// library's for "", with its most recent finished books.
+func (s *Service) Outbox(ctx context.Context, username string, limit int) (map[string]interface{}, error) {
+	a, err := s.lookup(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if a.userID != nil {
+		ctx = db.WithUser(ctx, *a.userID)
+	}
+	activities, err := s.Store.ListActivities(ctx, limit*2, true)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []map[string]interface{}{}
+	for _, activity := range activities {
+		if note := s.published(ctx, a, activity); note != nil {
+			items = append(items, s.create(a, note))
+		}
+		if len(items) >= limit {
+			break
+		}
+	}
+
+	return map[string]interface{}{
+		"@context":     "https://www.w3.org/ns/activitystreams",
+		"id":           a.endpoint("outbox"),
+		"type":         "OrderedCollection",
+		"totalItems":   len(items),
+		"orderedItems": items,
+	}, nil
+}
+
+// published returns the note by which a announced the finished book of
+// activity, or nil if it didn't: for other activities, books deleted or opted
+// out since, and books of others.
+func (s *Service) published(ctx context.Context, a *actor, activity model.Activity) map[string]interface{} {
+	if activity.Kind != model.ActivityBookFinished || activity.FollowID != nil || activity.BookID == nil {
+		return nil
+	}
+	book, err := s.Store.GetBookByID(ctx, *activity.BookID)
+	if err != nil || (book.UserID == nil) != (a.userID == nil) {
+		return nil // Book was deleted since, or was given to the first user since
+	}
+	return s.note(a, book, activity.ID, activity.OccurredAt)
+}
+
+// Note returns the note with which the actor of the user named username, or
+// the library's for "", announced the finished book of the activity id. It
+// returns an error wrapping db.ErrNotFound if there is no such note.
+func (s *Service) Note(ctx context.Context, username string, id int64) (map[string]interface{}, error) {
+	a, err := s.lookup(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if a.userID != nil {
+		ctx = db.WithUser(ctx, *a.userID)
+	}
+	activity, err := s.Store.GetActivityByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	note := s.published(ctx, a, *activity)
+	if note == nil {
+		return nil, fmt.Errorf("note %d %w", id, db.ErrNotFound)
+	}
+	note["@context"] = "https://www.w3.org/ns/activitystreams"
+	return note, nil
+}
+
+// Followers returns the followers collection of the actor of the user named
+// username, or of the library's for "". It has how many they are but not who,
+// which is for each of them to tell.
+func (s *Service) Followers(ctx context.Context, username string) (map[string]interface{}, error) {
+	a, err := s.lookup(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	followers, err := s.Store.GetFediverseFollowers(ctx, a.follows())
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"@context":   "https://www.w3.org/ns/activitystreams",
+		"id":         a.endpoint("followers"),
+		"type":       "OrderedCollection",
+		"totalItems": len(followers),
+	}, nil
+}
+
+// remoteActor is the subset of a remote actor document we need.
+type remoteActor struct {
+	ID        string `json:"id"`
+	Inbox     string `json:"inbox"`
+	PublicKey struct {
+		ID           string `json:"id"`
+		Owner        string `json:"owner"`
+		PublicKeyPem string `json:"publicKeyPem"`
+	} `json:"publicKey"`
+}
+
+func (s *Service) fetchActor(ctx context.Context, actorURL string) (*remoteActor, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, actorURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", ContentType)
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("actor fetch returned status %d", resp.StatusCode)
+	}
+
+	var actor remoteActor
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&actor); err != nil {
+		return nil, fmt.Errorf("failed to decode actor: %w", err)
+	}
+	return &actor, nil
+}
+
+// HandleInbox verifies and processes an activity POSTed to the inbox of the actor
+// of the user named username, or of the library's for "". Follow and Undo(Follow)
+// are supported; other activity types are ignored.
+func (s *Service) HandleInbox(ctx context.Context, username string, r *http.Request, body []byte) error {
+	a, err := s.lookup(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	var signer *remoteActor
+	_, err = verifyRequest(r, body, func(keyID string) (*rsa.PublicKey, error) {
+		actorURL, _, _ := strings.Cut(keyID, "#")
+		actor, err := s.fetchActor(ctx, actorURL)
+		if err != nil {
+			return nil, err
+		}
+		if actor.PublicKey.ID != keyID {
+			return nil, fmt.Errorf("key %s not published by actor", keyID)
+		}
+		signer = actor
+		return decodePublicKey(actor.PublicKey.PublicKeyPem)
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+
+	var activity struct {
+		ID     string          `json:"id"`
+		Type   string          `json:"type"`
+		Actor  string          `json:"actor"`
+		Object json.RawMessage `json:"object"`
+	}
+	if err := json.Unmarshal(body, &activity); err != nil {
+		return fmt.Errorf("invalid activity: %w", err)
+	}
+	if activity.Actor != signer.ID {
+		return fmt.Errorf("%w: activity actor does not match signer", ErrUnauthorized)
+	}
+
+	switch activity.Type {
+	case "Follow":
+		var object string
+		if err := json.Unmarshal(activity.Object, &object); err != nil || object != a.id {
+			return fmt.Errorf("follow object must be %s", a.id)
+		}
+		if err := s.Store.AddFediverseFollower(ctx, a.follows(), signer.ID, signer.Inbox); err != nil {
+			return err
+		}
+		slog.Info("New fediverse follower", "actor", signer.ID, "of", a.id)
+
+		accept := map[string]interface{}{
+			"@context": "https://www.w3.org/ns/activitystreams",
+			"id":       fmt.Sprintf("%s/accepts/%d", a.endpoints, time.Now().UnixNano()),
+			"type":     "Accept",
+			"actor":    a.id,
+			"object":   json.RawMessage(body),
+		}
+		go func() {
+			if err := s.deliver(context.Background(), a, signer.Inbox, accept); err != nil {
+				slog.Warn("Failed to deliver Accept", "inbox", signer.Inbox, "error", err)
+			}
+		}()
+	case "Undo":
+		var inner struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(activity.Object, &inner); err == nil && inner.Type == "Follow" {
+			slog.Info("Fediverse follower left", "actor", signer.ID, "of", a.id)
+			return s.Store.RemoveFediverseFollower(ctx, a.follows(), signer.ID)
+		}
+	default:
+		slog.Debug("Ignoring inbox activity", "type", activity.Type, "actor", activity.Actor)
+	}
+	return nil
+}
+
+// deliver signs an activity as a and POSTs it to a remote inbox.
+func (s *Service) deliver(ctx context.Context, a *actor, inbox string, activity interface{}) error {
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	if err := signRequest(req, body, a.keyID(), a.key); err != nil {
+		return err
+	}
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("inbox returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// PublishFinished delivers a Create(Note) for a finished book from the actors of
+// its owner to every follower of them: from the owner's, and for the first
+// user from the library's too, whose followers they adopted. It does nothing
+// for books that opted out of publishing.
+func (s *Service) PublishFinished(ctx context.Context, book *model.Book, activityID int64) {
+	if book.PublishOptOut {
+		slog.Info("Book opted out of fediverse publishing", "id", book.ID)
+		return
+	}
+	actors, err := s.actorsOf(ctx, book.UserID)
+	if err != nil {
+		slog.Error("Failed to find the book owner's ActivityPub actors", "id", book.ID, "error", err)
+		return
+	}
+
+	for _, a := range actors {
+		followers, err := s.Store.GetFediverseFollowers(ctx, a.follows())
+		if err != nil {
+			slog.Error("Failed to list fediverse followers", "actor", a.id, "error", err)
+			continue
+		}
+
+		// Deliver once per shared inbox URL
+		activity := s.create(a, s.note(a, book, activityID, time.Now()))
+		delivered := make(map[string]bool)
+		for _, f := range followers {
+			if delivered[f.InboxURL] {
+				continue
+			}
+			delivered[f.InboxURL] = true
+			if err := s.deliver(ctx, a, f.InboxURL, activity); err != nil {
+				slog.Warn("Failed to deliver to follower", "inbox", f.InboxURL, "error", err)
+			}
+		}
+		slog.Info("Published finished book to fediverse", "id", book.ID, "actor", a.id, "inboxes", len(delivered))
+	}
+}
@@ -0,0 +1,335 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/safehttp"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTestService(t *testing.T) (*Service, *db.SQLiteBookStore) {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := db.CreateSchema(database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	store := db.NewSQLiteBookStore(database)
+
+	svc, err := NewService(store, "https://books.example/", "reader")
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	return svc, store
+}
+
+func TestNewServiceReusesStoredKey(t *testing.T) {
+	svc, store := setupTestService(t)
+
+	again, err := NewService(store, "https://books.example", "reader")
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	if !again.key.Equal(svc.key) {
+		t.Error("Expected the stored key to be reused")
+	}
+
+	if _, err := NewService(store, "books.example", "reader"); err == nil {
+		t.Error("Expected error for relative base URL")
+	}
+}
+
+func TestWebFinger(t *testing.T) {
+	ctx := context.Background()
+	svc, store := setupTestService(t)
+	if err := store.AddUser(ctx, &model.User{Username: "alice", PasswordHash: "hash"}); err != nil {
+		t.Fatalf("AddUser failed: %v", err)
+	}
+
+	jrd, ok, err := svc.WebFinger(ctx, "acct:reader@books.example")
+	if err != nil || !ok {
+		t.Fatalf("Expected WebFinger to resolve the library's actor, got %v", err)
+	}
+	if jrd["subject"] != "acct:reader@books.example" {
+		t.Errorf("Unexpected subject: %v", jrd["subject"])
+	}
+	for _, resource := range []string{"acct:alice@books.example", "https://books.example/ap/users/alice"} {
+		jrd, ok, err := svc.WebFinger(ctx, resource)
+		if err != nil || !ok || jrd["subject"] != "acct:alice@books.example" {
+			t.Fatalf("Expected %s to resolve alice's actor, got %v (%v)", resource, jrd, err)
+		}
+		if href := jrd["links"].([]map[string]string)[0]["href"]; href != "https://books.example/ap/users/alice" {
+			t.Errorf("Expected alice's actor ID, got %s", href)
+		}
+	}
+	for _, resource := range []string{"acct:someone@books.example", "acct:alice@elsewhere.example", "https://books.example/ap/users/someone"} {
+		if _, ok, err := svc.WebFinger(ctx, resource); ok || err != nil {
+			t.Errorf("Expected %s to be rejected, got %v", resource, err)
+		}
+	}
+}
+
+func TestNote(t *testing.T) {
+	svc, _ := setupTestService(t)
+	rating := 7
+	comments := "The twist at the end!"
+	book := &model.Book{Title: "Dune <1>", Author: "Frank Herbert", Rating: &rating, Comments: &comments}
+
+	library, err := svc.library(context.Background())
+	if err != nil {
+		t.Fatalf("library failed: %v", err)
+	}
+	note := svc.note(library, book, 5, time.Now())
+	content := note["content"].(string)
+	if !strings.Contains(content, "Dune &lt;1&gt;") {
+		t.Errorf("Expected escaped title in content, got %s", content)
+	}
+	if !strings.Contains(content, "★★★½") {
+		t.Errorf("Expected 3.5 stars for rating 7, got %s", content)
+	}
+	if _, sensitive := note["sensitive"]; sensitive {
+		t.Error("Expected no content warning without spoiler flag")
+	}
+
+	book.CommentsSpoiler = true
+	note = svc.note(library, book, 5, time.Now())
+	if note["sensitive"] != true || note["summary"] != "Spoilers: Dune <1>" {
+		t.Errorf("Expected spoiler content warning, got %+v", note)
+	}
+
+	book.PublishOptOut = true
+	if svc.note(library, book, 5, time.Now()) != nil {
+		t.Error("Expected no note for opted-out book")
+	}
+}
+
+func TestHandleInboxFollow(t *testing.T) {
+	ctx := context.Background()
+	svc, store := setupTestService(t)
+
+	remoteKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	remotePub, _ := encodePublicKey(&remoteKey.PublicKey)
+
+	accepted := make(chan []byte, 1)
+	var remote *httptest.Server
+	remote = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/actor":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":    remote.URL + "/actor",
+				"inbox": remote.URL + "/inbox",
+				"publicKey": map[string]string{
+					"id":           remote.URL + "/actor#main-key",
+					"owner":        remote.URL + "/actor",
+					"publicKeyPem": remotePub,
+				},
+			})
+		case "/inbox":
+			body, _ := io.ReadAll(r.Body)
+			accepted <- body
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer remote.Close()
+
+	follow, _ := json.Marshal(map[string]string{
+		"id":     remote.URL + "/follows/1",
+		"type":   "Follow",
+		"actor":  remote.URL + "/actor",
+		"object": svc.ActorID(),
+	})
+	req, _ := http.NewRequest("POST", "https://books.example/ap/inbox", bytes.NewReader(follow))
+	if err := signRequest(req, follow, remote.URL+"/actor#main-key", remoteKey); err != nil {
+		t.Fatalf("signRequest failed: %v", err)
+	}
+
+	// The actor can't be fetched from loopback, as of the server's own network
+	if err := svc.HandleInbox(ctx, "", req, follow); err == nil || !strings.Contains(err.Error(), safehttp.ErrForbiddenAddress.Error()) {
+		t.Fatalf("Expected the actor on loopback to be refused, got %v", err)
+	}
+	if followers, _ := store.GetFediverseFollowers(ctx, nil); len(followers) != 0 {
+		t.Fatalf("Expected no follower from a refused actor, got %+v", followers)
+	}
+
+	svc.HTTPClient = remote.Client() // The test server is on loopback
+	if err := svc.HandleInbox(ctx, "", req, follow); err != nil {
+		t.Fatalf("HandleInbox failed: %v", err)
+	}
+
+	followers, err := store.GetFediverseFollowers(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetFediverseFollowers failed: %v", err)
+	}
+	if len(followers) != 1 || followers[0].InboxURL != remote.URL+"/inbox" {
+		t.Fatalf("Expected follower with remote inbox, got %+v", followers)
+	}
+
+	select {
+	case body := <-accepted:
+		if !bytes.Contains(body, []byte(`"Accept"`)) {
+			t.Errorf("Expected Accept activity, got %s", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Accept was not delivered")
+	}
+
+	// Unsigned requests are rejected
+	unsigned, _ := http.NewRequest("POST", "https://books.example/ap/inbox", bytes.NewReader(follow))
+	if err := svc.HandleInbox(ctx, "", unsigned, follow); err == nil {
+		t.Error("Expected unsigned Follow to be rejected")
+	}
+}
+
+// TestUserActors tests that each user's finished books are published through
+// an actor of their own, with its own key and followers, and the first user's
+// through the library's actor too.
+func TestUserActors(t *testing.T) {
+	ctx := context.Background()
+	svc, store := setupTestService(t)
+	alice, bob := model.User{Username: "alice", PasswordHash: "hash"}, model.User{Username: "bob", PasswordHash: "hash"}
+	for _, user := range []*model.User{&alice, &bob} {
+		if err := store.AddUser(ctx, user); err != nil {
+			t.Fatalf("AddUser failed: %v", err)
+		}
+	}
+	book := &model.Book{Title: "Secret Diary", Author: "Alice", OpenLibraryID: "OL1M", Status: model.StatusRead}
+	if _, err := store.AddBook(db.WithUser(ctx, alice.ID), book); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	finished := &model.Activity{BookID: &book.ID, Kind: model.ActivityBookFinished, Title: book.Title, Summary: "Finished"}
+	if err := store.RecordActivity(ctx, finished); err != nil {
+		t.Fatalf("RecordActivity failed: %v", err)
+	}
+
+	actor, err := svc.Actor(ctx, "alice")
+	if err != nil || actor["id"] != "https://books.example/ap/users/alice" || actor["inbox"] != "https://books.example/ap/users/alice/inbox" {
+		t.Fatalf("Unexpected actor for alice: %v (%v)", actor, err)
+	}
+	library, _ := svc.Actor(ctx, "")
+	if key := actor["publicKey"].(map[string]string)["publicKeyPem"]; key == library["publicKey"].(map[string]string)["publicKeyPem"] {
+		t.Error("Expected alice's actor to have a key of its own")
+	}
+	again, _ := NewService(store, "https://books.example", "reader")
+	if stored, _ := again.Actor(ctx, "alice"); stored["publicKey"].(map[string]string)["publicKeyPem"] != actor["publicKey"].(map[string]string)["publicKeyPem"] {
+		t.Error("Expected alice's stored key to be reused")
+	}
+	if _, err := svc.Actor(ctx, "nobody"); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("Expected an unknown user's actor not to be found, got %v", err)
+	}
+
+	for username, want := range map[string]int{"alice": 1, "bob": 0, "": 1} {
+		outbox, err := svc.Outbox(ctx, username, 20)
+		if err != nil {
+			t.Fatalf("Outbox of %q failed: %v", username, err)
+		}
+		if items := outbox["orderedItems"].([]map[string]interface{}); len(items) != want {
+			t.Errorf("Expected %d items in the outbox of %q, got %v", want, username, items)
+		}
+	}
+
+	// Only alice's followers hear of her book, from the actor they follow
+	delivered := make(chan string, 3)
+	inboxes := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var activity struct {
+			Actor string `json:"actor"`
+		}
+		json.NewDecoder(r.Body).Decode(&activity)
+		delivered <- r.URL.Path + " " + activity.Actor
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer inboxes.Close()
+	svc.HTTPClient = inboxes.Client()
+	for userID, inbox := range map[*int64]string{&alice.ID: "/alice", &bob.ID: "/bob", nil: "/library"} {
+		if err := store.AddFediverseFollower(ctx, userID, inboxes.URL+"/actor", inboxes.URL+inbox); err != nil {
+			t.Fatalf("AddFediverseFollower failed: %v", err)
+		}
+	}
+	owned, err := store.GetBookByID(ctx, book.ID)
+	if err != nil {
+		t.Fatalf("GetBookByID failed: %v", err)
+	}
+	svc.PublishFinished(ctx, owned, finished.ID)
+	close(delivered)
+	var got []string
+	for d := range delivered {
+		got = append(got, d)
+	}
+	sort.Strings(got)
+	if want := []string{"/alice https://books.example/ap/users/alice", "/library https://books.example/ap/actor"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected deliveries %v, got %v", want, got)
+	}
+}
+
+// TestLibraryFollowersAdopted tests that the first user adopts the library's
+// actor's followers with its books, who go on hearing of them from it.
+func TestLibraryFollowersAdopted(t *testing.T) {
+	ctx := context.Background()
+	svc, store := setupTestService(t)
+
+	delivered := make(chan string, 2)
+	inboxes := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var activity struct {
+			Actor string `json:"actor"`
+		}
+		json.NewDecoder(r.Body).Decode(&activity)
+		delivered <- r.URL.Path + " " + activity.Actor
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer inboxes.Close()
+	svc.HTTPClient = inboxes.Client()
+	if err := store.AddFediverseFollower(ctx, nil, inboxes.URL+"/actor", inboxes.URL+"/library"); err != nil {
+		t.Fatalf("AddFediverseFollower failed: %v", err)
+	}
+	book := &model.Book{Title: "Old Favourite", Author: "Someone", OpenLibraryID: "OL1M", Status: model.StatusRead}
+	if _, err := store.AddBook(ctx, book); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+
+	alice := model.User{Username: "alice", PasswordHash: "hash"}
+	if err := store.AddUser(ctx, &alice); err != nil {
+		t.Fatalf("AddUser failed: %v", err)
+	}
+	var owner sql.NullInt64
+	if err := store.DB.QueryRow(`SELECT user_id FROM fediverse_followers;`).Scan(&owner); err != nil || owner.Int64 != alice.ID {
+		t.Fatalf("Expected alice to adopt the library's follower, got %v (%v)", owner, err)
+	}
+	if followers, _ := store.GetFediverseFollowers(ctx, &alice.ID); len(followers) != 0 {
+		t.Errorf("Expected alice's own actor to have no followers, got %+v", followers)
+	}
+
+	adopted, err := store.GetBookByID(ctx, book.ID)
+	if err != nil || adopted.UserID == nil {
+		t.Fatalf("Expected alice to adopt the book, got %+v (%v)", adopted, err)
+	}
+	svc.PublishFinished(ctx, adopted, 1)
+	close(delivered)
+	var got []string
+	for d := range delivered {
+		got = append(got, d)
+	}
+	if len(got) != 1 || got[0] != "/library https://books.example/ap/actor" {
+		t.Errorf("Expected the library's follower to hear from the library's actor, got %v", got)
+	}
+}
@@ -0,0 +1,204 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// signedHeaders are the headers covered by outgoing HTTP signatures.
+var signedHeaders = []string{"(request-target)", "host", "date", "digest"}
+
+// maxClockSkew bounds how old an incoming signed request may be.
+const maxClockSkew = 12 * time.Hour
+
+// digestHeader returns the Digest header value for body.
+func digestHeader(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// signingString builds the string covered by the signature for the given headers.
+func signingString(r *http.Request, headers []string) (string, error) {
+	lines := make([]string, 0, len(headers))
+	for _, h := range headers {
+		h = strings.ToLower(h)
+		switch h {
+		case "(request-target)":
+			lines = append(lines, fmt.Sprintf("(request-target): %s %s", strings.ToLower(r.Method), r.URL.RequestURI()))
+		case "host":
+			host := r.Host
+			if host == "" {
+				host = r.URL.Host
+			}
+			lines = append(lines, "host: "+host)
+		default:
+			v := r.Header.Get(h)
+			if v == "" {
+				return "", fmt.Errorf("missing signed header %q", h)
+			}
+			lines = append(lines, h+": "+v)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// signRequest adds Date, Digest and Signature headers (draft-cavage-http-signatures, rsa-sha256).
+func signRequest(r *http.Request, body []byte, keyID string, key *rsa.PrivateKey) error {
+	r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	r.Header.Set("Digest", digestHeader(body))
+	if r.Host == "" {
+		r.Host = r.URL.Host
+	}
+
+	str, err := signingString(r, signedHeaders)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256([]byte(str))
+	sig, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	r.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(signedHeaders, " "), base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+// parsedSignature holds the fields of a Signature header.
+type parsedSignature struct {
+	KeyID     string
+	Headers   []string
+	Signature []byte
+}
+
+func parseSignatureHeader(value string) (*parsedSignature, error) {
+	if value == "" {
+		return nil, fmt.Errorf("missing Signature header")
+	}
+	params := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		params[k] = strings.Trim(v, `"`)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil || len(sig) == 0 {
+		return nil, fmt.Errorf("invalid signature value")
+	}
+	if params["keyId"] == "" {
+		return nil, fmt.Errorf("missing keyId")
+	}
+	headers := []string{"date"}
+	if h := params["headers"]; h != "" {
+		headers = strings.Fields(h)
+	}
+	return &parsedSignature{KeyID: params["keyId"], Headers: headers, Signature: sig}, nil
+}
+
+// verifyRequest checks the HTTP signature and body digest of an incoming request.
+// lookupKey resolves the signature's keyId to a public key.
+func verifyRequest(r *http.Request, body []byte, lookupKey func(keyID string) (*rsa.PublicKey, error)) (string, error) {
+	sig, err := parseSignatureHeader(r.Header.Get("Signature"))
+	if err != nil {
+		return "", err
+	}
+
+	covered := make(map[string]bool)
+	for _, h := range sig.Headers {
+		covered[strings.ToLower(h)] = true
+	}
+	if !covered["(request-target)"] || !covered["date"] {
+		return "", fmt.Errorf("signature must cover (request-target) and date")
+	}
+	if len(body) > 0 {
+		if !covered["digest"] {
+			return "", fmt.Errorf("signature must cover the body digest")
+		}
+		if r.Header.Get("Digest") != digestHeader(body) {
+			return "", fmt.Errorf("body digest mismatch")
+		}
+	}
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return "", fmt.Errorf("invalid Date header")
+	}
+	if d := time.Since(date); d > maxClockSkew || d < -maxClockSkew {
+		return "", fmt.Errorf("request date outside allowed window")
+	}
+
+	str, err := signingString(r, sig.Headers)
+	if err != nil {
+		return "", err
+	}
+	pub, err := lookupKey(sig.KeyID)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve signing key: %w", err)
+	}
+	hash := sha256.Sum256([]byte(str))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig.Signature); err != nil {
+		return "", fmt.Errorf("signature verification failed")
+	}
+	return sig.KeyID, nil
+}
+
+// encodePrivateKey encodes key as a PKCS#8 PEM block.
+func encodePrivateKey(key *rsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// decodePrivateKey parses a PKCS#8 PEM block produced by encodePrivateKey.
+func decodePrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not RSA")
+	}
+	return key, nil
+}
+
+// encodePublicKey encodes key as a PKIX PEM block, the format ActivityPub servers expect.
+func encodePublicKey(key *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// decodePublicKey parses a PKIX (or PKCS#1) PEM public key.
+func decodePublicKey(data string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("invalid public key PEM")
+	}
+	if parsed, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		if key, ok := parsed.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("public key is not RSA")
+	}
+	return x509.ParsePKCS1PublicKey(block.Bytes)
+}
@@ -0,0 +1,75 @@
+package activitypub
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestSignAndVerifyRequest(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	lookup := func(keyID string) (*rsa.PublicKey, error) {
+		if keyID != "https://remote.example/actor#main-key" {
+			return nil, fmt.Errorf("unknown key %s", keyID)
+		}
+		return &key.PublicKey, nil
+	}
+
+	body := []byte(`{"type":"Follow"}`)
+	req, _ := http.NewRequest("POST", "https://books.example/ap/inbox", bytes.NewReader(body))
+	if err := signRequest(req, body, "https://remote.example/actor#main-key", key); err != nil {
+		t.Fatalf("signRequest failed: %v", err)
+	}
+
+	if _, err := verifyRequest(req, body, lookup); err != nil {
+		t.Errorf("verifyRequest failed for valid signature: %v", err)
+	}
+
+	// Tampered body
+	if _, err := verifyRequest(req, []byte(`{"type":"Undo"}`), lookup); err == nil {
+		t.Error("Expected digest mismatch for tampered body")
+	}
+
+	// Missing signature
+	unsigned, _ := http.NewRequest("POST", "https://books.example/ap/inbox", bytes.NewReader(body))
+	if _, err := verifyRequest(unsigned, body, lookup); err == nil {
+		t.Error("Expected error for unsigned request")
+	}
+}
+
+func TestKeyEncodingRoundTrip(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	privPEM, err := encodePrivateKey(key)
+	if err != nil {
+		t.Fatalf("encodePrivateKey failed: %v", err)
+	}
+	decoded, err := decodePrivateKey(privPEM)
+	if err != nil {
+		t.Fatalf("decodePrivateKey failed: %v", err)
+	}
+	if !decoded.Equal(key) {
+		t.Error("Decoded private key does not match")
+	}
+
+	pubPEM, err := encodePublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("encodePublicKey failed: %v", err)
+	}
+	pub, err := decodePublicKey(pubPEM)
+	if err != nil {
+		t.Fatalf("decodePublicKey failed: %v", err)
+	}
+	if !pub.Equal(&key.PublicKey) {
+		t.Error("Decoded public key does not match")
+	}
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/activitypub"
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// respondWithActivityJSON sends an ActivityPub JSON document.
+func respondWithActivityJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to marshal ActivityPub document")
+		return
+	}
+	w.Header().Set("Content-Type", activitypub.ContentType)
+	w.WriteHeader(code)
+	w.Write(response)
+}
+
+// respondWithActivityPubError sends err, from finding an actor or serving it.
+func respondWithActivityPubError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, db.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "Unknown actor")
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, message+": "+err.Error())
+}
+
+// WebFingerHandler handles GET /.well-known/webfinger requests.
+func (h *APIHandler) WebFingerHandler(w http.ResponseWriter, r *http.Request) {
+	jrd, ok, err := h.ActivityPub.WebFinger(r.Context(), r.URL.Query().Get("resource"))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to look up resource: "+err.Error())
+		return
+	}
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Unknown resource")
+		return
+	}
+	w.Header().Set("Content-Type", "application/jrd+json")
+	response, _ := json.Marshal(jrd)
+	w.Write(response)
+}
+
+// ActorHandler handles GET /ap/users/{username} and /ap/actor requests, the
+// latter for the library's actor.
+func (h *APIHandler) ActorHandler(w http.ResponseWriter, r *http.Request) {
+	actor, err := h.ActivityPub.Actor(r.Context(), mux.Vars(r)["username"])
+	if err != nil {
+		respondWithActivityPubError(w, err, "Failed to build actor")
+		return
+	}
+	respondWithActivityJSON(w, http.StatusOK, actor)
+}
+
+// OutboxHandler handles GET /ap/users/{username}/outbox and /ap/outbox requests.
+func (h *APIHandler) OutboxHandler(w http.ResponseWriter, r *http.Request) {
+	outbox, err := h.ActivityPub.Outbox(r.Context(), mux.Vars(r)["username"], 20)
+	if err != nil {
+		respondWithActivityPubError(w, err, "Failed to build outbox")
+		return
+	}
+	respondWithActivityJSON(w, http.StatusOK, outbox)
+}
+
+// FollowersHandler handles GET /ap/users/{username}/followers and /ap/followers requests.
+func (h *APIHandler) FollowersHandler(w http.ResponseWriter, r *http.Request) {
+	followers, err := h.ActivityPub.Followers(r.Context(), mux.Vars(r)["username"])
+	if err != nil {
+		respondWithActivityPubError(w, err, "Failed to build followers")
+		return
+	}
+	respondWithActivityJSON(w, http.StatusOK, followers)
+}
+
+// NoteHandler handles GET /ap/users/{username}/notes/{id} and /ap/notes/{id}
+// requests, for the notes actors' activities are about.
+func (h *APIHandler) NoteHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid note ID")
+		return
+	}
+	note, err := h.ActivityPub.Note(r.Context(), vars["username"], id)
+	if errors.Is(err, db.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "Unknown note")
+		return
+	}
+	if err != nil {
+		respondWithActivityPubError(w, err, "Failed to build note")
+		return
+	}
+	respondWithActivityJSON(w, http.StatusOK, note)
+}
+
+// InboxHandler handles POST /ap/users/{username}/inbox and /ap/inbox requests
+// (Follow / Undo Follow from remote servers).
+func (h *APIHandler) InboxHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1*1024*1024))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+
+	if err := h.ActivityPub.HandleInbox(r.Context(), mux.Vars(r)["username"], r, body); err != nil {
+		switch {
+		case errors.Is(err, db.ErrNotFound):
+			respondWithError(w, http.StatusNotFound, "Unknown actor")
+		case errors.Is(err, activitypub.ErrUnauthorized):
+			respondWithError(w, http.StatusUnauthorized, err.Error())
+		default:
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// UpdateBookSharingHandler handles PUT /api/books/{id}/sharing requests (fediverse opt-out and spoiler flag).
+func (h *APIHandler) UpdateBookSharingHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+
+	var payload model.SharingSettings
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	patch := model.BookPatch{PublishOptOut: model.Some(payload.PublishOptOut), CommentsSpoiler: model.Some(payload.CommentsSpoiler)}
+	if err := h.Store.UpdateBook(r.Context(), id, patch); err != nil {
+		respondWithStoreError(w, err, "Failed to update book sharing")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Book sharing settings updated successfully"})
+}
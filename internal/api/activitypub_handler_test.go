@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/activitypub"
+)
+
+// TestActivityPubIDsResolve tests that the IDs the actors give their notes
+// and followers are served.
+func TestActivityPubIDsResolve(t *testing.T) {
+	handler := newTestAPIHandler(t)
+	var err error
+	if handler.ActivityPub, err = activitypub.NewService(handler.Store, "https://books.example", "reader"); err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	router := SetupRouter(handler, t.TempDir())
+	alice, bob := loginTestUser(t, router), registerTestUser(t, router, "bob")
+	finish := func(token, book string) {
+		rr := authRequest(router, "POST", "/api/v1/books", token, book)
+		var added struct {
+			ID int64 `json:"id"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &added); err != nil || rr.Code != http.StatusCreated {
+			t.Fatalf("Adding a book: got status %d, body: %s", rr.Code, rr.Body.String())
+		}
+		if rr := authRequest(router, "PATCH", "/api/v1/books/"+itoa(added.ID), token, `{"status":"Read"}`); rr.Code != http.StatusOK {
+			t.Fatalf("Finishing a book: got status %d, body: %s", rr.Code, rr.Body.String())
+		}
+	}
+	finish(alice, `{"title":"Secret Diary","author":"Alice","open_library_id":"OL1M","status":"Currently Reading"}`)
+	finish(bob, `{"title":"Bob's Book","author":"Bob","open_library_id":"OL2M","status":"Currently Reading"}`)
+
+	get := func(id string) *httptest.ResponseRecorder {
+		path, ok := strings.CutPrefix(id, "https://books.example")
+		if !ok {
+			t.Fatalf("ID %s is not of this instance", id)
+		}
+		req, _ := http.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	var notes []string
+	for _, actorID := range []string{"https://books.example/ap/users/alice", "https://books.example/ap/actor"} {
+		var actor struct {
+			Outbox    string `json:"outbox"`
+			Followers string `json:"followers"`
+		}
+		if rr := get(actorID); rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &actor) != nil {
+			t.Fatalf("GET %s: got status %d, body: %s", actorID, rr.Code, rr.Body.String())
+		}
+		if rr := get(actor.Followers); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"totalItems":0`) {
+			t.Errorf("GET %s: got status %d, body: %s", actor.Followers, rr.Code, rr.Body.String())
+		}
+
+		var outbox struct {
+			OrderedItems []struct {
+				Object struct {
+					ID string   `json:"id"`
+					CC []string `json:"cc"`
+				} `json:"object"`
+			} `json:"orderedItems"`
+		}
+		if rr := get(actor.Outbox); rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &outbox) != nil || len(outbox.OrderedItems) != 1 {
+			t.Fatalf("GET %s: got status %d, body: %s", actor.Outbox, rr.Code, rr.Body.String())
+		}
+		note := outbox.OrderedItems[0].Object
+		if len(note.CC) != 1 || note.CC[0] != actor.Followers {
+			t.Errorf("Expected the note to be cc'd to %s, got %v", actor.Followers, note.CC)
+		}
+		rr := get(note.ID)
+		if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Secret Diary") || !strings.Contains(rr.Body.String(), `"id":"`+note.ID+`"`) {
+			t.Errorf("GET %s: got status %d, body: %s", note.ID, rr.Code, rr.Body.String())
+		}
+		notes = append(notes, note.ID)
+	}
+
+	// Notes are only served by the actor that published them
+	if rr := get(strings.Replace(notes[0], "/users/alice/", "/users/bob/", 1)); rr.Code != http.StatusNotFound {
+		t.Errorf("Getting alice's note from bob's actor: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := get("https://books.example/ap/notes/999"); rr.Code != http.StatusNotFound {
+		t.Errorf("Getting an unknown note: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// AdminAccess restricts the admin routes, below /admin, to some networks or
+// to a listener of their own, such as one bound to localhost. It is checked
+// before users' credentials, and in addition to them.
+type AdminAccess struct {
+	allowed      []netip.Prefix // Any address when empty
+	listenerOnly bool
+}
+
+// NewAdminAccess returns the admin restrictions allowing the comma-separated
+// addresses or CIDR prefixes of allowed, or any address when it is empty, and
+// when listenerOnly is true only requests served through AdminListener.
+func NewAdminAccess(allowed string, listenerOnly bool) (*AdminAccess, error) {
+	prefixes, err := parsePrefixes(allowed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid admin addresses: %w", err)
+	}
+	return &AdminAccess{allowed: prefixes, listenerOnly: listenerOnly}, nil
+}
+
+type adminListenerKey struct{}
+
+// AdminListener marks the requests h serves as sent to the admin listener,
+// the only one serving the admin routes when AdminAccess is listenerOnly.
+func AdminListener(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), adminListenerKey{}, true)))
+	})
+}
+
+// isAdminRoute reports whether the route r matched is an admin route.
+func isAdminRoute(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+	template, err := route.GetPathTemplate()
+	return err == nil && strings.HasPrefix(specPath(template), "/admin/")
+}
+
+// AdminMiddleware refuses admin requests that AdminAccess doesn't allow: with
+// 404 Not Found when they weren't sent to the admin listener, as if the routes
+// weren't there, or 403 Forbidden from other addresses. Those it lets through
+// still need an admin's credentials, see AdminRoleMiddleware.
+func (h *APIHandler) AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.AdminAccess == nil || !isAdminRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if h.AdminAccess.listenerOnly && r.Context().Value(adminListenerKey{}) == nil {
+			respondWithError(w, http.StatusNotFound, "Admin endpoints are served on the admin listener")
+			return
+		}
+		if len(h.AdminAccess.allowed) > 0 && !sentFrom(r, h.AdminAccess.allowed) {
+			respondWithError(w, http.StatusForbidden, "Admin endpoints can't be reached from this address")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ParseAdmins returns the usernames of the comma-separated list, for
+// APIHandler.Admins.
+func ParseAdmins(list string) ([]string, error) {
+	var admins []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if err := model.ValidateUsername(name); err != nil {
+			return nil, fmt.Errorf("invalid admin %q: %w", name, err)
+		}
+		admins = append(admins, name)
+	}
+	return admins, nil
+}
+
+// isAdmin reports whether the user with ID userID is an admin: one of Admins,
+// or when there are none the first user registered, who adopted the library
+// from before there were accounts.
+func (h *APIHandler) isAdmin(ctx context.Context, userID int64) (bool, error) {
+	if len(h.Admins) == 0 {
+		first, err := h.Store.FirstUserID(ctx)
+		if errors.Is(err, db.ErrNotFound) {
+			return false, nil
+		}
+		return first == userID, err
+	}
+	user, err := h.Store.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range h.Admins {
+		if strings.EqualFold(name, user.Username) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AdminRoleMiddleware refuses admin requests from users who aren't admins
+// with 403 Forbidden, and anonymous ones with 401 Unauthorized, as the admin
+// routes reach across every library: backups hold every user's books and
+// credentials, and the jobs they start change every user's books. It runs
+// after UserMiddleware, which finds the user. In single-user mode, the one
+// user is the admin.
+func (h *APIHandler) AdminRoleMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.SingleUser != nil || !isAdminRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, ok := db.UserFromContext(r.Context())
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bookshelf"`)
+			respondWithError(w, http.StatusUnauthorized, "Log in as an admin to use the admin endpoints")
+			return
+		}
+		admin, err := h.isAdmin(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to check for an admin: "+err.Error())
+			return
+		}
+		if !admin {
+			respondWithError(w, http.StatusForbidden, "Only admins can use the admin endpoints")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
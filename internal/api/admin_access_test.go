@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminAccess(t *testing.T) {
+	if _, err := NewAdminAccess("10.0.0.0/8, intranet", false); err == nil {
+		t.Error("Expected an invalid address to be rejected")
+	}
+	handler := newTestAPIHandler(t)
+	var err error
+	router := SetupRouter(handler, t.TempDir())
+	adminRouter := AdminListener(router)
+	token := loginTestUser(t, router) // The first user, an admin
+
+	request := func(h http.Handler, path, remoteAddr string) int {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if handler.AdminAccess, err = NewAdminAccess("10.0.0.0/8, ::1", false); err != nil {
+		t.Fatalf("NewAdminAccess failed: %v", err)
+	}
+	for _, tc := range []struct {
+		path, remoteAddr string
+		want             int
+	}{
+		{"/api/v1/admin/providers", "10.1.2.3:5555", http.StatusOK},
+		{"/api/v1/admin/providers", "[::1]:5555", http.StatusOK},
+		{"/api/v1/admin/providers", "203.0.113.5:5555", http.StatusForbidden},
+		{"/api/admin/providers", "203.0.113.5:5555", http.StatusForbidden},
+		// Only the admin routes are restricted
+		{"/api/v1/books", "203.0.113.5:5555", http.StatusOK},
+	} {
+		if got := request(router, tc.path, tc.remoteAddr); got != tc.want {
+			t.Errorf("GET %s from %s: got status %d, want %d", tc.path, tc.remoteAddr, got, tc.want)
+		}
+	}
+
+	// With a listener of their own, the main one doesn't serve them
+	if handler.AdminAccess, err = NewAdminAccess("", true); err != nil {
+		t.Fatalf("NewAdminAccess failed: %v", err)
+	}
+	if got := request(router, "/api/v1/admin/providers", "10.1.2.3:5555"); got != http.StatusNotFound {
+		t.Errorf("GET /api/v1/admin/providers from the main listener: got status %d, want %d", got, http.StatusNotFound)
+	}
+	if got := request(adminRouter, "/api/v1/admin/providers", "203.0.113.5:5555"); got != http.StatusOK {
+		t.Errorf("GET /api/v1/admin/providers from the admin listener: got status %d, want %d", got, http.StatusOK)
+	}
+	if got := request(adminRouter, "/api/v1/books", "203.0.113.5:5555"); got != http.StatusOK {
+		t.Errorf("GET /api/v1/books from the admin listener: got status %d, want %d", got, http.StatusOK)
+	}
+}
+
+// TestAdminRole tests that only admins can use the admin routes, which reach
+// across every user's library.
+func TestAdminRole(t *testing.T) {
+	handler := newTestAPIHandler(t)
+	router := SetupRouter(handler, t.TempDir())
+	alice := loginTestUser(t, router)
+	authRequest(router, "POST", "/api/v1/users/register", "", `{"username":"bob","password":"correct horse"}`)
+	rr := authRequest(router, "POST", "/api/v1/users/login", "", `{"username":"bob","password":"correct horse"}`)
+	var session struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &session); err != nil || session.Token == "" {
+		t.Fatalf("Logging in as bob: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	bob := session.Token
+
+	routes := []struct{ method, path string }{
+		{"POST", "/api/v1/admin/backup"},
+		{"POST", "/api/v1/admin/restore"},
+		{"GET", "/api/v1/admin/backups"},
+		{"GET", "/api/v1/admin/logging"},
+		{"GET", "/api/v1/admin/slow-queries"},
+		{"POST", "/api/v1/admin/backfill"},
+		{"POST", "/api/v1/admin/covers/repair"},
+		{"POST", "/api/v1/admin/series/suggestions"},
+		{"GET", "/api/admin/providers"},
+	}
+	for _, route := range routes {
+		if rr := authRequest(router, route.method, route.path, bob, ""); rr.Code != http.StatusForbidden {
+			t.Errorf("%s %s as bob: got status %d, want %d", route.method, route.path, rr.Code, http.StatusForbidden)
+		}
+		if rr := authRequest(router, route.method, route.path, "", ""); rr.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without logging in: got status %d, want %d", route.method, route.path, rr.Code, http.StatusUnauthorized)
+		}
+	}
+	if rr := authRequest(router, "GET", "/api/v1/admin/slow-queries", alice, ""); rr.Code != http.StatusOK {
+		t.Errorf("GET /admin/slow-queries as alice, the first user: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	admin := func(token string) bool {
+		var me currentUserResponse
+		json.Unmarshal(authRequest(router, "GET", "/api/v1/users/me", token, "").Body.Bytes(), &me)
+		return me.Admin
+	}
+	if !admin(alice) || admin(bob) {
+		t.Errorf("Expected only alice to be an admin, got alice %v, bob %v", admin(alice), admin(bob))
+	}
+
+	// Named admins replace the first user
+	var err error
+	if handler.Admins, err = ParseAdmins(" Bob ,"); err != nil {
+		t.Fatalf("ParseAdmins failed: %v", err)
+	}
+	if rr := authRequest(router, "GET", "/api/v1/admin/slow-queries", bob, ""); rr.Code != http.StatusOK {
+		t.Errorf("GET /admin/slow-queries as bob, a named admin: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := authRequest(router, "GET", "/api/v1/admin/slow-queries", alice, ""); rr.Code != http.StatusForbidden {
+		t.Errorf("GET /admin/slow-queries as alice, no longer an admin: got status %d, want %d", rr.Code, http.StatusForbidden)
+	}
+	if _, err := ParseAdmins("alice,no spaces"); err == nil {
+		t.Error("Expected an invalid username to be rejected")
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// announceFinishedBook publishes a finished book to the fediverse and any
+// configured cross-posting accounts. Delivery happens in the background so the
+// status update response is never delayed by remote servers.
+func (h *APIHandler) announceFinishedBook(ctx context.Context, id int64) {
+	if h.ActivityPub == nil && h.CrossPost == nil {
+		return
+	}
+
+	book, err := h.Store.GetBookByID(ctx, id)
+	if err != nil {
+		slog.Warn("Cannot announce finished book", "id", id, "error", err)
+		return
+	}
+	if book.PublishOptOut {
+		slog.Info("Book opted out of publishing", "id", id)
+		return
+	}
+
+	if h.ActivityPub != nil {
+		// Reuse the timeline entry ID so delivered notes match the outbox
+		var activityID int64
+		if activities, err := h.Store.ListActivities(ctx, 10, true); err == nil {
+			for _, a := range activities {
+				if a.Kind == model.ActivityBookFinished && a.BookID != nil && *a.BookID == id {
+					activityID = a.ID
+					break
+				}
+			}
+		}
+		go h.ActivityPub.PublishFinished(context.Background(), book, activityID)
+	}
+
+	if h.CrossPost != nil {
+		go h.CrossPost.PublishFinished(context.Background(), book)
+	}
+}
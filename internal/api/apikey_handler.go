@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// APIKeyPrefix starts every API key, telling them apart from session tokens.
+const APIKeyPrefix = "bks_"
+
+// apiKeyPrefixLength is how much of a key is kept to recognize it by.
+const apiKeyPrefixLength = len(APIKeyPrefix) + 6
+
+// GetAPIKeysHandler handles GET /api/api-keys requests, listing the keys of
+// the user who is logged in, newest first. The keys themselves are not shown.
+func (h *APIHandler) GetAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := currentUser(w, r); !ok {
+		return
+	}
+	keys, err := h.Store.GetAPIKeys(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve API keys: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, keys)
+}
+
+// CreateAPIKeyHandler handles POST /api/api-keys requests. Expects
+// {"name": "backup script"} and responds with the new key in "key"; it can't
+// be shown again.
+func (h *APIHandler) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	var payload struct {
+		Name string `json:"name"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create API key: "+err.Error())
+		return
+	}
+	secret = APIKeyPrefix + secret
+	key := model.APIKey{UserID: userID, Name: strings.TrimSpace(payload.Name), Prefix: secret[:apiKeyPrefixLength]}
+	if err := h.Store.AddAPIKey(r.Context(), &key, secret); err != nil {
+		respondWithStoreError(w, err, "Failed to create API key")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, struct {
+		model.APIKey
+		Key string `json:"key"`
+	}{key, secret})
+}
+
+// DeleteAPIKeyHandler handles DELETE /api/api-keys/{id} requests, revoking
+// the key.
+func (h *APIHandler) DeleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := currentUser(w, r); !ok {
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+	if err := h.Store.DeleteAPIKey(r.Context(), id); err != nil {
+		respondWithStoreError(w, err, "Failed to delete API key")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
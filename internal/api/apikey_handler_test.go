@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestAPIKeys(t *testing.T) {
+	router, _ := newAuthRouter(t)
+	token := loginTestUser(t, router)
+
+	if rr := authRequest(router, "POST", "/api/v1/api-keys", "", `{"name":"backup"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Creating a key without logging in: got status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+	if rr := authRequest(router, "POST", "/api/v1/api-keys", token, `{"name":""}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Creating a key without a name: got status %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+	rr := authRequest(router, "POST", "/api/v1/api-keys", token, `{"name":"backup"}`)
+	var created struct {
+		model.APIKey
+		Key string `json:"key"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	if rr.Code != http.StatusCreated || !strings.HasPrefix(created.Key, APIKeyPrefix) || !strings.HasPrefix(created.Key, created.Prefix) || created.Name != "backup" {
+		t.Fatalf("Expected a new key, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Scripts use the key in place of a session
+	if rr := authRequest(router, "POST", "/api/v1/vacations", created.Key, `{"start_date":"2025-07-01","end_date":"2025-07-14"}`); rr.Code != http.StatusCreated {
+		t.Errorf("Changing the library with the key: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	rr = authRequest(router, "GET", "/api/v1/api-keys", created.Key, "")
+	var keys []model.APIKey
+	json.Unmarshal(rr.Body.Bytes(), &keys)
+	if rr.Code != http.StatusOK || len(keys) != 1 || keys[0].LastUsedAt == nil || strings.Contains(rr.Body.String(), created.Key) {
+		t.Errorf("Expected the used key without its secret, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := authRequest(router, "DELETE", "/api/v1/api-keys/"+itoa(created.ID), token, ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("Revoking the key: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := authRequest(router, "GET", "/api/v1/books", created.Key, ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Using a revoked key: got status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+	if rr := authRequest(router, "DELETE", "/api/v1/api-keys/"+itoa(created.ID), token, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Revoking a revoked key: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
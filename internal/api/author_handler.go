@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// GetAuthorsHandler handles GET /api/authors requests. Every author named by
+// a book is listed, with their profile when one was entered.
+func (h *APIHandler) GetAuthorsHandler(w http.ResponseWriter, r *http.Request) {
+	authors, err := h.Store.GetAuthors(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve authors: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, authors)
+}
+
+// GetAuthorHandler handles GET /api/authors/{id} requests. The books naming
+// the author are listed by GET /api/authors/{id}/books, or by
+// GET /api/books?author_id={id} with the other filters.
+func (h *APIHandler) GetAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid author ID")
+		return
+	}
+	author, err := h.Store.GetAuthor(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve author")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, author)
+}
+
+// GetAuthorBooksHandler handles GET /api/authors/{id}/books requests, listing
+// the books naming the author, co-written ones included, in title order.
+func (h *APIHandler) GetAuthorBooksHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid author ID")
+		return
+	}
+	books, err := h.Store.GetBooksByAuthor(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve author books")
+		return
+	}
+	if books == nil {
+		books = []model.Book{}
+	}
+	respondWithJSON(w, http.StatusOK, newBookResponses(books))
+}
+
+// SetAuthorHandler handles PUT /api/authors requests. Expects {"name": "...",
+// "gender": "woman", "nationality": "..."}; the profile of the author with
+// that name is created or replaced.
+func (h *APIHandler) SetAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Name        string             `json:"name"`
+		Gender      model.AuthorGender `json:"gender"`
+		Nationality *string            `json:"nationality"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	author := model.AuthorProfile{Name: payload.Name, Gender: payload.Gender, Nationality: payload.Nationality}
+	if err := h.Store.SetAuthor(r.Context(), &author); err != nil {
+		respondWithStoreError(w, err, "Failed to save author")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, author)
+}
+
+// DeleteAuthorHandler handles DELETE /api/authors/{id} requests. Only the
+// profile is removed; the author's books are kept, and an author books still
+// name stays listed without a profile.
+func (h *APIHandler) DeleteAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid author ID")
+		return
+	}
+	if err := h.Store.DeleteAuthor(r.Context(), id); err != nil {
+		respondWithStoreError(w, err, "Failed to delete author")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDiversityStatsHandler handles GET /api/stats/diversity requests. The
+// stats cover the books finished in ?year=, by default the current year.
+func (h *APIHandler) GetDiversityStatsHandler(w http.ResponseWriter, r *http.Request) {
+	year := time.Now().UTC().Year()
+	if raw := r.URL.Query().Get("year"); raw != "" {
+		var err error
+		if year, err = strconv.Atoi(raw); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid year")
+			return
+		}
+	}
+	stats, err := h.Store.GetDiversityStats(r.Context(), year)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to compute stats: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, stats)
+}
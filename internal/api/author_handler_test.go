@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestAuthorProfilesAndStats tests entering author profiles and the diversity
+// stats derived from them
+func TestAuthorProfilesAndStats(t *testing.T) {
+	ctx := context.Background()
+	finished := time.Date(1999, 3, 1, 0, 0, 0, 0, time.UTC)
+	book := createTestBook(model.StatusRead, "Profiled")
+	book.DateFinished = &finished
+	book.Translated = true
+	id, err := testStore.AddBook(ctx, book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(ctx, id)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("PUT", "/api/authors", `{"name":"`+book.Author+`","gender":"woman","nationality":"Chile"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Saving an author: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	var author model.AuthorProfile
+	json.Unmarshal(rr.Body.Bytes(), &author)
+	defer testStore.DeleteAuthor(ctx, author.ID)
+	if rr := do("PUT", "/api/authors", `{"name":"X","gender":"robot"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid gender: got status %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	rr = do("GET", "/api/authors/"+itoa(author.ID), "")
+	var got model.AuthorProfile
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil || rr.Code != http.StatusOK || got.BookCount != 1 || got.Gender != model.GenderWoman {
+		t.Errorf("Expected the author with their book, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = do("GET", "/api/authors/"+itoa(author.ID)+"/books", "")
+	var books []map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &books); err != nil || len(books) != 1 || books[0]["id"] != float64(id) {
+		t.Errorf("Expected the author's book, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = do("GET", "/api/books?author_id="+itoa(author.ID)+"&fields=id", "")
+	books = nil
+	if err := json.Unmarshal(rr.Body.Bytes(), &books); err != nil || len(books) != 1 || books[0]["id"] != float64(id) {
+		t.Errorf("Expected only the author's book, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/books?author_id=someone", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid author filter, got %d", rr.Code)
+	}
+	if rr := do("GET", "/api/authors/999999/books", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Missing author: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	rr = do("GET", "/api/stats/diversity?year=1999", "")
+	var stats model.DiversityStats
+	json.Unmarshal(rr.Body.Bytes(), &stats)
+	if rr.Code != http.StatusOK || stats.Year != 1999 || stats.Books != 1 || stats.Gender[0].Percent != 100 ||
+		stats.Nationality[0].Value != "Chile" || stats.Translated.Books != 1 {
+		t.Errorf("Unexpected stats %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := do("DELETE", "/api/authors/"+itoa(author.ID), ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Deleting an author: got status %d, want %d", rr.Code, http.StatusNoContent)
+	}
+	if rr := do("DELETE", "/api/authors/"+itoa(author.ID), ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Deleting twice: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/backfill"
+)
+
+// GetBackfillHandler handles GET /api/admin/backfill requests and returns
+// the progress or outcome of the latest metadata backfill.
+func (h *APIHandler) GetBackfillHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, h.Backfill.Report())
+}
+
+// StartBackfillHandler handles POST /api/admin/backfill requests. Every book
+// the data quality report flags for a missing ISBN, cover, page count or
+// publication year is looked up in the background; matches scoring at least
+// the optional threshold query parameter (0-1, default the configured accept
+// threshold) are applied, and the rest are queued in GET /api/review.
+func (h *APIHandler) StartBackfillHandler(w http.ResponseWriter, r *http.Request) {
+	var threshold float64
+	if s := r.URL.Query().Get("threshold"); s != "" {
+		t, err := strconv.ParseFloat(s, 64)
+		if err != nil || t <= 0 || t > 1 {
+			respondWithError(w, http.StatusBadRequest, "threshold must be a number between 0 and 1")
+			return
+		}
+		threshold = t
+	}
+	// The job outlives the request, so it must not inherit its context
+	if err := h.Backfill.Start(context.Background(), threshold); err != nil {
+		if errors.Is(err, backfill.ErrRunning) {
+			respondWithError(w, http.StatusConflict, err.Error())
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to start metadata backfill: "+err.Error())
+		}
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, h.Backfill.Report())
+}
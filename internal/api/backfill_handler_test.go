@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/backfill"
+	"github.com/ericdahl/bookshelf/internal/metadata"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// titleProvider finds one book by its title and author.
+type titleProvider struct {
+	query string
+	book  metadata.Book
+}
+
+func (p titleProvider) Name() string { return "title" }
+
+func (p titleProvider) Search(ctx context.Context, query string) ([]metadata.Book, error) {
+	if query == p.query {
+		return []metadata.Book{p.book}, nil
+	}
+	return nil, nil
+}
+
+func (p titleProvider) LookupISBN(ctx context.Context, isbn string) ([]metadata.Book, error) {
+	return nil, nil
+}
+
+// TestBackfillHandlers tests running the metadata backfill and confirming
+// the match it queued from the review queue.
+func TestBackfillHandlers(t *testing.T) {
+	ctx := context.Background()
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	book := &model.Book{Title: "The Dispossessed", Author: "Ursula K. Le Guin", OpenLibraryID: "OLDISPOSSESSED1M", Status: model.StatusRead}
+	if _, err := testStore.AddBook(ctx, book); err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(ctx, book.ID)
+
+	chain := testHandler.Backfill.Metadata
+	defer func() { testHandler.Backfill.Metadata = chain }()
+	testHandler.Backfill.Metadata = metadata.Chain{titleProvider{
+		query: "The Dispossessed Ursula K. Le Guin",
+		book: metadata.Book{Provider: "title", ID: "OLDISPOSSESSED2W", Title: "The Dispossessed", Authors: []string{"Ursula Le Guin"},
+			ISBN: "9780061054884", PageCount: 387},
+	}}
+
+	if rr := do("POST", "/api/admin/backfill?threshold=2", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid threshold: got status %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+	if rr := do("POST", "/api/admin/backfill?threshold=1", ""); rr.Code != http.StatusAccepted {
+		t.Fatalf("Expected the run to start, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var report backfill.Report
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		json.Unmarshal(do("GET", "/api/admin/backfill", "").Body.Bytes(), &report)
+		if !report.Running || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if report.Running || report.Error != "" || report.Threshold != 1 || report.Queued != 1 {
+		t.Fatalf("Expected the run to queue one match, got %+v", report)
+	}
+
+	var queue []model.PendingMatch
+	json.Unmarshal(do("GET", "/api/review", "").Body.Bytes(), &queue)
+	var pending *model.PendingMatch
+	for i := range queue {
+		if queue[i].Source == model.SourceBackfill && queue[i].SourceRef == itoa(book.ID) {
+			pending = &queue[i]
+		}
+	}
+	if pending == nil {
+		t.Fatalf("Expected a backfill entry for book %d, got %+v", book.ID, queue)
+	}
+
+	// Creating a book from a backfill entry is refused; linking fills it in
+	if rr := do("POST", "/api/review/"+itoa(pending.ID)+"/resolve", `{"action":"create"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Create: got status %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+	if rr := do("POST", "/api/review/"+itoa(pending.ID)+"/resolve", `{"action":"link","book_id":`+itoa(book.ID)+`}`); rr.Code != http.StatusOK {
+		t.Fatalf("Link: got status %d: %s", rr.Code, rr.Body.String())
+	}
+	filled, err := testStore.GetBookByID(ctx, book.ID)
+	if err != nil {
+		t.Fatalf("Failed to get book: %v", err)
+	}
+	if filled.ISBN != "9780061054884" || filled.PageCount == nil || *filled.PageCount != 387 {
+		t.Errorf("Expected the ISBN and page count to be filled, got %+v", filled)
+	}
+}
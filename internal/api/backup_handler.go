@@ -0,0 +1,126 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/backup"
+)
+
+// maxRestoreBytes caps the size of backups sent to be restored.
+const maxRestoreBytes = 1 << 30
+
+// BackupHandler handles POST /api/admin/backup requests and downloads a
+// consistent snapshot of the SQLite database, which POST /api/admin/restore
+// or a copy over the database file while the server is stopped restores.
+func (h *APIHandler) BackupHandler(w http.ResponseWriter, r *http.Request) {
+	dir, err := os.MkdirTemp("", "bookshelf-backup-*")
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create backup: "+err.Error())
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	path := filepath.Join(dir, "backup.db")
+	if err := h.Store.Backup(r.Context(), path); err != nil {
+		respondWithStoreError(w, err, "Failed to create backup")
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read backup: "+err.Error())
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read backup: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", backup.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, backup.Filename(now)))
+	w.Header().Set("Content-Length", fmt.Sprint(info.Size()))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, file); err != nil {
+		slog.ErrorContext(r.Context(), "Error writing backup response", "error", err)
+	}
+}
+
+// RestoreHandler handles POST /api/admin/restore requests, whose body is a
+// backup downloaded from POST /api/admin/backup, and replaces the whole
+// bookshelf with it. Backups that aren't intact bookshelf databases, or are
+// from a newer version of the server, are rejected with 400 Bad Request and
+// change nothing.
+func (h *APIHandler) RestoreHandler(w http.ResponseWriter, r *http.Request) {
+	file, err := os.CreateTemp("", "bookshelf-restore-*.db")
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to store backup: "+err.Error())
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	body := &readRecorder{Reader: http.MaxBytesReader(w, r.Body, maxRestoreBytes)}
+	n, err := io.Copy(file, body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			respondWithError(w, http.StatusRequestEntityTooLarge, "Backup must not be larger than 1 GB")
+		case body.err != nil:
+			respondWithError(w, http.StatusBadRequest, "Failed to read backup: "+err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to store backup: "+err.Error())
+		}
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusBadRequest, "Request body must be a backup from POST /api/admin/backup")
+		return
+	}
+	if err := file.Close(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to store backup: "+err.Error())
+		return
+	}
+
+	if err := h.Store.Restore(r.Context(), file.Name()); err != nil {
+		respondWithStoreError(w, err, "Failed to restore backup")
+		return
+	}
+	slog.InfoContext(r.Context(), "Restored backup", "bytes", n)
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Backup restored"})
+}
+
+// readRecorder remembers the error reading from its Reader failed with, telling
+// a request that couldn't be read from a file that couldn't be written.
+type readRecorder struct {
+	io.Reader
+	err error
+}
+
+func (r *readRecorder) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+// GetBackupsHandler handles GET /api/admin/backups requests and returns the
+// backup schedule, the outcome of the latest scheduled backup, when the next
+// one runs and the backups kept at the destination.
+func (h *APIHandler) GetBackupsHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Backups == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Scheduled backups are not enabled on this server")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, h.Backups.Status(r.Context()))
+}
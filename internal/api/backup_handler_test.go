@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/ericdahl/bookshelf/internal/backup"
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/export"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestBackupRestoreHandlers(t *testing.T) {
+	database, err := db.InitDB(filepath.Join(t.TempDir(), "books.db"))
+	if err != nil {
+		t.Fatalf("InitDB failed: %v", err)
+	}
+	defer database.Close()
+	h := NewAPIHandler(db.NewSQLiteBookStore(database))
+	ctx := context.Background()
+	if _, err := h.Store.AddBook(ctx, createTestBook(model.StatusRead, "Backup")); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	h.BackupHandler(rr, httptest.NewRequest("POST", "/api/admin/backup", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Backup: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(got, `attachment; filename="bookshelf-`) {
+		t.Errorf("Backup: got Content-Disposition %q", got)
+	}
+	backup := rr.Body.Bytes()
+	if !bytes.HasPrefix(backup, []byte("SQLite format 3\x00")) {
+		t.Fatalf("Backup: expected a SQLite database, got %d bytes", len(backup))
+	}
+
+	if _, err := h.Store.AddBook(ctx, createTestBook(model.StatusRead, "After")); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	rr = httptest.NewRecorder()
+	h.RestoreHandler(rr, httptest.NewRequest("POST", "/api/admin/restore", bytes.NewReader(backup)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Restore: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	books, err := h.Store.GetBooks(ctx)
+	if err != nil || len(books) != 1 || books[0].Title != "Test Book Backup" {
+		t.Errorf("Expected only the backed up book after restoring, got %+v, %v", books, err)
+	}
+
+	for _, body := range []string{"", "not a database"} {
+		req, _ := http.NewRequest("POST", "/api/admin/restore", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Restore of %q: got status %d, want %d", body, rr.Code, http.StatusBadRequest)
+		}
+	}
+
+	// A body cut off on its way is the request's fault, not the server's
+	rr = httptest.NewRecorder()
+	h.RestoreHandler(rr, httptest.NewRequest("POST", "/api/admin/restore", io.MultiReader(bytes.NewReader(backup[:1024]), iotest.ErrReader(io.ErrUnexpectedEOF))))
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), io.ErrUnexpectedEOF.Error()) {
+		t.Errorf("Restore of a truncated body: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestGetBackupsHandler(t *testing.T) {
+	request := func() *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/api/admin/backups", nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+	if rr := request(); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Without scheduled backups: got status %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+
+	schedule, err := backup.ParseSchedule("30 3 * * *")
+	if err != nil {
+		t.Fatalf("ParseSchedule failed: %v", err)
+	}
+	dir := t.TempDir()
+	testHandler.Backups, err = backup.NewScheduler(testStore, schedule, &export.LocalDir{Dir: dir}, dir, 7, 30)
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+	defer func() { testHandler.Backups = nil }()
+	rr := request()
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"schedule":"30 3 * * *"`) || !strings.Contains(rr.Body.String(), `"keep":7,"keep_days":30,"running":false,"backups":[]`) {
+		t.Errorf("Got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// collectionResponse is a collection with a link to the book list of its
+// books.
+type collectionResponse struct {
+	model.Collection
+	Link string `json:"link"`
+}
+
+func newCollectionResponse(collection model.Collection) collectionResponse {
+	return collectionResponse{Collection: collection, Link: booksLink(url.Values{"collection": {strconv.FormatInt(collection.ID, 10)}})}
+}
+
+func newCollectionResponses(collections []model.Collection) []collectionResponse {
+	resps := make([]collectionResponse, len(collections))
+	for i, collection := range collections {
+		resps[i] = newCollectionResponse(collection)
+	}
+	return resps
+}
+
+// decodeCollection reads a collection from the request body, as
+// {"name": "Beach reads", "description": "..."}. It responds with the error
+// and returns false when the body is invalid.
+func decodeCollection(w http.ResponseWriter, r *http.Request) (model.Collection, bool) {
+	var payload struct {
+		Name        string  `json:"name"`
+		Description *string `json:"description"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return model.Collection{}, false
+	}
+	return model.Collection{Name: payload.Name, Description: payload.Description}, true
+}
+
+// GetCollectionsHandler handles GET /api/collections requests, listing every
+// collection by name with the number of books in it.
+func (h *APIHandler) GetCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	collections, err := h.Store.GetCollections(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve collections: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newCollectionResponses(collections))
+}
+
+// GetCollectionHandler handles GET /api/collections/{id} requests. The books
+// in it are listed by GET /api/books?collection={id}.
+func (h *APIHandler) GetCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid collection ID")
+		return
+	}
+	collection, err := h.Store.GetCollection(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve collection")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newCollectionResponse(*collection))
+}
+
+// AddCollectionHandler handles POST /api/collections requests, creating an
+// empty collection.
+func (h *APIHandler) AddCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	collection, ok := decodeCollection(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Store.AddCollection(r.Context(), &collection); err != nil {
+		respondWithStoreError(w, err, "Failed to add collection")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newCollectionResponse(collection))
+}
+
+// UpdateCollectionHandler handles PUT /api/collections/{id} requests,
+// renaming a collection and replacing its description.
+func (h *APIHandler) UpdateCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid collection ID")
+		return
+	}
+	collection, ok := decodeCollection(w, r)
+	if !ok {
+		return
+	}
+	collection.ID = id
+	if err := h.Store.UpdateCollection(r.Context(), &collection); err != nil {
+		respondWithStoreError(w, err, "Failed to update collection")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newCollectionResponse(collection))
+}
+
+// DeleteCollectionHandler handles DELETE /api/collections/{id} requests. The
+// books in the collection are kept.
+func (h *APIHandler) DeleteCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid collection ID")
+		return
+	}
+	if err := h.Store.DeleteCollection(r.Context(), id); err != nil {
+		respondWithStoreError(w, err, "Failed to delete collection")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// collectionBookIDs reads the collection and book IDs of the URL. It
+// responds with the error and returns false when either is invalid.
+func collectionBookIDs(w http.ResponseWriter, r *http.Request) (collectionID, bookID int64, ok bool) {
+	vars := mux.Vars(r)
+	collectionID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid collection ID")
+		return 0, 0, false
+	}
+	bookID, err = strconv.ParseInt(vars["bookID"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return 0, 0, false
+	}
+	return collectionID, bookID, true
+}
+
+// AddCollectionBookHandler handles PUT /api/collections/{id}/books/{bookID}
+// requests, putting a book in a collection, and returns the collection.
+// Adding a book already in the collection changes nothing.
+func (h *APIHandler) AddCollectionBookHandler(w http.ResponseWriter, r *http.Request) {
+	collectionID, bookID, ok := collectionBookIDs(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Store.AddBookToCollection(r.Context(), collectionID, bookID); err != nil {
+		respondWithStoreError(w, err, "Failed to add book to collection")
+		return
+	}
+	collection, err := h.Store.GetCollection(r.Context(), collectionID)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve collection")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newCollectionResponse(*collection))
+}
+
+// RemoveCollectionBookHandler handles DELETE
+// /api/collections/{id}/books/{bookID} requests, taking a book out of a
+// collection.
+func (h *APIHandler) RemoveCollectionBookHandler(w http.ResponseWriter, r *http.Request) {
+	collectionID, bookID, ok := collectionBookIDs(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Store.RemoveBookFromCollection(r.Context(), collectionID, bookID); err != nil {
+		respondWithStoreError(w, err, "Failed to remove book from collection")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetBookCollectionsHandler handles GET /api/books/{id}/collections requests,
+// listing the collections a book is in.
+func (h *APIHandler) GetBookCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	collections, err := h.Store.GetBookCollections(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve book collections")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newCollectionResponses(collections))
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestCollectionHandlers tests creating a collection, putting books in it,
+// listing them through the book list and removing them again
+func TestCollectionHandlers(t *testing.T) {
+	book := createTestBook(model.StatusWantToRead, "Collected")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("POST", "/api/collections", `{"name": "Beach reads", "description": "For the summer"}`)
+	var collection collectionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &collection); err != nil || rr.Code != http.StatusCreated || collection.ID == 0 {
+		t.Fatalf("Expected the collection created, got %d: %s", rr.Code, rr.Body.String())
+	}
+	defer testStore.DeleteCollection(context.Background(), collection.ID)
+	if collection.Link != "/api/v1/books?collection="+itoa(collection.ID) {
+		t.Errorf("Unexpected link %q", collection.Link)
+	}
+	if rr := do("POST", "/api/collections", `{"name": "Beach reads"}`); rr.Code != http.StatusConflict {
+		t.Errorf("Duplicate name: got status %d, want %d", rr.Code, http.StatusConflict)
+	}
+	for _, body := range []string{`{"name": ""}`, `{"name": "Bad", "color": "red"}`} {
+		if rr := do("POST", "/api/collections", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want %d", body, rr.Code, http.StatusBadRequest)
+		}
+	}
+
+	path := "/api/collections/" + itoa(collection.ID)
+	rr = do("PUT", path+"/books/"+itoa(id), "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &collection); err != nil || rr.Code != http.StatusOK || collection.BookCount != 1 {
+		t.Fatalf("Expected the book added, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("PUT", path+"/books/999999", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Adding a missing book: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	rr = do("GET", "/api/books?collection="+itoa(collection.ID)+"&fields=id", "")
+	var list []map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil || len(list) != 1 || list[0]["id"] != float64(id) {
+		t.Errorf("Expected only the collected book, got %s (%v)", rr.Body.String(), err)
+	}
+	if rr := do("GET", "/api/books?collection=beach", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid collection filter, got %d", rr.Code)
+	}
+	rr = do("GET", "/api/books/"+itoa(id)+"/collections", "")
+	var collections []collectionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &collections); err != nil || len(collections) != 1 || collections[0].ID != collection.ID {
+		t.Errorf("Expected the book's collection, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do("PUT", path, `{"name": "Summer"}`)
+	if err := json.Unmarshal(rr.Body.Bytes(), &collection); err != nil || rr.Code != http.StatusOK || collection.Name != "Summer" || collection.Description != nil || collection.BookCount != 1 {
+		t.Errorf("Expected the collection renamed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = do("GET", "/api/collections", "")
+	collections = nil
+	if err := json.Unmarshal(rr.Body.Bytes(), &collections); err != nil || rr.Code != http.StatusOK || len(collections) != 1 || collections[0].Name != "Summer" {
+		t.Errorf("Expected the collection in the list, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := do("DELETE", path+"/books/"+itoa(id), ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Removing the book: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("DELETE", path+"/books/"+itoa(id), ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Removing the book twice: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := do("DELETE", path, ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Deleting the collection: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", path, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected the deleted collection to be gone, got %d", rr.Code)
+	}
+}
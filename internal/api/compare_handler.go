@@ -0,0 +1,147 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/ericdahl/bookshelf/internal/export"
+	"github.com/ericdahl/bookshelf/internal/match"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// comparedItem is a book of the other library, as its file describes it.
+type comparedItem struct {
+	Title         string           `json:"title"`
+	Author        string           `json:"author,omitempty"`
+	ISBN          string           `json:"isbn,omitempty"`
+	OpenLibraryID string           `json:"open_library_id,omitempty"`
+	Year          *int             `json:"year,omitempty"`
+	Status        model.BookStatus `json:"status,omitempty"` // Their shelf; only full exports have it
+}
+
+// comparedBook is a book of this library in a comparison.
+type comparedBook struct {
+	ID     int64            `json:"id"`
+	Title  string           `json:"title"`
+	Author string           `json:"author"`
+	Status model.BookStatus `json:"status"`
+}
+
+func newComparedBook(b model.Book) comparedBook {
+	return comparedBook{ID: b.ID, Title: b.FullTitle(), Author: b.Author, Status: b.Status}
+}
+
+// comparedOverlap is a book both libraries have.
+type comparedOverlap struct {
+	Theirs comparedItem `json:"theirs"`
+	Mine   comparedBook `json:"mine"`
+}
+
+// comparedUncertain is a book of the other library that may be one of this
+// library's, but not surely enough to call it an overlap.
+type comparedUncertain struct {
+	Theirs     comparedItem             `json:"theirs"`
+	Candidates []model.PendingCandidate `json:"candidates"`
+}
+
+// libraryComparison is the response of POST /api/compare.
+type libraryComparison struct {
+	Theirs     int                 `json:"theirs"` // Books in the other library
+	Mine       int                 `json:"mine"`   // Books in this one
+	OnlyTheirs []comparedItem      `json:"only_theirs"`
+	Overlap    []comparedOverlap   `json:"overlap"`
+	Uncertain  []comparedUncertain `json:"uncertain"`
+	OnlyMine   []comparedBook      `json:"only_mine"`
+}
+
+// decodeComparedLibrary reads the books of a library file: a JSON export
+// from GET /api/export, or a list from GET /api/lists/export.
+func decodeComparedLibrary(data []byte) ([]comparedItem, error) {
+	var header struct {
+		Format string `json:"format"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	if header.Format != "" {
+		var list model.SharedList
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, err
+		}
+		if err := list.Validate(); err != nil {
+			return nil, err
+		}
+		items := make([]comparedItem, len(list.Books))
+		for i, b := range list.Books {
+			items[i] = comparedItem{Title: b.Title, Author: b.Author, ISBN: b.ISBN, OpenLibraryID: b.OpenLibraryID, Year: b.Year}
+		}
+		return items, nil
+	}
+	var doc export.Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Books == nil {
+		return nil, errors.New("unrecognized library file, expected a JSON export or a shared list")
+	}
+	items := make([]comparedItem, len(doc.Books))
+	for i, b := range doc.Books {
+		items[i] = comparedItem{Title: b.FullTitle(), Author: b.Author, ISBN: b.ISBN, OpenLibraryID: b.OpenLibraryID,
+			Year: b.PublishYear, Status: b.Status}
+	}
+	return items, nil
+}
+
+// CompareLibraryHandler handles POST /api/compare requests, comparing the
+// bookshelf with a friend's, for planning swaps and loans. The body is their
+// JSON export (GET /api/export) or a shared list (GET /api/lists/export).
+// Books are matched as list imports match them: identifiers such as the ISBN
+// or Open Library ID decide outright, otherwise title, author and year are
+// compared, and books too close to call are listed as uncertain.
+func (h *APIHandler) CompareLibraryHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 5*1024*1024) // 5 MB limit
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to read library file: "+err.Error())
+		return
+	}
+	theirs, err := decodeComparedLibrary(bytes.TrimSpace(data))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid library file: "+err.Error())
+		return
+	}
+
+	mine, err := h.Store.GetBooks(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve books: "+err.Error())
+		return
+	}
+	index := match.NewIndex(mine)
+	index.Thresholds = h.MatchThresholds
+
+	result := libraryComparison{Theirs: len(theirs), Mine: len(mine), OnlyTheirs: []comparedItem{}, Overlap: []comparedOverlap{},
+		Uncertain: []comparedUncertain{}, OnlyMine: []comparedBook{}}
+	shared := make(map[int64]bool)
+	for _, item := range theirs {
+		m := index.Match(match.Candidate{OpenLibraryID: item.OpenLibraryID, ISBNs: []string{item.ISBN}, Title: item.Title,
+			Author: item.Author, Year: item.Year})
+		switch m.Outcome {
+		case match.Matched:
+			shared[m.Best.Book.ID] = true
+			result.Overlap = append(result.Overlap, comparedOverlap{Theirs: item, Mine: newComparedBook(m.Best.Book)})
+		case match.Review:
+			result.Uncertain = append(result.Uncertain, comparedUncertain{Theirs: item, Candidates: m.PendingCandidates()})
+		default:
+			result.OnlyTheirs = append(result.OnlyTheirs, item)
+		}
+	}
+	for _, b := range mine {
+		if !shared[b.ID] {
+			result.OnlyMine = append(result.OnlyMine, newComparedBook(b))
+		}
+	}
+	respondWithJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestCompareLibraryHandler tests comparing the bookshelf with a friend's
+// JSON export and shared list
+func TestCompareLibraryHandler(t *testing.T) {
+	book := createTestBook(model.StatusRead, "Compared")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	do := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/compare", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Their export has the same book under another Open Library ID, and one of their own
+	rr := do(`{"export_id": 3, "exported_at": "2025-03-01T00:00:00Z", "books": [
+        {"id": 40, "title": "Other", "author": "Someone", "open_library_id": "OLTHEIRS1M", "isbn": "` + book.ISBN + `", "status": "Read"},
+        {"id": 41, "title": "The Left Hand of Darkness", "author": "Ursula K. Le Guin", "open_library_id": "OLTHEIRS2M", "status": "Want to Read"}
+    ], "deleted": []}`)
+	var result libraryComparison
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected a comparison, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if result.Theirs != 2 || len(result.Overlap) != 1 || result.Overlap[0].Mine.ID != id ||
+		len(result.OnlyTheirs) != 1 || result.OnlyTheirs[0].Status != model.StatusWantToRead {
+		t.Errorf("Unexpected comparison: %s", rr.Body.String())
+	}
+	for _, b := range result.OnlyMine {
+		if b.ID == id {
+			t.Errorf("Expected the shared book left out of only_mine: %s", rr.Body.String())
+		}
+	}
+
+	// A shared list matches on title and author alone
+	rr = do(`{"format": "bookshelf-list", "version": 1, "name": "Friend", "exported_at": "2025-03-01T00:00:00Z",
+        "books": [{"title": "` + book.Title + `", "author": "` + book.Author + `"}]}`)
+	result = libraryComparison{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil || rr.Code != http.StatusOK || len(result.Overlap) != 1 {
+		t.Errorf("Expected the listed book to overlap, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	for _, body := range []string{`not json`, `{"name": "no books"}`, `{"format": "spreadsheet", "books": []}`} {
+		if rr := do(body); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", body, rr.Code, rr.Body.String())
+		}
+	}
+}
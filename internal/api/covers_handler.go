@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/covers"
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/ratelimit"
+	"github.com/gorilla/mux"
+)
+
+// GetCoverRepairHandler handles GET /api/admin/covers/repair requests and
+// returns the progress or outcome of the latest cover repair.
+func (h *APIHandler) GetCoverRepairHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, h.Covers.Report())
+}
+
+// StartCoverRepairHandler handles POST /api/admin/covers/repair requests.
+// The repair checks every book's cover in the background; poll
+// GetCoverRepairHandler for the report of unresolved books.
+func (h *APIHandler) StartCoverRepairHandler(w http.ResponseWriter, r *http.Request) {
+	// The job outlives the request, so it must not inherit its context
+	if err := h.Covers.Start(context.Background()); err != nil {
+		if errors.Is(err, covers.ErrRunning) {
+			respondWithError(w, http.StatusConflict, err.Error())
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to start cover repair: "+err.Error())
+		}
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, h.Covers.Report())
+}
+
+// requireCoverCache responds with 503 and returns false when no cover cache is configured.
+func (h *APIHandler) requireCoverCache(w http.ResponseWriter) bool {
+	if h.CoverCache == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "The cover cache requires the server to be started with --cover-cache-dir")
+		return false
+	}
+	return true
+}
+
+// GetCoverImageHandler handles GET /api/covers/{hash} requests and serves a
+// cached cover. Images are content-addressed, so they can be cached forever.
+func (h *APIHandler) GetCoverImageHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.requireCoverCache(w) {
+		return
+	}
+	f, image, err := h.CoverCache.Open(r.Context(), mux.Vars(r)["hash"])
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Cover not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to open cover: "+err.Error())
+		}
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", image.ContentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", `"`+image.Hash+`"`)
+	http.ServeContent(w, r, "", image.CreatedAt, f)
+}
+
+// GetBookCoverHandler handles GET /api/books/{id}/cover requests and serves
+// the book's cover from the cover cache. A cover not cached yet, such as one
+// added before the cache was enabled, is downloaded and cached first, so
+// remote covers move to the cache as they are viewed. Without a cache, or
+// when the download fails, the client is redirected to the original cover.
+func (h *APIHandler) GetBookCoverHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+	book, err := h.Store.GetBookByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve book")
+		return
+	}
+	if book.CoverHash == nil && h.CoverCache != nil && book.CoverURL != nil {
+		if _, err := h.CoverCache.CacheBook(r.Context(), *book); err != nil {
+			slog.WarnContext(r.Context(), "Failed to cache cover on request", "id", book.ID, "error", err)
+		} else if book, err = h.Store.GetBookByID(r.Context(), id); err != nil {
+			respondWithStoreError(w, err, "Failed to retrieve book")
+			return
+		}
+	}
+	if book.CoverHash == nil || h.CoverCache == nil {
+		if book.CoverURL == nil || *book.CoverURL == "" {
+			respondWithError(w, http.StatusNotFound, "Book has no cover")
+			return
+		}
+		http.Redirect(w, r, *book.CoverURL, http.StatusFound)
+		return
+	}
+
+	f, image, err := h.CoverCache.Open(r.Context(), *book.CoverHash)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Cover not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to open cover: "+err.Error())
+		}
+		return
+	}
+	defer f.Close()
+
+	// Unlike /api/covers/{hash}, the image behind this URL changes with the book's cover
+	w.Header().Set("Content-Type", image.ContentType)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("ETag", `"`+image.Hash+`"`)
+	http.ServeContent(w, r, "", image.CreatedAt, f)
+}
+
+// CacheCoversHandler handles POST /api/admin/covers/cache requests. It caches
+// every cover not cached yet and removes images no book uses any more.
+func (h *APIHandler) CacheCoversHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.requireCoverCache(w) {
+		return
+	}
+	report, err := h.CoverCache.CacheAll(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update cover cache: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// cacheCover caches a newly added book's cover in the background.
+func (h *APIHandler) cacheCover(book model.Book) {
+	if h.CoverCache == nil || book.CoverURL == nil {
+		return
+	}
+	go func() {
+		ctx := ratelimit.WithPriority(context.Background(), ratelimit.Background)
+		if _, err := h.CoverCache.CacheBook(ctx, book); err != nil {
+			slog.Warn("Failed to cache cover", "id", book.ID, "error", err)
+		}
+	}()
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/covers"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestCoverRepairHandlers tests starting a cover repair and polling its report
+func TestCoverRepairHandlers(t *testing.T) {
+	send := func(method string) (*httptest.ResponseRecorder, covers.Report) {
+		req, _ := http.NewRequest(method, "/api/admin/covers/repair", nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		var report covers.Report
+		json.Unmarshal(rr.Body.Bytes(), &report)
+		return rr, report
+	}
+
+	if rr, report := send("GET"); rr.Code != http.StatusOK || report.Running || report.StartedAt != nil {
+		t.Fatalf("Expected an idle report before any run, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr, report := send("POST")
+	if rr.Code != http.StatusAccepted || report.StartedAt == nil {
+		t.Fatalf("Expected the repair to start, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for report.Running && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		_, report = send("GET")
+	}
+	if report.Running || report.FinishedAt == nil || report.Error != "" {
+		t.Errorf("Expected the repair to finish, got %+v", report)
+	}
+}
+
+// TestCoverCacheHandlers tests caching covers and serving cached images. The
+// book it adds is deleted again.
+func TestCoverCacheHandlers(t *testing.T) {
+	ctx := context.Background()
+	send := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := send("POST", "/api/admin/covers/cache"); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a cover cache, got %d", rr.Code)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png bytes"))
+	}))
+	defer server.Close()
+	testHandler.CoverCache = covers.NewCache(testStore, t.TempDir())
+	testHandler.CoverCache.HTTPClient = server.Client()
+	defer func() { testHandler.CoverCache = nil }()
+
+	cover := server.URL + "/cover.png"
+	book := &model.Book{Title: "Cached", Author: "A", OpenLibraryID: "OLCACHEDW", Status: model.StatusRead, CoverURL: &cover}
+	if _, err := testStore.AddBook(ctx, book); err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(ctx, book.ID)
+
+	rr := send("POST", "/api/admin/covers/cache")
+	var report covers.CacheReport
+	json.Unmarshal(rr.Body.Bytes(), &report)
+	if rr.Code != http.StatusOK || report.Cached != 1 {
+		t.Fatalf("Expected the cover to be cached, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	cached, _ := testStore.GetBookByID(ctx, book.ID)
+	rr = send("GET", "/api/covers/"+*cached.CoverHash)
+	if rr.Code != http.StatusOK || rr.Body.String() != "png bytes" || rr.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("Unexpected cached cover response %d (%s): %q", rr.Code, rr.Header().Get("Content-Type"), rr.Body.String())
+	}
+	if rr := send("GET", "/api/covers/"+strings.Repeat("0", 64)); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown cover, got %d", rr.Code)
+	}
+}
+
+// TestGetBookCoverHandler tests serving a book's cover, caching it on first
+// view and redirecting to the original cover without a cache
+func TestGetBookCoverHandler(t *testing.T) {
+	ctx := context.Background()
+	send := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	var downloads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png bytes"))
+	}))
+	defer server.Close()
+
+	cover := server.URL + "/lazy.png"
+	book := &model.Book{Title: "Lazy Cover", Author: "A", OpenLibraryID: "OLLAZYCOVERW", Status: model.StatusRead, CoverURL: &cover}
+	if _, err := testStore.AddBook(ctx, book); err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(ctx, book.ID)
+	path := "/api/books/" + itoa(book.ID) + "/cover"
+
+	if rr := send(path); rr.Code != http.StatusFound || rr.Header().Get("Location") != cover {
+		t.Errorf("Expected a redirect to the original cover without a cache, got %d %q", rr.Code, rr.Header().Get("Location"))
+	}
+
+	testHandler.CoverCache = covers.NewCache(testStore, t.TempDir())
+	testHandler.CoverCache.HTTPClient = server.Client()
+	defer func() { testHandler.CoverCache = nil }()
+	for i := 0; i < 2; i++ {
+		rr := send(path)
+		if rr.Code != http.StatusOK || rr.Body.String() != "png bytes" || rr.Header().Get("Content-Type") != "image/png" {
+			t.Fatalf("Request %d: expected the cached cover, got %d: %s", i+1, rr.Code, rr.Body.String())
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("Expected the cover to be downloaded once, got %d", downloads)
+	}
+	if cached, _ := testStore.GetBookByID(ctx, book.ID); cached.CoverHash == nil {
+		t.Error("Expected the book to point at the cached cover")
+	}
+
+	uncovered := &model.Book{Title: "No Cover", Author: "A", OpenLibraryID: "OLNOCOVERW", Status: model.StatusRead}
+	if _, err := testStore.AddBook(ctx, uncovered); err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(ctx, uncovered.ID)
+	if rr := send("/api/books/" + itoa(uncovered.ID) + "/cover"); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a book without a cover, got %d", rr.Code)
+	}
+	if rr := send("/api/books/999999/cover"); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown book, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ericdahl/bookshelf/internal/crosspost"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// GetCrosspostAccountsHandler handles GET /api/crosspost/accounts requests.
+// Credentials are never included in the response.
+func (h *APIHandler) GetCrosspostAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	accounts, err := h.Store.GetCrosspostAccounts(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve cross-posting accounts: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, accounts)
+}
+
+// AddCrosspostAccountHandler handles POST /api/crosspost/accounts requests.
+// Expects provider, instance_url, handle, token (Mastodon access token or Bluesky
+// app password) and an optional text/template for the post body.
+func (h *APIHandler) AddCrosspostAccountHandler(w http.ResponseWriter, r *http.Request) {
+	if h.CrossPost == nil || !h.CrossPost.Box.Enabled() {
+		respondWithError(w, http.StatusServiceUnavailable, "Cross-posting requires the server to be started with --secret-key")
+		return
+	}
+
+	var payload struct {
+		Provider    model.CrosspostProvider `json:"provider"`
+		InstanceURL string                  `json:"instance_url"`
+		Handle      string                  `json:"handle"`
+		Token       string                  `json:"token"`
+		Template    string                  `json:"template"`
+		Enabled     *bool                   `json:"enabled"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if !payload.Provider.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid provider. Must be 'mastodon' or 'bluesky'")
+		return
+	}
+	if payload.InstanceURL == "" && payload.Provider == model.ProviderBluesky {
+		payload.InstanceURL = "https://bsky.social"
+	}
+	if u, err := url.Parse(payload.InstanceURL); err != nil || u.Scheme != "https" || u.Host == "" {
+		respondWithError(w, http.StatusBadRequest, "instance_url must be an absolute https URL")
+		return
+	}
+	if payload.Handle == "" || payload.Token == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing required fields: handle and token")
+		return
+	}
+	if payload.Template == "" {
+		payload.Template = model.DefaultCrosspostTemplate
+	}
+	if err := crosspost.ValidateTemplate(payload.Template); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sealed, err := h.CrossPost.Box.Seal(payload.Token)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to encrypt token: "+err.Error())
+		return
+	}
+
+	account := model.CrosspostAccount{
+		Provider:       payload.Provider,
+		InstanceURL:    strings.TrimRight(payload.InstanceURL, "/"),
+		Handle:         payload.Handle,
+		Template:       payload.Template,
+		Enabled:        payload.Enabled == nil || *payload.Enabled,
+		EncryptedToken: sealed,
+	}
+	if _, err := h.Store.AddCrosspostAccount(r.Context(), &account); err != nil {
+		respondWithStoreError(w, err, "Failed to add cross-posting account")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, account)
+}
+
+// DeleteCrosspostAccountHandler handles DELETE /api/crosspost/accounts/{id} requests.
+func (h *APIHandler) DeleteCrosspostAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+	if err := h.Store.DeleteCrosspostAccount(r.Context(), id); err != nil {
+		respondWithStoreError(w, err, "Failed to delete cross-posting account")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
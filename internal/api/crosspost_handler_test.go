@@ -0,0 +1,96 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/crosspost"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/secrets"
+)
+
+// TestCrosspostAccountLifecycle tests adding, listing and deleting cross-posting accounts
+func TestCrosspostAccountLifecycle(t *testing.T) {
+	payload := []byte(`{"provider":"mastodon","instance_url":"https://mastodon.example/","handle":"@reader","token":"super-secret"}`)
+
+	// Without a secret key the server refuses to store credentials
+	req, _ := http.NewRequest("POST", "/api/crosspost/accounts", bytes.NewBuffer(payload))
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 without a secret key, got %d", rr.Code)
+	}
+
+	box, err := secrets.NewBox("test-key")
+	if err != nil {
+		t.Fatalf("NewBox failed: %v", err)
+	}
+	testHandler.CrossPost = crosspost.NewService(testStore, box)
+	defer func() { testHandler.CrossPost = nil }()
+
+	invalid := []string{
+		`{"provider":"myspace","instance_url":"https://x.example","handle":"a","token":"b"}`,
+		`{"provider":"mastodon","instance_url":"http://x.example","handle":"a","token":"b"}`,
+		`{"provider":"mastodon","instance_url":"https://x.example","handle":"a","token":""}`,
+		`{"provider":"mastodon","instance_url":"https://x.example","handle":"a","token":"b","template":"{{.Nope}}"}`,
+	}
+	for _, body := range invalid {
+		req, _ := http.NewRequest("POST", "/api/crosspost/accounts", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rr.Code)
+		}
+	}
+
+	req, _ = http.NewRequest("POST", "/api/crosspost/accounts", bytes.NewBuffer(payload))
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if strings.Contains(rr.Body.String(), "super-secret") {
+		t.Error("Response must not contain the token")
+	}
+	var created model.CrosspostAccount
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	if created.InstanceURL != "https://mastodon.example" || !created.Enabled || created.Template != model.DefaultCrosspostTemplate {
+		t.Errorf("Unexpected account: %+v", created)
+	}
+
+	stored, err := testStore.GetCrosspostAccounts(context.Background())
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("Expected 1 stored account, got %v (%v)", stored, err)
+	}
+	if stored[0].EncryptedToken == "super-secret" {
+		t.Error("Token was stored in plaintext")
+	}
+	if token, err := box.Open(stored[0].EncryptedToken); err != nil || token != "super-secret" {
+		t.Errorf("Stored token does not decrypt: %q, %v", token, err)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/crosspost/accounts", nil)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "super-secret") || strings.Contains(rr.Body.String(), stored[0].EncryptedToken) {
+		t.Errorf("Unexpected list response %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("DELETE", "/api/crosspost/accounts/"+itoa(created.ID), nil)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", rr.Code)
+	}
+	req, _ = http.NewRequest("DELETE", "/api/crosspost/accounts/"+itoa(created.ID), nil)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// deletionPreviewResponse is a preview of a delete by filter, with the
+// request that carries it out.
+type deletionPreviewResponse struct {
+	*model.DeletionPreview
+	Confirm string `json:"confirm"` // Send DELETE to this URL to delete the books
+}
+
+// bulkDeletionResponse is a delete by filter carried out, with the request
+// that undoes it.
+type bulkDeletionResponse struct {
+	*model.BulkDeletion
+	Undo string `json:"undo"` // Send POST to this URL to restore the books
+}
+
+func undoLink(id int64) string {
+	return "/api/" + APIVersion + "/deletions/" + strconv.FormatInt(id, 10) + "/undo"
+}
+
+// DeleteBooksHandler handles DELETE /api/books requests, which move every
+// book matching the filters of GET /api/books to the trash, e.g. a bad import
+// with ?source=goodreads_import&added_after=2025-06-01. It takes two steps:
+// without ?confirm= nothing is deleted, and the response lists the books that
+// would be with a confirmation token. Sending the same request with
+// ?confirm=<token> then deletes them as one bulk deletion, which can be
+// undone, unless the matching books changed in between (409 Conflict).
+func (h *APIHandler) DeleteBooksHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	for _, key := range []string{"limit", "offset", "sort", "order", "favorites_first"} {
+		if q.Has(key) {
+			respondWithError(w, http.StatusBadRequest, key+" does not apply to deleting books")
+			return
+		}
+	}
+	opts, _, err := parseListOptions(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	token := q.Get("confirm")
+	if token == "" {
+		preview, err := h.Store.PreviewBulkDeletion(r.Context(), opts.Filter)
+		if err != nil {
+			respondWithStoreError(w, err, "Failed to preview deletion")
+			return
+		}
+		q.Set("confirm", preview.Token)
+		respondWithJSON(w, http.StatusOK, deletionPreviewResponse{DeletionPreview: preview, Confirm: "/api/" + APIVersion + "/books?" + q.Encode()})
+		return
+	}
+
+	q.Del("confirm")
+	deletion, err := h.Store.DeleteBooks(r.Context(), opts.Filter, q.Encode(), token)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to delete books")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, bulkDeletionResponse{BulkDeletion: deletion, Undo: undoLink(deletion.ID)})
+}
+
+// GetBulkDeletionsHandler handles GET /api/deletions requests, listing every
+// delete by filter, newest first, with the number of its books still in the
+// trash.
+func (h *APIHandler) GetBulkDeletionsHandler(w http.ResponseWriter, r *http.Request) {
+	deletions, err := h.Store.GetBulkDeletions(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve deletions: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, deletions)
+}
+
+// UndoBulkDeletionHandler handles POST /api/deletions/{id}/undo requests,
+// restoring the books of a delete by filter that are still in the trash.
+func (h *APIHandler) UndoBulkDeletionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid deletion ID")
+		return
+	}
+	deletion, err := h.Store.UndoBulkDeletion(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to undo deletion")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, deletion)
+}
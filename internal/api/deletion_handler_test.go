@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestDeleteBooksHandler tests deleting books by filter in two steps and undoing it
+func TestDeleteBooksHandler(t *testing.T) {
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	var ids []int64
+	for _, title := range []string{"Bulk One", "Bulk Two"} {
+		rr := do("POST", "/api/books", `{"title": "`+title+`", "author": "Bulk Deleter", "open_library_id": "OL`+strings.ReplaceAll(title, " ", "")+`M"}`)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Adding a book: got status %d, body: %s", rr.Code, rr.Body.String())
+		}
+		var book BookResponse
+		json.Unmarshal(rr.Body.Bytes(), &book)
+		ids = append(ids, book.ID)
+	}
+
+	for _, path := range []string{"/api/books", "/api/books?author=Bulk+Deleter&limit=1", "/api/books?status=nope"} {
+		if rr := do("DELETE", path, ""); rr.Code != http.StatusBadRequest {
+			t.Errorf("DELETE %s: expected 400, got %d: %s", path, rr.Code, rr.Body.String())
+		}
+	}
+
+	rr := do("DELETE", "/api/books?author=Bulk+Deleter", "")
+	var preview deletionPreviewResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &preview); err != nil || rr.Code != http.StatusOK || preview.DeletionPreview == nil || preview.Count != 2 {
+		t.Fatalf("Expected a preview of 2 books, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/books/"+itoa(ids[0]), ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected the preview to delete nothing, got status %d", rr.Code)
+	}
+	if rr := do("DELETE", "/api/books?author=Bulk+Deleter&confirm=stale", ""); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a wrong token, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	confirm, err := url.Parse(preview.Confirm)
+	if err != nil || !strings.HasPrefix(confirm.Path, "/api/v1/books") || confirm.Query().Get("confirm") != preview.Token {
+		t.Fatalf("Expected a confirmation URL with the token, got %q", preview.Confirm)
+	}
+	rr = do("DELETE", "/api/books?"+confirm.RawQuery, "")
+	var deletion bulkDeletionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &deletion); err != nil || rr.Code != http.StatusOK || deletion.BulkDeletion == nil || deletion.BookCount != 2 {
+		t.Fatalf("Expected 2 books deleted, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if deletion.Filter != "author=Bulk+Deleter" || deletion.Undo != "/api/v1/deletions/"+itoa(deletion.ID)+"/undo" {
+		t.Errorf("Expected the filter and an undo link, got %+v", deletion)
+	}
+	for _, id := range ids {
+		if rr := do("GET", "/api/books/"+itoa(id), ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected book %d in the trash, got status %d", id, rr.Code)
+		}
+	}
+
+	rr = do("GET", "/api/deletions", "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"in_trash":2`) {
+		t.Errorf("Expected the deletion listed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = do("POST", "/api"+strings.TrimPrefix(deletion.Undo, "/api/v1"), "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"restored":2`) {
+		t.Errorf("Expected the deletion undone, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("POST", "/api/deletions/"+itoa(deletion.ID)+"/undo", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected a second undo to be refused, got %d", rr.Code)
+	}
+	for _, id := range ids {
+		if rr := do("GET", "/api/books/"+itoa(id), ""); rr.Code != http.StatusOK {
+			t.Errorf("Expected book %d restored, got status %d", id, rr.Code)
+		}
+		do("DELETE", "/api/books/"+itoa(id), "")
+	}
+	if rr := do("POST", "/api/deletions/99999/undo", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing deletion, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,24 @@
+package api
+
+import "net/http"
+
+// CleanDescriptionsHandler handles POST /api/admin/descriptions/clean
+// requests. Every stored description is cleaned again, and the response
+// reports how many were checked and how many changed. With ?dry_run=true
+// nothing is changed, and the response lists each change that would be made.
+func (h *APIHandler) CleanDescriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	r, dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
+	report, err := h.Store.CleanDescriptions(r.Context())
+	if dryRun {
+		respondWithDryRun(w, report, nil, err, "Failed to clean descriptions")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to clean descriptions: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+)
+
+// TestDescriptionCleanup tests that descriptions are cleaned on add and by the re-clean job
+func TestDescriptionCleanup(t *testing.T) {
+	body := `{"title":"Described","author":"A","open_library_id":"OL777DESCM","description":"<p>Great <i>fun</i>.<br>Really.</p>"}`
+	req, _ := http.NewRequest("POST", "/api/books", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Adding a book: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	var book BookResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &book); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), book.ID)
+	if book.Description == nil || *book.Description != "Great _fun_.\nReally." {
+		t.Errorf("Expected a cleaned description, got %v", book.Description)
+	}
+
+	req, _ = http.NewRequest("POST", "/api/admin/descriptions/clean", nil)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Re-cleaning: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	var report db.DescriptionCleanup
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if report.Checked < 1 || report.Cleaned != 0 {
+		t.Errorf("Expected clean descriptions to be left alone, got %+v", report)
+	}
+}
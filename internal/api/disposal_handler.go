@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// AddBookDisposalHandler handles POST /api/books/{id}/disposals requests,
+// recording that a book left the collection and archiving it. Expects
+// {"kind": "gifted", "recipient": "Alice", "reason": "...", "disposed_on":
+// "2025-12-24"}; all but kind are optional, and the date defaults to today.
+func (h *APIHandler) AddBookDisposalHandler(w http.ResponseWriter, r *http.Request) {
+	bookID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	var payload struct {
+		Kind       model.DisposalKind `json:"kind"`
+		Recipient  *string            `json:"recipient"`
+		Reason     *string            `json:"reason"`
+		DisposedOn string             `json:"disposed_on"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	disposal := model.Disposal{BookID: &bookID, Kind: payload.Kind, Recipient: payload.Recipient, Reason: payload.Reason, DisposedOn: payload.DisposedOn}
+	if err := h.Store.AddDisposal(r.Context(), &disposal); err != nil {
+		respondWithStoreError(w, err, "Failed to record disposal")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, disposal)
+}
+
+// disposalYear reads the optional ?year= of the disposal endpoints, 0 when it
+// is left out. It responds with the error and returns false when it is
+// invalid.
+func disposalYear(w http.ResponseWriter, r *http.Request) (int, bool) {
+	raw := r.URL.Query().Get("year")
+	if raw == "" {
+		return 0, true
+	}
+	year, err := strconv.Atoi(raw)
+	if err != nil || year < 1 || year > 9999 {
+		respondWithError(w, http.StatusBadRequest, "Invalid year")
+		return 0, false
+	}
+	return year, true
+}
+
+// GetDisposalsHandler handles GET /api/disposals requests, listing the books
+// that left the collection in ?year=, or ever, newest first.
+func (h *APIHandler) GetDisposalsHandler(w http.ResponseWriter, r *http.Request) {
+	year, ok := disposalYear(w, r)
+	if !ok {
+		return
+	}
+	disposals, err := h.Store.GetDisposals(r.Context(), year)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve disposals: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, disposals)
+}
+
+// GetDisposalReportHandler handles GET /api/disposals/report requests,
+// summing up the disposals of ?year=, or of every year, by kind and
+// recipient.
+func (h *APIHandler) GetDisposalReportHandler(w http.ResponseWriter, r *http.Request) {
+	year, ok := disposalYear(w, r)
+	if !ok {
+		return
+	}
+	report, err := h.Store.GetDisposalReport(r.Context(), year)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to compute disposal report: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// DeleteDisposalHandler handles DELETE /api/disposals/{id} requests. The
+// book stays archived.
+func (h *APIHandler) DeleteDisposalHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid disposal ID")
+		return
+	}
+	if err := h.Store.DeleteDisposal(r.Context(), id); err != nil {
+		respondWithStoreError(w, err, "Failed to delete disposal")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestDisposalHandlers tests recording a book leaving the collection, listing
+// and reporting disposals, and deleting one
+func TestDisposalHandlers(t *testing.T) {
+	book := createTestBook(model.StatusRead, "Disposal")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("POST", "/api/books/"+itoa(id)+"/disposals", `{"kind": "gifted", "recipient": "Bob", "reason": "He loved it", "disposed_on": "1999-05-01"}`)
+	var disposal model.Disposal
+	if err := json.Unmarshal(rr.Body.Bytes(), &disposal); err != nil || rr.Code != http.StatusCreated || disposal.ID == 0 {
+		t.Fatalf("Expected the disposal recorded, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if disposal.Title != book.Title || disposal.BookID == nil || *disposal.BookID != id {
+		t.Errorf("Expected the disposal of the book, got %+v", disposal)
+	}
+	if got, _ := testStore.GetBookByID(context.Background(), id); !got.Archived {
+		t.Error("Expected the disposed book to be archived")
+	}
+	for _, body := range []string{`{}`, `{"kind": "burned"}`, `{"kind": "sold", "disposed_on": "May 1"}`, `{"kind": "sold", "price": 5}`} {
+		if rr := do("POST", "/api/books/"+itoa(id)+"/disposals", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("POST %s: expected 400, got %d: %s", body, rr.Code, rr.Body.String())
+		}
+	}
+	if rr := do("POST", "/api/books/999999/disposals", `{"kind": "lost"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing book, got %d", rr.Code)
+	}
+
+	rr = do("GET", "/api/disposals?year=1999", "")
+	var disposals []model.Disposal
+	if err := json.Unmarshal(rr.Body.Bytes(), &disposals); err != nil || len(disposals) != 1 || disposals[0].ID != disposal.ID {
+		t.Errorf("Expected the 1999 disposal listed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = do("GET", "/api/disposals/report?year=1999", "")
+	var report model.DisposalReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected the report, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if report.Total != 1 || len(report.Recipients) != 1 || report.Recipients[0].Value != "Bob" || report.Kinds[0].Books != 1 {
+		t.Errorf("Expected one gift to Bob, got %+v", report)
+	}
+	if rr := do("GET", "/api/disposals/report?year=abc", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid year, got %d", rr.Code)
+	}
+
+	if rr := do("DELETE", "/api/disposals/"+itoa(disposal.ID), ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 deleting the disposal, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("DELETE", "/api/disposals/"+itoa(disposal.ID), ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting the disposal again, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+)
+
+// apiDocsPage is the page of GET /api/docs, browsing the OpenAPI document and
+// trying its requests. It's self-contained, loading nothing from other sites,
+// so the docs work offline and no CDN's script runs with a user's login.
+//
+//go:embed api_docs.html
+var apiDocsPage []byte
+
+// apiDocsPolicy is the Content-Security-Policy of apiDocsPage, which keeps it
+// to its own script and style and to requests to this server.
+const apiDocsPolicy = "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; connect-src 'self'; form-action 'none'; frame-ancestors 'none'"
+
+// servedOpenAPIDocument is the OpenAPI document as GET /api/openapi.json
+// serves it. The embedded one only describes what requests are validated
+// against, so each operation without responses is given a default one, as
+// OpenAPI requires; responses are described in the README.
+var servedOpenAPIDocument = mustServedOpenAPIDocument(openAPIDocument)
+
+func mustServedOpenAPIDocument(document []byte) []byte {
+	var spec map[string]interface{}
+	if err := json.Unmarshal(document, &spec); err != nil {
+		panic(err)
+	}
+	paths, _ := spec["paths"].(map[string]interface{})
+	for _, item := range paths {
+		operations, _ := item.(map[string]interface{})
+		for method, op := range operations {
+			op, ok := op.(map[string]interface{})
+			if !ok || method == "parameters" {
+				continue
+			}
+			if _, ok := op["responses"]; !ok {
+				op["responses"] = map[string]interface{}{
+					"default": map[string]interface{}{"description": "See the API documentation in the README."},
+				}
+			}
+		}
+	}
+	served, err := json.Marshal(spec)
+	if err != nil {
+		panic(err)
+	}
+	return served
+}
+
+// GetOpenAPIHandler handles GET /api/openapi.json requests, serving the
+// OpenAPI document requests are validated against.
+func (h *APIHandler) GetOpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(servedOpenAPIDocument)
+}
+
+// GetAPIDocsHandler handles GET /api/docs requests, serving a page for
+// browsing and trying the API.
+func (h *APIHandler) GetAPIDocsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", apiDocsPolicy)
+	w.Write(apiDocsPage)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPIDocs(t *testing.T) {
+	router, _ := newAuthRouter(t)
+	// The documentation needs no credentials, even once there are users
+	loginTestUser(t, router)
+
+	for _, path := range []string{"/api/v1/openapi.json", "/api/openapi.json"} {
+		rr := authRequest(router, "GET", path, "", "")
+		if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/json" {
+			t.Fatalf("GET %s: got status %d, headers %v", path, rr.Code, rr.Header())
+		}
+		var spec struct {
+			OpenAPI string                                `json:"openapi"`
+			Paths   map[string]map[string]json.RawMessage `json:"paths"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &spec); err != nil || spec.OpenAPI == "" {
+			t.Fatalf("GET %s: invalid document: %v", path, err)
+		}
+		if spec.Paths["/books/{id}"]["get"] == nil || spec.Paths["/openapi.json"]["get"] == nil {
+			t.Errorf("GET %s: expected every route, got paths %d", path, len(spec.Paths))
+		}
+		// OpenAPI requires responses of every operation
+		for p, item := range spec.Paths {
+			for method, raw := range item {
+				var op struct {
+					Responses map[string]interface{} `json:"responses"`
+				}
+				if method != "parameters" && (json.Unmarshal(raw, &op) != nil || op.Responses == nil) {
+					t.Errorf("GET %s: %s %s has no responses", path, method, p)
+				}
+			}
+		}
+	}
+
+	rr := authRequest(router, "GET", "/api/v1/docs", "", "")
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("GET /api/v1/docs: got status %d, headers %v", rr.Code, rr.Header())
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "fetch('openapi.json'") {
+		t.Errorf("Expected a page browsing the document, got:\n%s", body)
+	}
+	// It loads nothing from other sites
+	if body := rr.Body.String(); strings.Contains(body, "https://") || strings.Contains(body, "http://") {
+		t.Errorf("Expected the page to be self-contained, got:\n%s", body)
+	}
+	if policy := rr.Header().Get("Content-Security-Policy"); !strings.Contains(policy, "default-src 'none'") || !strings.Contains(policy, "connect-src 'self'") {
+		t.Errorf("Expected a policy keeping the page to this server, got %q", policy)
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+)
+
+// dryRunResponse answers a bulk request made with ?dry_run=true: what the
+// request would have returned, and the errors that would have rejected it.
+type dryRunResponse struct {
+	DryRun bool         `json:"dry_run"`
+	Result interface{}  `json:"result"` // nil when there are errors
+	Errors []FieldError `json:"errors"`
+}
+
+// parseDryRun reads the dry_run query parameter of a bulk endpoint. For a dry
+// run it returns the request with a context that rolls the store's changes
+// back. It responds with 400 and returns false when the parameter is invalid.
+func parseDryRun(w http.ResponseWriter, r *http.Request) (*http.Request, bool, bool) {
+	value := r.URL.Query().Get("dry_run")
+	if value == "" {
+		return r, false, true
+	}
+	dry, err := strconv.ParseBool(value)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid dry_run value. Must be true or false")
+		return r, false, false
+	}
+	if !dry {
+		return r, false, true
+	}
+	return r.WithContext(db.WithDryRun(r.Context())), true, true
+}
+
+// respondWithDryRun sends the outcome of a dry run. A store error that would
+// have rejected the request is reported among the errors with 200 OK, so the
+// client sees every problem the same way; any other error is sent as usual.
+func respondWithDryRun(w http.ResponseWriter, result interface{}, errs []FieldError, err error, message string) {
+	if err != nil {
+		if !errors.Is(err, db.ErrValidation) && !errors.Is(err, db.ErrDuplicate) && !errors.Is(err, db.ErrForeignKey) {
+			respondWithStoreError(w, err, message)
+			return
+		}
+		errs = append(errs, FieldError{Location: "body", Message: err.Error()})
+	}
+	if errs == nil {
+		errs = []FieldError{}
+	} else {
+		result = nil
+	}
+	respondWithJSON(w, http.StatusOK, dryRunResponse{DryRun: true, Result: result, Errors: errs})
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/match"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// The types in this file are the JSON representation of books in the API.
+// They are kept separate from model.Book so that storage details (such as the
+// cover cache key) stay internal and fields can be renamed or computed without
+// touching the database layer.
+
+// BookResponse is a book as returned by the API.
+type BookResponse struct {
+	ID              int64             `json:"id"`
+	Title           string            `json:"title"`
+	Subtitle        *string           `json:"subtitle,omitempty"`
+	FullTitle       string            `json:"full_title"` // Title and subtitle, for display
+	Author          string            `json:"author"`
+	OpenLibraryID   string            `json:"open_library_id"`
+	ISBN            string            `json:"isbn,omitempty"`
+	Status          model.BookStatus  `json:"status"`
+	Type            model.BookType    `json:"type"`
+	Rating          *int              `json:"rating,omitempty"`
+	Comments        *string           `json:"comments,omitempty"`
+	Description     *string           `json:"description,omitempty"`     // Markdown
+	CoverURL        *string           `json:"cover_url,omitempty"`       // Where the cover was found
+	CoverImageURL   string            `json:"cover_image_url,omitempty"` // Where clients should load the cover from: the cached copy when there is one
+	CoverBlurhash   string            `json:"cover_blurhash,omitempty"`
+	CoverLQIP       string            `json:"cover_lqip,omitempty"`
+	Series          *string           `json:"series,omitempty"`
+	SeriesIndex     *int              `json:"series_index,omitempty"`
+	PublishYear     *int              `json:"publish_year,omitempty"`
+	Edition         *int              `json:"edition,omitempty"`
+	PageCount       *int              `json:"page_count,omitempty"`
+	Publisher       *string           `json:"publisher,omitempty"`
+	Language        *string           `json:"language,omitempty"` // A code such as en or eng
+	CourseCode      *string           `json:"course_code,omitempty"`
+	Semester        *string           `json:"semester,omitempty"`
+	ReadingMode     model.ReadingMode `json:"reading_mode"`
+	PublishOptOut   bool              `json:"publish_opt_out"`
+	CommentsSpoiler bool              `json:"comments_spoiler"`
+	Translated      bool              `json:"translated"`
+	Favorite        bool              `json:"favorite"`
+	Label           *string           `json:"label,omitempty"` // An emoji, or a color as #rrggbb
+	CurrentPage     *int              `json:"current_page,omitempty"`
+	ProgressPercent *float64          `json:"progress_percent,omitempty"`
+	Archived        bool              `json:"archived"`
+	NoteCount       int               `json:"note_count"` // See GET /api/books/{id}/notes
+	LentOut         bool              `json:"lent_out"`   // See GET /api/books/{id}/loans
+	DateStarted     *time.Time        `json:"date_started,omitempty"`
+	DateFinished    *time.Time        `json:"date_finished,omitempty"`
+	UpdatedAt       *time.Time        `json:"updated_at,omitempty"`
+	// EstimatedFinishDate is when a book being read should be finished at the
+	// recent reading pace; see APIHandler.estimateFinish.
+	EstimatedFinishDate *time.Time `json:"estimated_finish_date,omitempty"`
+	// SourceRating is the rating as last imported from a tracker, on the
+	// tracker's own scale.
+	SourceRating *model.SourceRating `json:"source_rating,omitempty"`
+	// Source and AddedAt record where and when the book was added.
+	Source  model.BookSource `json:"source,omitempty"`
+	AddedAt *time.Time       `json:"added_at,omitempty"`
+	// ImportBatchID is the import that added the book, if any; see
+	// POST /api/imports/{id}/rollback.
+	ImportBatchID *int64 `json:"import_batch_id,omitempty"`
+	// DeletedAt is when a book in the trash was deleted; see GET /api/trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// newBookResponse converts a stored book to its API representation.
+func newBookResponse(b *model.Book) BookResponse {
+	resp := BookResponse{
+		ID:              b.ID,
+		Title:           b.Title,
+		Subtitle:        b.Subtitle,
+		FullTitle:       b.FullTitle(),
+		Author:          b.Author,
+		OpenLibraryID:   b.OpenLibraryID,
+		ISBN:            b.ISBN,
+		Status:          b.Status,
+		Type:            b.Type,
+		Rating:          b.Rating,
+		SourceRating:    b.SourceRating,
+		Comments:        b.Comments,
+		Description:     b.Description,
+		CoverURL:        b.CoverURL,
+		CoverBlurhash:   b.CoverBlurhash,
+		CoverLQIP:       b.CoverLQIP,
+		Series:          b.Series,
+		SeriesIndex:     b.SeriesIndex,
+		PublishYear:     b.PublishYear,
+		Edition:         b.Edition,
+		PageCount:       b.PageCount,
+		Publisher:       b.Publisher,
+		Language:        b.Language,
+		CourseCode:      b.CourseCode,
+		Semester:        b.Semester,
+		ReadingMode:     b.ReadingMode,
+		PublishOptOut:   b.PublishOptOut,
+		CommentsSpoiler: b.CommentsSpoiler,
+		Translated:      b.Translated,
+		Favorite:        b.Favorite,
+		Label:           b.Label,
+		CurrentPage:     b.CurrentPage,
+		ProgressPercent: b.ProgressPercent,
+		Archived:        b.Archived,
+		NoteCount:       b.NoteCount,
+		LentOut:         b.LentOut,
+		DateStarted:     b.DateStarted,
+		DateFinished:    b.DateFinished,
+		UpdatedAt:       b.UpdatedAt,
+		Source:          b.Source,
+		AddedAt:         b.AddedAt,
+		ImportBatchID:   b.ImportBatchID,
+		DeletedAt:       b.DeletedAt,
+	}
+	switch {
+	case b.CoverHash != nil:
+		resp.CoverImageURL = "/api/" + APIVersion + "/covers/" + *b.CoverHash
+	case b.CoverURL != nil:
+		// Served through the cover cache, which fetches the cover on first view
+		resp.CoverImageURL = "/api/" + APIVersion + "/books/" + strconv.FormatInt(b.ID, 10) + "/cover"
+	}
+	return resp
+}
+
+// newBookResponses converts a list of stored books.
+func newBookResponses(books []model.Book) []BookResponse {
+	out := make([]BookResponse, len(books))
+	for i := range books {
+		out[i] = newBookResponse(&books[i])
+	}
+	return out
+}
+
+// BookRequest is the body of POST /api/books.
+type BookRequest struct {
+	Title           string            `json:"title"` // A subtitle after ": " is split off unless Subtitle is given; an empty one keeps the title whole
+	Subtitle        *string           `json:"subtitle"`
+	Author          string            `json:"author"`
+	OpenLibraryID   string            `json:"open_library_id"`
+	ISBN            string            `json:"isbn"`
+	Status          model.BookStatus  `json:"status"`
+	Type            model.BookType    `json:"type"`
+	Description     *string           `json:"description"` // From the provider; HTML is converted to Markdown
+	CoverURL        *string           `json:"cover_url"`
+	Series          *string           `json:"series"`
+	SeriesIndex     *int              `json:"series_index"`
+	PublishYear     *int              `json:"publish_year"`
+	Edition         *int              `json:"edition"`
+	PageCount       *int              `json:"page_count"`
+	Publisher       *string           `json:"publisher"`
+	Language        *string           `json:"language"`
+	CourseCode      *string           `json:"course_code"`
+	Semester        *string           `json:"semester"`
+	ReadingMode     model.ReadingMode `json:"reading_mode"`
+	PublishOptOut   bool              `json:"publish_opt_out"`
+	CommentsSpoiler bool              `json:"comments_spoiler"`
+	Translated      bool              `json:"translated"`
+	Label           *string           `json:"label"`         // An emoji, or a color as #rrggbb
+	DateStarted     *time.Time        `json:"date_started"`  // For books added mid-read or already read
+	DateFinished    *time.Time        `json:"date_finished"` // Also logged as the book's first read
+	// Source is how the book was found, e.g. isbn_scan; defaults to api.
+	Source model.BookSource `json:"source"`
+
+	readOnlyBookFields
+}
+
+// readOnlyBookFields are the fields of BookResponse that clients cannot set.
+// They are accepted, and ignored, so a book returned by the API can be posted
+// back as-is. Rating, comments, the favorite and archived flags and reading
+// progress are set once the book is on a shelf.
+type readOnlyBookFields struct {
+	ID              int64      `json:"id"`
+	FullTitle       string     `json:"full_title"`
+	Rating          *int       `json:"rating"`
+	Comments        *string    `json:"comments"`
+	Favorite        bool       `json:"favorite"`
+	CurrentPage     *int       `json:"current_page"`
+	ProgressPercent *float64   `json:"progress_percent"`
+	Archived        bool       `json:"archived"`
+	NoteCount       int        `json:"note_count"`
+	LentOut         bool       `json:"lent_out"`
+	CoverImageURL   string     `json:"cover_image_url"`
+	CoverBlurhash   string     `json:"cover_blurhash"`
+	CoverLQIP       string     `json:"cover_lqip"`
+	UpdatedAt       *time.Time `json:"updated_at"`
+	AddedAt         *time.Time `json:"added_at"`
+	ImportBatchID   *int64     `json:"import_batch_id"`
+	DeletedAt       *time.Time `json:"deleted_at"`
+}
+
+// toModel converts the request to a new book.
+func (r *BookRequest) toModel() model.Book {
+	return model.Book{
+		Title:           r.Title,
+		Subtitle:        r.Subtitle,
+		Author:          r.Author,
+		OpenLibraryID:   r.OpenLibraryID,
+		ISBN:            r.ISBN,
+		Status:          r.Status,
+		Type:            r.Type,
+		Description:     r.Description,
+		CoverURL:        r.CoverURL,
+		Series:          r.Series,
+		SeriesIndex:     r.SeriesIndex,
+		PublishYear:     r.PublishYear,
+		Edition:         r.Edition,
+		PageCount:       r.PageCount,
+		Publisher:       r.Publisher,
+		Language:        r.Language,
+		CourseCode:      r.CourseCode,
+		Semester:        r.Semester,
+		ReadingMode:     r.ReadingMode,
+		PublishOptOut:   r.PublishOptOut,
+		CommentsSpoiler: r.CommentsSpoiler,
+		Translated:      r.Translated,
+		Label:           r.Label,
+		DateStarted:     r.DateStarted,
+		DateFinished:    r.DateFinished,
+		Source:          r.Source,
+	}
+}
+
+// ScoredBookResponse is a book with its similarity score, as returned by the
+// duplicate finder.
+type ScoredBookResponse struct {
+	Book  BookResponse `json:"book"`
+	Score float64      `json:"score"`
+}
+
+func newDuplicateGroups(groups [][]match.Scored) [][]ScoredBookResponse {
+	out := make([][]ScoredBookResponse, len(groups))
+	for i, group := range groups {
+		out[i] = make([]ScoredBookResponse, len(group))
+		for j := range group {
+			out[i][j] = ScoredBookResponse{Book: newBookResponse(&group[j].Book), Score: group[j].Score}
+		}
+	}
+	return out
+}
+
+// MemoryResponse is a book finished or added on the same day of an earlier year.
+type MemoryResponse struct {
+	YearsAgo int          `json:"years_ago"`
+	Date     time.Time    `json:"date"`
+	Book     BookResponse `json:"book"`
+}
+
+// OnThisDayResponse is the body of GET /api/onthisday.
+type OnThisDayResponse struct {
+	Date     string           `json:"date"` // The day looked up, as YYYY-MM-DD
+	Finished []MemoryResponse `json:"finished"`
+	Added    []MemoryResponse `json:"added"`
+}
+
+func newMemoryResponses(memories []model.Memory) []MemoryResponse {
+	out := make([]MemoryResponse, len(memories))
+	for i := range memories {
+		out[i] = MemoryResponse{YearsAgo: memories[i].YearsAgo, Date: memories[i].Date.UTC(), Book: newBookResponse(&memories[i].Book)}
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestBookResponse(t *testing.T) {
+	coverURL := "https://covers.example.com/1.jpg"
+	hash := strings.Repeat("ab", 32)
+	book := model.Book{ID: 7, Title: "Dune", Author: "Frank Herbert", CoverURL: &coverURL, CoverHash: &hash}
+
+	resp := newBookResponse(&book)
+	if resp.CoverImageURL != "/api/v1/covers/"+hash {
+		t.Errorf("Expected the cached cover to be preferred, got %q", resp.CoverImageURL)
+	}
+	data, _ := json.Marshal(resp)
+	if strings.Contains(string(data), "cover_hash") {
+		t.Errorf("Internal cover hash leaked into the response: %s", data)
+	}
+
+	book.CoverHash = nil
+	if resp := newBookResponse(&book); resp.CoverImageURL != "/api/v1/books/7/cover" {
+		t.Errorf("Expected the book's cover endpoint without a cached copy, got %q", resp.CoverImageURL)
+	}
+	book.CoverURL = nil
+	if resp := newBookResponse(&book); resp.CoverImageURL != "" {
+		t.Errorf("Expected no cover image URL, got %q", resp.CoverImageURL)
+	}
+}
+
+func TestBookRequestIgnoresReadOnlyFields(t *testing.T) {
+	var req BookRequest
+	body := `{"id": 42, "title": "Dune", "open_library_id": "OL1M", "rating": 9, "comments": "Great", "updated_at": "2025-01-02T15:04:05Z"}`
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatalf("Failed to decode request: %v", err)
+	}
+	book := req.toModel()
+	if book.ID != 0 || book.Rating != nil || book.Comments != nil || book.UpdatedAt != nil {
+		t.Errorf("Read-only fields must not reach the new book: %+v", book)
+	}
+	if book.Title != "Dune" || book.OpenLibraryID != "OL1M" {
+		t.Errorf("Writable fields were not copied: %+v", book)
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/export"
+)
+
+// ExportHandler handles GET /api/export requests and downloads the whole
+// bookshelf. Optional query parameters:
+//   - format: csv, goodreads (CSV in the Goodreads layout), json (default) or markdown
+//   - since: RFC 3339 timestamp; only books changed after it are exported,
+//     together with tombstones for books deleted after it
+//   - since_export: ID of an earlier export to continue from, as returned in
+//     the X-Export-ID header; an alternative to since
+func (h *APIHandler) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := export.Format(query.Get("format"))
+	if format == "" {
+		format = export.FormatJSON
+	} else if !format.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid format. Must be 'csv', 'goodreads', 'json' or 'markdown'")
+		return
+	}
+
+	var since *time.Time
+	switch {
+	case query.Get("since") != "" && query.Get("since_export") != "":
+		respondWithError(w, http.StatusBadRequest, "Use either since or since_export, not both")
+		return
+	case query.Get("since") != "":
+		t, err := time.Parse(time.RFC3339, query.Get("since"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid since timestamp, expected RFC 3339 (e.g., 2025-01-02T15:04:05Z)")
+			return
+		}
+		since = &t
+	case query.Get("since_export") != "":
+		id, err := strconv.ParseInt(query.Get("since_export"), 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid since_export ID")
+			return
+		}
+		run, err := h.Store.GetExportByID(r.Context(), id)
+		if err != nil {
+			respondWithStoreError(w, err, "Failed to retrieve export")
+			return
+		}
+		since = &run.ExportedAt
+	}
+
+	now := time.Now().UTC()
+	snap, err := export.Take(r.Context(), h.Store, format, since, now)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to export books: "+err.Error())
+		return
+	}
+	var buf bytes.Buffer
+	if err := export.Write(&buf, snap, format); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to render export: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.Filename(now, format)))
+	w.Header().Set("X-Export-ID", strconv.FormatInt(snap.ExportID, 10))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		slog.ErrorContext(r.Context(), "Error writing export response", "error", err)
+	}
+}
+
+// ExportInsuranceHandler handles GET /api/export/insurance requests and
+// downloads a ZIP archive documenting the books owned for insurance: an
+// inventory with each book's latest estimated value, as CSV and as Markdown
+// for printing, and the book covers from the cover cache.
+func (h *APIHandler) ExportInsuranceHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	inv, err := export.TakeInventory(r.Context(), h.Store, now)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to take inventory: "+err.Error())
+		return
+	}
+	var covers export.Covers
+	if h.CoverCache != nil {
+		covers = h.CoverCache
+	}
+	var buf bytes.Buffer
+	if err := export.WriteInsurance(r.Context(), &buf, inv, covers); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to render insurance export: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.InsuranceFilename(now)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		slog.ErrorContext(r.Context(), "Error writing insurance export response", "error", err)
+	}
+}
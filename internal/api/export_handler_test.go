@@ -0,0 +1,107 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/export"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestExportHandler tests full and differential exports. The book it adds is
+// deleted again, so the shared test database is left without books.
+func TestExportHandler(t *testing.T) {
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	book := &model.Book{Title: "Middlemarch", Author: "George Eliot", OpenLibraryID: "OLMIDDLEMARCHW", Status: model.StatusWantToRead}
+	if _, err := testStore.AddBook(context.Background(), book); err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+
+	rr := get("/api/export")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var full export.Document
+	if err := json.Unmarshal(rr.Body.Bytes(), &full); err != nil {
+		t.Fatalf("Could not unmarshal export: %v", err)
+	}
+	if full.Since != nil || len(full.Books) == 0 || rr.Header().Get("X-Export-ID") != itoa(full.ExportID) {
+		t.Errorf("Unexpected full export (header %q): %+v", rr.Header().Get("X-Export-ID"), full)
+	}
+
+	rr = get("/api/export?format=goodreads")
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Body.String(), "Book Id,Title,Author,") || !strings.Contains(rr.Body.String(), "Middlemarch,George Eliot,\"Eliot, George\"") ||
+		!strings.HasSuffix(rr.Header().Get("Content-Disposition"), `.goodreads.csv"`) {
+		t.Errorf("Expected a Goodreads CSV, got %d (%q): %s", rr.Code, rr.Header().Get("Content-Disposition"), rr.Body.String())
+	}
+
+	if err := testStore.DeleteBook(context.Background(), book.ID); err != nil {
+		t.Fatalf("Failed to delete test book: %v", err)
+	}
+
+	rr = get("/api/export?since_export=" + itoa(full.ExportID))
+	var delta export.Document
+	json.Unmarshal(rr.Body.Bytes(), &delta)
+	if rr.Code != http.StatusOK || delta.Since == nil || len(delta.Books) != 0 || len(delta.Deleted) != 1 || delta.Deleted[0].BookID != book.ID {
+		t.Errorf("Expected only the deletion since export %d, got %d: %s", full.ExportID, rr.Code, rr.Body.String())
+	}
+
+	rr = get("/api/export?format=markdown&since=" + full.ExportedAt.Format("2006-01-02T15:04:05.999999999Z07:00"))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "~~Middlemarch~~") ||
+		!strings.HasPrefix(rr.Header().Get("Content-Type"), "text/markdown") {
+		t.Errorf("Expected Markdown changes listing the removal, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	for path, want := range map[string]int{
+		"/api/export?format=xml":                                http.StatusBadRequest,
+		"/api/export?since=yesterday":                           http.StatusBadRequest,
+		"/api/export?since_export=abc":                          http.StatusBadRequest,
+		"/api/export?since_export=1&since=2025-01-01T00:00:00Z": http.StatusBadRequest,
+		"/api/export?since_export=999999":                       http.StatusNotFound,
+	} {
+		if rr := get(path); rr.Code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, rr.Code)
+		}
+	}
+}
+
+// TestExportInsuranceHandler tests downloading the insurance archive
+func TestExportInsuranceHandler(t *testing.T) {
+	book := createTestBook(model.StatusRead, "Insured")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	req, _ := http.NewRequest("GET", "/api/export/insurance", nil)
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/zip" ||
+		!strings.Contains(rr.Header().Get("Content-Disposition"), "bookshelf-insurance-") {
+		t.Fatalf("Expected a ZIP download, got %d with headers %v", rr.Code, rr.Header())
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	if err != nil {
+		t.Fatalf("Response is not a ZIP archive: %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "inventory.csv,inventory.md" {
+		t.Errorf("Expected the inventory without covers, got %v", names)
+	}
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/federation"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// FeedHandler handles GET /api/users/{username}/feed.json and /api/feed.json requests.
+// It publishes a user's recent local activity as a JSON Feed that other instances can
+// follow, once the user has made their feed public; until then it isn't found. /feed.json,
+// the feed from before there were accounts, is that of the first user, who adopted the
+// library, or of the whole library while there are no users.
+func (h *APIHandler) FeedHandler(w http.ResponseWriter, r *http.Request) {
+	// The feed is the same whoever asks for it
+	ctx := r.Context()
+	title, path := "Bookshelf activity", "api/feed.json"
+	var user *model.User
+	if username, ok := mux.Vars(r)["username"]; ok {
+		var err error
+		if user, err = h.Store.GetUserByUsername(ctx, username); errors.Is(err, db.ErrNotFound) {
+			// Unknown users look like those whose feed is private
+			respondWithError(w, http.StatusNotFound, "Feed not found")
+			return
+		} else if err != nil {
+			respondWithStoreError(w, err, "Failed to retrieve user")
+			return
+		}
+		title, path = "Bookshelf activity of "+user.Username, "api/users/"+url.PathEscape(user.Username)+"/feed.json"
+	} else if first, err := h.Store.FirstUserID(ctx); err == nil {
+		if user, err = h.Store.GetUserByID(ctx, first); err != nil {
+			respondWithStoreError(w, err, "Failed to retrieve user")
+			return
+		}
+	} else if !errors.Is(err, db.ErrNotFound) {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve activity: "+err.Error())
+		return
+	}
+	if user != nil {
+		if !user.PublicFeed {
+			respondWithError(w, http.StatusNotFound, "Feed not found")
+			return
+		}
+		ctx = db.WithUser(ctx, user.ID)
+	}
+
+	activities, err := h.Store.ListPublicActivities(ctx, 50)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve activity: "+err.Error())
+		return
+	}
+
+	homeURL := baseURL(r)
+	feed := federation.BuildFeed(title, homeURL, homeURL+path, activities)
+
+	w.Header().Set("Content-Type", "application/feed+json")
+	response, err := json.Marshal(feed)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to encode feed: "+err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// TimelineHandler handles GET /api/timeline requests.
+// Returns local and followed activity, newest first. Query params: limit (default 50), local=true.
+func (h *APIHandler) TimelineHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+		limit = n
+	}
+	localOnly := r.URL.Query().Get("local") == "true"
+
+	activities, err := h.Store.ListActivities(r.Context(), limit, localOnly)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve timeline: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, activities)
+}
+
+// GetFollowsHandler handles GET /api/follows requests.
+func (h *APIHandler) GetFollowsHandler(w http.ResponseWriter, r *http.Request) {
+	follows, err := h.Store.GetFollows(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve follows: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, follows)
+}
+
+// AddFollowHandler handles POST /api/follows requests.
+// Expects {"feed_url": "...", "name": "..."}; the feed is fetched once immediately.
+func (h *APIHandler) AddFollowHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		FeedURL string `json:"feed_url"`
+		Name    string `json:"name"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	parsed, err := url.Parse(payload.FeedURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		respondWithError(w, http.StatusBadRequest, "feed_url must be an absolute http(s) URL")
+		return
+	}
+	if payload.Name == "" {
+		payload.Name = parsed.Host
+	}
+
+	follow := model.Follow{FeedURL: payload.FeedURL, Name: payload.Name}
+	if _, err := h.Store.AddFollow(r.Context(), &follow); err != nil {
+		if errors.Is(err, db.ErrDuplicate) {
+			respondWithError(w, http.StatusConflict, "Already following this feed")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to add follow: "+err.Error())
+		}
+		return
+	}
+
+	// Fetch once so the timeline is populated right away; failures are recorded on the follow.
+	h.Feeds.RefreshFollow(r.Context(), follow)
+	if refreshed, err := h.Store.GetFollowByID(r.Context(), follow.ID); err == nil {
+		follow = *refreshed
+	}
+	respondWithJSON(w, http.StatusCreated, follow)
+}
+
+// RefreshFollowHandler handles POST /api/follows/{id}/refresh requests.
+func (h *APIHandler) RefreshFollowHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid follow ID")
+		return
+	}
+
+	follow, err := h.Store.GetFollowByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve follow")
+		return
+	}
+
+	inserted, err := h.Feeds.RefreshFollow(r.Context(), *follow)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, "Failed to refresh feed: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]int{"new_items": inserted})
+}
+
+// DeleteFollowHandler handles DELETE /api/follows/{id} requests.
+func (h *APIHandler) DeleteFollowHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid follow ID")
+		return
+	}
+
+	if err := h.Store.DeleteFollow(r.Context(), id); err != nil {
+		respondWithStoreError(w, err, "Failed to delete follow")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
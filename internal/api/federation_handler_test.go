@@ -0,0 +1,238 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/federation"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestFeedHandler tests the GET /api/feed.json endpoint
+func TestFeedHandler(t *testing.T) {
+	// Record activity directly so the shared books table is left untouched for other tests
+	activity := &model.Activity{Kind: model.ActivityBookAdded, Title: "Feed Test Book", Summary: "Added"}
+	if err := testStore.RecordActivity(context.Background(), activity); err != nil {
+		t.Fatalf("Failed to record activity: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "/api/feed.json", nil)
+	if err != nil {
+		t.Fatalf("Could not create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/feed+json" {
+		t.Errorf("Expected Content-Type application/feed+json, got %s", ct)
+	}
+
+	activities, err := federation.ParseFeed(rr.Body.Bytes())
+	if err != nil {
+		t.Fatalf("Feed could not be parsed: %v", err)
+	}
+	found := false
+	for _, a := range activities {
+		if a.Title == activity.Title && a.Kind == model.ActivityBookAdded {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Feed does not contain the recorded activity")
+	}
+}
+
+// TestFollowLifecycle tests following a remote feed and seeing it in the timeline
+func TestFollowLifecycle(t *testing.T) {
+	remoteFeed := federation.BuildFeed("Remote", "http://remote/", "", []model.Activity{
+		{ID: 1, Kind: model.ActivityBookFinished, Title: "Remote Finished Book", Summary: "Finished"},
+	})
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(remoteFeed)
+	}))
+	defer remote.Close()
+	feedClient := testHandler.Feeds.HTTPClient
+	testHandler.Feeds.HTTPClient = remote.Client() // The test server is on loopback
+	defer func() { testHandler.Feeds.HTTPClient = feedClient }()
+
+	// Follow the remote feed
+	body, _ := json.Marshal(map[string]string{"feed_url": remote.URL, "name": "Remote Friend"})
+	req, _ := http.NewRequest("POST", "/api/follows", bytes.NewBuffer(body))
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusCreated {
+		t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", status, http.StatusCreated, rr.Body.String())
+	}
+	var follow model.Follow
+	if err := json.Unmarshal(rr.Body.Bytes(), &follow); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if follow.LastFetchedAt == nil {
+		t.Errorf("Expected follow to be fetched on creation")
+	}
+
+	// Following again is a conflict
+	req, _ = http.NewRequest("POST", "/api/follows", bytes.NewBuffer(body))
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusConflict {
+		t.Errorf("Handler returned wrong status code for duplicate: got %v want %v", status, http.StatusConflict)
+	}
+
+	// Remote activity shows up in the timeline
+	req, _ = http.NewRequest("GET", "/api/timeline", nil)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	var timeline []model.Activity
+	if err := json.Unmarshal(rr.Body.Bytes(), &timeline); err != nil {
+		t.Fatalf("Could not unmarshal timeline: %v", err)
+	}
+	found := false
+	for _, a := range timeline {
+		if a.Title == "Remote Finished Book" && a.Source == "Remote Friend" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Timeline does not contain remote activity")
+	}
+
+	// Unfollow
+	req, _ = http.NewRequest("DELETE", "/api/follows/"+itoa(follow.ID), nil)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusNoContent {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusNoContent)
+	}
+}
+
+// TestAddFollowHandlerInvalidURL tests that only absolute http(s) feed URLs are accepted
+func TestAddFollowHandlerInvalidURL(t *testing.T) {
+	for _, feedURL := range []string{"", "not a url", "ftp://example.com/feed", "/relative/feed.json"} {
+		body, _ := json.Marshal(map[string]string{"feed_url": feedURL})
+		req, _ := http.NewRequest("POST", "/api/follows", bytes.NewBuffer(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("feed_url %q: got status %v want %v", feedURL, status, http.StatusBadRequest)
+		}
+	}
+}
+
+// TestTimelinePerUser tests that each user has follows, a timeline and a
+// public feed of their own.
+func TestTimelinePerUser(t *testing.T) {
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(federation.BuildFeed("Remote", "http://remote/", "", []model.Activity{
+			{ID: 1, Kind: model.ActivityBookFinished, Title: "Remote Finished Book", Summary: "Finished"},
+		}))
+	}))
+	defer remote.Close()
+
+	handler := newTestAPIHandler(t)
+	handler.Feeds.HTTPClient = remote.Client() // The test server is on loopback
+	router := SetupRouter(handler, t.TempDir())
+	alice, bob := loginTestUser(t, router), registerTestUser(t, router, "bob")
+
+	if rr := authRequest(router, "POST", "/api/v1/books", alice, `{"title":"Secret Diary","author":"Alice","open_library_id":"OL1M","status":"Read"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("Adding a book: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	follow := `{"feed_url":"` + remote.URL + `","name":"Remote Friend"}`
+	rr := authRequest(router, "POST", "/api/v1/follows", alice, follow)
+	var followed model.Follow
+	if err := json.Unmarshal(rr.Body.Bytes(), &followed); err != nil || rr.Code != http.StatusCreated {
+		t.Fatalf("Following a feed: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	timeline := func(path, token string) string {
+		rr := authRequest(router, "GET", path, token, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Getting %s: got status %d, body: %s", path, rr.Code, rr.Body.String())
+		}
+		return rr.Body.String()
+	}
+	if body := timeline("/api/v1/timeline", alice); !strings.Contains(body, "Secret Diary") || !strings.Contains(body, "Remote Finished Book") {
+		t.Errorf("Expected alice's timeline to have her book and follow, got %s", body)
+	}
+	if body := timeline("/api/v1/timeline", bob); strings.Contains(body, "Secret Diary") || strings.Contains(body, "Remote Finished Book") {
+		t.Errorf("Expected bob's timeline to leave out alice's, got %s", body)
+	}
+	if body := timeline("/api/v1/follows", bob); strings.TrimSpace(body) != "[]" {
+		t.Errorf("Expected bob to follow nothing, got %s", body)
+	}
+	if rr := authRequest(router, "DELETE", "/api/v1/follows/"+itoa(followed.ID), bob, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Deleting another user's follow: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := authRequest(router, "POST", "/api/v1/follows", bob, follow); rr.Code != http.StatusCreated {
+		t.Errorf("Following the feed alice follows: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	// The public feeds are per user, /feed.json being the first user's, and
+	// private until their users make them public
+	for _, path := range []string{"/api/v1/users/alice/feed.json", "/api/v1/users/bob/feed.json", "/api/v1/feed.json"} {
+		if rr := authRequest(router, "GET", path, "", ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Getting the private feed %s: got status %d, want %d", path, rr.Code, http.StatusNotFound)
+		}
+	}
+	for _, token := range []string{alice, bob} {
+		if rr := authRequest(router, "PATCH", "/api/v1/users/me", token, `{"public_feed": true}`); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"public_feed":true`) {
+			t.Fatalf("Making a feed public: got status %d, body: %s", rr.Code, rr.Body.String())
+		}
+	}
+	for path, want := range map[string]bool{"/api/v1/users/alice/feed.json": true, "/api/v1/users/bob/feed.json": false, "/api/v1/feed.json": true} {
+		if body := timeline(path, ""); strings.Contains(body, "Secret Diary") != want || strings.Contains(body, "Remote Finished Book") {
+			t.Errorf("%s: expected alice's book %v, got %s", path, want, body)
+		}
+	}
+	if rr := authRequest(router, "GET", "/api/v1/users/nobody/feed.json", "", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Getting an unknown user's feed: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestFeedLeavesOutOptedOutBooks(t *testing.T) {
+	router, _ := newAuthRouter(t)
+	alice := loginTestUser(t, router)
+	if rr := authRequest(router, "PATCH", "/api/v1/users/me", alice, `{"public_feed": true}`); rr.Code != http.StatusOK {
+		t.Fatalf("Making the feed public: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	var ids []int64
+	for i, title := range []string{"Shared Book", "Private Book", "Trashed Book"} {
+		rr := authRequest(router, "POST", "/api/v1/books", alice, `{"title":"`+title+`","author":"Alice","open_library_id":"OL`+itoa(int64(i+1))+`M","status":"Read"}`)
+		var book struct {
+			ID int64 `json:"id"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &book); err != nil || rr.Code != http.StatusCreated {
+			t.Fatalf("Adding %s: got status %d, body: %s", title, rr.Code, rr.Body.String())
+		}
+		ids = append(ids, book.ID)
+	}
+	if rr := authRequest(router, "PUT", "/api/v1/books/"+itoa(ids[1])+"/sharing", alice, `{"publish_opt_out": true}`); rr.Code != http.StatusOK {
+		t.Fatalf("Opting out: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := authRequest(router, "DELETE", "/api/v1/books/"+itoa(ids[2]), alice, ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("Deleting a book: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	for _, path := range []string{"/api/v1/feed.json", "/api/v1/users/alice/feed.json"} {
+		rr := authRequest(router, "GET", path, "", "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Getting %s: got status %d, body: %s", path, rr.Code, rr.Body.String())
+		}
+		body := rr.Body.String()
+		if !strings.Contains(body, "Shared Book") || strings.Contains(body, "Private Book") || strings.Contains(body, "Trashed Book") {
+			t.Errorf("%s: expected only the shared book, got %s", path, body)
+		}
+	}
+	if rr := authRequest(router, "GET", "/api/v1/timeline", alice, ""); !strings.Contains(rr.Body.String(), "Private Book") {
+		t.Errorf("Expected alice's own timeline to keep the opted-out book, got %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// bookFields is the set of JSON field names a client may request with ?fields=.
+var bookFields = jsonFieldNames(reflect.TypeOf(BookResponse{}))
+
+// jsonFieldNames returns the JSON names of the exported fields of a struct
+// type, including those promoted from embedded structs.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			for name := range jsonFieldNames(f.Type) {
+				names[name] = true
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// parseFields reads a sparse fieldset such as ?fields=id,title,status.
+// It returns nil when the parameter is absent, meaning every field is wanted.
+// The id is always included so clients can still address what they receive.
+func parseFields(r *http.Request, allowed map[string]bool) ([]string, error) {
+	raw := r.URL.Query().Get("fields")
+	if raw == "" {
+		return nil, nil
+	}
+	fields := []string{"id"}
+	seen := map[string]bool{"id": true}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if !allowed[name] {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		seen[name] = true
+		fields = append(fields, name)
+	}
+	return fields, nil
+}
+
+// selectFields reduces v to the requested fields. Fields that are empty and
+// marked omitempty stay omitted, exactly as in the full representation.
+func selectFields(v interface{}, fields []string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	out := make(map[string]json.RawMessage, len(fields))
+	for _, name := range fields {
+		if value, ok := all[name]; ok {
+			out[name] = value
+		}
+	}
+	return out, nil
+}
+
+// shapeBooks applies a sparse fieldset to a list of books. A nil fieldset
+// returns the books unchanged.
+func shapeBooks(books []BookResponse, fields []string) (interface{}, error) {
+	if fields == nil {
+		return books, nil
+	}
+	shaped := make([]map[string]json.RawMessage, 0, len(books))
+	for i := range books {
+		b, err := selectFields(&books[i], fields)
+		if err != nil {
+			return nil, err
+		}
+		shaped = append(shaped, b)
+	}
+	return shaped, nil
+}
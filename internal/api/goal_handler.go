@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// GetReadingGoalsHandler handles GET /api/goals requests, listing every
+// reading goal with its progress, latest year first.
+func (h *APIHandler) GetReadingGoalsHandler(w http.ResponseWriter, r *http.Request) {
+	goals, err := h.Store.GetReadingGoals(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve reading goals: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, goals)
+}
+
+// GetReadingGoalHandler handles GET /api/goals/{year} requests. It returns
+// the year's goal with the books finished so far and the pace to meet it.
+func (h *APIHandler) GetReadingGoalHandler(w http.ResponseWriter, r *http.Request) {
+	year, err := strconv.Atoi(mux.Vars(r)["year"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid year")
+		return
+	}
+	progress, err := h.Store.GetReadingGoal(r.Context(), year)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve reading goal")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, progress)
+}
+
+// SetReadingGoalHandler handles PUT /api/goals/{year} requests. Expects
+// {"target": 52}, the number of books to finish in the year, and returns the
+// goal's progress.
+func (h *APIHandler) SetReadingGoalHandler(w http.ResponseWriter, r *http.Request) {
+	year, err := strconv.Atoi(mux.Vars(r)["year"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid year")
+		return
+	}
+	var payload struct {
+		Target int `json:"target"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	goal := model.ReadingGoal{Year: year, Target: payload.Target}
+	if err := h.Store.SetReadingGoal(r.Context(), &goal); err != nil {
+		respondWithStoreError(w, err, "Failed to set reading goal")
+		return
+	}
+	progress, err := h.Store.GetReadingGoal(r.Context(), year)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve reading goal: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, progress)
+}
+
+// DeleteReadingGoalHandler handles DELETE /api/goals/{year} requests.
+func (h *APIHandler) DeleteReadingGoalHandler(w http.ResponseWriter, r *http.Request) {
+	year, err := strconv.Atoi(mux.Vars(r)["year"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid year")
+		return
+	}
+	if err := h.Store.DeleteReadingGoal(r.Context(), year); err != nil {
+		respondWithStoreError(w, err, "Failed to delete reading goal")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestReadingGoalHandlers tests setting a reading goal and following its
+// progress
+func TestReadingGoalHandlers(t *testing.T) {
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	book := createTestBook(model.StatusRead, "Goal")
+	finished := time.Date(1986, 3, 1, 0, 0, 0, 0, time.UTC)
+	book.DateFinished = &finished
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	rr := do("PUT", "/api/goals/1986", `{"target":12}`)
+	var progress model.GoalProgress
+	json.Unmarshal(rr.Body.Bytes(), &progress)
+	if rr.Code != http.StatusOK || progress.Year != 1986 || progress.Target != 12 || progress.Finished != 1 || progress.OnTrack || progress.Behind != 11 {
+		t.Fatalf("Setting a goal: got %d: %s", rr.Code, rr.Body.String())
+	}
+	defer testStore.DeleteReadingGoal(context.Background(), 1986)
+	for _, body := range []string{`{"target":0}`, `{"target":"twelve"}`, `{"target":12,"books":3}`} {
+		if rr := do("PUT", "/api/goals/1986", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want %d", body, rr.Code, http.StatusBadRequest)
+		}
+	}
+
+	if rr := do("GET", "/api/goals/1986", ""); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"finished":1`) {
+		t.Errorf("Getting a goal: got %d: %s", rr.Code, rr.Body.String())
+	}
+	var goals []model.GoalProgress
+	json.Unmarshal(do("GET", "/api/goals", "").Body.Bytes(), &goals)
+	if len(goals) != 1 || goals[0].Year != 1986 {
+		t.Errorf("Expected the goal in the list, got %+v", goals)
+	}
+	if rr := do("GET", "/api/goals/1985", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Missing goal: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	if rr := do("DELETE", "/api/goals/1986", ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Deleting a goal: got status %d, want %d", rr.Code, http.StatusNoContent)
+	}
+}
@@ -1,46 +1,201 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
-	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/ericdahl/bookshelf/internal/activitypub"
+	"github.com/ericdahl/bookshelf/internal/backfill"
+	"github.com/ericdahl/bookshelf/internal/backup"
+	"github.com/ericdahl/bookshelf/internal/covers"
+	"github.com/ericdahl/bookshelf/internal/crosspost"
 	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/federation"
+	"github.com/ericdahl/bookshelf/internal/health"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/match"
+	"github.com/ericdahl/bookshelf/internal/metadata"
 	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/ocr"
+	"github.com/ericdahl/bookshelf/internal/series"
+	"github.com/ericdahl/bookshelf/internal/speech"
+	"github.com/ericdahl/bookshelf/internal/tracker"
+	"github.com/ericdahl/bookshelf/internal/webhook"
 )
 
 // APIHandler holds dependencies for API handlers, like the database store.
 type APIHandler struct {
 	Store      db.BookStore
-	HTTPClient *http.Client // For Open Library calls
+	HTTPClient *http.Client        // For Open Library and Google Books calls
+	Feeds      *federation.Fetcher // For followed remote feeds
+	// Metadata finds books to add, asking each provider in turn, as a
+	// metadata.Chain does.
+	Metadata metadata.Searcher
+	// ActivityPub publishes finished books to the fediverse; nil when disabled.
+	ActivityPub *activitypub.Service
+	// CrossPost posts finished books to Mastodon/Bluesky; nil when no secret key is configured.
+	CrossPost *crosspost.Service
+	Sync      *tracker.Syncer      // Hardcover/Goodreads sync
+	Covers    *covers.Repairer     // Bulk cover repair job
+	Series    *series.Suggester    // Bulk series suggestion job
+	Backfill  *backfill.Backfiller // Bulk metadata backfill job
+	// Backups backs up the database on a schedule; nil when no backup schedule is configured.
+	Backups *backup.Scheduler
+	// CoverCache stores deduplicated local copies of covers; nil when no cache directory is configured.
+	CoverCache *covers.Cache
+	// MatchThresholds tune how imports are reconciled with existing books.
+	MatchThresholds match.Thresholds
+	// Health records the latency and errors of outbound requests per provider.
+	Health *health.Monitor
+	// OCR reads photos of covers to add books from; nil when no engine is configured.
+	OCR ocr.Engine
+	// Vision finds the spines on photos of shelves; nil when no vision service is configured.
+	Vision ocr.Segmenter
+	// Webhooks sends book events to users' webhooks; nil when no secret key is configured.
+	Webhooks *webhook.Dispatcher
+	// STT transcribes spoken quick adds; nil when no speech-to-text provider is configured.
+	STT speech.Transcriber
+	// PasskeyOrigin is the origin of the web UI passkeys are created for, such
+	// as "https://books.example.com"; when empty, the origin each request was sent to.
+	PasskeyOrigin string
+	// SingleUser logs in with one configured password instead of users'
+	// accounts; nil unless single-user mode is enabled.
+	SingleUser *SingleUser
+	// ProxyAuth logs users in by the identity a trusted reverse proxy sends;
+	// nil unless a proxy header is configured.
+	ProxyAuth *ProxyAuth
+	// AdminAccess restricts the admin routes to some networks or a listener
+	// of their own; nil when they are served like the others.
+	AdminAccess *AdminAccess
+	// Admins are the usernames of the users who may use the admin routes;
+	// when empty, the first user registered is the admin.
+	Admins []string
+	// RateLimits limits how often each client changes things and searches;
+	// nil for no limits.
+	RateLimits *RateLimits
+	// Logging changes the log level and format while the server runs; nil
+	// when they can't be changed.
+	Logging *logging.Controller
+	// SlowQueries keeps the latest queries that took at least its threshold.
+	SlowQueries *db.SlowQueryLog
 }
 
 // NewAPIHandler creates a new APIHandler with dependencies.
 func NewAPIHandler(store db.BookStore) *APIHandler {
-	return &APIHandler{
+	h := &APIHandler{
 		Store: store,
 		HTTPClient: &http.Client{
 			Timeout: 10 * time.Second, // Sensible timeout for external API calls
 		},
-	}
+		Feeds:           federation.NewFetcher(store),
+		Sync:            tracker.NewSyncer(store, nil),
+		Covers:          covers.NewRepairer(store),
+		Series:          series.NewSuggester(store),
+		MatchThresholds: match.DefaultThresholds,
+		Health:          health.NewMonitor(),
+		SlowQueries:     db.SlowQueries,
+	}
+	h.Metadata = metadata.Chain{metadata.NewOpenLibrary(h.HTTPClient)}
+	h.Backfill = backfill.NewBackfiller(store, h.Metadata)
+	h.Health.Instrument(h.HTTPClient, "openlibrary")
+	h.Health.Instrument(h.Feeds.HTTPClient, "feeds")
+	h.Health.Instrument(h.Sync.HTTPClient, "trackers")
+	h.Health.Instrument(h.Covers.HTTPClient, "covers")
+	h.Health.Instrument(h.Series.HTTPClient, "openlibrary")
+	return h
 }
 
 // --- Helper Functions ---
 
+// optionalString returns a pointer to s, or nil when s is empty.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// estimateFinish sets the estimated finish date of each response whose book
+// is being read, at the pace of recent reads. The estimate is a convenience,
+// so when the pace can't be measured it is left out rather than failing the
+// request. resps[i] must be the response for books[i].
+func (h *APIHandler) estimateFinish(ctx context.Context, books []model.Book, resps []BookResponse) {
+	reading := false
+	for i := range books {
+		reading = reading || books[i].Status == model.StatusCurrentlyReading && books[i].PageCount != nil && books[i].DateStarted != nil
+	}
+	if !reading {
+		return
+	}
+	pace, err := h.Store.GetReadingPace(ctx)
+	if err != nil {
+		slog.Warn("Could not measure reading pace", "error", err)
+		return
+	}
+	now := time.Now()
+	for i := range books {
+		resps[i].EstimatedFinishDate = pace.EstimateFinish(&books[i], now)
+	}
+}
+
 // respondWithError sends a JSON error response.
 func respondWithError(w http.ResponseWriter, code int, message string) {
 	slog.Error("HTTP Error", "code", code, "message", message)
 	respondWithJSON(w, code, map[string]string{"error": message})
 }
 
+// duplicateBookResponse is the 409 for a book already on the bookshelf, with
+// a pointer to the existing record, or for one in the trash, with the URL
+// that restores it.
+type duplicateBookResponse struct {
+	Error          string `json:"error"`
+	ExistingBookID int64  `json:"existing_book_id"`
+	ExistingBook   string `json:"existing_book,omitempty"` // URL of the existing book
+	Restore        string `json:"restore,omitempty"`       // URL to POST to restore the book from the trash
+}
+
+// respondWithStoreError sends the error status matching a store error: 404 for
+// missing records, 409 for unique key conflicts, 400 for rejected values and
+// 422 for references to missing records. Anything else is a 500 prefixed with
+// message. A book already on the bookshelf gets a 409 pointing at it, and one
+// in the trash a 409 pointing at its restore URL.
+func respondWithStoreError(w http.ResponseWriter, err error, message string) {
+	var dup *db.DuplicateBookError
+	switch {
+	case errors.As(err, &dup):
+		slog.Error("HTTP Error", "code", http.StatusConflict, "message", err.Error())
+		if dup.Trashed {
+			url := "/api/" + APIVersion + "/trash/" + strconv.FormatInt(dup.ExistingID, 10) + "/restore"
+			respondWithJSON(w, http.StatusConflict, duplicateBookResponse{Error: err.Error(), ExistingBookID: dup.ExistingID, Restore: url})
+			return
+		}
+		url := "/api/" + APIVersion + "/books/" + strconv.FormatInt(dup.ExistingID, 10)
+		w.Header().Set("Location", url)
+		respondWithJSON(w, http.StatusConflict, duplicateBookResponse{Error: err.Error(), ExistingBookID: dup.ExistingID, ExistingBook: url})
+	case errors.Is(err, db.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, db.ErrDuplicate), errors.Is(err, db.ErrConflict):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, db.ErrValidation):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, db.ErrForeignKey):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, db.ErrUnsupported):
+		respondWithError(w, http.StatusNotImplemented, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, message+": "+err.Error())
+	}
+}
+
 // respondWithJSON sends a JSON response.
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, err := json.Marshal(payload)
@@ -61,8 +216,35 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 // --- Book Handlers ---
 
 // GetBooksHandler handles GET /api/books requests.
+// An optional ?fields=id,title,... limits each book to the listed fields.
+// Archived books are left out unless ?archived=true (only them) or ?archived=all.
+// Filters (?status=read&type=audiobook&author=&min_rating=8&reread=true), paging
+// (?limit=&offset=) and ordering (?sort=title|author|rating|added&order=asc|desc)
+// are optional too and are applied in SQL; when used, the number of matching
+// books is returned in X-Total-Count and the neighbouring pages in a Link header.
 func (h *APIHandler) GetBooksHandler(w http.ResponseWriter, r *http.Request) {
-	books, err := h.Store.GetBooks()
+	fields, err := parseFields(r, bookFields)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts, paged, err := parseListOptions(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var books []model.Book
+	if paged {
+		var total int
+		books, total, err = h.Store.GetBooksPage(r.Context(), opts)
+		if err == nil {
+			setPageHeaders(w, r, opts, total)
+		}
+	} else {
+		books, err = h.Store.GetBooks(r.Context())
+		books = unarchived(books)
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve books: "+err.Error())
 		return
@@ -70,13 +252,68 @@ func (h *APIHandler) GetBooksHandler(w http.ResponseWriter, r *http.Request) {
 	if books == nil {
 		books = []model.Book{} // Return empty array instead of null
 	}
-	respondWithJSON(w, http.StatusOK, books)
+	resps := newBookResponses(books)
+	h.estimateFinish(r.Context(), books, resps)
+	payload, err := shapeBooks(resps, fields)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to shape books: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, payload)
+}
+
+// unarchived leaves the archived books out of books. GetBooks returns every
+// book, for the exports and background jobs that need them all, while lists
+// only show archived books when asked for with ?archived=.
+func unarchived(books []model.Book) []model.Book {
+	kept := books[:0]
+	for _, book := range books {
+		if !book.Archived {
+			kept = append(kept, book)
+		}
+	}
+	return kept
+}
+
+// GetBookHandler handles GET /api/books/{id} requests.
+// Like the list endpoint, it accepts an optional ?fields= sparse fieldset.
+func (h *APIHandler) GetBookHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+	fields, err := parseFields(r, bookFields)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	book, err := h.Store.GetBookByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve book")
+		return
+	}
+	resps := []BookResponse{newBookResponse(book)}
+	h.estimateFinish(r.Context(), []model.Book{*book}, resps)
+	resp := resps[0]
+	if fields == nil {
+		respondWithJSON(w, http.StatusOK, resp)
+		return
+	}
+	shaped, err := selectFields(&resp, fields)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to shape book: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, shaped)
 }
 
 // AddBookHandler handles POST /api/books requests.
 // Expects JSON body based on Open Library search result selection.
 func (h *APIHandler) AddBookHandler(w http.ResponseWriter, r *http.Request) {
-	var book model.Book
+	var payload BookRequest
 
 	// Limit request body size to prevent potential abuse
 	r.Body = http.MaxBytesReader(w, r.Body, 1*1024*1024) // 1 MB limit
@@ -84,7 +321,7 @@ func (h *APIHandler) AddBookHandler(w http.ResponseWriter, r *http.Request) {
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields() // Prevent unexpected fields
 
-	if err := decoder.Decode(&book); err != nil {
+	if err := decoder.Decode(&payload); err != nil {
 		var syntaxError *json.SyntaxError
 		var unmarshalTypeError *json.UnmarshalTypeError
 		var maxBytesError *http.MaxBytesError
@@ -115,10 +352,105 @@ func (h *APIHandler) AddBookHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	book, msg := newBookFromRequest(&payload)
+	if msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	// Add the book to the database
+	newID, err := h.Store.AddBook(r.Context(), &book)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to add book to database")
+		return
+	}
+
+	book.ID = newID // Ensure the returned book has the ID
+	h.cacheCover(book)
+	respondWithJSON(w, http.StatusCreated, newBookResponse(&book))
+}
+
+// maxBatchBooks caps the number of books in one POST /api/books/batch request.
+const maxBatchBooks = 1000
+
+// BatchAddBooksHandler handles POST /api/books/batch requests. Expects a JSON
+// array of books as for POST /api/books. The books are added in one
+// transaction, so if any of them is rejected none is added. They form an
+// import batch, which can be rolled back through /api/imports. With
+// ?dry_run=true nothing is added, and every rejected book is reported.
+func (h *APIHandler) BatchAddBooksHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1*1024*1024) // 1 MB limit, like request validation
+	var payload []BookRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if len(payload) == 0 || len(payload) > maxBatchBooks {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("A batch must contain between 1 and %d books", maxBatchBooks))
+		return
+	}
+
+	r, dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
+
+	books := make([]model.Book, len(payload))
+	batch := make([]*model.Book, len(payload))
+	var errs []FieldError
+	for i := range payload {
+		book, msg := newBookFromRequest(&payload[i])
+		if msg != "" && !dryRun {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Book %d: %s", i+1, msg))
+			return
+		}
+		books[i] = book
+		batch[i] = &books[i]
+		switch {
+		case msg != "":
+			errs = append(errs, FieldError{"body", strconv.Itoa(i), msg})
+		case dryRun:
+			// Each book is tried on its own too, so every rejected book is reported
+			if _, err := h.Store.AddBook(r.Context(), batch[i]); err != nil {
+				errs = append(errs, FieldError{"body", strconv.Itoa(i), err.Error()})
+			}
+		}
+	}
+	// The batch is named for its books' source when they share one
+	imported := &model.ImportBatch{Source: books[0].Source}
+	for _, book := range books {
+		if book.Source != imported.Source {
+			imported.Source = model.BookSourceAPI
+		}
+	}
+	if dryRun {
+		var err error
+		if errs == nil {
+			err = h.Store.ImportBooks(r.Context(), imported, batch)
+		}
+		respondWithDryRun(w, newBookResponses(books), errs, err, "Failed to add books to database")
+		return
+	}
+	if err := h.Store.ImportBooks(r.Context(), imported, batch); err != nil {
+		respondWithStoreError(w, err, "Failed to add books to database")
+		return
+	}
+	for _, book := range books {
+		h.cacheCover(book)
+	}
+	respondWithJSON(w, http.StatusCreated, newBookResponses(books))
+}
+
+// newBookFromRequest converts the body of an add request to a book, filling
+// in defaults. It returns a message for the client if the book is invalid.
+func newBookFromRequest(payload *BookRequest) (model.Book, string) {
+	book := payload.toModel()
+
 	// Basic validation for required fields from search result
 	if book.Title == "" || book.OpenLibraryID == "" {
-		respondWithError(w, http.StatusBadRequest, "Missing required fields: title and open_library_id")
-		return
+		return book, "Missing required fields: title and open_library_id"
 	}
 	// Author is highly recommended but might be missing in some OL entries
 	if book.Author == "" {
@@ -134,32 +466,19 @@ func (h *APIHandler) AddBookHandler(w http.ResponseWriter, r *http.Request) {
 		// Let's stick to "Want to Read" as a safer default.
 		book.Status = model.StatusWantToRead
 	}
-
-	// Ensure Rating and Comments are initially null unless explicitly provided (unlikely for Add)
-	book.Rating = nil
-	book.Comments = nil
+	if book.Source == "" {
+		book.Source = model.BookSourceAPI
+	}
 
 	// Validate the model (e.g., rating range, although unlikely here)
 	if err := book.Validate(); err != nil {
 		var validationErr *model.ValidationError
 		if errors.As(err, &validationErr) {
-			respondWithError(w, http.StatusBadRequest, validationErr.Message)
-		} else {
-			respondWithError(w, http.StatusBadRequest, "Invalid book data: "+err.Error())
+			return book, validationErr.Message
 		}
-		return
-	}
-
-	// Add the book to the database
-	newID, err := h.Store.AddBook(&book)
-	if err != nil {
-		// TODO: Check for specific DB errors like UNIQUE constraint violation
-		respondWithError(w, http.StatusInternalServerError, "Failed to add book to database: "+err.Error())
-		return
+		return book, "Invalid book data: " + err.Error()
 	}
-
-	book.ID = newID // Ensure the returned book has the ID
-	respondWithJSON(w, http.StatusCreated, book)
+	return book, ""
 }
 
 // UpdateBookStatusHandler handles PUT /api/books/{id} requests (for status update).
@@ -193,20 +512,60 @@ func (h *APIHandler) UpdateBookStatusHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = h.Store.UpdateBookStatus(id, payload.Status)
+	err = h.Store.UpdateBookStatus(r.Context(), id, payload.Status)
 	if err != nil {
-		// TODO: Differentiate between Not Found (404) and other errors (500)
-		if strings.Contains(err.Error(), "not found") { // Basic check, better to use custom errors
-			respondWithError(w, http.StatusNotFound, err.Error())
-		} else {
-			respondWithError(w, http.StatusInternalServerError, "Failed to update book status: "+err.Error())
-		}
+		respondWithStoreError(w, err, "Failed to update book status")
 		return
 	}
 
+	if payload.Status == model.StatusRead {
+		h.announceFinishedBook(r.Context(), id)
+	}
+
 	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Book status updated successfully"})
 }
 
+// PatchBookHandler handles PATCH /api/books/{id} requests. Only the fields in
+// the body are changed and null clears a field, so any combination of fields
+// can be edited at once. Responds with the updated book.
+func (h *APIHandler) PatchBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+
+	var patch model.BookPatch
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	before, err := h.Store.GetBookByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve book")
+		return
+	}
+	if err := h.Store.UpdateBook(r.Context(), id, patch); err != nil {
+		respondWithStoreError(w, err, "Failed to update book")
+		return
+	}
+	book, err := h.Store.GetBookByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve updated book")
+		return
+	}
+
+	if book.Status == model.StatusRead && before.Status != model.StatusRead {
+		h.announceFinishedBook(r.Context(), id)
+	}
+	resps := []BookResponse{newBookResponse(book)}
+	h.estimateFinish(r.Context(), []model.Book{*book}, resps)
+	respondWithJSON(w, http.StatusOK, resps[0])
+}
+
 // UpdateBookTypeHandler handles PUT /api/books/{id}/type requests (for book type update).
 func (h *APIHandler) UpdateBookTypeHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -237,19 +596,50 @@ func (h *APIHandler) UpdateBookTypeHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = h.Store.UpdateBookType(id, payload.Type)
+	err = h.Store.UpdateBook(r.Context(), id, model.BookPatch{Type: model.Some(payload.Type)})
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			respondWithError(w, http.StatusNotFound, err.Error())
-		} else {
-			respondWithError(w, http.StatusInternalServerError, "Failed to update book type: "+err.Error())
-		}
+		respondWithStoreError(w, err, "Failed to update book type")
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Book type updated successfully"})
 }
 
+// UpdateBookFavoriteHandler handles PUT /api/books/{id}/favorite requests,
+// which pin a book as a favorite or unpin it, as {"favorite": true}.
+func (h *APIHandler) UpdateBookFavoriteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+
+	var payload struct {
+		Favorite *bool `json:"favorite"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if payload.Favorite == nil {
+		respondWithError(w, http.StatusBadRequest, "favorite is required")
+		return
+	}
+
+	if err := h.Store.UpdateBook(r.Context(), id, model.BookPatch{Favorite: model.Some(*payload.Favorite)}); err != nil {
+		respondWithStoreError(w, err, "Failed to update book favorite")
+		return
+	}
+	book, err := h.Store.GetBookByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to get book")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newBookResponse(book))
+}
+
 // UpdateBookDetailsHandler handles PUT /api/books/{id}/details requests (for rating, comments, and series info).
 func (h *APIHandler) UpdateBookDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -264,12 +654,12 @@ func (h *APIHandler) UpdateBookDetailsHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Use pointers in the payload struct to detect if a field was provided (even if null)
+	// Only the fields in the body are changed; null clears a field
 	var payload struct {
-		Rating      *int    `json:"rating"`        // Pointer allows distinguishing between 0 and not provided/null
-		Comments    *string `json:"comments"`      // Pointer allows distinguishing between "" and not provided/null
-		Series      *string `json:"series"`        // Name of series
-		SeriesIndex *int    `json:"series_index"` // Position in series
+		Rating      model.Optional[int]    `json:"rating"`
+		Comments    model.Optional[string] `json:"comments"`
+		Series      model.Optional[string] `json:"series"`       // Name of series
+		SeriesIndex model.Optional[int]    `json:"series_index"` // Position in series
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -283,70 +673,91 @@ func (h *APIHandler) UpdateBookDetailsHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	// Validate rating if provided
-	if payload.Rating != nil && (*payload.Rating < 1 || *payload.Rating > 10) {
+	if rating := payload.Rating.Value; rating != nil && (*rating < 1 || *rating > 10) {
 		respondWithError(w, http.StatusBadRequest, "Rating must be between 1 and 10")
 		return
 	}
 	
 	// Validate series index if provided
-	if payload.SeriesIndex != nil && *payload.SeriesIndex <= 0 {
+	if index := payload.SeriesIndex.Value; index != nil && *index <= 0 {
 		respondWithError(w, http.StatusBadRequest, "Series index must be greater than 0")
 		return
 	}
 	
-	// If series is null/empty but series_index is provided, return error
-	if (payload.Series == nil || *payload.Series == "") && payload.SeriesIndex != nil {
-		respondWithError(w, http.StatusBadRequest, "Cannot provide series_index without series name")
+	// Clearing the series also clears the position in it
+	patch := model.BookPatch{
+		Rating:      payload.Rating,
+		Comments:    payload.Comments,
+		Series:      payload.Series,
+		SeriesIndex: payload.SeriesIndex,
+	}
+	err = h.Store.UpdateBook(r.Context(), id, patch)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to update book details")
 		return
 	}
 
-	// If only some fields are provided, get existing book to preserve other fields
-	var existingBook *model.Book
-	if payload.Rating != nil && payload.Comments == nil || 
-	   payload.Rating == nil && payload.Comments != nil ||
-	   payload.Series != nil && (payload.SeriesIndex == nil && !(*payload.Series == "")) {
-		var err error
-		existingBook, err = h.Store.GetBookByID(id)
-		if err != nil {
-			if strings.Contains(err.Error(), "not found") {
-				respondWithError(w, http.StatusNotFound, err.Error())
-			} else {
-				respondWithError(w, http.StatusInternalServerError, "Failed to retrieve book: "+err.Error())
-			}
-			return
-		}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Book details updated successfully"})
+}
+
+// UpdateBookStudyHandler handles PUT /api/books/{id}/study requests (for edition, course code, semester and reading mode).
+// All fields are replaced; omitted fields are cleared and reading_mode defaults to "leisure".
+func (h *APIHandler) UpdateBookStudyHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr, ok := vars["id"]
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Missing book ID")
+		return
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
 	}
 
-	// Preserve existing values if not provided in the update
-	if payload.Rating == nil && existingBook != nil {
-		payload.Rating = existingBook.Rating
+	var payload model.StudyInfo
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
 	}
-	if payload.Comments == nil && existingBook != nil {
-		payload.Comments = existingBook.Comments
+
+	if payload.ReadingMode == "" {
+		payload.ReadingMode = model.ModeLeisure
+	} else if !payload.ReadingMode.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid reading_mode value. Must be 'leisure' or 'reference'")
+		return
+	}
+	if payload.Edition != nil && *payload.Edition <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Edition must be greater than 0")
+		return
 	}
-	if payload.Series == nil && existingBook != nil {
-		payload.Series = existingBook.Series
+	// Treat empty strings as a request to clear the field
+	if payload.CourseCode != nil && *payload.CourseCode == "" {
+		payload.CourseCode = nil
 	}
-	if payload.SeriesIndex == nil && existingBook != nil {
-		payload.SeriesIndex = existingBook.SeriesIndex
+	if payload.Semester != nil && *payload.Semester == "" {
+		payload.Semester = nil
 	}
 
-	// Perform the update
-	err = h.Store.UpdateBookDetails(id, payload.Rating, payload.Comments, payload.Series, payload.SeriesIndex)
+	err = h.Store.UpdateBook(r.Context(), id, model.BookPatch{
+		Edition:     model.Of(payload.Edition),
+		CourseCode:  model.Of(payload.CourseCode),
+		Semester:    model.Of(payload.Semester),
+		ReadingMode: model.Some(payload.ReadingMode),
+	})
 	if err != nil {
-		// Differentiate between Not Found (404) and other errors (500)
-		if strings.Contains(err.Error(), "not found") { // Basic check
-			respondWithError(w, http.StatusNotFound, err.Error())
-		} else {
-			respondWithError(w, http.StatusInternalServerError, "Failed to update book details: "+err.Error())
-		}
+		respondWithStoreError(w, err, "Failed to update book study info")
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Book details updated successfully"})
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Book study info updated successfully"})
 }
 
-// DeleteBookHandler handles the deletion of a book
+// DeleteBookHandler handles the deletion of a book, which moves it to the
+// trash; see GetTrashHandler.
 func (h *APIHandler) DeleteBookHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.ParseInt(vars["id"], 10, 64)
@@ -355,51 +766,93 @@ func (h *APIHandler) DeleteBookHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.Store.DeleteBook(id)
+	err = h.Store.DeleteBook(r.Context(), id)
 	if err != nil {
-		slog.Error("Error deleting book", "error", err, "id", id)
-		// Check if book not found
-		if strings.Contains(err.Error(), "not found") {
-			respondWithError(w, http.StatusNotFound, err.Error())
-		} else {
-			respondWithError(w, http.StatusInternalServerError, "Failed to delete book")
-		}
+		slog.ErrorContext(r.Context(), "Error deleting book", "error", err, "id", id)
+		respondWithStoreError(w, err, "Failed to delete book")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// --- Open Library Search Handler ---
+// --- Book Search Handler ---
 
 // OpenLibrarySearchResult defines the structure we want to return from our search endpoint.
 type OpenLibrarySearchResult struct {
-	OpenLibraryID string  `json:"open_library_id"` // e.g., OL7353617M
+	OpenLibraryID string  `json:"open_library_id"` // e.g., OL7353617M, or gbooks:<volume> for Google Books
 	Title         string  `json:"title"`
+	Subtitle      *string `json:"subtitle,omitempty"`
 	Author        string  `json:"author"`              // Combined author names
 	ISBN          *string `json:"isbn,omitempty"`      // First available ISBN-13 or ISBN-10
 	CoverURL      *string `json:"cover_url,omitempty"` // URL for medium cover
+	PublishYear   *int    `json:"publish_year,omitempty"`
+	PageCount     *int    `json:"page_count,omitempty"` // Of the edition looked up by ISBN, otherwise the median over the work's editions
+	Publisher     *string `json:"publisher,omitempty"`  // Of the edition; only known for ISBN lookups and Google Books
+	Language      *string `json:"language,omitempty"`   // e.g. "en" or "eng"; see Publisher
 	// Fields to identify if book already exists in library
 	ExistingID    *int64       `json:"existing_id,omitempty"`    // ID if book already in library
 	ExistingShelf *string      `json:"existing_shelf,omitempty"` // Shelf name if already in library
+	// Provider is the metadata provider the result came from; empty for books in the library
+	Provider string `json:"provider,omitempty"`
+}
+
+// newProviderResult describes a book a metadata provider found.
+func newProviderResult(book metadata.Book) OpenLibrarySearchResult {
+	result := OpenLibrarySearchResult{
+		OpenLibraryID: book.ID,
+		Title:         book.Title,
+		Author:        strings.Join(book.Authors, ", "), // Combine authors
+		Subtitle:      optionalString(book.Subtitle),
+		ISBN:          optionalString(book.ISBN),
+		CoverURL:      optionalString(book.CoverURL),
+		Publisher:     optionalString(book.Publisher),
+		Language:      optionalString(book.Language),
+		Provider:      book.Provider,
+	}
+	if book.PublishYear > 0 {
+		year := book.PublishYear
+		result.PublishYear = &year
+	}
+	if book.PageCount > 0 {
+		pages := book.PageCount
+		result.PageCount = &pages
+	}
+	return result
 }
 
-// openLibrarySearchResponse is the structure matching the Open Library Search API JSON response.
-// We only map the fields we need. See: https://openlibrary.org/dev/docs/api/search
-type openLibrarySearchResponse struct {
-	NumFound int `json:"numFound"`
-	Docs     []struct {
-		Key              string   `json:"key"` // e.g., "/works/OL7353617M"
-		Title            string   `json:"title"`
-		AuthorName       []string `json:"author_name"` // Array of author names
-		ISBN             []string `json:"isbn"`        // Array of ISBNs (10 and 13)
-		CoverI           int      `json:"cover_i"`     // Cover ID (integer)
-		AuthorKey        []string `json:"author_key"`  // Array of author IDs
-		FirstPublishYear int      `json:"first_publish_year"`
-	} `json:"docs"`
+// newBookFromProvider returns a book filled in from a metadata provider's
+// result, for the handlers that add books in one call. Its shelf is left to
+// the caller.
+func newBookFromProvider(result metadata.Book) model.Book {
+	book := model.Book{
+		Title:         result.Title,
+		Subtitle:      optionalString(result.Subtitle),
+		Author:        strings.Join(result.Authors, ", "),
+		OpenLibraryID: result.ID,
+		ISBN:          result.ISBN,
+		CoverURL:      optionalString(result.CoverURL),
+		Publisher:     optionalString(result.Publisher),
+		Language:      optionalString(result.Language),
+	}
+	if book.Author == "" {
+		book.Author = "Unknown Author"
+	}
+	if result.PublishYear > 0 {
+		year := result.PublishYear
+		book.PublishYear = &year
+	}
+	if result.PageCount > 0 {
+		pages := result.PageCount
+		book.PageCount = &pages
+	}
+	return book
 }
 
-// SearchBooksHandler handles GET /api/search?q={query}
+// SearchBooksHandler handles GET /api/books/search?q={query}. Matching books
+// already in the library come first, followed by results of the first
+// metadata provider to find any. A query that is an ISBN is looked up as one.
+// With scope=library only the library is searched and full books are returned.
 func (h *APIHandler) SearchBooksHandler(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 	if query == "" {
@@ -407,51 +860,26 @@ func (h *APIHandler) SearchBooksHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Construct Open Library API URL
-	// Using the works search endpoint as it often has better consolidated data
-	apiURL := fmt.Sprintf("https://openlibrary.org/search.json?q=%s&fields=key,title,author_name,isbn,cover_i,author_key,first_publish_year&limit=20", url.QueryEscape(query))
-	slog.Info("Querying Open Library", "url", apiURL)
-
-	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, apiURL, nil)
+	// Search the library first. The full-text index matches words anywhere in
+	// the title, author or comments and tolerates small typos.
+	matches, err := h.Store.SearchBooks(r.Context(), query)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to create Open Library request: "+err.Error())
+		respondWithError(w, http.StatusInternalServerError, "Failed to search library: "+err.Error())
 		return
 	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("User-Agent", "BookshelfApp/1.0 (github.com/ericdahl/bookshelf; contact@example.com)") // Be a good API citizen
-
-	start := time.Now()
-	resp, err := h.HTTPClient.Do(req)
-	elapsed := time.Since(start)
-	if resp != nil {
-		slog.Info("OpenLibrary API response", 
-			"url", apiURL, 
-			"status", resp.StatusCode, 
-			"responseTime", elapsed)
-	}
-
-	if err != nil {
-		respondWithError(w, http.StatusBadGateway, "Failed to contact Open Library API: "+err.Error())
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body) // Read body for context, ignore error
-		errMsg := fmt.Sprintf("Open Library API returned status %d: %s", resp.StatusCode, string(bodyBytes))
-		respondWithError(w, http.StatusBadGateway, errMsg)
+	if r.URL.Query().Get("scope") == "library" {
+		respondWithJSON(w, http.StatusOK, newBookResponses(matches))
 		return
 	}
 
-	// Decode the response
-	var olResponse openLibrarySearchResponse
-	if err := json.NewDecoder(resp.Body).Decode(&olResponse); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to decode Open Library response: "+err.Error())
+	found, err := h.Metadata.Search(r.Context(), query)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, err.Error())
 		return
 	}
 
 	// Get all existing books and create a map for quick lookup
-	existingBooks, err := h.Store.GetBooks()
+	existingBooks, err := h.Store.GetBooks(r.Context())
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve existing books: "+err.Error())
 		return
@@ -463,90 +891,41 @@ func (h *APIHandler) SearchBooksHandler(w http.ResponseWriter, r *http.Request)
 		existingBooksMap[book.OpenLibraryID] = book
 	}
 
-	// Also check for books in the local database matching the search query
-	booksInDB, err := h.Store.GetBooks()
-	if err != nil {
-		slog.Error("Error retrieving books from database for search", "error", err)
-		// Continue with API results only
-	} else {
-		// Filter books that match the search query in title or author
-		queryLower := strings.ToLower(query)
-		for _, book := range booksInDB {
-			if strings.Contains(strings.ToLower(book.Title), queryLower) || 
-			   strings.Contains(strings.ToLower(book.Author), queryLower) {
-				// Make sure this book's OpenLibraryID is in the existingBooksMap
-				existingBooksMap[book.OpenLibraryID] = book
-			}
-		}
-	}
-
 	// Transform the results into our desired format
 	results := []OpenLibrarySearchResult{}
 
-	// First add books from database that match the search query
-	for id, book := range existingBooksMap {
-		if strings.Contains(strings.ToLower(book.Title), strings.ToLower(query)) || 
-		   strings.Contains(strings.ToLower(book.Author), strings.ToLower(query)) {
-			shelf := string(book.Status)
-			result := OpenLibrarySearchResult{
-				OpenLibraryID: id,
-				Title:         book.Title,
-				Author:        book.Author,
-				ISBN:          &book.ISBN,
-				CoverURL:      book.CoverURL,
-				ExistingID:    &book.ID,
-				ExistingShelf: &shelf,
-			}
-			results = append(results, result)
-		}
-	}
-
-	// Then add results from the API
-	for _, doc := range olResponse.Docs {
-		// Extract OpenLibrary ID from the key (e.g., "/works/OL7353617M" -> "OL7353617M")
-		parts := strings.Split(doc.Key, "/")
-		olid := ""
-		if len(parts) > 0 {
-			olid = parts[len(parts)-1]
-		}
-		if olid == "" {
-			continue // Skip if we can't get an ID
-		}
-
+	// First add books from the library that match the search query
+	listed := make(map[string]bool)
+	for _, book := range matches {
+		shelf := string(book.Status)
+		results = append(results, OpenLibrarySearchResult{
+			OpenLibraryID: book.OpenLibraryID,
+			Title:         book.Title,
+			Subtitle:      book.Subtitle,
+			Author:        book.Author,
+			ISBN:          &book.ISBN,
+			CoverURL:      book.CoverURL,
+			PublishYear:   book.PublishYear,
+			PageCount:     book.PageCount,
+			Publisher:     book.Publisher,
+			Language:      book.Language,
+			ExistingID:    &book.ID,
+			ExistingShelf: &shelf,
+		})
+		listed[book.OpenLibraryID] = true
+	}
+
+	// Then add results from the provider
+	for _, book := range found {
 		// Skip if we already added this book from the local database
-		if _, exists := existingBooksMap[olid]; exists {
+		if listed[book.ID] {
 			continue
 		}
 
-		// Find a suitable ISBN (prefer ISBN-13)
-		var isbn *string
-		for _, code := range doc.ISBN {
-			if len(code) == 13 { // Basic check for ISBN-13
-				isbn = &code
-				break
-			}
-		}
-		if isbn == nil && len(doc.ISBN) > 0 { // Fallback to first ISBN if no 13 found
-			isbn = &doc.ISBN[0]
-		}
-
-		// Construct cover URL (Medium size)
-		var coverURL *string
-		if doc.CoverI > 0 {
-			url := fmt.Sprintf("https://covers.openlibrary.org/b/id/%d-M.jpg", doc.CoverI)
-			coverURL = &url
-		}
-
-		result := OpenLibrarySearchResult{
-			OpenLibraryID: olid,
-			Title:         doc.Title,
-			Author:        strings.Join(doc.AuthorName, ", "), // Combine authors
-			ISBN:          isbn,
-			CoverURL:      coverURL,
-		}
+		result := newProviderResult(book)
 		
 		// Check if the book exists in the user's library
-		if existingBook, exists := existingBooksMap[olid]; exists {
+		if existingBook, exists := existingBooksMap[book.ID]; exists {
 			// Book exists in the library, set the ExistingID and ExistingShelf fields
 			result.ExistingID = &existingBook.ID
 			shelf := string(existingBook.Status)
@@ -2,14 +2,18 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ericdahl/bookshelf/internal/db"
 	"github.com/ericdahl/bookshelf/internal/model"
@@ -48,17 +52,176 @@ func setupTestAPI() error {
 	// Create the test store and handler
 	testStore = db.NewSQLiteBookStore(testDB)
 	testHandler = NewAPIHandler(testStore)
+	testHandler.Series.OpenLibraryURL = "" // Detect series from titles only
 
 	// Set up the router
 	testRouter = mux.NewRouter()
 	testRouter.Use(GzipMiddleware) // Add the gzip middleware for compression tests
 	testRouter.HandleFunc("/api/books", testHandler.GetBooksHandler).Methods(http.MethodGet)
 	testRouter.HandleFunc("/api/books", testHandler.AddBookHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/books", testHandler.DeleteBooksHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/books/batch", testHandler.BatchAddBooksHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/books/isbn/{isbn}", testHandler.AddBookByISBNHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/books/scan/cover", testHandler.ScanCoverHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/books/scan/shelf", testHandler.ScanShelfHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/books/quick", testHandler.QuickAddHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/books/quick/speech", testHandler.QuickAddSpeechHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}", testHandler.GetBookHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}", testHandler.PatchBookHandler).Methods(http.MethodPatch)
 	testRouter.HandleFunc("/api/books/{id:[0-9]+}", testHandler.UpdateBookStatusHandler).Methods(http.MethodPut)
 	testRouter.HandleFunc("/api/books/{id:[0-9]+}/type", testHandler.UpdateBookTypeHandler).Methods(http.MethodPut)
 	testRouter.HandleFunc("/api/books/{id:[0-9]+}/details", testHandler.UpdateBookDetailsHandler).Methods(http.MethodPut)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/study", testHandler.UpdateBookStudyHandler).Methods(http.MethodPut)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/sharing", testHandler.UpdateBookSharingHandler).Methods(http.MethodPut)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/favorite", testHandler.UpdateBookFavoriteHandler).Methods(http.MethodPut)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/progress", testHandler.GetBookProgressHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/progress", testHandler.UpdateBookProgressHandler).Methods(http.MethodPatch)
 	testRouter.HandleFunc("/api/books/{id:[0-9]+}", testHandler.DeleteBookHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/cover", testHandler.GetBookCoverHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/tags", testHandler.GetBookTagsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/tags", testHandler.AddBookTagHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/tags/{tagID:[0-9]+}", testHandler.RemoveBookTagHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/collections", testHandler.GetBookCollectionsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/reads", testHandler.GetBookReadsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/reads", testHandler.AddBookReadHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/reads/{readID:[0-9]+}", testHandler.DeleteBookReadHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/notes", testHandler.GetBookNotesHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/notes", testHandler.AddBookNoteHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/notes/{noteID:[0-9]+}", testHandler.UpdateBookNoteHandler).Methods(http.MethodPut)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/notes/{noteID:[0-9]+}", testHandler.DeleteBookNoteHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/quotes", testHandler.GetBookQuotesHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/quotes", testHandler.AddBookQuoteHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/quotes/{quoteID:[0-9]+}", testHandler.UpdateBookQuoteHandler).Methods(http.MethodPut)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/quotes/{quoteID:[0-9]+}", testHandler.DeleteBookQuoteHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/quotes/random", testHandler.GetRandomQuoteHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/loans", testHandler.GetBookLoansHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/loans", testHandler.LendBookHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/loans/return", testHandler.ReturnBookHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/value", testHandler.GetBookValueHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/loans", testHandler.GetOutstandingLoansHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/disposals", testHandler.AddBookDisposalHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/series/suggestion", testHandler.GetSeriesSuggestionHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/merge", testHandler.MergeBookHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/books/{id:[0-9]+}/history", testHandler.GetBookHistoryHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/books/duplicates", testHandler.FindDuplicatesHandler).Methods(http.MethodGet)
 	testRouter.HandleFunc("/api/books/search", testHandler.SearchBooksHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/tags", testHandler.GetTagsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/tags/{id:[0-9]+}", testHandler.RenameTagHandler).Methods(http.MethodPut)
+	testRouter.HandleFunc("/api/tags/{id:[0-9]+}", testHandler.DeleteTagHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/authors", testHandler.GetAuthorsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/authors", testHandler.SetAuthorHandler).Methods(http.MethodPut)
+	testRouter.HandleFunc("/api/authors/{id:[0-9]+}", testHandler.GetAuthorHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/authors/{id:[0-9]+}", testHandler.DeleteAuthorHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/authors/{id:[0-9]+}/books", testHandler.GetAuthorBooksHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/stats", testHandler.GetReadingStatsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/stats/diversity", testHandler.GetDiversityStatsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/stats/reads", testHandler.GetRereadStatsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/stats/length", testHandler.GetLengthStatsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/stats/value", testHandler.GetCollectionValueHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/onthisday", testHandler.GetOnThisDayHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/vacations", testHandler.GetVacationsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/vacations", testHandler.AddVacationHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/vacations/{id:[0-9]+}", testHandler.DeleteVacationHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/disposals", testHandler.GetDisposalsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/disposals/report", testHandler.GetDisposalReportHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/disposals/{id:[0-9]+}", testHandler.DeleteDisposalHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/collections", testHandler.GetCollectionsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/collections", testHandler.AddCollectionHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/collections/{id:[0-9]+}", testHandler.GetCollectionHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/collections/{id:[0-9]+}", testHandler.UpdateCollectionHandler).Methods(http.MethodPut)
+	testRouter.HandleFunc("/api/collections/{id:[0-9]+}", testHandler.DeleteCollectionHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/collections/{id:[0-9]+}/books/{bookID:[0-9]+}", testHandler.AddCollectionBookHandler).Methods(http.MethodPut)
+	testRouter.HandleFunc("/api/collections/{id:[0-9]+}/books/{bookID:[0-9]+}", testHandler.RemoveCollectionBookHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/presets", testHandler.GetShelfPresetsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/presets", testHandler.AddShelfPresetHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/presets/{id:[0-9]+}", testHandler.GetShelfPresetHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/presets/{id:[0-9]+}", testHandler.UpdateShelfPresetHandler).Methods(http.MethodPut)
+	testRouter.HandleFunc("/api/presets/{id:[0-9]+}", testHandler.DeleteShelfPresetHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/goals", testHandler.GetReadingGoalsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/goals/{year:[0-9]+}", testHandler.GetReadingGoalHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/goals/{year:[0-9]+}", testHandler.SetReadingGoalHandler).Methods(http.MethodPut)
+	testRouter.HandleFunc("/api/goals/{year:[0-9]+}", testHandler.DeleteReadingGoalHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/imports", testHandler.GetImportsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/imports/{id:[0-9]+}/rollback", testHandler.RollbackImportHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/merges", testHandler.GetMergesHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/merges/{id:[0-9]+}/unmerge", testHandler.UnmergeHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/trash", testHandler.GetTrashHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/trash/{id:[0-9]+}/restore", testHandler.RestoreBookHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/trash/{id:[0-9]+}", testHandler.PurgeBookHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/deletions", testHandler.GetBulkDeletionsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/deletions/{id:[0-9]+}/undo", testHandler.UndoBulkDeletionHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/export", testHandler.ExportHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/export/insurance", testHandler.ExportInsuranceHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/lists/export", testHandler.ExportListHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/lists/import", testHandler.ImportListHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/compare", testHandler.CompareLibraryHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/feed.json", testHandler.FeedHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/users/{username}/feed.json", testHandler.FeedHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/timeline", testHandler.TimelineHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/users/register", testHandler.RegisterHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/users/login", testHandler.LoginHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/users/logout", testHandler.LogoutHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/users/me", testHandler.CurrentUserHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/users/me", testHandler.UpdateCurrentUserHandler).Methods(http.MethodPatch)
+	testRouter.HandleFunc("/api/users/passkeys", testHandler.GetPasskeysHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/users/passkeys/register/begin", testHandler.BeginPasskeyRegistrationHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/users/passkeys/register/finish", testHandler.FinishPasskeyRegistrationHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/users/passkeys/{id:[0-9]+}", testHandler.DeletePasskeyHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/users/passkeys/login/begin", testHandler.BeginPasskeyLoginHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/users/passkeys/login/finish", testHandler.FinishPasskeyLoginHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/api-keys", testHandler.GetAPIKeysHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/api-keys", testHandler.CreateAPIKeyHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/api-keys/{id:[0-9]+}", testHandler.DeleteAPIKeyHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/share-links", testHandler.GetShareLinksHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/share-links", testHandler.CreateShareLinkHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/share-links/{id:[0-9]+}", testHandler.DeleteShareLinkHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/shared/{token}", testHandler.GetSharedLibraryHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/wishlist/members", testHandler.GetWishlistMembersHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/wishlist/members", testHandler.AddWishlistMemberHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/wishlist/members/{userID:[0-9]+}", testHandler.DeleteWishlistMemberHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/wishlists", testHandler.GetSharedWishlistsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/wishlists/{ownerID:[0-9]+}", testHandler.GetSharedWishlistHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/wishlists/{ownerID:[0-9]+}/books/{bookID:[0-9]+}/claim", testHandler.ClaimWishlistBookHandler).Methods(http.MethodPut)
+	testRouter.HandleFunc("/api/wishlists/{ownerID:[0-9]+}/books/{bookID:[0-9]+}/claim", testHandler.UnclaimWishlistBookHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/follows", testHandler.GetFollowsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/follows", testHandler.AddFollowHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/follows/{id:[0-9]+}/refresh", testHandler.RefreshFollowHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/follows/{id:[0-9]+}", testHandler.DeleteFollowHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/crosspost/accounts", testHandler.GetCrosspostAccountsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/crosspost/accounts", testHandler.AddCrosspostAccountHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/crosspost/accounts/{id:[0-9]+}", testHandler.DeleteCrosspostAccountHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/webhooks", testHandler.GetWebhooksHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/webhooks", testHandler.AddWebhookHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/webhooks/{id:[0-9]+}", testHandler.DeleteWebhookHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/webhooks/{id:[0-9]+}/deliveries", testHandler.GetWebhookDeliveriesHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/webhooks/deliveries/{id:[0-9]+}/retry", testHandler.RetryWebhookDeliveryHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/sync/accounts", testHandler.GetSyncAccountsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/sync/accounts", testHandler.AddSyncAccountHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/sync/accounts/{id:[0-9]+}", testHandler.DeleteSyncAccountHandler).Methods(http.MethodDelete)
+	testRouter.HandleFunc("/api/sync/accounts/{id:[0-9]+}/run", testHandler.RunSyncHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/sync/log", testHandler.SyncLogHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/review", testHandler.GetReviewQueueHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/review/{id:[0-9]+}/resolve", testHandler.ResolveReviewHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/admin/covers/repair", testHandler.GetCoverRepairHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/admin/covers/repair", testHandler.StartCoverRepairHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/admin/covers/cache", testHandler.CacheCoversHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/admin/descriptions/clean", testHandler.CleanDescriptionsHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/admin/titles/split", testHandler.SplitSubtitlesHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/admin/series/suggestions", testHandler.GetSeriesSuggestionsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/admin/series/suggestions", testHandler.StartSeriesSuggestionsHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/admin/providers", testHandler.GetProvidersHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/admin/logging", testHandler.GetLoggingHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/admin/logging", testHandler.UpdateLoggingHandler).Methods(http.MethodPut)
+	testRouter.HandleFunc("/api/admin/backup", testHandler.BackupHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/admin/restore", testHandler.RestoreHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/admin/backups", testHandler.GetBackupsHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/admin/slow-queries", testHandler.GetSlowQueriesHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/admin/quality", testHandler.GetDataQualityHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/admin/backfill", testHandler.GetBackfillHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/admin/backfill", testHandler.StartBackfillHandler).Methods(http.MethodPost)
+	testRouter.HandleFunc("/api/covers/{hash:[0-9a-f]{64}}", testHandler.GetCoverImageHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/openapi.json", testHandler.GetOpenAPIHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/api/docs", testHandler.GetAPIDocsHandler).Methods(http.MethodGet)
 
 	return nil
 }
@@ -95,7 +258,7 @@ func createTestBook(status model.BookStatus, suffix string) *model.Book {
 		Title:         "Test Book " + suffix,
 		Author:        "Test Author " + suffix,
 		OpenLibraryID: "OL12345M" + suffix,
-		ISBN:          "9781234567890",
+		ISBN:          "9781234567890" + suffix,
 		Status:        status,
 		Type:          model.TypeBook,
 		Rating:        &rating,
@@ -108,7 +271,7 @@ func createTestBook(status model.BookStatus, suffix string) *model.Book {
 func TestGetBooksHandler(t *testing.T) {
 	// Add test books
 	book1 := createTestBook(model.StatusWantToRead, "1")
-	_, err := testStore.AddBook(book1)
+	_, err := testStore.AddBook(context.Background(), book1)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -189,11 +352,37 @@ func TestAddBookHandler(t *testing.T) {
 	}
 }
 
+// TestAddBookHandlerDuplicate tests that adding a book already on the
+// bookshelf returns 409 pointing at the existing book
+func TestAddBookHandlerDuplicate(t *testing.T) {
+	existing := createTestBook(model.StatusWantToRead, "dup")
+	if _, err := testStore.AddBook(context.Background(), existing); err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+
+	for name, body := range map[string]string{
+		"same Open Library ID": `{"title": "Again", "author": "A", "open_library_id": "` + existing.OpenLibraryID + `"}`,
+		"same ISBN":            `{"title": "Again", "author": "A", "open_library_id": "OLDUPISBNM", "isbn": "` + existing.ISBN + `"}`,
+	} {
+		req, _ := http.NewRequest("POST", "/api/books", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+
+		var resp duplicateBookResponse
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		if rr.Code != http.StatusConflict || resp.ExistingBookID != existing.ID ||
+			resp.ExistingBook != "/api/v1/books/"+itoa(existing.ID) || rr.Header().Get("Location") != resp.ExistingBook {
+			t.Errorf("%s: expected a 409 pointing at book %d, got %d: %s", name, existing.ID, rr.Code, rr.Body.String())
+		}
+	}
+}
+
 // TestUpdateBookStatusHandler tests the PUT /api/books/{id} endpoint
 func TestUpdateBookStatusHandler(t *testing.T) {
 	// Add test book
 	book := createTestBook(model.StatusWantToRead, "3")
-	id, err := testStore.AddBook(book)
+	id, err := testStore.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -226,7 +415,7 @@ func TestUpdateBookStatusHandler(t *testing.T) {
 	}
 
 	// Verify the status was updated in the database
-	updatedBook, err := testStore.GetBookByID(id)
+	updatedBook, err := testStore.GetBookByID(context.Background(), id)
 	if err != nil {
 		t.Fatalf("Failed to retrieve updated book: %v", err)
 	}
@@ -240,7 +429,7 @@ func TestUpdateBookStatusHandler(t *testing.T) {
 func TestUpdateBookDetailsHandler(t *testing.T) {
 	// Add test book
 	book := createTestBook(model.StatusWantToRead, "4")
-	id, err := testStore.AddBook(book)
+	id, err := testStore.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -276,7 +465,7 @@ func TestUpdateBookDetailsHandler(t *testing.T) {
 	}
 
 	// Verify the details were updated in the database
-	updatedBook, err := testStore.GetBookByID(id)
+	updatedBook, err := testStore.GetBookByID(context.Background(), id)
 	if err != nil {
 		t.Fatalf("Failed to retrieve updated book: %v", err)
 	}
@@ -294,7 +483,7 @@ func TestUpdateBookDetailsHandler(t *testing.T) {
 func TestDeleteBookHandler(t *testing.T) {
 	// Add test book
 	book := createTestBook(model.StatusWantToRead, "5")
-	id, err := testStore.AddBook(book)
+	id, err := testStore.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -317,7 +506,7 @@ func TestDeleteBookHandler(t *testing.T) {
 	}
 
 	// Verify the book was deleted from the database
-	_, err = testStore.GetBookByID(id)
+	_, err = testStore.GetBookByID(context.Background(), id)
 	if err == nil {
 		t.Errorf("Book was not deleted from the database")
 	}
@@ -329,7 +518,7 @@ func TestSearchBooksHandler(t *testing.T) {
 	book1 := createTestBook(model.StatusWantToRead, "Search1")
 	book1.Title = "The Great Gatsby"
 	book1.Author = "F. Scott Fitzgerald"
-	_, err := testStore.AddBook(book1)
+	_, err := testStore.AddBook(context.Background(), book1)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -337,7 +526,7 @@ func TestSearchBooksHandler(t *testing.T) {
 	book2 := createTestBook(model.StatusCurrentlyReading, "Search2")
 	book2.Title = "The Great Adventure"
 	book2.Author = "John Smith"
-	_, err = testStore.AddBook(book2)
+	_, err = testStore.AddBook(context.Background(), book2)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -409,7 +598,7 @@ func TestAddBookHandlerInvalidInput(t *testing.T) {
 func TestUpdateBookTypeHandler(t *testing.T) {
 	// Add test book
 	book := createTestBook(model.StatusWantToRead, "TypeTest")
-	id, err := testStore.AddBook(book)
+	id, err := testStore.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -442,7 +631,7 @@ func TestUpdateBookTypeHandler(t *testing.T) {
 	}
 
 	// Verify the type was updated in the database
-	updatedBook, err := testStore.GetBookByID(id)
+	updatedBook, err := testStore.GetBookByID(context.Background(), id)
 	if err != nil {
 		t.Fatalf("Failed to retrieve updated book: %v", err)
 	}
@@ -456,7 +645,7 @@ func TestUpdateBookTypeHandler(t *testing.T) {
 func TestUpdateBookTypeHandlerInvalidType(t *testing.T) {
 	// Add test book
 	book := createTestBook(model.StatusWantToRead, "InvalidType")
-	id, err := testStore.AddBook(book)
+	id, err := testStore.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -488,7 +677,7 @@ func TestUpdateBookTypeHandlerInvalidType(t *testing.T) {
 func TestUpdateBookStatusHandlerInvalidStatus(t *testing.T) {
 	// Add test book
 	book := createTestBook(model.StatusWantToRead, "InvalidStatus")
-	id, err := testStore.AddBook(book)
+	id, err := testStore.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -578,7 +767,7 @@ func TestMalformedJSON(t *testing.T) {
 func TestUpdateBookDetailsHandlerPartialUpdate(t *testing.T) {
 	// Add test book
 	book := createTestBook(model.StatusWantToRead, "PartialUpdate")
-	id, err := testStore.AddBook(book)
+	id, err := testStore.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -606,7 +795,7 @@ func TestUpdateBookDetailsHandlerPartialUpdate(t *testing.T) {
 	}
 
 	// Verify only rating was updated
-	updatedBook, err := testStore.GetBookByID(id)
+	updatedBook, err := testStore.GetBookByID(context.Background(), id)
 	if err != nil {
 		t.Fatalf("Failed to retrieve updated book: %v", err)
 	}
@@ -631,7 +820,7 @@ func TestUpdateBookDetailsHandlerPartialUpdate(t *testing.T) {
 func TestGzipCompression(t *testing.T) {
 	// Add test books with a unique OpenLibraryID to avoid conflicts with other tests
 	book1 := createTestBook(model.StatusWantToRead, "Gzip")
-	_, err := testStore.AddBook(book1)
+	_, err := testStore.AddBook(context.Background(), book1)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -732,3 +921,633 @@ func TestGzipCompression(t *testing.T) {
 		})
 	}
 }
+
+// TestUpdateBookStudyHandler tests the PUT /api/books/{id}/study endpoint
+func TestUpdateBookStudyHandler(t *testing.T) {
+	book := createTestBook(model.StatusCurrentlyReading, "Study")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"valid textbook info", `{"edition": 3, "course_code": "CS 101", "semester": "Fall 2025", "reading_mode": "reference"}`, http.StatusOK},
+		{"invalid reading mode", `{"reading_mode": "skimming"}`, http.StatusBadRequest},
+		{"invalid edition", `{"edition": 0}`, http.StatusBadRequest},
+		{"unknown field", `{"course": "CS 101"}`, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("PUT", "/api/books/"+itoa(id)+"/study", bytes.NewBufferString(tc.body))
+			if err != nil {
+				t.Fatalf("Could not create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			testRouter.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.wantStatus {
+				t.Errorf("Handler returned wrong status code: got %v want %v, body: %s", status, tc.wantStatus, rr.Body.String())
+			}
+		})
+	}
+
+	// Verify the valid update was persisted
+	updatedBook, err := testStore.GetBookByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Failed to retrieve updated book: %v", err)
+	}
+	if updatedBook.ReadingMode != model.ModeReference {
+		t.Errorf("Expected reading mode %s, got %s", model.ModeReference, updatedBook.ReadingMode)
+	}
+	if updatedBook.Edition == nil || *updatedBook.Edition != 3 {
+		t.Errorf("Expected edition 3, got %v", updatedBook.Edition)
+	}
+	if updatedBook.CourseCode == nil || *updatedBook.CourseCode != "CS 101" {
+		t.Errorf("Expected course code CS 101, got %v", updatedBook.CourseCode)
+	}
+}
+
+// TestUpdateBookSharingHandler tests the PUT /api/books/{id}/sharing endpoint
+func TestUpdateBookSharingHandler(t *testing.T) {
+	book := createTestBook(model.StatusRead, "Sharing")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+
+	req, err := http.NewRequest("PUT", "/api/books/"+itoa(id)+"/sharing", bytes.NewBufferString(`{"publish_opt_out": true, "comments_spoiler": true}`))
+	if err != nil {
+		t.Fatalf("Could not create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", status, http.StatusOK, rr.Body.String())
+	}
+
+	updatedBook, err := testStore.GetBookByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Failed to retrieve updated book: %v", err)
+	}
+	if !updatedBook.PublishOptOut || !updatedBook.CommentsSpoiler {
+		t.Errorf("Sharing settings not updated: %+v", updatedBook)
+	}
+
+	// Non-existent book
+	req, _ = http.NewRequest("PUT", "/api/books/99999/sharing", bytes.NewBufferString(`{"publish_opt_out": true}`))
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+// TestUpdateBookFavoriteHandler tests the PUT /api/books/{id}/favorite endpoint
+// and the favorite filter of the book list
+func TestUpdateBookFavoriteHandler(t *testing.T) {
+	book := createTestBook(model.StatusRead, "Favorite")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("PUT", "/api/books/"+itoa(id)+"/favorite", `{"favorite": true}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var resp BookResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || resp.ID != id || !resp.Favorite {
+		t.Errorf("Expected the book back as a favorite, got %+v (%v)", resp, err)
+	}
+
+	rr = do("GET", "/api/books?favorite=true&fields=id", "")
+	var list []map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil || len(list) != 1 || list[0]["id"] != float64(id) {
+		t.Errorf("Expected only the favorite, got %s (%v)", rr.Body.String(), err)
+	}
+	if rr := do("GET", "/api/books?favorite=maybe", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid favorite filter, got %d", rr.Code)
+	}
+	rr = do("GET", "/api/books?favorites_first=true&limit=1&fields=id", "")
+	list = nil
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil || len(list) != 1 || list[0]["id"] != float64(id) {
+		t.Errorf("Expected the favorite first, got %s (%v)", rr.Body.String(), err)
+	}
+
+	rr = do("PUT", "/api/books/"+itoa(id)+"/favorite", `{"favorite": false}`)
+	if updated, _ := testStore.GetBookByID(context.Background(), id); rr.Code != http.StatusOK || updated == nil || updated.Favorite {
+		t.Errorf("Expected the book to be unpinned, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := do("PUT", "/api/books/"+itoa(id)+"/favorite", `{}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without favorite, got %d", rr.Code)
+	}
+	if rr := do("PUT", "/api/books/99999/favorite", `{"favorite": true}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing book, got %d", rr.Code)
+	}
+}
+
+// TestArchivedBooks tests archiving a book, which hides it from the book list unless asked for
+func TestArchivedBooks(t *testing.T) {
+	book := createTestBook(model.StatusRead, "Archived")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+	listed := func(path string) bool {
+		t.Helper()
+		rr := do("GET", path, "")
+		var list []map[string]interface{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+			t.Fatalf("GET %s: got %d: %s", path, rr.Code, rr.Body.String())
+		}
+		for _, b := range list {
+			if b["id"] == float64(id) {
+				return true
+			}
+		}
+		return false
+	}
+
+	rr := do("PATCH", "/api/books/"+itoa(id), `{"archived": true}`)
+	var resp BookResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || rr.Code != http.StatusOK || !resp.Archived {
+		t.Fatalf("Expected the book back archived, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if listed("/api/books?fields=id") || listed("/api/books?status=read&fields=id") {
+		t.Error("Expected the archived book hidden from the book list")
+	}
+	if !listed("/api/books?archived=true&fields=id") || !listed("/api/books?archived=all&fields=id") {
+		t.Error("Expected the archived book listed when asked for")
+	}
+	if rr := do("GET", "/api/books?archived=maybe", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid archived filter, got %d", rr.Code)
+	}
+	if rr := do("GET", "/api/books/"+itoa(id), ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected the archived book to still be found by ID, got %d", rr.Code)
+	}
+
+	do("PATCH", "/api/books/"+itoa(id), `{"archived": false}`)
+	if !listed("/api/books?fields=id") {
+		t.Error("Expected the book listed again once unarchived")
+	}
+}
+
+// TestBookLabels tests setting a book's label and listing books by it
+func TestBookLabels(t *testing.T) {
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("POST", "/api/books", `{"title": "Labelled", "author": "A", "open_library_id": "OLLABELLED", "label": "🐉"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var created BookResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil || created.Label == nil || *created.Label != "🐉" {
+		t.Fatalf("Expected the label back, got %+v (%v)", created, err)
+	}
+	defer testStore.DeleteBook(context.Background(), created.ID)
+
+	rr = do("PATCH", "/api/books/"+itoa(created.ID), `{"label": "#C0FFEE"}`)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"label":"#c0ffee"`) {
+		t.Errorf("Expected the color to be normalized, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = do("GET", "/api/books?label=%23c0ffee&fields=id,label", "")
+	var list []map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil || len(list) != 1 || list[0]["id"] != float64(created.ID) {
+		t.Errorf("Expected only the labelled book, got %s (%v)", rr.Body.String(), err)
+	}
+
+	for _, path := range []string{"/api/books?label=red", "/api/books?label=%23ff"} {
+		if rr := do("GET", path, ""); rr.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: expected 400, got %d", path, rr.Code)
+		}
+	}
+	if rr := do("PATCH", "/api/books/"+itoa(created.ID), `{"label": "sci-fi"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a label that is not an emoji or a color, got %d", rr.Code)
+	}
+}
+
+func TestBookFieldSelection(t *testing.T) {
+	book := createTestBook(model.StatusRead, "Fields")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	req, _ := http.NewRequest("GET", "/api/books?fields=title,%20status,title", nil)
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var list []map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if len(list) == 0 {
+		t.Fatal("Expected books in the list")
+	}
+	for _, b := range list {
+		if len(b) != 3 || b["id"] == nil || b["title"] == nil || b["status"] == nil {
+			t.Errorf("Expected only id, title and status, got %v", b)
+		}
+	}
+
+	req, _ = http.NewRequest("GET", "/api/books/"+itoa(id)+"?fields=rating,cover_url", nil)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	var detail map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &detail); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if len(detail) != 3 || detail["rating"] != float64(8) || detail["cover_url"] != "http://example.com/cover.jpg" {
+		t.Errorf("Unexpected shaped book: %v", detail)
+	}
+
+	// Without fields the full book is returned
+	req, _ = http.NewRequest("GET", "/api/books/"+itoa(id), nil)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	var full model.Book
+	if err := json.Unmarshal(rr.Body.Bytes(), &full); err != nil || full.Title != book.Title || full.Author != book.Author {
+		t.Errorf("Unexpected full book: %+v (%v)", full, err)
+	}
+
+	for path, want := range map[string]int{
+		"/api/books?fields=title,password":    http.StatusBadRequest,
+		"/api/books/99999":                    http.StatusNotFound,
+		"/api/books/" + itoa(id) + "?fields=": http.StatusOK,
+	} {
+		req, _ = http.NewRequest("GET", path, nil)
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("GET %s: got status %d, want %d", path, rr.Code, want)
+		}
+	}
+}
+
+func TestGetBooksHandlerPaging(t *testing.T) {
+	ctx := context.Background()
+	var ids []int64
+	for _, suffix := range []string{"PageA", "PageB"} {
+		id, err := testStore.AddBook(ctx, createTestBook(model.StatusRead, suffix))
+		if err != nil {
+			t.Fatalf("Failed to add test book: %v", err)
+		}
+		ids = append(ids, id)
+		defer testStore.DeleteBook(ctx, id)
+	}
+	all, err := testStore.GetBooks(ctx)
+	if err != nil {
+		t.Fatalf("GetBooks failed: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "/api/books?sort=added&order=desc&limit=1&fields=title", nil)
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var page []model.Book
+	if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if len(page) != 1 || page[0].ID != ids[1] {
+		t.Errorf("Expected only the last added book, got %+v", page)
+	}
+	if got := rr.Header().Get("X-Total-Count"); got != strconv.Itoa(len(all)) {
+		t.Errorf("Expected X-Total-Count %d, got %q", len(all), got)
+	}
+	if got := rr.Header().Get("Link"); got != `</api/books?fields=title&limit=1&offset=1&order=desc&sort=added>; rel="next"` {
+		t.Errorf("Unexpected Link header %q", got)
+	}
+
+	for _, query := range []string{"limit=0", "limit=abc", "offset=-1", "sort=isbn", "order=up"} {
+		req, _ = http.NewRequest("GET", "/api/books?"+query, nil)
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("GET /api/books?%s: got status %d, want %d", query, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestGetBooksHandlerFilters(t *testing.T) {
+	audiobook := createTestBook(model.StatusRead, "FilterAudio")
+	audiobook.Type = model.TypeAudiobook
+	audiobook.Author = "Filter Narrator"
+	paper := createTestBook(model.StatusRead, "FilterPaper")
+	paper.Author = "Filter Narrator"
+	for _, book := range []*model.Book{audiobook, paper} {
+		id, err := testStore.AddBook(context.Background(), book)
+		if err != nil {
+			t.Fatalf("Failed to add test book: %v", err)
+		}
+		defer testStore.DeleteBook(context.Background(), id)
+	}
+
+	req, _ := http.NewRequest("GET", "/api/books?status=read&type=audiobook&minRating=8&author=filter%20narr", nil)
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var books []model.Book
+	if err := json.Unmarshal(rr.Body.Bytes(), &books); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if len(books) != 1 || books[0].ID != audiobook.ID || rr.Header().Get("X-Total-Count") != "1" {
+		t.Errorf("Expected only the audiobook, got %+v (total %s)", books, rr.Header().Get("X-Total-Count"))
+	}
+
+	for _, query := range []string{"status=finished", "type=ebook", "min_rating=11", "minRating=x", "source=amazon", "added_after=last-week"} {
+		req, _ = http.NewRequest("GET", "/api/books?"+query, nil)
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("GET /api/books?%s: got status %d, want %d", query, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestAddBookHandlerSource(t *testing.T) {
+	post := func(body string) BookResponse {
+		req, _ := http.NewRequest("POST", "/api/books", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		var book BookResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &book); err != nil || rr.Code != http.StatusCreated {
+			t.Fatalf("Adding %s: got status %d, body: %s", body, rr.Code, rr.Body.String())
+		}
+		t.Cleanup(func() { testStore.DeleteBook(context.Background(), book.ID) })
+		return book
+	}
+	fromAPI := post(`{"title":"Source API","author":"A","open_library_id":"OLSOURCEAPIM"}`)
+	scanned := post(`{"title":"Source Scan","author":"A","open_library_id":"OLSOURCESCANM","source":"isbn_scan"}`)
+	if fromAPI.Source != model.BookSourceAPI || fromAPI.AddedAt == nil || scanned.Source != model.BookSourceISBNScan {
+		t.Errorf("Expected sources api and isbn_scan with the time added, got %+v and %+v", fromAPI, scanned)
+	}
+
+	since := url.QueryEscape(scanned.AddedAt.Add(-time.Minute).Format(time.RFC3339))
+	req, _ := http.NewRequest("GET", "/api/books?source=isbn_scan&added_after="+since, nil)
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	var books []BookResponse
+	json.Unmarshal(rr.Body.Bytes(), &books)
+	if rr.Code != http.StatusOK || len(books) != 1 || books[0].ID != scanned.ID {
+		t.Errorf("Expected only the scanned book, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestSearchLibraryHandler(t *testing.T) {
+	book := createTestBook(model.StatusRead, "SearchLibrary")
+	book.Title = "Children of Time"
+	book.Author = "Adrian Tchaikovsky"
+	comments := "Spiders build a civilisation"
+	book.Comments = &comments
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	for _, query := range []string{"tchaikovsky", "tchaikovksy", "spider civil", "child"} {
+		req, _ := http.NewRequest("GET", "/api/books/search?scope=library&q="+url.QueryEscape(query), nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Handler returned wrong status code for %q: got %v want %v, body: %s", query, rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var books []BookResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &books); err != nil {
+			t.Fatalf("Could not unmarshal response: %v", err)
+		}
+		if len(books) != 1 || books[0].ID != id {
+			t.Errorf("Search for %q: expected only %q, got %+v", query, book.Title, books)
+		}
+	}
+}
+
+func TestAddBookHandlerConflict(t *testing.T) {
+	book := createTestBook(model.StatusWantToRead, "Conflict")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	jsonData, _ := json.Marshal(map[string]string{"title": "Another Title", "author": "Someone", "open_library_id": book.OpenLibraryID})
+	req, _ := http.NewRequest("POST", "/api/books", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Adding a book twice: got status %d want %d, body: %s", rr.Code, http.StatusConflict, rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("DELETE", "/api/books/"+itoa(id+1000), nil)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Deleting a missing book: got status %d want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+// TestPatchBookHandler tests partial updates through PATCH /api/books/{id}
+func TestPatchBookHandler(t *testing.T) {
+	book := createTestBook(model.StatusCurrentlyReading, "Patch")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PATCH", "/api/books/"+itoa(id), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := patch(`{"status": "Read", "comments": null, "series": "Saga", "series_index": 3}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var got BookResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if got.Status != model.StatusRead || got.Comments != nil || got.Series == nil || *got.Series != "Saga" ||
+		got.SeriesIndex == nil || *got.SeriesIndex != 3 || got.DateFinished == nil {
+		t.Errorf("Unexpected book after patch: %+v", got)
+	}
+	// Fields left out of the patch keep their values
+	if got.Rating == nil || *got.Rating != *book.Rating || got.Title != book.Title || got.Type != model.TypeBook {
+		t.Errorf("Patch changed fields it did not set: %+v", got)
+	}
+
+	for _, tt := range []struct {
+		body string
+		want int
+	}{
+		{`{"rating": 11}`, http.StatusBadRequest},
+		{`{"title": null}`, http.StatusBadRequest},
+		{`{"shelf": "top"}`, http.StatusBadRequest},
+		{`{"series": null, "series_index": 2}`, http.StatusBadRequest},
+		{`{}`, http.StatusOK},
+	} {
+		if rr := patch(tt.body); rr.Code != tt.want {
+			t.Errorf("PATCH %s: got status %d, want %d, body: %s", tt.body, rr.Code, tt.want, rr.Body.String())
+		}
+	}
+
+	req, _ := http.NewRequest("PATCH", "/api/books/999999", bytes.NewBufferString(`{"rating": 5}`))
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Patching a missing book: got status %d want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+// TestBatchAddBooksHandler tests adding books through POST /api/books/batch
+func TestBatchAddBooksHandler(t *testing.T) {
+	existing := createTestBook(model.StatusWantToRead, "BatchExisting")
+	existingID, err := testStore.AddBook(context.Background(), existing)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), existingID)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/books/batch", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := post(`[{"title": "Batch One", "author": "A", "open_library_id": "OLBATCH1M"},
+		{"title": "Batch Two", "open_library_id": "OLBATCH2M", "status": "Read"}]`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var added []BookResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &added); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	for _, book := range added {
+		defer testStore.DeleteBook(context.Background(), book.ID)
+	}
+	if len(added) != 2 || added[0].ID == 0 || added[1].Author != "Unknown Author" || added[1].Status != model.StatusRead {
+		t.Errorf("Unexpected added books %+v", added)
+	}
+
+	// One duplicate rejects the whole batch
+	rr = post(`[{"title": "Batch Three", "author": "A", "open_library_id": "OLBATCH3M"},
+		{"title": "Batch Dup", "author": "A", "open_library_id": "` + existing.OpenLibraryID + `"}]`)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Batch with a duplicate: got status %d want %d, body: %s", rr.Code, http.StatusConflict, rr.Body.String())
+	}
+	if books, _ := testStore.SearchBooks(context.Background(), "Batch Three"); len(books) != 0 {
+		t.Errorf("Expected the failed batch to add nothing, got %+v", books)
+	}
+
+	for name, body := range map[string]string{
+		"empty":         `[]`,
+		"missing title": `[{"author": "A", "open_library_id": "OLBATCH4M"}]`,
+		"not an array":  `{"title": "Batch", "open_library_id": "OLBATCH5M"}`,
+	} {
+		if rr := post(body); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d want %d", name, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+// TestBatchAddBooksDryRun tests that a dry run of POST /api/books/batch
+// reports every rejected book and adds nothing
+func TestBatchAddBooksDryRun(t *testing.T) {
+	existing := createTestBook(model.StatusWantToRead, "DryRunExisting")
+	existingID, err := testStore.AddBook(context.Background(), existing)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), existingID)
+
+	post := func(body string) (*httptest.ResponseRecorder, dryRunResponse) {
+		req, _ := http.NewRequest("POST", "/api/books/batch?dry_run=true", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		var resp dryRunResponse
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return rr, resp
+	}
+
+	rr, resp := post(`[{"title": "Dry One: A Subtitle", "author": "A", "open_library_id": "OLDRY1M"}]`)
+	books, _ := resp.Result.([]interface{})
+	if rr.Code != http.StatusOK || !resp.DryRun || len(resp.Errors) != 0 || len(books) != 1 {
+		t.Fatalf("Expected the would-be book, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if book := books[0].(map[string]interface{}); book["title"] != "Dry One" || book["subtitle"] != "A Subtitle" {
+		t.Errorf("Expected the book as it would be stored, got %v", book)
+	}
+	if found, _ := testStore.SearchBooks(context.Background(), "Dry One"); len(found) != 0 {
+		t.Errorf("Expected a dry run to add nothing, got %+v", found)
+	}
+
+	rr, resp = post(`[{"title": "Dry Two", "author": "A", "open_library_id": "OLDRY2M"},
+		{"author": "A", "open_library_id": "OLDRY3M"},
+		{"title": "Dry Dup", "author": "A", "open_library_id": "` + existing.OpenLibraryID + `"}]`)
+	if rr.Code != http.StatusOK || resp.Result != nil || len(resp.Errors) != 2 || resp.Errors[0].Field != "1" || resp.Errors[1].Field != "2" {
+		t.Errorf("Expected both rejected books reported, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr, resp = post(`[{"title": "Dry Four", "author": "A", "open_library_id": "OLDRY4M"},
+		{"title": "Dry Four Again", "author": "A", "open_library_id": "OLDRY4M"}]`)
+	if rr.Code != http.StatusOK || len(resp.Errors) != 1 || !bytes.Contains([]byte(resp.Errors[0].Message), []byte("book 2")) {
+		t.Errorf("Expected the duplicate within the batch reported, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if rr, _ := post(`[{"title": "Dry", "author": "A", "open_library_id": "OLDRY5M"}]`); rr.Code != http.StatusOK {
+		t.Errorf("Unexpected status %d", rr.Code)
+	}
+	req, _ := http.NewRequest("POST", "/api/books/batch?dry_run=maybe", bytes.NewBufferString(`[]`))
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid dry_run: got status %d want %d", rr.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GetBookHistoryHandler handles GET /api/books/{id}/history requests,
+// returning the audit log of a book, oldest first: who created, changed,
+// deleted or restored it and when, with the fields each update changed. The
+// log of a book in the trash or purged is still available.
+func (h *APIHandler) GetBookHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+	events, err := h.Store.GetBookEvents(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve book history")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, events)
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestBookHistoryHandler tests viewing the audit log of a book
+func TestBookHistoryHandler(t *testing.T) {
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("POST", "/api/books", `{"title": "History Book", "author": "A", "open_library_id": "OLHISTORY1M"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Adding a book: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	var book BookResponse
+	json.Unmarshal(rr.Body.Bytes(), &book)
+	id := itoa(book.ID)
+	if rr := do("PATCH", "/api/books/"+id, `{"author": "B"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Patching: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("DELETE", "/api/books/"+id, ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("Deleting: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	// The log of a book in the trash is still available
+	rr = do("GET", "/api/books/"+id+"/history", "")
+	var events []model.BookEvent
+	json.Unmarshal(rr.Body.Bytes(), &events)
+	if rr.Code != http.StatusOK || len(events) != 3 || events[0].Kind != model.EventCreated || events[2].Kind != model.EventDeleted {
+		t.Fatalf("History: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), `"changes":[{"field":"author","from":"A","to":"B"}]`) {
+		t.Errorf("Expected the author change in the history, got %s", rr.Body.String())
+	}
+
+	if rr := do("GET", "/api/books/999999/history", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Unknown book: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
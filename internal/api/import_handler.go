@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// GetImportsHandler handles GET /api/imports requests, listing every import
+// batch, newest first, with the number of its books still on the bookshelf.
+func (h *APIHandler) GetImportsHandler(w http.ResponseWriter, r *http.Request) {
+	batches, err := h.Store.GetImportBatches(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve imports: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, batches)
+}
+
+// RollbackImportHandler handles POST /api/imports/{id}/rollback requests,
+// deleting the books the import added. Books changed, tagged or read since
+// the import are kept and listed under "kept" with a warning, unless the
+// optional body is {"force": true}. The rollback may be repeated, for
+// instance forced after reviewing the kept books. With ?dry_run=true nothing
+// is deleted, and the response tells what would be.
+func (h *APIHandler) RollbackImportHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid import ID")
+		return
+	}
+	r, dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
+	var payload struct {
+		Force bool `json:"force"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	rollback, err := h.Store.RollbackImportBatch(r.Context(), id, payload.Force)
+	if dryRun {
+		respondWithDryRun(w, rollback, nil, err, "Failed to roll back import")
+		return
+	}
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to roll back import")
+		return
+	}
+	response := struct {
+		*model.ImportRollback
+		Warning string `json:"warning,omitempty"`
+	}{ImportRollback: rollback}
+	if len(rollback.Kept) > 0 {
+		response.Warning = strconv.Itoa(len(rollback.Kept)) + " book(s) changed since the import were kept; roll back with {\"force\": true} to delete them too"
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
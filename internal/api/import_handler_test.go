@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestImportHandlers tests listing an import and rolling it back
+func TestImportHandlers(t *testing.T) {
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("POST", "/api/books/batch", `[{"title": "Import One", "author": "A", "open_library_id": "OLIMPORT1M", "source": "goodreads_import"},
+		{"title": "Import Two", "author": "B", "open_library_id": "OLIMPORT2M", "source": "goodreads_import"}]`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Importing books: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	var books []BookResponse
+	json.Unmarshal(rr.Body.Bytes(), &books)
+	if len(books) != 2 || books[0].ImportBatchID == nil {
+		t.Fatalf("Expected two books in an import batch, got %s", rr.Body.String())
+	}
+	batchID := *books[0].ImportBatchID
+
+	rr = do("GET", "/api/imports", "")
+	var batches []model.ImportBatch
+	json.Unmarshal(rr.Body.Bytes(), &batches)
+	if rr.Code != http.StatusOK || len(batches) == 0 || batches[0].ID != batchID ||
+		batches[0].Source != model.BookSourceGoodreads || batches[0].BookCount != 2 {
+		t.Fatalf("Expected the import first in the list, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// A book changed since the import survives a plain rollback, with a warning
+	if rr := do("PUT", "/api/books/"+itoa(books[0].ID), `{"status": "Read"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Updating status: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	type rollbackResponse struct {
+		model.ImportRollback
+		Warning string `json:"warning"`
+	}
+	rr = do("POST", "/api/imports/"+itoa(batchID)+"/rollback?dry_run=true", "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"dry_run":true`) || !strings.Contains(rr.Body.String(), "Import Two") {
+		t.Fatalf("Expected a dry run to list the books, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/books/"+itoa(books[1].ID), ""); rr.Code != http.StatusOK {
+		t.Fatalf("Expected a dry run to delete nothing, got status %d", rr.Code)
+	}
+
+	rr = do("POST", "/api/imports/"+itoa(batchID)+"/rollback", "")
+	var rollback rollbackResponse
+	json.Unmarshal(rr.Body.Bytes(), &rollback)
+	if rr.Code != http.StatusOK || len(rollback.Deleted) != 1 || rollback.Deleted[0].ID != books[1].ID ||
+		len(rollback.Kept) != 1 || rollback.Warning == "" {
+		t.Fatalf("Expected the unchanged book to be deleted and the other kept, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do("POST", "/api/imports/"+itoa(batchID)+"/rollback", `{"force": true}`)
+	rollback = rollbackResponse{}
+	json.Unmarshal(rr.Body.Bytes(), &rollback)
+	if rr.Code != http.StatusOK || len(rollback.Deleted) != 1 || len(rollback.Kept) != 0 || rollback.Warning != "" {
+		t.Errorf("Expected a forced rollback to delete the changed book, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/books/"+itoa(books[0].ID), ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected the book to be gone, got status %d", rr.Code)
+	}
+
+	if rr := do("POST", "/api/imports/999999/rollback", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Rolling back an unknown import: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := do("POST", "/api/imports/"+itoa(batchID)+"/rollback", `{"force": "yes"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid body: got status %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+}
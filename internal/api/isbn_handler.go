@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/ericdahl/bookshelf/internal/isbn"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// AddBookByISBNHandler handles POST /api/books/isbn/{isbn} requests, adding
+// the book with that ISBN in one call, as barcode scanners do. The ISBN-10 or
+// ISBN-13 has its check digit verified and is looked up as an ISBN-13 with
+// the metadata providers, whose first result fills in the book and its cover.
+// An optional body sets the shelf and type, e.g. {"status": "Read"}; books
+// go on the Want to Read shelf otherwise. A book already on the bookshelf is
+// a 409, as for POST /api/books.
+func (h *APIHandler) AddBookByISBNHandler(w http.ResponseWriter, r *http.Request) {
+	code, err := isbn.To13(mux.Vars(r)["isbn"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid ISBN: expected an ISBN-10 or ISBN-13 with a valid check digit")
+		return
+	}
+	var payload struct {
+		Status model.BookStatus `json:"status"`
+		Type   model.BookType   `json:"type"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if payload.Status == "" {
+		payload.Status = model.StatusWantToRead
+	}
+
+	found, err := h.Metadata.Search(r.Context(), code)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	if len(found) == 0 {
+		respondWithError(w, http.StatusNotFound, "No book found with ISBN "+code)
+		return
+	}
+	book := newBookFromProvider(found[0])
+	book.ISBN = code // The edition scanned, whichever the provider listed first
+	book.Status, book.Type, book.Source = payload.Status, payload.Type, model.BookSourceISBNScan
+
+	id, err := h.Store.AddBook(r.Context(), &book)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to add book to database")
+		return
+	}
+	book.ID = id
+	h.cacheCover(book)
+	respondWithJSON(w, http.StatusCreated, newBookResponse(&book))
+}
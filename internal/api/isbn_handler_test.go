@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/metadata"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// isbnProvider finds one book by its ISBN-13.
+type isbnProvider struct {
+	isbn string
+	book metadata.Book
+}
+
+func (p isbnProvider) Name() string { return "isbn" }
+
+func (p isbnProvider) Search(ctx context.Context, query string) ([]metadata.Book, error) {
+	return nil, nil
+}
+
+func (p isbnProvider) LookupISBN(ctx context.Context, isbn string) ([]metadata.Book, error) {
+	if isbn == p.isbn {
+		return []metadata.Book{p.book}, nil
+	}
+	return nil, nil
+}
+
+// TestAddBookByISBNHandler tests adding a book from its scanned ISBN alone
+func TestAddBookByISBNHandler(t *testing.T) {
+	chain := testHandler.Metadata
+	defer func() { testHandler.Metadata = chain }()
+	testHandler.Metadata = metadata.Chain{isbnProvider{
+		isbn: "9780141439518",
+		book: metadata.Book{Provider: "isbn", ID: "OLPRIDE1M", Title: "Pride and Prejudice", Authors: []string{"Jane Austen"},
+			ISBN: "9780141040349", PublishYear: 1813, PageCount: 480, Publisher: "Penguin Classics", Language: "eng"},
+	}}
+	do := func(path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for _, code := range []string{"0141439514", "978-0-14-143951-9", "12345"} {
+		if rr := do("/api/books/isbn/"+code, ""); rr.Code != http.StatusBadRequest {
+			t.Errorf("ISBN %s: got status %d, want %d", code, rr.Code, http.StatusBadRequest)
+		}
+	}
+	if rr := do("/api/books/isbn/9780306406157", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Unknown ISBN: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	// The ISBN-10 is looked up as its ISBN-13
+	rr := do("/api/books/isbn/0-14-143951-3", `{"status": "Read"}`)
+	var book BookResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &book); err != nil || rr.Code != http.StatusCreated {
+		t.Fatalf("Expected the book added, got %d: %s", rr.Code, rr.Body.String())
+	}
+	defer testStore.PurgeBook(context.Background(), book.ID)
+	if book.Title != "Pride and Prejudice" || book.ISBN != "9780141439518" || book.Status != model.StatusRead || book.Source != model.BookSourceISBNScan ||
+		book.PageCount == nil || *book.PageCount != 480 || book.Publisher == nil || *book.Publisher != "Penguin Classics" {
+		t.Errorf("Unexpected book %+v", book)
+	}
+
+	if rr := do("/api/books/isbn/9780141439518", ""); rr.Code != http.StatusConflict {
+		t.Errorf("Adding the book twice: got status %d, want %d", rr.Code, http.StatusConflict)
+	}
+}
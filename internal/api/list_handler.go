@@ -0,0 +1,302 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/isbn"
+	"github.com/ericdahl/bookshelf/internal/match"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// listImportResult summarizes the outcome of importing a shared list.
+type listImportResult struct {
+	Imported []model.Book         `json:"imported"`
+	Queued   []model.PendingMatch `json:"queued"` // Uncertain matches waiting in the review queue
+	Skipped  []listImportSkipped  `json:"skipped"`
+	// ImportBatchID identifies the imported books for a rollback, when any were imported
+	ImportBatchID *int64 `json:"import_batch_id,omitempty"`
+}
+
+// listImportSkipped describes a list entry that was not imported and why.
+type listImportSkipped struct {
+	Title  string `json:"title"`
+	Reason string `json:"reason"`
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ExportListHandler handles GET /api/lists/export requests.
+// Optional query parameters:
+//   - status: only export books on this shelf (defaults to all shelves)
+//   - collection: only export the books of this collection, by ID
+//   - tag: only export books with this tag
+//   - name: name stored in the file (defaults to the collection, tag or
+//     shelf name)
+//   - notes: set to "true" to include comments, which are personal and left
+//     out by default
+func (h *APIHandler) ExportListHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := db.BookFilter{Status: model.BookStatus(query.Get("status")), Tag: query.Get("tag"), Archived: db.ArchivedIncluded}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid status value. Must be 'Want to Read', 'Currently Reading', or 'Read'")
+		return
+	}
+	includeNotes := false
+	if v := query.Get("notes"); v != "" {
+		var err error
+		if includeNotes, err = strconv.ParseBool(v); err != nil {
+			respondWithError(w, http.StatusBadRequest, "notes must be true or false")
+			return
+		}
+	}
+
+	name := query.Get("name")
+	if name == "" {
+		name = "My Bookshelf"
+		switch {
+		case filter.Tag != "":
+			name = filter.Tag
+		case filter.Status != "":
+			name = string(filter.Status)
+		}
+	}
+	if v := query.Get("collection"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 1 {
+			respondWithError(w, http.StatusBadRequest, "collection must be a collection ID")
+			return
+		}
+		collection, err := h.Store.GetCollection(r.Context(), id)
+		if err != nil {
+			respondWithStoreError(w, err, "Failed to retrieve collection")
+			return
+		}
+		filter.Collection = collection.ID
+		if query.Get("name") == "" {
+			name = collection.Name
+		}
+	}
+
+	books, _, err := h.Store.GetBooksPage(r.Context(), db.ListOptions{Filter: filter})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve books: "+err.Error())
+		return
+	}
+
+	list := model.SharedList{
+		Format:     model.SharedListFormat,
+		Version:    model.SharedListVersion,
+		Name:       name,
+		ExportedAt: time.Now().UTC(),
+		Books:      []model.SharedListItem{},
+	}
+	for _, book := range books {
+		item := model.SharedListItem{
+			Title:         book.Title,
+			Author:        book.Author,
+			ISBN:          book.ISBN,
+			OpenLibraryID: book.OpenLibraryID,
+			CoverURL:      book.CoverURL,
+			Year:          book.PublishYear,
+		}
+		if includeNotes {
+			item.Notes = book.Comments
+		}
+		list.Books = append(list.Books, item)
+	}
+
+	filename := strings.Trim(unsafeFilenameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if filename == "" {
+		filename = "reading-list"
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+	respondWithJSON(w, http.StatusOK, list)
+}
+
+// ImportListHandler handles POST /api/lists/import requests.
+// The body must be a file produced by ExportListHandler. Books already on the
+// bookshelf are skipped: identifiers match outright, otherwise entries are fuzzy
+// matched on title, author and year, and uncertain matches are added to the review queue.
+// Entries without an Open Library ID, as in lists written by hand or by other
+// apps, are looked up with the metadata providers first. Imported books are
+// placed on the shelf given by the optional status query parameter (default
+// "Want to Read").
+func (h *APIHandler) ImportListHandler(w http.ResponseWriter, r *http.Request) {
+	status := model.BookStatus(r.URL.Query().Get("status"))
+	if status == "" {
+		status = model.StatusWantToRead
+	} else if !status.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid status value. Must be 'Want to Read', 'Currently Reading', or 'Read'")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 5*1024*1024) // 5 MB limit
+
+	var list model.SharedList
+	if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid list file: "+err.Error())
+		return
+	}
+	if err := list.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	existingBooks, err := h.Store.GetBooks(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve existing books: "+err.Error())
+		return
+	}
+	index := match.NewIndex(existingBooks)
+	index.Thresholds = h.MatchThresholds
+
+	result := listImportResult{Imported: []model.Book{}, Queued: []model.PendingMatch{}, Skipped: []listImportSkipped{}}
+	// Entries are added one at a time, so one bad entry does not stop the
+	// rest; the batch is created with the first book that is added
+	var batch *model.ImportBatch
+	for _, item := range list.Books {
+		if reason := h.resolveListItem(r.Context(), &item); reason != "" {
+			result.Skipped = append(result.Skipped, listImportSkipped{item.Title, reason})
+			continue
+		}
+		m := index.Match(match.Candidate{
+			OpenLibraryID: item.OpenLibraryID,
+			ISBNs:         []string{item.ISBN},
+			Title:         item.Title,
+			Author:        item.Author,
+			Year:          item.Year,
+		})
+		switch m.Outcome {
+		case match.Matched:
+			result.Skipped = append(result.Skipped, listImportSkipped{item.Title, "already on bookshelf"})
+			continue
+		case match.Review:
+			pending := &model.PendingMatch{
+				Source:    model.SourceListImport,
+				SourceRef: list.Name,
+				ItemKey:   item.OpenLibraryID,
+				Item: model.PendingItem{
+					Title:         item.Title,
+					Author:        item.Author,
+					ISBN:          item.ISBN,
+					OpenLibraryID: item.OpenLibraryID,
+					Year:          item.Year,
+					CoverURL:      item.CoverURL,
+					Notes:         item.Notes,
+					Status:        status,
+				},
+				Candidates: m.PendingCandidates(),
+			}
+			queued, err := h.Store.AddPendingMatch(r.Context(), pending)
+			switch {
+			case err != nil:
+				slog.WarnContext(r.Context(), "Failed to queue list entry for review", "title", item.Title, "error", err)
+				result.Skipped = append(result.Skipped, listImportSkipped{item.Title, err.Error()})
+			case queued:
+				result.Queued = append(result.Queued, *pending)
+			default:
+				result.Skipped = append(result.Skipped, listImportSkipped{item.Title, "already in review queue"})
+			}
+			continue
+		}
+
+		book := model.Book{
+			Title:         item.Title,
+			Author:        item.Author,
+			ISBN:          item.ISBN,
+			OpenLibraryID: item.OpenLibraryID,
+			Status:        status,
+			CoverURL:      item.CoverURL,
+			PublishYear:   item.Year,
+			Comments:      item.Notes,
+			Source:        model.BookSourceListImport,
+		}
+		if book.Author == "" {
+			book.Author = "Unknown Author"
+		}
+
+		if batch == nil {
+			batch = &model.ImportBatch{Source: model.BookSourceListImport, Name: list.Name}
+			if err := h.Store.AddImportBatch(r.Context(), batch); err != nil {
+				respondWithStoreError(w, err, "Failed to start import")
+				return
+			}
+		}
+		book.ImportBatchID = &batch.ID
+		if _, err := h.Store.AddBook(r.Context(), &book); err != nil {
+			slog.WarnContext(r.Context(), "Failed to import list entry", "title", item.Title, "error", err)
+			result.Skipped = append(result.Skipped, listImportSkipped{item.Title, err.Error()})
+			continue
+		}
+		index.Add(book)
+		result.Imported = append(result.Imported, book)
+	}
+	if batch != nil && len(result.Imported) == 0 {
+		if err := h.Store.DeleteImportBatch(r.Context(), batch.ID); err != nil {
+			slog.WarnContext(r.Context(), "Failed to delete empty import batch", "id", batch.ID, "error", err)
+		}
+	} else if batch != nil {
+		result.ImportBatchID = &batch.ID
+	}
+
+	slog.InfoContext(r.Context(), "Imported shared list", "name", list.Name, "imported", len(result.Imported),
+		"queued", len(result.Queued), "skipped", len(result.Skipped))
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// resolveListItem gives a list entry without an Open Library ID the ID of the
+// book the metadata providers find for it: by its ISBN when it has a valid
+// one, by its title and author otherwise. What the entry leaves out is taken
+// from the book found, but the ISBN it lists is kept, as an ISBN-13, being
+// the edition shared.
+// It returns why the entry can't be imported, or "" when it can.
+func (h *APIHandler) resolveListItem(ctx context.Context, item *model.SharedListItem) string {
+	if item.OpenLibraryID != "" {
+		if item.Title == "" {
+			return "missing title"
+		}
+		return ""
+	}
+	query := strings.TrimSpace(item.Title + " " + item.Author)
+	if code, err := isbn.To13(item.ISBN); err == nil {
+		query, item.ISBN = code, code
+	} else if item.Title == "" {
+		return "missing title, ISBN or open_library_id"
+	}
+	found, err := h.Metadata.Search(ctx, query)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to look up list entry", "title", item.Title, "isbn", item.ISBN, "error", err)
+		return "failed to look up book: " + err.Error()
+	}
+	if len(found) == 0 {
+		return "no book found for its ISBN, title or author"
+	}
+	book := found[0]
+	item.OpenLibraryID = book.ID
+	if item.Title == "" {
+		item.Title = book.Title
+	}
+	if item.Author == "" {
+		item.Author = strings.Join(book.Authors, ", ")
+	}
+	if item.ISBN == "" {
+		item.ISBN = book.ISBN
+	}
+	if item.CoverURL == nil && book.CoverURL != "" {
+		item.CoverURL = &book.CoverURL
+	}
+	if item.Year == nil && book.PublishYear > 0 {
+		item.Year = &book.PublishYear
+	}
+	return ""
+}
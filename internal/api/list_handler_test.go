@@ -0,0 +1,207 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/metadata"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestExportListHandler tests the GET /api/lists/export endpoint
+func TestExportListHandler(t *testing.T) {
+	book := createTestBook(model.StatusRead, "ListExport")
+	if _, err := testStore.AddBook(context.Background(), book); err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "/api/lists/export?status=Read&notes=false", nil)
+	if err != nil {
+		t.Fatalf("Could not create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", status, http.StatusOK, rr.Body.String())
+	}
+	if cd := rr.Header().Get("Content-Disposition"); !strings.Contains(cd, `filename="read.json"`) {
+		t.Errorf("Unexpected Content-Disposition header: %s", cd)
+	}
+
+	var list model.SharedList
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if err := list.Validate(); err != nil {
+		t.Errorf("Exported list failed validation: %v", err)
+	}
+
+	found := false
+	for _, item := range list.Books {
+		if item.OpenLibraryID == book.OpenLibraryID {
+			found = true
+			if item.Notes != nil {
+				t.Errorf("Expected notes to be omitted, got %q", *item.Notes)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Exported list does not contain %s", book.OpenLibraryID)
+	}
+
+	// Invalid status filter
+	req, _ = http.NewRequest("GET", "/api/lists/export?status=Unknown", nil)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code for invalid status: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+// TestExportListFilters tests exporting a collection or a tag, and that
+// notes are only exported when asked for
+func TestExportListFilters(t *testing.T) {
+	ctx := context.Background()
+	inCollection := createTestBook(model.StatusWantToRead, "ListCollection")
+	tagged := createTestBook(model.StatusRead, "ListTagged")
+	for _, book := range []*model.Book{inCollection, tagged} {
+		if _, err := testStore.AddBook(ctx, book); err != nil {
+			t.Fatalf("Failed to add test book: %v", err)
+		}
+	}
+	collection := &model.Collection{Name: "Summer Swap"}
+	if err := testStore.AddCollection(ctx, collection); err != nil {
+		t.Fatalf("AddCollection failed: %v", err)
+	}
+	if err := testStore.AddBookToCollection(ctx, collection.ID, inCollection.ID); err != nil {
+		t.Fatalf("AddBookToCollection failed: %v", err)
+	}
+	if _, err := testStore.AddBookTag(ctx, tagged.ID, "lend-out"); err != nil {
+		t.Fatalf("AddBookTag failed: %v", err)
+	}
+
+	export := func(query string) (*httptest.ResponseRecorder, model.SharedList) {
+		req, _ := http.NewRequest("GET", "/api/lists/export?"+query, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		var list model.SharedList
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+				t.Fatalf("Could not unmarshal response: %v", err)
+			}
+		}
+		return rr, list
+	}
+
+	rr, list := export("collection=" + strconv.FormatInt(collection.ID, 10))
+	if rr.Code != http.StatusOK || list.Name != "Summer Swap" || len(list.Books) != 1 || list.Books[0].OpenLibraryID != inCollection.OpenLibraryID {
+		t.Fatalf("Exporting the collection: got status %d, list %+v", rr.Code, list)
+	}
+	if list.Books[0].Notes != nil {
+		t.Errorf("Expected notes left out by default, got %q", *list.Books[0].Notes)
+	}
+	if cd := rr.Header().Get("Content-Disposition"); !strings.Contains(cd, `filename="summer-swap.json"`) {
+		t.Errorf("Unexpected Content-Disposition header: %s", cd)
+	}
+
+	rr, list = export("tag=Lend-Out&notes=true")
+	if rr.Code != http.StatusOK || list.Name != "Lend-Out" || len(list.Books) != 1 || list.Books[0].OpenLibraryID != tagged.OpenLibraryID {
+		t.Fatalf("Exporting the tag: got status %d, list %+v", rr.Code, list)
+	}
+	if notes := list.Books[0].Notes; notes == nil || *notes != "Test comments" {
+		t.Errorf("Expected notes with notes=true, got %v", notes)
+	}
+
+	for query, want := range map[string]int{"collection=999999": http.StatusNotFound, "collection=summer": http.StatusBadRequest, "notes=maybe": http.StatusBadRequest} {
+		if rr, _ := export(query); rr.Code != want {
+			t.Errorf("%s: got status %d, want %d", query, rr.Code, want)
+		}
+	}
+}
+
+// TestImportListHandler tests the POST /api/lists/import endpoint
+func TestImportListHandler(t *testing.T) {
+	// Entries without an Open Library ID are looked up by ISBN
+	chain := testHandler.Metadata
+	defer func() { testHandler.Metadata = chain }()
+	testHandler.Metadata = metadata.Chain{isbnProvider{
+		isbn: "9780306406157",
+		book: metadata.Book{Provider: "isbn", ID: "OLLISTISBN1M", Title: "Found By ISBN", Authors: []string{"List Author"},
+			ISBN: "9780306400001", PublishYear: 1999},
+	}}
+
+	existing := createTestBook(model.StatusRead, "ListExisting")
+	if _, err := testStore.AddBook(context.Background(), existing); err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+
+	notes := "Loved the ending"
+	list := model.SharedList{
+		Format:  model.SharedListFormat,
+		Version: model.SharedListVersion,
+		Name:    "Beach reads",
+		Books: []model.SharedListItem{
+			{Title: "Shared Book", Author: "Friend Author", OpenLibraryID: "OLSHARED1M", Notes: &notes},
+			{Title: existing.Title, OpenLibraryID: existing.OpenLibraryID},
+			{Title: "No ID"},
+			{ISBN: "0-306-40615-2"},
+		},
+	}
+	jsonData, err := json.Marshal(list)
+	if err != nil {
+		t.Fatalf("Failed to marshal JSON: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", "/api/lists/import", bytes.NewBuffer(jsonData))
+	if err != nil {
+		t.Fatalf("Could not create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", status, http.StatusOK, rr.Body.String())
+	}
+
+	var result listImportResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if len(result.Imported) != 2 {
+		t.Fatalf("Expected 2 imported books, got %d: %s", len(result.Imported), rr.Body.String())
+	}
+	if len(result.Skipped) != 2 {
+		t.Errorf("Expected 2 skipped entries, got %d", len(result.Skipped))
+	}
+	if byISBN := result.Imported[1]; byISBN.OpenLibraryID != "OLLISTISBN1M" || byISBN.Title != "Found By ISBN" ||
+		byISBN.Author != "List Author" || byISBN.ISBN != "9780306406157" || byISBN.PublishYear == nil || *byISBN.PublishYear != 1999 {
+		t.Errorf("Expected the ISBN-only entry to be filled in from its lookup, got %+v", byISBN)
+	}
+
+	imported, err := testStore.GetBookByID(context.Background(), result.Imported[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to retrieve imported book: %v", err)
+	}
+	if imported.Status != model.StatusWantToRead {
+		t.Errorf("Expected imported status %s, got %s", model.StatusWantToRead, imported.Status)
+	}
+	if imported.Comments == nil || *imported.Comments != notes {
+		t.Errorf("Expected imported notes %q, got %v", notes, imported.Comments)
+	}
+
+	// Wrong file format
+	req, _ = http.NewRequest("POST", "/api/lists/import", bytes.NewBufferString(`{"format":"csv","version":1}`))
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code for bad format: got %v want %v", status, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// GetBookLoansHandler handles GET /api/books/{id}/loans requests and returns
+// every loan of the book, the latest first.
+func (h *APIHandler) GetBookLoansHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	loans, err := h.Store.GetLoans(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve loans")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, loans)
+}
+
+// LendBookHandler handles POST /api/books/{id}/loans requests, lending a
+// book out. Expects {"borrower": "Alice", "lent_on": "2025-03-01", "due_on":
+// "2025-04-01"}; the dates are optional and lent_on defaults to today.
+func (h *APIHandler) LendBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	var payload struct {
+		Borrower string  `json:"borrower"`
+		LentOn   string  `json:"lent_on"`
+		DueOn    *string `json:"due_on"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	loan := model.Loan{BookID: id, Borrower: payload.Borrower, LentOn: payload.LentOn, DueOn: payload.DueOn}
+	if err := h.Store.LendBook(r.Context(), &loan); err != nil {
+		respondWithStoreError(w, err, "Failed to lend book")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, loan)
+}
+
+// ReturnBookHandler handles POST /api/books/{id}/loans/return requests,
+// closing the book's outstanding loan. The body, {"returned_on":
+// "2025-03-20"}, is optional; the book is returned today by default.
+func (h *APIHandler) ReturnBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	var payload struct {
+		ReturnedOn string `json:"returned_on"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	loan, err := h.Store.ReturnBook(r.Context(), id, payload.ReturnedOn)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to return book")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, loan)
+}
+
+// GetOutstandingLoansHandler handles GET /api/loans requests, listing the
+// books lent out and not yet returned, the longest out first.
+func (h *APIHandler) GetOutstandingLoansHandler(w http.ResponseWriter, r *http.Request) {
+	loans, err := h.Store.GetOutstandingLoans(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve loans: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, loans)
+}
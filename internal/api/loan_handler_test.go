@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestLoanHandlers tests lending a book, listing outstanding loans and
+// returning it
+func TestLoanHandlers(t *testing.T) {
+	book := createTestBook(model.StatusRead, "Loans")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+	path := "/api/books/" + itoa(id) + "/loans"
+	lentOut := func() bool {
+		rr := do("GET", "/api/books/"+itoa(id), "")
+		var resp BookResponse
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return resp.LentOut
+	}
+
+	rr := do("POST", path, `{"borrower": "Carol", "due_on": "2999-01-01"}`)
+	var loan model.Loan
+	if err := json.Unmarshal(rr.Body.Bytes(), &loan); err != nil || rr.Code != http.StatusCreated || loan.ID == 0 || loan.LentOn == "" || loan.Overdue {
+		t.Fatalf("Expected the loan recorded, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !lentOut() {
+		t.Error("Expected the book to be lent out")
+	}
+	if rr := do("POST", path, `{"borrower": "Dan"}`); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 lending a lent out book, got %d: %s", rr.Code, rr.Body.String())
+	}
+	for _, body := range []string{`{}`, `{"borrower": "x", "due_on": "soon"}`, `{"borrower": "x", "phone": "555"}`} {
+		if rr := do("POST", path, body); rr.Code != http.StatusBadRequest {
+			t.Errorf("POST %s: expected 400, got %d: %s", body, rr.Code, rr.Body.String())
+		}
+	}
+
+	rr = do("GET", "/api/loans", "")
+	var outstanding []model.OutstandingLoan
+	if err := json.Unmarshal(rr.Body.Bytes(), &outstanding); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected the outstanding loans, got %d: %s", rr.Code, rr.Body.String())
+	}
+	found := false
+	for _, l := range outstanding {
+		found = found || l.ID == loan.ID && l.Borrower == "Carol" && l.Title != ""
+	}
+	if !found {
+		t.Errorf("Expected the loan to Carol outstanding, got %s", rr.Body.String())
+	}
+
+	rr = do("POST", path+"/return", "")
+	var returned model.Loan
+	if err := json.Unmarshal(rr.Body.Bytes(), &returned); err != nil || rr.Code != http.StatusOK || returned.ReturnedOn == nil {
+		t.Fatalf("Expected the book returned, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if lentOut() {
+		t.Error("Expected the returned book not to be lent out")
+	}
+	if rr := do("POST", path+"/return", `{"returned_on": "2025-01-01"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 returning a book not lent out, got %d", rr.Code)
+	}
+	rr = do("GET", path, "")
+	var loans []model.Loan
+	if err := json.Unmarshal(rr.Body.Bytes(), &loans); err != nil || len(loans) != 1 || loans[0].ReturnedOn == nil {
+		t.Errorf("Expected the returned loan in the book's history, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
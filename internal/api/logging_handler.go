@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+)
+
+// loggingRequest is the body of PUT /api/admin/logging. Settings left out
+// are kept.
+type loggingRequest struct {
+	Level      *slog.Level            `json:"level"`
+	Format     *logging.Format        `json:"format"`
+	Subsystems *map[string]slog.Level `json:"subsystems"`
+	Sample     *int                   `json:"sample"`
+}
+
+// loggingEnabled responds with 503 Service Unavailable and returns false
+// when the log settings can't be changed.
+func (h *APIHandler) loggingEnabled(w http.ResponseWriter) bool {
+	if h.Logging == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "The log settings can't be changed on this server")
+		return false
+	}
+	return true
+}
+
+// GetLoggingHandler handles GET /api/admin/logging requests and returns the
+// log level, format and subsystem levels in effect.
+func (h *APIHandler) GetLoggingHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.loggingEnabled(w) {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, h.Logging.Settings())
+}
+
+// UpdateLoggingHandler handles PUT /api/admin/logging requests. Expects
+// {"level": "debug", "format": "json", "subsystems": {"db": "warn"},
+// "sample": 10}, any of them optional, and applies them at once until the
+// server restarts or the configuration file changes them. Subsystems replace
+// those in effect.
+func (h *APIHandler) UpdateLoggingHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.loggingEnabled(w) {
+		return
+	}
+	var payload loggingRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	settings := h.Logging.Settings()
+	if payload.Level != nil {
+		settings.Level = *payload.Level
+	}
+	if payload.Format != nil {
+		settings.Format = *payload.Format
+	}
+	if payload.Subsystems != nil {
+		settings.Subsystems = *payload.Subsystems
+	}
+	if payload.Sample != nil {
+		settings.Sample = *payload.Sample
+	}
+	if err := h.Logging.Set(settings); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	slog.InfoContext(r.Context(), "Log settings changed", "level", settings.Level, "format", settings.Format,
+		"subsystems", logging.FormatSubsystems(settings.Subsystems), "sample", settings.Sample)
+	respondWithJSON(w, http.StatusOK, h.Logging.Settings())
+}
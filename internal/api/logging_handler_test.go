@@ -0,0 +1,48 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+)
+
+func TestLoggingHandlers(t *testing.T) {
+	request := func(method, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/api/admin/logging", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := request("GET", ""); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Without log controls: got status %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+
+	testHandler.Logging = logging.NewController()
+	defer func() { testHandler.Logging = nil }()
+	if rr := request("GET", ""); rr.Code != http.StatusOK || rr.Body.String() != `{"level":"INFO","format":"text","subsystems":{},"sample":0}` {
+		t.Errorf("GET: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	// Settings left out are kept
+	rr := request("PUT", `{"level":"debug","subsystems":{"db":"warn"},"sample":5}`)
+	if rr.Code != http.StatusOK || rr.Body.String() != `{"level":"DEBUG","format":"text","subsystems":{"db":"WARN"},"sample":5}` {
+		t.Errorf("PUT: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := request("PUT", `{"format":"json"}`); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"format":"json","subsystems":{"db":"WARN"}`) {
+		t.Errorf("PUT of the format: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	for _, body := range []string{`{"format":"xml"}`, `{"level":"loud"}`, `{"subsystems":{"internal/db":"warn"}}`, `{"sample":-1}`, `{"colour":true}`} {
+		if rr := request("PUT", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s: got status %d, want %d", body, rr.Code, http.StatusBadRequest)
+		}
+	}
+	if s := testHandler.Logging.Settings(); s.Level != slog.LevelDebug || s.Format != logging.JSON || s.Subsystems["db"] != slog.LevelWarn || s.Sample != 5 {
+		t.Errorf("Expected invalid settings to change nothing, got %+v", s)
+	}
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/backfill"
+	"github.com/ericdahl/bookshelf/internal/match"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// FindDuplicatesHandler handles GET /api/books/duplicates requests.
+// Returns groups of books that appear to be the same book, each starting with the
+// earliest entry. The optional min_score query parameter (0-1) overrides the
+// configured review threshold.
+func (h *APIHandler) FindDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	thresholds := h.MatchThresholds
+	if s := r.URL.Query().Get("min_score"); s != "" {
+		score, err := strconv.ParseFloat(s, 64)
+		if err != nil || score <= 0 || score > 1 {
+			respondWithError(w, http.StatusBadRequest, "min_score must be a number between 0 and 1")
+			return
+		}
+		thresholds.Review = score
+		if thresholds.Accept < score {
+			thresholds.Accept = score
+		}
+	}
+
+	books, err := h.Store.GetBooks(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve books: "+err.Error())
+		return
+	}
+
+	index := match.NewIndex(books)
+	index.Thresholds = thresholds
+	respondWithJSON(w, http.StatusOK, newDuplicateGroups(index.Duplicates()))
+}
+
+// GetReviewQueueHandler handles GET /api/review requests.
+// The optional status query parameter selects entries by review state
+// (default "pending"); "all" returns every entry.
+func (h *APIHandler) GetReviewQueueHandler(w http.ResponseWriter, r *http.Request) {
+	status := model.PendingMatchStatus(r.URL.Query().Get("status"))
+	switch {
+	case status == "":
+		status = model.MatchPending
+	case status == "all":
+		status = ""
+	case !status.IsValid():
+		respondWithError(w, http.StatusBadRequest, "Invalid status value. Must be 'pending', 'linked', 'created', 'skipped' or 'all'")
+		return
+	}
+
+	matches, err := h.Store.GetPendingMatches(r.Context(), status)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve review queue: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, matches)
+}
+
+// ResolveReviewHandler handles POST /api/review/{id}/resolve requests.
+// Expects {"action": "link", "book_id": N} to confirm the item is an existing book,
+// {"action": "create"} to add it as a new book, or {"action": "skip"} to dismiss it.
+// Linking a backfill entry fills in the fields the book is missing from it.
+func (h *APIHandler) ResolveReviewHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid review ID")
+		return
+	}
+
+	var payload struct {
+		Action string `json:"action"`
+		BookID *int64 `json:"book_id"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	pending, err := h.Store.GetPendingMatchByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve review entry")
+		return
+	}
+	if pending.Status != model.MatchPending {
+		respondWithError(w, http.StatusConflict, "Review entry is already "+string(pending.Status))
+		return
+	}
+
+	var status model.PendingMatchStatus
+	var bookID *int64
+	switch payload.Action {
+	case "link":
+		if payload.BookID == nil {
+			respondWithError(w, http.StatusBadRequest, "book_id is required to link")
+			return
+		}
+		book, err := h.Store.GetBookByID(r.Context(), *payload.BookID)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		switch pending.Source {
+		case model.SourceTrackerSync:
+			accountID, err := strconv.ParseInt(pending.SourceRef, 10, 64)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Review entry references an invalid sync account")
+				return
+			}
+			// Only onto one of the user's own accounts
+			if _, err := h.Store.GetSyncAccountByID(r.Context(), accountID); err != nil {
+				respondWithStoreError(w, err, "Failed to retrieve sync account")
+				return
+			}
+			// An empty status marks the link as never synced, so the next sync
+			// reconciles it with the account's conflict policy.
+			link := model.SyncLink{AccountID: accountID, BookID: *payload.BookID, RemoteID: pending.Item.RemoteID}
+			if err := h.Store.SaveSyncLink(r.Context(), link); err != nil {
+				respondWithStoreError(w, err, "Failed to link book")
+				return
+			}
+		case model.SourceBackfill:
+			// Only the fields the book is still missing are filled
+			if patch, fields := backfill.Patch(*book, pending.Item); len(fields) > 0 {
+				if err := h.Store.UpdateBook(r.Context(), book.ID, patch); err != nil {
+					respondWithStoreError(w, err, "Failed to fill in book")
+					return
+				}
+			}
+		}
+		status, bookID = model.MatchLinked, payload.BookID
+	case "create":
+		source := model.BookSourceListImport
+		switch pending.Source {
+		case model.SourceListImport:
+		case model.SourceShelfScan:
+			source = model.BookSourceManual // Confirmed one by one, as if added by hand
+		default:
+			respondWithError(w, http.StatusBadRequest, "Only list imports and shelf scans can be added as new books; add the book first and link it instead")
+			return
+		}
+		book := model.Book{
+			Title:         pending.Item.Title,
+			Author:        pending.Item.Author,
+			ISBN:          pending.Item.ISBN,
+			OpenLibraryID: pending.Item.OpenLibraryID,
+			Status:        pending.Item.Status,
+			CoverURL:      pending.Item.CoverURL,
+			PublishYear:   pending.Item.Year,
+			PageCount:     pending.Item.PageCount,
+			Comments:      pending.Item.Notes,
+			Source:        source,
+		}
+		if book.Author == "" {
+			book.Author = "Unknown Author"
+		}
+		if _, err := h.Store.AddBook(r.Context(), &book); err != nil {
+			respondWithStoreError(w, err, "Failed to add book")
+			return
+		}
+		status, bookID = model.MatchCreated, &book.ID
+	case "skip":
+		status = model.MatchSkipped
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid action. Must be 'link', 'create' or 'skip'")
+		return
+	}
+
+	if err := h.Store.ResolvePendingMatch(r.Context(), id, status, bookID); err != nil {
+		respondWithStoreError(w, err, "Failed to resolve review entry")
+		return
+	}
+	resolved, err := h.Store.GetPendingMatchByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve review entry: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, resolved)
+}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/match"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestImportListFuzzyMatching tests that list imports reconcile near-identical books
+func TestImportListFuzzyMatching(t *testing.T) {
+	existing := &model.Book{Title: "Piranesi", Author: "Susanna Clarke", OpenLibraryID: "OLPIRANESI1W", Status: model.StatusRead}
+	if _, err := testStore.AddBook(context.Background(), existing); err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+
+	list := model.SharedList{
+		Format:  model.SharedListFormat,
+		Version: model.SharedListVersion,
+		Name:    "Fuzzy",
+		Books: []model.SharedListItem{
+			{Title: "Piranesi: A Novel", Author: "Clarke, Susanna", OpenLibraryID: "OLPIRANESI2W"},
+			{Title: "Piranesi", OpenLibraryID: "OLPIRANESI3W"},
+		},
+	}
+	jsonData, _ := json.Marshal(list)
+	req, _ := http.NewRequest("POST", "/api/lists/import", bytes.NewBuffer(jsonData))
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var result listImportResult
+	json.Unmarshal(rr.Body.Bytes(), &result)
+	if len(result.Imported) != 0 || len(result.Skipped) != 1 || len(result.Queued) != 1 {
+		t.Fatalf("Expected one skipped and one queued entry, got %+v", result)
+	}
+	if result.Skipped[0].Reason != "already on bookshelf" {
+		t.Errorf("Expected confident match, got %q", result.Skipped[0].Reason)
+	}
+	queued := result.Queued[0]
+	if queued.Item.OpenLibraryID != "OLPIRANESI3W" || len(queued.Candidates) == 0 || queued.Candidates[0].BookID != existing.ID {
+		t.Errorf("Expected title-only match to be queued against %d, got %+v", existing.ID, queued)
+	}
+
+	// Importing the same list again does not queue the entry twice
+	req, _ = http.NewRequest("POST", "/api/lists/import", bytes.NewBuffer(jsonData))
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	json.Unmarshal(rr.Body.Bytes(), &result)
+	if len(result.Queued) != 0 || len(result.Skipped) != 2 {
+		t.Errorf("Expected re-import to skip the queued entry, got %+v", result)
+	}
+}
+
+// TestReviewQueue tests listing and resolving review entries
+func TestReviewQueue(t *testing.T) {
+	ctx := context.Background()
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	existing := &model.Book{Title: "Beloved", Author: "Toni Morrison", OpenLibraryID: "OLBELOVED1W", Status: model.StatusRead}
+	if _, err := testStore.AddBook(ctx, existing); err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	var ids []int64
+	for _, olid := range []string{"OLBELOVED2W", "OLBELOVED3W", "OLBELOVED4W"} {
+		pending := &model.PendingMatch{
+			Source:     model.SourceListImport,
+			SourceRef:  "Review test",
+			ItemKey:    olid,
+			Item:       model.PendingItem{Title: "Beloved", OpenLibraryID: olid, Status: model.StatusWantToRead},
+			Candidates: []model.PendingCandidate{{BookID: existing.ID, Title: existing.Title, Score: 0.85}},
+		}
+		if _, err := testStore.AddPendingMatch(ctx, pending); err != nil {
+			t.Fatalf("AddPendingMatch failed: %v", err)
+		}
+		ids = append(ids, pending.ID)
+	}
+
+	rr := send("GET", "/api/review", "")
+	var queue []model.PendingMatch
+	json.Unmarshal(rr.Body.Bytes(), &queue)
+	if rr.Code != http.StatusOK || len(queue) < 3 {
+		t.Fatalf("Expected pending entries, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Link to the existing book
+	rr = send("POST", "/api/review/"+itoa(ids[0])+"/resolve", `{"action":"link","book_id":`+itoa(existing.ID)+`}`)
+	var resolved model.PendingMatch
+	json.Unmarshal(rr.Body.Bytes(), &resolved)
+	if rr.Code != http.StatusOK || resolved.Status != model.MatchLinked || *resolved.BookID != existing.ID {
+		t.Errorf("Unexpected link response %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := send("POST", "/api/review/"+itoa(ids[0])+"/resolve", `{"action":"skip"}`); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 when resolving twice, got %d", rr.Code)
+	}
+
+	// Add as a new book
+	rr = send("POST", "/api/review/"+itoa(ids[1])+"/resolve", `{"action":"create"}`)
+	json.Unmarshal(rr.Body.Bytes(), &resolved)
+	if rr.Code != http.StatusOK || resolved.Status != model.MatchCreated || resolved.BookID == nil {
+		t.Fatalf("Unexpected create response %d: %s", rr.Code, rr.Body.String())
+	}
+	created, err := testStore.GetBookByID(ctx, *resolved.BookID)
+	if err != nil || created.OpenLibraryID != "OLBELOVED3W" {
+		t.Errorf("Expected new book to be created, got %+v (%v)", created, err)
+	}
+
+	// Dismiss
+	if rr := send("POST", "/api/review/"+itoa(ids[2])+"/resolve", `{"action":"skip"}`); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 for skip, got %d", rr.Code)
+	}
+
+	invalid := map[string]string{
+		`{"action":"link"}`:         "link without book_id",
+		`{"action":"maybe"}`:        "unknown action",
+		`{"action":"link","bad":1}`: "unknown field",
+	}
+	for body, name := range invalid {
+		if rr := send("POST", "/api/review/"+itoa(ids[2])+"/resolve", body); rr.Code != http.StatusBadRequest && rr.Code != http.StatusConflict {
+			t.Errorf("Expected rejection for %s, got %d", name, rr.Code)
+		}
+	}
+	if rr := send("POST", "/api/review/999999/resolve", `{"action":"skip"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown entry, got %d", rr.Code)
+	}
+	if rr := send("GET", "/api/review?status=bogus", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid status, got %d", rr.Code)
+	}
+	rr = send("GET", "/api/review?status=skipped", "")
+	json.Unmarshal(rr.Body.Bytes(), &queue)
+	if len(queue) != 1 || queue[0].ID != ids[2] {
+		t.Errorf("Expected the skipped entry, got %s", rr.Body.String())
+	}
+}
+
+// TestFindDuplicatesHandler tests the GET /api/books/duplicates endpoint
+func TestFindDuplicatesHandler(t *testing.T) {
+	first := &model.Book{Title: "Station Eleven", Author: "Emily St. John Mandel", OpenLibraryID: "OLSTATION1W", Status: model.StatusRead}
+	second := &model.Book{Title: "STATION ELEVEN (Vintage)", Author: "Mandel, Emily St. John", OpenLibraryID: "OLSTATION2W", Status: model.StatusWantToRead}
+	for _, b := range []*model.Book{first, second} {
+		if _, err := testStore.AddBook(context.Background(), b); err != nil {
+			t.Fatalf("Failed to add test book: %v", err)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/api/books/duplicates", nil)
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var groups [][]match.Scored
+	if err := json.Unmarshal(rr.Body.Bytes(), &groups); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	found := false
+	for _, g := range groups {
+		ids := map[int64]bool{}
+		for _, s := range g {
+			ids[s.Book.ID] = true
+		}
+		found = found || (ids[first.ID] && ids[second.ID])
+	}
+	if !found {
+		t.Errorf("Expected %d and %d to be reported as duplicates, got %s", first.ID, second.ID, rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/api/books/duplicates?min_score=2", nil)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid min_score, got %d", rr.Code)
+	}
+}
+
+// TestReviewQueuePerUser tests that users only see and resolve the review
+// entries they queued, and link books only to their own sync accounts.
+func TestReviewQueuePerUser(t *testing.T) {
+	router, store := newAuthRouter(t)
+	alice, bob := loginTestUser(t, router), registerTestUser(t, router, "bob")
+	owned := func(username string) context.Context {
+		user, err := store.GetUserByUsername(context.Background(), username)
+		if err != nil {
+			t.Fatalf("GetUserByUsername failed: %v", err)
+		}
+		return db.WithUser(context.Background(), user.ID)
+	}
+	account := &model.SyncAccount{Provider: model.ProviderGoodreads, RemoteUser: "12345", Enabled: true}
+	if _, err := store.AddSyncAccount(owned("alice"), account); err != nil {
+		t.Fatalf("AddSyncAccount failed: %v", err)
+	}
+	queue := func(username, remoteID string) int64 {
+		pending := &model.PendingMatch{
+			Source:    model.SourceTrackerSync,
+			SourceRef: itoa(account.ID),
+			ItemKey:   remoteID,
+			Item:      model.PendingItem{Title: "Secret Diary", Author: "Alice", RemoteID: remoteID, Status: model.StatusRead},
+		}
+		if _, err := store.AddPendingMatch(owned(username), pending); err != nil {
+			t.Fatalf("AddPendingMatch failed: %v", err)
+		}
+		return pending.ID
+	}
+	alices := queue("alice", "gr-1")
+
+	if rr := authRequest(router, "GET", "/api/v1/review", bob, ""); rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "Secret Diary") {
+		t.Errorf("Expected bob's queue to leave out alice's entry, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := authRequest(router, "POST", "/api/v1/review/"+itoa(alices)+"/resolve", bob, `{"action":"skip"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Resolving another user's entry: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := authRequest(router, "GET", "/api/v1/review", alice, ""); !strings.Contains(rr.Body.String(), "Secret Diary") {
+		t.Errorf("Expected alice's queue to have her entry, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Even an entry of bob's can't link onto alice's account
+	rr := authRequest(router, "POST", "/api/v1/books", bob, `{"title":"Secret Diary","author":"Alice","open_library_id":"OL1M","status":"Read"}`)
+	var book BookResponse
+	json.Unmarshal(rr.Body.Bytes(), &book)
+	bobs := queue("bob", "gr-1")
+	if rr := authRequest(router, "POST", "/api/v1/review/"+itoa(bobs)+"/resolve", bob, `{"action":"link","book_id":`+itoa(book.ID)+`}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Linking onto another user's sync account: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if links, err := store.GetSyncLinks(context.Background(), account.ID); err != nil || len(links) != 0 {
+		t.Errorf("Expected alice's account to have no links, got %+v (%v)", links, err)
+	}
+}
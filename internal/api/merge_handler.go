@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// MergeBookHandler handles POST /api/books/{id}/merge requests, merging the
+// duplicate given as {"duplicate_id": N} into the book. The book keeps its
+// fields and takes those it lacks from the duplicate, along with the
+// duplicate's reading history, tags and tracker links; the duplicate is
+// deleted. Returns the merge, which POST /api/merges/{id}/unmerge undoes.
+func (h *APIHandler) MergeBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+	var payload struct {
+		DuplicateID int64 `json:"duplicate_id"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if payload.DuplicateID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "duplicate_id is required")
+		return
+	}
+
+	merge, err := h.Store.MergeBooks(r.Context(), id, payload.DuplicateID)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to merge books")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, merge)
+}
+
+// GetMergesHandler handles GET /api/merges requests, listing every merge,
+// newest first.
+func (h *APIHandler) GetMergesHandler(w http.ResponseWriter, r *http.Request) {
+	merges, err := h.Store.GetBookMerges(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve merges: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, merges)
+}
+
+// UnmergeHandler handles POST /api/merges/{id}/unmerge requests, restoring
+// the duplicate a merge deleted and giving the kept book back its own fields.
+func (h *APIHandler) UnmergeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid merge ID")
+		return
+	}
+	merge, err := h.Store.UnmergeBooks(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to undo merge")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, merge)
+}
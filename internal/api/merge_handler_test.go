@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestMergeHandlers tests merging a duplicate into a book and undoing it
+func TestMergeHandlers(t *testing.T) {
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	var ids []int64
+	for _, body := range []string{`{"title": "Merge Kept", "author": "A", "open_library_id": "OLMERGE1M"}`,
+		`{"title": "Merge Duplicate", "author": "A", "open_library_id": "OLMERGE2M", "page_count": 320}`} {
+		rr := do("POST", "/api/books", body)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Adding a book: got status %d, body: %s", rr.Code, rr.Body.String())
+		}
+		var book BookResponse
+		json.Unmarshal(rr.Body.Bytes(), &book)
+		ids = append(ids, book.ID)
+	}
+
+	rr := do("POST", "/api/books/"+itoa(ids[0])+"/merge", `{"duplicate_id": `+itoa(ids[1])+`}`)
+	var merge model.BookMerge
+	json.Unmarshal(rr.Body.Bytes(), &merge)
+	if rr.Code != http.StatusOK || merge.ID == 0 || len(merge.Filled) != 1 || merge.Filled[0] != "page_count" {
+		t.Fatalf("Merging: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/books/"+itoa(ids[1]), ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected the duplicate to be gone, got status %d", rr.Code)
+	}
+	rr = do("GET", "/api/merges", "")
+	var merges []model.BookMerge
+	json.Unmarshal(rr.Body.Bytes(), &merges)
+	if rr.Code != http.StatusOK || len(merges) == 0 || merges[0].ID != merge.ID {
+		t.Errorf("Expected the merge first in the list, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do("POST", "/api/merges/"+itoa(merge.ID)+"/unmerge", "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"unmerged_at"`) {
+		t.Fatalf("Unmerging: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/books/"+itoa(ids[1]), ""); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Merge Duplicate") {
+		t.Errorf("Expected the duplicate back, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("POST", "/api/merges/"+itoa(merge.ID)+"/unmerge", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Unmerging twice: got status %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	tests := []struct {
+		name, path, body string
+		want             int
+	}{
+		{"into itself", "/api/books/" + itoa(ids[0]) + "/merge", `{"duplicate_id": ` + itoa(ids[0]) + `}`, http.StatusBadRequest},
+		{"missing duplicate", "/api/books/" + itoa(ids[0]) + "/merge", `{}`, http.StatusBadRequest},
+		{"unknown duplicate", "/api/books/" + itoa(ids[0]) + "/merge", `{"duplicate_id": 999999}`, http.StatusNotFound},
+		{"unknown merge", "/api/merges/999999/unmerge", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if rr := do("POST", tt.path, tt.body); rr.Code != tt.want {
+			t.Errorf("%s: got status %d, want %d (%s)", tt.name, rr.Code, tt.want, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// Requests served, for /metrics, by route template rather than path so that
+// book IDs don't each make a series.
+var (
+	httpRequests = metrics.NewCounter("bookshelf_http_requests_total",
+		"HTTP requests served, by route, method and status code.", "route", "method", "code")
+	httpDuration = metrics.NewHistogram("bookshelf_http_request_duration_seconds",
+		"Duration of HTTP requests, by route and method.", metrics.DefBuckets, "route", "method")
+)
+
+// statusRecorder remembers the status code and size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Flush lets streamed responses through, such as job progress.
+func (w *statusRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController the underlying writer.
+func (w *statusRecorder) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// routeLabel returns the route r matched, as its OpenAPI path for API routes
+// so both versions' prefixes count together, or "none".
+func routeLabel(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "none"
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return "none"
+	}
+	return specPath(template)
+}
+
+// MetricsMiddleware counts and times the requests it serves for /metrics.
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		route := routeLabel(r)
+		httpRequests.Inc(route, r.Method, strconv.Itoa(recorder.status))
+		httpDuration.Observe(time.Since(start).Seconds(), route, r.Method)
+	})
+}
+
+// booksGauge reads the number of books on each shelf, of every library, when
+// metrics are scraped.
+func (h *APIHandler) booksGauge() metrics.Collector {
+	return metrics.NewGaugeFunc("bookshelf_books", "Books on the bookshelf, by status.", func(ctx context.Context) ([]metrics.Sample, error) {
+		counts, err := h.Store.CountBooksByStatus(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var samples []metrics.Sample
+		for _, status := range []model.BookStatus{model.StatusWantToRead, model.StatusCurrentlyReading, model.StatusRead} {
+			samples = append(samples, metrics.Sample{LabelValues: []string{string(status)}, Value: float64(counts[status])})
+		}
+		return samples, nil
+	}, "status")
+}
+
+// MetricsHandler handles GET /metrics requests, serving the server's metrics
+// to Prometheus: requests served, database queries, outbound requests to
+// providers and the books on each shelf.
+func (h *APIHandler) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	metrics.Handler(h.booksGauge()).ServeHTTP(w, r)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	router, _ := newAuthRouter(t)
+	token := loginTestUser(t, router)
+	for id, status := range map[string]model.BookStatus{"OL1M": model.StatusRead, "OL2M": model.StatusWantToRead} {
+		body := `{"title":"Dune","author":"Frank Herbert","open_library_id":"` + id + `","status":"` + string(status) + `"}`
+		if rr := authRequest(router, "POST", "/api/v1/books", token, body); rr.Code != http.StatusCreated {
+			t.Fatalf("Adding a book: got status %d, body: %s", rr.Code, rr.Body.String())
+		}
+	}
+	authRequest(router, "GET", "/api/v1/books/12345", token, "")
+
+	// Scrapers need no credentials, even once there are users
+	rr := authRequest(router, "GET", "/metrics", "", "")
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("GET /metrics: got status %d, headers %v", rr.Code, rr.Header())
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		"# TYPE bookshelf_http_requests_total counter\n",
+		`bookshelf_http_requests_total{route="/books",method="POST",code="201"} `,
+		`bookshelf_http_requests_total{route="/books/{id}",method="GET",code="404"} `,
+		`bookshelf_http_request_duration_seconds_bucket{route="/books",method="POST",le="+Inf"} `,
+		"# TYPE bookshelf_books gauge\n",
+		`bookshelf_books{status="Read"} 1` + "\n",
+		`bookshelf_books{status="Currently Reading"} 0` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in metrics:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// parsePrefixes parses a comma-separated list of IP addresses and CIDR
+// prefixes, such as "10.0.0.1, 192.168.0.0/16", an address standing for
+// itself alone.
+func parsePrefixes(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(s)
+			if addrErr != nil {
+				return nil, fmt.Errorf("%q is not an address or CIDR prefix", s)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// remoteAddr returns the address of the connection r came over.
+func remoteAddr(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	return addr.Unmap(), err == nil
+}
+
+// contains reports whether addr is in one of prefixes.
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// sentFrom reports whether r came from an address of prefixes. That is the
+// address of the connection, which behind a reverse proxy is the proxy's.
+func sentFrom(r *http.Request, prefixes []netip.Prefix) bool {
+	addr, ok := remoteAddr(r)
+	return ok && contains(prefixes, addr)
+}
+
+// clientAddr returns the address of the client that sent r: that of the
+// connection or, when it is one of the trusted proxies, the last address of
+// X-Forwarded-For that isn't, since clients can put anything before those
+// the proxies add.
+func clientAddr(r *http.Request, trusted []netip.Prefix) string {
+	addr, ok := remoteAddr(r)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !contains(trusted, addr) {
+		return addr.String()
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		if addr = hop.Unmap(); !contains(trusted, addr) {
+			break
+		}
+	}
+	return addr.String()
+}
+
+// LocalSocket serves h on a unix socket, whose requests have no address. They
+// are given the loopback one, since only processes on the host can connect,
+// so trusted proxies and admin networks treat them as local.
+func LocalSocket(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		local := *r
+		local.RemoteAddr = "127.0.0.1:0"
+		h.ServeHTTP(w, &local)
+	})
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// notePayload is the body of POST /api/books/{id}/notes and
+// PUT /api/books/{id}/notes/{noteID}.
+type notePayload struct {
+	Kind     model.NoteKind `json:"kind"`
+	Body     string         `json:"body"`
+	Page     *int           `json:"page"`
+	Location *string        `json:"location"`
+}
+
+// decodeNote reads a note of the book in the URL from the request body.
+// It responds with the error and returns nil when the request is invalid.
+func decodeNote(w http.ResponseWriter, r *http.Request) *model.Note {
+	bookID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return nil
+	}
+	var payload notePayload
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return nil
+	}
+	return &model.Note{BookID: bookID, Kind: payload.Kind, Body: payload.Body, Page: payload.Page, Location: payload.Location}
+}
+
+// GetBookNotesHandler handles GET /api/books/{id}/notes requests and returns
+// the book's notes and highlights, oldest first.
+func (h *APIHandler) GetBookNotesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	notes, err := h.Store.GetNotes(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve notes")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, notes)
+}
+
+// AddBookNoteHandler handles POST /api/books/{id}/notes requests, taking a
+// note on a book. Expects {"body": "...", "kind": "highlight", "page": 42,
+// "location": "Chapter 3"}; all but body are optional.
+func (h *APIHandler) AddBookNoteHandler(w http.ResponseWriter, r *http.Request) {
+	note := decodeNote(w, r)
+	if note == nil {
+		return
+	}
+	if err := h.Store.AddNote(r.Context(), note); err != nil {
+		respondWithStoreError(w, err, "Failed to add note")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, note)
+}
+
+// UpdateBookNoteHandler handles PUT /api/books/{id}/notes/{noteID} requests,
+// replacing a note with the body of the request, as for AddBookNoteHandler.
+func (h *APIHandler) UpdateBookNoteHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := strconv.ParseInt(mux.Vars(r)["noteID"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid note ID")
+		return
+	}
+	note := decodeNote(w, r)
+	if note == nil {
+		return
+	}
+	note.ID = noteID
+	if err := h.Store.UpdateNote(r.Context(), note); err != nil {
+		respondWithStoreError(w, err, "Failed to update note")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, note)
+}
+
+// DeleteBookNoteHandler handles DELETE /api/books/{id}/notes/{noteID} requests.
+func (h *APIHandler) DeleteBookNoteHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	noteID, err := strconv.ParseInt(vars["noteID"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid note ID")
+		return
+	}
+	if err := h.Store.DeleteNote(r.Context(), id, noteID); err != nil {
+		respondWithStoreError(w, err, "Failed to delete note")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestBookNoteHandlers tests taking, listing, editing and deleting notes on a book
+func TestBookNoteHandlers(t *testing.T) {
+	book := createTestBook(model.StatusCurrentlyReading, "Notes")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+	path := "/api/books/" + itoa(id) + "/notes"
+
+	rr := do("POST", path, `{"body": "It was a pleasure to burn.", "kind": "highlight", "page": 1}`)
+	var note model.Note
+	if err := json.Unmarshal(rr.Body.Bytes(), &note); err != nil || rr.Code != http.StatusCreated || note.ID == 0 || note.BookID != id {
+		t.Fatalf("Expected the note created, got %d: %s", rr.Code, rr.Body.String())
+	}
+	for _, body := range []string{`{}`, `{"body": " "}`, `{"body": "x", "kind": "quote"}`, `{"body": "x", "page": 0}`, `{"body": "x", "chapter": 3}`} {
+		if rr := do("POST", path, body); rr.Code != http.StatusBadRequest {
+			t.Errorf("POST %s: expected 400, got %d: %s", body, rr.Code, rr.Body.String())
+		}
+	}
+	if rr := do("POST", "/api/books/999999/notes", `{"body": "x"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing book, got %d", rr.Code)
+	}
+
+	rr = do("PUT", path+"/"+itoa(note.ID), `{"body": "Burning was a pleasure.", "location": "Part One"}`)
+	var updated model.Note
+	if err := json.Unmarshal(rr.Body.Bytes(), &updated); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected the note updated, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if updated.Kind != model.NoteKindNote || updated.Page != nil || updated.Location == nil || *updated.Location != "Part One" {
+		t.Errorf("Expected the note replaced, got %+v", updated)
+	}
+	if rr := do("PUT", path+"/999999", `{"body": "x"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing note, got %d", rr.Code)
+	}
+
+	rr = do("GET", path, "")
+	var notes []model.Note
+	if err := json.Unmarshal(rr.Body.Bytes(), &notes); err != nil || len(notes) != 1 || notes[0].Body != "Burning was a pleasure." {
+		t.Errorf("Expected the updated note listed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = do("GET", "/api/books?status=currently-reading&fields=note_count", "")
+	var list []map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Listing books: got %d: %s", rr.Code, rr.Body.String())
+	}
+	found := false
+	for _, b := range list {
+		if b["id"] == float64(id) {
+			found = b["note_count"] == float64(1)
+		}
+	}
+	if !found {
+		t.Errorf("Expected the book listed with 1 note, got %s", rr.Body.String())
+	}
+
+	if rr := do("DELETE", path+"/"+itoa(note.ID), ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 deleting the note, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("DELETE", path+"/"+itoa(note.ID), ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting the note again, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+// GetOnThisDayHandler handles GET /api/onthisday requests. It returns the
+// books finished or added on today's date in earlier years, or on the month
+// and day of ?date=YYYY-MM-DD. Days are in UTC.
+func (h *APIHandler) GetOnThisDayHandler(w http.ResponseWriter, r *http.Request) {
+	day := time.Now().UTC()
+	if raw := r.URL.Query().Get("date"); raw != "" {
+		var err error
+		if day, err = time.Parse("2006-01-02", raw); err != nil {
+			respondWithError(w, http.StatusBadRequest, "date must be formatted as YYYY-MM-DD")
+			return
+		}
+	}
+	memories, err := h.Store.GetOnThisDay(r.Context(), day)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve books: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, OnThisDayResponse{
+		Date:     day.Format("2006-01-02"),
+		Finished: newMemoryResponses(memories.Finished),
+		Added:    newMemoryResponses(memories.Added),
+	})
+}
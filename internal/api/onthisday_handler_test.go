@@ -0,0 +1,48 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestGetOnThisDayHandler tests looking up a book finished on the same day
+// of an earlier year.
+func TestGetOnThisDayHandler(t *testing.T) {
+	book := createTestBook(model.StatusRead, "On This Day")
+	finished := time.Date(2001, 7, 4, 18, 0, 0, 0, time.UTC)
+	book.DateFinished = &finished
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/api/onthisday?date=2011-07-04")
+	var resp OnThisDayResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if rr.Code != http.StatusOK || resp.Date != "2011-07-04" || len(resp.Finished) != 1 || resp.Finished[0].Book.ID != id ||
+		resp.Finished[0].YearsAgo != 10 || resp.Added == nil {
+		t.Errorf("Expected the book finished ten years before, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := get("/api/onthisday?date=2011-07-05"); rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &resp) != nil || len(resp.Finished) != 0 {
+		t.Errorf("Expected nothing on another day, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := get("/api/onthisday?date=2011-02-30"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid date: got status %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+}
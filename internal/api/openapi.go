@@ -0,0 +1,513 @@
+package api
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// openAPIDocument describes the API routes, their parameters and request bodies.
+// Requests are validated against it before they reach a handler.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// maxValidatedBody caps the request bodies read for validation.
+const maxValidatedBody = 1 * 1024 * 1024
+
+// schema is the subset of an OpenAPI 3.0 schema object that requests are
+// validated against.
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Enum                 []interface{}      `json:"enum"`
+	Nullable             bool               `json:"nullable"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum"`
+	MinLength            *int               `json:"minLength"`
+	Pattern              string             `json:"pattern"`
+
+	pattern *regexp.Regexp
+}
+
+type parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *schema `json:"schema"`
+}
+
+type operation struct {
+	Parameters  []parameter `json:"parameters"`
+	RequestBody *struct {
+		Required bool `json:"required"`
+		Content  map[string]struct {
+			Schema *schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+// pathItem holds the operations of a path, keyed by HTTP method.
+type pathItem struct {
+	Parameters []parameter
+	Operations map[string]*operation
+}
+
+func (p *pathItem) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	p.Operations = make(map[string]*operation)
+	for key, value := range raw {
+		if key == "parameters" {
+			if err := json.Unmarshal(value, &p.Parameters); err != nil {
+				return err
+			}
+			continue
+		}
+		var op operation
+		if err := json.Unmarshal(value, &op); err != nil {
+			return fmt.Errorf("operation %s: %w", key, err)
+		}
+		p.Operations[strings.ToUpper(key)] = &op
+	}
+	return nil
+}
+
+type openAPISpec struct {
+	Paths      map[string]*pathItem `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// FieldError describes one way in which a request does not match the API description.
+type FieldError struct {
+	Location string `json:"location"` // "query", "path" or "body"
+	Field    string `json:"field"`    // Parameter name or dotted path into the body; empty for the body itself
+	Message  string `json:"message"`
+}
+
+// RequestValidator checks requests against the OpenAPI document.
+type RequestValidator struct {
+	spec *openAPISpec
+}
+
+// NewRequestValidator parses an OpenAPI document and resolves its schema references.
+func NewRequestValidator(document []byte) (*RequestValidator, error) {
+	var spec openAPISpec
+	if err := json.Unmarshal(document, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	v := &RequestValidator{spec: &spec}
+
+	var resolve func(s *schema, depth int) (*schema, error)
+	resolve = func(s *schema, depth int) (*schema, error) {
+		if s == nil {
+			return nil, nil
+		}
+		if depth > 32 {
+			return nil, errors.New("schema references nest too deeply")
+		}
+		if s.Ref != "" {
+			name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+			target, ok := spec.Components.Schemas[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown schema reference %s", s.Ref)
+			}
+			return resolve(target, depth+1)
+		}
+		if s.Pattern != "" && s.pattern == nil {
+			re, err := regexp.Compile(s.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+			}
+			s.pattern = re
+		}
+		for name, prop := range s.Properties {
+			resolved, err := resolve(prop, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			s.Properties[name] = resolved
+		}
+		items, err := resolve(s.Items, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		s.Items = items
+		return s, nil
+	}
+
+	resolveParams := func(params []parameter) error {
+		for i := range params {
+			resolved, err := resolve(params[i].Schema, 0)
+			if err != nil {
+				return fmt.Errorf("parameter %s: %w", params[i].Name, err)
+			}
+			params[i].Schema = resolved
+		}
+		return nil
+	}
+	for path, item := range spec.Paths {
+		if err := resolveParams(item.Parameters); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for method, op := range item.Operations {
+			if err := resolveParams(op.Parameters); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+			if op.RequestBody == nil {
+				continue
+			}
+			for contentType, media := range op.RequestBody.Content {
+				resolved, err := resolve(media.Schema, 0)
+				if err != nil {
+					return nil, fmt.Errorf("%s %s request body: %w", method, path, err)
+				}
+				media.Schema = resolved
+				op.RequestBody.Content[contentType] = media
+			}
+		}
+	}
+	return v, nil
+}
+
+// defaultValidator validates requests against the embedded API description.
+var defaultValidator = mustNewRequestValidator(openAPIDocument)
+
+func mustNewRequestValidator(document []byte) *RequestValidator {
+	v, err := NewRequestValidator(document)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// specPath converts a mux route template such as /api/v1/books/{id:[0-9]+}
+// to the matching OpenAPI path, /books/{id}.
+func specPath(template string) string {
+	template = strings.TrimPrefix(template, "/api")
+	template = strings.TrimPrefix(template, "/"+APIVersion)
+
+	var b strings.Builder
+	inVar, inPattern, nested := false, false, 0
+	for _, c := range template {
+		switch {
+		case !inVar:
+			inVar = c == '{'
+			b.WriteRune(c)
+		case !inPattern && c == ':':
+			inPattern, nested = true, 0 // Skip the variable's pattern up to its closing brace
+		case !inPattern:
+			inVar = c != '}'
+			b.WriteRune(c)
+		case c == '{':
+			nested++
+		case c == '}' && nested > 0:
+			nested--
+		case c == '}':
+			inVar, inPattern = false, false
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// lookup returns the operation and path-level parameters for a request, or
+// nil when the route is not described.
+func (v *RequestValidator) lookup(r *http.Request) (*operation, []parameter) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return nil, nil
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return nil, nil
+	}
+	item, ok := v.spec.Paths[specPath(template)]
+	if !ok {
+		return nil, nil
+	}
+	op, ok := item.Operations[r.Method]
+	if !ok {
+		return nil, nil
+	}
+	return op, item.Parameters
+}
+
+// Validate checks a request's parameters and JSON body. The body is read and
+// replaced so that handlers can decode it again.
+func (v *RequestValidator) Validate(w http.ResponseWriter, r *http.Request) ([]FieldError, error) {
+	op, pathParams := v.lookup(r)
+	if op == nil {
+		return nil, nil
+	}
+
+	var errs []FieldError
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+	for _, p := range append(append([]parameter{}, pathParams...), op.Parameters...) {
+		var raw string
+		var present bool
+		switch p.In {
+		case "path":
+			raw, present = vars[p.Name]
+		case "query":
+			present = query.Has(p.Name)
+			raw = query.Get(p.Name)
+		default:
+			continue
+		}
+		if !present || raw == "" {
+			if p.Required {
+				errs = append(errs, FieldError{p.In, p.Name, "is required"})
+			}
+			continue
+		}
+		errs = append(errs, validateParameter(p, raw)...)
+	}
+
+	if op.RequestBody != nil {
+		media, ok := op.RequestBody.Content["application/json"]
+		if ok && media.Schema != nil {
+			bodyErrs, err := validateBody(w, r, media.Schema, op.RequestBody.Required)
+			if err != nil {
+				return nil, err
+			}
+			errs = append(errs, bodyErrs...)
+		}
+	}
+	return errs, nil
+}
+
+// validateParameter converts a raw query or path value to the parameter's
+// type and checks it against the schema.
+func validateParameter(p parameter, raw string) []FieldError {
+	s := p.Schema
+	if s == nil {
+		return nil
+	}
+	var value interface{} = raw
+	switch s.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(raw, 10, 64); err != nil {
+			return []FieldError{{p.In, p.Name, "must be an integer"}}
+		}
+		value = json.Number(raw)
+	case "number":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return []FieldError{{p.In, p.Name, "must be a number"}}
+		}
+		value = json.Number(raw)
+	case "boolean":
+		if raw != "true" && raw != "false" {
+			return []FieldError{{p.In, p.Name, "must be true or false"}}
+		}
+		value = raw == "true"
+	}
+	var errs []FieldError
+	validateValue(s, value, p.In, p.Name, &errs)
+	return errs
+}
+
+func validateBody(w http.ResponseWriter, r *http.Request, s *schema, required bool) ([]FieldError, error) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxValidatedBody))
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if required {
+			return []FieldError{{"body", "", "is required"}}, nil
+		}
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []FieldError{{"body", "", "must be valid JSON"}}, nil
+	}
+	var errs []FieldError
+	validateValue(s, value, "body", "", &errs)
+	return errs, nil
+}
+
+// validateValue checks a decoded JSON value against s, appending any problems to errs.
+func validateValue(s *schema, value interface{}, location, field string, errs *[]FieldError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{location, field, fmt.Sprintf(format, args...)})
+	}
+	if value == nil {
+		if !s.Nullable {
+			fail("must not be null")
+		}
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				*errs = append(*errs, FieldError{location, joinField(field, name), "is required"})
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					*errs = append(*errs, FieldError{location, joinField(field, name), "is not a recognized field"})
+				}
+				continue
+			}
+			validateValue(prop, obj[name], location, joinField(field, name), errs)
+		}
+		return
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				validateValue(s.Items, item, location, fmt.Sprintf("%s[%d]", field, i), errs)
+			}
+		}
+		return
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be a boolean")
+		}
+		return
+	case "integer", "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			fail("must be %s", map[string]string{"integer": "an integer", "number": "a number"}[s.Type])
+			return
+		}
+		if s.Type == "integer" {
+			if _, err := n.Int64(); err != nil {
+				fail("must be an integer")
+				return
+			}
+		}
+		f, _ := n.Float64()
+		switch {
+		case s.Minimum != nil && s.ExclusiveMinimum && f <= *s.Minimum:
+			fail("must be greater than %v", *s.Minimum)
+		case s.Minimum != nil && f < *s.Minimum:
+			fail("must be at least %v", *s.Minimum)
+		case s.Maximum != nil && f > *s.Maximum:
+			fail("must be at most %v", *s.Maximum)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		if s.MinLength != nil && len(str) < *s.MinLength {
+			if *s.MinLength == 1 {
+				fail("must not be empty")
+			} else {
+				fail("must be at least %d characters", *s.MinLength)
+			}
+			return
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				fail("must be an RFC 3339 date-time (e.g., 2025-01-02T15:04:05Z)")
+				return
+			}
+		}
+		if s.Format == "date" {
+			if _, err := time.Parse("2006-01-02", str); err != nil {
+				fail("must be a date formatted as YYYY-MM-DD")
+				return
+			}
+		}
+		if s.pattern != nil && !s.pattern.MatchString(str) {
+			fail("must match %s", s.Pattern)
+			return
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		for _, allowed := range s.Enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				return
+			}
+		}
+		options := make([]string, len(s.Enum))
+		for i, allowed := range s.Enum {
+			options[i] = fmt.Sprintf("%q", fmt.Sprint(allowed))
+		}
+		fail("must be one of %s", strings.Join(options, ", "))
+	}
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// ValidationMiddleware rejects requests that do not match the API description
+// with a 400 listing every problem, so handlers only see well-formed input.
+func ValidationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		errs, err := defaultValidator.Validate(w, r)
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body must not be larger than %d bytes", maxBytesError.Limit))
+			} else {
+				respondWithError(w, http.StatusBadRequest, "Failed to read request body: "+err.Error())
+			}
+			return
+		}
+		if len(errs) > 0 {
+			slog.InfoContext(r.Context(), "Request validation failed", "method", r.Method, "uri", r.RequestURI, "errors", len(errs))
+			respondWithJSON(w, http.StatusBadRequest, struct {
+				Error  string       `json:"error"`
+				Errors []FieldError `json:"errors"`
+			}{"Request validation failed", errs})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
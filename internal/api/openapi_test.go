@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestSpecPath(t *testing.T) {
+	for template, want := range map[string]string{
+		"/api/v1/books":                      "/books",
+		"/api/books/{id:[0-9]+}/details":     "/books/{id}/details",
+		"/api/v1/covers/{hash:[0-9a-f]{64}}": "/covers/{hash}",
+		"/api/v1/review/{id}/resolve":        "/review/{id}/resolve",
+	} {
+		if got := specPath(template); got != want {
+			t.Errorf("specPath(%q) = %q, want %q", template, got, want)
+		}
+	}
+}
+
+// Every API route must be described, so that none of them skips validation.
+func TestSpecDescribesEveryRoute(t *testing.T) {
+	router := SetupRouter(testHandler, t.TempDir())
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(template, "/api/"+APIVersion+"/") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		item, ok := defaultValidator.spec.Paths[specPath(template)]
+		if !ok {
+			t.Errorf("Route %s is missing from the OpenAPI document", template)
+			return nil
+		}
+		for _, method := range methods {
+			if item.Operations[method] == nil {
+				t.Errorf("Operation %s %s is missing from the OpenAPI document", method, template)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+
+	// The book request schema must accept exactly the fields of BookRequest
+	var want, got []string
+	for name := range jsonFieldNames(reflect.TypeOf(BookRequest{})) {
+		want = append(want, name)
+	}
+	for name := range defaultValidator.spec.Components.Schemas["BookInput"].Properties {
+		got = append(got, name)
+	}
+	sort.Strings(want)
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BookInput properties %v do not match BookRequest fields %v", got, want)
+	}
+}
+
+func TestValidationMiddleware(t *testing.T) {
+	// Changes need credentials before they are validated
+	router, _ := newAuthRouter(t)
+	token := loginTestUser(t, router)
+
+	type validationResponse struct {
+		Error  string       `json:"error"`
+		Errors []FieldError `json:"errors"`
+	}
+	for _, tc := range []struct {
+		method, path, body string
+		want               []FieldError
+	}{
+		{"POST", "/api/v1/books", `{"title": "", "rating": 11, "status": "Done", "shelf": "top", "series_index": 1.5}`, []FieldError{
+			{"body", "open_library_id", "is required"},
+			{"body", "rating", "must be at most 10"},
+			{"body", "series_index", "must be an integer"},
+			{"body", "shelf", "is not a recognized field"},
+			{"body", "status", `must be one of "Want to Read", "Currently Reading", "Read"`},
+			{"body", "title", "must not be empty"},
+		}},
+		{"POST", "/api/books", ``, []FieldError{{"body", "", "is required"}}},
+		{"POST", "/api/v1/books", `{"title": `, []FieldError{{"body", "", "must be valid JSON"}}},
+		{"PUT", "/api/v1/books/1", `["Read"]`, []FieldError{{"body", "", "must be an object"}}},
+		{"PUT", "/api/v1/books/1/sharing", `{"publish_opt_out": "yes"}`, []FieldError{{"body", "publish_opt_out", "must be a boolean"}}},
+		{"PUT", "/api/v1/books/1/type", `{"type": null}`, []FieldError{{"body", "type", "must not be null"}}},
+		{"GET", "/api/v1/books?limit=abc&sort=isbn", ``, []FieldError{
+			{"query", "limit", "must be an integer"},
+			{"query", "sort", `must be one of "title", "author", "rating", "added"`},
+		}},
+		{"GET", "/api/v1/books/duplicates?min_score=0", ``, []FieldError{{"query", "min_score", "must be greater than 0"}}},
+		{"GET", "/api/v1/export?since=yesterday", ``, []FieldError{{"query", "since", "must be an RFC 3339 date-time (e.g., 2025-01-02T15:04:05Z)"}}},
+		{"GET", "/api/v1/onthisday?date=10/14", ``, []FieldError{{"query", "date", "must be a date formatted as YYYY-MM-DD"}}},
+		{"GET", "/api/v1/books/search", ``, []FieldError{{"query", "q", "is required"}}},
+	} {
+		req, _ := http.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: got status %d, want %d", tc.method, tc.path, rr.Code, http.StatusBadRequest)
+			continue
+		}
+		var resp validationResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s %s: could not unmarshal response: %v", tc.method, tc.path, err)
+		}
+		if resp.Error != "Request validation failed" || !reflect.DeepEqual(resp.Errors, tc.want) {
+			t.Errorf("%s %s: got %+v, want %+v", tc.method, tc.path, resp.Errors, tc.want)
+		}
+	}
+
+	// Valid requests reach the handler with their body intact
+	req, _ := http.NewRequest("POST", "/api/v1/books", bytes.NewBufferString(`{"title": "Valid", "author": "A", "open_library_id": "OLVALID1M", "cover_url": null}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Valid POST: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	var created struct{ ID int64 }
+	json.Unmarshal(rr.Body.Bytes(), &created)
+
+	req, _ = http.NewRequest("GET", "/api/v1/books/"+itoa(created.ID)+"?fields=title", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"title":"Valid"`) {
+		t.Errorf("Valid GET: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+}
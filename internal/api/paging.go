@@ -0,0 +1,197 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// maxPageSize caps ?limit= on list endpoints.
+const maxPageSize = 1000
+
+// listParams are the query parameters read by parseListOptions.
+var listParams = []string{"limit", "offset", "sort", "order", "status", "type", "author", "author_id", "min_rating", "minRating", "tag", "collection", "reread", "source", "added_after", "added_before", "missing", "series", "favorite", "favorites_first", "label", "archived"}
+
+// parseListOptions reads the filtering, paging and ordering query parameters
+// of a book list request. paged is false when none of them are present.
+func parseListOptions(r *http.Request) (opts db.ListOptions, paged bool, err error) {
+	q := r.URL.Query()
+	for _, key := range listParams {
+		if q.Has(key) {
+			paged = true
+		}
+	}
+	if !paged {
+		return opts, false, nil
+	}
+
+	if v := q.Get("limit"); v != "" {
+		opts.Limit, err = strconv.Atoi(v)
+		if err != nil || opts.Limit < 1 || opts.Limit > maxPageSize {
+			return opts, true, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		opts.Offset, err = strconv.Atoi(v)
+		if err != nil || opts.Offset < 0 {
+			return opts, true, fmt.Errorf("offset must be a non-negative integer")
+		}
+	}
+	if v := q.Get("sort"); v != "" {
+		opts.Sort = db.SortField(v)
+		if !opts.Sort.IsValid() {
+			return opts, true, fmt.Errorf("invalid sort field %q (use title, author, rating or added)", v)
+		}
+	}
+	if v := q.Get("favorites_first"); v != "" {
+		opts.FavoritesFirst, err = strconv.ParseBool(v)
+		if err != nil {
+			return opts, true, fmt.Errorf("favorites_first must be true or false")
+		}
+	}
+	switch strings.ToLower(q.Get("order")) {
+	case "", "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		return opts, true, fmt.Errorf("order must be asc or desc")
+	}
+
+	if v := q.Get("status"); v != "" {
+		opts.Filter.Status = parseStatus(v)
+		if opts.Filter.Status == "" {
+			return opts, true, fmt.Errorf("invalid status %q (use want-to-read, currently-reading or read)", v)
+		}
+	}
+	if v := q.Get("type"); v != "" {
+		opts.Filter.Type = model.BookType(strings.ToLower(v))
+		if !opts.Filter.Type.IsValid() {
+			return opts, true, fmt.Errorf("invalid type %q (use book or audiobook)", v)
+		}
+	}
+	opts.Filter.Author = strings.TrimSpace(q.Get("author"))
+	if v := q.Get("author_id"); v != "" {
+		opts.Filter.AuthorID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || opts.Filter.AuthorID < 1 {
+			return opts, true, fmt.Errorf("author_id must be an author ID")
+		}
+	}
+	opts.Filter.Tag = q.Get("tag")
+	if v := q.Get("collection"); v != "" {
+		opts.Filter.Collection, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || opts.Filter.Collection < 1 {
+			return opts, true, fmt.Errorf("collection must be a collection ID")
+		}
+	}
+	minRating := q.Get("min_rating")
+	if minRating == "" {
+		minRating = q.Get("minRating")
+	}
+	if minRating != "" {
+		opts.Filter.MinRating, err = strconv.Atoi(minRating)
+		if err != nil || opts.Filter.MinRating < 1 || opts.Filter.MinRating > 10 {
+			return opts, true, fmt.Errorf("min_rating must be between 1 and 10")
+		}
+	}
+	if v := q.Get("reread"); v != "" {
+		reread, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, true, fmt.Errorf("reread must be true or false")
+		}
+		opts.Filter.Reread = &reread
+	}
+	if v := q.Get("source"); v != "" {
+		opts.Filter.Source = model.BookSource(strings.ToLower(v))
+		if !opts.Filter.Source.IsValid() {
+			return opts, true, fmt.Errorf("invalid source %q (use manual, api, isbn_scan, goodreads_import or list_import)", v)
+		}
+	}
+	for key, bound := range map[string]**time.Time{"added_after": &opts.Filter.AddedAfter, "added_before": &opts.Filter.AddedBefore} {
+		if v := q.Get(key); v != "" {
+			t, err := parseTimeBound(v)
+			if err != nil {
+				return opts, true, fmt.Errorf("%s must be a date (YYYY-MM-DD) or an RFC 3339 time", key)
+			}
+			*bound = &t
+		}
+	}
+	if v := q.Get("missing"); v != "" {
+		if _, ok := db.MissingFields[v]; !ok {
+			return opts, true, fmt.Errorf("invalid missing field %q (use isbn, cover, page_count or rating)", v)
+		}
+		opts.Filter.Missing = v
+	}
+	opts.Filter.Series = strings.TrimSpace(q.Get("series"))
+	if v := q.Get("label"); v != "" {
+		opts.Filter.Label = model.NormalizeLabel(v)
+		if err := model.ValidateLabel(opts.Filter.Label); err != nil {
+			return opts, true, err
+		}
+	}
+	if v := q.Get("favorite"); v != "" {
+		favorite, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, true, fmt.Errorf("favorite must be true or false")
+		}
+		opts.Filter.Favorite = &favorite
+	}
+	switch strings.ToLower(q.Get("archived")) {
+	case "", "false":
+	case "true":
+		opts.Filter.Archived = db.ArchivedOnly
+	case "all":
+		opts.Filter.Archived = db.ArchivedIncluded
+	default:
+		return opts, true, fmt.Errorf("archived must be true, false or all")
+	}
+	return opts, true, nil
+}
+
+// parseTimeBound reads a time given either in full (RFC 3339) or as a date,
+// which stands for the start of that day in UTC.
+func parseTimeBound(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+// parseStatus accepts a shelf either by name ("Currently Reading") or as a
+// slug in any case ("currently-reading", "currently_reading"). It returns ""
+// for anything else.
+func parseStatus(v string) model.BookStatus {
+	normalized := strings.NewReplacer("-", " ", "_", " ").Replace(strings.ToLower(strings.TrimSpace(v)))
+	for _, status := range []model.BookStatus{model.StatusWantToRead, model.StatusCurrentlyReading, model.StatusRead} {
+		if strings.ToLower(string(status)) == normalized {
+			return status
+		}
+	}
+	return ""
+}
+
+// setPageHeaders reports the total count and links to the previous and next
+// pages, keeping every other query parameter of the request.
+func setPageHeaders(w http.ResponseWriter, r *http.Request, opts db.ListOptions, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if opts.Limit == 0 {
+		return
+	}
+	link := func(offset int, rel string) {
+		u := *r.URL
+		q := u.Query()
+		q.Set("offset", strconv.Itoa(offset))
+		u.RawQuery = q.Encode()
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=%q", u.RequestURI(), rel))
+	}
+	if opts.Offset+opts.Limit < total {
+		link(opts.Offset+opts.Limit, "next")
+	}
+	if opts.Offset > 0 {
+		link(max(0, opts.Offset-opts.Limit), "prev")
+	}
+}
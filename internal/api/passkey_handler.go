@@ -0,0 +1,300 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/webauthn"
+	"github.com/gorilla/mux"
+)
+
+// passkeyRPName is the name browsers show for the site passkeys are for.
+const passkeyRPName = "Bookshelf"
+
+// relyingParty returns the site passkeys are created for and used with: the
+// configured PasskeyOrigin, or else the origin the request was sent to.
+func (h *APIHandler) relyingParty(r *http.Request) (webauthn.RelyingParty, error) {
+	origin := h.PasskeyOrigin
+	if origin == "" {
+		origin = baseURL(r)
+	}
+	return webauthn.NewRelyingParty(origin, passkeyRPName)
+}
+
+// userHandle is the WebAuthn user handle of a user: their ID, which unlike
+// their username never changes.
+func userHandle(userID int64) []byte {
+	return []byte(strconv.FormatInt(userID, 10))
+}
+
+// newPasskeyChallenge stores a challenge for a ceremony of userID, responding
+// with an error and returning "" when it can't.
+func (h *APIHandler) newPasskeyChallenge(w http.ResponseWriter, r *http.Request, userID *int64, ceremony db.PasskeyCeremony) string {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create challenge: "+err.Error())
+		return ""
+	}
+	expiresAt := time.Now().UTC().Add(webauthn.Timeout * time.Millisecond)
+	if err := h.Store.AddPasskeyChallenge(r.Context(), challenge, userID, ceremony, expiresAt); err != nil {
+		respondWithStoreError(w, err, "Failed to create challenge")
+		return ""
+	}
+	return challenge
+}
+
+// descriptors returns the descriptors of passkeys for options.
+func descriptors(passkeys []model.Passkey) []webauthn.CredentialDescriptor {
+	d := make([]webauthn.CredentialDescriptor, len(passkeys))
+	for i, passkey := range passkeys {
+		d[i] = webauthn.CredentialDescriptor{Type: "public-key", ID: passkey.CredentialID, Transports: passkey.Transports}
+	}
+	return d
+}
+
+// GetPasskeysHandler handles GET /api/users/passkeys requests, listing the
+// passkeys of the user who is logged in, newest first.
+func (h *APIHandler) GetPasskeysHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := currentUser(w, r); !ok {
+		return
+	}
+	passkeys, err := h.Store.GetPasskeys(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve passkeys: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, passkeys)
+}
+
+// BeginPasskeyRegistrationHandler handles POST
+// /api/users/passkeys/register/begin requests, responding with the options
+// to pass to navigator.credentials.create() in "publicKey" to add a passkey
+// to the account that is logged in.
+func (h *APIHandler) BeginPasskeyRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	rp, err := h.relyingParty(r)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Passkeys are unavailable: "+err.Error())
+		return
+	}
+	user, err := h.Store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to get user")
+		return
+	}
+	passkeys, err := h.Store.GetPasskeys(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve passkeys: "+err.Error())
+		return
+	}
+	challenge := h.newPasskeyChallenge(w, r, &userID, db.PasskeyRegister)
+	if challenge == "" {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"publicKey": rp.CreationOptions(challenge, userHandle(userID), user.Username, descriptors(passkeys)),
+	})
+}
+
+// FinishPasskeyRegistrationHandler handles POST
+// /api/users/passkeys/register/finish requests. Expects
+// {"name": "Laptop", "credential": {...}} with the credential the browser
+// created, as its toJSON() gives it, and adds the passkey.
+func (h *APIHandler) FinishPasskeyRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	var payload struct {
+		Name       string                       `json:"name"`
+		Credential webauthn.AttestationResponse `json:"credential"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	rp, err := h.relyingParty(r)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Passkeys are unavailable: "+err.Error())
+		return
+	}
+	challenge, err := webauthn.Challenge(payload.Credential.Response.ClientDataJSON)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	owner, err := h.Store.ConsumePasskeyChallenge(r.Context(), challenge, db.PasskeyRegister)
+	if errors.Is(err, db.ErrNotFound) || (err == nil && (owner == nil || *owner != userID)) {
+		respondWithError(w, http.StatusBadRequest, "Unknown or expired challenge, start the registration again")
+		return
+	} else if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check challenge: "+err.Error())
+		return
+	}
+	credential, err := rp.VerifyRegistration(payload.Credential, challenge)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	passkey := model.Passkey{
+		UserID:       userID,
+		Name:         strings.TrimSpace(payload.Name),
+		CredentialID: base64.RawURLEncoding.EncodeToString(credential.ID),
+		PublicKey:    credential.PublicKey,
+		SignCount:    credential.SignCount,
+		Transports:   credential.Transports,
+	}
+	if passkey.Name == "" {
+		passkey.Name = "Passkey"
+	}
+	if err := h.Store.AddPasskey(r.Context(), &passkey); err != nil {
+		if errors.Is(err, db.ErrDuplicate) {
+			respondWithError(w, http.StatusConflict, "This passkey is already registered")
+			return
+		}
+		respondWithStoreError(w, err, "Failed to add passkey")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, passkey)
+}
+
+// DeletePasskeyHandler handles DELETE /api/users/passkeys/{id} requests,
+// removing the passkey.
+func (h *APIHandler) DeletePasskeyHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := currentUser(w, r); !ok {
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid passkey ID")
+		return
+	}
+	if err := h.Store.DeletePasskey(r.Context(), id); err != nil {
+		respondWithStoreError(w, err, "Failed to delete passkey")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BeginPasskeyLoginHandler handles POST /api/users/passkeys/login/begin
+// requests, responding with the options to pass to
+// navigator.credentials.get() in "publicKey". An optional
+// {"username": "..."} limits them to that user's passkeys, for authenticators
+// that can't discover them.
+func (h *APIHandler) BeginPasskeyLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if h.accountsDisabled(w) {
+		return
+	}
+	var payload struct {
+		Username string `json:"username"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	rp, err := h.relyingParty(r)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Passkeys are unavailable: "+err.Error())
+		return
+	}
+	var allow []webauthn.CredentialDescriptor
+	if username := strings.TrimSpace(payload.Username); username != "" {
+		user, err := h.Store.GetUserByUsername(r.Context(), username)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			respondWithError(w, http.StatusInternalServerError, "Failed to look up user: "+err.Error())
+			return
+		}
+		// Unknown users get no passkeys to choose from, like users without any
+		if user != nil {
+			passkeys, err := h.Store.GetPasskeys(db.WithUser(r.Context(), user.ID))
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to retrieve passkeys: "+err.Error())
+				return
+			}
+			allow = descriptors(passkeys)
+		}
+	}
+	challenge := h.newPasskeyChallenge(w, r, nil, db.PasskeyLogin)
+	if challenge == "" {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"publicKey": rp.RequestOptions(challenge, allow)})
+}
+
+// FinishPasskeyLoginHandler handles POST /api/users/passkeys/login/finish
+// requests. Expects the credential the browser logged in with, as its
+// toJSON() gives it, and responds like a login with a password.
+func (h *APIHandler) FinishPasskeyLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if h.accountsDisabled(w) {
+		return
+	}
+	var resp webauthn.AssertionResponse
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	rp, err := h.relyingParty(r)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Passkeys are unavailable: "+err.Error())
+		return
+	}
+	challenge, err := webauthn.Challenge(resp.Response.ClientDataJSON)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id, err := webauthn.CredentialID(resp.ID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := h.Store.ConsumePasskeyChallenge(r.Context(), challenge, db.PasskeyLogin); errors.Is(err, db.ErrNotFound) {
+		respondWithError(w, http.StatusUnauthorized, "Unknown or expired challenge, start the login again")
+		return
+	} else if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check challenge: "+err.Error())
+		return
+	}
+
+	passkey, err := h.Store.GetPasskeyByCredentialID(r.Context(), base64.RawURLEncoding.EncodeToString(id))
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		respondWithError(w, http.StatusInternalServerError, "Failed to look up passkey: "+err.Error())
+		return
+	}
+	// A discoverable passkey names its user, which must be the one it was registered to
+	handle := strings.TrimRight(resp.Response.UserHandle, "=")
+	if passkey == nil || (handle != "" && handle != base64.RawURLEncoding.EncodeToString(userHandle(passkey.UserID))) {
+		respondWithError(w, http.StatusUnauthorized, "Unknown passkey")
+		return
+	}
+	signCount, err := rp.VerifyLogin(resp, challenge, passkey.PublicKey, passkey.SignCount)
+	if errors.Is(err, webauthn.ErrVerification) {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	} else if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.Store.UpdatePasskeyUse(r.Context(), passkey.ID, signCount); err != nil {
+		respondWithStoreError(w, err, "Failed to record passkey use")
+		return
+	}
+	user, err := h.Store.GetUserByID(r.Context(), passkey.UserID)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to get user")
+		return
+	}
+	h.startSession(w, r, user)
+}
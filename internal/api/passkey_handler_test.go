@@ -0,0 +1,191 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// testPasskey is a software authenticator with one P-256 passkey for
+// books.example.com.
+type testPasskey struct {
+	id        []byte
+	key       *ecdsa.PrivateKey
+	signCount uint32
+}
+
+const passkeyTestOrigin = "http://books.example.com"
+
+func newTestPasskey(t *testing.T) *testPasskey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	return &testPasskey{id: []byte("test-passkey-id1"), key: key}
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func (p *testPasskey) authData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte("books.example.com"))
+	return binary.BigEndian.AppendUint32(append(rpIDHash[:], flags), p.signCount)
+}
+
+func (p *testPasskey) clientData(ceremony, challenge string) []byte {
+	data, _ := json.Marshal(map[string]string{"type": ceremony, "challenge": challenge, "origin": passkeyTestOrigin})
+	return data
+}
+
+// create returns the credential JSON of the passkey made for challenge.
+func (p *testPasskey) create(challenge string) string {
+	x, y := make([]byte, 32), make([]byte, 32)
+	p.key.X.FillBytes(x)
+	p.key.Y.FillBytes(y)
+	// {1: 2, 3: -7, -1: 1, -2: x, -3: y}
+	cose := append(append(append([]byte{0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21, 0x58, 0x20}, x...), 0x22, 0x58, 0x20), y...)
+	authData := append(p.authData(0x45), make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(p.id)))
+	authData = append(append(authData, p.id...), cose...)
+	// {"fmt": "none", "attStmt": {}, "authData": authData}
+	object := append([]byte("\xa3\x63fmt\x64none\x67attStmt\xa0\x68authData\x58"), byte(len(authData)))
+	object = append(object, authData...)
+	credential, _ := json.Marshal(map[string]interface{}{
+		"id": b64(p.id), "type": "public-key",
+		"response": map[string]interface{}{
+			"clientDataJSON":    b64(p.clientData("webauthn.create", challenge)),
+			"attestationObject": b64(object),
+			"transports":        []string{"internal"},
+		},
+	})
+	return string(credential)
+}
+
+// get returns the credential JSON of a login in answer to challenge.
+func (p *testPasskey) get(t *testing.T, challenge, userHandle string) string {
+	p.signCount++
+	authData := p.authData(0x05)
+	clientData := p.clientData("webauthn.get", challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, p.key, digest[:])
+	if err != nil {
+		t.Fatalf("SignASN1 failed: %v", err)
+	}
+	credential, _ := json.Marshal(map[string]interface{}{
+		"id": b64(p.id), "type": "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64(clientData),
+			"authenticatorData": b64(authData),
+			"signature":         b64(signature),
+			"userHandle":        userHandle,
+		},
+	})
+	return string(credential)
+}
+
+// passkeyChallenge returns the challenge of begin's options.
+func passkeyChallenge(t *testing.T, router http.Handler, path, token, body string) (string, map[string]interface{}) {
+	rr := authRequest(router, "POST", passkeyTestOrigin+path, token, body)
+	var options struct {
+		PublicKey map[string]interface{} `json:"publicKey"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &options); err != nil || options.PublicKey["challenge"] == nil {
+		t.Fatalf("%s: got status %d, body: %s", path, rr.Code, rr.Body.String())
+	}
+	return options.PublicKey["challenge"].(string), options.PublicKey
+}
+
+// TestPasskeys tests adding a passkey to an account and logging in with it.
+func TestPasskeys(t *testing.T) {
+	router, _ := newAuthRouter(t)
+	token := loginTestUser(t, router)
+	passkey := newTestPasskey(t)
+	do := func(method, path, token, body string) (int, string) {
+		rr := authRequest(router, method, passkeyTestOrigin+path, token, body)
+		return rr.Code, rr.Body.String()
+	}
+
+	if code, _ := do("POST", "/api/v1/users/passkeys/register/begin", "", `{}`); code != http.StatusUnauthorized {
+		t.Errorf("Expected registering a passkey to need a login, got %d", code)
+	}
+	challenge, options := passkeyChallenge(t, router, "/api/v1/users/passkeys/register/begin", token, ``)
+	if rp := options["rp"].(map[string]interface{}); rp["id"] != "books.example.com" {
+		t.Errorf("Expected the RP ID from the request, got %v", rp)
+	}
+	user := options["user"].(map[string]interface{})
+	if user["name"] != "alice" {
+		t.Errorf("Unexpected user %v", user)
+	}
+	if code, body := do("POST", "/api/v1/users/passkeys/register/finish", token, `{"name":"Laptop","credential":`+passkey.create("made-up")+`}`); code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown challenge to be rejected, got %d: %s", code, body)
+	}
+	code, body := do("POST", "/api/v1/users/passkeys/register/finish", token, `{"name":"Laptop","credential":`+passkey.create(challenge)+`}`)
+	if code != http.StatusCreated || !strings.Contains(body, `"name":"Laptop"`) || strings.Contains(body, "public_key") {
+		t.Fatalf("Finishing registration: got status %d, body: %s", code, body)
+	}
+	// Each challenge is answered once
+	if code, _ := do("POST", "/api/v1/users/passkeys/register/finish", token, `{"name":"Again","credential":`+passkey.create(challenge)+`}`); code != http.StatusBadRequest {
+		t.Errorf("Expected a used challenge to be rejected, got %d", code)
+	}
+	_, options = passkeyChallenge(t, router, "/api/v1/users/passkeys/register/begin", token, ``)
+	if exclude := options["excludeCredentials"].([]interface{}); len(exclude) != 1 {
+		t.Errorf("Expected the passkey excluded from registering again, got %v", exclude)
+	}
+
+	// Logging in needs no account, with or without a username
+	challenge, options = passkeyChallenge(t, router, "/api/v1/users/passkeys/login/begin", "", `{"username":"alice"}`)
+	if allow := options["allowCredentials"].([]interface{}); len(allow) != 1 {
+		t.Errorf("Expected alice's passkey allowed, got %v", allow)
+	}
+	code, body = do("POST", "/api/v1/users/passkeys/login/finish", "", passkey.get(t, challenge, user["id"].(string)))
+	if code != http.StatusOK || !strings.Contains(body, `"token"`) || !strings.Contains(body, `"username":"alice"`) {
+		t.Fatalf("Finishing login: got status %d, body: %s", code, body)
+	}
+	var session struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal([]byte(body), &session)
+	if code, _ := do("GET", "/api/v1/users/me", session.Token, ""); code != http.StatusOK {
+		t.Errorf("Expected the passkey's session to work, got %d", code)
+	}
+
+	challenge, options = passkeyChallenge(t, router, "/api/v1/users/passkeys/login/begin", "", ``)
+	if allow := options["allowCredentials"].([]interface{}); len(allow) != 0 {
+		t.Errorf("Expected any passkey allowed, got %v", allow)
+	}
+	if code, _ := do("POST", "/api/v1/users/passkeys/login/finish", "", passkey.get(t, challenge, b64([]byte("2")))); code != http.StatusUnauthorized {
+		t.Errorf("Expected a passkey naming another user to be rejected, got %d", code)
+	}
+	challenge, _ = passkeyChallenge(t, router, "/api/v1/users/passkeys/login/begin", "", ``)
+	forged := newTestPasskey(t)
+	if code, _ := do("POST", "/api/v1/users/passkeys/login/finish", "", forged.get(t, challenge, "")); code != http.StatusUnauthorized {
+		t.Errorf("Expected a login signed by another key to be rejected, got %d", code)
+	}
+	challenge, _ = passkeyChallenge(t, router, "/api/v1/users/passkeys/login/begin", "", ``)
+	if code, body := do("POST", "/api/v1/users/passkeys/login/finish", "", passkey.get(t, challenge, "")); code != http.StatusOK {
+		t.Errorf("Expected logging in without a user handle to work, got %d: %s", code, body)
+	}
+
+	code, body = do("GET", "/api/v1/users/passkeys", token, "")
+	var passkeys []struct {
+		ID         int64   `json:"id"`
+		LastUsedAt *string `json:"last_used_at"`
+	}
+	if err := json.Unmarshal([]byte(body), &passkeys); err != nil || code != http.StatusOK || len(passkeys) != 1 || passkeys[0].LastUsedAt == nil {
+		t.Fatalf("Listing passkeys: got status %d, body: %s", code, body)
+	}
+	if code, _ := do("DELETE", "/api/v1/users/passkeys/"+itoa(passkeys[0].ID), token, ""); code != http.StatusNoContent {
+		t.Fatalf("Expected the passkey removed, got %d", code)
+	}
+	challenge, _ = passkeyChallenge(t, router, "/api/v1/users/passkeys/login/begin", "", ``)
+	if code, _ := do("POST", "/api/v1/users/passkeys/login/finish", "", passkey.get(t, challenge, "")); code != http.StatusUnauthorized {
+		t.Errorf("Expected a removed passkey not to log in, got %d", code)
+	}
+}
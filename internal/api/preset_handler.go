@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// presetResponse is a shelf preset with a link to the book list it shows.
+type presetResponse struct {
+	model.ShelfPreset
+	Link string `json:"link"`
+}
+
+// presetQuery returns the book list query parameters of a preset.
+func presetQuery(preset model.ShelfPreset) url.Values {
+	q := url.Values{}
+	for key, value := range preset.Filters {
+		q.Set(key, value)
+	}
+	if preset.Sort != "" {
+		q.Set("sort", preset.Sort)
+	}
+	if preset.Order != "" {
+		q.Set("order", preset.Order)
+	}
+	if len(preset.Fields) > 0 {
+		q.Set("fields", strings.Join(preset.Fields, ","))
+	}
+	return q
+}
+
+func newPresetResponse(preset model.ShelfPreset) presetResponse {
+	return presetResponse{ShelfPreset: preset, Link: booksLink(presetQuery(preset))}
+}
+
+// validatePreset checks a preset's filters, sort field and fields the way
+// GET /api/books checks its query parameters.
+func validatePreset(preset model.ShelfPreset) error {
+	for key := range preset.Filters {
+		switch key {
+		case "limit", "offset", "sort", "order", "fields":
+			return fmt.Errorf("%s is not a filter", key)
+		}
+		known := false
+		for _, param := range listParams {
+			known = known || key == param
+		}
+		if !known {
+			return fmt.Errorf("unknown filter %q", key)
+		}
+	}
+	r := &http.Request{URL: &url.URL{RawQuery: presetQuery(preset).Encode()}}
+	if _, _, err := parseListOptions(r); err != nil {
+		return err
+	}
+	_, err := parseFields(r, bookFields)
+	return err
+}
+
+// decodePreset reads a shelf preset from a request body, as
+// {"name": "Unrated reads", "filters": {"status": "read", "missing": "rating"},
+// "sort": "added", "order": "desc", "fields": ["title", "author"]}.
+func decodePreset(r *http.Request) (model.ShelfPreset, error) {
+	var payload struct {
+		Name    string            `json:"name"`
+		Filters map[string]string `json:"filters"`
+		Sort    string            `json:"sort"`
+		Order   string            `json:"order"`
+		Fields  []string          `json:"fields"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		return model.ShelfPreset{}, fmt.Errorf("Invalid request body: %w", err)
+	}
+	preset := model.ShelfPreset{Name: payload.Name, Filters: payload.Filters, Sort: payload.Sort, Order: payload.Order, Fields: payload.Fields}
+	if err := validatePreset(preset); err != nil {
+		return model.ShelfPreset{}, err
+	}
+	return preset, nil
+}
+
+// GetShelfPresetsHandler handles GET /api/presets requests, listing every
+// shelf preset by name.
+func (h *APIHandler) GetShelfPresetsHandler(w http.ResponseWriter, r *http.Request) {
+	presets, err := h.Store.GetShelfPresets(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve shelf presets: "+err.Error())
+		return
+	}
+	resps := make([]presetResponse, len(presets))
+	for i, preset := range presets {
+		resps[i] = newPresetResponse(preset)
+	}
+	respondWithJSON(w, http.StatusOK, resps)
+}
+
+// GetShelfPresetHandler handles GET /api/presets/{id} requests.
+func (h *APIHandler) GetShelfPresetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid shelf preset ID")
+		return
+	}
+	preset, err := h.Store.GetShelfPreset(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve shelf preset")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newPresetResponse(*preset))
+}
+
+// AddShelfPresetHandler handles POST /api/presets requests. Filters are the
+// query parameters of GET /api/books other than sort, order and paging.
+func (h *APIHandler) AddShelfPresetHandler(w http.ResponseWriter, r *http.Request) {
+	preset, err := decodePreset(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.Store.AddShelfPreset(r.Context(), &preset); err != nil {
+		respondWithStoreError(w, err, "Failed to add shelf preset")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newPresetResponse(preset))
+}
+
+// UpdateShelfPresetHandler handles PUT /api/presets/{id} requests, replacing
+// the preset with the one in the body.
+func (h *APIHandler) UpdateShelfPresetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid shelf preset ID")
+		return
+	}
+	preset, err := decodePreset(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	preset.ID = id
+	if err := h.Store.UpdateShelfPreset(r.Context(), &preset); err != nil {
+		respondWithStoreError(w, err, "Failed to update shelf preset")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newPresetResponse(preset))
+}
+
+// DeleteShelfPresetHandler handles DELETE /api/presets/{id} requests.
+func (h *APIHandler) DeleteShelfPresetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid shelf preset ID")
+		return
+	}
+	if err := h.Store.DeleteShelfPreset(r.Context(), id); err != nil {
+		respondWithStoreError(w, err, "Failed to delete shelf preset")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestShelfPresetHandlers tests saving, listing, changing and removing shelf
+// presets, and following a preset's link to its books
+func TestShelfPresetHandlers(t *testing.T) {
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("POST", "/api/presets", `{"name":"Best reads","filters":{"status":"read","min_rating":"9"},"sort":"rating","order":"desc","fields":["title","rating"]}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Adding a preset: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	var preset presetResponse
+	json.Unmarshal(rr.Body.Bytes(), &preset)
+	defer testStore.DeleteShelfPreset(context.Background(), preset.ID)
+	if preset.Link != "/api/v1/books?fields=title%2Crating&min_rating=9&order=desc&sort=rating&status=read" {
+		t.Errorf("Unexpected link %q", preset.Link)
+	}
+	if rr := do("POST", "/api/presets", `{"name":"Best reads"}`); rr.Code != http.StatusConflict {
+		t.Errorf("Duplicate name: got status %d, want %d", rr.Code, http.StatusConflict)
+	}
+	for _, body := range []string{
+		`{"name":""}`,
+		`{"name":"Bad","filters":{"colour":"red"}}`,
+		`{"name":"Bad","filters":{"limit":"5"}}`,
+		`{"name":"Bad","filters":{"status":"someday"}}`,
+		`{"name":"Bad","sort":"colour"}`,
+		`{"name":"Bad","fields":["colour"]}`,
+		`{"name":"Bad","view":"grid"}`,
+	} {
+		if rr := do("POST", "/api/presets", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want %d", body, rr.Code, http.StatusBadRequest)
+		}
+	}
+
+	// The link shows the books
+	rr = do("GET", "/api"+strings.TrimPrefix(preset.Link, "/api/v1"), "")
+	if rr.Code != http.StatusOK {
+		t.Errorf("Following the link: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do("GET", "/api/presets", "")
+	var presets []presetResponse
+	json.Unmarshal(rr.Body.Bytes(), &presets)
+	if rr.Code != http.StatusOK || len(presets) != 1 || presets[0].ID != preset.ID || presets[0].Filters["min_rating"] != "9" {
+		t.Errorf("Expected the preset in the list, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do("PUT", "/api/presets/"+itoa(preset.ID), `{"name":"To read","filters":{"status":"want-to-read"}}`)
+	preset = presetResponse{}
+	json.Unmarshal(rr.Body.Bytes(), &preset)
+	if rr.Code != http.StatusOK || preset.Name != "To read" || preset.Sort != "" || preset.Link != "/api/v1/books?status=want-to-read" {
+		t.Errorf("Replacing a preset: got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/presets/"+itoa(preset.ID), ""); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"To read"`) {
+		t.Errorf("Getting a preset: got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("PUT", "/api/presets/999999", `{"name":"Missing"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Replacing a missing preset: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	if rr := do("DELETE", "/api/presets/"+itoa(preset.ID), ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Deleting a preset: got status %d, want %d", rr.Code, http.StatusNoContent)
+	}
+	if rr := do("GET", "/api/presets/"+itoa(preset.ID), ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Getting a deleted preset: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
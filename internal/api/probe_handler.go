@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ProbeTimeout bounds the checks of a readiness probe, so a hung database
+// fails the probe rather than outlasting the orchestrator's timeout.
+const ProbeTimeout = 2 * time.Second
+
+// componentStatus is the result of one check of a readiness probe.
+type componentStatus struct {
+	Status    string `json:"status"` // "ok" or "error"
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+	// Schema versions, for the schema check
+	Version  *int `json:"version,omitempty"`
+	Expected *int `json:"expected,omitempty"`
+}
+
+// probeResponse is the body of GET /healthz and GET /readyz.
+type probeResponse struct {
+	Status     string                     `json:"status"` // "ok" or "unavailable"
+	Components map[string]componentStatus `json:"components,omitempty"`
+}
+
+// HealthzHandler handles GET /healthz requests, the liveness probe: it only
+// reports that the process is serving requests, so that a slow database
+// doesn't get the server restarted.
+func (h *APIHandler) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, probeResponse{Status: "ok"})
+}
+
+// ReadyzHandler handles GET /readyz requests, the readiness probe: it pings
+// the database and checks that its schema is at the version of the server's
+// newest migration. Responds with 503 Service Unavailable when either fails,
+// with the status of each component.
+func (h *APIHandler) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), ProbeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	database := componentStatus{Status: "ok"}
+	if err := h.Store.Ping(ctx); err != nil {
+		database.Status, database.Error = "error", err.Error()
+	}
+	database.LatencyMS = time.Since(start).Milliseconds()
+
+	start = time.Now()
+	schema := componentStatus{Status: "ok"}
+	if applied, latest, err := h.Store.SchemaVersions(ctx); err != nil {
+		schema.Status, schema.Error = "error", err.Error()
+	} else {
+		schema.Version, schema.Expected = &applied, &latest
+		if applied != latest {
+			schema.Status = "error"
+			schema.Error = fmt.Sprintf("database schema is at version %d, expected %d", applied, latest)
+		}
+	}
+	schema.LatencyMS = time.Since(start).Milliseconds()
+
+	resp := probeResponse{Status: "ok", Components: map[string]componentStatus{"database": database, "schema": schema}}
+	status := http.StatusOK
+	if database.Status != "ok" || schema.Status != "ok" {
+		resp.Status, status = "unavailable", http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, status, resp)
+}
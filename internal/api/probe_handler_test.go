@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+)
+
+func TestProbes(t *testing.T) {
+	router, store := newAuthRouter(t)
+	// Orchestrators have no credentials, even once there are users
+	loginTestUser(t, router)
+
+	if rr := authRequest(router, "GET", "/healthz", "", ""); rr.Code != http.StatusOK {
+		t.Errorf("GET /healthz: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	var resp probeResponse
+	rr := authRequest(router, "GET", "/readyz", "", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || rr.Code != http.StatusOK || resp.Status != "ok" {
+		t.Fatalf("GET /readyz: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	schema := resp.Components["schema"]
+	if resp.Components["database"].Status != "ok" || schema.Status != "ok" || schema.Version == nil || *schema.Version != db.LatestSchemaVersion(false) {
+		t.Errorf("Unexpected components: %+v", resp.Components)
+	}
+
+	// A database behind the server's migrations isn't ready
+	database := store.(*db.SQLiteBookStore).DB
+	if _, err := database.Exec(`DELETE FROM schema_migrations WHERE version = ?`, db.LatestSchemaVersion(false)); err != nil {
+		t.Fatalf("Failed to roll back the schema version: %v", err)
+	}
+	rr = authRequest(router, "GET", "/readyz", "", "")
+	resp = probeResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || rr.Code != http.StatusServiceUnavailable || resp.Components["schema"].Status != "error" || resp.Components["database"].Status != "ok" {
+		t.Errorf("GET /readyz behind on migrations: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	database.Close()
+	rr = authRequest(router, "GET", "/readyz", "", "")
+	resp = probeResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || rr.Code != http.StatusServiceUnavailable || resp.Status != "unavailable" {
+		t.Fatalf("GET /readyz without a database: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if c := resp.Components["database"]; c.Status != "error" || c.Error == "" {
+		t.Errorf("Expected the database to be reported down, got %+v", c)
+	}
+	// Liveness doesn't depend on the database
+	if rr := authRequest(router, "GET", "/healthz", "", ""); rr.Code != http.StatusOK {
+		t.Errorf("GET /healthz without a database: got status %d", rr.Code)
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// UpdateBookProgressHandler handles PATCH /api/books/{id}/progress requests,
+// which record how far through a Currently Reading book the reader is, as
+// {"current_page": 120} or, for a book without pages to count,
+// {"percent": 45}. With a page count the other is derived. It returns the
+// book, with its finish date estimated from the pages left.
+func (h *APIHandler) UpdateBookProgressHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+
+	var payload model.ProgressUpdate
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.Store.SetReadingProgress(r.Context(), id, payload); err != nil {
+		respondWithStoreError(w, err, "Failed to update reading progress")
+		return
+	}
+	book, err := h.Store.GetBookByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to get book")
+		return
+	}
+	resps := []BookResponse{newBookResponse(book)}
+	h.estimateFinish(r.Context(), []model.Book{*book}, resps)
+	respondWithJSON(w, http.StatusOK, resps[0])
+}
+
+// GetBookProgressHandler handles GET /api/books/{id}/progress requests and
+// returns the progress history of the book's current or latest read, oldest
+// first, with the pace between updates.
+func (h *APIHandler) GetBookProgressHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	progress, err := h.Store.GetReadingProgress(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve reading progress")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, progress)
+}
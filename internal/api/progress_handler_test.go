@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestBookProgressHandlers tests recording reading progress and reading its history
+func TestBookProgressHandlers(t *testing.T) {
+	book := createTestBook(model.StatusWantToRead, "Progress")
+	pages := 200
+	book.PageCount = &pages
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+	path := "/api/books/" + itoa(id) + "/progress"
+
+	if rr := do("PATCH", path, `{"current_page": 50}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a book not being read, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if err := testStore.UpdateBookStatus(context.Background(), id, model.StatusCurrentlyReading); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+
+	rr := do("PATCH", path, `{"percent": 25}`)
+	var resp BookResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected the book back, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if resp.CurrentPage == nil || *resp.CurrentPage != 50 || resp.ProgressPercent == nil || *resp.ProgressPercent != 25 {
+		t.Errorf("Expected page 50 at 25%%, got %v, %v", resp.CurrentPage, resp.ProgressPercent)
+	}
+
+	for _, body := range []string{`{}`, `{"current_page": 201}`, `{"percent": 101}`, `{"page": 10}`} {
+		if rr := do("PATCH", path, body); rr.Code != http.StatusBadRequest {
+			t.Errorf("PATCH %s: expected 400, got %d: %s", body, rr.Code, rr.Body.String())
+		}
+	}
+
+	rr = do("GET", path, "")
+	var progress model.ReadingProgress
+	if err := json.Unmarshal(rr.Body.Bytes(), &progress); err != nil || rr.Code != http.StatusOK || len(progress.Entries) != 1 {
+		t.Fatalf("Expected 1 progress entry, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if entry := progress.Entries[0]; entry.Page == nil || *entry.Page != 50 || progress.BookID != id {
+		t.Errorf("Expected an entry at page 50, got %+v", entry)
+	}
+	if rr := do("GET", "/api/books/999999/progress", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing book, got %d", rr.Code)
+	}
+}
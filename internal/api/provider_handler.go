@@ -0,0 +1,23 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ericdahl/bookshelf/internal/health"
+)
+
+// providersResponse is the body of GET /api/admin/providers.
+type providersResponse struct {
+	WindowSeconds int                    `json:"window_seconds"`
+	Providers     []health.ProviderStats `json:"providers"`
+}
+
+// GetProvidersHandler handles GET /api/admin/providers requests and reports
+// the status, latency and error rate of each metadata provider and outbound
+// integration over the recent window.
+func (h *APIHandler) GetProvidersHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, providersResponse{
+		WindowSeconds: int(health.Window.Seconds()),
+		Providers:     h.Health.Stats(),
+	})
+}
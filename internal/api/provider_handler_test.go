@@ -0,0 +1,40 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetProvidersHandler(t *testing.T) {
+	msg := "503 Service Unavailable"
+	testHandler.Health.Record("openlibrary", 250*time.Millisecond, nil)
+	testHandler.Health.Record("openlibrary", 50*time.Millisecond, &msg)
+
+	req, _ := http.NewRequest("GET", "/api/admin/providers", nil)
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var resp providersResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if resp.WindowSeconds != 900 {
+		t.Errorf("Expected a 900s window, got %d", resp.WindowSeconds)
+	}
+	for _, p := range resp.Providers {
+		if p.Name != "openlibrary" {
+			continue
+		}
+		if p.Requests < 2 || p.Errors < 1 || p.LastError != msg {
+			t.Errorf("Unexpected openlibrary stats %+v", p)
+		}
+		return
+	}
+	t.Errorf("openlibrary missing from %+v", resp.Providers)
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// DefaultTrustedProxies are the addresses proxies are trusted from unless
+// configured otherwise: the loopback ones, as of a proxy on the same host
+// such as "tailscale serve".
+const DefaultTrustedProxies = "127.0.0.1/8,::1/128"
+
+// ProxyAuth trusts the identity a reverse proxy that authenticates users,
+// such as Authelia, authentik or Tailscale, sends in a header, as
+// "Remote-User: alice". The header is only believed from the proxies'
+// addresses, since anyone else could send it too. Users are created on their
+// first request with a password nobody knows, so they log in through the
+// proxy, or with the API keys and passkeys they add. Registering and logging
+// in with passwords are turned off, since anyone could otherwise register a
+// name before the proxy vouches for it and take over that user.
+type ProxyAuth struct {
+	header  string
+	trusted []netip.Prefix
+}
+
+// NewProxyAuth returns the proxy authentication taking usernames from header,
+// believed from the comma-separated addresses or CIDR prefixes of trusted.
+func NewProxyAuth(header, trusted string) (*ProxyAuth, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return nil, errors.New("a header is required")
+	}
+	prefixes, err := parsePrefixes(trusted)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	if len(prefixes) == 0 {
+		return nil, errors.New("at least one trusted proxy is required")
+	}
+	return &ProxyAuth{header: http.CanonicalHeaderKey(header), trusted: prefixes}, nil
+}
+
+// fromProxy reports whether r was sent by one of the trusted proxies.
+func (p *ProxyAuth) fromProxy(r *http.Request) bool {
+	return sentFrom(r, p.trusted)
+}
+
+// passwordsDisabled responds with an error and returns true when users are
+// logged in by a reverse proxy, for registering and logging in with passwords.
+func (h *APIHandler) passwordsDisabled(w http.ResponseWriter) bool {
+	if h.ProxyAuth == nil {
+		return false
+	}
+	respondWithError(w, http.StatusForbidden, "Users log in through the reverse proxy in front of this bookshelf")
+	return true
+}
+
+// proxyUser returns the user a trusted proxy says sent r, creating them the
+// first time, or nil when proxy authentication is off or r isn't from a
+// trusted proxy with the header set. An identity that can't be a username
+// returns a *model.ValidationError.
+func (h *APIHandler) proxyUser(r *http.Request) (*model.User, error) {
+	if h.ProxyAuth == nil || !h.ProxyAuth.fromProxy(r) {
+		return nil, nil
+	}
+	username := strings.TrimSpace(r.Header.Get(h.ProxyAuth.header))
+	if username == "" {
+		return nil, nil
+	}
+	if err := model.ValidateUsername(username); err != nil {
+		return nil, err
+	}
+	ctx := r.Context()
+	user, err := h.Store.GetUserByUsername(ctx, username)
+	if !errors.Is(err, db.ErrNotFound) {
+		return user, err
+	}
+
+	// Passwords can't be unset, so new users get one nobody knows
+	password, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	user = &model.User{Username: username}
+	if err := user.SetPassword(password); err != nil {
+		return nil, err
+	}
+	if err := h.Store.AddUser(ctx, user); err != nil {
+		if errors.Is(err, db.ErrDuplicate) {
+			// Created by a request sent at the same time
+			return h.Store.GetUserByUsername(ctx, username)
+		}
+		return nil, err
+	}
+	slog.InfoContext(r.Context(), "Created user from proxy identity", "username", username, "header", h.ProxyAuth.header)
+	return user, nil
+}
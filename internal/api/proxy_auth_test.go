@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+)
+
+func TestNewProxyAuth(t *testing.T) {
+	for _, bad := range [][2]string{{"", DefaultTrustedProxies}, {"Remote-User", ""}, {"Remote-User", "10.0.0.0/33"}, {"Remote-User", "proxy.local"}} {
+		if _, err := NewProxyAuth(bad[0], bad[1]); err == nil {
+			t.Errorf("NewProxyAuth(%q, %q): expected an error", bad[0], bad[1])
+		}
+	}
+	p, err := NewProxyAuth("remote-user", "10.0.0.1, 192.168.0.0/16,::1")
+	if err != nil {
+		t.Fatalf("NewProxyAuth failed: %v", err)
+	}
+	for addr, want := range map[string]bool{
+		"10.0.0.1:4321":          true,
+		"[::ffff:10.0.0.1]:4321": true,
+		"192.168.7.9:80":         true,
+		"[::1]:80":               true,
+		"10.0.0.2:4321":          false,
+		"203.0.113.5:443":        false,
+		"":                       false,
+	} {
+		if got := p.fromProxy(&http.Request{RemoteAddr: addr}); got != want {
+			t.Errorf("fromProxy(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestProxyAuth(t *testing.T) {
+	handler := newTestAPIHandler(t)
+	var err error
+	if handler.ProxyAuth, err = NewProxyAuth("Remote-User", "10.0.0.1"); err != nil {
+		t.Fatalf("NewProxyAuth failed: %v", err)
+	}
+	router := SetupRouter(handler, t.TempDir())
+
+	request := func(method, path, remoteAddr, user, site string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(`{"start_date":"2025-07-01","end_date":"2025-07-14"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = remoteAddr
+		if user != "" {
+			req.Header.Set("Remote-User", user)
+		}
+		if site != "" {
+			req.Header.Set("Sec-Fetch-Site", site)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Users are created on their first request, and found on the next
+	if rr := request("POST", "/api/v1/vacations", "10.0.0.1:5555", "alice@example.com", "same-origin"); rr.Code != http.StatusCreated {
+		t.Fatalf("Changing the library through the proxy: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	rr := request("GET", "/api/v1/users/me", "10.0.0.1:5555", "Alice@Example.com", "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"username":"alice@example.com"`) {
+		t.Errorf("GET /users/me through the proxy: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := request("GET", "/api/v1/vacations", "10.0.0.1:5555", "alice@example.com", ""); !strings.Contains(rr.Body.String(), "2025-07-01") {
+		t.Errorf("Expected alice's vacation, got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := request("GET", "/api/v1/vacations", "10.0.0.1:5555", "bob", ""); rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "2025-07-01") {
+		t.Errorf("Expected bob to get a library of his own, got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	// The proxy's header is sent along by browsers, like the cookie
+	if rr := request("POST", "/api/v1/vacations", "10.0.0.1:5555", "alice@example.com", "cross-site"); rr.Code != http.StatusForbidden {
+		t.Errorf("Changing the library from another site: got status %d, want %d", rr.Code, http.StatusForbidden)
+	}
+	if rr := request("GET", "/api/v1/users/me", "203.0.113.5:5555", "alice@example.com", ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Sending the header around the proxy: got status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+	if rr := request("GET", "/api/v1/users/me", "10.0.0.1:5555", "Alice Smith", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("An identity that can't be a username: got status %d, want %d", rr.Code, http.StatusForbidden)
+	}
+}
+
+// TestProxyAuthRefusesPasswords tests that a proxy identity can't be claimed
+// by registering it first, nor logged in to with a password.
+func TestProxyAuthRefusesPasswords(t *testing.T) {
+	handler := newTestAPIHandler(t)
+	var err error
+	if handler.ProxyAuth, err = NewProxyAuth("Remote-User", "10.0.0.1"); err != nil {
+		t.Fatalf("NewProxyAuth failed: %v", err)
+	}
+	router := SetupRouter(handler, t.TempDir())
+
+	credentials := `{"username":"alice@example.com","password":"correct horse battery"}`
+	for _, path := range []string{"/api/v1/users/register", "/api/v1/users/login"} {
+		if rr := authRequest(router, "POST", path, "", credentials); rr.Code != http.StatusForbidden {
+			t.Errorf("POST %s with proxy authentication: got status %d, want %d", path, rr.Code, http.StatusForbidden)
+		}
+	}
+	if _, err := handler.Store.GetUserByUsername(context.Background(), "alice@example.com"); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("Expected no user to be registered, got %v", err)
+	}
+
+	// The proxy's alice gets a library of her own, not a registered one's
+	req, _ := http.NewRequest("GET", "/api/v1/users/me", nil)
+	req.RemoteAddr = "10.0.0.1:5555"
+	req.Header.Set("Remote-User", "alice@example.com")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET /users/me through the proxy: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := authRequest(router, "POST", "/api/v1/users/login", "", credentials); rr.Code != http.StatusForbidden {
+		t.Errorf("Logging in to the proxy's user with a password: got status %d, want %d", rr.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// qualityCheck is one check of the data quality report, with a link listing
+// the books it found and, for metadata the providers have, one fixing them.
+type qualityCheck struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+	Link  string `json:"link,omitempty"` // Lists the books; series gaps are linked one by one
+	Fix   string `json:"fix,omitempty"`  // POST to start a metadata backfill
+}
+
+// seriesGapResponse is a series with missing positions, with a link listing
+// the books of the series in order.
+type seriesGapResponse struct {
+	model.SeriesGap
+	Link string `json:"link"`
+}
+
+type qualityResponse struct {
+	Books      int                 `json:"books"`
+	Checks     []qualityCheck      `json:"checks"`
+	SeriesGaps []seriesGapResponse `json:"series_gaps"`
+}
+
+// backfillLink starts the job filling in missing metadata from the providers.
+const backfillLink = "/api/" + APIVersion + "/admin/backfill"
+
+// booksLink returns the URL of the book list filtered by query.
+func booksLink(query url.Values) string {
+	return "/api/" + APIVersion + "/books?" + query.Encode()
+}
+
+// GetDataQualityHandler handles GET /api/admin/quality requests. It reports
+// the books missing an ISBN, a cover or a page count, the read books without
+// a rating and the series with gaps, each with a count and a link to the
+// books concerned, to guide cleanup sessions. The checks the providers can
+// fix link to the metadata backfill too.
+func (h *APIHandler) GetDataQualityHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := h.Store.GetDataQuality(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check data quality: "+err.Error())
+		return
+	}
+	resp := qualityResponse{
+		Books: report.Books,
+		Checks: []qualityCheck{
+			{Name: "missing_isbn", Count: report.MissingISBN, Link: booksLink(url.Values{"missing": {"isbn"}}), Fix: backfillLink},
+			{Name: "missing_cover", Count: report.MissingCover, Link: booksLink(url.Values{"missing": {"cover"}}), Fix: backfillLink},
+			{Name: "missing_page_count", Count: report.MissingPageCount, Link: booksLink(url.Values{"missing": {"page_count"}}), Fix: backfillLink},
+			{Name: "unrated_read", Count: report.UnratedRead, Link: booksLink(url.Values{"status": {"read"}, "missing": {"rating"}})},
+			{Name: "series_gaps", Count: len(report.SeriesGaps)},
+		},
+		SeriesGaps: make([]seriesGapResponse, len(report.SeriesGaps)),
+	}
+	for i, gap := range report.SeriesGaps {
+		resp.SeriesGaps[i] = seriesGapResponse{SeriesGap: gap, Link: booksLink(url.Values{"series": {gap.Series}})}
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestGetDataQualityHandler tests the data quality report and its links
+func TestGetDataQualityHandler(t *testing.T) {
+	for i, suffix := range []string{"quality1", "quality3"} {
+		book := createTestBook(model.StatusRead, suffix)
+		name, index := "Quality Saga", 1+2*i
+		book.Series, book.SeriesIndex = &name, &index
+		if _, err := testStore.AddBook(context.Background(), book); err != nil {
+			t.Fatalf("Failed to add test book: %v", err)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/quality", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %d, body: %s", rr.Code, rr.Body.String())
+	}
+	var resp qualityResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	checks := map[string]qualityCheck{}
+	for _, check := range resp.Checks {
+		checks[check.Name] = check
+	}
+	if checks["unrated_read"].Link != "/api/v1/books?missing=rating&status=read" || checks["missing_page_count"].Count < 2 ||
+		checks["missing_isbn"].Fix != "/api/v1/admin/backfill" || checks["unrated_read"].Fix != "" {
+		t.Errorf("Unexpected checks %+v", resp.Checks)
+	}
+
+	var saga *seriesGapResponse
+	for i := range resp.SeriesGaps {
+		if resp.SeriesGaps[i].Series == "Quality Saga" {
+			saga = &resp.SeriesGaps[i]
+		}
+	}
+	if saga == nil || len(saga.Missing) != 1 || saga.Missing[0] != 2 || saga.Link != "/api/v1/books?series=Quality+Saga" {
+		t.Fatalf("Expected the gap in Quality Saga, got %+v", resp.SeriesGaps)
+	}
+
+	// The link lists the books of the series
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, httptest.NewRequest("GET", "/api"+strings.TrimPrefix(saga.Link, "/api/v1"), nil))
+	var books []BookResponse
+	json.Unmarshal(rr.Body.Bytes(), &books)
+	if rr.Code != http.StatusOK || len(books) != 2 {
+		t.Errorf("Expected the two books of the series, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
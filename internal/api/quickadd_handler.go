@@ -0,0 +1,220 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// maxSpeechBytes caps the size of voice clips sent to be transcribed.
+const maxSpeechBytes = 10 * 1024 * 1024
+
+// quickAdd is what was asked for in a quick add.
+type quickAdd struct {
+	Title  string
+	Author string
+	Status model.BookStatus // Empty unless a shelf was named
+}
+
+// Query returns what the metadata providers are asked for the book.
+func (q quickAdd) Query() string {
+	return strings.TrimSpace(q.Title + " " + q.Author)
+}
+
+var (
+	// quickAddVerb matches the request to add a book leading a quick add,
+	// e.g. "please add".
+	quickAddVerb = regexp.MustCompile(`(?i)^(?:(?:please|hey|ok|okay)[\s,]+)?(?:add|put|shelve|save)\s+`)
+	// quickAddShelf matches a shelf named at the end of a quick add, e.g. "to
+	// my want to read list" or "on the currently reading shelf".
+	quickAddShelf = regexp.MustCompile(`(?i)\s+(?:to|on|onto|in|into|as)\s+(?:my\s+|the\s+)?(want[\s-]to[\s-]read|to[\s-]read|currently[\s-]reading|reading|read|finished)(?:\s+(?:shelf|list|pile))?$`)
+	// quickAddBy splits the title from the author.
+	quickAddBy = regexp.MustCompile(`(?i)\s+by\s+`)
+)
+
+// quickAddShelves maps the shelf names of quick adds to shelves.
+var quickAddShelves = map[string]model.BookStatus{
+	"want to read":      model.StatusWantToRead,
+	"to read":           model.StatusWantToRead,
+	"currently reading": model.StatusCurrentlyReading,
+	"reading":           model.StatusCurrentlyReading,
+	"read":              model.StatusRead,
+	"finished":          model.StatusRead,
+}
+
+// parseQuickAdd reads a quick add such as "add Project Hail Mary by Andy
+// Weir to my want to read list": an optional request to add, the title, an
+// optional author after the last "by" and an optional shelf. The title is
+// empty when there is none.
+func parseQuickAdd(text string) quickAdd {
+	text = strings.TrimSpace(strings.Join(strings.Fields(text), " "))
+	text = strings.TrimRight(text, ".!?,; ")
+	text = quickAddVerb.ReplaceAllString(text, "")
+
+	var q quickAdd
+	if m := quickAddShelf.FindStringSubmatchIndex(text); m != nil {
+		name := strings.ToLower(strings.NewReplacer("-", " ").Replace(text[m[2]:m[3]]))
+		q.Status = quickAddShelves[strings.Join(strings.Fields(name), " ")]
+		text = text[:m[0]]
+	}
+	if by := quickAddBy.FindAllStringIndex(text, -1); len(by) > 0 {
+		last := by[len(by)-1]
+		q.Author = strings.TrimSpace(text[last[1]:])
+		text = text[:last[0]]
+	}
+	q.Title = strings.Trim(strings.TrimSpace(text), `"“”'`)
+	return q
+}
+
+// quickAddResult is the response of the quick add endpoints.
+type quickAddResult struct {
+	Text         string                    `json:"text"` // What was asked for, as transcribed for spoken requests
+	Title        string                    `json:"title"`
+	Author       string                    `json:"author"`
+	Query        string                    `json:"query"` // What the providers were asked
+	Book         *BookResponse             `json:"book"`
+	Alternatives []OpenLibrarySearchResult `json:"alternatives"` // The providers' other results, should the wrong book have been added
+}
+
+// quickAdd adds the book text asks for: it is looked up with the metadata
+// providers, and their first result is added on the shelf text names, or
+// on status, or on the Want to Read shelf. It responds with the outcome.
+func (h *APIHandler) quickAdd(w http.ResponseWriter, r *http.Request, text string, status model.BookStatus) {
+	parsed := parseQuickAdd(text)
+	result := quickAddResult{Text: text, Title: parsed.Title, Author: parsed.Author, Query: parsed.Query(), Alternatives: []OpenLibrarySearchResult{}}
+	if parsed.Title == "" {
+		respondWithError(w, http.StatusUnprocessableEntity, "No title found in "+strconv.Quote(text))
+		return
+	}
+	if parsed.Status != "" {
+		status = parsed.Status
+	}
+	if status == "" {
+		status = model.StatusWantToRead
+	}
+
+	found, err := h.Metadata.Search(r.Context(), result.Query)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	if len(found) == 0 {
+		respondWithError(w, http.StatusNotFound, "No book found for "+strconv.Quote(result.Query))
+		return
+	}
+	book := newBookFromProvider(found[0])
+	book.Status, book.Source = status, model.BookSourceAPI
+	id, err := h.Store.AddBook(r.Context(), &book)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to add book to database")
+		return
+	}
+	book.ID = id
+	h.cacheCover(book)
+
+	response := newBookResponse(&book)
+	result.Book = &response
+	for _, alternative := range found[1:] {
+		result.Alternatives = append(result.Alternatives, newProviderResult(alternative))
+	}
+	respondWithJSON(w, http.StatusCreated, result)
+}
+
+// QuickAddHandler handles POST /api/books/quick requests, adding a book from
+// a sentence such as {"text": "Project Hail Mary by Andy Weir"} in one call.
+// A shelf named in the text ("... to my currently reading list") takes
+// precedence over the optional status of the body.
+func (h *APIHandler) QuickAddHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Text   string           `json:"text"`
+		Status model.BookStatus `json:"status"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 4096)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if strings.TrimSpace(payload.Text) == "" {
+		respondWithError(w, http.StatusBadRequest, "text is required")
+		return
+	}
+	if payload.Status != "" && !payload.Status.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid status. Must be 'Want to Read', 'Currently Reading' or 'Read'")
+		return
+	}
+	h.quickAdd(w, r, payload.Text, payload.Status)
+}
+
+// QuickAddSpeechHandler handles POST /api/books/quick/speech requests, for
+// adding books hands-free while shelving them. The body is a short voice
+// clip ("add Project Hail Mary by Andy Weir"), sent with its audio content
+// type; the speech-to-text provider transcribes it and the transcript is
+// added as by POST /api/books/quick. The optional status query parameter
+// sets the shelf when the clip doesn't name one.
+func (h *APIHandler) QuickAddSpeechHandler(w http.ResponseWriter, r *http.Request) {
+	if h.STT == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Spoken quick adds require the server to be started with --stt-provider")
+		return
+	}
+	status := model.BookStatus(r.URL.Query().Get("status"))
+	if status != "" && !status.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid status. Must be 'Want to Read', 'Currently Reading' or 'Read'")
+		return
+	}
+	clip, contentType, ok := readClip(w, r)
+	if !ok {
+		return
+	}
+	text, err := h.STT.Transcribe(r.Context(), clip, contentType)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, "Failed to transcribe the clip: "+err.Error())
+		return
+	}
+	if text == "" {
+		respondWithError(w, http.StatusUnprocessableEntity, "No speech could be made out in the clip")
+		return
+	}
+	h.quickAdd(w, r, text, status)
+}
+
+// readClip reads the voice clip of a spoken quick add, sent as the request
+// body with an audio content type; browsers record WebM and Ogg, which are
+// also accepted as video/webm and application/ogg. Clips sent without one
+// have it sniffed. It responds with the error and returns false when there
+// is no clip.
+func readClip(w http.ResponseWriter, r *http.Request) ([]byte, string, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxSpeechBytes)
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "Clip must not be larger than 10 MB")
+		} else {
+			respondWithError(w, http.StatusBadRequest, "Failed to read the clip: "+err.Error())
+		}
+		return nil, "", false
+	}
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if !isAudio(contentType) {
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	if len(data) == 0 || !isAudio(contentType) {
+		respondWithError(w, http.StatusBadRequest, "Request body must be a voice clip (e.g. WebM, Ogg, MP3, M4A or WAV)")
+		return nil, "", false
+	}
+	return data, contentType, true
+}
+
+// isAudio reports whether contentType is that of a voice clip.
+func isAudio(contentType string) bool {
+	return strings.HasPrefix(contentType, "audio/") || contentType == "video/webm" || contentType == "application/ogg"
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/metadata"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestParseQuickAdd(t *testing.T) {
+	for text, want := range map[string]quickAdd{
+		"add Project Hail Mary by Andy Weir":                             {Title: "Project Hail Mary", Author: "Andy Weir"},
+		"Please add Dune by Frank Herbert to my currently reading list.": {Title: "Dune", Author: "Frank Herbert", Status: model.StatusCurrentlyReading},
+		"Add Stand By Me by Stephen King to my to-read shelf":            {Title: "Stand By Me", Author: "Stephen King", Status: model.StatusWantToRead},
+		"add Middlemarch as read":                                        {Title: "Middlemarch", Status: model.StatusRead},
+		"The Left Hand of Darkness":                                      {Title: "The Left Hand of Darkness"},
+		"add":                                                            {Title: "add"},
+		"  ":                                                             {},
+	} {
+		if got := parseQuickAdd(text); got != want {
+			t.Errorf("parseQuickAdd(%q) = %+v, want %+v", text, got, want)
+		}
+	}
+}
+
+// fixedSTT hears the same words in every clip.
+type fixedSTT string
+
+func (f fixedSTT) Name() string { return "fixed" }
+
+func (f fixedSTT) Transcribe(ctx context.Context, audio []byte, contentType string) (string, error) {
+	return string(f), nil
+}
+
+// TestQuickAddHandlers tests adding a book from a sentence, typed and spoken
+func TestQuickAddHandlers(t *testing.T) {
+	chain := testHandler.Metadata
+	defer func() { testHandler.Metadata = chain }()
+	testHandler.Metadata = metadata.Chain{titleProvider{
+		query: "Project Hail Mary Andy Weir",
+		book:  metadata.Book{Provider: "title", ID: "OLHAILMARY1M", Title: "Project Hail Mary", Authors: []string{"Andy Weir"}, PageCount: 496},
+	}}
+	do := func(path, contentType string, body []byte) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, bytes.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := do("/api/books/quick", "", []byte(`{"text": "add The Unfindable Book"}`)); rr.Code != http.StatusNotFound {
+		t.Errorf("Unknown book: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := do("/api/books/quick", "", []byte(`{"text": "add Dune", "status": "Shelved"}`)); rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid status: got status %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	// Spoken adds need a speech-to-text provider
+	if rr := do("/api/books/quick/speech", "audio/webm", []byte("webm")); rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 without a provider, got %d", rr.Code)
+	}
+	testHandler.STT = fixedSTT("Add Project Hail Mary by Andy Weir to my currently reading list.")
+	defer func() { testHandler.STT = nil }()
+	if rr := do("/api/books/quick/speech", "text/plain", []byte("add Dune")); rr.Code != http.StatusBadRequest {
+		t.Errorf("Sending text: got status %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	rr := do("/api/books/quick/speech?status=Read", "audio/webm;codecs=opus", []byte("webm"))
+	var result quickAddResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil || rr.Code != http.StatusCreated || result.Book == nil {
+		t.Fatalf("Expected the book added, got %d: %s", rr.Code, rr.Body.String())
+	}
+	defer testStore.PurgeBook(context.Background(), result.Book.ID)
+	// The shelf spoken takes precedence over the query parameter
+	if result.Title != "Project Hail Mary" || result.Author != "Andy Weir" || result.Book.Status != model.StatusCurrentlyReading ||
+		result.Book.PageCount == nil || *result.Book.PageCount != 496 || !strings.HasPrefix(result.Text, "Add Project") {
+		t.Errorf("Unexpected result %+v, book %+v", result, result.Book)
+	}
+
+	if rr := do("/api/books/quick", "", []byte(`{"text": "Project Hail Mary by Andy Weir"}`)); rr.Code != http.StatusConflict {
+		t.Errorf("Adding the book twice: got status %d, want %d", rr.Code, http.StatusConflict)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// decodeQuote reads a quote of the book in the URL from the request body,
+// {"text": "...", "page": 42}. It responds with the error and returns nil
+// when the request is invalid.
+func decodeQuote(w http.ResponseWriter, r *http.Request) *model.Quote {
+	bookID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return nil
+	}
+	var payload struct {
+		Text string `json:"text"`
+		Page *int   `json:"page"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return nil
+	}
+	return &model.Quote{BookID: bookID, Text: payload.Text, Page: payload.Page}
+}
+
+// GetBookQuotesHandler handles GET /api/books/{id}/quotes requests and
+// returns the book's quotes, in the order they were added.
+func (h *APIHandler) GetBookQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	quotes, err := h.Store.GetQuotes(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve quotes")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, quotes)
+}
+
+// AddBookQuoteHandler handles POST /api/books/{id}/quotes requests, adding a
+// passage to the book's quotes. The page is optional.
+func (h *APIHandler) AddBookQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	quote := decodeQuote(w, r)
+	if quote == nil {
+		return
+	}
+	if err := h.Store.AddQuote(r.Context(), quote); err != nil {
+		respondWithStoreError(w, err, "Failed to add quote")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, quote)
+}
+
+// UpdateBookQuoteHandler handles PUT /api/books/{id}/quotes/{quoteID}
+// requests, replacing a quote with the body of the request.
+func (h *APIHandler) UpdateBookQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	quoteID, err := strconv.ParseInt(mux.Vars(r)["quoteID"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid quote ID")
+		return
+	}
+	quote := decodeQuote(w, r)
+	if quote == nil {
+		return
+	}
+	quote.ID = quoteID
+	if err := h.Store.UpdateQuote(r.Context(), quote); err != nil {
+		respondWithStoreError(w, err, "Failed to update quote")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, quote)
+}
+
+// DeleteBookQuoteHandler handles DELETE /api/books/{id}/quotes/{quoteID}
+// requests.
+func (h *APIHandler) DeleteBookQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	quoteID, err := strconv.ParseInt(vars["quoteID"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid quote ID")
+		return
+	}
+	if err := h.Store.DeleteQuote(r.Context(), id, quoteID); err != nil {
+		respondWithStoreError(w, err, "Failed to delete quote")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetRandomQuoteHandler handles GET /api/quotes/random requests, returning
+// one of the quotes of any book on the shelf with its title and author, for
+// the dashboard.
+func (h *APIHandler) GetRandomQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	quote, err := h.Store.GetRandomQuote(r.Context())
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to pick a quote")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, quote)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestBookQuoteHandlers tests collecting, editing and deleting quotes, and
+// picking one at random
+func TestBookQuoteHandlers(t *testing.T) {
+	book := createTestBook(model.StatusRead, "Quotes")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+	path := "/api/books/" + itoa(id) + "/quotes"
+
+	rr := do("POST", path, `{"text": "All that is gold does not glitter.", "page": 170}`)
+	var quote model.Quote
+	if err := json.Unmarshal(rr.Body.Bytes(), &quote); err != nil || rr.Code != http.StatusCreated || quote.ID == 0 || quote.BookID != id {
+		t.Fatalf("Expected the quote added, got %d: %s", rr.Code, rr.Body.String())
+	}
+	for _, body := range []string{`{}`, `{"text": " "}`, `{"text": "x", "page": 0}`, `{"text": "x", "chapter": 3}`} {
+		if rr := do("POST", path, body); rr.Code != http.StatusBadRequest {
+			t.Errorf("POST %s: expected 400, got %d: %s", body, rr.Code, rr.Body.String())
+		}
+	}
+	if rr := do("POST", "/api/books/999999/quotes", `{"text": "x"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing book, got %d", rr.Code)
+	}
+
+	rr = do("PUT", path+"/"+itoa(quote.ID), `{"text": "Not all those who wander are lost."}`)
+	var updated model.Quote
+	if err := json.Unmarshal(rr.Body.Bytes(), &updated); err != nil || rr.Code != http.StatusOK || updated.Page != nil {
+		t.Fatalf("Expected the quote replaced, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("PUT", path+"/999999", `{"text": "x"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing quote, got %d", rr.Code)
+	}
+
+	rr = do("GET", path, "")
+	var quotes []model.Quote
+	if err := json.Unmarshal(rr.Body.Bytes(), &quotes); err != nil || len(quotes) != 1 || quotes[0].Text != "Not all those who wander are lost." {
+		t.Errorf("Expected the updated quote listed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = do("GET", "/api/quotes/random", "")
+	var random model.RandomQuote
+	if err := json.Unmarshal(rr.Body.Bytes(), &random); err != nil || rr.Code != http.StatusOK || random.ID == 0 || random.Title == "" {
+		t.Errorf("Expected a random quote with its book, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := do("DELETE", path+"/"+itoa(quote.ID), ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 deleting the quote, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("DELETE", path+"/"+itoa(quote.ID), ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting the quote again, got %d", rr.Code)
+	}
+}
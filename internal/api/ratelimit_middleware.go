@@ -0,0 +1,137 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/ratelimit"
+	"github.com/gorilla/mux"
+)
+
+// RateLimits limits how often each client can change things and search Open
+// Library, so one client hammering an exposed instance can't swamp it or use
+// up the instance's Open Library budget. Clients are told apart by the API
+// key or session token they send, or else their address.
+type RateLimits struct {
+	trusted []netip.Prefix
+	now     func() time.Time // Overridable for tests
+
+	mu                sync.Mutex
+	writes            *ratelimit.Buckets // Nil for no limit
+	searches          *ratelimit.Buckets // Nil for no limit
+	writesPerMinute   int
+	searchesPerMinute int
+}
+
+// NewRateLimits returns rate limits allowing each client writesPerMinute
+// requests that change something and searchesPerMinute searches, in bursts of
+// as many, with 0 for no limit. Behind the proxies of the comma-separated
+// trustedProxies, clients' addresses are taken from X-Forwarded-For.
+func NewRateLimits(writesPerMinute, searchesPerMinute int, trustedProxies string) (*RateLimits, error) {
+	return newRateLimits(writesPerMinute, searchesPerMinute, trustedProxies, time.Now)
+}
+
+// newRateLimits is NewRateLimits with buckets telling the time with now.
+func newRateLimits(writesPerMinute, searchesPerMinute int, trustedProxies string, now func() time.Time) (*RateLimits, error) {
+	trusted, err := parsePrefixes(trustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	l := &RateLimits{trusted: trusted, now: now}
+	if err := l.Update(writesPerMinute, searchesPerMinute); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Update changes the limits while they're in use, such as when the
+// configuration is reloaded. Clients keep their buckets under a limit that
+// didn't change, and start with full ones under a limit that did.
+func (l *RateLimits) Update(writesPerMinute, searchesPerMinute int) error {
+	if writesPerMinute < 0 || searchesPerMinute < 0 {
+		return fmt.Errorf("rate limits can't be negative")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if writesPerMinute != l.writesPerMinute {
+		l.writes, l.writesPerMinute = perMinute(writesPerMinute, l.now), writesPerMinute
+	}
+	if searchesPerMinute != l.searchesPerMinute {
+		l.searches, l.searchesPerMinute = perMinute(searchesPerMinute, l.now), searchesPerMinute
+	}
+	return nil
+}
+
+// perMinute returns buckets allowing n requests a minute, or nil for 0.
+func perMinute(n int, now func() time.Time) *ratelimit.Buckets {
+	if n == 0 {
+		return nil
+	}
+	return ratelimit.NewBucketsWithClock(float64(n)/60, n, now)
+}
+
+// buckets returns the buckets limiting r, or nil for none.
+func (l *RateLimits) buckets(r *http.Request) *ratelimit.Buckets {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil && specPath(template) == "/books/search" {
+			return l.searches
+		}
+	}
+	if isMutating(r) {
+		return l.writes
+	}
+	return nil
+}
+
+// client returns the key of the client that sent r. Tokens are hashed, so the
+// buckets don't hold credentials.
+func (l *RateLimits) client(r *http.Request) string {
+	if token := bearerToken(r); token != "" {
+		sum := sha256.Sum256([]byte(token))
+		return "token:" + hex.EncodeToString(sum[:])
+	}
+	return "addr:" + clientAddr(r, l.trusted)
+}
+
+// seconds rounds d up to whole seconds, for headers.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// RateLimitMiddleware refuses requests over their client's rate limit with
+// 429 Too Many Requests. Limited requests carry RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers, and refused ones
+// Retry-After. It runs after UserMiddleware, which refuses invalid tokens, so
+// made-up tokens can't dodge the limit of an address.
+func (h *APIHandler) RateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.RateLimits == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		buckets := h.RateLimits.buckets(r)
+		if buckets == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		q := buckets.Take(h.RateLimits.client(r))
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(q.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(q.Remaining))
+		w.Header().Set("RateLimit-Reset", seconds(q.Reset))
+		if !q.Allowed {
+			w.Header().Set("Retry-After", seconds(q.RetryAfter))
+			respondWithError(w, http.StatusTooManyRequests, "Too many requests; try again in "+seconds(q.RetryAfter)+" seconds")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	if _, err := NewRateLimits(-1, 0, DefaultTrustedProxies); err == nil {
+		t.Error("Expected a negative limit to be rejected")
+	}
+	handler := newTestAPIHandler(t)
+	var err error
+	// A fixed clock, so registering and logging in, however slow, refill nothing
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	if handler.RateLimits, err = newRateLimits(2, 1, "10.0.0.1", func() time.Time { return now }); err != nil {
+		t.Fatalf("NewRateLimits failed: %v", err)
+	}
+	router := SetupRouter(handler, t.TempDir())
+
+	// Registering and logging in use up the address's two writes
+	token := loginTestUser(t, router)
+	rr := authRequest(router, "POST", "/api/v1/users/login", "", `{"username":"alice","password":"correct horse"}`)
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "30" || rr.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("A third write from the address: got status %d, headers %v", rr.Code, rr.Header())
+	}
+
+	// Alice's token has a bucket of its own
+	vacation := `{"start_date":"2025-07-01","end_date":"2025-07-14"}`
+	rr = authRequest(router, "POST", "/api/v1/vacations", token, vacation)
+	if rr.Code != http.StatusCreated || rr.Header().Get("RateLimit-Limit") != "2" || rr.Header().Get("RateLimit-Remaining") != "1" || rr.Header().Get("RateLimit-Reset") != "30" {
+		t.Errorf("A write with a token: got status %d, headers %v", rr.Code, rr.Header())
+	}
+	authRequest(router, "POST", "/api/v1/vacations", token, vacation)
+	if rr := authRequest(router, "POST", "/api/v1/vacations", token, vacation); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("A third write with a token: got status %d, want %d", rr.Code, http.StatusTooManyRequests)
+	}
+
+	// Reading isn't limited
+	if rr := authRequest(router, "GET", "/api/v1/vacations", token, ""); rr.Code != http.StatusOK || rr.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("Reading: got status %d, headers %v", rr.Code, rr.Header())
+	}
+
+	// Behind a trusted proxy, clients are told apart by X-Forwarded-For
+	fromProxy := func(client string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/v1/users/login", strings.NewReader(`{"username":"alice","password":"wrong horse"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "10.0.0.1:4444"
+		req.Header.Set("X-Forwarded-For", client)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if rr := fromProxy("198.51.100.7, 203.0.113.9"); rr.Code != want {
+			t.Errorf("Login %d through the proxy: got status %d, want %d", i+1, rr.Code, want)
+		}
+	}
+	if rr := fromProxy("203.0.113.10"); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Login from another client through the proxy: got status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+
+	// Searches have a limit of their own
+	if rr := authRequest(router, "GET", "/api/v1/books/search?q=dune", token, ""); rr.Code == http.StatusTooManyRequests || rr.Header().Get("RateLimit-Limit") != "1" {
+		t.Errorf("A search: got status %d, headers %v", rr.Code, rr.Header())
+	}
+	if rr := authRequest(router, "GET", "/api/v1/books/search?q=dune", token, ""); rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "60" {
+		t.Errorf("A second search: got status %d, headers %v", rr.Code, rr.Header())
+	}
+
+	// Changed limits start with full buckets; unchanged ones keep theirs
+	if err := handler.RateLimits.Update(2, 3); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if rr := authRequest(router, "GET", "/api/v1/books/search?q=dune", token, ""); rr.Code == http.StatusTooManyRequests || rr.Header().Get("RateLimit-Remaining") != "2" {
+		t.Errorf("A search after raising the limit: got status %d, headers %v", rr.Code, rr.Header())
+	}
+	if rr := authRequest(router, "POST", "/api/v1/vacations", token, vacation); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("A write after raising the search limit: got status %d, want %d", rr.Code, http.StatusTooManyRequests)
+	}
+	if err := handler.RateLimits.Update(0, -1); err == nil {
+		t.Error("Expected a negative limit to be rejected")
+	}
+	if err := handler.RateLimits.Update(0, 0); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if rr := authRequest(router, "POST", "/api/v1/vacations", token, vacation); rr.Code != http.StatusCreated || rr.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("A write without a limit: got status %d, headers %v", rr.Code, rr.Header())
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// readPayload is the body of POST /api/books/{id}/reads.
+type readPayload struct {
+	DateStarted  *time.Time `json:"date_started"`
+	DateFinished *time.Time `json:"date_finished"`
+}
+
+// GetBookReadsHandler handles GET /api/books/{id}/reads requests and returns
+// the book's reading history, most recent first.
+func (h *APIHandler) GetBookReadsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	reads, err := h.Store.GetReads(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve reads")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, reads)
+}
+
+// AddBookReadHandler handles POST /api/books/{id}/reads requests, logging a
+// completed read. Expects {"date_finished": "...", "date_started": "..."};
+// date_started is optional.
+func (h *APIHandler) AddBookReadHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	var payload readPayload
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	read := &model.Read{BookID: id, DateStarted: payload.DateStarted}
+	if payload.DateFinished != nil {
+		read.DateFinished = *payload.DateFinished
+	}
+	if err := h.Store.AddRead(r.Context(), read); err != nil {
+		respondWithStoreError(w, err, "Failed to log read")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, read)
+}
+
+// DeleteBookReadHandler handles DELETE /api/books/{id}/reads/{readID} requests.
+func (h *APIHandler) DeleteBookReadHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	readID, err := strconv.ParseInt(vars["readID"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid read ID")
+		return
+	}
+	if err := h.Store.DeleteRead(r.Context(), id, readID); err != nil {
+		respondWithStoreError(w, err, "Failed to delete read")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
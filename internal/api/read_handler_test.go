@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestReadHandlers tests finishing a book, logging a re-read and listing the history
+func TestReadHandlers(t *testing.T) {
+	book := createTestBook(model.StatusCurrentlyReading, "Reads")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := do("PUT", "/api/books/"+itoa(id), `{"status":"Read"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Finishing the book: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	rr := do("GET", "/api/books/"+itoa(id), "")
+	var resp BookResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if resp.DateFinished == nil {
+		t.Errorf("Expected date_finished once the book is read, got %s", rr.Body.String())
+	}
+
+	rr = do("POST", "/api/books/"+itoa(id)+"/reads", `{"date_started":"2020-01-01T00:00:00Z","date_finished":"2020-02-01T00:00:00Z"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Logging a read: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	var logged model.Read
+	json.Unmarshal(rr.Body.Bytes(), &logged)
+
+	for _, tt := range []struct {
+		method, path, body string
+		want               int
+	}{
+		{"POST", "/api/books/" + itoa(id) + "/reads", `{}`, http.StatusBadRequest},
+		{"POST", "/api/books/" + itoa(id) + "/reads", `{"date_started":"2020-03-01T00:00:00Z","date_finished":"2020-02-01T00:00:00Z"}`, http.StatusBadRequest},
+		{"POST", "/api/books/999999/reads", `{"date_finished":"2020-02-01T00:00:00Z"}`, http.StatusNotFound},
+		{"GET", "/api/books/999999/reads", "", http.StatusNotFound},
+		{"DELETE", "/api/books/999999/reads/" + itoa(logged.ID), "", http.StatusNotFound},
+	} {
+		if rr := do(tt.method, tt.path, tt.body); rr.Code != tt.want {
+			t.Errorf("%s %s %s: got status %d, want %d", tt.method, tt.path, tt.body, rr.Code, tt.want)
+		}
+	}
+
+	rr = do("GET", "/api/books/"+itoa(id)+"/reads", "")
+	var reads []model.Read
+	if err := json.Unmarshal(rr.Body.Bytes(), &reads); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if len(reads) != 2 || reads[1].ID != logged.ID {
+		t.Errorf("Expected the finished read then the logged one, got %+v", reads)
+	}
+
+	// With two reads the book counts as re-read
+	rr = do("GET", "/api/books?reread=true", "")
+	var books []BookResponse
+	json.Unmarshal(rr.Body.Bytes(), &books)
+	found := false
+	for _, b := range books {
+		found = found || b.ID == id
+	}
+	if rr.Code != http.StatusOK || !found {
+		t.Errorf("Expected the book among re-read books, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/books?reread=maybe", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid reread filter: got status %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+	rr = do("GET", "/api/stats/reads", "")
+	var stats model.RereadStats
+	json.Unmarshal(rr.Body.Bytes(), &stats)
+	found = false
+	for _, b := range stats.MostReread {
+		found = found || b.BookID == id && b.Reads == 2
+	}
+	if rr.Code != http.StatusOK || !found {
+		t.Errorf("Expected the book among the most re-read, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/stats/reads?limit=0", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid limit: got status %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	if rr := do("DELETE", "/api/books/"+itoa(id)+"/reads/"+itoa(logged.ID), ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Deleting a read: got status %d", rr.Code)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
@@ -8,30 +9,47 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/requestid"
 	"github.com/gorilla/mux"
 	"github.com/klauspost/compress/gzip"
 )
 
-// LoggingMiddleware logs incoming HTTP requests.
+// LoggingMiddleware gives each request an ID and logs the request once it's
+// been served, with its status, duration and the bytes sent. The ID is that
+// of the X-Request-ID header when a reverse proxy sent a valid one, and is
+// sent back in it. Log lines made with the request's context while serving
+// it, such as those of its SQL queries, carry the ID too. Requests are logged
+// by their route rather than their URI, which is redacted: URIs hold share
+// tokens and what people search for.
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		// Use a response writer wrapper if you need to capture status code
-		// For simple logging, this is often sufficient.
-		slog.Debug("HTTP Request started",
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		w.Header().Set(requestid.Header, id)
+		r = r.WithContext(requestid.NewContext(r.Context(), id))
+		slog.DebugContext(r.Context(), "HTTP Request started",
 			"method", r.Method,
-			"uri", r.RequestURI,
+			logging.Redact("uri", r.RequestURI),
 			"remoteAddr", r.RemoteAddr)
 
-		next.ServeHTTP(w, r) // Call the next handler
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r) // Call the next handler
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
 
-		// Log after the request is handled
-		// Note: Status code logging requires a response writer wrapper.
-		// For now, just log duration.
-		slog.Info("HTTP Request completed",
+		slog.InfoContext(r.Context(), "HTTP Request completed",
 			"method", r.Method,
-			"uri", r.RequestURI,
-			"duration", time.Since(start))
+			logging.Redact("uri", r.RequestURI),
+			"route", routeLabel(r),
+			"status", recorder.status,
+			"duration", time.Since(start),
+			"bytes", recorder.bytes,
+			"remoteAddr", r.RemoteAddr)
 	})
 }
 
@@ -70,23 +88,79 @@ func (w gzipResponseWriter) Write(b []byte) (int, error) {
 	return w.Writer.Write(b)
 }
 
+// APIVersion is the current version of the REST API, served under /api/v1.
+const APIVersion = "v1"
+
+// Legacy unversioned routes under /api keep working as an alias of the current
+// version, but announce their retirement with Deprecation (RFC 9745) and
+// Sunset (RFC 8594) headers so clients can move to /api/v1 before breaking
+// changes land there.
+var (
+	LegacyAPIDeprecatedAt = time.Date(2026, time.October, 14, 0, 0, 0, 0, time.UTC)
+	LegacyAPISunset       = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// VersionMiddleware reports the API version that served the request.
+func VersionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", APIVersion)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// DeprecationMiddleware marks responses from the unversioned /api routes as
+// deprecated and links each one to its versioned successor.
+func DeprecationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		successor := "/api/" + APIVersion + strings.TrimPrefix(r.URL.Path, "/api")
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", LegacyAPIDeprecatedAt.Unix()))
+		w.Header().Set("Sunset", LegacyAPISunset.Format(http.TimeFormat))
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		w.Header().Set("API-Version", APIVersion)
+		next.ServeHTTP(w, r)
+	})
+}
+
 // SetupRouter configures the routes for the application.
 func SetupRouter(apiHandler *APIHandler, webDir string) *mux.Router {
 	r := mux.NewRouter()
 
 	// Apply middlewares to all routes
 	r.Use(LoggingMiddleware)
+	r.Use(MetricsMiddleware)
 	r.Use(GzipMiddleware)
 
-	// API Routes (prefixed with /api)
-	apiRouter := r.PathPrefix("/api").Subrouter()
-	apiRouter.HandleFunc("/books", apiHandler.GetBooksHandler).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/books", apiHandler.AddBookHandler).Methods(http.MethodPost)
-	apiRouter.HandleFunc("/books/{id:[0-9]+}", apiHandler.UpdateBookStatusHandler).Methods(http.MethodPut)          // For status update
-	apiRouter.HandleFunc("/books/{id:[0-9]+}/type", apiHandler.UpdateBookTypeHandler).Methods(http.MethodPut)       // For type update
-	apiRouter.HandleFunc("/books/{id:[0-9]+}/details", apiHandler.UpdateBookDetailsHandler).Methods(http.MethodPut) // For rating/comments
-	apiRouter.HandleFunc("/books/search", apiHandler.SearchBooksHandler).Methods(http.MethodGet)                    // Expects ?q=query
-	apiRouter.HandleFunc("/books/{id:[0-9]+}", apiHandler.DeleteBookHandler).Methods(http.MethodDelete)             // Delete a book
+	// API Routes. The versioned prefix must be registered first, since /api
+	// would otherwise also match /api/v1/... paths.
+	v1Router := r.PathPrefix("/api/" + APIVersion).Subrouter()
+	v1Router.Use(VersionMiddleware, apiHandler.AdminMiddleware, apiHandler.UserMiddleware, apiHandler.AdminRoleMiddleware, apiHandler.RateLimitMiddleware, ValidationMiddleware)
+	registerAPIRoutes(v1Router, apiHandler)
+
+	legacyRouter := r.PathPrefix("/api").Subrouter()
+	legacyRouter.Use(DeprecationMiddleware, apiHandler.AdminMiddleware, apiHandler.UserMiddleware, apiHandler.AdminRoleMiddleware, apiHandler.RateLimitMiddleware, ValidationMiddleware)
+	registerAPIRoutes(legacyRouter, apiHandler)
+
+	// ActivityPub actors (optional): each user's, and the library's
+	if apiHandler.ActivityPub != nil {
+		r.HandleFunc("/.well-known/webfinger", apiHandler.WebFingerHandler).Methods(http.MethodGet)
+		r.HandleFunc("/ap/users/{username}", apiHandler.ActorHandler).Methods(http.MethodGet)
+		r.HandleFunc("/ap/users/{username}/outbox", apiHandler.OutboxHandler).Methods(http.MethodGet)
+		r.HandleFunc("/ap/users/{username}/inbox", apiHandler.InboxHandler).Methods(http.MethodPost)
+		r.HandleFunc("/ap/users/{username}/followers", apiHandler.FollowersHandler).Methods(http.MethodGet)
+		r.HandleFunc("/ap/users/{username}/notes/{id:[0-9]+}", apiHandler.NoteHandler).Methods(http.MethodGet)
+		r.HandleFunc("/ap/actor", apiHandler.ActorHandler).Methods(http.MethodGet)
+		r.HandleFunc("/ap/outbox", apiHandler.OutboxHandler).Methods(http.MethodGet)
+		r.HandleFunc("/ap/inbox", apiHandler.InboxHandler).Methods(http.MethodPost)
+		r.HandleFunc("/ap/followers", apiHandler.FollowersHandler).Methods(http.MethodGet)
+		r.HandleFunc("/ap/notes/{id:[0-9]+}", apiHandler.NoteHandler).Methods(http.MethodGet)
+	}
+
+	// Prometheus metrics, outside the API so scrapers need no credentials
+	r.HandleFunc("/metrics", apiHandler.MetricsHandler).Methods(http.MethodGet)
+
+	// Liveness and readiness probes for container orchestration, likewise
+	r.HandleFunc("/healthz", apiHandler.HealthzHandler).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc("/readyz", apiHandler.ReadyzHandler).Methods(http.MethodGet, http.MethodHead)
 
 	// Static File Server for Frontend
 	// Serve files from the web directory.
@@ -109,6 +183,176 @@ func SetupRouter(apiHandler *APIHandler, webDir string) *mux.Router {
 	return r
 }
 
+// registerAPIRoutes adds the REST API routes to apiRouter, which is mounted
+// under a version prefix.
+func registerAPIRoutes(apiRouter *mux.Router, apiHandler *APIHandler) {
+	apiRouter.HandleFunc("/books", apiHandler.GetBooksHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books", apiHandler.AddBookHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books", apiHandler.DeleteBooksHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/books/batch", apiHandler.BatchAddBooksHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/isbn/{isbn}", apiHandler.AddBookByISBNHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/scan/cover", apiHandler.ScanCoverHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/scan/shelf", apiHandler.ScanShelfHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/quick", apiHandler.QuickAddHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/quick/speech", apiHandler.QuickAddSpeechHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}", apiHandler.GetBookHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}", apiHandler.PatchBookHandler).Methods(http.MethodPatch)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}", apiHandler.UpdateBookStatusHandler).Methods(http.MethodPut)          // For status update
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/type", apiHandler.UpdateBookTypeHandler).Methods(http.MethodPut)       // For type update
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/details", apiHandler.UpdateBookDetailsHandler).Methods(http.MethodPut) // For rating/comments
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/study", apiHandler.UpdateBookStudyHandler).Methods(http.MethodPut)     // For edition/course/semester/reading mode
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/sharing", apiHandler.UpdateBookSharingHandler).Methods(http.MethodPut) // For fediverse opt-out/spoilers
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/favorite", apiHandler.UpdateBookFavoriteHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/progress", apiHandler.GetBookProgressHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/progress", apiHandler.UpdateBookProgressHandler).Methods(http.MethodPatch)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/cover", apiHandler.GetBookCoverHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/tags", apiHandler.GetBookTagsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/tags", apiHandler.AddBookTagHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/tags/{tagID:[0-9]+}", apiHandler.RemoveBookTagHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/collections", apiHandler.GetBookCollectionsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/reads", apiHandler.GetBookReadsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/reads", apiHandler.AddBookReadHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/reads/{readID:[0-9]+}", apiHandler.DeleteBookReadHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/notes", apiHandler.GetBookNotesHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/notes", apiHandler.AddBookNoteHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/notes/{noteID:[0-9]+}", apiHandler.UpdateBookNoteHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/notes/{noteID:[0-9]+}", apiHandler.DeleteBookNoteHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/quotes", apiHandler.GetBookQuotesHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/quotes", apiHandler.AddBookQuoteHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/quotes/{quoteID:[0-9]+}", apiHandler.UpdateBookQuoteHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/quotes/{quoteID:[0-9]+}", apiHandler.DeleteBookQuoteHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/quotes/random", apiHandler.GetRandomQuoteHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/loans", apiHandler.GetBookLoansHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/loans", apiHandler.LendBookHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/loans/return", apiHandler.ReturnBookHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/value", apiHandler.GetBookValueHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/loans", apiHandler.GetOutstandingLoansHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/disposals", apiHandler.AddBookDisposalHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/series/suggestion", apiHandler.GetSeriesSuggestionHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/merge", apiHandler.MergeBookHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/history", apiHandler.GetBookHistoryHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/duplicates", apiHandler.FindDuplicatesHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/search", apiHandler.SearchBooksHandler).Methods(http.MethodGet)         // Expects ?q=query
+	apiRouter.HandleFunc("/books/{id:[0-9]+}", apiHandler.DeleteBookHandler).Methods(http.MethodDelete)  // Move a book to the trash
+	apiRouter.HandleFunc("/export", apiHandler.ExportHandler).Methods(http.MethodGet)                    // Full or differential backup
+	apiRouter.HandleFunc("/export/insurance", apiHandler.ExportInsuranceHandler).Methods(http.MethodGet) // Inventory with values and covers
+	apiRouter.HandleFunc("/lists/export", apiHandler.ExportListHandler).Methods(http.MethodGet)          // Shareable list file
+	apiRouter.HandleFunc("/lists/import", apiHandler.ImportListHandler).Methods(http.MethodPost)         // Import a shared list file
+	apiRouter.HandleFunc("/compare", apiHandler.CompareLibraryHandler).Methods(http.MethodPost)          // Diff against a friend's export
+	apiRouter.HandleFunc("/feed.json", apiHandler.FeedHandler).Methods(http.MethodGet)                   // Public activity feed
+	apiRouter.HandleFunc("/timeline", apiHandler.TimelineHandler).Methods(http.MethodGet)                // Local + followed activity
+	apiRouter.HandleFunc("/tags", apiHandler.GetTagsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/tags/{id:[0-9]+}", apiHandler.RenameTagHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/tags/{id:[0-9]+}", apiHandler.DeleteTagHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/authors", apiHandler.GetAuthorsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/authors", apiHandler.SetAuthorHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/authors/{id:[0-9]+}", apiHandler.GetAuthorHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/authors/{id:[0-9]+}", apiHandler.DeleteAuthorHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/authors/{id:[0-9]+}/books", apiHandler.GetAuthorBooksHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/stats", apiHandler.GetReadingStatsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/stats/diversity", apiHandler.GetDiversityStatsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/stats/reads", apiHandler.GetRereadStatsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/stats/length", apiHandler.GetLengthStatsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/stats/value", apiHandler.GetCollectionValueHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/onthisday", apiHandler.GetOnThisDayHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/vacations", apiHandler.GetVacationsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/vacations", apiHandler.AddVacationHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/vacations/{id:[0-9]+}", apiHandler.DeleteVacationHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/disposals", apiHandler.GetDisposalsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/disposals/report", apiHandler.GetDisposalReportHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/disposals/{id:[0-9]+}", apiHandler.DeleteDisposalHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/collections", apiHandler.GetCollectionsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/collections", apiHandler.AddCollectionHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/collections/{id:[0-9]+}", apiHandler.GetCollectionHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/collections/{id:[0-9]+}", apiHandler.UpdateCollectionHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/collections/{id:[0-9]+}", apiHandler.DeleteCollectionHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/collections/{id:[0-9]+}/books/{bookID:[0-9]+}", apiHandler.AddCollectionBookHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/collections/{id:[0-9]+}/books/{bookID:[0-9]+}", apiHandler.RemoveCollectionBookHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/presets", apiHandler.GetShelfPresetsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/presets", apiHandler.AddShelfPresetHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/presets/{id:[0-9]+}", apiHandler.GetShelfPresetHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/presets/{id:[0-9]+}", apiHandler.UpdateShelfPresetHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/presets/{id:[0-9]+}", apiHandler.DeleteShelfPresetHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/goals", apiHandler.GetReadingGoalsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/goals/{year:[0-9]+}", apiHandler.GetReadingGoalHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/goals/{year:[0-9]+}", apiHandler.SetReadingGoalHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/goals/{year:[0-9]+}", apiHandler.DeleteReadingGoalHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/imports", apiHandler.GetImportsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/imports/{id:[0-9]+}/rollback", apiHandler.RollbackImportHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/merges", apiHandler.GetMergesHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/merges/{id:[0-9]+}/unmerge", apiHandler.UnmergeHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/trash", apiHandler.GetTrashHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/trash/{id:[0-9]+}/restore", apiHandler.RestoreBookHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/trash/{id:[0-9]+}", apiHandler.PurgeBookHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/deletions", apiHandler.GetBulkDeletionsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/deletions/{id:[0-9]+}/undo", apiHandler.UndoBulkDeletionHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/users/register", apiHandler.RegisterHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/users/login", apiHandler.LoginHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/users/logout", apiHandler.LogoutHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/users/me", apiHandler.CurrentUserHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/users/me", apiHandler.UpdateCurrentUserHandler).Methods(http.MethodPatch)
+	apiRouter.HandleFunc("/users/{username}/feed.json", apiHandler.FeedHandler).Methods(http.MethodGet) // A user's activity feed, if public
+	apiRouter.HandleFunc("/users/passkeys", apiHandler.GetPasskeysHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/users/passkeys/register/begin", apiHandler.BeginPasskeyRegistrationHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/users/passkeys/register/finish", apiHandler.FinishPasskeyRegistrationHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/users/passkeys/{id:[0-9]+}", apiHandler.DeletePasskeyHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/users/passkeys/login/begin", apiHandler.BeginPasskeyLoginHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/users/passkeys/login/finish", apiHandler.FinishPasskeyLoginHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/api-keys", apiHandler.GetAPIKeysHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/api-keys", apiHandler.CreateAPIKeyHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/api-keys/{id:[0-9]+}", apiHandler.DeleteAPIKeyHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/share-links", apiHandler.GetShareLinksHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/share-links", apiHandler.CreateShareLinkHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/share-links/{id:[0-9]+}", apiHandler.DeleteShareLinkHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/shared/{token}", apiHandler.GetSharedLibraryHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/wishlist/members", apiHandler.GetWishlistMembersHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/wishlist/members", apiHandler.AddWishlistMemberHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/wishlist/members/{userID:[0-9]+}", apiHandler.DeleteWishlistMemberHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/wishlists", apiHandler.GetSharedWishlistsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/wishlists/{ownerID:[0-9]+}", apiHandler.GetSharedWishlistHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/wishlists/{ownerID:[0-9]+}/books/{bookID:[0-9]+}/claim", apiHandler.ClaimWishlistBookHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/wishlists/{ownerID:[0-9]+}/books/{bookID:[0-9]+}/claim", apiHandler.UnclaimWishlistBookHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/follows", apiHandler.GetFollowsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/follows", apiHandler.AddFollowHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/follows/{id:[0-9]+}/refresh", apiHandler.RefreshFollowHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/follows/{id:[0-9]+}", apiHandler.DeleteFollowHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/crosspost/accounts", apiHandler.GetCrosspostAccountsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/crosspost/accounts", apiHandler.AddCrosspostAccountHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/crosspost/accounts/{id:[0-9]+}", apiHandler.DeleteCrosspostAccountHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/webhooks", apiHandler.GetWebhooksHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/webhooks", apiHandler.AddWebhookHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/webhooks/{id:[0-9]+}", apiHandler.DeleteWebhookHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", apiHandler.GetWebhookDeliveriesHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/webhooks/deliveries/{id:[0-9]+}/retry", apiHandler.RetryWebhookDeliveryHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/sync/accounts", apiHandler.GetSyncAccountsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/sync/accounts", apiHandler.AddSyncAccountHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/sync/accounts/{id:[0-9]+}", apiHandler.DeleteSyncAccountHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/sync/accounts/{id:[0-9]+}/run", apiHandler.RunSyncHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/sync/log", apiHandler.SyncLogHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/review", apiHandler.GetReviewQueueHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/review/{id:[0-9]+}/resolve", apiHandler.ResolveReviewHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/admin/covers/repair", apiHandler.GetCoverRepairHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/covers/repair", apiHandler.StartCoverRepairHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/admin/covers/cache", apiHandler.CacheCoversHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/admin/descriptions/clean", apiHandler.CleanDescriptionsHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/admin/titles/split", apiHandler.SplitSubtitlesHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/admin/series/suggestions", apiHandler.GetSeriesSuggestionsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/series/suggestions", apiHandler.StartSeriesSuggestionsHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/admin/providers", apiHandler.GetProvidersHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/logging", apiHandler.GetLoggingHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/logging", apiHandler.UpdateLoggingHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/admin/backup", apiHandler.BackupHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/admin/restore", apiHandler.RestoreHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/admin/backups", apiHandler.GetBackupsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/slow-queries", apiHandler.GetSlowQueriesHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/quality", apiHandler.GetDataQualityHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/backfill", apiHandler.GetBackfillHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/backfill", apiHandler.StartBackfillHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/covers/{hash:[0-9a-f]{64}}", apiHandler.GetCoverImageHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/openapi.json", apiHandler.GetOpenAPIHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/docs", apiHandler.GetAPIDocsHandler).Methods(http.MethodGet)
+}
+
 // NoDirListing wraps a http.Handler (like http.FileServer) and prevents directory listings.
 func NoDirListing(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
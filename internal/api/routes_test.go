@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/requestid"
+)
+
+func TestVersionedAndLegacyRoutes(t *testing.T) {
+	router := SetupRouter(testHandler, t.TempDir())
+
+	req, _ := http.NewRequest("GET", "/api/v1/books", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET /api/v1/books: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("API-Version") != "v1" {
+		t.Errorf("Expected API-Version v1, got %q", rr.Header().Get("API-Version"))
+	}
+	if rr.Header().Get("Deprecation") != "" || rr.Header().Get("Sunset") != "" {
+		t.Errorf("Versioned routes must not be marked deprecated: %v", rr.Header())
+	}
+
+	req, _ = http.NewRequest("GET", "/api/books?fields=title", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET /api/books: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Deprecation"); got != "@1791936000" {
+		t.Errorf("Unexpected Deprecation header %q", got)
+	}
+	if got := rr.Header().Get("Sunset"); got != "Fri, 30 Apr 2027 00:00:00 GMT" {
+		t.Errorf("Unexpected Sunset header %q", got)
+	}
+	if got := rr.Header().Get("Link"); got != `</api/v1/books>; rel="successor-version"` {
+		t.Errorf("Unexpected Link header %q", got)
+	}
+
+	// Unknown versions fall through to the frontend rather than an API handler
+	req, _ = http.NewRequest("GET", "/api/v2/books", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Header().Get("API-Version") != "" {
+		t.Errorf("Expected no API handler for /api/v2, got headers %v", rr.Header())
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(requestid.NewHandler(slog.NewJSONHandler(&logs, nil))))
+	router := SetupRouter(testHandler, t.TempDir())
+
+	get := func(id string) *httptest.ResponseRecorder {
+		logs.Reset()
+		req, _ := http.NewRequest("GET", "/api/v1/books", nil)
+		req.Header.Set(requestid.Header, id)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	entries := func() []map[string]any {
+		var entries []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("Unexpected log line %q: %v", line, err)
+			}
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+
+	// A proxy's ID is kept, and every line logged for the request carries it
+	rr := get("proxy-7f3a:1")
+	if got := rr.Header().Get(requestid.Header); got != "proxy-7f3a:1" {
+		t.Errorf("Expected the request ID to be sent back, got %q", got)
+	}
+	var sql, access map[string]any
+	for _, entry := range entries() {
+		if entry[requestid.Key] != "proxy-7f3a:1" {
+			t.Errorf("Expected every line to carry the request ID, got %v", entry)
+		}
+		switch entry["msg"] {
+		case "SQL: Executing GetBooks query":
+			sql = entry
+		case "HTTP Request completed":
+			access = entry
+		}
+	}
+	if sql == nil {
+		t.Error("Expected the SQL query to be logged")
+	}
+	if access == nil || access["status"] != float64(http.StatusOK) || access["route"] != "/books" ||
+		access["bytes"] != float64(rr.Body.Len()) || access["remoteAddr"] != "192.0.2.1:1234" {
+		t.Errorf("Unexpected access log entry %v", access)
+	}
+
+	// Otherwise an ID is made up
+	rr = get("not a\tvalid id")
+	id := rr.Header().Get(requestid.Header)
+	if len(id) != 16 || !requestid.Valid(id) {
+		t.Errorf("Expected a new request ID, got %q", id)
+	}
+	for _, entry := range entries() {
+		if entry[requestid.Key] != id {
+			t.Errorf("Expected every line to carry the new request ID, got %v", entry)
+		}
+	}
+
+	// URIs are left out of the access log below the debug level, since they
+	// hold share tokens and searches, and the route is logged instead
+	logs.Reset()
+	slog.SetDefault(slog.New(logging.NewHandler(&logs, logging.NewController())))
+	req, _ := http.NewRequest("GET", "/api/v1/shared/s3cr3t-t0ken?q=embarrassing", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if strings.Contains(logs.String(), "s3cr3t-t0ken") || strings.Contains(logs.String(), "embarrassing") {
+		t.Errorf("Expected the URI to be left out of the log, got %s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "route=/shared/{token}") {
+		t.Errorf("Expected the route to be logged, got %s", logs.String())
+	}
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/ericdahl/bookshelf/internal/match"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/ocr"
+)
+
+// maxScanBytes caps the size of photos sent to be scanned.
+const maxScanBytes = 10 * 1024 * 1024
+
+// coverScan is the response of POST /api/books/scan/cover.
+type coverScan struct {
+	Text    string                    `json:"text"` // Everything the OCR engine read
+	Title   string                    `json:"title"`
+	Author  string                    `json:"author"`
+	Query   string                    `json:"query"` // What the providers were asked
+	Results []OpenLibrarySearchResult `json:"results"`
+}
+
+// readPhoto reads the photo of a scan request, sent as the request body
+// with an image content type. It responds with the error and returns false
+// when there is none.
+func readPhoto(w http.ResponseWriter, r *http.Request) ([]byte, string, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxScanBytes)
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "Photo must not be larger than 10 MB")
+		return nil, "", false
+	}
+	contentType := http.DetectContentType(data)
+	if len(data) == 0 || !strings.HasPrefix(contentType, "image/") {
+		respondWithError(w, http.StatusBadRequest, "Request body must be a photo (JPEG, PNG, GIF or WebP)")
+		return nil, "", false
+	}
+	return data, contentType, true
+}
+
+// markExisting marks the results that are already in the library with the
+// book's ID and shelf.
+func (h *APIHandler) markExisting(r *http.Request, results []OpenLibrarySearchResult) error {
+	books, err := h.Store.GetBooks(r.Context())
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]model.Book, len(books))
+	for _, book := range books {
+		existing[book.OpenLibraryID] = book
+	}
+	for i := range results {
+		if book, ok := existing[results[i].OpenLibraryID]; ok {
+			id, shelf := book.ID, string(book.Status)
+			results[i].ExistingID, results[i].ExistingShelf = &id, &shelf
+		}
+	}
+	return nil
+}
+
+// ScanCoverHandler handles POST /api/books/scan/cover requests, for adding a
+// book whose barcode is missing or unreadable. The body is a photo of the
+// book's cover; the OCR engine reads its text, the title and author are
+// picked out of it and looked up with the metadata providers as by
+// GET /api/books/search. The results are returned for the client to choose
+// from and add with POST /api/books; nothing is added.
+func (h *APIHandler) ScanCoverHandler(w http.ResponseWriter, r *http.Request) {
+	if h.OCR == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Cover scanning requires the server to be started with --ocr-engine")
+		return
+	}
+	photo, contentType, ok := readPhoto(w, r)
+	if !ok {
+		return
+	}
+	text, err := h.OCR.Recognize(r.Context(), photo, contentType)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, "Failed to read the cover: "+err.Error())
+		return
+	}
+	cover := ocr.ParseCover(text)
+	scan := coverScan{Text: text, Title: cover.Title, Author: cover.Author, Query: cover.Query(), Results: []OpenLibrarySearchResult{}}
+	if scan.Query == "" {
+		respondWithError(w, http.StatusUnprocessableEntity, "No title could be read from the photo")
+		return
+	}
+
+	found, err := h.Metadata.Search(r.Context(), scan.Query)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	for _, book := range found {
+		scan.Results = append(scan.Results, newProviderResult(book))
+	}
+	if err := h.markExisting(r, scan.Results); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve existing books: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, scan)
+}
+
+// Outcomes of the spines of a shelf scan.
+const (
+	spineQueued        = "queued"         // Queued for review
+	spineOnShelf       = "on_shelf"       // Already on the bookshelf
+	spineAlreadyQueued = "already_queued" // In the review queue from an earlier scan
+	spineNotFound      = "not_found"      // No provider knows the book
+	spineUnreadable    = "unreadable"     // No title could be read
+	spineFailed        = "failed"         // OCR or the providers failed; see Error
+)
+
+// shelfSpine is a spine of a shelf scan and what became of it.
+type shelfSpine struct {
+	Text    string              `json:"text"`
+	Query   string              `json:"query,omitempty"`
+	Outcome string              `json:"outcome"`
+	BookID  *int64              `json:"book_id,omitempty"` // The book already on the bookshelf
+	Review  *model.PendingMatch `json:"review,omitempty"`  // The review queue entry
+	Error   string              `json:"error,omitempty"`
+}
+
+// shelfScan is the response of POST /api/books/scan/shelf.
+type shelfScan struct {
+	Spines []shelfSpine `json:"spines"`
+	Queued int          `json:"queued"`
+}
+
+// ScanShelfHandler handles POST /api/books/scan/shelf requests, for
+// cataloging a physical library. The body is a photo of a shelf; the vision
+// service finds the spines on it, the OCR engine reads those it didn't read
+// itself, and each is looked up with the metadata providers as a cover scan
+// is. Books found that aren't on the bookshelf yet are queued for review,
+// with any books they might be as candidates, to be added on the shelf given
+// by the optional status query parameter (default "Want to Read") with
+// POST /api/review/{id}/resolve. The optional name query parameter labels
+// the scan (default "shelf"); a book already queued under it isn't queued
+// again.
+func (h *APIHandler) ScanShelfHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Vision == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Shelf scanning requires the server to be started with --vision-url")
+		return
+	}
+	status := model.BookStatus(r.URL.Query().Get("status"))
+	if status == "" {
+		status = model.StatusWantToRead
+	} else if !status.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid status value. Must be 'Want to Read', 'Currently Reading', or 'Read'")
+		return
+	}
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" {
+		name = "shelf"
+	}
+	photo, contentType, ok := readPhoto(w, r)
+	if !ok {
+		return
+	}
+
+	spines, err := h.Vision.Segment(r.Context(), photo, contentType)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, "Failed to find the spines: "+err.Error())
+		return
+	}
+	unread := false
+	for _, spine := range spines {
+		unread = unread || spine.Text == ""
+	}
+	var crops [][]byte
+	if unread {
+		if h.OCR == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "Reading spines requires the server to be started with --ocr-engine")
+			return
+		}
+		if crops, err = ocr.CropSpines(photo, spines); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	existingBooks, err := h.Store.GetBooks(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve existing books: "+err.Error())
+		return
+	}
+	index := match.NewIndex(existingBooks)
+	index.Thresholds = h.MatchThresholds
+
+	result := shelfScan{Spines: make([]shelfSpine, len(spines))}
+	for i, spine := range spines {
+		out := &result.Spines[i]
+		out.Text = spine.Text
+		if out.Text == "" && crops[i] != nil {
+			if out.Text, err = h.OCR.Recognize(r.Context(), crops[i], "image/png"); err != nil {
+				out.Outcome, out.Error = spineFailed, err.Error()
+				continue
+			}
+		}
+		out.Query = ocr.ParseCover(out.Text).Query()
+		if out.Query == "" {
+			out.Outcome = spineUnreadable
+			continue
+		}
+		found, err := h.Metadata.Search(r.Context(), out.Query)
+		if err != nil {
+			out.Outcome, out.Error = spineFailed, err.Error()
+			continue
+		}
+		if len(found) == 0 {
+			out.Outcome = spineNotFound
+			continue
+		}
+		book := found[0]
+		item := model.PendingItem{Title: book.Title, Author: strings.Join(book.Authors, ", "), ISBN: book.ISBN,
+			OpenLibraryID: book.ID, CoverURL: optionalString(book.CoverURL), Status: status}
+		if book.PublishYear > 0 {
+			year := book.PublishYear
+			item.Year = &year
+		}
+		if book.PageCount > 0 {
+			pages := book.PageCount
+			item.PageCount = &pages
+		}
+		m := index.Match(match.Candidate{OpenLibraryID: item.OpenLibraryID, ISBNs: []string{item.ISBN}, Title: item.Title,
+			Author: item.Author, Year: item.Year})
+		if m.Outcome == match.Matched {
+			id := m.Best.Book.ID
+			out.Outcome, out.BookID = spineOnShelf, &id
+			continue
+		}
+		pending := &model.PendingMatch{Source: model.SourceShelfScan, SourceRef: name, ItemKey: item.OpenLibraryID,
+			Item: item, Candidates: m.PendingCandidates()}
+		queued, err := h.Store.AddPendingMatch(r.Context(), pending)
+		switch {
+		case err != nil:
+			slog.WarnContext(r.Context(), "Failed to queue shelf spine for review", "title", item.Title, "error", err)
+			out.Outcome, out.Error = spineFailed, err.Error()
+		case queued:
+			out.Outcome, out.Review = spineQueued, pending
+			result.Queued++
+		default:
+			out.Outcome = spineAlreadyQueued
+		}
+	}
+	respondWithJSON(w, http.StatusOK, result)
+}
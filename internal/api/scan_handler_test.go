@@ -0,0 +1,162 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/metadata"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/ocr"
+)
+
+// fixedOCR reads the same text off every photo.
+type fixedOCR string
+
+func (f fixedOCR) Name() string { return "fixed" }
+
+func (f fixedOCR) Recognize(ctx context.Context, image []byte, contentType string) (string, error) {
+	return string(f), nil
+}
+
+// pngPhoto is the start of a PNG, enough to be sniffed as one.
+var pngPhoto = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// TestScanCoverHandler tests looking up the book on a photo of its cover
+func TestScanCoverHandler(t *testing.T) {
+	book := createTestBook(model.StatusRead, "Scanned")
+	book.OpenLibraryID = "OLSCANNED1M"
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.PurgeBook(context.Background(), id)
+
+	chain := testHandler.Metadata
+	defer func() { testHandler.Metadata = chain; testHandler.OCR = nil }()
+	testHandler.Metadata = metadata.Chain{titleProvider{
+		query: "THE DISPOSSESSED URSULA K. LE GUIN",
+		book:  metadata.Book{Provider: "title", ID: "OLSCANNED1M", Title: "The Dispossessed", Authors: []string{"Ursula K. Le Guin"}},
+	}}
+	do := func(body []byte) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/books/scan/cover", bytes.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := do(pngPhoto); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Scanning without an OCR engine: got status %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+	testHandler.OCR = fixedOCR("WINNER OF THE HUGO AWARD\nTHE\nDISPOSSESSED\nURSULA K. LE GUIN\n")
+	if rr := do([]byte("not a photo")); rr.Code != http.StatusBadRequest {
+		t.Errorf("Scanning text: got status %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	rr := do(pngPhoto)
+	var scan coverScan
+	if err := json.Unmarshal(rr.Body.Bytes(), &scan); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected the cover scanned, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if scan.Title != "THE DISPOSSESSED" || scan.Author != "URSULA K. LE GUIN" || len(scan.Results) != 1 {
+		t.Fatalf("Unexpected scan %+v", scan)
+	}
+	if result := scan.Results[0]; result.ExistingID == nil || *result.ExistingID != id || result.Provider != "title" {
+		t.Errorf("Expected the result marked as already in the library, got %+v", result)
+	}
+
+	testHandler.OCR = fixedOCR("~ ; .\n")
+	if rr := do(pngPhoto); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Scanning an unreadable cover: got status %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+// fixedSpines finds the same spines on every photo.
+type fixedSpines []ocr.Spine
+
+func (f fixedSpines) Name() string { return "fixed" }
+
+func (f fixedSpines) Segment(ctx context.Context, photo []byte, contentType string) ([]ocr.Spine, error) {
+	return f, nil
+}
+
+// TestScanShelfHandler tests queuing the books on a photo of a shelf for
+// review, and adding one from the queue
+func TestScanShelfHandler(t *testing.T) {
+	book := createTestBook(model.StatusRead, "Shelved")
+	book.OpenLibraryID = "OLSHELVED1M"
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.PurgeBook(context.Background(), id)
+
+	chain := testHandler.Metadata
+	defer func() { testHandler.Metadata = chain; testHandler.Vision = nil; testHandler.OCR = nil }()
+	testHandler.Metadata = metadata.Chain{
+		titleProvider{query: "THE LATHE OF HEAVEN LE GUIN", book: metadata.Book{Provider: "title", ID: "OLLATHE1M", Title: "The Lathe of Heaven",
+			Authors: []string{"Ursula K. Le Guin"}, PublishYear: 1971}},
+		titleProvider{query: "SHELVED", book: metadata.Book{Provider: "title", ID: "OLSHELVED1M", Title: book.Title, Authors: []string{book.Author}}},
+	}
+	do := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, bytes.NewReader(pngPhoto))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := do("/api/books/scan/shelf"); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Scanning without a vision service: got status %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+	testHandler.Vision = fixedSpines{{Text: "THE LATHE OF HEAVEN LE GUIN"}, {Text: "SHELVED"}, {Text: "MIDDLEMARCH"}, {Text: "; ."}}
+	if rr := do("/api/books/scan/shelf?status=Owned"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Scanning with an invalid status: got status %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	rr := do("/api/books/scan/shelf?status=Read&name=study")
+	var scan shelfScan
+	if err := json.Unmarshal(rr.Body.Bytes(), &scan); err != nil || rr.Code != http.StatusOK || len(scan.Spines) != 4 || scan.Queued != 1 {
+		t.Fatalf("Expected the shelf scanned, got %d: %s", rr.Code, rr.Body.String())
+	}
+	for i, want := range []string{spineQueued, spineOnShelf, spineNotFound, spineUnreadable} {
+		if scan.Spines[i].Outcome != want {
+			t.Errorf("Spine %d: got outcome %q, want %q", i, scan.Spines[i].Outcome, want)
+		}
+	}
+	if scan.Spines[1].BookID == nil || *scan.Spines[1].BookID != id {
+		t.Errorf("Expected the shelved book found, got %+v", scan.Spines[1])
+	}
+	review := scan.Spines[0].Review
+	if review == nil || review.Source != model.SourceShelfScan || review.SourceRef != "study" || review.Item.Status != model.StatusRead {
+		t.Fatalf("Unexpected review entry %+v", review)
+	}
+
+	// Scanning again doesn't queue the book twice
+	rr = do("/api/books/scan/shelf?name=study")
+	json.Unmarshal(rr.Body.Bytes(), &scan)
+	if scan.Queued != 0 || scan.Spines[0].Outcome != spineAlreadyQueued {
+		t.Errorf("Expected the book already queued, got %s", rr.Body.String())
+	}
+
+	req, _ := http.NewRequest("POST", "/api/review/"+itoa(review.ID)+"/resolve", strings.NewReader(`{"action": "create"}`))
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	var resolved model.PendingMatch
+	if err := json.Unmarshal(rr.Body.Bytes(), &resolved); err != nil || rr.Code != http.StatusOK || resolved.BookID == nil {
+		t.Fatalf("Expected the book added from the queue, got %d: %s", rr.Code, rr.Body.String())
+	}
+	defer testStore.PurgeBook(context.Background(), *resolved.BookID)
+	if added, err := testStore.GetBookByID(context.Background(), *resolved.BookID); err != nil || added.Title != "The Lathe of Heaven" || added.Status != model.StatusRead {
+		t.Errorf("Unexpected book added: %+v, %v", added, err)
+	}
+
+	// Spines the vision service didn't read need an OCR engine
+	testHandler.Vision = fixedSpines{{}}
+	if rr := do("/api/books/scan/shelf"); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Reading spines without an OCR engine: got status %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/series"
+	"github.com/gorilla/mux"
+)
+
+// GetSeriesSuggestionHandler handles GET /api/books/{id}/series/suggestion
+// requests. It returns the series detected for the book, or 404 when none is
+// found. Nothing is saved; apply the suggestion with PUT /api/books/{id}/details.
+func (h *APIHandler) GetSeriesSuggestionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	book, err := h.Store.GetBookByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve book")
+		return
+	}
+	suggestion, ok := h.Series.Suggest(r.Context(), *book)
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "No new series detected for this book")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, suggestion)
+}
+
+// GetSeriesSuggestionsHandler handles GET /api/admin/series/suggestions
+// requests and returns the progress or outcome of the latest suggestion run.
+func (h *APIHandler) GetSeriesSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, h.Series.Report())
+}
+
+// StartSeriesSuggestionsHandler handles POST /api/admin/series/suggestions
+// requests. Every book without a series position is checked in the
+// background; poll GetSeriesSuggestionsHandler for the suggestions.
+func (h *APIHandler) StartSeriesSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	// The job outlives the request, so it must not inherit its context
+	if err := h.Series.Start(context.Background()); err != nil {
+		if errors.Is(err, series.ErrRunning) {
+			respondWithError(w, http.StatusConflict, err.Error())
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to start series suggestion: "+err.Error())
+		}
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, h.Series.Report())
+}
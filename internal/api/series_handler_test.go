@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/series"
+)
+
+// TestSeriesSuggestionHandlers tests suggesting a series for one book and for
+// the whole library. The books it adds are deleted again.
+func TestSeriesSuggestionHandlers(t *testing.T) {
+	ctx := context.Background()
+	book := createTestBook(model.StatusRead, "Dune Messiah (Dune, #2)")
+	id, err := testStore.AddBook(ctx, book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(ctx, id)
+	plain := createTestBook(model.StatusRead, "Standalone")
+	plainID, err := testStore.AddBook(ctx, plain)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(ctx, plainID)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/api/books/" + itoa(id) + "/series/suggestion")
+	var suggestion series.Suggestion
+	json.Unmarshal(rr.Body.Bytes(), &suggestion)
+	if rr.Code != http.StatusOK || suggestion.Series != "Dune" || suggestion.SeriesIndex != 2 || suggestion.Source != series.SourceTitle {
+		t.Errorf("Expected a Dune #2 suggestion, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := get("/api/books/" + itoa(plainID) + "/series/suggestion"); rr.Code != http.StatusNotFound {
+		t.Errorf("Book without a series: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := get("/api/books/999999/series/suggestion"); rr.Code != http.StatusNotFound {
+		t.Errorf("Missing book: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	req, _ := http.NewRequest("POST", "/api/admin/series/suggestions", nil)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("Expected the run to start, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var report series.Report
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		json.Unmarshal(get("/api/admin/series/suggestions").Body.Bytes(), &report)
+		if !report.Running || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if report.Running || report.FinishedAt == nil || report.Error != "" {
+		t.Fatalf("Expected the run to finish, got %+v", report)
+	}
+	found := false
+	for _, s := range report.Suggestions {
+		if s.BookID == plainID {
+			t.Errorf("Unexpected suggestion for a book without a series: %+v", s)
+		}
+		found = found || s.BookID == id && s.Series == "Dune" && s.SeriesIndex == 2
+	}
+	if !found {
+		t.Errorf("Expected a suggestion for book %d, got %+v", id, report.Suggestions)
+	}
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// shareTokenPrefixLength is how much of a share token is kept to recognize
+// its link by.
+const shareTokenPrefixLength = 6
+
+// baseURL returns the scheme and host the request was sent to, ending in a
+// slash, for links back to the server.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/"
+}
+
+// shareURL returns the URL of the shared view of token.
+func shareURL(r *http.Request, token string) string {
+	return baseURL(r) + "api/" + APIVersion + "/shared/" + token
+}
+
+// GetShareLinksHandler handles GET /api/share-links requests, listing the
+// share links of the library, newest first. The tokens are not shown.
+func (h *APIHandler) GetShareLinksHandler(w http.ResponseWriter, r *http.Request) {
+	links, err := h.Store.GetShareLinks(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve share links: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, links)
+}
+
+// CreateShareLinkHandler handles POST /api/share-links requests. Expects
+// {"name": "book club", "status": "Read"}, optionally with a tag to narrow
+// the view to, whether to include ratings and comments (default neither) and
+// when the link expires, and responds with the link, its token in "token" and
+// the URL of the shared view in "url"; neither can be shown again.
+func (h *APIHandler) CreateShareLinkHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Name            string           `json:"name"`
+		Status          model.BookStatus `json:"status"`
+		Tag             string           `json:"tag"`
+		IncludeRatings  bool             `json:"include_ratings"`
+		IncludeComments bool             `json:"include_comments"`
+		ExpiresAt       *time.Time       `json:"expires_at"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if payload.Status != "" && !payload.Status.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid status. Must be 'Want to Read', 'Currently Reading' or 'Read'")
+		return
+	}
+	if payload.ExpiresAt != nil && !payload.ExpiresAt.After(time.Now()) {
+		respondWithError(w, http.StatusBadRequest, "expires_at must be in the future")
+		return
+	}
+
+	token, err := newSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create share link: "+err.Error())
+		return
+	}
+	link := model.ShareLink{
+		Name:            strings.TrimSpace(payload.Name),
+		Prefix:          token[:shareTokenPrefixLength],
+		Status:          payload.Status,
+		Tag:             strings.TrimSpace(payload.Tag),
+		IncludeRatings:  payload.IncludeRatings,
+		IncludeComments: payload.IncludeComments,
+	}
+	if payload.ExpiresAt != nil {
+		expiresAt := payload.ExpiresAt.UTC()
+		link.ExpiresAt = &expiresAt
+	}
+	if err := h.Store.AddShareLink(r.Context(), &link, token); err != nil {
+		respondWithStoreError(w, err, "Failed to create share link")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, struct {
+		model.ShareLink
+		Token string `json:"token"`
+		URL   string `json:"url"`
+	}{link, token, shareURL(r, token)})
+}
+
+// DeleteShareLinkHandler handles DELETE /api/share-links/{id} requests,
+// revoking the link; its URL stops working at once.
+func (h *APIHandler) DeleteShareLinkHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid share link ID")
+		return
+	}
+	if err := h.Store.DeleteShareLink(r.Context(), id); err != nil {
+		respondWithStoreError(w, err, "Failed to delete share link")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sharedBookResponse is a book of a shared view, with where to load its
+// cover from: the cached copy, which is public, when there is one.
+type sharedBookResponse struct {
+	model.SharedBook
+	CoverImageURL string `json:"cover_image_url,omitempty"`
+}
+
+// sharedLibrary is the response of GET /api/shared/{token}.
+type sharedLibrary struct {
+	Name   string               `json:"name"`
+	Status model.BookStatus     `json:"status,omitempty"`
+	Tag    string               `json:"tag,omitempty"`
+	Books  []sharedBookResponse `json:"books"`
+	Total  int                  `json:"total"`
+}
+
+// GetSharedLibraryHandler handles GET /api/shared/{token} requests, the
+// read-only view of a library a share link gives. It needs no login: the
+// token is the credential, and only the books in the link's scope are
+// shown, without the details the link leaves out. Unknown, revoked and
+// expired links are a 404. Optional query parameters: limit (default all,
+// up to 1000) and offset.
+func (h *APIHandler) GetSharedLibraryHandler(w http.ResponseWriter, r *http.Request) {
+	// Shared views are never cached by proxies, so a revoked link is gone
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	limit, offset := 0, 0
+	q := r.URL.Query()
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxPageSize))
+			return
+		}
+		limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			respondWithError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		offset = n
+	}
+
+	link, err := h.Store.GetShareLinkByToken(r.Context(), mux.Vars(r)["token"])
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve share link")
+		return
+	}
+	books, total, err := h.Store.GetSharedBooks(r.Context(), link, limit, offset)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve shared books: "+err.Error())
+		return
+	}
+	library := sharedLibrary{Name: link.Name, Status: link.Status, Tag: link.Tag, Books: make([]sharedBookResponse, len(books)), Total: total}
+	for i, book := range books {
+		library.Books[i].SharedBook = book
+		if book.CoverHash != nil {
+			library.Books[i].CoverImageURL = "/api/" + APIVersion + "/covers/" + *book.CoverHash
+		}
+	}
+	respondWithJSON(w, http.StatusOK, library)
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestShareLinks tests sharing a filtered view of a library with people
+// who aren't logged in, and revoking it.
+func TestShareLinks(t *testing.T) {
+	router, store := newAuthRouter(t)
+	alice := loginTestUser(t, router)
+	ctx := context.Background()
+	rating, comments := 9, "Loved it"
+	me := authRequest(router, "GET", "/api/v1/users/me", alice, "")
+	var user model.User
+	json.Unmarshal(me.Body.Bytes(), &user)
+	aliceCtx := db.WithUser(ctx, user.ID)
+	for _, book := range []*model.Book{
+		{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL1M", Status: model.StatusRead, Rating: &rating, Comments: &comments},
+		{Title: "Emma", Author: "Jane Austen", OpenLibraryID: "OL2M", Status: model.StatusWantToRead},
+	} {
+		if _, err := store.AddBook(aliceCtx, book); err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+	}
+
+	for _, body := range []string{`{"name": ""}`, `{"name": "club", "status": "Shelved"}`, `{"name": "club", "expires_at": "2001-01-01T00:00:00Z"}`} {
+		if rr := authRequest(router, "POST", "/api/v1/share-links", alice, body); rr.Code != http.StatusBadRequest {
+			t.Errorf("Creating %s: got status %d, want %d", body, rr.Code, http.StatusBadRequest)
+		}
+	}
+	rr := authRequest(router, "POST", "/api/v1/share-links", alice, `{"name": "book club", "status": "Read"}`)
+	var created struct {
+		model.ShareLink
+		Token string `json:"token"`
+		URL   string `json:"url"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil || rr.Code != http.StatusCreated || created.Token == "" ||
+		!strings.HasSuffix(created.URL, "/api/v1/shared/"+created.Token) || !strings.HasPrefix(created.Token, created.Prefix) {
+		t.Fatalf("Expected the share link created, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := authRequest(router, "GET", "/api/v1/share-links", alice, ""); rr.Code != http.StatusOK ||
+		strings.Contains(rr.Body.String(), created.Token) || !strings.Contains(rr.Body.String(), "book club") {
+		t.Errorf("Expected the link listed without its token, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Anyone with the token sees the Read books, without ratings or comments
+	rr = authRequest(router, "GET", "/api/v1/shared/"+created.Token, "", "")
+	var library sharedLibrary
+	if err := json.Unmarshal(rr.Body.Bytes(), &library); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected the shared view, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if library.Name != "book club" || library.Total != 1 || len(library.Books) != 1 || library.Books[0].Title != "Dune" ||
+		strings.Contains(rr.Body.String(), "Loved it") || strings.Contains(rr.Body.String(), "rating") {
+		t.Errorf("Unexpected shared view %s", rr.Body.String())
+	}
+	if rr := authRequest(router, "GET", "/api/v1/shared/not-a-token", "", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Unknown token: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	// The token grants nothing else
+	if rr := authRequest(router, "GET", "/api/v1/books", created.Token, ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Using the share token as credentials: got status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+
+	rr = authRequest(router, "POST", "/api/v1/share-links", alice, `{"name": "everything", "include_ratings": true, "include_comments": true}`)
+	var everything struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &everything)
+	rr = authRequest(router, "GET", "/api/v1/shared/"+everything.Token+"?limit=1&offset=0", "", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &library); err != nil || library.Total != 2 || len(library.Books) != 1 ||
+		library.Books[0].Rating == nil || *library.Books[0].Rating != 9 || library.Books[0].Comments == nil {
+		t.Errorf("Expected the first of both books with its rating and comments, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := authRequest(router, "DELETE", "/api/v1/share-links/"+itoa(created.ID), alice, ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("Revoking the link: got status %d, want %d", rr.Code, http.StatusNoContent)
+	}
+	if rr := authRequest(router, "GET", "/api/v1/shared/"+created.Token, "", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Revoked link: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"golang.org/x/crypto/scrypt"
+)
+
+// SingleUserName is the username the owner of a single-user bookshelf is
+// shown as.
+const SingleUserName = "owner"
+
+// SingleUser is the single-user mode: rather than registering accounts, one
+// configured password logs in, and sessions are signed cookies instead of
+// rows of the sessions table. Requests are left unscoped, so the one library
+// is the books without an owner, as before the first user registered.
+type SingleUser struct {
+	password [sha256.Size]byte
+	key      []byte
+}
+
+// NewSingleUser returns the single-user mode logging in with password, which
+// must be 8-72 bytes like users' passwords. Its sessions are signed with a
+// key derived from the password, so changing it logs everyone out.
+func NewSingleUser(password string) (*SingleUser, error) {
+	if len(password) < 8 || len(password) > 72 {
+		return nil, errors.New("password must be between 8 and 72 bytes")
+	}
+	// Slow to derive, so a leaked cookie doesn't make the password quick to guess
+	key, err := scrypt.Key([]byte(password), []byte("bookshelf single-user session"), 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	return &SingleUser{password: sha256.Sum256([]byte(password)), key: key}, nil
+}
+
+// checkPassword reports whether password is the configured one.
+func (s *SingleUser) checkPassword(password string) bool {
+	given := sha256.Sum256([]byte(password))
+	return subtle.ConstantTimeCompare(given[:], s.password[:]) == 1
+}
+
+// sign returns the signature of a session expiring at expires, a Unix time.
+func (s *SingleUser) sign(expires string) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte("session:" + expires))
+	return mac.Sum(nil)
+}
+
+// newToken returns a session token valid until expiresAt: its expiry and
+// signature, as "<unix time>.<signature>".
+func (s *SingleUser) newToken(expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expires + "." + base64.RawURLEncoding.EncodeToString(s.sign(expires))
+}
+
+// valid reports whether token is a session token of newToken that hasn't
+// expired.
+func (s *SingleUser) valid(token string) bool {
+	expires, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() >= unix {
+		return false
+	}
+	given, err := base64.RawURLEncoding.DecodeString(signature)
+	return err == nil && hmac.Equal(given, s.sign(expires))
+}
+
+// singleUserMiddleware is UserMiddleware in single-user mode: every request
+// but those of publicRoutes needs a session token, as "Authorization: Bearer
+// <token>" or the session cookie, from the start.
+func (h *APIHandler) singleUserMiddleware(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	token, fromCookie := bearerToken(r), false
+	if cookie, err := r.Cookie(SessionCookie); token == "" && err == nil && cookie.Value != "" {
+		token, fromCookie = cookie.Value, true
+	}
+	if token != "" && !h.SingleUser.valid(token) {
+		if !fromCookie {
+			respondWithError(w, http.StatusUnauthorized, "Invalid or expired credentials")
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: SessionCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+		token = ""
+	}
+	if token == "" {
+		if !isPublicRoute(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bookshelf"`)
+			respondWithError(w, http.StatusUnauthorized, "Log in to use this bookshelf")
+			return
+		}
+	} else if fromCookie && isMutating(r) && isCrossSite(r) {
+		respondWithError(w, http.StatusForbidden, "Cross-site requests may not make changes")
+		return
+	}
+	next.ServeHTTP(w, r)
+}
+
+// singleUserLogin logs in with the password of a login request in
+// single-user mode, ignoring its username.
+func (h *APIHandler) singleUserLogin(w http.ResponseWriter, r *http.Request, c credentials) {
+	if !h.SingleUser.checkPassword(c.Password) {
+		respondWithError(w, http.StatusUnauthorized, "Invalid username or password")
+		return
+	}
+	expiresAt := time.Now().UTC().Add(SessionLifetime)
+	token := h.SingleUser.newToken(expiresAt)
+	setSessionCookie(w, r, token, expiresAt)
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"token":      token,
+		"expires_at": expiresAt,
+		"user":       model.User{Username: SingleUserName},
+	})
+}
+
+// accountsDisabled responds with an error and returns true in single-user
+// mode, for the routes of users' accounts.
+func (h *APIHandler) accountsDisabled(w http.ResponseWriter) bool {
+	if h.SingleUser == nil {
+		return false
+	}
+	respondWithError(w, http.StatusForbidden, "This bookshelf has a single user, logging in with its password")
+	return true
+}
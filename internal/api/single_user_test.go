@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSingleUser(t *testing.T) {
+	if _, err := NewSingleUser("short"); err == nil {
+		t.Error("Expected a short password to be rejected")
+	}
+	handler := newTestAPIHandler(t)
+	var err error
+	if handler.SingleUser, err = NewSingleUser("correct horse"); err != nil {
+		t.Fatalf("NewSingleUser: %v", err)
+	}
+	router := SetupRouter(handler, t.TempDir())
+
+	// Reading needs logging in from the start, and there are no accounts
+	if rr := authRequest(router, "GET", "/api/v1/books", "", ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Reading without logging in: got status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+	if rr := authRequest(router, "POST", "/api/v1/users/register", "", `{"username":"mallory","password":"correct horse"}`); rr.Code != http.StatusForbidden {
+		t.Errorf("Registering: got status %d, want %d", rr.Code, http.StatusForbidden)
+	}
+	if rr := authRequest(router, "POST", "/api/v1/users/login", "", `{"username":"","password":"wrong horse"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Logging in with the wrong password: got status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+
+	rr := authRequest(router, "POST", "/api/v1/users/login", "", `{"username":"","password":"correct horse"}`)
+	var session struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &session); err != nil || session.Token == "" {
+		t.Fatalf("Logging in: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	var cookie *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == SessionCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil || cookie.Value != session.Token || !cookie.HttpOnly {
+		t.Fatalf("Expected an HttpOnly session cookie with the token, got %+v", cookie)
+	}
+
+	if rr := authRequest(router, "GET", "/api/v1/users/me", session.Token, ""); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"username":"owner"`) {
+		t.Errorf("GET /users/me: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := authRequest(router, "POST", "/api/v1/vacations", session.Token, `{"start_date":"2025-07-01","end_date":"2025-07-14"}`); rr.Code != http.StatusCreated {
+		t.Errorf("Changing the library: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	// No user is needed: the library is the books without an owner
+	if rr := authRequest(router, "GET", "/api/v1/vacations", session.Token, ""); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "2025-07-01") {
+		t.Errorf("GET /vacations: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	withCookie := func(method, path, site string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(`{"start_date":"2025-08-01","end_date":"2025-08-14"}`))
+		req.AddCookie(cookie)
+		req.Header.Set("Sec-Fetch-Site", site)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	if rr := withCookie("POST", "/api/v1/vacations", "same-origin"); rr.Code != http.StatusCreated {
+		t.Errorf("Changing the library from the web UI: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := withCookie("POST", "/api/v1/vacations", "cross-site"); rr.Code != http.StatusForbidden {
+		t.Errorf("Changing the library from another site: got status %d, want %d", rr.Code, http.StatusForbidden)
+	}
+	if rr := withCookie("POST", "/api/v1/users/logout", "same-origin"); rr.Code != http.StatusNoContent || !strings.Contains(rr.Header().Get("Set-Cookie"), "Max-Age=0") {
+		t.Errorf("Logging out: got status %d, headers %v", rr.Code, rr.Header())
+	}
+
+	// Tokens are checked by their signature and expiry
+	other, _ := NewSingleUser("battery staple")
+	for name, token := range map[string]string{
+		"tampered":             strings.Replace(session.Token, session.Token[:2], "99", 1),
+		"expired":              handler.SingleUser.newToken(time.Now().Add(-time.Minute)),
+		"of another password":  other.newToken(time.Now().Add(time.Hour)),
+		"of a user's sessions": "not-a-signed-token",
+	} {
+		if rr := authRequest(router, "GET", "/api/v1/books", token, ""); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Reading with a token %s: got status %d, want %d", name, rr.Code, http.StatusUnauthorized)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+)
+
+// slowQueriesResponse is the body of GET /api/admin/slow-queries.
+type slowQueriesResponse struct {
+	ThresholdMS float64        `json:"threshold_ms"` // 0 when the log is off
+	Total       int64          `json:"total"`        // Slow queries since startup, including those no longer kept
+	Queries     []db.SlowQuery `json:"queries"`      // Newest first
+}
+
+// GetSlowQueriesHandler handles GET /api/admin/slow-queries requests and
+// returns the most recent queries that took at least the slow query
+// threshold, with their shape, duration and number of parameters, to find
+// those missing an index.
+func (h *APIHandler) GetSlowQueriesHandler(w http.ResponseWriter, r *http.Request) {
+	if h.SlowQueries == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "The slow query log is not enabled on this server")
+		return
+	}
+	queries, total := h.SlowQueries.Recent()
+	respondWithJSON(w, http.StatusOK, slowQueriesResponse{
+		ThresholdMS: float64(h.SlowQueries.Threshold()) / float64(time.Millisecond),
+		Total:       total,
+		Queries:     queries,
+	})
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestGetSlowQueriesHandler(t *testing.T) {
+	database, err := db.InitDB(filepath.Join(t.TempDir(), "books.db"))
+	if err != nil {
+		t.Fatalf("InitDB failed: %v", err)
+	}
+	defer database.Close()
+	h := NewAPIHandler(db.NewSQLiteBookStore(database))
+
+	db.SlowQueries.SetThreshold(time.Microsecond)
+	defer db.SlowQueries.SetThreshold(0)
+	if _, err := h.Store.AddBook(context.Background(), createTestBook(model.StatusRead, "Slow")); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	h.GetSlowQueriesHandler(rr, httptest.NewRequest("GET", "/api/admin/slow-queries", nil))
+	var resp slowQueriesResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); rr.Code != http.StatusOK || err != nil {
+		t.Fatalf("Got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	inserted := false
+	for _, q := range resp.Queries {
+		inserted = inserted || q.Operation == "insert" && q.Table == "books" && q.Params > 0
+	}
+	if resp.ThresholdMS != 0.001 || resp.Total == 0 || !inserted {
+		t.Errorf("Expected the book's insert among the slow queries, got %+v", resp)
+	}
+
+	h.SlowQueries = nil
+	rr = httptest.NewRecorder()
+	h.GetSlowQueriesHandler(rr, httptest.NewRequest("GET", "/api/admin/slow-queries", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Without a slow query log: got status %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// GetReadingStatsHandler handles GET /api/stats requests. It summarizes the
+// books finished in ?year=, or in every year when it is left out: counts per
+// month and year, pages, the average rating, books per type and author, and
+// the reading streaks.
+func (h *APIHandler) GetReadingStatsHandler(w http.ResponseWriter, r *http.Request) {
+	var year int
+	if raw := r.URL.Query().Get("year"); raw != "" {
+		var err error
+		if year, err = strconv.Atoi(raw); err != nil || year < 1 || year > 9999 {
+			respondWithError(w, http.StatusBadRequest, "Invalid year")
+			return
+		}
+	}
+	stats, err := h.Store.GetReadingStats(r.Context(), year)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to compute stats: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, stats)
+}
+
+// defaultRereadLimit is how many books the "most re-read" list holds unless
+// ?limit= asks for another number.
+const defaultRereadLimit = 10
+
+// GetRereadStatsHandler handles GET /api/stats/reads requests. It returns the
+// first reads and re-reads per year and the books read most often, up to
+// ?limit= of them (default 10).
+func (h *APIHandler) GetRereadStatsHandler(w http.ResponseWriter, r *http.Request) {
+	limit := defaultRereadLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			respondWithError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+	}
+	stats, err := h.Store.GetRereadStats(r.Context(), limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to compute stats: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, stats)
+}
+
+// GetLengthStatsHandler handles GET /api/stats/length requests. It returns
+// the average days to finish a book for short, medium and long books.
+func (h *APIHandler) GetLengthStatsHandler(w http.ResponseWriter, r *http.Request) {
+	buckets, err := h.Store.GetLengthStats(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to compute stats: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, buckets)
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestGetLengthStatsHandler tests that a finished book with a page count is
+// averaged into its length bucket.
+func TestGetLengthStatsHandler(t *testing.T) {
+	book := createTestBook(model.StatusRead, "Length")
+	pages := 1200
+	started := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	finished := started.AddDate(0, 0, 20)
+	book.PageCount, book.DateStarted, book.DateFinished = &pages, &started, &finished
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	req, _ := http.NewRequest("GET", "/api/stats/length", nil)
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	var buckets []model.LengthBucket
+	if err := json.Unmarshal(rr.Body.Bytes(), &buckets); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if rr.Code != http.StatusOK || len(buckets) != 3 || buckets[2].Label != "400+" || buckets[2].Reads < 1 || buckets[2].AverageDays == nil {
+		t.Errorf("Expected the book in the 400+ bucket, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/books/"+itoa(id), nil)
+	testRouter.ServeHTTP(rr, req)
+	var resp BookResponse
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if resp.PageCount == nil || *resp.PageCount != pages {
+		t.Errorf("Expected page_count %d, got %s", pages, rr.Body.String())
+	}
+}
+
+// TestEstimatedFinishDate tests that a book being read gets an estimated
+// finish date from the pace of a finished one.
+func TestEstimatedFinishDate(t *testing.T) {
+	ctx := context.Background()
+	pages := 300
+	started := time.Date(2002, 1, 1, 0, 0, 0, 0, time.UTC)
+	finished := started.AddDate(0, 0, 10)
+	done := createTestBook(model.StatusRead, "Paced")
+	done.PageCount, done.DateStarted, done.DateFinished = &pages, &started, &finished
+	doneID, err := testStore.AddBook(ctx, done)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(ctx, doneID)
+	reading := createTestBook(model.StatusCurrentlyReading, "Pacing")
+	today := time.Now().UTC()
+	reading.PageCount, reading.DateStarted = &pages, &today
+	readingID, err := testStore.AddBook(ctx, reading)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(ctx, readingID)
+
+	get := func(id int64) BookResponse {
+		req, _ := http.NewRequest("GET", "/api/books/"+itoa(id), nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		var resp BookResponse
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return resp
+	}
+	if resp := get(readingID); resp.EstimatedFinishDate == nil || resp.EstimatedFinishDate.Before(time.Now().UTC().Truncate(24*time.Hour)) {
+		t.Errorf("Expected an estimated finish date from today on, got %+v", resp.EstimatedFinishDate)
+	}
+	if resp := get(doneID); resp.EstimatedFinishDate != nil {
+		t.Errorf("Expected no estimate for a finished book, got %v", resp.EstimatedFinishDate)
+	}
+}
+
+// TestGetReadingStatsHandler tests the reading stats of one year.
+func TestGetReadingStatsHandler(t *testing.T) {
+	book := createTestBook(model.StatusRead, "Stats")
+	pages := 250
+	finished := time.Date(1987, 4, 2, 0, 0, 0, 0, time.UTC)
+	book.PageCount, book.DateFinished = &pages, &finished
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, httptest.NewRequest("GET", "/api/stats?year=1987", nil))
+	var stats model.ReadingStats
+	if err := json.Unmarshal(rr.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if rr.Code != http.StatusOK || stats.Reads != 1 || stats.Pages != 250 || len(stats.Months) != 12 || stats.Months[3].Reads != 1 {
+		t.Errorf("Expected the book in April 1987, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, httptest.NewRequest("GET", "/api/stats?year=soon", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid year: got status %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// GetSyncAccountsHandler handles GET /api/sync/accounts requests.
+// Credentials are never included in the response.
+func (h *APIHandler) GetSyncAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	accounts, err := h.Store.GetSyncAccounts(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve sync accounts: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, accounts)
+}
+
+// AddSyncAccountHandler handles POST /api/sync/accounts requests.
+// Hardcover accounts need an API token (stored encrypted, so the server must have
+// a secret key); Goodreads accounts need the numeric user ID as remote_user.
+func (h *APIHandler) AddSyncAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Provider       model.SyncProvider   `json:"provider"`
+		RemoteUser     string               `json:"remote_user"`
+		Token          string               `json:"token"`
+		ConflictPolicy model.ConflictPolicy `json:"conflict_policy"`
+		RatingMapping  model.RatingMapping  `json:"rating_mapping"`
+		RatingRounding model.RatingRounding `json:"rating_rounding"`
+		Enabled        *bool                `json:"enabled"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if !payload.Provider.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid provider. Must be 'hardcover' or 'goodreads'")
+		return
+	}
+	if payload.ConflictPolicy == "" {
+		payload.ConflictPolicy = model.ConflictLocalWins
+	} else if !payload.ConflictPolicy.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid conflict_policy. Must be 'local' or 'remote'")
+		return
+	}
+
+	account := model.SyncAccount{
+		Provider:       payload.Provider,
+		RemoteUser:     payload.RemoteUser,
+		ConflictPolicy: payload.ConflictPolicy,
+		RatingMapping:  payload.RatingMapping,
+		RatingRounding: payload.RatingRounding,
+		Enabled:        payload.Enabled == nil || *payload.Enabled,
+	}
+
+	switch payload.Provider {
+	case model.ProviderHardcover:
+		if payload.Token == "" {
+			respondWithError(w, http.StatusBadRequest, "Missing required field: token")
+			return
+		}
+		if !h.Sync.Box.Enabled() {
+			respondWithError(w, http.StatusServiceUnavailable, "Hardcover sync requires the server to be started with --secret-key")
+			return
+		}
+		sealed, err := h.Sync.Box.Seal(payload.Token)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to encrypt token: "+err.Error())
+			return
+		}
+		account.EncryptedToken = sealed
+	case model.ProviderGoodreads:
+		if _, err := strconv.ParseInt(payload.RemoteUser, 10, 64); err != nil {
+			respondWithError(w, http.StatusBadRequest, "remote_user must be the numeric Goodreads user ID")
+			return
+		}
+	}
+
+	if _, err := h.Store.AddSyncAccount(r.Context(), &account); err != nil {
+		respondWithStoreError(w, err, "Failed to add sync account")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, account)
+}
+
+// DeleteSyncAccountHandler handles DELETE /api/sync/accounts/{id} requests.
+func (h *APIHandler) DeleteSyncAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+	if err := h.Store.DeleteSyncAccount(r.Context(), id); err != nil {
+		respondWithStoreError(w, err, "Failed to delete sync account")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunSyncHandler handles POST /api/sync/accounts/{id}/run requests, syncing the account immediately.
+func (h *APIHandler) RunSyncHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	account, err := h.Store.GetSyncAccountByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve sync account")
+		return
+	}
+
+	result, err := h.Sync.SyncAccount(r.Context(), *account)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, "Sync failed: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// SyncLogHandler handles GET /api/sync/log requests.
+// Optional query parameters: account (only entries for this account ID) and limit (default 100).
+func (h *APIHandler) SyncLogHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var accountID int64
+	if s := query.Get("account"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid account ID")
+			return
+		}
+		accountID = id
+	}
+	limit := 100
+	if s := query.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 1000 {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.Store.ListSyncLog(r.Context(), accountID, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve sync log: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, entries)
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/secrets"
+)
+
+// TestSyncAccountLifecycle tests linking, listing, running and deleting sync accounts
+func TestSyncAccountLifecycle(t *testing.T) {
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Hardcover tokens cannot be stored without a secret key
+	if rr := send("POST", "/api/sync/accounts", `{"provider":"hardcover","token":"hc-secret"}`); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a secret key, got %d", rr.Code)
+	}
+
+	invalid := []string{
+		`{"provider":"librarything","remote_user":"1"}`,
+		`{"provider":"goodreads","remote_user":"not-a-number"}`,
+		`{"provider":"goodreads","remote_user":"1","conflict_policy":"newest"}`,
+		`{"provider":"hardcover"}`,
+	}
+	for _, body := range invalid {
+		if rr := send("POST", "/api/sync/accounts", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rr.Code)
+		}
+	}
+
+	box, err := secrets.NewBox("test-key")
+	if err != nil {
+		t.Fatalf("NewBox failed: %v", err)
+	}
+	testHandler.Sync.Box = box
+	defer func() { testHandler.Sync.Box = nil }()
+
+	rr := send("POST", "/api/sync/accounts", `{"provider":"hardcover","token":"hc-secret","conflict_policy":"remote"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if strings.Contains(rr.Body.String(), "hc-secret") {
+		t.Error("Response must not contain the token")
+	}
+	var hardcover model.SyncAccount
+	json.Unmarshal(rr.Body.Bytes(), &hardcover)
+	if hardcover.ConflictPolicy != model.ConflictRemoteWins || !hardcover.Enabled {
+		t.Errorf("Unexpected account: %+v", hardcover)
+	}
+
+	// Point the Goodreads client at a server that fails so the run reports an error
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer remote.Close()
+	original := testHandler.Sync.GoodreadsURL
+	testHandler.Sync.GoodreadsURL = remote.URL
+	defer func() { testHandler.Sync.GoodreadsURL = original }()
+
+	rr = send("POST", "/api/sync/accounts", `{"provider":"goodreads","remote_user":"42"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var goodreads model.SyncAccount
+	json.Unmarshal(rr.Body.Bytes(), &goodreads)
+
+	rr = send("GET", "/api/sync/accounts", "")
+	var accounts []model.SyncAccount
+	json.Unmarshal(rr.Body.Bytes(), &accounts)
+	if rr.Code != http.StatusOK || len(accounts) != 2 || strings.Contains(rr.Body.String(), "hc-secret") {
+		t.Errorf("Unexpected list response %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := send("POST", "/api/sync/accounts/"+itoa(goodreads.ID)+"/run", ""); rr.Code != http.StatusBadGateway {
+		t.Errorf("Expected 502 for a failing provider, got %d", rr.Code)
+	}
+	if rr := send("POST", "/api/sync/accounts/999999/run", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown account, got %d", rr.Code)
+	}
+
+	rr = send("GET", "/api/sync/log?account="+itoa(goodreads.ID), "")
+	var entries []model.SyncLogEntry
+	json.Unmarshal(rr.Body.Bytes(), &entries)
+	if rr.Code != http.StatusOK || len(entries) != 1 || entries[0].Direction != model.SyncError {
+		t.Errorf("Expected the failed run in the sync log, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := send("GET", "/api/sync/log?limit=0", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid limit, got %d", rr.Code)
+	}
+
+	for _, id := range []int64{hardcover.ID, goodreads.ID} {
+		if rr := send("DELETE", "/api/sync/accounts/"+itoa(id), ""); rr.Code != http.StatusNoContent {
+			t.Errorf("Expected 204, got %d", rr.Code)
+		}
+	}
+	if rr := send("DELETE", "/api/sync/accounts/"+itoa(goodreads.ID), ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rr.Code)
+	}
+}
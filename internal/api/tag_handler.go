@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// tagPayload is the body of requests that name a tag.
+type tagPayload struct {
+	Name string `json:"name"`
+}
+
+func decodeTagPayload(w http.ResponseWriter, r *http.Request) (tagPayload, bool) {
+	var payload tagPayload
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return payload, false
+	}
+	return payload, true
+}
+
+// GetTagsHandler handles GET /api/tags requests. Each tag includes the number
+// of books it is on.
+func (h *APIHandler) GetTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.Store.GetTags(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve tags: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, tags)
+}
+
+// RenameTagHandler handles PUT /api/tags/{id} requests. Expects {"name": "..."}.
+func (h *APIHandler) RenameTagHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid tag ID")
+		return
+	}
+	payload, ok := decodeTagPayload(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Store.RenameTag(r.Context(), id, payload.Name); err != nil {
+		respondWithStoreError(w, err, "Failed to rename tag")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Tag renamed successfully"})
+}
+
+// DeleteTagHandler handles DELETE /api/tags/{id} requests. The tag is removed
+// from every book; the books themselves are kept.
+func (h *APIHandler) DeleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid tag ID")
+		return
+	}
+	if err := h.Store.DeleteTag(r.Context(), id); err != nil {
+		respondWithStoreError(w, err, "Failed to delete tag")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetBookTagsHandler handles GET /api/books/{id}/tags requests.
+func (h *APIHandler) GetBookTagsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	tags, err := h.Store.GetBookTags(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve book tags")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, tags)
+}
+
+// AddBookTagHandler handles POST /api/books/{id}/tags requests. Expects
+// {"name": "..."}; a tag with that name is created if there is none yet.
+func (h *APIHandler) AddBookTagHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	payload, ok := decodeTagPayload(w, r)
+	if !ok {
+		return
+	}
+	tag, err := h.Store.AddBookTag(r.Context(), id, payload.Name)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to tag book")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, tag)
+}
+
+// RemoveBookTagHandler handles DELETE /api/books/{id}/tags/{tagID} requests.
+func (h *APIHandler) RemoveBookTagHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	tagID, err := strconv.ParseInt(vars["tagID"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid tag ID")
+		return
+	}
+	if err := h.Store.RemoveBookTag(r.Context(), id, tagID); err != nil {
+		respondWithStoreError(w, err, "Failed to untag book")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
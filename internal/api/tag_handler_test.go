@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestTagLifecycle tests tagging books, filtering by tag and managing tags
+func TestTagLifecycle(t *testing.T) {
+	ctx := context.Background()
+	book := createTestBook(model.StatusRead, "Tagged")
+	id, err := testStore.AddBook(ctx, book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(ctx, id)
+	other := createTestBook(model.StatusRead, "Untagged")
+	otherID, err := testStore.AddBook(ctx, other)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(ctx, otherID)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("POST", "/api/books/"+itoa(id)+"/tags", `{"name":" Space  Opera "}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Tagging a book: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	var tag model.Tag
+	if err := json.Unmarshal(rr.Body.Bytes(), &tag); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if tag.Name != "Space Opera" || tag.BookCount != 1 {
+		t.Errorf("Unexpected tag %+v", tag)
+	}
+	defer testStore.DeleteTag(ctx, tag.ID)
+
+	for _, tt := range []struct {
+		method, path, body string
+		want               int
+	}{
+		{"POST", "/api/books/" + itoa(id) + "/tags", `{"name":""}`, http.StatusBadRequest},
+		{"POST", "/api/books/" + itoa(id) + "/tags", `{"label":"x"}`, http.StatusBadRequest},
+		{"POST", "/api/books/999999/tags", `{"name":"x"}`, http.StatusNotFound},
+		{"GET", "/api/books/999999/tags", "", http.StatusNotFound},
+		{"DELETE", "/api/books/" + itoa(otherID) + "/tags/" + itoa(tag.ID), "", http.StatusNotFound},
+		{"PUT", "/api/tags/999999", `{"name":"x"}`, http.StatusNotFound},
+		{"DELETE", "/api/tags/999999", "", http.StatusNotFound},
+	} {
+		if rr := do(tt.method, tt.path, tt.body); rr.Code != tt.want {
+			t.Errorf("%s %s %s: got status %d, want %d", tt.method, tt.path, tt.body, rr.Code, tt.want)
+		}
+	}
+
+	rr = do("GET", "/api/books?tag=space%20opera", "")
+	var books []BookResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &books); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if len(books) != 1 || books[0].ID != id {
+		t.Errorf("Filtering by tag: expected only the tagged book, got %+v", books)
+	}
+
+	if rr := do("PUT", "/api/tags/"+itoa(tag.ID), `{"name":"SF"}`); rr.Code != http.StatusOK {
+		t.Errorf("Renaming a tag: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	rr = do("GET", "/api/books/"+itoa(id)+"/tags", "")
+	var tags []model.Tag
+	json.Unmarshal(rr.Body.Bytes(), &tags)
+	if len(tags) != 1 || tags[0].Name != "SF" {
+		t.Errorf("Book tags after rename: got %+v", tags)
+	}
+
+	// A second tag can't take the first one's name
+	rr = do("POST", "/api/books/"+itoa(otherID)+"/tags", `{"name":"Cozy"}`)
+	var cozy model.Tag
+	json.Unmarshal(rr.Body.Bytes(), &cozy)
+	defer testStore.DeleteTag(ctx, cozy.ID)
+	if rr := do("PUT", "/api/tags/"+itoa(cozy.ID), `{"name":"sf"}`); rr.Code != http.StatusConflict {
+		t.Errorf("Renaming onto an existing tag: got status %d, want %d", rr.Code, http.StatusConflict)
+	}
+
+	if rr := do("DELETE", "/api/books/"+itoa(id)+"/tags/"+itoa(tag.ID), ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Untagging a book: got status %d", rr.Code)
+	}
+	rr = do("GET", "/api/tags", "")
+	json.Unmarshal(rr.Body.Bytes(), &tags)
+	counts := map[string]int{}
+	for _, tag := range tags {
+		counts[tag.Name] = tag.BookCount
+	}
+	if n, ok := counts["SF"]; !ok || n != 0 || counts["Cozy"] != 1 {
+		t.Errorf("Unexpected tag counts %v", counts)
+	}
+
+	if rr := do("DELETE", "/api/tags/"+itoa(cozy.ID), ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Deleting a tag: got status %d", rr.Code)
+	}
+}
@@ -0,0 +1,25 @@
+package api
+
+import "net/http"
+
+// SplitSubtitlesHandler handles POST /api/admin/titles/split requests. Books
+// added before subtitles were kept separately have the subtitle moved out of
+// their title, and the response reports how many were checked and split.
+// With ?dry_run=true nothing is changed, and the response lists each change
+// that would be made.
+func (h *APIHandler) SplitSubtitlesHandler(w http.ResponseWriter, r *http.Request) {
+	r, dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
+	report, err := h.Store.SplitSubtitles(r.Context())
+	if dryRun {
+		respondWithDryRun(w, report, nil, err, "Failed to split subtitles")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to split subtitles: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
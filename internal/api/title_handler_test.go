@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+)
+
+// TestSubtitleSplitting tests that subtitles are split off on add and by the backfill job
+func TestSubtitleSplitting(t *testing.T) {
+	body := `{"title":"Sapiens: A Brief History of Humankind","author":"Yuval Noah Harari","open_library_id":"OL777SUBM"}`
+	req, _ := http.NewRequest("POST", "/api/books", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Adding a book: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	var book BookResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &book); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), book.ID)
+	if book.Title != "Sapiens" || book.Subtitle == nil || *book.Subtitle != "A Brief History of Humankind" ||
+		book.FullTitle != "Sapiens: A Brief History of Humankind" {
+		t.Errorf("Expected the subtitle split off, got %+v", book)
+	}
+
+	req, _ = http.NewRequest("POST", "/api/admin/titles/split", nil)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Backfilling: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	var report db.SubtitleSplit
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if report.Split != 0 {
+		t.Errorf("Expected split titles to be left alone, got %+v", report)
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GetTrashHandler handles GET /api/trash requests, listing the books
+// DELETE /api/books/{id} moved to the trash, most recently deleted first.
+func (h *APIHandler) GetTrashHandler(w http.ResponseWriter, r *http.Request) {
+	books, err := h.Store.GetTrash(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve trash: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newBookResponses(books))
+}
+
+// RestoreBookHandler handles POST /api/trash/{id}/restore requests, putting a
+// book back on the bookshelf with its tags and reading history. Returns the
+// restored book.
+func (h *APIHandler) RestoreBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+	if err := h.Store.RestoreBook(r.Context(), id); err != nil {
+		respondWithStoreError(w, err, "Failed to restore book")
+		return
+	}
+	book, err := h.Store.GetBookByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve restored book")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newBookResponse(book))
+}
+
+// PurgeBookHandler handles DELETE /api/trash/{id} requests, deleting a book in
+// the trash for good. This cannot be undone.
+func (h *APIHandler) PurgeBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+	if err := h.Store.PurgeBook(r.Context(), id); err != nil {
+		respondWithStoreError(w, err, "Failed to purge book")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
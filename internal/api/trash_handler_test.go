@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestTrashHandlers tests deleting a book to the trash, restoring it and purging it
+func TestTrashHandlers(t *testing.T) {
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	const body = `{"title": "Trash Me", "author": "A", "open_library_id": "OLTRASH1M"}`
+	rr := do("POST", "/api/books", body)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Adding a book: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	var book BookResponse
+	json.Unmarshal(rr.Body.Bytes(), &book)
+	id := itoa(book.ID)
+
+	if rr := do("DELETE", "/api/books/"+id, ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("Deleting: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/books/"+id, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected the deleted book to be gone, got status %d", rr.Code)
+	}
+	rr = do("GET", "/api/trash", "")
+	var trash []BookResponse
+	json.Unmarshal(rr.Body.Bytes(), &trash)
+	if rr.Code != http.StatusOK || len(trash) == 0 || trash[0].ID != book.ID || trash[0].DeletedAt == nil {
+		t.Errorf("Expected the book first in the trash, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Adding it again points at the restore URL
+	rr = do("POST", "/api/books", body)
+	if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), `"restore":"/api/v1/trash/`+id+`/restore"`) {
+		t.Errorf("Adding a book in the trash: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do("POST", "/api/trash/"+id+"/restore", "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Trash Me") || strings.Contains(rr.Body.String(), "deleted_at") {
+		t.Fatalf("Restoring: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/books/"+id, ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected the book back, got status %d", rr.Code)
+	}
+
+	tests := []struct {
+		name, method, path string
+		want               int
+	}{
+		{"restore a shelved book", "POST", "/api/trash/" + id + "/restore", http.StatusNotFound},
+		{"purge a shelved book", "DELETE", "/api/trash/" + id, http.StatusNotFound},
+		{"delete", "DELETE", "/api/books/" + id, http.StatusNoContent},
+		{"purge", "DELETE", "/api/trash/" + id, http.StatusNoContent},
+		{"restore a purged book", "POST", "/api/trash/" + id + "/restore", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if rr := do(tt.method, tt.path, ""); rr.Code != tt.want {
+			t.Errorf("%s: got status %d, want %d (%s)", tt.name, rr.Code, tt.want, rr.Body.String())
+		}
+	}
+}
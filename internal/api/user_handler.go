@@ -0,0 +1,390 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// SessionLifetime is how long a login session lasts.
+const SessionLifetime = 30 * 24 * time.Hour
+
+// publicRoutes are the API routes, below the version prefix, that can be used
+// without logging in once there are users.
+var publicRoutes = map[string]bool{
+	"/users/register":              true,
+	"/users/login":                 true,
+	"/users/passkeys/login/begin":  true,
+	"/users/passkeys/login/finish": true,
+	"/feed.json":                   true,
+	"/users/{username}/feed.json":  true,
+	"/covers/{hash:[0-9a-f]{64}}":  true,
+	"/shared/{token}":              true,
+	"/openapi.json":                true,
+	"/docs":                        true,
+}
+
+// credentials is the request body of registration and login.
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// decodeCredentials reads credentials from the request body, responding with
+// an error and returning false when it is malformed.
+func decodeCredentials(w http.ResponseWriter, r *http.Request) (credentials, bool) {
+	var c credentials
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&c); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return c, false
+	}
+	c.Username = strings.TrimSpace(c.Username)
+	return c, true
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header, or "".
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// isPublicRoute reports whether the route r matched is one of publicRoutes.
+func isPublicRoute(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return false
+	}
+	return publicRoutes[strings.TrimPrefix(strings.TrimPrefix(template, "/api"), "/"+APIVersion)]
+}
+
+// SessionCookie is the cookie the web UI's login session is kept in.
+const SessionCookie = "bookshelf_session"
+
+// authenticate returns the user whose credentials the request carries: an API
+// key or session token as "Authorization: Bearer <token>", or else the
+// identity a trusted proxy sends, or else the session cookie. It returns a nil
+// user and error when there are none, and reports whether they came from the
+// cookie or the proxy, which browsers send along by themselves.
+func (h *APIHandler) authenticate(r *http.Request) (user *model.User, fromCookie bool, err error) {
+	ctx := r.Context()
+	if token := bearerToken(r); token != "" {
+		if strings.HasPrefix(token, APIKeyPrefix) {
+			user, err = h.Store.GetAPIKeyUser(ctx, token)
+		} else {
+			user, err = h.Store.GetSessionUser(ctx, token)
+		}
+		return user, false, err
+	}
+	if user, err := h.proxyUser(r); user != nil || err != nil {
+		return user, true, err
+	}
+	if cookie, err := r.Cookie(SessionCookie); err == nil && cookie.Value != "" {
+		user, err := h.Store.GetSessionUser(ctx, cookie.Value)
+		return user, true, err
+	}
+	return nil, false, nil
+}
+
+// isMutating reports whether a request may change something.
+func isMutating(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// isCrossSite reports whether a request was sent by a page of another site,
+// which the session cookie must not authorize changes for.
+func isCrossSite(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" {
+		return site != "same-origin" && site != "none"
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	return err != nil || u.Host != r.Host
+}
+
+// UserMiddleware authenticates each API request and scopes it to the library
+// of its user. Requests that change something always need credentials, even
+// before the first user registers; once there are users every request does,
+// except for publicRoutes. Until then reading the one shared library stays
+// open as before. In single-user mode, see singleUserMiddleware.
+func (h *APIHandler) UserMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.SingleUser != nil {
+			h.singleUserMiddleware(w, r, next)
+			return
+		}
+		ctx := r.Context()
+		user, fromCookie, err := h.authenticate(r)
+		var validationErr *model.ValidationError
+		if errors.Is(err, db.ErrNotFound) {
+			if !fromCookie {
+				respondWithError(w, http.StatusUnauthorized, "Invalid or expired credentials")
+				return
+			}
+			// A stale cookie is dropped, leaving the request anonymous
+			http.SetCookie(w, &http.Cookie{Name: SessionCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+		} else if errors.As(err, &validationErr) {
+			respondWithError(w, http.StatusForbidden, "Proxy identity can't be used: "+err.Error())
+			return
+		} else if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to check credentials: "+err.Error())
+			return
+		}
+		if user != nil {
+			if fromCookie && isMutating(r) && isCrossSite(r) {
+				respondWithError(w, http.StatusForbidden, "Cross-site requests may not make changes")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(db.WithUser(ctx, user.ID)))
+			return
+		}
+		if !isPublicRoute(r) {
+			users, err := h.Store.CountUsers(ctx)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to check for users: "+err.Error())
+				return
+			}
+			if users > 0 || isMutating(r) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="bookshelf"`)
+				message := "Log in to use this bookshelf"
+				if users == 0 {
+					message = "Register an account to make changes to this bookshelf"
+				}
+				respondWithError(w, http.StatusUnauthorized, message)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// currentUser returns the ID of the user the request is authenticated as,
+// responding with an error and returning false when there is none.
+func currentUser(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, ok := db.UserFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Not logged in")
+	}
+	return id, ok
+}
+
+// newSecret returns a random, URL-safe secret for a session token or API key.
+func newSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// RegisterHandler handles POST /api/users/register requests. Expects
+// {"username": "...", "password": "..."} and creates the user with an empty
+// library, except that the first user gets the books already there.
+func (h *APIHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	if h.accountsDisabled(w) || h.passwordsDisabled(w) {
+		return
+	}
+	c, ok := decodeCredentials(w, r)
+	if !ok {
+		return
+	}
+	user := model.User{Username: c.Username}
+	if err := model.ValidateUsername(user.Username); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := user.SetPassword(c.Password); err != nil {
+		var validationErr *model.ValidationError
+		if errors.As(err, &validationErr) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to hash password: "+err.Error())
+		return
+	}
+	if err := h.Store.AddUser(r.Context(), &user); err != nil {
+		if errors.Is(err, db.ErrDuplicate) {
+			respondWithError(w, http.StatusConflict, "Username is already taken")
+			return
+		}
+		respondWithStoreError(w, err, "Failed to register user")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, user)
+}
+
+// LoginHandler handles POST /api/users/login requests. Expects the same body
+// as registration and responds with a session token to send as
+// "Authorization: Bearer <token>", with when it expires. The session is also
+// set as a cookie for the web UI. In single-user mode only the password
+// counts, and with proxy authentication passwords don't.
+func (h *APIHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	if h.passwordsDisabled(w) {
+		return
+	}
+	c, ok := decodeCredentials(w, r)
+	if !ok {
+		return
+	}
+	if h.SingleUser != nil {
+		h.singleUserLogin(w, r, c)
+		return
+	}
+	user, err := h.Store.GetUserByUsername(r.Context(), c.Username)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		respondWithError(w, http.StatusInternalServerError, "Failed to look up user: "+err.Error())
+		return
+	}
+	// Unknown users and wrong passwords get the same answer
+	if user == nil || !user.CheckPassword(c.Password) {
+		respondWithError(w, http.StatusUnauthorized, "Invalid username or password")
+		return
+	}
+	h.startSession(w, r, user)
+}
+
+// startSession logs user in, responding with the session token and when it
+// expires and setting it as a cookie for the web UI.
+func (h *APIHandler) startSession(w http.ResponseWriter, r *http.Request, user *model.User) {
+	token, err := newSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create session: "+err.Error())
+		return
+	}
+	expiresAt := time.Now().UTC().Add(SessionLifetime)
+	if err := h.Store.CreateSession(r.Context(), user.ID, token, expiresAt); err != nil {
+		respondWithStoreError(w, err, "Failed to create session")
+		return
+	}
+	setSessionCookie(w, r, token, expiresAt)
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"token":      token,
+		"expires_at": expiresAt,
+		"user":       user,
+	})
+}
+
+// setSessionCookie sets the session cookie to token until expiresAt.
+func setSessionCookie(w http.ResponseWriter, r *http.Request, token string, expiresAt time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// LogoutHandler handles POST /api/users/logout requests, ending the session
+// whose token or cookie the request carries. Single-user sessions aren't
+// stored, so logging out only drops the cookie.
+func (h *APIHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if h.SingleUser != nil {
+		http.SetCookie(w, &http.Cookie{Name: SessionCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	token := bearerToken(r)
+	if cookie, err := r.Cookie(SessionCookie); token == "" && err == nil {
+		token = cookie.Value
+	}
+	if token == "" {
+		respondWithError(w, http.StatusUnauthorized, "Not logged in")
+		return
+	}
+	if err := h.Store.DeleteSession(r.Context(), token); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to log out: "+err.Error())
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: SessionCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CurrentUserHandler handles GET /api/users/me requests, returning the user
+// who is logged in, or SingleUserName in single-user mode.
+func (h *APIHandler) CurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	if h.SingleUser != nil {
+		respondWithJSON(w, http.StatusOK, currentUserResponse{User: model.User{Username: SingleUserName, PublicFeed: true}, Admin: true})
+		return
+	}
+	id, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	user, err := h.Store.GetUserByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to get user")
+		return
+	}
+	admin, err := h.isAdmin(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check for an admin: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, currentUserResponse{User: *user, Admin: admin})
+}
+
+// userSettings is the body of PATCH /api/users/me, the settings to change.
+type userSettings struct {
+	PublicFeed *bool `json:"public_feed"`
+}
+
+// UpdateCurrentUserHandler handles PATCH /api/users/me requests, changing the
+// settings of the user who is logged in: whether their activity feed is
+// public. Settings left out of the body are kept.
+func (h *APIHandler) UpdateCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	if h.accountsDisabled(w) {
+		return
+	}
+	id, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	var settings userSettings
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&settings); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if settings.PublicFeed != nil {
+		if err := h.Store.SetPublicFeed(r.Context(), id, *settings.PublicFeed); err != nil {
+			respondWithStoreError(w, err, "Failed to update user")
+			return
+		}
+	}
+	h.CurrentUserHandler(w, r)
+}
+
+// currentUserResponse is the body of GET /api/users/me: the user, and
+// whether they may use the admin endpoints.
+type currentUserResponse struct {
+	model.User
+	Admin bool `json:"admin"`
+}
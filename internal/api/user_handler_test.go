@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// authRequest sends a request to router, with a bearer token unless it is
+// empty, and returns the response.
+func authRequest(router http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+// newAuthRouter returns the full router, so requests pass the user
+// middleware, over a database of its own, since users change how every
+// request is served.
+func newAuthRouter(t *testing.T) (http.Handler, db.BookStore) {
+	handler := newTestAPIHandler(t)
+	return SetupRouter(handler, t.TempDir()), handler.Store
+}
+
+// newTestAPIHandler returns a handler over a database of its own, for tests
+// that configure it before passing it to SetupRouter.
+func newTestAPIHandler(t *testing.T) *APIHandler {
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := db.CreateSchema(database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	return NewAPIHandler(db.NewSQLiteBookStore(database))
+}
+
+// loginTestUser registers alice on router's bookshelf and returns a session
+// token of hers.
+func loginTestUser(t *testing.T, router http.Handler) string {
+	return registerTestUser(t, router, "alice")
+}
+
+// registerTestUser registers username on router's bookshelf and returns a
+// session token of theirs.
+func registerTestUser(t *testing.T, router http.Handler, username string) string {
+	credentials := `{"username":"` + username + `","password":"correct horse"}`
+	authRequest(router, "POST", "/api/v1/users/register", "", credentials)
+	rr := authRequest(router, "POST", "/api/v1/users/login", "", credentials)
+	var session struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &session); err != nil || session.Token == "" {
+		t.Fatalf("Logging in as %s: got status %d, body: %s", username, rr.Code, rr.Body.String())
+	}
+	return session.Token
+}
+
+// TestUserLibraries tests registering, logging in and keeping each user's
+// books apart.
+func TestUserLibraries(t *testing.T) {
+	router, store := newAuthRouter(t)
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		return authRequest(router, method, path, token, body)
+	}
+	login := func(username string) string {
+		rr := do("POST", "/api/v1/users/login", "", `{"username":"`+username+`","password":"correct horse"}`)
+		var session struct {
+			Token string     `json:"token"`
+			User  model.User `json:"user"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &session)
+		if rr.Code != http.StatusOK || session.Token == "" || session.User.Username != username {
+			t.Fatalf("Logging in as %s: got status %d, body: %s", username, rr.Code, rr.Body.String())
+		}
+		return session.Token
+	}
+
+	// Without users the shared library can be read, but not changed
+	if _, err := store.AddBook(context.Background(), &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL1M", Status: model.StatusRead}); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if rr := do("GET", "/api/v1/books", "", ""); rr.Code != http.StatusOK {
+		t.Errorf("Listing books without users: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("POST", "/api/v1/books", "", `{"title":"Emma","author":"Jane Austen","open_library_id":"OL2M","status":"Read"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Adding a book without logging in: got status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+
+	for _, username := range []string{"alice", "bob"} {
+		if rr := do("POST", "/api/v1/users/register", "", `{"username":"`+username+`","password":"correct horse"}`); rr.Code != http.StatusCreated {
+			t.Fatalf("Registering %s: got status %d, body: %s", username, rr.Code, rr.Body.String())
+		}
+	}
+	for body, want := range map[string]int{
+		`{"username":"ALICE","password":"correct horse"}`:     http.StatusConflict,
+		`{"username":"carol","password":"short"}`:             http.StatusBadRequest,
+		`{"username":"no spaces","password":"correct horse"}`: http.StatusBadRequest,
+	} {
+		if rr := do("POST", "/api/v1/users/register", "", body); rr.Code != want {
+			t.Errorf("Registering %s: got status %d, want %d", body, rr.Code, want)
+		}
+	}
+
+	// Once there are users, the library needs a login
+	if rr := do("GET", "/api/v1/books", "", ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Listing books without logging in: got status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+	if rr := do("POST", "/api/v1/users/login", "", `{"username":"alice","password":"wrong password"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Logging in with the wrong password: got status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+	alice, bob := login("alice"), login("bob")
+
+	rr := do("GET", "/api/v1/users/me", bob, "")
+	var me model.User
+	json.Unmarshal(rr.Body.Bytes(), &me)
+	if rr.Code != http.StatusOK || me.Username != "bob" || strings.Contains(rr.Body.String(), "hash") {
+		t.Errorf("Expected bob without his password hash, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// The first user adopted the book added before there were users
+	var books []BookResponse
+	rr = do("GET", "/api/v1/books", alice, "")
+	json.Unmarshal(rr.Body.Bytes(), &books)
+	if rr.Code != http.StatusOK || len(books) != 1 || books[0].Title != "Dune" {
+		t.Fatalf("Expected alice to have the existing book, got %d: %s", rr.Code, rr.Body.String())
+	}
+	dune := books[0].ID
+	rr = do("GET", "/api/v1/books", bob, "")
+	json.Unmarshal(rr.Body.Bytes(), &books)
+	if rr.Code != http.StatusOK || len(books) != 0 {
+		t.Errorf("Expected bob to start with an empty library, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/v1/books/"+itoa(dune), bob, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Getting another user's book: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := do("POST", "/api/v1/books", bob, `{"title":"Dune","author":"Frank Herbert","open_library_id":"OL1M","status":"Want to Read"}`); rr.Code != http.StatusCreated {
+		t.Errorf("Expected bob to add the same edition, got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	// Public routes need no login, though feeds are only found once public
+	if rr := do("GET", "/api/v1/feed.json", "", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Getting the private feed: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := do("PATCH", "/api/v1/users/me", alice, `{"public_feed": true}`); rr.Code != http.StatusOK {
+		t.Fatalf("Making the feed public: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/v1/feed.json", "", ""); rr.Code != http.StatusOK {
+		t.Errorf("Getting the public feed: got status %d, want %d", rr.Code, http.StatusOK)
+	}
+	if rr := do("PATCH", "/api/v1/users/me", "", `{"public_feed": false}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Changing settings without logging in: got status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+
+	if rr := do("POST", "/api/v1/users/logout", bob, ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("Logging out: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/v1/books", bob, ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Using a token after logging out: got status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+}
+
+// TestSessionCookie tests that logging in sets a cookie the web UI is
+// authenticated by, which doesn't authorize changes from other sites.
+func TestSessionCookie(t *testing.T) {
+	router, _ := newAuthRouter(t)
+	authRequest(router, "POST", "/api/v1/users/register", "", `{"username":"alice","password":"correct horse"}`)
+	rr := authRequest(router, "POST", "/api/v1/users/login", "", `{"username":"alice","password":"correct horse"}`)
+	var cookie *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == SessionCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("Expected an HttpOnly, SameSite=Lax session cookie, got %+v", cookie)
+	}
+
+	withCookie := func(method, path, site string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(`{"start_date":"2025-07-01","end_date":"2025-07-14"}`))
+		req.AddCookie(cookie)
+		if site != "" {
+			req.Header.Set("Sec-Fetch-Site", site)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	if rr := withCookie("GET", "/api/v1/users/me", ""); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "alice") {
+		t.Errorf("Expected the cookie to log in alice, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := withCookie("POST", "/api/v1/vacations", "same-origin"); rr.Code != http.StatusCreated {
+		t.Errorf("Changing the library from the web UI: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := withCookie("POST", "/api/v1/vacations", "cross-site"); rr.Code != http.StatusForbidden {
+		t.Errorf("Changing the library from another site: got status %d, want %d", rr.Code, http.StatusForbidden)
+	}
+
+	if rr := withCookie("POST", "/api/v1/users/logout", "same-origin"); rr.Code != http.StatusNoContent {
+		t.Fatalf("Logging out: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := withCookie("GET", "/api/v1/users/me", ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Using the cookie after logging out: got status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// GetVacationsHandler handles GET /api/vacations requests, listing every
+// vacation, earliest first.
+func (h *APIHandler) GetVacationsHandler(w http.ResponseWriter, r *http.Request) {
+	vacations, err := h.Store.GetVacations(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve vacations: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, vacations)
+}
+
+// AddVacationHandler handles POST /api/vacations requests. Expects
+// {"start_date": "2025-07-01", "end_date": "2025-07-14", "note": "..."}; both
+// dates are included and the note is optional.
+func (h *APIHandler) AddVacationHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		StartDate string  `json:"start_date"`
+		EndDate   string  `json:"end_date"`
+		Note      *string `json:"note"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	vacation := model.Vacation{StartDate: payload.StartDate, EndDate: payload.EndDate, Note: payload.Note}
+	if err := h.Store.AddVacation(r.Context(), &vacation); err != nil {
+		respondWithStoreError(w, err, "Failed to add vacation")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, vacation)
+}
+
+// DeleteVacationHandler handles DELETE /api/vacations/{id} requests.
+func (h *APIHandler) DeleteVacationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid vacation ID")
+		return
+	}
+	if err := h.Store.DeleteVacation(r.Context(), id); err != nil {
+		respondWithStoreError(w, err, "Failed to delete vacation")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestVacationHandlers tests adding, listing and removing vacations
+func TestVacationHandlers(t *testing.T) {
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("POST", "/api/vacations", `{"start_date":"2025-07-01","end_date":"2025-07-14","note":"Trip"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Adding a vacation: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	var vacation model.Vacation
+	json.Unmarshal(rr.Body.Bytes(), &vacation)
+	defer testStore.DeleteVacation(context.Background(), vacation.ID)
+
+	rr = do("GET", "/api/vacations", "")
+	var vacations []model.Vacation
+	json.Unmarshal(rr.Body.Bytes(), &vacations)
+	if rr.Code != http.StatusOK || len(vacations) != 1 || vacations[0].ID != vacation.ID || vacations[0].EndDate != "2025-07-14" {
+		t.Errorf("Expected the vacation in the list, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	for _, body := range []string{`{"start_date":"2025-07-14","end_date":"2025-07-01"}`, `{"start_date":"2025-07-01"}`, `{"start_date":"tomorrow","end_date":"2025-07-01"}`} {
+		if rr := do("POST", "/api/vacations", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want %d", body, rr.Code, http.StatusBadRequest)
+		}
+	}
+
+	if rr := do("DELETE", "/api/vacations/"+itoa(vacation.ID), ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Deleting a vacation: got status %d, want %d", rr.Code, http.StatusNoContent)
+	}
+	if rr := do("DELETE", "/api/vacations/"+itoa(vacation.ID), ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Deleting twice: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
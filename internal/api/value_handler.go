@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GetBookValueHandler handles GET /api/books/{id}/value requests and returns
+// the market value samples of the book's ISBN, oldest first.
+func (h *APIHandler) GetBookValueHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	history, err := h.Store.GetBookValueHistory(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve market values")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, history)
+}
+
+// GetCollectionValueHandler handles GET /api/stats/value requests, returning
+// the market value of the books owned over time, per currency.
+func (h *APIHandler) GetCollectionValueHandler(w http.ResponseWriter, r *http.Request) {
+	value, err := h.Store.GetCollectionValue(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to compute collection value: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, value)
+}
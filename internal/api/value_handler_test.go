@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestValueHandlers tests reading the market value history of a book and of
+// the collection
+func TestValueHandlers(t *testing.T) {
+	book := createTestBook(model.StatusRead, "Value")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.DeleteBook(context.Background(), id)
+	sample := model.MarketValue{ISBN: book.ISBN, Value: 42, Currency: "XTS", Source: "test"}
+	if err := testStore.AddMarketValue(context.Background(), &sample); err != nil {
+		t.Fatalf("AddMarketValue failed: %v", err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/api/books/" + itoa(id) + "/value")
+	var history model.BookValueHistory
+	if err := json.Unmarshal(rr.Body.Bytes(), &history); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected the book's value history, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(history.Samples) != 1 || history.Latest == nil || history.Latest.Value != 42 {
+		t.Errorf("Expected the sample as the latest value, got %+v", history)
+	}
+	if rr := get("/api/books/999999/value"); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing book, got %d", rr.Code)
+	}
+
+	rr = get("/api/stats/value")
+	var collection model.CollectionValue
+	if err := json.Unmarshal(rr.Body.Bytes(), &collection); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected the collection value, got %d: %s", rr.Code, rr.Body.String())
+	}
+	found := false
+	for _, series := range collection.Series {
+		found = found || series.Currency == "XTS" && len(series.Points) == 1 && series.Points[0].Value == 42
+	}
+	if !found {
+		t.Errorf("Expected a XTS series worth 42, got %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// webhooksEnabled responds with an error and returns false when webhooks
+// are unavailable, as their secrets can't be encrypted.
+func (h *APIHandler) webhooksEnabled(w http.ResponseWriter) bool {
+	if h.Webhooks == nil || !h.Webhooks.Box.Enabled() {
+		respondWithError(w, http.StatusServiceUnavailable, "Webhooks require the server to be started with --secret-key")
+		return false
+	}
+	return true
+}
+
+// GetWebhooksHandler handles GET /api/webhooks requests. Secrets are never
+// included in the response.
+func (h *APIHandler) GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.Store.GetWebhooks(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve webhooks: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, webhooks)
+}
+
+// AddWebhookHandler handles POST /api/webhooks requests. Expects the URL to
+// post events to and the events to subscribe to (default all of them), and
+// responds with the webhook and the secret its payloads are signed with in
+// "secret"; it can't be shown again.
+func (h *APIHandler) AddWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.webhooksEnabled(w) {
+		return
+	}
+	var payload struct {
+		URL     string               `json:"url"`
+		Events  []model.WebhookEvent `json:"events"`
+		Enabled *bool                `json:"enabled"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if u, err := url.Parse(payload.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		respondWithError(w, http.StatusBadRequest, "url must be an absolute http or https URL")
+		return
+	}
+	if len(payload.Events) == 0 {
+		payload.Events = model.WebhookEvents
+	}
+	events := []model.WebhookEvent{}
+	seen := map[model.WebhookEvent]bool{}
+	for _, event := range payload.Events {
+		if !event.IsValid() {
+			respondWithError(w, http.StatusBadRequest, "Invalid event "+strconv.Quote(string(event))+". Must be 'book.created', 'book.status_changed', 'book.finished' or 'book.deleted'")
+			return
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create webhook secret: "+err.Error())
+		return
+	}
+	sealed, err := h.Webhooks.Box.Seal(secret)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to encrypt webhook secret: "+err.Error())
+		return
+	}
+	webhook := model.Webhook{
+		URL:             payload.URL,
+		Events:          events,
+		Enabled:         payload.Enabled == nil || *payload.Enabled,
+		EncryptedSecret: sealed,
+	}
+	if _, err := h.Store.AddWebhook(r.Context(), &webhook); err != nil {
+		respondWithStoreError(w, err, "Failed to add webhook")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, struct {
+		model.Webhook
+		Secret string `json:"secret"`
+	}{webhook, secret})
+}
+
+// DeleteWebhookHandler handles DELETE /api/webhooks/{id} requests. Its
+// delivery log goes with it, and deliveries still pending are dropped.
+func (h *APIHandler) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+	if err := h.Store.DeleteWebhook(r.Context(), id); err != nil {
+		respondWithStoreError(w, err, "Failed to delete webhook")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWebhookDeliveriesHandler handles GET /api/webhooks/{id}/deliveries
+// requests, the delivery log of a webhook, newest first, with the payloads
+// sent. Optional query parameter: limit (default 100).
+func (h *APIHandler) GetWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+	limit := 100
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 1000 {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		limit = n
+	}
+	deliveries, err := h.Store.GetWebhookDeliveries(r.Context(), id, limit)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve webhook deliveries")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, deliveries)
+}
+
+// RetryWebhookDeliveryHandler handles POST
+// /api/webhooks/deliveries/{id}/retry requests, queuing a failed (or
+// delivered) delivery to be sent again on the dispatcher's next round, with
+// a fresh set of retries.
+func (h *APIHandler) RetryWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.webhooksEnabled(w) {
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid delivery ID")
+		return
+	}
+	delivery, err := h.Store.RetryWebhookDelivery(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retry webhook delivery")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, delivery)
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/secrets"
+	"github.com/ericdahl/bookshelf/internal/webhook"
+)
+
+// TestWebhookLifecycle tests adding a webhook, the deliveries queued for it
+// as a book is finished, retrying one and deleting the webhook
+func TestWebhookLifecycle(t *testing.T) {
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+	payload := `{"url": "https://books.example/hooks", "events": ["book.finished", "book.finished"]}`
+
+	// Without a secret key the server can't keep the signing secret
+	if rr := do("POST", "/api/webhooks", payload); rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 without a secret key, got %d", rr.Code)
+	}
+
+	box, err := secrets.NewBox("test-key")
+	if err != nil {
+		t.Fatalf("NewBox failed: %v", err)
+	}
+	testHandler.Webhooks = webhook.NewDispatcher(testStore, box)
+	defer func() { testHandler.Webhooks = nil }()
+
+	for _, body := range []string{
+		`{"url": "ftp://books.example/hooks"}`,
+		`{"url": "/hooks"}`,
+		`{"url": "https://books.example/hooks", "events": ["book.read"]}`,
+	} {
+		if rr := do("POST", "/api/webhooks", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rr.Code)
+		}
+	}
+
+	rr := do("POST", "/api/webhooks", payload)
+	var created struct {
+		model.Webhook
+		Secret string `json:"secret"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil || rr.Code != http.StatusCreated || created.Secret == "" ||
+		len(created.Events) != 1 || !created.Enabled {
+		t.Fatalf("Expected the webhook created, got %d: %s", rr.Code, rr.Body.String())
+	}
+	defer testStore.DeleteWebhook(context.Background(), created.ID)
+	if rr := do("GET", "/api/webhooks", ""); rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), created.Secret) ||
+		!strings.Contains(rr.Body.String(), "https://books.example/hooks") {
+		t.Errorf("Expected the webhook listed without its secret, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	book := createTestBook(model.StatusCurrentlyReading, "Hooked")
+	id, err := testStore.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	defer testStore.PurgeBook(context.Background(), id)
+	if rr := do("PUT", "/api/books/"+itoa(id), `{"status": "Read"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Failed to finish the book: %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do("GET", "/api/webhooks/"+itoa(created.ID)+"/deliveries?limit=5", "")
+	var deliveries []model.WebhookDelivery
+	if err := json.Unmarshal(rr.Body.Bytes(), &deliveries); err != nil || rr.Code != http.StatusOK || len(deliveries) != 1 {
+		t.Fatalf("Expected one delivery, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if d := deliveries[0]; d.Event != model.WebhookBookFinished || d.BookID != id || d.Status != model.DeliveryPending ||
+		!strings.Contains(string(d.Payload), `"previous_status":"Currently Reading"`) {
+		t.Errorf("Unexpected delivery %+v", d)
+	}
+	if rr := do("GET", "/api/webhooks/"+itoa(created.ID)+"/deliveries?limit=0", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid limit, got %d", rr.Code)
+	}
+
+	if rr := do("POST", "/api/webhooks/deliveries/"+itoa(deliveries[0].ID)+"/retry", ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected the delivery queued again, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("POST", "/api/webhooks/deliveries/999999/retry", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 retrying a missing delivery, got %d", rr.Code)
+	}
+
+	if rr := do("DELETE", "/api/webhooks/"+itoa(created.ID), ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", rr.Code)
+	}
+	if rr := do("GET", "/api/webhooks/"+itoa(created.ID)+"/deliveries", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a deleted webhook, got %d", rr.Code)
+	}
+}
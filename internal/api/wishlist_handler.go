@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GetWishlistMembersHandler handles GET /api/wishlist/members requests,
+// listing the users the wishlist of the user logged in is shared with.
+func (h *APIHandler) GetWishlistMembersHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := currentUser(w, r); !ok {
+		return
+	}
+	members, err := h.Store.GetWishlistMembers(r.Context())
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve wishlist members")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, members)
+}
+
+// AddWishlistMemberHandler handles POST /api/wishlist/members requests.
+// Expects {"username": "bob"} and shares the wishlist, the Want to Read
+// shelf, with that user so they can claim books on it to give.
+func (h *APIHandler) AddWishlistMemberHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := currentUser(w, r); !ok {
+		return
+	}
+	var payload struct {
+		Username string `json:"username"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	member, err := h.Store.ShareWishlist(r.Context(), payload.Username)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to share wishlist")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, member)
+}
+
+// DeleteWishlistMemberHandler handles DELETE /api/wishlist/members/{userID}
+// requests, no longer sharing the wishlist with that user. Their claims on
+// it are dropped.
+func (h *APIHandler) DeleteWishlistMemberHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := currentUser(w, r); !ok {
+		return
+	}
+	memberID, err := strconv.ParseInt(mux.Vars(r)["userID"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if err := h.Store.UnshareWishlist(r.Context(), memberID); err != nil {
+		respondWithStoreError(w, err, "Failed to unshare wishlist")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSharedWishlistsHandler handles GET /api/wishlists requests, listing the
+// users whose wishlists are shared with the user logged in.
+func (h *APIHandler) GetSharedWishlistsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := currentUser(w, r); !ok {
+		return
+	}
+	owners, err := h.Store.GetSharedWishlists(r.Context())
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve shared wishlists")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, owners)
+}
+
+// GetSharedWishlistHandler handles GET /api/wishlists/{ownerID} requests,
+// listing the books on a wishlist shared with the user logged in and which
+// of them members claimed. The owner gets a 404, so the claims stay secret.
+func (h *APIHandler) GetSharedWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := currentUser(w, r); !ok {
+		return
+	}
+	ownerID, err := strconv.ParseInt(mux.Vars(r)["ownerID"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	items, err := h.Store.GetSharedWishlist(r.Context(), ownerID)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to retrieve wishlist")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, items)
+}
+
+// wishlistBookIDs reads the owner and book IDs of the URL. It responds with
+// the error and returns false when either is invalid.
+func wishlistBookIDs(w http.ResponseWriter, r *http.Request) (ownerID, bookID int64, ok bool) {
+	vars := mux.Vars(r)
+	ownerID, err := strconv.ParseInt(vars["ownerID"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return 0, 0, false
+	}
+	bookID, err = strconv.ParseInt(vars["bookID"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		return 0, 0, false
+	}
+	return ownerID, bookID, true
+}
+
+// ClaimWishlistBookHandler handles PUT
+// /api/wishlists/{ownerID}/books/{bookID}/claim requests, claiming a book on
+// a shared wishlist to give it, so other members don't buy it too. An
+// optional {"purchased": true} records that it was bought. A book another
+// member claimed is a 409.
+func (h *APIHandler) ClaimWishlistBookHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := currentUser(w, r); !ok {
+		return
+	}
+	ownerID, bookID, ok := wishlistBookIDs(w, r)
+	if !ok {
+		return
+	}
+	var payload struct {
+		Purchased bool `json:"purchased"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	item, err := h.Store.ClaimWishlistBook(r.Context(), ownerID, bookID, payload.Purchased)
+	if err != nil {
+		respondWithStoreError(w, err, "Failed to claim book")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, item)
+}
+
+// UnclaimWishlistBookHandler handles DELETE
+// /api/wishlists/{ownerID}/books/{bookID}/claim requests, withdrawing the
+// claim of the user logged in.
+func (h *APIHandler) UnclaimWishlistBookHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := currentUser(w, r); !ok {
+		return
+	}
+	ownerID, bookID, ok := wishlistBookIDs(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Store.UnclaimWishlistBook(r.Context(), ownerID, bookID); err != nil {
+		respondWithStoreError(w, err, "Failed to unclaim book")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestWishlistGiftClaims tests sharing a wishlist with household members who
+// claim books on it without the owner seeing.
+func TestWishlistGiftClaims(t *testing.T) {
+	router, _ := newAuthRouter(t)
+	alice := loginTestUser(t, router)
+	authRequest(router, "POST", "/api/v1/users/register", "", `{"username":"bob","password":"correct horse"}`)
+	rr := authRequest(router, "POST", "/api/v1/users/login", "", `{"username":"bob","password":"correct horse"}`)
+	var session struct {
+		Token string     `json:"token"`
+		User  model.User `json:"user"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &session); err != nil || session.Token == "" {
+		t.Fatalf("Logging in as bob: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	bob := session.Token
+
+	rr = authRequest(router, "POST", "/api/v1/books", alice, `{"title":"Emma","author":"Jane Austen","open_library_id":"OL2M","status":"Want to Read"}`)
+	var book BookResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &book); err != nil || rr.Code != http.StatusCreated {
+		t.Fatalf("Adding a book: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = authRequest(router, "GET", "/api/v1/users/me", alice, "")
+	var me model.User
+	json.Unmarshal(rr.Body.Bytes(), &me)
+	wishlist := "/api/v1/wishlists/" + itoa(me.ID)
+	claim := wishlist + "/books/" + itoa(book.ID) + "/claim"
+
+	if rr := authRequest(router, "GET", wishlist, bob, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Getting a wishlist not shared: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := authRequest(router, "POST", "/api/v1/wishlist/members", alice, `{"username":"carol"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Sharing with a missing user: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	rr = authRequest(router, "POST", "/api/v1/wishlist/members", alice, `{"username":"bob"}`)
+	var member model.WishlistShare
+	if err := json.Unmarshal(rr.Body.Bytes(), &member); err != nil || rr.Code != http.StatusCreated || member.UserID != session.User.ID {
+		t.Fatalf("Sharing the wishlist with bob: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := authRequest(router, "PUT", claim, bob, ""); rr.Code != http.StatusOK {
+		t.Fatalf("Claiming the book: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	rr = authRequest(router, "PUT", claim, bob, `{"purchased":true}`)
+	var item model.WishlistItem
+	if err := json.Unmarshal(rr.Body.Bytes(), &item); err != nil || rr.Code != http.StatusOK || !item.ClaimedByMe || !item.Purchased {
+		t.Errorf("Marking the book purchased: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	rr = authRequest(router, "GET", wishlist, bob, "")
+	var items []model.WishlistItem
+	if err := json.Unmarshal(rr.Body.Bytes(), &items); err != nil || len(items) != 1 || !items[0].Claimed {
+		t.Errorf("Expected the claimed book on the wishlist, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// The owner can't see the claims
+	if rr := authRequest(router, "GET", wishlist, alice, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Owner getting her own wishlist: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := authRequest(router, "PUT", claim, alice, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Owner claiming her own book: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	if rr := authRequest(router, "DELETE", claim, bob, ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Withdrawing the claim: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := authRequest(router, "DELETE", "/api/v1/wishlist/members/"+itoa(member.UserID), alice, ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Unsharing the wishlist: got status %d, body: %s", rr.Code, rr.Body.String())
+	}
+	rr = authRequest(router, "GET", "/api/v1/wishlists", bob, "")
+	var owners []model.WishlistShare
+	if err := json.Unmarshal(rr.Body.Bytes(), &owners); err != nil || len(owners) != 0 {
+		t.Errorf("Expected no wishlists shared with bob, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,299 @@
+// Package backfill fills in the fields the data quality report finds missing
+// (ISBNs, covers, page counts and publication years) from the metadata
+// providers. Matches confident enough are applied straight away; the rest
+// go to the manual review queue, to be confirmed or dismissed there.
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/match"
+	"github.com/ericdahl/bookshelf/internal/metadata"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/ratelimit"
+)
+
+// ErrRunning is returned when a backfill is started while another is in progress.
+var ErrRunning = fmt.Errorf("metadata backfill is already running")
+
+// Fix is a book given fields from the providers, or a failure to give them.
+type Fix struct {
+	BookID   int64    `json:"book_id"`
+	Title    string   `json:"title"`
+	Provider string   `json:"provider,omitempty"`
+	Score    float64  `json:"score"`
+	Fields   []string `json:"fields"`          // Filled, or that would have been
+	Error    string   `json:"error,omitempty"` // Why the fields could not be saved
+}
+
+// Report summarizes a backfill run.
+type Report struct {
+	Running    bool       `json:"running"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Threshold  float64    `json:"threshold"` // Score at or above which fixes are applied
+	Checked    int        `json:"checked"`   // Books missing a field the providers can fill
+	Filled     int        `json:"filled"`    // Books fixed automatically
+	Queued     int        `json:"queued"`    // Books newly sent to the review queue
+	Unmatched  int        `json:"unmatched"` // Books the providers found nothing for
+	Fixes      []Fix      `json:"fixes"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Backfiller looks up the books missing metadata and fills in what it finds.
+// Only one backfill runs at a time; the report of the latest run is kept.
+type Backfiller struct {
+	Store    db.BookStore
+	Metadata metadata.Searcher
+	// Thresholds decide what is applied (Accept, unless a run overrides it)
+	// and what is queued for review; weaker matches are dropped.
+	Thresholds match.Thresholds
+
+	mu     sync.Mutex
+	report Report
+}
+
+// NewBackfiller creates a Backfiller asking metadata, such as a Chain.
+func NewBackfiller(store db.BookStore, metadata metadata.Searcher) *Backfiller {
+	return &Backfiller{
+		Store:      store,
+		Metadata:   metadata,
+		Thresholds: match.DefaultThresholds,
+		report:     Report{Fixes: []Fix{}},
+	}
+}
+
+// Report returns the progress or outcome of the latest run.
+func (b *Backfiller) Report() Report {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	report := b.report
+	report.Fixes = append([]Fix{}, b.report.Fixes...)
+	return report
+}
+
+// Start begins a backfill in the background, applying matches scoring at
+// least threshold, or the accept threshold when it is 0. It returns
+// ErrRunning if a backfill is already in progress.
+func (b *Backfiller) Start(ctx context.Context, threshold float64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.report.Running {
+		return ErrRunning
+	}
+	if threshold == 0 {
+		threshold = b.Thresholds.Accept
+	}
+	now := time.Now().UTC()
+	b.report = Report{Running: true, StartedAt: &now, Threshold: threshold, Fixes: []Fix{}}
+	go b.run(ctx, threshold)
+	return nil
+}
+
+// Run backfills the library and waits for the result.
+func (b *Backfiller) Run(ctx context.Context, threshold float64) (Report, error) {
+	if err := b.Start(ctx, threshold); err != nil {
+		return Report{}, err
+	}
+	for {
+		report := b.Report()
+		if !report.Running {
+			return report, nil
+		}
+		select {
+		case <-ctx.Done():
+			return report, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func (b *Backfiller) run(ctx context.Context, threshold float64) {
+	ctx = ratelimit.WithPriority(ctx, ratelimit.Background)
+	books, err := b.Store.GetBooks(ctx)
+	if err != nil {
+		b.finish(fmt.Errorf("failed to load books: %w", err))
+		return
+	}
+	slog.Info("Starting metadata backfill", "books", len(books), "threshold", threshold)
+	for _, book := range books {
+		if ctx.Err() != nil {
+			break
+		}
+		if len(Missing(book)) == 0 {
+			continue
+		}
+		b.backfill(ctx, book, threshold)
+	}
+	b.finish(ctx.Err())
+}
+
+func (b *Backfiller) finish(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now().UTC()
+	b.report.Running = false
+	b.report.FinishedAt = &now
+	if err != nil {
+		b.report.Error = err.Error()
+	}
+	slog.Info("Metadata backfill finished", "checked", b.report.Checked, "filled", b.report.Filled,
+		"queued", b.report.Queued, "unmatched", b.report.Unmatched, "error", err)
+}
+
+func (b *Backfiller) update(fn func(*Report)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	fn(&b.report)
+}
+
+// backfill looks one book up and fills it in, or queues the match for review.
+func (b *Backfiller) backfill(ctx context.Context, book model.Book, threshold float64) {
+	query := book.ISBN
+	if query == "" {
+		query = book.FullTitle() + " " + book.Author
+	}
+	results, err := b.Metadata.Search(ctx, query)
+	if err != nil {
+		slog.Warn("Metadata backfill lookup failed", "id", book.ID, "error", err)
+	}
+
+	// The best match that has something to give
+	var best *metadata.Book
+	var item model.PendingItem
+	var score float64
+	for i := range results {
+		candidate := Item(results[i])
+		if _, fields := Patch(book, candidate); len(fields) == 0 {
+			continue
+		}
+		if s := Score(book, results[i]); best == nil || s > score {
+			best, item, score = &results[i], candidate, s
+		}
+	}
+	if best == nil || score < b.Thresholds.Review {
+		b.update(func(r *Report) { r.Checked++; r.Unmatched++ })
+		return
+	}
+
+	patch, fields := Patch(book, item)
+	fix := Fix{BookID: book.ID, Title: book.Title, Provider: best.Provider, Score: score, Fields: fields}
+	if score < threshold {
+		pending := &model.PendingMatch{
+			Source:     model.SourceBackfill,
+			SourceRef:  strconv.FormatInt(book.ID, 10),
+			ItemKey:    best.Provider + ":" + best.ID,
+			Item:       item,
+			Candidates: []model.PendingCandidate{{BookID: book.ID, Title: book.FullTitle(), Author: book.Author, Score: score}},
+		}
+		if book.UserID != nil {
+			ctx = db.WithUser(ctx, *book.UserID) // For its owner to review
+		}
+		added, err := b.Store.AddPendingMatch(ctx, pending)
+		if err != nil {
+			fix.Error = "failed to queue for review: " + err.Error()
+			b.update(func(r *Report) { r.Checked++; r.Fixes = append(r.Fixes, fix) })
+			return
+		}
+		b.update(func(r *Report) {
+			r.Checked++
+			if added {
+				r.Queued++
+			}
+		})
+		return
+	}
+
+	if err := b.Store.UpdateBook(ctx, book.ID, patch); err != nil {
+		fix.Error = "failed to save fields: " + err.Error()
+		b.update(func(r *Report) { r.Checked++; r.Fixes = append(r.Fixes, fix) })
+		return
+	}
+	slog.Info("Backfilled book metadata", "id", book.ID, "title", book.Title, "fields", fields, "score", score)
+	b.update(func(r *Report) { r.Checked++; r.Filled++; r.Fixes = append(r.Fixes, fix) })
+}
+
+// Missing returns the fields of book the providers may be able to fill.
+func Missing(book model.Book) []string {
+	var fields []string
+	if book.ISBN == "" {
+		fields = append(fields, "isbn")
+	}
+	if book.CoverURL == nil || *book.CoverURL == "" {
+		fields = append(fields, "cover_url")
+	}
+	if book.PageCount == nil {
+		fields = append(fields, "page_count")
+	}
+	if book.PublishYear == nil {
+		fields = append(fields, "publish_year")
+	}
+	return fields
+}
+
+// Item converts a provider's book to the form the review queue stores.
+func Item(found metadata.Book) model.PendingItem {
+	item := model.PendingItem{Title: found.Title, Author: strings.Join(found.Authors, ", "), ISBN: found.ISBN}
+	if found.Subtitle != "" {
+		item.Title += ": " + found.Subtitle
+	}
+	if !strings.Contains(found.ID, ":") {
+		item.OpenLibraryID = found.ID
+	}
+	if found.CoverURL != "" {
+		item.CoverURL = &found.CoverURL
+	}
+	if found.PageCount > 0 {
+		item.PageCount = &found.PageCount
+	}
+	if found.PublishYear > 0 {
+		item.Year = &found.PublishYear
+	}
+	return item
+}
+
+// Patch returns the patch filling the fields book is missing from item, and
+// the JSON names of those fields. Fields the book has are never changed.
+func Patch(book model.Book, item model.PendingItem) (model.BookPatch, []string) {
+	var patch model.BookPatch
+	fields := []string{}
+	for _, field := range Missing(book) {
+		switch {
+		case field == "isbn" && item.ISBN != "":
+			patch.ISBN = model.Some(item.ISBN)
+		case field == "cover_url" && item.CoverURL != nil:
+			patch.CoverURL = model.Some(*item.CoverURL)
+		case field == "page_count" && item.PageCount != nil:
+			patch.PageCount = model.Some(*item.PageCount)
+		case field == "publish_year" && item.Year != nil:
+			patch.PublishYear = model.Some(*item.Year)
+		default:
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return patch, fields
+}
+
+// Score is how confident the bookshelf is that a provider's book is book,
+// from 0 to 1. A shared Open Library ID or ISBN is certain.
+func Score(book model.Book, found metadata.Book) float64 {
+	if book.OpenLibraryID != "" && found.ID == book.OpenLibraryID {
+		return 1
+	}
+	candidate := match.Candidate{Title: Item(found).Title, Author: strings.Join(found.Authors, ", ")}
+	if found.ISBN != "" {
+		candidate.ISBNs = []string{found.ISBN}
+	}
+	if found.PublishYear > 0 {
+		candidate.Year = &found.PublishYear
+	}
+	return match.Score(candidate, book)
+}
@@ -0,0 +1,128 @@
+package backfill
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/metadata"
+	"github.com/ericdahl/bookshelf/internal/model"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// stubProvider finds the books listed under a query, by title or ISBN.
+type stubProvider map[string][]metadata.Book
+
+func (s stubProvider) Name() string { return "stub" }
+
+func (s stubProvider) Search(ctx context.Context, query string) ([]metadata.Book, error) {
+	return s[query], nil
+}
+
+func (s stubProvider) LookupISBN(ctx context.Context, isbn string) ([]metadata.Book, error) {
+	return s[isbn], nil
+}
+
+func TestBackfillerRun(t *testing.T) {
+	ctx := context.Background()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+	if err := db.CreateSchema(database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	store := db.NewSQLiteBookStore(database)
+
+	pages, year, cover := 320, 1940, "https://covers.example/1.jpg"
+	books := []*model.Book{
+		{Title: "Kallocain", Author: "Karin Boye", OpenLibraryID: "OL1M", ISBN: "9789100123456", PageCount: &pages},
+		{Title: "The Left Hand of Darkness", Author: "Ursula K. Le Guin", OpenLibraryID: "OL2M"},
+		{Title: "Obscure Pamphlet", Author: "Nobody", OpenLibraryID: "OL3M"},
+		{Title: "Complete", Author: "Someone", OpenLibraryID: "OL4M", ISBN: "9780000000002", PageCount: &pages, PublishYear: &year, CoverURL: &cover},
+	}
+	for _, b := range books {
+		b.Status = model.StatusRead
+		if _, err := store.AddBook(ctx, b); err != nil {
+			t.Fatalf("Failed to add book: %v", err)
+		}
+	}
+
+	provider := stubProvider{
+		"9789100123456": {{Provider: "stub", ID: "OLKALLOCAINM", Title: "Kallocain", Authors: []string{"Karin Boye"},
+			ISBN: "9789100123456", CoverURL: "https://covers.example/kallocain.jpg", PublishYear: 1940, PageCount: 200}},
+		"The Left Hand of Darkness Ursula K. Le Guin": {{Provider: "stub", ID: "OLLEFTHANDW", Title: "The Left Hand of Darkness",
+			Authors: []string{"Ursula Le Guin"}, ISBN: "9780441478125", PublishYear: 1969, PageCount: 304}},
+	}
+	backfiller := NewBackfiller(store, metadata.Chain{provider})
+	// Only the ISBN match is certain enough to apply
+	report, err := backfiller.Run(ctx, 1)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Running || report.FinishedAt == nil || report.Error != "" || report.Threshold != 1 ||
+		report.Checked != 3 || report.Filled != 1 || report.Queued != 1 || report.Unmatched != 1 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if len(report.Fixes) != 1 || report.Fixes[0].BookID != books[0].ID || len(report.Fixes[0].Fields) != 2 {
+		t.Errorf("Expected the cover and year of Kallocain to be filled, got %+v", report.Fixes)
+	}
+
+	kallocain, err := store.GetBookByID(ctx, books[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to get book: %v", err)
+	}
+	if kallocain.CoverURL == nil || *kallocain.CoverURL != "https://covers.example/kallocain.jpg" ||
+		kallocain.PublishYear == nil || *kallocain.PublishYear != 1940 || *kallocain.PageCount != 320 {
+		t.Errorf("Expected only the missing fields to be filled, got %+v", kallocain)
+	}
+
+	queue, err := store.GetPendingMatches(ctx, model.MatchPending)
+	if err != nil {
+		t.Fatalf("Failed to get review queue: %v", err)
+	}
+	if len(queue) != 1 || queue[0].Source != model.SourceBackfill || queue[0].SourceRef != strconv.FormatInt(books[1].ID, 10) ||
+		queue[0].Item.ISBN != "9780441478125" || len(queue[0].Candidates) != 1 || queue[0].Candidates[0].BookID != books[1].ID {
+		t.Errorf("Expected The Left Hand of Darkness to be queued, got %+v", queue)
+	}
+	if left, _ := store.GetBookByID(ctx, books[1].ID); left.ISBN != "" {
+		t.Errorf("Queued book was changed: %+v", left)
+	}
+
+	// The default threshold applies the confident title match
+	report, err = backfiller.Run(ctx, 0)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Threshold != backfiller.Thresholds.Accept || report.Filled != 1 || report.Queued != 0 {
+		t.Errorf("Unexpected second report: %+v", report)
+	}
+	if left, _ := store.GetBookByID(ctx, books[1].ID); left.ISBN != "9780441478125" || left.PageCount == nil || *left.PageCount != 304 {
+		t.Errorf("Expected The Left Hand of Darkness to be filled, got %+v", left)
+	}
+
+	// A second run while one is in progress is refused
+	backfiller.update(func(r *Report) { r.Running = true })
+	if err := backfiller.Start(ctx, 0); err != ErrRunning {
+		t.Errorf("Expected ErrRunning, got %v", err)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	pages := 100
+	book := model.Book{Title: "T", Author: "A", PageCount: &pages}
+	cover, more, year := "https://covers.example/t.jpg", 250, 2001
+	patch, fields := Patch(book, model.PendingItem{ISBN: "9780000000002", CoverURL: &cover, PageCount: &more, Year: &year})
+	if len(fields) != 3 || fields[0] != "isbn" || fields[1] != "cover_url" || fields[2] != "publish_year" {
+		t.Errorf("Unexpected fields %v", fields)
+	}
+	if patch.PageCount.Set || *patch.ISBN.Value != "9780000000002" || *patch.PublishYear.Value != 2001 {
+		t.Errorf("Unexpected patch %+v", patch)
+	}
+	if _, fields := Patch(book, model.PendingItem{Title: "T"}); len(fields) != 0 {
+		t.Errorf("Expected nothing to fill, got %v", fields)
+	}
+}
@@ -0,0 +1,327 @@
+// Package backup takes backups of the SQLite database on a cron-style
+// schedule, writes them to a directory or an S3-compatible bucket and prunes
+// the old ones by count and by age.
+package backup
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/export"
+)
+
+// ErrRunning is returned when a backup is started while another is in progress.
+var ErrRunning = fmt.Errorf("a scheduled backup is already running")
+
+// filePrefix and fileSuffix surround the time in the name of every backup
+// file, so retention only ever touches files written by the scheduler.
+const (
+	filePrefix = "bookshelf-"
+	fileSuffix = "Z.db"
+	timeLayout = "20060102T150405"
+)
+
+// ContentType is the media type of backup files.
+const ContentType = "application/vnd.sqlite3"
+
+// lastRunSetting is the settings key holding the time of the last successful
+// backup, so one missed while the server was down runs when it starts.
+const lastRunSetting = "backup_last_run"
+
+// Filename returns the name of a backup taken at t. Names sort
+// chronologically.
+func Filename(t time.Time) string {
+	return filePrefix + t.UTC().Format(timeLayout) + fileSuffix
+}
+
+// takenAt returns the time in the name of a backup file, or false if name
+// isn't one.
+func takenAt(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(timeLayout, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+	return t, err == nil
+}
+
+// Destination is where backups go, which can list and delete them for
+// retention.
+type Destination interface {
+	export.Destination
+	export.Lister
+}
+
+// ParseDestination builds a destination from its configured location: a
+// directory, optionally prefixed with file://, or s3://bucket/prefix
+// (credentials from the AWS_* environment), as for exports. Unlike exports,
+// backups aren't sent to webhooks, which couldn't prune them.
+func ParseDestination(location string, client *http.Client) (Destination, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return nil, fmt.Errorf("backups go to a directory or s3://bucket/prefix, not a URL")
+	}
+	dest, err := export.ParseDestination(location, client)
+	if err != nil {
+		return nil, err
+	}
+	d, ok := dest.(Destination)
+	if !ok {
+		return nil, fmt.Errorf("backups at %s couldn't be pruned", location)
+	}
+	return d, nil
+}
+
+// Backup is a backup file at the destination.
+type Backup struct {
+	Name    string    `json:"name"`
+	TakenAt time.Time `json:"taken_at"`
+}
+
+// Run is the outcome of a scheduled backup.
+type Run struct {
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	Bytes      int        `json:"bytes"`
+	Pruned     int        `json:"pruned"` // Old backups deleted after it
+	Error      string     `json:"error,omitempty"`
+}
+
+// Status describes the schedule, its latest run and the backups kept.
+type Status struct {
+	Schedule      string     `json:"schedule"`
+	Destination   string     `json:"destination"`
+	Keep          int        `json:"keep"`      // Newest backups kept; 0 keeps any number
+	KeepDays      int        `json:"keep_days"` // Days backups are kept; 0 keeps them for good
+	Running       bool       `json:"running"`
+	NextRunAt     *time.Time `json:"next_run_at,omitempty"`
+	LastRun       *Run       `json:"last_run,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	Backups       []Backup   `json:"backups"` // Newest first
+	ListError     string     `json:"list_error,omitempty"`
+}
+
+// Scheduler backs up the database on a schedule and applies retention.
+type Scheduler struct {
+	Store       db.BookStore
+	Schedule    *Schedule
+	Destination Destination
+	Location    string // Of the destination, as shown in the status
+	Keep        int    // Newest backups kept at the destination; 0 keeps any number
+	KeepDays    int    // Days backups are kept at the destination; 0 keeps them for good
+
+	now func() time.Time // Overridable for tests
+
+	mu      sync.Mutex
+	running bool
+	next    *time.Time
+	last    *Run
+}
+
+// NewScheduler creates a Scheduler.
+func NewScheduler(store db.BookStore, schedule *Schedule, dest Destination, location string, keep, keepDays int) (*Scheduler, error) {
+	if keep < 0 || keepDays < 0 {
+		return nil, fmt.Errorf("backup retention must not be negative")
+	}
+	return &Scheduler{Store: store, Schedule: schedule, Destination: dest, Location: location, Keep: keep, KeepDays: keepDays}, nil
+}
+
+func (s *Scheduler) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// lastRun returns the time of the last successful backup.
+func (s *Scheduler) lastRun(ctx context.Context) (time.Time, bool) {
+	value, ok, err := s.Store.GetSetting(ctx, lastRunSetting)
+	if err != nil || !ok {
+		return time.Time{}, false
+	}
+	last, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return last, true
+}
+
+// Status returns the schedule, the latest run and the backups at the
+// destination.
+func (s *Scheduler) Status(ctx context.Context) Status {
+	s.mu.Lock()
+	status := Status{
+		Schedule:    s.Schedule.String(),
+		Destination: s.Location,
+		Keep:        s.Keep,
+		KeepDays:    s.KeepDays,
+		Running:     s.running,
+		NextRunAt:   s.next,
+		Backups:     []Backup{},
+	}
+	if s.last != nil {
+		last := *s.last
+		status.LastRun = &last
+	}
+	s.mu.Unlock()
+
+	if last, ok := s.lastRun(ctx); ok {
+		status.LastSuccessAt = &last
+	}
+	backups, err := s.list(ctx)
+	if err != nil {
+		status.ListError = err.Error()
+	} else {
+		status.Backups = backups
+	}
+	return status
+}
+
+// list returns the backups at the destination, newest first.
+func (s *Scheduler) list(ctx context.Context) ([]Backup, error) {
+	names, err := s.Destination.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	backups := []Backup{}
+	for _, name := range names {
+		if t, ok := takenAt(name); ok {
+			backups = append(backups, Backup{Name: name, TakenAt: t})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].TakenAt.After(backups[j].TakenAt) })
+	return backups, nil
+}
+
+// RunOnce backs up the database to the destination, applies retention and
+// records the run.
+func (s *Scheduler) RunOnce(ctx context.Context) (Run, error) {
+	now := s.clock().UTC()
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return Run{}, ErrRunning
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	run := Run{StartedAt: now}
+	err := s.backup(ctx, &run)
+	finished := s.clock().UTC()
+	run.FinishedAt = &finished
+	if err != nil {
+		run.Error = err.Error()
+	}
+	s.mu.Lock()
+	s.running, s.last = false, &run
+	s.mu.Unlock()
+	return run, err
+}
+
+func (s *Scheduler) backup(ctx context.Context, run *Run) error {
+	dir, err := os.MkdirTemp("", "bookshelf-backup-*")
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "backup.db")
+	if err := s.Store.Backup(ctx, path); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	name := Filename(run.StartedAt)
+	if err := s.Destination.Put(ctx, name, ContentType, data); err != nil {
+		return err
+	}
+	run.Name, run.Bytes = name, len(data)
+	slog.Info("Backup written", "name", name, "destination", s.Location, "bytes", len(data))
+
+	if err := s.Store.SetSetting(ctx, lastRunSetting, run.StartedAt.Format(time.RFC3339Nano)); err != nil {
+		slog.Warn("Failed to record backup time", "error", err)
+	}
+	if run.Pruned, err = s.prune(ctx, run.StartedAt); err != nil {
+		// The new backup is safe; old ones will be pruned next time
+		slog.Warn("Failed to apply backup retention", "error", err)
+	} else if run.Pruned > 0 {
+		slog.Info("Pruned old backups", "deleted", run.Pruned, "keep", s.Keep, "keepDays", s.KeepDays)
+	}
+	return nil
+}
+
+// prune deletes the backups beyond the newest Keep and those older than
+// KeepDays at now. The newest backup is always kept.
+func (s *Scheduler) prune(ctx context.Context, now time.Time) (int, error) {
+	if s.Keep == 0 && s.KeepDays == 0 {
+		return 0, nil
+	}
+	backups, err := s.list(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := now.AddDate(0, 0, -s.KeepDays)
+	deleted := 0
+	for i, b := range backups {
+		if i == 0 || (s.Keep == 0 || i < s.Keep) && (s.KeepDays == 0 || !b.TakenAt.Before(cutoff)) {
+			continue
+		}
+		if err := s.Destination.Delete(ctx, b.Name); err != nil {
+			return deleted, fmt.Errorf("failed to delete old backup %s: %w", b.Name, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// firstWait returns how long to wait before the first backup. One missed
+// according to the last recorded run, or never run, is due immediately.
+func (s *Scheduler) firstWait(ctx context.Context) time.Duration {
+	now := s.clock()
+	last, ok := s.lastRun(ctx)
+	if !ok || !s.Schedule.Next(last.In(now.Location())).After(now) {
+		return 0
+	}
+	return s.Schedule.Next(now).Sub(now)
+}
+
+// Run backs up on schedule until ctx is cancelled. Failed backups are
+// retried after an hour unless the next scheduled one is sooner.
+func (s *Scheduler) Run(ctx context.Context) {
+	slog.Info("Starting scheduled backups", "schedule", s.Schedule, "destination", s.Location, "keep", s.Keep, "keepDays", s.KeepDays)
+
+	wait := s.firstWait(ctx)
+	for {
+		next := s.clock().Add(wait).UTC()
+		s.mu.Lock()
+		s.next = &next
+		s.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			slog.Info("Stopping scheduled backups")
+			return
+		case <-timer.C:
+		}
+		_, err := s.RunOnce(ctx)
+		if err != nil {
+			slog.Error("Scheduled backup failed", "error", err)
+		}
+		now := s.clock()
+		wait = s.Schedule.Next(now).Sub(now)
+		if err != nil && wait > time.Hour {
+			wait = time.Hour
+		}
+	}
+}
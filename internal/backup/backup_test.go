@@ -0,0 +1,119 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/export"
+)
+
+func newTestScheduler(t *testing.T, keep, keepDays int) (*Scheduler, string) {
+	t.Helper()
+	database, err := db.InitDB(filepath.Join(t.TempDir(), "books.db"))
+	if err != nil {
+		t.Fatalf("InitDB failed: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	schedule, err := ParseSchedule("@daily")
+	if err != nil {
+		t.Fatalf("ParseSchedule failed: %v", err)
+	}
+	dir := t.TempDir()
+	s, err := NewScheduler(db.NewSQLiteBookStore(database), schedule, &export.LocalDir{Dir: dir}, dir, keep, keepDays)
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+	return s, dir
+}
+
+func TestRunOnceRetention(t *testing.T) {
+	ctx := context.Background()
+	s, dir := newTestScheduler(t, 3, 10)
+	// Files of other sorts are left alone
+	for _, name := range []string{"bookshelf-20200101T000000Z.json", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+
+	now := time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	for i := 0; i < 5; i++ {
+		run, err := s.RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if run.Name != Filename(now) || run.Bytes == 0 {
+			t.Errorf("Unexpected run: %+v", run)
+		}
+		now = now.Add(24 * time.Hour)
+	}
+	status := s.Status(ctx)
+	if len(status.Backups) != 3 || status.Backups[0].Name != "bookshelf-20250105T030000Z.db" || status.Backups[2].Name != "bookshelf-20250103T030000Z.db" {
+		t.Errorf("Expected the newest 3 backups, got %+v", status.Backups)
+	}
+	if status.LastRun == nil || status.LastRun.Pruned != 1 || status.LastSuccessAt == nil || !status.LastSuccessAt.Equal(now.Add(-24*time.Hour)) {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+	for _, name := range []string{"bookshelf-20200101T000000Z.json", "notes.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s to be kept: %v", name, err)
+		}
+	}
+
+	// Ten days on, only the new backup is young enough
+	now = now.Add(10 * 24 * time.Hour)
+	if run, err := s.RunOnce(ctx); err != nil || run.Pruned != 3 {
+		t.Errorf("RunOnce = %+v, %v; want 3 pruned", run, err)
+	}
+	if status := s.Status(ctx); len(status.Backups) != 1 {
+		t.Errorf("Expected backups older than 10 days pruned, got %+v", status.Backups)
+	}
+
+	// The backup is a database holding the library
+	restored := s.Status(ctx).Backups[0].Name
+	if err := s.Store.Restore(ctx, filepath.Join(dir, restored)); err != nil {
+		t.Errorf("Restore of %s failed: %v", restored, err)
+	}
+}
+
+func TestFirstWait(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestScheduler(t, 0, 0)
+	now := time.Date(2025, 1, 1, 18, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	if wait := s.firstWait(ctx); wait != 0 {
+		t.Errorf("Never backed up: got a wait of %v, want 0", wait)
+	}
+	if err := s.Store.SetSetting(ctx, lastRunSetting, now.Add(-time.Hour).Format(time.RFC3339Nano)); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	if wait := s.firstWait(ctx); wait != 6*time.Hour {
+		t.Errorf("Backed up today: got a wait of %v, want 6h until midnight", wait)
+	}
+	if err := s.Store.SetSetting(ctx, lastRunSetting, now.Add(-30*time.Hour).Format(time.RFC3339Nano)); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	if wait := s.firstWait(ctx); wait != 0 {
+		t.Errorf("Missed last night's backup: got a wait of %v, want 0", wait)
+	}
+}
+
+func TestParseDestination(t *testing.T) {
+	if dest, err := ParseDestination("file:///var/backups", nil); err != nil || dest.(*export.LocalDir).Dir != "/var/backups" {
+		t.Errorf("ParseDestination of a directory = %v, %v", dest, err)
+	}
+	for _, location := range []string{"", "https://example.com/backups"} {
+		if _, err := ParseDestination(location, nil); err == nil {
+			t.Errorf("ParseDestination(%q): expected an error", location)
+		}
+	}
+	if _, err := NewScheduler(nil, nil, nil, "", -1, 0); err == nil {
+		t.Error("Expected negative retention to be rejected")
+	}
+}
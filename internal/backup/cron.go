@@ -0,0 +1,145 @@
+package backup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is when scheduled backups run, parsed from a cron expression.
+type Schedule struct {
+	expr                                   string
+	minutes, hours, days, months, weekdays uint64 // Bit i set for each value i that matches
+	// anyDay and anyWeekday are set for a day of the month or week starting
+	// with "*". When neither is, as in cron, a day matching either runs.
+	anyDay, anyWeekday bool
+}
+
+// macros are the shorthands ParseSchedule accepts for common schedules.
+var macros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// field is one of the five fields of a cron expression.
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = [5]field{{"minute", 0, 59}, {"hour", 0, 23}, {"day of month", 1, 31}, {"month", 1, 12}, {"day of week", 0, 7}}
+
+// ParseSchedule parses a cron expression of five fields: minute, hour, day
+// of the month, month and day of the week (0 or 7 for Sunday), such as
+// "30 3 * * *" for 03:30 every day. Each field is "*", a number, a range such
+// as "1-5", any of them with a step such as "*/15", or a comma-separated
+// list of them. The shorthands @hourly, @daily, @weekly and @monthly work
+// too. Times are in the server's time zone.
+func ParseSchedule(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	spec := expr
+	if macro, ok := macros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("invalid schedule '%s': expected 5 fields (minute hour day month weekday), got %d", expr, len(parts))
+	}
+	var sets [5]uint64
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule '%s': %v", expr, err)
+		}
+		sets[i] = set
+	}
+	s := &Schedule{
+		expr:    expr,
+		minutes: sets[0], hours: sets[1], days: sets[2], months: sets[3],
+		weekdays: sets[4] | sets[4]>>7, // 7 is Sunday as well as 0
+		anyDay:   strings.HasPrefix(parts[2], "*"), anyWeekday: strings.HasPrefix(parts[4], "*"),
+	}
+	if s.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return nil, fmt.Errorf("invalid schedule '%s': it never runs", expr)
+	}
+	return s, nil
+}
+
+// parseField returns the values of f that part matches, as bits.
+func parseField(part string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(part, ",") {
+		rng, stepText, stepped := strings.Cut(item, "/")
+		step := 1
+		if stepped {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step '%s' of the %s", stepText, f.name)
+			}
+		}
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			first, last, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("invalid %s '%s'", f.name, item)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("invalid %s '%s'", f.name, item)
+				}
+			} else if stepped {
+				hi = f.max // "5/15" is 5, 20, 35 and 50
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("%s '%s' is out of range %d-%d", f.name, item, f.min, f.max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// String returns the expression the schedule was parsed from.
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// Next returns the first time after t the schedule runs, in t's time zone,
+// or the zero time if it doesn't within five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.months&(1<<t.Month()) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hours&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minutes&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchesDay reports whether the schedule runs on t's day.
+func (s *Schedule) matchesDay(t time.Time) bool {
+	day := s.days&(1<<t.Day()) != 0
+	weekday := s.weekdays&(1<<t.Weekday()) != 0
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
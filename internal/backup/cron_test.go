@@ -0,0 +1,55 @@
+package backup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2025, 1, 1, 10, 17, 30, 0, time.UTC)
+	for expr, want := range map[string]string{
+		"30 3 * * *":         "2025-01-02T03:30",
+		"*/15 * * * *":       "2025-01-01T10:30",
+		"5/20 10 * * *":      "2025-01-01T10:25",
+		"0 9-17 * * 1-5":     "2025-01-01T11:00",
+		"0 0 * * 0":          "2025-01-05T00:00",
+		"0 0 * * 7":          "2025-01-05T00:00",
+		"0 12 1,15 * *":      "2025-01-01T12:00",
+		"0 0 13 * 5":         "2025-01-03T00:00", // The 13th or a Friday
+		"0 0 */10 * 1":       "2025-03-31T00:00", // A Monday the 1st, 11th, 21st or 31st
+		"0 4 29 2 *":         "2028-02-29T04:00",
+		"@daily":             "2025-01-02T00:00",
+		"@hourly":            "2025-01-01T11:00",
+		"@monthly":           "2025-02-01T00:00",
+		"  17,45 10 * 1 3  ": "2025-01-01T10:45",
+		"0 0 1 jan-mar *":    "",
+		"60 * * * *":         "",
+		"0 0 31 2 *":         "",
+		"* * * *":            "",
+		"*/0 * * * *":        "",
+		"5-1 * * * *":        "",
+		"0 0 * * 8":          "",
+		"0,,5 * * * *":       "",
+		"@yearly-ish":        "",
+		"0 0 1 1 * extra":    "",
+		"":                   "",
+		"0 0 1 1 1":          "2025-01-06T00:00",
+		"0 0 1-7 * */7":      "2025-01-05T00:00", // Both days starting with "*" narrow it to Sundays
+	} {
+		s, err := ParseSchedule(expr)
+		if want == "" {
+			if err == nil {
+				t.Errorf("ParseSchedule(%q): expected an error", expr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) failed: %v", expr, err)
+			continue
+		}
+		if got := s.Next(from).Format("2006-01-02T15:04"); got != want {
+			t.Errorf("%q: next run after %v is %s, want %s", expr, from, got, want)
+		}
+	}
+}
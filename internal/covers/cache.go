@@ -0,0 +1,223 @@
+package covers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/ratelimit"
+	"github.com/ericdahl/bookshelf/internal/safehttp"
+)
+
+// maxCoverSize bounds how much of a remote cover is downloaded.
+const maxCoverSize = 5 * 1024 * 1024 // 5 MB
+
+var validHash = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Cache keeps local copies of book covers. Files are content-addressed by
+// SHA-256, so identical images across editions and copies are stored once
+// and each book only holds a reference to its image.
+type Cache struct {
+	Store      db.BookStore
+	Dir        string
+	HTTPClient *http.Client
+	Pipeline   *Pipeline // Processes covers before they are stored; nil stores them as downloaded
+}
+
+// NewCache creates a cache storing images under dir. Cover URLs can be set by
+// users, so its client can't reach the server's own networks.
+func NewCache(store db.BookStore, dir string) *Cache {
+	return &Cache{
+		Store:      store,
+		Dir:        dir,
+		HTTPClient: safehttp.NewClient(30 * time.Second),
+	}
+}
+
+// CacheReport summarizes a cache run.
+type CacheReport struct {
+	Cached       int                   `json:"cached"`       // Books whose cover was stored as a new image
+	Deduplicated int                   `json:"deduplicated"` // Books whose cover matched an image already stored
+	Failed       []Unresolved          `json:"failed"`
+	Pruned       int                   `json:"pruned"` // Images removed because no book used them any more
+	Stats        model.CoverCacheStats `json:"stats"`
+}
+
+// path returns where the image with hash is stored. Images are spread over
+// subdirectories by hash prefix to keep directories small.
+func (c *Cache) path(hash string) string {
+	return filepath.Join(c.Dir, hash[:2], hash)
+}
+
+// Open returns the cached image with hash. The caller must close the file.
+func (c *Cache) Open(ctx context.Context, hash string) (*os.File, *model.CoverImage, error) {
+	if !validHash.MatchString(hash) {
+		return nil, nil, fmt.Errorf("cover image %s %w", hash, db.ErrNotFound)
+	}
+	image, err := c.Store.GetCoverImage(ctx, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(c.path(hash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("cover image %s %w", hash, db.ErrNotFound)
+		}
+		return nil, nil, err
+	}
+	return f, image, nil
+}
+
+// CacheBook downloads the cover of book and points the book at it. Returns
+// true when the image was already cached for another book.
+func (c *Cache) CacheBook(ctx context.Context, book model.Book) (bool, error) {
+	if book.CoverURL == nil || *book.CoverURL == "" {
+		return false, fmt.Errorf("book has no cover")
+	}
+	data, contentType, err := c.download(ctx, *book.CoverURL)
+	if err != nil {
+		return false, err
+	}
+
+	image := &model.CoverImage{ContentType: contentType}
+	if c.Pipeline != nil {
+		processed, err := c.Pipeline.Process(data, contentType)
+		if err != nil {
+			return false, err
+		}
+		data, image.ContentType = processed.Data, processed.ContentType
+		image.Width, image.Height = processed.Width, processed.Height
+		image.Blurhash, image.LQIP = processed.Blurhash, processed.LQIP
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	image.Hash, image.Size = hash, int64(len(data))
+	if _, err := os.Stat(c.path(hash)); os.IsNotExist(err) {
+		if err := c.write(hash, data); err != nil {
+			return false, err
+		}
+	}
+	isNew, err := c.Store.SaveCoverImage(ctx, image)
+	if err != nil {
+		return false, err
+	}
+	if err := c.Store.SetBookCoverHash(ctx, book.ID, &hash); err != nil {
+		return false, err
+	}
+	return !isNew, nil
+}
+
+func (c *Cache) download(ctx context.Context, coverURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, coverURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid cover URL: %w", err)
+	}
+	req.Header.Set("User-Agent", "BookshelfApp/1.0 (github.com/ericdahl/bookshelf)")
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download cover: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("cover returned status %d", resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", fmt.Errorf("cover is not an image (%s)", contentType)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCoverSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read cover: %w", err)
+	}
+	if len(data) > maxCoverSize {
+		return nil, "", fmt.Errorf("cover exceeds %d bytes", maxCoverSize)
+	}
+	return data, contentType, nil
+}
+
+// write stores data atomically so readers never see a partial image.
+func (c *Cache) write(hash string, data []byte) error {
+	dir := filepath.Dir(c.path(hash))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cover cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".cover-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cover file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cover file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cover file: %w", err)
+	}
+	return os.Rename(tmp.Name(), c.path(hash))
+}
+
+// CacheAll caches the cover of every book that has none cached yet, then
+// removes images no book references any more.
+func (c *Cache) CacheAll(ctx context.Context) (CacheReport, error) {
+	ctx = ratelimit.WithPriority(ctx, ratelimit.Background)
+	report := CacheReport{Failed: []Unresolved{}}
+	books, err := c.Store.GetBooks(ctx)
+	if err != nil {
+		return report, fmt.Errorf("failed to load books: %w", err)
+	}
+	for _, book := range books {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		if book.CoverHash != nil || book.CoverURL == nil || *book.CoverURL == "" {
+			continue
+		}
+		dup, err := c.CacheBook(ctx, book)
+		switch {
+		case err != nil:
+			report.Failed = append(report.Failed, Unresolved{BookID: book.ID, Title: book.Title, Reason: err.Error()})
+		case dup:
+			report.Deduplicated++
+		default:
+			report.Cached++
+		}
+	}
+
+	pruned, err := c.Prune(ctx)
+	if err != nil {
+		return report, err
+	}
+	report.Pruned = pruned
+	if report.Stats, err = c.Store.GetCoverCacheStats(ctx); err != nil {
+		return report, err
+	}
+	slog.Info("Cover cache updated", "cached", report.Cached, "deduplicated", report.Deduplicated,
+		"failed", len(report.Failed), "pruned", report.Pruned, "images", report.Stats.Images, "bytes", report.Stats.Bytes)
+	return report, nil
+}
+
+// Prune deletes images that no book references. Returns the number removed.
+func (c *Cache) Prune(ctx context.Context) (int, error) {
+	hashes, err := c.Store.DeleteUnreferencedCoverImages(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, hash := range hashes {
+		if err := os.Remove(c.path(hash)); err != nil && !os.IsNotExist(err) {
+			slog.Warn("Failed to remove cached cover", "hash", hash, "error", err)
+		}
+	}
+	return len(hashes), nil
+}
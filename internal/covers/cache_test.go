@@ -0,0 +1,182 @@
+package covers
+
+import (
+	"context"
+	"database/sql"
+	"image/color"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestCacheDeduplicates(t *testing.T) {
+	ctx := context.Background()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+	if err := db.CreateSchema(database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	store := db.NewSQLiteBookStore(database)
+
+	images := map[string]string{
+		"/hardcover.jpg": "same cover",
+		"/paperback.jpg": "same cover",
+		"/other.jpg":     "another cover",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := images[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		io.WriteString(w, body)
+	}))
+	defer server.Close()
+
+	var books []*model.Book
+	for i, path := range []string{"/hardcover.jpg", "/paperback.jpg", "/missing.jpg"} {
+		cover := server.URL + path
+		book := &model.Book{Title: "Copy", Author: "A", OpenLibraryID: "OLCOPY" + string(rune('A'+i)) + "W", Status: model.StatusRead, CoverURL: &cover}
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("Failed to add book: %v", err)
+		}
+		books = append(books, book)
+	}
+
+	cache := NewCache(store, t.TempDir())
+	cache.HTTPClient = server.Client()
+	report, err := cache.CacheAll(ctx)
+	if err != nil {
+		t.Fatalf("CacheAll failed: %v", err)
+	}
+	if report.Cached != 1 || report.Deduplicated != 1 || len(report.Failed) != 1 || report.Stats.Images != 1 || report.Stats.References != 2 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	first, _ := store.GetBookByID(ctx, books[0].ID)
+	second, _ := store.GetBookByID(ctx, books[1].ID)
+	if first.CoverHash == nil || second.CoverHash == nil || *first.CoverHash != *second.CoverHash {
+		t.Fatalf("Expected both copies to share one image, got %v and %v", first.CoverHash, second.CoverHash)
+	}
+	hash := *first.CoverHash
+	f, image, err := cache.Open(ctx, hash)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	data, _ := io.ReadAll(f)
+	f.Close()
+	if string(data) != "same cover" || image.ContentType != "image/jpeg" {
+		t.Errorf("Unexpected cached image %q (%s)", data, image.ContentType)
+	}
+
+	// Changing a cover detaches the old image; it stays while the other copy uses it
+	other := server.URL + "/other.jpg"
+	store.UpdateBookCover(ctx, books[0].ID, &other)
+	if report, _ = cache.CacheAll(ctx); report.Cached != 1 || report.Pruned != 0 || report.Stats.Images != 2 {
+		t.Errorf("Unexpected report after cover change: %+v", report)
+	}
+
+	// Books in the trash keep their images; once no book uses one, it is pruned from disk
+	store.DeleteBook(ctx, books[1].ID)
+	if report, _ = cache.CacheAll(ctx); report.Pruned != 0 {
+		t.Errorf("Expected a trashed book to keep its image, got %+v", report)
+	}
+	store.PurgeBook(ctx, books[1].ID)
+	if report, _ = cache.CacheAll(ctx); report.Pruned != 1 || report.Stats.Images != 1 {
+		t.Errorf("Unexpected report after delete: %+v", report)
+	}
+	if _, err := os.Stat(cache.path(hash)); !os.IsNotExist(err) {
+		t.Errorf("Expected pruned image file to be removed, got %v", err)
+	}
+	if _, _, err := cache.Open(ctx, "../../etc/passwd"); err == nil {
+		t.Error("Expected invalid hashes to be rejected")
+	}
+}
+
+func TestCachedPlaceholdersInBookList(t *testing.T) {
+	ctx := context.Background()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+	if err := db.CreateSchema(database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	store := db.NewSQLiteBookStore(database)
+
+	cover := solidPNG(t, 40, 60, color.RGBA{200, 30, 30, 255})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(cover)
+	}))
+	defer server.Close()
+
+	coverURL := server.URL + "/cover.png"
+	book := &model.Book{Title: "Red", Author: "A", OpenLibraryID: "OLRED1W", Status: model.StatusRead, CoverURL: &coverURL}
+	if _, err := store.AddBook(ctx, book); err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+
+	cache := NewCache(store, t.TempDir())
+	cache.HTTPClient = server.Client()
+	cache.Pipeline = &Pipeline{MaxWidth: 400, MaxHeight: 600, Quality: 80, Blurhash: true, LQIP: true}
+	if _, err := cache.CacheAll(ctx); err != nil {
+		t.Fatalf("CacheAll failed: %v", err)
+	}
+
+	books, err := store.GetBooks(ctx)
+	if err != nil || len(books) != 1 {
+		t.Fatalf("GetBooks returned %v, %v", books, err)
+	}
+	if books[0].CoverBlurhash == "" || !strings.HasPrefix(books[0].CoverLQIP, "data:image/jpeg;base64,") {
+		t.Errorf("Expected placeholders on the listed book, got %q and %q", books[0].CoverBlurhash, books[0].CoverLQIP)
+	}
+
+	// Changing the cover drops the placeholders until it is cached again
+	store.UpdateBookCover(ctx, book.ID, &coverURL)
+	updated, _ := store.GetBookByID(ctx, book.ID)
+	if updated.CoverBlurhash != "" || updated.CoverLQIP != "" {
+		t.Errorf("Expected no placeholders for an uncached cover, got %q and %q", updated.CoverBlurhash, updated.CoverLQIP)
+	}
+}
+
+func TestCacheRefusesOwnNetworks(t *testing.T) {
+	ctx := context.Background()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+	if err := db.CreateSchema(database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	store := db.NewSQLiteBookStore(database)
+
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.Write([]byte("internal"))
+	}))
+	defer server.Close()
+
+	coverURL := server.URL + "/cover.jpg"
+	book := &model.Book{Title: "Internal", Author: "A", OpenLibraryID: "OLINT1W", Status: model.StatusRead, CoverURL: &coverURL}
+	if _, err := store.AddBook(ctx, book); err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+	if _, err := NewCache(store, t.TempDir()).CacheBook(ctx, *book); err == nil || requested {
+		t.Errorf("Expected a cover on loopback to be refused, got %v", err)
+	}
+}
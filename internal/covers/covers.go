@@ -0,0 +1,313 @@
+// Package covers finds books whose cover images are missing or broken and
+// repairs them from Open Library and Google Books.
+package covers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/ratelimit"
+)
+
+// ErrRunning is returned when a repair is started while another is in progress.
+var ErrRunning = fmt.Errorf("cover repair is already running")
+
+// Unresolved is a book whose cover could not be repaired.
+type Unresolved struct {
+	BookID int64  `json:"book_id"`
+	Title  string `json:"title"`
+	Reason string `json:"reason"`
+}
+
+// Report summarizes a repair run.
+type Report struct {
+	Running    bool         `json:"running"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	Checked    int          `json:"checked"`
+	Broken     int          `json:"broken"` // Books with a missing or unreachable cover
+	Repaired   int          `json:"repaired"`
+	Unresolved []Unresolved `json:"unresolved"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// Repairer checks every book's cover and looks for a working replacement.
+// Only one repair runs at a time; the report of the latest run is kept.
+type Repairer struct {
+	Store      db.BookStore
+	HTTPClient *http.Client
+	Workers    int // Number of books checked in parallel
+
+	// Provider endpoints, overridable for tests.
+	OpenLibraryURL string
+	CoversURL      string
+	GoogleBooksURL string
+
+	mu     sync.Mutex
+	report Report
+}
+
+// NewRepairer creates a Repairer using the public provider endpoints.
+func NewRepairer(store db.BookStore) *Repairer {
+	return &Repairer{
+		Store:          store,
+		HTTPClient:     &http.Client{Timeout: 15 * time.Second},
+		Workers:        4,
+		OpenLibraryURL: "https://openlibrary.org",
+		CoversURL:      "https://covers.openlibrary.org",
+		GoogleBooksURL: "https://www.googleapis.com/books/v1",
+		report:         Report{Unresolved: []Unresolved{}},
+	}
+}
+
+// Report returns the progress or outcome of the latest run.
+func (r *Repairer) Report() Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := r.report
+	report.Unresolved = append([]Unresolved{}, r.report.Unresolved...)
+	return report
+}
+
+// Start begins a repair in the background. It returns ErrRunning if a repair
+// is already in progress.
+func (r *Repairer) Start(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.report.Running {
+		return ErrRunning
+	}
+	now := time.Now().UTC()
+	r.report = Report{Running: true, StartedAt: &now, Unresolved: []Unresolved{}}
+	go r.run(ctx)
+	return nil
+}
+
+// Run repairs covers and waits for the result.
+func (r *Repairer) Run(ctx context.Context) (Report, error) {
+	if err := r.Start(ctx); err != nil {
+		return Report{}, err
+	}
+	for {
+		report := r.Report()
+		if !report.Running {
+			return report, nil
+		}
+		select {
+		case <-ctx.Done():
+			return report, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func (r *Repairer) run(ctx context.Context) {
+	ctx = ratelimit.WithPriority(ctx, ratelimit.Background)
+	books, err := r.Store.GetBooks(ctx)
+	if err != nil {
+		r.finish(fmt.Errorf("failed to load books: %w", err))
+		return
+	}
+	slog.Info("Starting cover repair", "books", len(books))
+
+	jobs := make(chan model.Book)
+	var wg sync.WaitGroup
+	workers := r.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for book := range jobs {
+				r.repair(ctx, book)
+			}
+		}()
+	}
+	for _, book := range books {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- book
+	}
+	close(jobs)
+	wg.Wait()
+	r.finish(ctx.Err())
+}
+
+func (r *Repairer) finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now().UTC()
+	r.report.Running = false
+	r.report.FinishedAt = &now
+	if err != nil {
+		r.report.Error = err.Error()
+	}
+	slog.Info("Cover repair finished", "checked", r.report.Checked, "broken", r.report.Broken,
+		"repaired", r.report.Repaired, "unresolved", len(r.report.Unresolved), "error", err)
+}
+
+// repair checks one book and replaces its cover if needed.
+func (r *Repairer) repair(ctx context.Context, book model.Book) {
+	broken := book.CoverURL == nil || *book.CoverURL == "" || !r.isImage(ctx, *book.CoverURL)
+	if !broken {
+		r.update(func(rep *Report) { rep.Checked++ })
+		return
+	}
+
+	var reason string
+	for _, candidate := range r.candidates(ctx, book) {
+		if candidate == "" || (book.CoverURL != nil && candidate == *book.CoverURL) {
+			continue
+		}
+		if !r.isImage(ctx, candidate) {
+			continue
+		}
+		if err := r.Store.UpdateBookCover(ctx, book.ID, &candidate); err != nil {
+			reason = "failed to save cover: " + err.Error()
+			break
+		}
+		slog.Info("Repaired book cover", "id", book.ID, "title", book.Title, "cover", candidate)
+		r.update(func(rep *Report) { rep.Checked++; rep.Broken++; rep.Repaired++ })
+		return
+	}
+
+	if reason == "" {
+		reason = "no cover found"
+		if book.CoverURL != nil && *book.CoverURL != "" {
+			reason = "cover unreachable and no replacement found"
+		}
+	}
+	r.update(func(rep *Report) {
+		rep.Checked++
+		rep.Broken++
+		rep.Unresolved = append(rep.Unresolved, Unresolved{BookID: book.ID, Title: book.Title, Reason: reason})
+	})
+}
+
+func (r *Repairer) update(fn func(*Report)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(&r.report)
+}
+
+// isImage reports whether url serves an image. Servers that do not support
+// HEAD are retried with a one-byte ranged GET.
+func (r *Repairer) isImage(ctx context.Context, target string) bool {
+	status, contentType, err := r.probe(ctx, http.MethodHead, target)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, contentType, err = r.probe(ctx, http.MethodGet, target)
+	}
+	if err != nil {
+		slog.Debug("Cover check failed", "url", target, "error", err)
+		return false
+	}
+	return (status == http.StatusOK || status == http.StatusPartialContent) && strings.HasPrefix(contentType, "image/")
+}
+
+func (r *Repairer) probe(ctx context.Context, method, target string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("User-Agent", "BookshelfApp/1.0 (github.com/ericdahl/bookshelf)")
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	resp, err := r.HTTPClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1024))
+	resp.Body.Close()
+	return resp.StatusCode, resp.Header.Get("Content-Type"), nil
+}
+
+// candidates returns replacement cover URLs for book, best first.
+func (r *Repairer) candidates(ctx context.Context, book model.Book) []string {
+	var urls []string
+	if id := r.workCoverID(ctx, book.OpenLibraryID); id > 0 {
+		urls = append(urls, fmt.Sprintf("%s/b/id/%d-M.jpg", r.CoversURL, id))
+	} else if strings.HasSuffix(book.OpenLibraryID, "M") {
+		urls = append(urls, fmt.Sprintf("%s/b/olid/%s-M.jpg?default=false", r.CoversURL, url.PathEscape(book.OpenLibraryID)))
+	}
+	if book.ISBN != "" {
+		urls = append(urls, fmt.Sprintf("%s/b/isbn/%s-M.jpg?default=false", r.CoversURL, url.PathEscape(book.ISBN)))
+		urls = append(urls, r.googleBooksCover(ctx, book.ISBN))
+	}
+	return urls
+}
+
+// workCoverID returns the first cover of an Open Library work, or 0.
+func (r *Repairer) workCoverID(ctx context.Context, olid string) int {
+	if !strings.HasSuffix(olid, "W") {
+		return 0
+	}
+	var work struct {
+		Covers []int `json:"covers"`
+	}
+	if err := r.getJSON(ctx, fmt.Sprintf("%s/works/%s.json", r.OpenLibraryURL, url.PathEscape(olid)), &work); err != nil {
+		slog.Debug("Open Library work lookup failed", "olid", olid, "error", err)
+		return 0
+	}
+	for _, id := range work.Covers {
+		if id > 0 { // Open Library uses -1 for removed covers
+			return id
+		}
+	}
+	return 0
+}
+
+// googleBooksCover returns the thumbnail of the first Google Books volume with isbn.
+func (r *Repairer) googleBooksCover(ctx context.Context, isbn string) string {
+	var result struct {
+		Items []struct {
+			VolumeInfo struct {
+				ImageLinks struct {
+					Thumbnail string `json:"thumbnail"`
+				} `json:"imageLinks"`
+			} `json:"volumeInfo"`
+		} `json:"items"`
+	}
+	if err := r.getJSON(ctx, r.GoogleBooksURL+"/volumes?q=isbn:"+url.QueryEscape(isbn), &result); err != nil {
+		slog.Debug("Google Books lookup failed", "isbn", isbn, "error", err)
+		return ""
+	}
+	for _, item := range result.Items {
+		if thumb := item.VolumeInfo.ImageLinks.Thumbnail; thumb != "" {
+			// Google returns http links that redirect; ask for https directly
+			return strings.Replace(thumb, "http://", "https://", 1)
+		}
+	}
+	return ""
+}
+
+func (r *Repairer) getJSON(ctx context.Context, target string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "BookshelfApp/1.0 (github.com/ericdahl/bookshelf)")
+	resp, err := r.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("returned status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
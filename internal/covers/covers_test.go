@@ -0,0 +1,139 @@
+package covers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newProviders(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	image := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte{0xff, 0xd8})
+	}
+	mux.HandleFunc("/b/id/123-M.jpg", image)
+	mux.HandleFunc("/google.jpg", image)
+	mux.HandleFunc("/no-head.jpg", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		image(w, r)
+	})
+	mux.HandleFunc("/placeholder.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+	})
+	mux.HandleFunc("/works/OLCOVERW.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"covers":[-1,123]}`)
+	})
+	server := httptest.NewTLSServer(mux)
+	mux.HandleFunc("/volumes", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") != "isbn:9780000000002" {
+			fmt.Fprint(w, `{"items":[]}`)
+			return
+		}
+		// Google returns http links; the repairer upgrades them to https
+		fmt.Fprintf(w, `{"items":[{"volumeInfo":{"imageLinks":{"thumbnail":"http://%s/google.jpg"}}}]}`, server.Listener.Addr())
+	})
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRepair(t *testing.T) {
+	ctx := context.Background()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+	if err := db.CreateSchema(database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	store := db.NewSQLiteBookStore(database)
+	server := newProviders(t)
+
+	cover := func(path string) *string {
+		u := server.URL + path
+		return &u
+	}
+	books := []*model.Book{
+		{Title: "Working", Author: "A", OpenLibraryID: "OLWORKINGW", CoverURL: cover("/no-head.jpg")},
+		{Title: "Broken", Author: "B", OpenLibraryID: "OLCOVERW", CoverURL: cover("/missing.jpg")},
+		{Title: "Google", Author: "C", OpenLibraryID: "OLGOOGLEW", ISBN: "9780000000002"},
+		{Title: "Placeholder", Author: "D", OpenLibraryID: "OLPLACEHOLDERW", CoverURL: cover("/placeholder.html")},
+		{Title: "Nothing", Author: "E", OpenLibraryID: "OLNOTHINGW"},
+	}
+	for _, b := range books {
+		b.Status = model.StatusRead
+		if _, err := store.AddBook(ctx, b); err != nil {
+			t.Fatalf("Failed to add book: %v", err)
+		}
+	}
+
+	repairer := NewRepairer(store)
+	repairer.HTTPClient = server.Client()
+	repairer.OpenLibraryURL = server.URL
+	repairer.CoversURL = server.URL
+	repairer.GoogleBooksURL = server.URL
+
+	report, err := repairer.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Running || report.FinishedAt == nil || report.Checked != 5 || report.Broken != 4 || report.Repaired != 2 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	reasons := make(map[string]string)
+	for _, u := range report.Unresolved {
+		reasons[u.Title] = u.Reason
+	}
+	if len(reasons) != 2 || reasons["Nothing"] != "no cover found" || reasons["Placeholder"] != "cover unreachable and no replacement found" {
+		t.Errorf("Unexpected unresolved books: %+v", report.Unresolved)
+	}
+
+	want := map[int64]string{
+		books[0].ID: server.URL + "/no-head.jpg",
+		books[1].ID: server.URL + "/b/id/123-M.jpg",
+		books[2].ID: server.URL + "/google.jpg",
+	}
+	for id, url := range want {
+		book, _ := store.GetBookByID(ctx, id)
+		if book.CoverURL == nil || *book.CoverURL != url {
+			t.Errorf("Book %d: expected cover %s, got %v", id, url, book.CoverURL)
+		}
+	}
+}
+
+func TestStartWhileRunning(t *testing.T) {
+	block := make(chan struct{})
+	repairer := NewRepairer(blockingStore{block: block})
+	if err := repairer.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if err := repairer.Start(context.Background()); err != ErrRunning {
+		t.Errorf("Expected ErrRunning, got %v", err)
+	}
+	if !repairer.Report().Running {
+		t.Error("Expected report to show the repair running")
+	}
+	close(block)
+}
+
+// blockingStore holds GetBooks until block is closed.
+type blockingStore struct {
+	db.BookStore
+	block chan struct{}
+}
+
+func (s blockingStore) GetBooks(ctx context.Context) ([]model.Book, error) {
+	<-s.block
+	return nil, nil
+}
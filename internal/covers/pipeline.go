@@ -0,0 +1,228 @@
+package covers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	_ "image/gif" // Decode GIF covers
+)
+
+// Encoder writes an image in one output format.
+type Encoder interface {
+	Encode(w io.Writer, img image.Image, quality int) error
+	ContentType() string
+}
+
+type jpegEncoder struct{}
+
+func (jpegEncoder) Encode(w io.Writer, img image.Image, quality int) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+}
+func (jpegEncoder) ContentType() string { return "image/jpeg" }
+
+type pngEncoder struct{}
+
+func (pngEncoder) Encode(w io.Writer, img image.Image, quality int) error { return png.Encode(w, img) }
+func (pngEncoder) ContentType() string                                    { return "image/png" }
+
+// CommandEncoder encodes through an external tool such as cwebp or avifenc,
+// for formats the standard library cannot write. Args receives the quality
+// and the input (PNG) and output file paths.
+type CommandEncoder struct {
+	Command  string
+	MimeType string
+	Args     func(quality int, in, out string) []string
+}
+
+// Encode runs the tool on a temporary PNG copy of img.
+func (e *CommandEncoder) Encode(w io.Writer, img image.Image, quality int) error {
+	dir, err := os.MkdirTemp("", "bookshelf-cover-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	in, out := filepath.Join(dir, "in.png"), filepath.Join(dir, "out")
+	f, err := os.Create(in)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if output, err := exec.Command(e.Command, e.Args(quality, in, out)...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", e.Command, err, bytes.TrimSpace(output))
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// ContentType returns the MIME type of the tool's output.
+func (e *CommandEncoder) ContentType() string { return e.MimeType }
+
+// Encoders lists the output formats a Pipeline can produce. WebP and AVIF
+// need cwebp and avifenc (from libwebp and libavif) on the PATH.
+var Encoders = map[string]Encoder{
+	"jpeg": jpegEncoder{},
+	"png":  pngEncoder{},
+	"webp": &CommandEncoder{Command: "cwebp", MimeType: "image/webp", Args: func(q int, in, out string) []string {
+		return []string{"-quiet", "-q", strconv.Itoa(q), in, "-o", out}
+	}},
+	"avif": &CommandEncoder{Command: "avifenc", MimeType: "image/avif", Args: func(q int, in, out string) []string {
+		return []string{"-q", strconv.Itoa(q), in, out}
+	}},
+}
+
+// Pipeline processes downloaded covers before they are cached: it shrinks
+// them to the maximum dimensions, re-encodes them and derives placeholders.
+type Pipeline struct {
+	MaxWidth  int    // 0 for no limit
+	MaxHeight int    // 0 for no limit
+	Format    string // Key of Encoders; empty keeps the source encoding unless resizing
+	Quality   int    // 1-100, for lossy formats
+	Blurhash  bool   // Generate a blurhash placeholder
+	LQIP      bool   // Generate a tiny inline JPEG placeholder
+}
+
+// Validate checks the pipeline settings, including that external encoders are installed.
+func (p *Pipeline) Validate() error {
+	if p.MaxWidth < 0 || p.MaxHeight < 0 {
+		return fmt.Errorf("cover dimensions must not be negative")
+	}
+	if p.Quality < 1 || p.Quality > 100 {
+		return fmt.Errorf("cover quality must be between 1 and 100, got %d", p.Quality)
+	}
+	if p.Format == "" {
+		return nil
+	}
+	enc, ok := Encoders[p.Format]
+	if !ok {
+		return fmt.Errorf("unsupported cover format %q", p.Format)
+	}
+	if cmd, ok := enc.(*CommandEncoder); ok {
+		if _, err := exec.LookPath(cmd.Command); err != nil {
+			return fmt.Errorf("cover format %s requires %s to be installed", p.Format, cmd.Command)
+		}
+	}
+	return nil
+}
+
+// Processed is a cover after the pipeline ran.
+type Processed struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+	Blurhash    string
+	LQIP        string // data: URI
+}
+
+// Process runs the pipeline over an image. Covers whose encoding cannot be
+// decoded (e.g., WebP sources) are passed through without placeholders.
+func (p *Pipeline) Process(data []byte, contentType string) (*Processed, error) {
+	out := &Processed{Data: data, ContentType: contentType}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return out, nil
+	}
+
+	bounds := img.Bounds()
+	out.Width, out.Height = bounds.Dx(), bounds.Dy()
+	w, h := fit(out.Width, out.Height, p.MaxWidth, p.MaxHeight)
+	resized := w != out.Width || h != out.Height
+	if resized {
+		img = resize(img, w, h)
+		out.Width, out.Height = w, h
+	}
+
+	format := p.Format
+	if format == "" && resized {
+		format = "png"
+		if contentType == "image/jpeg" {
+			format = "jpeg"
+		}
+	}
+	if format != "" {
+		enc := Encoders[format]
+		var buf bytes.Buffer
+		if err := enc.Encode(&buf, img, p.Quality); err != nil {
+			return nil, fmt.Errorf("failed to encode cover as %s: %w", format, err)
+		}
+		out.Data, out.ContentType = buf.Bytes(), enc.ContentType()
+	}
+
+	if p.Blurhash {
+		// Blurhashes carry no detail, so compute them from a small copy
+		bw, bh := fit(out.Width, out.Height, 32, 32)
+		out.Blurhash = Blurhash(resize(img, bw, bh), 4, 3)
+	}
+	if p.LQIP {
+		tw, th := fit(out.Width, out.Height, 16, 16)
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resize(img, tw, th), &jpeg.Options{Quality: 40}); err == nil {
+			out.LQIP = "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+		}
+	}
+	return out, nil
+}
+
+// fit scales w×h down to fit within maxW×maxH (0 meaning unbounded), keeping the aspect ratio.
+func fit(w, h, maxW, maxH int) (int, int) {
+	scale := 1.0
+	if maxW > 0 && w > maxW {
+		scale = float64(maxW) / float64(w)
+	}
+	if maxH > 0 && float64(h)*scale > float64(maxH) {
+		scale = float64(maxH) / float64(h)
+	}
+	if scale == 1 {
+		return w, h
+	}
+	return max(1, int(float64(w)*scale+0.5)), max(1, int(float64(h)*scale+0.5))
+}
+
+// resize scales img to w×h by averaging the source pixels covering each
+// destination pixel, which keeps downscaled covers free of aliasing.
+func resize(img image.Image, w, h int) *image.RGBA {
+	src := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					px := row[sx*4 : sx*4+4]
+					r, g, b, a = r+int(px[0]), g+int(px[1]), b+int(px[2]), a+int(px[3])
+					n++
+				}
+			}
+			i := y*dst.Stride + x*4
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return dst
+}
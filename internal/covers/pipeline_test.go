@@ -0,0 +1,90 @@
+package covers
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func solidPNG(t *testing.T, w, h int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestPipelineResizeAndEncode(t *testing.T) {
+	p := &Pipeline{MaxWidth: 100, MaxHeight: 100, Format: "jpeg", Quality: 70, Blurhash: true, LQIP: true}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	out, err := p.Process(solidPNG(t, 400, 600, color.RGBA{200, 30, 30, 255}), "image/png")
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if out.ContentType != "image/jpeg" || out.Width != 67 || out.Height != 100 {
+		t.Errorf("Unexpected output %s %dx%d", out.ContentType, out.Width, out.Height)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(out.Data))
+	if err != nil || cfg.Width != 67 || cfg.Height != 100 {
+		t.Errorf("Expected a 67x100 JPEG, got %+v (%v)", cfg, err)
+	}
+	if len(out.Blurhash) != 28 {
+		t.Errorf("Expected a 4x3 blurhash, got %q", out.Blurhash)
+	}
+	if !strings.HasPrefix(out.LQIP, "data:image/jpeg;base64,") {
+		t.Errorf("Expected an inline JPEG placeholder, got %q", out.LQIP)
+	}
+}
+
+func TestPipelineKeepsSmallOriginals(t *testing.T) {
+	src := solidPNG(t, 50, 80, color.White)
+	out, err := (&Pipeline{MaxWidth: 100, Quality: 80}).Process(src, "image/png")
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if !bytes.Equal(out.Data, src) || out.Width != 50 || out.Blurhash != "" {
+		t.Errorf("Expected the original to pass through untouched, got %dx%d", out.Width, out.Height)
+	}
+
+	// Undecodable formats are stored as they are
+	out, err = (&Pipeline{MaxWidth: 10, Quality: 80, Blurhash: true}).Process([]byte("RIFF....WEBP"), "image/webp")
+	if err != nil || out.ContentType != "image/webp" || out.Blurhash != "" {
+		t.Errorf("Expected undecodable image to pass through, got %+v (%v)", out, err)
+	}
+}
+
+func TestPipelineValidate(t *testing.T) {
+	for _, p := range []Pipeline{
+		{Quality: 0},
+		{Quality: 80, MaxWidth: -1},
+		{Quality: 80, Format: "bmp"},
+	} {
+		if err := p.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", p)
+		}
+	}
+}
+
+func TestBlurhash(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	// "L" is the 4x3 size flag and "TSUA" the white DC component
+	if got := Blurhash(img, 4, 3); got != "LfTSUA~qfQ~q~qt7fQt7fQfQfQfQ" {
+		t.Errorf("Unexpected blurhash for a white image: %s", got)
+	}
+}
@@ -0,0 +1,83 @@
+package crosspost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Bluesky posts to an AT Protocol PDS using an app password.
+type Bluesky struct {
+	BaseURL     string // PDS URL, e.g., https://bsky.social
+	Identifier  string // Handle or DID
+	AppPassword string
+	HTTPClient  *http.Client
+}
+
+type blueskySession struct {
+	AccessJwt string `json:"accessJwt"`
+	DID       string `json:"did"`
+}
+
+func (b *Bluesky) call(ctx context.Context, method, token, contentType string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.BaseURL+"/xrpc/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := b.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s returned status %d: %s", method, resp.StatusCode, msg)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// Publish creates an app.bsky.feed.post record, embedding the image if present.
+func (b *Bluesky) Publish(ctx context.Context, post Post) error {
+	login, _ := json.Marshal(map[string]string{"identifier": b.Identifier, "password": b.AppPassword})
+	var session blueskySession
+	if err := b.call(ctx, "com.atproto.server.createSession", "", "application/json", login, &session); err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+
+	record := map[string]interface{}{
+		"$type":     "app.bsky.feed.post",
+		"text":      post.Text,
+		"createdAt": time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if len(post.Image) > 0 {
+		var uploaded struct {
+			Blob json.RawMessage `json:"blob"`
+		}
+		if err := b.call(ctx, "com.atproto.repo.uploadBlob", session.AccessJwt, post.ImageType, post.Image, &uploaded); err != nil {
+			return fmt.Errorf("image upload failed: %w", err)
+		}
+		record["embed"] = map[string]interface{}{
+			"$type":  "app.bsky.embed.images",
+			"images": []map[string]interface{}{{"alt": post.AltText, "image": uploaded.Blob}},
+		}
+	}
+
+	create, _ := json.Marshal(map[string]interface{}{
+		"repo":       session.DID,
+		"collection": "app.bsky.feed.post",
+		"record":     record,
+	})
+	return b.call(ctx, "com.atproto.repo.createRecord", session.AccessJwt, "application/json", create, nil)
+}
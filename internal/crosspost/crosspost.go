@@ -0,0 +1,201 @@
+// Package crosspost announces finished books on Mastodon and Bluesky using
+// their HTTP APIs. Accounts and templates are stored in the database with
+// credentials encrypted by a secrets.Box.
+package crosspost
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/safehttp"
+	"github.com/ericdahl/bookshelf/internal/secrets"
+)
+
+// maxImageSize bounds the cover image attached to a post.
+const maxImageSize = 1 * 1024 * 1024 // Bluesky rejects blobs over 1 MB
+
+// Post is the content sent to a connector.
+type Post struct {
+	Text      string
+	Image     []byte // Optional cover image
+	ImageType string // MIME type of Image
+	AltText   string
+}
+
+// Connector publishes posts to one social network account.
+type Connector interface {
+	Publish(ctx context.Context, post Post) error
+}
+
+// TemplateData is the data available to post templates.
+type TemplateData struct {
+	Title    string
+	Author   string
+	Rating   int    // 0 when unrated
+	Stars    string // Rating rendered as up to five stars
+	Comments string // Empty when the comments are flagged as spoilers
+	Series   string
+}
+
+// Stars renders a 1-10 rating as up to five stars, with a half star for odd ratings.
+func Stars(rating int) string {
+	return strings.Repeat("★", rating/2) + strings.Repeat("½", rating%2)
+}
+
+// ValidateTemplate checks that tmpl parses and renders against sample data.
+func ValidateTemplate(tmpl string) error {
+	_, err := RenderTemplate(tmpl, &model.Book{Title: "Title", Author: "Author"})
+	return err
+}
+
+// RenderTemplate renders the post text for book. An empty tmpl uses the default.
+func RenderTemplate(tmpl string, book *model.Book) (string, error) {
+	if tmpl == "" {
+		tmpl = model.DefaultCrosspostTemplate
+	}
+	t, err := template.New("post").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+
+	data := TemplateData{Title: book.Title, Author: book.Author}
+	if book.Rating != nil {
+		data.Rating = *book.Rating
+		data.Stars = Stars(*book.Rating)
+	}
+	if book.Comments != nil && !book.CommentsSpoiler {
+		data.Comments = *book.Comments
+	}
+	if book.Series != nil {
+		data.Series = *book.Series
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// Service sends finished-book posts to every enabled account.
+type Service struct {
+	Store      db.CrosspostStore
+	Box        *secrets.Box
+	HTTPClient *http.Client
+}
+
+// NewService creates a cross-posting service. Instances are whichever users
+// link, so its client can't reach the server's own networks.
+func NewService(store db.CrosspostStore, box *secrets.Box) *Service {
+	return &Service{
+		Store:      store,
+		Box:        box,
+		HTTPClient: safehttp.NewClient(20 * time.Second),
+	}
+}
+
+// connectorFor builds the connector for account using the decrypted token.
+func (s *Service) connectorFor(account model.CrosspostAccount, token string) (Connector, error) {
+	base := strings.TrimRight(account.InstanceURL, "/")
+	switch account.Provider {
+	case model.ProviderMastodon:
+		return &Mastodon{BaseURL: base, Token: token, HTTPClient: s.HTTPClient}, nil
+	case model.ProviderBluesky:
+		return &Bluesky{BaseURL: base, Identifier: account.Handle, AppPassword: token, HTTPClient: s.HTTPClient}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider %s", account.Provider)
+	}
+}
+
+// fetchCover downloads the cover image for book, returning nil if unavailable.
+func (s *Service) fetchCover(ctx context.Context, book *model.Book) ([]byte, string) {
+	if book.CoverURL == nil || *book.CoverURL == "" {
+		return nil, ""
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, *book.CoverURL, nil)
+	if err != nil {
+		return nil, ""
+	}
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		slog.Warn("Failed to fetch cover for cross-post", "url", *book.CoverURL, "error", err)
+		return nil, ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, ""
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil || len(data) > maxImageSize {
+		return nil, ""
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = http.DetectContentType(data)
+	}
+	return data, contentType
+}
+
+// PublishFinished posts about a finished book to every enabled account of
+// the book's owner. Books that opted out of publishing are skipped. Returns the
+// number of successful posts.
+func (s *Service) PublishFinished(ctx context.Context, book *model.Book) int {
+	if book.PublishOptOut {
+		return 0
+	}
+	if book.UserID != nil {
+		ctx = db.WithUser(ctx, *book.UserID)
+	}
+	accounts, err := s.Store.GetCrosspostAccounts(ctx)
+	if err != nil {
+		slog.Error("Failed to list cross-posting accounts", "error", err)
+		return 0
+	}
+
+	var image []byte
+	var imageType string
+	imageFetched := false
+
+	posted := 0
+	for _, account := range accounts {
+		if !account.Enabled {
+			continue
+		}
+		text, err := RenderTemplate(account.Template, book)
+		if err != nil {
+			slog.Warn("Failed to render cross-post template", "account", account.ID, "error", err)
+			continue
+		}
+		token, err := s.Box.Open(account.EncryptedToken)
+		if err != nil {
+			slog.Warn("Failed to decrypt cross-posting credentials", "account", account.ID, "error", err)
+			continue
+		}
+		connector, err := s.connectorFor(account, token)
+		if err != nil {
+			slog.Warn("No connector for account", "account", account.ID, "error", err)
+			continue
+		}
+
+		if !imageFetched {
+			image, imageType = s.fetchCover(ctx, book)
+			imageFetched = true
+		}
+		post := Post{Text: text, Image: image, ImageType: imageType, AltText: "Cover of " + book.Title}
+		if err := connector.Publish(ctx, post); err != nil {
+			slog.Warn("Cross-post failed", "provider", account.Provider, "handle", account.Handle, "error", err)
+			continue
+		}
+		slog.Info("Cross-posted finished book", "provider", account.Provider, "handle", account.Handle, "bookID", book.ID)
+		posted++
+	}
+	return posted
+}
@@ -0,0 +1,207 @@
+package crosspost
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/secrets"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	rating := 7
+	comments := "Loved the ending"
+	book := &model.Book{Title: "Dune", Author: "Frank Herbert", Rating: &rating, Comments: &comments}
+
+	text, err := RenderTemplate("{{.Title}} by {{.Author}} {{.Stars}} {{.Comments}}", book)
+	if err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+	if text != "Dune by Frank Herbert ★★★½ Loved the ending" {
+		t.Errorf("Unexpected text: %q", text)
+	}
+
+	book.CommentsSpoiler = true
+	text, err = RenderTemplate("{{.Title}}:{{.Comments}}", book)
+	if err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+	if text != "Dune:" {
+		t.Errorf("Expected spoiler comments to be blanked, got %q", text)
+	}
+
+	if err := ValidateTemplate("{{.Nope}}"); err == nil {
+		t.Error("Expected error for unknown template field")
+	}
+	if err := ValidateTemplate("{{.Title"); err == nil {
+		t.Error("Expected error for malformed template")
+	}
+}
+
+func TestMastodonPublish(t *testing.T) {
+	var mu sync.Mutex
+	var status map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v2/media":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Errorf("Invalid media upload: %v", err)
+			}
+			if r.FormValue("description") != "Cover" {
+				t.Errorf("Expected alt text, got %q", r.FormValue("description"))
+			}
+			json.NewEncoder(w).Encode(map[string]string{"id": "m1"})
+		case "/api/v1/statuses":
+			mu.Lock()
+			defer mu.Unlock()
+			json.NewDecoder(r.Body).Decode(&status)
+			json.NewEncoder(w).Encode(map[string]string{"id": "s1"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	m := &Mastodon{BaseURL: server.URL, Token: "secret", HTTPClient: server.Client()}
+	err := m.Publish(context.Background(), Post{Text: "Finished Dune", Image: []byte("png"), ImageType: "image/png", AltText: "Cover"})
+	if err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if status["status"] != "Finished Dune" {
+		t.Errorf("Unexpected status text: %v", status["status"])
+	}
+	if ids, ok := status["media_ids"].([]interface{}); !ok || len(ids) != 1 || ids[0] != "m1" {
+		t.Errorf("Expected media ID to be attached, got %v", status["media_ids"])
+	}
+
+	m.Token = "wrong"
+	if err := m.Publish(context.Background(), Post{Text: "x"}); err == nil {
+		t.Error("Expected error for rejected token")
+	}
+}
+
+func TestBlueskyPublish(t *testing.T) {
+	var record map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.server.createSession":
+			var login map[string]string
+			json.NewDecoder(r.Body).Decode(&login)
+			if login["identifier"] != "reader.bsky.social" || login["password"] != "app-pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"accessJwt": "jwt", "did": "did:plc:abc"})
+		case "/xrpc/com.atproto.repo.uploadBlob":
+			if r.Header.Get("Authorization") != "Bearer jwt" || r.Header.Get("Content-Type") != "image/jpeg" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			io.Copy(io.Discard, r.Body)
+			w.Write([]byte(`{"blob":{"$type":"blob","ref":{"$link":"cid"},"mimeType":"image/jpeg","size":3}}`))
+		case "/xrpc/com.atproto.repo.createRecord":
+			json.NewDecoder(r.Body).Decode(&record)
+			w.Write([]byte(`{"uri":"at://x","cid":"y"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	b := &Bluesky{BaseURL: server.URL, Identifier: "reader.bsky.social", AppPassword: "app-pass", HTTPClient: server.Client()}
+	err := b.Publish(context.Background(), Post{Text: "Finished Dune", Image: []byte("jpg"), ImageType: "image/jpeg", AltText: "Cover"})
+	if err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if record["repo"] != "did:plc:abc" {
+		t.Errorf("Unexpected repo: %v", record["repo"])
+	}
+	post := record["record"].(map[string]interface{})
+	if post["text"] != "Finished Dune" {
+		t.Errorf("Unexpected text: %v", post["text"])
+	}
+	embed, ok := post["embed"].(map[string]interface{})
+	if !ok || embed["$type"] != "app.bsky.embed.images" {
+		t.Errorf("Expected image embed, got %v", post["embed"])
+	}
+}
+
+func TestServicePublishFinished(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var posted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/statuses" {
+			var status map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&status)
+			mu.Lock()
+			posted = append(posted, status["status"].(string))
+			mu.Unlock()
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id": "1"})
+	}))
+	defer server.Close()
+
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	defer database.Close()
+	if err := db.CreateSchema(database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	store := db.NewSQLiteBookStore(database)
+
+	box, err := secrets.NewBox("passphrase")
+	if err != nil {
+		t.Fatalf("NewBox failed: %v", err)
+	}
+	sealed, err := box.Seal("token")
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	for _, enabled := range []bool{true, false} {
+		account := &model.CrosspostAccount{
+			Provider:       model.ProviderMastodon,
+			InstanceURL:    server.URL,
+			Handle:         "@reader",
+			Template:       "Done: {{.Title}}",
+			Enabled:        enabled,
+			EncryptedToken: sealed,
+		}
+		if _, err := store.AddCrosspostAccount(ctx, account); err != nil {
+			t.Fatalf("AddCrosspostAccount failed: %v", err)
+		}
+	}
+
+	// Instances on the server's own networks are refused
+	svc := NewService(store, box)
+	if n := svc.PublishFinished(ctx, &model.Book{Title: "Dune"}); n != 0 || len(posted) != 0 {
+		t.Fatalf("Expected the instance on loopback to be refused, got %d posts", n)
+	}
+	svc.HTTPClient = server.Client() // The test server is on loopback
+
+	if n := svc.PublishFinished(ctx, &model.Book{Title: "Dune"}); n != 1 {
+		t.Errorf("Expected 1 post (disabled account skipped), got %d", n)
+	}
+	if len(posted) != 1 || !strings.HasPrefix(posted[0], "Done: Dune") {
+		t.Errorf("Unexpected posts: %v", posted)
+	}
+
+	if n := svc.PublishFinished(ctx, &model.Book{Title: "Secret", PublishOptOut: true}); n != 0 {
+		t.Errorf("Expected opted-out book not to be posted, got %d", n)
+	}
+}
@@ -0,0 +1,89 @@
+package crosspost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+)
+
+// Mastodon posts statuses using the Mastodon REST API and an OAuth access token.
+type Mastodon struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+func (m *Mastodon) do(req *http.Request, out interface{}) error {
+	req.Header.Set("Authorization", "Bearer "+m.Token)
+	resp, err := m.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("mastodon returned status %d: %s", resp.StatusCode, body)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// uploadMedia uploads an image attachment and returns its media ID.
+func (m *Mastodon) uploadMedia(ctx context.Context, post Post) (string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="file"; filename="cover"`)
+	header.Set("Content-Type", post.ImageType)
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return "", err
+	}
+	part.Write(post.Image)
+	mw.WriteField("description", post.AltText)
+	mw.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.BaseURL+"/api/v2/media", &buf)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	var media struct {
+		ID string `json:"id"`
+	}
+	if err := m.do(req, &media); err != nil {
+		return "", fmt.Errorf("media upload failed: %w", err)
+	}
+	return media.ID, nil
+}
+
+// Publish posts a public status, attaching the image if present.
+func (m *Mastodon) Publish(ctx context.Context, post Post) error {
+	status := map[string]interface{}{
+		"status":     post.Text,
+		"visibility": "public",
+	}
+	if len(post.Image) > 0 {
+		mediaID, err := m.uploadMedia(ctx, post)
+		if err != nil {
+			return err
+		}
+		status["media_ids"] = []string{mediaID}
+	}
+
+	body, _ := json.Marshal(status)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.BaseURL+"/api/v1/statuses", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return m.do(req, nil)
+}
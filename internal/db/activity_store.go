@@ -0,0 +1,359 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// ActivityStore defines the database operations for the activity timeline.
+type ActivityStore interface {
+	RecordActivity(ctx context.Context, activity *model.Activity) error
+	ListActivities(ctx context.Context, limit int, localOnly bool) ([]model.Activity, error)
+	ListPublicActivities(ctx context.Context, limit int) ([]model.Activity, error)
+	GetActivityByID(ctx context.Context, id int64) (*model.Activity, error)
+}
+
+// FollowStore defines the database operations for followed remote feeds.
+type FollowStore interface {
+	AddFollow(ctx context.Context, follow *model.Follow) (int64, error)
+	GetFollows(ctx context.Context) ([]model.Follow, error)
+	GetFollowByID(ctx context.Context, id int64) (*model.Follow, error)
+	DeleteFollow(ctx context.Context, id int64) error
+	UpdateFollowFetchStatus(ctx context.Context, id int64, fetchedAt time.Time, fetchErr *string) error
+	SaveRemoteActivities(ctx context.Context, followID int64, activities []model.Activity) (int, error)
+}
+
+// RecordActivity inserts a local activity entry. OccurredAt defaults to now.
+// It belongs to the owner of its book, or else to the user of ctx.
+func (s *SQLiteBookStore) RecordActivity(ctx context.Context, activity *model.Activity) error {
+	if !activity.Kind.IsValid() {
+		return invalidf("invalid activity kind: %s", activity.Kind)
+	}
+	if activity.OccurredAt.IsZero() {
+		activity.OccurredAt = time.Now().UTC()
+	}
+
+	query := `INSERT INTO activities (user_id, book_id, kind, title, author, status, url, summary, occurred_at)
+        VALUES (COALESCE((SELECT user_id FROM books WHERE id = ?), ?), ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;`
+	slog.InfoContext(ctx, "SQL: Executing RecordActivity query", "kind", activity.Kind, "bookID", activity.BookID)
+
+	if err := s.DB.QueryRowContext(ctx, query, activity.BookID, owner(ctx), activity.BookID, activity.Kind, activity.Title,
+		activity.Author, activity.Status, activity.URL, activity.Summary, activity.OccurredAt).Scan(&activity.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing RecordActivity statement failed", "error", err)
+		return fmt.Errorf("failed to record activity: %w", classify(err))
+	}
+	activity.Source = "local"
+	return nil
+}
+
+// recordBookActivity records an activity for a book mutation. Failures are logged
+// but not returned, since the timeline must never block the mutation itself.
+func (s *SQLiteBookStore) recordBookActivity(ctx context.Context, kind model.ActivityKind, book *model.Book) {
+	var summary string
+	switch kind {
+	case model.ActivityBookAdded:
+		summary = fmt.Sprintf("Added %q to %s", book.Title, book.Status)
+	case model.ActivityBookFinished:
+		summary = fmt.Sprintf("Finished reading %q", book.Title)
+	default:
+		summary = fmt.Sprintf("Moved %q to %s", book.Title, book.Status)
+	}
+
+	id := book.ID
+	activity := &model.Activity{
+		BookID:  &id,
+		Kind:    kind,
+		Title:   book.Title,
+		Author:  book.Author,
+		Status:  book.Status,
+		Summary: summary,
+	}
+	// The mutation has already happened, so the entry is written even if the caller has gone
+	if err := s.RecordActivity(context.WithoutCancel(ctx), activity); err != nil {
+		slog.WarnContext(ctx, "Failed to record activity", "kind", kind, "bookID", book.ID, "error", err)
+	}
+}
+
+const activityColumns = `a.id, a.follow_id, a.remote_id, a.book_id, a.kind, a.title, a.author, a.status, a.url,
+            a.summary, a.occurred_at, f.name`
+
+// scanActivity reads an activities row of activityColumns, from activities a
+// joined with their follows f.
+func scanActivity(row rowScanner) (model.Activity, error) {
+	var a model.Activity
+	var followID, bookID sql.NullInt64
+	var remoteID, author, status, url, followName sql.NullString
+	if err := row.Scan(&a.ID, &followID, &remoteID, &bookID, &a.Kind, &a.Title, &author, &status, &url,
+		&a.Summary, &a.OccurredAt, &followName); err != nil {
+		return a, err
+	}
+	if followID.Valid {
+		a.FollowID = &followID.Int64
+		a.Source = followName.String
+	} else {
+		a.Source = "local"
+	}
+	if remoteID.Valid {
+		a.RemoteID = &remoteID.String
+	}
+	if bookID.Valid {
+		a.BookID = &bookID.Int64
+	}
+	a.Author = author.String
+	a.Status = model.BookStatus(status.String)
+	if url.Valid {
+		a.URL = &url.String
+	}
+	return a, nil
+}
+
+// GetActivityByID returns an activity of the user of ctx.
+func (s *SQLiteBookStore) GetActivityByID(ctx context.Context, id int64) (*model.Activity, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetActivityByID query", "id", id)
+	owned, args := ownedBy(ctx, "a.user_id")
+	a, err := scanActivity(s.DB.QueryRowContext(ctx, `SELECT `+activityColumns+` FROM activities a
+        LEFT JOIN follows f ON f.id = a.follow_id WHERE a.id = ? AND `+owned+`;`, append([]interface{}{id}, args...)...))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("activity with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetActivityByID query failed", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to get activity %d: %w", id, err)
+	}
+	return &a, nil
+}
+
+// ListActivities returns the most recent activities of the user of ctx, newest first.
+// When localOnly is true, activities mirrored from followed feeds are excluded.
+func (s *SQLiteBookStore) ListActivities(ctx context.Context, limit int, localOnly bool) ([]model.Activity, error) {
+	slog.InfoContext(ctx, "SQL: Executing ListActivities query", "limit", limit, "localOnly", localOnly)
+	return s.listActivities(ctx, limit, localOnly, false)
+}
+
+// ListPublicActivities returns the local activities of the user of ctx that may be
+// published, newest first: those about books which are on the shelf and not opted out.
+func (s *SQLiteBookStore) ListPublicActivities(ctx context.Context, limit int) ([]model.Activity, error) {
+	slog.InfoContext(ctx, "SQL: Executing ListPublicActivities query", "limit", limit)
+	return s.listActivities(ctx, limit, true, true)
+}
+
+func (s *SQLiteBookStore) listActivities(ctx context.Context, limit int, localOnly, public bool) ([]model.Activity, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	query := `SELECT ` + activityColumns + ` FROM activities a LEFT JOIN follows f ON f.id = a.follow_id`
+	if public {
+		query += ` LEFT JOIN books b ON b.id = a.book_id`
+	}
+	owned, args := ownedBy(ctx, "a.user_id")
+	query += ` WHERE ` + owned
+	if localOnly {
+		query += ` AND a.follow_id IS NULL`
+	}
+	if public {
+		// Books purged since leave no row and are left out with the rest
+		query += ` AND (a.book_id IS NULL OR (b.publish_opt_out = ? AND b.deleted_at IS NULL))`
+		args = append(args, false)
+	}
+	query += ` ORDER BY a.occurred_at DESC, a.id DESC LIMIT ?;`
+
+	rows, err := s.DB.QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing ListActivities query failed", "error", err)
+		return nil, fmt.Errorf("failed to query activities: %w", err)
+	}
+	defer rows.Close()
+
+	activities := []model.Activity{}
+	for rows.Next() {
+		a, err := scanActivity(rows)
+		if err != nil {
+			slog.ErrorContext(ctx, "SQL Error: Scanning activity row failed", "error", err)
+			return nil, fmt.Errorf("failed to scan activity row: %w", err)
+		}
+		activities = append(activities, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating activity rows: %w", err)
+	}
+
+	slog.InfoContext(ctx, "SQL: Retrieved activities", "count", len(activities))
+	return activities, nil
+}
+
+// AddFollow inserts a new followed feed, owned by the user of ctx.
+func (s *SQLiteBookStore) AddFollow(ctx context.Context, follow *model.Follow) (int64, error) {
+	if follow.FeedURL == "" {
+		return 0, fmt.Errorf("feed URL is required")
+	}
+	if follow.CreatedAt.IsZero() {
+		follow.CreatedAt = time.Now().UTC()
+	}
+
+	query := `INSERT INTO follows (user_id, feed_url, name, created_at) VALUES (?, ?, ?, ?) RETURNING id;`
+	slog.InfoContext(ctx, "SQL: Executing AddFollow query", logging.Redact("feedURL", follow.FeedURL), "name", follow.Name)
+
+	var id int64
+	if err := s.DB.QueryRowContext(ctx, query, owner(ctx), follow.FeedURL, follow.Name, follow.CreatedAt).Scan(&id); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddFollow statement failed", "error", err)
+		return 0, fmt.Errorf("failed to add follow: %w", classify(err))
+	}
+	follow.ID = id
+	slog.InfoContext(ctx, "SQL: Successfully added follow", "id", id)
+	return id, nil
+}
+
+const followColumns = `id, feed_url, name, created_at, last_fetched_at, last_error`
+
+func scanFollow(row rowScanner) (*model.Follow, error) {
+	var f model.Follow
+	var lastFetched sql.NullTime
+	var lastError sql.NullString
+	if err := row.Scan(&f.ID, &f.FeedURL, &f.Name, &f.CreatedAt, &lastFetched, &lastError); err != nil {
+		return nil, err
+	}
+	if lastFetched.Valid {
+		f.LastFetchedAt = &lastFetched.Time
+	}
+	if lastError.Valid {
+		f.LastError = &lastError.String
+	}
+	return &f, nil
+}
+
+// GetFollows returns the followed feeds of the user of ctx, or every user's
+// without one, ordered by name.
+func (s *SQLiteBookStore) GetFollows(ctx context.Context) ([]model.Follow, error) {
+	owned, args := ownedBy(ctx, "user_id")
+	query := `SELECT ` + followColumns + ` FROM follows WHERE ` + owned + ` ORDER BY name;`
+	slog.InfoContext(ctx, "SQL: Executing GetFollows query")
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetFollows query failed", "error", err)
+		return nil, fmt.Errorf("failed to query follows: %w", err)
+	}
+	defer rows.Close()
+
+	follows := []model.Follow{}
+	for rows.Next() {
+		f, err := scanFollow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan follow row: %w", err)
+		}
+		follows = append(follows, *f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating follow rows: %w", err)
+	}
+	return follows, nil
+}
+
+// GetFollowByID returns a single followed feed.
+func (s *SQLiteBookStore) GetFollowByID(ctx context.Context, id int64) (*model.Follow, error) {
+	owned, args := ownedBy(ctx, "user_id")
+	query := `SELECT ` + followColumns + ` FROM follows WHERE id = ? AND ` + owned + `;`
+	slog.InfoContext(ctx, "SQL: Executing GetFollowByID query", "id", id)
+
+	f, err := scanFollow(s.DB.QueryRowContext(ctx, query, append([]interface{}{id}, args...)...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("follow with ID %d %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to scan follow row for ID %d: %w", id, err)
+	}
+	return f, nil
+}
+
+// DeleteFollow removes a followed feed and its cached activity.
+func (s *SQLiteBookStore) DeleteFollow(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "SQL: Executing DeleteFollow query", "id", id)
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Another user's follow isn't found, rolling back the deletion of its activity
+	if _, err := tx.ExecContext(ctx, `DELETE FROM activities WHERE follow_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to delete cached activity: %w", err)
+	}
+	owned, args := ownedBy(ctx, "user_id")
+	res, err := tx.ExecContext(ctx, `DELETE FROM follows WHERE id = ? AND `+owned+`;`, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete follow: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("follow with ID %d %w", id, ErrNotFound)
+	}
+	return tx.Commit()
+}
+
+// UpdateFollowFetchStatus records the outcome of the last fetch of a followed feed.
+func (s *SQLiteBookStore) UpdateFollowFetchStatus(ctx context.Context, id int64, fetchedAt time.Time, fetchErr *string) error {
+	slog.InfoContext(ctx, "SQL: Executing UpdateFollowFetchStatus query", "id", id)
+	owned, args := ownedBy(ctx, "user_id")
+	_, err := s.DB.ExecContext(ctx, `UPDATE follows SET last_fetched_at = ?, last_error = ? WHERE id = ? AND `+owned+`;`,
+		append([]interface{}{fetchedAt, fetchErr, id}, args...)...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing UpdateFollowFetchStatus statement failed", "error", err)
+		return fmt.Errorf("failed to update follow fetch status: %w", classify(err))
+	}
+	return nil
+}
+
+// SaveRemoteActivities caches activities fetched from a followed feed.
+// Entries already cached (same remote ID) are ignored, and new ones belong to the
+// follow's owner. Returns the number of new entries.
+func (s *SQLiteBookStore) SaveRemoteActivities(ctx context.Context, followID int64, activities []model.Activity) (int, error) {
+	slog.InfoContext(ctx, "SQL: Executing SaveRemoteActivities", "followID", followID, "count", len(activities))
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO activities
+        (user_id, follow_id, remote_id, kind, title, author, status, url, summary, occurred_at)
+        VALUES ((SELECT user_id FROM follows WHERE id = ?), ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING;`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare remote activity insert: %w", err)
+	}
+	defer stmt.Close()
+
+	inserted := 0
+	for _, a := range activities {
+		if a.RemoteID == nil || *a.RemoteID == "" {
+			continue // Without an ID we cannot deduplicate on the next fetch
+		}
+		if !a.Kind.IsValid() {
+			a.Kind = model.ActivityStatusChanged
+		}
+		res, err := stmt.ExecContext(ctx, followID, followID, *a.RemoteID, a.Kind, a.Title, a.Author, a.Status, a.URL, a.Summary, a.OccurredAt)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert remote activity: %w", classify(err))
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			inserted++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit remote activities: %w", err)
+	}
+	slog.InfoContext(ctx, "SQL: Saved remote activities", "followID", followID, "inserted", inserted)
+	return inserted, nil
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestBookMutationsRecordActivity tests that adding and moving books populates the timeline
+func TestBookMutationsRecordActivity(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	id, err := store.AddBook(ctx, book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	if err := store.UpdateBookStatus(ctx, id, model.StatusRead); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+
+	activities, err := store.ListActivities(ctx, 10, true)
+	if err != nil {
+		t.Fatalf("ListActivities failed: %v", err)
+	}
+	if len(activities) != 2 {
+		t.Fatalf("Expected 2 activities, got %d", len(activities))
+	}
+
+	// Newest first
+	if activities[0].Kind != model.ActivityBookFinished {
+		t.Errorf("Expected newest activity %s, got %s", model.ActivityBookFinished, activities[0].Kind)
+	}
+	if activities[1].Kind != model.ActivityBookAdded {
+		t.Errorf("Expected oldest activity %s, got %s", model.ActivityBookAdded, activities[1].Kind)
+	}
+	if activities[0].BookID == nil || *activities[0].BookID != id {
+		t.Errorf("Expected activity book ID %d, got %v", id, activities[0].BookID)
+	}
+	if activities[0].Source != "local" {
+		t.Errorf("Expected local source, got %s", activities[0].Source)
+	}
+}
+
+// TestFollows tests follow CRUD and remote activity caching
+func TestFollows(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	follow := &model.Follow{FeedURL: "http://friend.example/api/feed.json", Name: "Friend"}
+	id, err := store.AddFollow(ctx, follow)
+	if err != nil {
+		t.Fatalf("AddFollow failed: %v", err)
+	}
+
+	// Duplicate feed URL
+	if _, err := store.AddFollow(ctx, &model.Follow{FeedURL: follow.FeedURL, Name: "Again"}); err == nil {
+		t.Errorf("Expected error when following the same feed twice")
+	}
+
+	remoteID := "activity-1"
+	inserted, err := store.SaveRemoteActivities(ctx, id, []model.Activity{
+		{RemoteID: &remoteID, Kind: model.ActivityBookAdded, Title: "Remote Book", Summary: "Added"},
+		{Kind: model.ActivityBookAdded, Title: "No ID", Summary: "Skipped"},
+	})
+	if err != nil {
+		t.Fatalf("SaveRemoteActivities failed: %v", err)
+	}
+	if inserted != 1 {
+		t.Errorf("Expected 1 inserted activity, got %d", inserted)
+	}
+
+	local, err := store.ListActivities(ctx, 10, true)
+	if err != nil {
+		t.Fatalf("ListActivities failed: %v", err)
+	}
+	if len(local) != 0 {
+		t.Errorf("Expected no local activities, got %d", len(local))
+	}
+
+	if err := store.DeleteFollow(ctx, id); err != nil {
+		t.Fatalf("DeleteFollow failed: %v", err)
+	}
+	all, err := store.ListActivities(ctx, 10, false)
+	if err != nil {
+		t.Fatalf("ListActivities failed: %v", err)
+	}
+	if len(all) != 0 {
+		t.Errorf("Expected cached activity to be removed with the follow, got %d", len(all))
+	}
+
+	if err := store.DeleteFollow(ctx, id); err == nil {
+		t.Errorf("Expected error when deleting non-existent follow")
+	}
+}
@@ -0,0 +1,281 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// AuthorStore defines the database operations for authors, their profiles
+// and books, and the reading stats derived from them.
+type AuthorStore interface {
+	GetAuthors(ctx context.Context) ([]model.AuthorProfile, error)
+	GetAuthor(ctx context.Context, id int64) (*model.AuthorProfile, error)
+	GetBooksByAuthor(ctx context.Context, id int64) ([]model.Book, error)
+	SetAuthor(ctx context.Context, author *model.AuthorProfile) error
+	DeleteAuthor(ctx context.Context, id int64) error
+	GetDiversityStats(ctx context.Context, year int) (model.DiversityStats, error)
+}
+
+// unknownStat is the bucket for books whose authors have no value for a stat.
+const unknownStat = "unknown"
+
+// authorProfiles loads every profile, keyed by lowercase name.
+func (s *SQLiteBookStore) authorProfiles(ctx context.Context) (map[string]model.AuthorProfile, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, name, gender, nationality, updated_at FROM authors;`)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing author query failed", "error", err)
+		return nil, fmt.Errorf("failed to query authors: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := make(map[string]model.AuthorProfile)
+	for rows.Next() {
+		var a model.AuthorProfile
+		if err := rows.Scan(&a.ID, &a.Name, &a.Gender, &a.Nationality, &a.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan author row: %w", err)
+		}
+		profiles[strings.ToLower(a.Name)] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating author rows: %w", err)
+	}
+	return profiles, nil
+}
+
+// authorColumns selects an author with the number of books on the shelf of
+// the user ctx is scoped to naming them. Its arguments come first.
+func authorColumns(ctx context.Context) (string, []interface{}) {
+	owned, args := shelved(ctx, "books")
+	return `authors.id, authors.name, authors.gender, authors.nationality, authors.updated_at,
+        (SELECT COUNT(*) FROM book_authors JOIN books ON books.id = book_authors.book_id
+            WHERE book_authors.author_id = authors.id AND ` + owned + `)`, args
+}
+
+func scanAuthor(row rowScanner) (*model.AuthorProfile, error) {
+	var a model.AuthorProfile
+	if err := row.Scan(&a.ID, &a.Name, &a.Gender, &a.Nationality, &a.UpdatedAt, &a.BookCount); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// GetAuthors returns every author named by a book or with a profile, in name
+// order, with the number of books naming them. Profiles are shared by every
+// library.
+func (s *SQLiteBookStore) GetAuthors(ctx context.Context) ([]model.AuthorProfile, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetAuthors query")
+	columns, args := authorColumns(ctx)
+	rows, err := s.DB.QueryContext(ctx, `SELECT * FROM (SELECT `+columns+` AS book_count FROM authors) AS a
+        WHERE book_count > 0 OR gender != '' OR nationality IS NOT NULL ORDER BY name, id;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetAuthors query failed", "error", err)
+		return nil, fmt.Errorf("failed to query authors: %w", err)
+	}
+	defer rows.Close()
+
+	authors := []model.AuthorProfile{}
+	for rows.Next() {
+		a, err := scanAuthor(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan author row: %w", err)
+		}
+		authors = append(authors, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating author rows: %w", err)
+	}
+	return authors, nil
+}
+
+// GetAuthor returns one author with the number of books naming them.
+func (s *SQLiteBookStore) GetAuthor(ctx context.Context, id int64) (*model.AuthorProfile, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetAuthor query", "id", id)
+	columns, args := authorColumns(ctx)
+	author, err := scanAuthor(s.DB.QueryRowContext(ctx, `SELECT `+columns+` FROM authors WHERE authors.id = ?;`, append(args, id)...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("author with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get author: %w", err)
+	}
+	return author, nil
+}
+
+// GetBooksByAuthor returns the books naming an author, whether alone or with
+// others, in title order.
+func (s *SQLiteBookStore) GetBooksByAuthor(ctx context.Context, id int64) ([]model.Book, error) {
+	if _, err := s.GetAuthor(ctx, id); err != nil {
+		return nil, err
+	}
+	books, _, err := s.GetBooksPage(ctx, ListOptions{Filter: BookFilter{AuthorID: id}})
+	return books, err
+}
+
+// linkBookAuthors links a book to the authors its author field names, in
+// order, replacing its links. Authors not known yet are added without a
+// profile.
+func linkBookAuthors(ctx context.Context, tx execer, bookID int64, author string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM book_authors WHERE book_id = ?;`, bookID); err != nil {
+		return fmt.Errorf("failed to unlink book authors: %w", err)
+	}
+	now := time.Now().UTC()
+	for i, name := range model.AuthorNames(author) {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO authors (name, updated_at) VALUES (?, ?) ON CONFLICT(name) DO NOTHING;`, name, now); err != nil {
+			return fmt.Errorf("failed to add author: %w", classify(err))
+		}
+		// A name given twice links once, at its first position
+		if _, err := tx.ExecContext(ctx, `INSERT INTO book_authors (book_id, author_id, position)
+            SELECT ?, id, ? FROM authors WHERE name = ? ON CONFLICT DO NOTHING;`, bookID, i, name); err != nil {
+			return fmt.Errorf("failed to link book author: %w", classify(err))
+		}
+	}
+	return nil
+}
+
+// SetAuthor creates or replaces the profile of the author called author.Name,
+// ignoring case, and sets its ID.
+func (s *SQLiteBookStore) SetAuthor(ctx context.Context, author *model.AuthorProfile) error {
+	if err := author.Validate(); err != nil {
+		return &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
+	}
+	slog.InfoContext(ctx, "SQL: Executing SetAuthor query", "name", author.Name, "gender", author.Gender, "nationality", author.Nationality)
+	now := time.Now().UTC()
+	author.UpdatedAt = &now
+	err := s.DB.QueryRowContext(ctx, `INSERT INTO authors (name, gender, nationality, updated_at) VALUES (?, ?, ?, ?)
+        ON CONFLICT(name) DO UPDATE SET name = excluded.name, gender = excluded.gender,
+            nationality = excluded.nationality, updated_at = excluded.updated_at
+        RETURNING id;`, author.Name, author.Gender, author.Nationality, author.UpdatedAt).Scan(&author.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing SetAuthor statement failed", "error", err)
+		return fmt.Errorf("failed to save author: %w", classify(err))
+	}
+	return nil
+}
+
+// DeleteAuthor removes an author's profile. An author still named by books
+// is kept without a profile, and their books count as unknown in the stats.
+func (s *SQLiteBookStore) DeleteAuthor(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "SQL: Executing DeleteAuthor query", "id", id)
+	res, err := s.DB.ExecContext(ctx, `UPDATE authors SET gender = '', nationality = NULL, updated_at = ?
+        WHERE id = ? AND (gender != '' OR nationality IS NOT NULL) AND id IN (SELECT author_id FROM book_authors);`, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to clear author profile: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+	res, err = s.DB.ExecContext(ctx, `DELETE FROM authors WHERE id = ? AND id NOT IN (SELECT author_id FROM book_authors);`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete author: %w", err)
+	}
+	rowsAffected, err = res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		// Only named by books, the author has no profile to remove
+		return fmt.Errorf("author with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// GetDiversityStats describes the authors of the books with a read finished
+// in year. Reference and archived books are left out, like in every reading
+// stat.
+func (s *SQLiteBookStore) GetDiversityStats(ctx context.Context, year int) (model.DiversityStats, error) {
+	stats := model.DiversityStats{Year: year, Gender: []model.StatCount{}, Nationality: []model.StatCount{}}
+	profiles, err := s.authorProfiles(ctx)
+	if err != nil {
+		return stats, err
+	}
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	slog.InfoContext(ctx, "SQL: Executing GetDiversityStats query", "year", year)
+	counted, args := s.counted(ctx, "")
+	rows, err := s.DB.QueryContext(ctx, `SELECT author, translated FROM books WHERE `+counted+`
+        AND id IN (SELECT book_id FROM reads WHERE date_finished >= ? AND date_finished < ?);`,
+		append(args, from, from.AddDate(1, 0, 0))...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetDiversityStats query failed", "error", err)
+		return stats, fmt.Errorf("failed to query finished books: %w", err)
+	}
+	defer rows.Close()
+
+	genders := make(map[string]int)
+	nationalities := make(map[string]int)
+	for rows.Next() {
+		var author string
+		var translated bool
+		if err := rows.Scan(&author, &translated); err != nil {
+			return stats, fmt.Errorf("failed to scan finished book: %w", err)
+		}
+		stats.Books++
+		if translated {
+			stats.Translated.Books++
+		}
+		bookGenders := make(map[string]bool)
+		bookNationalities := make(map[string]bool)
+		for _, name := range model.AuthorNames(author) {
+			profile := profiles[strings.ToLower(name)]
+			gender, nationality := string(profile.Gender), unknownStat
+			if gender == "" {
+				gender = unknownStat
+			}
+			if profile.Nationality != nil {
+				nationality = *profile.Nationality
+			}
+			bookGenders[gender] = true
+			bookNationalities[nationality] = true
+		}
+		if len(bookGenders) == 0 {
+			bookGenders[unknownStat], bookNationalities[unknownStat] = true, true
+		}
+		for g := range bookGenders {
+			genders[g]++
+		}
+		for n := range bookNationalities {
+			nationalities[n]++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return stats, fmt.Errorf("error iterating finished books: %w", err)
+	}
+
+	for _, g := range []model.AuthorGender{model.GenderWoman, model.GenderMan, model.GenderNonbinary, unknownStat} {
+		stats.Gender = append(stats.Gender, statCount(string(g), genders[string(g)], stats.Books))
+	}
+	for n, books := range nationalities {
+		if n != unknownStat {
+			stats.Nationality = append(stats.Nationality, statCount(n, books, stats.Books))
+		}
+	}
+	sort.Slice(stats.Nationality, func(i, j int) bool {
+		a, b := stats.Nationality[i], stats.Nationality[j]
+		return a.Books > b.Books || a.Books == b.Books && a.Value < b.Value
+	})
+	stats.Nationality = append(stats.Nationality, statCount(unknownStat, nationalities[unknownStat], stats.Books))
+	stats.Translated = statCount("translated", stats.Translated.Books, stats.Books)
+	return stats, nil
+}
+
+// statCount builds a bucket of books out of total, with its share rounded to
+// one decimal place.
+func statCount(value string, books, total int) model.StatCount {
+	c := model.StatCount{Value: value, Books: books}
+	if total > 0 {
+		c.Percent = math.Round(float64(books)*1000/float64(total)) / 10
+	}
+	return c
+}
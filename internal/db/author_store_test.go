@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestDiversityStats(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	finished := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	add := func(olid, author string, translated bool, mode model.ReadingMode, finishedAt *time.Time) {
+		t.Helper()
+		book := createTestBook()
+		book.OpenLibraryID, book.Author, book.Translated, book.ReadingMode = olid, author, translated, mode
+		book.DateFinished = finishedAt
+		if finishedAt != nil {
+			book.Status = model.StatusRead
+		}
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+	}
+	lastYear := finished.AddDate(-1, 0, 0)
+	add("OL1M", "Haruki Murakami", true, model.ModeLeisure, &finished)
+	add("OL2M", "Ursula K. Le Guin", false, model.ModeLeisure, &finished)
+	add("OL3M", "Neil Gaiman, Terry Pratchett", false, model.ModeLeisure, &finished)
+	add("OL4M", "Octavia E. Butler", false, model.ModeLeisure, &lastYear)
+	add("OL5M", "Ursula K. Le Guin", false, model.ModeReference, &finished)
+	add("OL6M", "Ursula K. Le Guin", false, model.ModeLeisure, nil)
+
+	japan := "Japan"
+	for _, a := range []model.AuthorProfile{
+		{Name: "haruki  murakami", Gender: model.GenderMan, Nationality: &japan},
+		{Name: "Ursula K. Le Guin", Gender: model.GenderWoman},
+		{Name: "Neil Gaiman", Gender: model.GenderMan},
+	} {
+		if err := store.SetAuthor(ctx, &a); err != nil || a.ID == 0 {
+			t.Fatalf("SetAuthor(%s) failed: %v", a.Name, err)
+		}
+	}
+	invalid := model.AuthorProfile{Name: "Someone", Gender: "robot"}
+	if err := store.SetAuthor(ctx, &invalid); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation for an invalid gender, got %v", err)
+	}
+
+	// Saving a profile again replaces it
+	replaced := model.AuthorProfile{Name: "Haruki Murakami", Gender: model.GenderMan, Nationality: &japan}
+	if err := store.SetAuthor(ctx, &replaced); err != nil {
+		t.Fatalf("SetAuthor failed: %v", err)
+	}
+	authors, err := store.GetAuthors(ctx)
+	if err != nil {
+		t.Fatalf("GetAuthors failed: %v", err)
+	}
+	byName := make(map[string]model.AuthorProfile)
+	for _, a := range authors {
+		byName[a.Name] = a
+	}
+	if len(authors) != 5 || byName["Haruki Murakami"].BookCount != 1 || byName["Ursula K. Le Guin"].BookCount != 3 ||
+		byName["Terry Pratchett"].ID == 0 {
+		t.Errorf("Unexpected authors %+v", authors)
+	}
+
+	stats, err := store.GetDiversityStats(ctx, 2025)
+	if err != nil {
+		t.Fatalf("GetDiversityStats failed: %v", err)
+	}
+	want := map[string]int{"woman": 1, "man": 2, "nonbinary": 0, "unknown": 1}
+	if stats.Books != 3 || len(stats.Gender) != 4 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+	for _, g := range stats.Gender {
+		if g.Books != want[g.Value] {
+			t.Errorf("Gender %s: got %d books, want %d", g.Value, g.Books, want[g.Value])
+		}
+	}
+	if stats.Gender[0].Percent != 33.3 || stats.Translated.Books != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if len(stats.Nationality) != 2 || stats.Nationality[0].Value != "Japan" || stats.Nationality[1].Books != 2 {
+		t.Errorf("Unexpected nationalities %+v", stats.Nationality)
+	}
+
+	if err := store.DeleteAuthor(ctx, replaced.ID); err != nil {
+		t.Fatalf("DeleteAuthor failed: %v", err)
+	}
+	if err := store.DeleteAuthor(ctx, replaced.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Deleting twice: expected ErrNotFound, got %v", err)
+	}
+	if stats, _ := store.GetDiversityStats(ctx, 2024); stats.Books != 1 || stats.Gender[3].Books != 1 {
+		t.Errorf("Unexpected stats for 2024 %+v", stats)
+	}
+}
+
+func TestBookAuthors(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	book.Author = "Terry Pratchett, Neil Gaiman"
+	id, err := store.AddBook(ctx, book)
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	other := createTestBook()
+	other.OpenLibraryID, other.Author = "OL2M", "NEIL GAIMAN"
+	otherID, err := store.AddBook(ctx, other)
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	gaiman := model.AuthorProfile{Name: "Neil Gaiman", Gender: model.GenderMan}
+	if err := store.SetAuthor(ctx, &gaiman); err != nil {
+		t.Fatalf("SetAuthor failed: %v", err)
+	}
+	if books, err := store.GetBooksByAuthor(ctx, gaiman.ID); err != nil || len(books) != 2 {
+		t.Fatalf("Expected both books of a co-author, got %+v, %v", books, err)
+	}
+	if _, err := store.GetBooksByAuthor(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found for a missing author, got %v", err)
+	}
+
+	// Changing the author field relinks the book
+	author := "Terry Pratchett"
+	if err := store.UpdateBook(ctx, id, model.BookPatch{Author: model.Optional[string]{Set: true, Value: &author}}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+	if got, err := store.GetAuthor(ctx, gaiman.ID); err != nil || got.BookCount != 1 || got.Gender != model.GenderMan {
+		t.Errorf("Expected the profile kept with one book, got %+v, %v", got, err)
+	}
+
+	// Deleting the profile keeps an author books still name
+	if err := store.DeleteAuthor(ctx, gaiman.ID); err != nil {
+		t.Fatalf("DeleteAuthor failed: %v", err)
+	}
+	if got, err := store.GetAuthor(ctx, gaiman.ID); err != nil || got.Gender != "" || got.BookCount != 1 {
+		t.Errorf("Expected the author kept without a profile, got %+v, %v", got, err)
+	}
+	if err := store.DeleteBook(ctx, otherID); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	// A book in the trash may come back, so it still names its authors
+	if err := store.DeleteAuthor(ctx, gaiman.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected an author of a trashed book to be kept, got %v", err)
+	}
+	if err := store.PurgeBook(ctx, otherID); err != nil {
+		t.Fatalf("PurgeBook failed: %v", err)
+	}
+	if err := store.DeleteAuthor(ctx, gaiman.ID); err != nil {
+		t.Fatalf("DeleteAuthor failed for an author no book names: %v", err)
+	}
+	if _, err := store.GetAuthor(ctx, gaiman.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the author gone, got %v", err)
+	}
+}
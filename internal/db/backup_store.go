@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// BackupStore defines copying the database while it's in use. Copying the
+// file of a live SQLite database can catch it halfway through a write,
+// leaving a backup that's corrupt; these copies are consistent. PostgreSQL
+// databases are backed up with pg_dump instead, so they return
+// ErrUnsupported.
+type BackupStore interface {
+	// Backup writes a consistent snapshot of the database to path, which
+	// must not exist yet.
+	Backup(ctx context.Context, path string) error
+	// Restore replaces the contents of the database with those of the SQLite
+	// database at path, a backup, once it has been checked, and migrates them
+	// to the current schema.
+	Restore(ctx context.Context, path string) error
+}
+
+// Backup writes a snapshot of the database to path with VACUUM INTO, which
+// reads it in one transaction, so writes made meanwhile are either all in
+// the snapshot or not at all. The snapshot is also compacted.
+func (s *SQLiteBookStore) Backup(ctx context.Context, path string) error {
+	if s.dialect.postgres {
+		return fmt.Errorf("backing up PostgreSQL: %w; use pg_dump", ErrUnsupported)
+	}
+	slog.InfoContext(ctx, "SQL: Executing Backup query", "path", path)
+	if _, err := s.DB.ExecContext(ctx, `VACUUM INTO ?;`, path); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing Backup query failed", "error", err)
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// Restore checks the backup at path and copies it over the database with
+// SQLite's online backup API, in one write transaction, so requests see
+// either the old contents or the restored ones. Connections to the database
+// stay open.
+func (s *SQLiteBookStore) Restore(ctx context.Context, path string) error {
+	if s.dialect.postgres {
+		return fmt.Errorf("restoring PostgreSQL: %w; use pg_restore", ErrUnsupported)
+	}
+	slog.InfoContext(ctx, "SQL: Executing Restore", "path", path)
+	backup := sql.OpenDB(dsnConnector{"file:" + (&url.URL{Path: path}).EscapedPath() + "?mode=ro", &sqlite3.SQLiteDriver{}})
+	defer backup.Close()
+	if err := checkBackup(ctx, backup); err != nil {
+		return err
+	}
+
+	src, err := backup.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer src.Close()
+	dest, err := s.DB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer dest.Close()
+	err = dest.Raw(func(destConn any) error {
+		return src.Raw(func(srcConn any) error {
+			return copyDatabase(sqliteConn(destConn), sqliteConn(srcConn))
+		})
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Restoring backup failed", "error", err)
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+	// Handed back to the pool, which may have no other for CreateSchema
+	dest.Close()
+
+	// A backup from an older version of the server is brought up to date
+	if err := CreateSchema(s.DB); err != nil {
+		return fmt.Errorf("failed to migrate restored database: %w", err)
+	}
+	slog.InfoContext(ctx, "SQL: Restored backup", "path", path)
+	return nil
+}
+
+// checkBackup returns an ErrValidation unless backup is an intact bookshelf
+// database that this version of the server can use.
+func checkBackup(ctx context.Context, backup *sql.DB) error {
+	var result string
+	if err := backup.QueryRowContext(ctx, `PRAGMA integrity_check;`).Scan(&result); err != nil {
+		return invalidf("backup is not a SQLite database: %v", err)
+	}
+	if result != "ok" {
+		return invalidf("backup is corrupt: %s", result)
+	}
+	var books, migrations int
+	if err := backup.QueryRowContext(ctx, `SELECT
+        COUNT(*) FILTER (WHERE name = 'books'), COUNT(*) FILTER (WHERE name = 'schema_migrations')
+        FROM sqlite_master WHERE type = 'table';`).Scan(&books, &migrations); err != nil {
+		return fmt.Errorf("failed to inspect backup: %w", err)
+	}
+	if books == 0 {
+		return invalidf("backup is not a bookshelf database")
+	}
+	if migrations == 0 {
+		return nil // From before versioned migrations, which CreateSchema upgrades
+	}
+	version, err := schemaVersion(ctx, backup)
+	if err != nil {
+		return err
+	}
+	if latest := LatestSchemaVersion(false); version > latest {
+		return invalidf("backup has schema version %d, newer than this server's %d; restore it with a newer version of the server", version, latest)
+	}
+	return nil
+}
+
+// sqliteConn returns the SQLite connection of a driver connection, which
+// may be timed.
+func sqliteConn(conn any) *sqlite3.SQLiteConn {
+	if timed, ok := conn.(*timedConn); ok {
+		conn = timed.Conn
+	}
+	c, _ := conn.(*sqlite3.SQLiteConn)
+	return c
+}
+
+// copyDatabase copies the main database of src over that of dest.
+func copyDatabase(dest, src *sqlite3.SQLiteConn) error {
+	if dest == nil || src == nil {
+		return fmt.Errorf("not a SQLite connection")
+	}
+	backup, err := dest.Backup("main", src, "main")
+	if err != nil {
+		return err
+	}
+	if _, err := backup.Step(-1); err != nil {
+		backup.Finish()
+		return err
+	}
+	return backup.Finish()
+}
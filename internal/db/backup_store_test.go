@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestBackupRestore tests that a backup restores the books it was taken with,
+// and that files which aren't usable backups are rejected.
+func TestBackupRestore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := InitDB(filepath.Join(dir, "books.db"))
+	if err != nil {
+		t.Fatalf("InitDB failed: %v", err)
+	}
+	defer db.Close()
+	store := NewSQLiteBookStore(db)
+
+	kept, err := store.AddBook(ctx, createTestBook())
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	backup := filepath.Join(dir, "backup.db")
+	if err := store.Backup(ctx, backup); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if err := store.Backup(ctx, backup); err == nil {
+		t.Error("Expected a backup over an existing file to fail")
+	}
+	added := createTestBook()
+	added.OpenLibraryID = "OL67890M"
+	if _, err := store.AddBook(ctx, added); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+
+	if err := store.Restore(ctx, backup); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	books, err := store.GetBooks(ctx)
+	if err != nil {
+		t.Fatalf("GetBooks failed: %v", err)
+	}
+	if len(books) != 1 || books[0].ID != kept {
+		t.Errorf("Expected only book %d after restoring, got %+v", kept, books)
+	}
+
+	// A backup from a newer server
+	newer, err := InitDB(filepath.Join(dir, "newer.db"))
+	if err != nil {
+		t.Fatalf("InitDB failed: %v", err)
+	}
+	if _, err := newer.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (999, 'future', CURRENT_TIMESTAMP);`); err != nil {
+		t.Fatalf("Failed to set the schema version: %v", err)
+	}
+	newer.Close()
+	garbage := filepath.Join(dir, "garbage.db")
+	if err := os.WriteFile(garbage, []byte("not a database"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	for _, path := range []string{filepath.Join(dir, "newer.db"), garbage} {
+		if err := store.Restore(ctx, path); !errors.Is(err, ErrValidation) {
+			t.Errorf("Restore(%s): got %v, want ErrValidation", filepath.Base(path), err)
+		}
+	}
+	if books, _ := store.GetBooks(ctx); len(books) != 1 {
+		t.Errorf("Expected a rejected backup to change nothing, got %d books", len(books))
+	}
+
+	if err := NewPostgresBookStore(db).Backup(ctx, filepath.Join(dir, "pg.db")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("PostgreSQL Backup: got %v, want ErrUnsupported", err)
+	}
+}
@@ -1,27 +1,79 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
+	"github.com/ericdahl/bookshelf/internal/logging"
 	"github.com/ericdahl/bookshelf/internal/model"
 )
 
 // BookStore defines the interface for database operations on books.
 type BookStore interface {
-	AddBook(book *model.Book) (int64, error)
-	GetBooks() ([]model.Book, error)
-	GetBookByID(id int64) (*model.Book, error)
-	UpdateBookStatus(id int64, status model.BookStatus) error
-	UpdateBookType(id int64, bookType model.BookType) error
-	UpdateBookDetails(id int64, rating *int, comments *string, series *string, seriesIndex *int) error
-	DeleteBook(id int64) error
+	AddBook(ctx context.Context, book *model.Book) (int64, error)
+	BatchAddBooks(ctx context.Context, books []*model.Book) error
+	GetBooks(ctx context.Context) ([]model.Book, error)
+	GetBooksPage(ctx context.Context, opts ListOptions) ([]model.Book, int, error)
+	GetBookByID(ctx context.Context, id int64) (*model.Book, error)
+	SearchBooks(ctx context.Context, query string) ([]model.Book, error)
+	UpdateBookStatus(ctx context.Context, id int64, status model.BookStatus) error
+	UpdateBook(ctx context.Context, id int64, patch model.BookPatch) error
+	UpdateBookCover(ctx context.Context, id int64, coverURL *string) error
+	DeleteBook(ctx context.Context, id int64) error
+	ActivityStore
+	FollowStore
+	FederationStore
+	CrosspostStore
+	SyncStore
+	PendingMatchStore
+	ExportStore
+	CoverStore
+	TagStore
+	ReadStore
+	DescriptionStore
+	TitleStore
+	AuthorStore
+	StatsStore
+	OnThisDayStore
+	ImportStore
+	MergeStore
+	EventStore
+	QualityStore
+	TrashStore
+	DeletionStore
+	VacationStore
+	PresetStore
+	GoalStore
+	ProgressStore
+	NoteStore
+	QuoteStore
+	LoanStore
+	ValueStore
+	CollectionStore
+	WishlistStore
+	DisposalStore
+	WebhookStore
+	ShareStore
+	PasskeyStore
+	ReadinessStore
+	BackupStore
+	UserStore
 }
 
 // SQLiteBookStore implements the BookStore interface using SQLite.
 type SQLiteBookStore struct {
 	DB *sql.DB
+	// dialect adjusts the few queries written differently for PostgreSQL;
+	// the zero value is SQLite's.
+	dialect dialect
+	// ArchivedInStats counts the reads of archived books in reading stats
+	// and goals, which leave them out by default.
+	ArchivedInStats bool
 }
 
 // NewSQLiteBookStore creates a new SQLiteBookStore.
@@ -29,338 +81,963 @@ func NewSQLiteBookStore(db *sql.DB) *SQLiteBookStore {
 	return &SQLiteBookStore{DB: db}
 }
 
+// bookColumns is the column list shared by every query that loads full book rows
+// (selected FROM books). It must stay in sync with scanBook. Cover placeholders
+// come from the cached image the book points at, the note count from the
+// book's notes and whether it is lent out from its loans.
+const bookColumns = `id, title, author, open_library_id, isbn, status, type, rating, comments, cover_url, series, series_index,
+        edition, course_code, semester, reading_mode, publish_opt_out, comments_spoiler, publish_year, updated_at, cover_hash,
+        date_started, date_finished, description, subtitle, translated, page_count, user_id,
+        source_rating, source_rating_max, source_rating_provider, source, added_at, import_batch_id, deleted_at, favorite, label,
+        current_page, progress_percent, archived, publisher, language, (SELECT COUNT(*) FROM book_notes WHERE book_notes.book_id = books.id),
+        EXISTS (SELECT 1 FROM loans WHERE loans.book_id = books.id AND loans.returned_on IS NULL),
+        (SELECT blurhash FROM cover_images WHERE cover_images.hash = books.cover_hash),
+        (SELECT lqip FROM cover_images WHERE cover_images.hash = books.cover_hash)`
+
+// shelved returns the condition matching the books on the bookshelf of the
+// user ctx is scoped to, leaving out those in the trash, with its arguments.
+// table qualifies the columns of queries that join other tables.
+func shelved(ctx context.Context, table string) (string, []interface{}) {
+	prefix := ""
+	if table != "" {
+		prefix = table + "."
+	}
+	owned, args := ownedBy(ctx, prefix+"user_id")
+	return prefix + "deleted_at IS NULL AND " + owned, args
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanBook scans a row selected with bookColumns into a model.Book,
+// converting nullable columns to pointers.
+func scanBook(row rowScanner) (*model.Book, error) {
+	var book model.Book
+	var rating sql.NullInt64
+	var comments sql.NullString
+	var coverURL sql.NullString
+	var isbn sql.NullString
+	var series sql.NullString
+	var seriesIndex sql.NullInt64
+	var bookType sql.NullString
+	var edition sql.NullInt64
+	var pageCount sql.NullInt64
+	var courseCode sql.NullString
+	var semester sql.NullString
+	var readingMode sql.NullString
+	var publishYear sql.NullInt64
+	var updatedAt sql.NullTime
+	var coverHash sql.NullString
+	var dateStarted, dateFinished sql.NullTime
+	var description sql.NullString
+	var subtitle sql.NullString
+	var coverBlurhash, coverLQIP sql.NullString
+	var userID sql.NullInt64
+	var sourceRating, sourceRatingMax sql.NullFloat64
+	var sourceRatingProvider sql.NullString
+	var source sql.NullString
+	var addedAt sql.NullTime
+	var importBatchID sql.NullInt64
+	var deletedAt sql.NullTime
+	var label sql.NullString
+	var currentPage sql.NullInt64
+	var progressPercent sql.NullFloat64
+	var publisher, language sql.NullString
+
+	if err := row.Scan(&book.ID, &book.Title, &book.Author, &book.OpenLibraryID, &isbn,
+		&book.Status, &bookType, &rating, &comments, &coverURL, &series, &seriesIndex,
+		&edition, &courseCode, &semester, &readingMode, &book.PublishOptOut, &book.CommentsSpoiler, &publishYear, &updatedAt, &coverHash,
+		&dateStarted, &dateFinished, &description, &subtitle, &book.Translated, &pageCount, &userID,
+		&sourceRating, &sourceRatingMax, &sourceRatingProvider, &source, &addedAt, &importBatchID, &deletedAt, &book.Favorite, &label,
+		&currentPage, &progressPercent, &book.Archived, &publisher, &language, &book.NoteCount, &book.LentOut, &coverBlurhash, &coverLQIP); err != nil {
+		return nil, err
+	}
+
+	// Set type, defaulting to "book" if NULL or invalid
+	if bookType.Valid {
+		book.Type = model.BookType(bookType.String)
+	}
+	if !book.Type.IsValid() {
+		book.Type = model.TypeBook
+	}
+
+	// Set reading mode, defaulting to "leisure" if NULL or invalid
+	if readingMode.Valid {
+		book.ReadingMode = model.ReadingMode(readingMode.String)
+	}
+	if !book.ReadingMode.IsValid() {
+		book.ReadingMode = model.ModeLeisure
+	}
+
+	// Convert sql.Null types to pointers
+	if isbn.Valid {
+		book.ISBN = isbn.String
+	}
+	if rating.Valid {
+		r := int(rating.Int64)
+		book.Rating = &r
+	}
+	if comments.Valid {
+		book.Comments = &comments.String
+	}
+	if coverURL.Valid {
+		book.CoverURL = &coverURL.String
+	}
+	if series.Valid {
+		book.Series = &series.String
+	}
+	if seriesIndex.Valid {
+		si := int(seriesIndex.Int64)
+		book.SeriesIndex = &si
+	}
+	if edition.Valid {
+		e := int(edition.Int64)
+		book.Edition = &e
+	}
+	if pageCount.Valid {
+		n := int(pageCount.Int64)
+		book.PageCount = &n
+	}
+	if publisher.Valid {
+		book.Publisher = &publisher.String
+	}
+	if language.Valid {
+		book.Language = &language.String
+	}
+	if courseCode.Valid {
+		book.CourseCode = &courseCode.String
+	}
+	if semester.Valid {
+		book.Semester = &semester.String
+	}
+	if publishYear.Valid {
+		y := int(publishYear.Int64)
+		book.PublishYear = &y
+	}
+	if updatedAt.Valid {
+		book.UpdatedAt = &updatedAt.Time
+	}
+	if coverHash.Valid {
+		book.CoverHash = &coverHash.String
+	}
+	if dateStarted.Valid {
+		book.DateStarted = &dateStarted.Time
+	}
+	if dateFinished.Valid {
+		book.DateFinished = &dateFinished.Time
+	}
+	if description.Valid {
+		book.Description = &description.String
+	}
+	if subtitle.Valid {
+		book.Subtitle = &subtitle.String
+	}
+	if sourceRating.Valid {
+		book.SourceRating = &model.SourceRating{
+			Provider: model.SyncProvider(sourceRatingProvider.String), Value: sourceRating.Float64, Max: sourceRatingMax.Float64,
+		}
+	}
+	if userID.Valid {
+		book.UserID = &userID.Int64
+	}
+	book.Source = model.BookSource(source.String)
+	if addedAt.Valid {
+		book.AddedAt = &addedAt.Time
+	}
+	if importBatchID.Valid {
+		book.ImportBatchID = &importBatchID.Int64
+	}
+	if deletedAt.Valid {
+		book.DeletedAt = &deletedAt.Time
+	}
+	if label.Valid {
+		book.Label = &label.String
+	}
+	if currentPage.Valid {
+		page := int(currentPage.Int64)
+		book.CurrentPage = &page
+	}
+	if progressPercent.Valid {
+		book.ProgressPercent = &progressPercent.Float64
+	}
+	book.CoverBlurhash = coverBlurhash.String
+	book.CoverLQIP = coverLQIP.String
+
+	return &book, nil
+}
+
 // AddBook inserts a new book into the database.
-// It sets the book's ID after successful insertion.
-func (s *SQLiteBookStore) AddBook(book *model.Book) (int64, error) {
+// It sets the book's ID after successful insertion. A book added with a
+// finish date starts its reading history with that read.
+func (s *SQLiteBookStore) AddBook(ctx context.Context, book *model.Book) (int64, error) {
+	if _, err := s.addBooks(ctx, []*model.Book{book}, nil); err != nil {
+		return 0, err
+	}
+	return book.ID, nil
+}
+
+// BatchAddBooks inserts books like AddBook, all in one transaction: either
+// every book is added or, if one fails, none is. The error names the first
+// book that failed, counting from 1.
+func (s *SQLiteBookStore) BatchAddBooks(ctx context.Context, books []*model.Book) error {
+	slog.InfoContext(ctx, "SQL: Executing BatchAddBooks", "count", len(books))
+	failed, err := s.addBooks(ctx, books, nil)
+	if err != nil && failed >= 0 {
+		return fmt.Errorf("book %d (%q): %w", failed+1, books[failed].Title, err)
+	}
+	return err
+}
+
+// prepareBook applies defaults to a book about to be added and validates it.
+func prepareBook(book *model.Book) error {
 	// Default status if not provided (though handler should ensure it)
 	if book.Status == "" {
 		book.Status = model.StatusWantToRead // Or Currently Reading as per initial request? Let's stick to Want to Read for now.
 	} else if !book.Status.IsValid() {
-		return 0, fmt.Errorf("invalid status: %s", book.Status)
+		return invalidf("invalid status: %s", book.Status)
 	}
 
 	if err := book.Validate(); err != nil {
-		return 0, fmt.Errorf("validation failed: %w", err)
+		return &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
+	}
+	book.Description = cleanDescription(book.Description)
+	// An empty subtitle keeps a title with a colon whole
+	if book.Subtitle == nil {
+		book.SplitSubtitle()
+	} else if strings.TrimSpace(*book.Subtitle) == "" {
+		book.Subtitle = nil
+	}
+	return nil
+}
+
+// findDuplicateBook returns a DuplicateBookError if userID already has a book
+// with the Open Library ID or ISBN of book, as the unique indexes would. Books
+// in the trash count, since restoring them would bring the duplicate back.
+func findDuplicateBook(ctx context.Context, db execer, book *model.Book, userID *int64) error {
+	var ownerID int64
+	if userID != nil {
+		ownerID = *userID
+	}
+	for _, key := range []struct{ field, value string }{{"open_library_id", book.OpenLibraryID}, {"isbn", book.ISBN}} {
+		if key.value == "" {
+			continue
+		}
+		var id int64
+		var trashed bool
+		err := db.QueryRowContext(ctx, `SELECT id, deleted_at IS NOT NULL FROM books WHERE COALESCE(user_id, 0) = ? AND `+key.field+` = ?
+            ORDER BY id LIMIT 1;`, ownerID, key.value).Scan(&id, &trashed)
+		if err == nil {
+			return &DuplicateBookError{ExistingID: id, Field: key.field, Value: key.value, Trashed: trashed}
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to look for duplicate book: %w", err)
+		}
+	}
+	return nil
+}
+
+// sourceValue returns the source to store for a book, NULL when it is unknown.
+func sourceValue(source model.BookSource) interface{} {
+	if source == "" {
+		return nil
+	}
+	return string(source)
+}
+
+// addBooks inserts books in one transaction and records their activity once
+// it commits. In a dry run the books are validated and inserted, but not
+// committed, and get no ID. With a batch, the batch is created in the same transaction and
+// the books become part of it. On failure it returns the index of the book
+// that failed, or -1 when the failure is not down to one book, and no book
+// gets an ID.
+func (s *SQLiteBookStore) addBooks(ctx context.Context, books []*model.Book, batch *model.ImportBatch) (int, error) {
+	for i, book := range books {
+		if err := prepareBook(book); err != nil {
+			return i, err
+		}
 	}
 
 	query := `
-        INSERT INTO books (title, author, open_library_id, isbn, status, type, rating, comments, cover_url)
-        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+        INSERT INTO books (title, author, open_library_id, isbn, status, type, rating, comments, cover_url,
+            series, series_index, edition, course_code, semester, reading_mode, publish_opt_out, comments_spoiler, publish_year, updated_at, cover_hash,
+            date_started, date_finished, description, subtitle, translated, page_count, user_id, source, added_at, import_batch_id, label,
+            publisher, language)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+        RETURNING id;
     `
-	slog.Info("SQL: Executing AddBook query",
-		"title", book.Title,
-		"author", book.Author,
-		"openLibraryID", book.OpenLibraryID,
-		"isbn", book.ISBN,
-		"status", book.Status,
-		"type", book.Type,
-		"rating", book.Rating,
-		"comments", book.Comments,
-		"coverURL", book.CoverURL)
-	stmt, err := s.DB.Prepare(query)
+	updatedAt := time.Now().UTC()
+	userID := owner(ctx)
+	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
-		slog.Error("SQL Error: Preparing AddBook statement failed", "error", err)
-		return 0, fmt.Errorf("failed to prepare insert statement: %w", err)
+		return -1, fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	res, err := stmt.Exec(book.Title, book.Author, book.OpenLibraryID, book.ISBN, book.Status, book.Type, book.Rating, book.Comments, book.CoverURL)
-	if err != nil {
-		slog.Error("SQL Error: Executing AddBook statement failed", "error", err)
-		// Consider checking for UNIQUE constraint violation specifically
-		return 0, fmt.Errorf("failed to execute insert statement: %w", err)
+	var batchID *int64
+	if batch != nil {
+		if err := insertImportBatch(ctx, tx, batch, userID); err != nil {
+			return -1, err
+		}
+		batchID = &batch.ID
 	}
 
-	id, err := res.LastInsertId()
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
-		slog.Error("SQL Error: Failed to get last insert ID", "error", err)
-		return 0, fmt.Errorf("failed to retrieve last insert ID: %w", err)
+		slog.ErrorContext(ctx, "SQL Error: Preparing AddBook statement failed", "error", err)
+		return -1, fmt.Errorf("failed to prepare insert statement: %w", err)
 	}
-	book.ID = id // Set the ID on the original struct
-	slog.Info("SQL: Successfully added book", "id", id)
-	return id, nil
+	defer stmt.Close()
+
+	ids := make([]int64, len(books))
+	for i, book := range books {
+		slog.InfoContext(ctx, "SQL: Executing AddBook query",
+			"title", book.Title,
+			"subtitle", book.Subtitle,
+			"author", book.Author,
+			"openLibraryID", book.OpenLibraryID,
+			"isbn", book.ISBN,
+			"status", book.Status,
+			"type", book.Type,
+			"rating", book.Rating,
+			logging.Redact("comments", book.Comments),
+			"coverURL", book.CoverURL,
+			"series", book.Series,
+			"seriesIndex", book.SeriesIndex,
+			"edition", book.Edition,
+			"pageCount", book.PageCount,
+			"publisher", book.Publisher,
+			"language", book.Language,
+			"courseCode", book.CourseCode,
+			"semester", book.Semester,
+			"readingMode", book.ReadingMode,
+			"publishYear", book.PublishYear,
+			"dateStarted", book.DateStarted,
+			"dateFinished", book.DateFinished,
+			"source", book.Source)
+		if err := findDuplicateBook(ctx, tx, book, userID); err != nil {
+			return i, err
+		}
+		inBatch := book.ImportBatchID
+		if batchID != nil {
+			inBatch = batchID
+		}
+		var id int64
+		err := stmt.QueryRowContext(ctx, book.Title, book.Author, book.OpenLibraryID, book.ISBN, book.Status, book.Type, book.Rating, book.Comments, book.CoverURL,
+			book.Series, book.SeriesIndex, book.Edition, book.CourseCode, book.Semester, book.ReadingMode,
+			book.PublishOptOut, book.CommentsSpoiler, book.PublishYear, updatedAt, book.CoverHash,
+			utcTime(book.DateStarted), utcTime(book.DateFinished), book.Description, book.Subtitle, book.Translated, book.PageCount, userID,
+			sourceValue(book.Source), updatedAt, inBatch, book.Label, book.Publisher, book.Language).Scan(&id)
+		if err != nil {
+			slog.ErrorContext(ctx, "SQL Error: Executing AddBook statement failed", "error", err)
+			return i, fmt.Errorf("failed to execute insert statement: %w", classify(err))
+		}
+		if err := linkBookAuthors(ctx, tx, id, book.Author); err != nil {
+			return i, err
+		}
+		if book.DateFinished != nil {
+			if err := insertRead(ctx, tx, &model.Read{BookID: id, DateStarted: book.DateStarted, DateFinished: *book.DateFinished}); err != nil {
+				return i, err
+			}
+		}
+		if err := recordBookEvent(ctx, tx, &model.BookEvent{BookID: id, Kind: model.EventCreated}); err != nil {
+			return i, err
+		}
+		ids[i] = id
+	}
+	if err := commit(ctx, tx); err != nil {
+		return -1, fmt.Errorf("failed to commit book: %w", err)
+	}
+	if IsDryRun(ctx) {
+		if batch != nil {
+			batch.ID = 0
+		}
+		return 0, nil
+	}
+	for i, book := range books {
+		book.ID = ids[i] // Set the ID on the original struct
+		book.UserID = userID
+		book.UpdatedAt, book.AddedAt = &updatedAt, &updatedAt
+		if batchID != nil {
+			book.ImportBatchID = batchID
+		}
+		slog.InfoContext(ctx, "SQL: Successfully added book", "id", book.ID)
+		s.recordBookActivity(ctx, model.ActivityBookAdded, book)
+	}
+	return 0, nil
 }
 
 // GetBooks retrieves all books from the database.
-func (s *SQLiteBookStore) GetBooks() ([]model.Book, error) {
-	query := `SELECT id, title, author, open_library_id, isbn, status, type, rating, comments, cover_url, series, series_index FROM books ORDER BY title;`
-	slog.Info("SQL: Executing GetBooks query")
+func (s *SQLiteBookStore) GetBooks(ctx context.Context) ([]model.Book, error) {
+	owned, args := shelved(ctx, "")
+	query := `SELECT ` + bookColumns + ` FROM books WHERE ` + owned + ` ORDER BY title, subtitle;`
+	slog.InfoContext(ctx, "SQL: Executing GetBooks query")
 
-	rows, err := s.DB.Query(query)
+	rows, err := s.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		slog.Error("SQL Error: Executing GetBooks query failed", "error", err)
+		slog.ErrorContext(ctx, "SQL Error: Executing GetBooks query failed", "error", err)
 		return nil, fmt.Errorf("failed to query books: %w", err)
 	}
 	defer rows.Close()
 
 	books := []model.Book{}
 	for rows.Next() {
-		var book model.Book
-		// Ensure pointers are used for nullable fields
-		var rating sql.NullInt64
-		var comments sql.NullString
-		var coverURL sql.NullString
-		var isbn sql.NullString
-		var series sql.NullString
-		var seriesIndex sql.NullInt64
-		var bookType sql.NullString
-
-		if err := rows.Scan(&book.ID, &book.Title, &book.Author, &book.OpenLibraryID, &isbn, 
-			&book.Status, &bookType, &rating, &comments, &coverURL, &series, &seriesIndex); err != nil {
-			slog.Error("SQL Error: Scanning book row failed", "error", err)
+		book, err := scanBook(rows)
+		if err != nil {
+			slog.ErrorContext(ctx, "SQL Error: Scanning book row failed", "error", err)
 			return nil, fmt.Errorf("failed to scan book row: %w", err)
 		}
-		
-		// Set type, defaulting to "book" if NULL or invalid
-		if bookType.Valid {
-			book.Type = model.BookType(bookType.String)
-		}
-		if !book.Type.IsValid() {
-			book.Type = model.TypeBook
-		}
-
-		// Convert sql.Null types to pointers
-		if isbn.Valid {
-			book.ISBN = isbn.String
-		}
-		if rating.Valid {
-			r := int(rating.Int64)
-			book.Rating = &r
-		}
-		if comments.Valid {
-			book.Comments = &comments.String
-		}
-		if coverURL.Valid {
-			book.CoverURL = &coverURL.String
-		}
-		if series.Valid {
-			book.Series = &series.String
-		}
-		if seriesIndex.Valid {
-			si := int(seriesIndex.Int64)
-			book.SeriesIndex = &si
-		}
-
-		books = append(books, book)
+		books = append(books, *book)
 	}
 
 	if err = rows.Err(); err != nil {
-		slog.Error("SQL Error: Error during row iteration", "error", err)
+		slog.ErrorContext(ctx, "SQL Error: Error during row iteration", "error", err)
 		return nil, fmt.Errorf("error iterating book rows: %w", err)
 	}
 
-	slog.Info("SQL: Retrieved books", "count", len(books))
+	slog.InfoContext(ctx, "SQL: Retrieved books", "count", len(books))
 	return books, nil
 }
 
-// GetBookByID retrieves a single book by its ID.
-func (s *SQLiteBookStore) GetBookByID(id int64) (*model.Book, error) {
-	query := `SELECT id, title, author, open_library_id, isbn, status, type, rating, comments, cover_url, series, series_index FROM books WHERE id = ?;`
-	slog.Info("SQL: Executing GetBookByID query", "id", id)
+// SortField names a column books can be listed by.
+type SortField string
 
-	row := s.DB.QueryRow(query, id)
+const (
+	SortTitle  SortField = "title"
+	SortAuthor SortField = "author"
+	SortRating SortField = "rating"
+	SortAdded  SortField = "added" // Insertion order
+)
 
-	var book model.Book
-	var rating sql.NullInt64
-	var comments sql.NullString
-	var coverURL sql.NullString
-	var isbn sql.NullString
-	var series sql.NullString
-	var seriesIndex sql.NullInt64
-	var bookType sql.NullString
+// orderBy maps each sort field to its ORDER BY expression. Unrated books sort
+// last in either direction; ties are broken by ID so pages are stable.
+var orderBy = map[SortField]string{
+	SortTitle:  "title %s, subtitle %[1]s, id %[1]s",
+	SortAuthor: "author %s, title, subtitle, id",
+	SortRating: "rating IS NULL, rating %s, title, subtitle, id",
+	SortAdded:  "id %s",
+}
 
-	err := row.Scan(&book.ID, &book.Title, &book.Author, &book.OpenLibraryID, &isbn, 
-		&book.Status, &bookType, &rating, &comments, &coverURL, &series, &seriesIndex)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			slog.Info("SQL: No book found", "id", id)
-			return nil, fmt.Errorf("book with ID %d not found", id) // Consider a specific error type (e.g., ErrNotFound)
+// IsValid checks if the sort field is one of the supported fields.
+func (f SortField) IsValid() bool {
+	_, ok := orderBy[f]
+	return ok
+}
+
+// BookFilter selects books by their fields. Zero values match every book.
+type BookFilter struct {
+	Status     model.BookStatus
+	Type       model.BookType
+	Author     string // Case-insensitive substring of the author
+	AuthorID   int64  // ID of an author the book names
+	MinRating  int    // Only books rated at least this; unrated books never match
+	Tag        string // Name of a tag the book must have, ignoring case
+	Collection int64  // ID of a collection the book must be in
+	Reread     *bool  // Only books read more than once (true) or at most once (false)
+	Source     model.BookSource
+	// AddedAfter and AddedBefore bound when the book was added; books from
+	// before this was recorded never match.
+	AddedAfter, AddedBefore *time.Time
+	Missing                 string // A field the book lacks, see MissingFields
+	Series                  string // Name of the book's series, ignoring case
+	Favorite                *bool  // Only favorites (true) or only other books (false)
+	Label                   string // The book's label, see model.NormalizeLabel
+	Archived                ArchivedFilter
+}
+
+// ArchivedFilter selects books by whether they are archived. Archived books
+// are hidden unless asked for.
+type ArchivedFilter string
+
+const (
+	ArchivedHidden   ArchivedFilter = ""        // Only books that aren't archived
+	ArchivedOnly     ArchivedFilter = "only"    // Only archived books
+	ArchivedIncluded ArchivedFilter = "include" // Books whether archived or not
+)
+
+// MissingFields maps the fields BookFilter.Missing accepts to the condition
+// matching the books that lack them.
+var MissingFields = map[string]string{
+	"isbn":       "(isbn IS NULL OR isbn = '')",
+	"cover":      "(cover_url IS NULL OR cover_url = '')",
+	"page_count": "page_count IS NULL",
+	"rating":     "rating IS NULL",
+}
+
+// where builds the WHERE clause for the filter, with its arguments. Only the
+// books of the user ctx is scoped to match.
+func (f BookFilter) where(ctx context.Context, d dialect) (string, []interface{}) {
+	owned, args := shelved(ctx, "")
+	conds := []string{owned}
+	if f.Status != "" {
+		conds = append(conds, "status = ?")
+		args = append(args, f.Status)
+	}
+	if f.Type != "" {
+		conds = append(conds, "type = ?")
+		args = append(args, f.Type)
+	}
+	if f.Author != "" {
+		conds = append(conds, "author "+d.like()+` ? ESCAPE '\'`)
+		args = append(args, "%"+likeEscaper.Replace(f.Author)+"%")
+	}
+	if f.AuthorID != 0 {
+		conds = append(conds, "id IN (SELECT book_id FROM book_authors WHERE author_id = ?)")
+		args = append(args, f.AuthorID)
+	}
+	if f.MinRating > 0 {
+		conds = append(conds, "rating >= ?")
+		args = append(args, f.MinRating)
+	}
+	if f.Tag != "" {
+		conds = append(conds, "id IN (SELECT book_id FROM book_tags JOIN tags ON tags.id = book_tags.tag_id WHERE tags.name = ?)")
+		args = append(args, model.NormalizeTagName(f.Tag))
+	}
+	if f.Collection != 0 {
+		conds = append(conds, "id IN (SELECT book_id FROM collection_books WHERE collection_id = ?)")
+		args = append(args, f.Collection)
+	}
+	if f.Reread != nil {
+		op := "<="
+		if *f.Reread {
+			op = ">"
 		}
-		slog.Error("SQL Error: Scanning book row failed", "id", id, "error", err)
-		return nil, fmt.Errorf("failed to scan book row for ID %d: %w", id, err)
+		conds = append(conds, "(SELECT COUNT(*) FROM reads WHERE reads.book_id = books.id) "+op+" 1")
 	}
-	
-	// Set type, defaulting to "book" if NULL or invalid
-	if bookType.Valid {
-		book.Type = model.BookType(bookType.String)
+	if f.Source != "" {
+		conds = append(conds, "source = ?")
+		args = append(args, f.Source)
 	}
-	if !book.Type.IsValid() {
-		book.Type = model.TypeBook
+	if f.AddedAfter != nil {
+		conds = append(conds, "added_at >= ?")
+		args = append(args, f.AddedAfter.UTC())
 	}
-
-	// Convert sql.Null types to pointers
-	if isbn.Valid {
-		book.ISBN = isbn.String
+	if f.AddedBefore != nil {
+		conds = append(conds, "added_at < ?")
+		args = append(args, f.AddedBefore.UTC())
 	}
-	if rating.Valid {
-		r := int(rating.Int64)
-		book.Rating = &r
+	if cond, ok := MissingFields[f.Missing]; ok {
+		conds = append(conds, cond)
 	}
-	if comments.Valid {
-		book.Comments = &comments.String
+	if f.Series != "" {
+		conds = append(conds, "LOWER(series) = LOWER(?)")
+		args = append(args, f.Series)
 	}
-	if coverURL.Valid {
-		book.CoverURL = &coverURL.String
+	if f.Label != "" {
+		conds = append(conds, "label = ?")
+		args = append(args, model.NormalizeLabel(f.Label))
 	}
-	if series.Valid {
-		book.Series = &series.String
+	if f.Favorite != nil {
+		conds = append(conds, "favorite = ?")
+		args = append(args, *f.Favorite)
 	}
-	if seriesIndex.Valid {
-		si := int(seriesIndex.Int64)
-		book.SeriesIndex = &si
+	switch f.Archived {
+	case ArchivedHidden:
+		conds = append(conds, "archived = ?")
+		args = append(args, false)
+	case ArchivedOnly:
+		conds = append(conds, "archived = ?")
+		args = append(args, true)
 	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
 
-	slog.Info("SQL: Retrieved book", "id", id)
-	return &book, nil
+// likeEscaper escapes the LIKE wildcards in a literal search term.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ListOptions controls filtering, paging and ordering of GetBooksPage.
+type ListOptions struct {
+	Filter BookFilter
+	Limit  int       // Maximum number of books to return; 0 returns all
+	Offset int       // Number of books to skip
+	Sort   SortField // Defaults to SortTitle
+	Desc   bool      // Sort descending
+	// FavoritesFirst lists favorites ahead of the other books, each in the
+	// requested order.
+	FavoritesFirst bool
 }
 
-// UpdateBookStatus updates the status of a specific book.
-func (s *SQLiteBookStore) UpdateBookStatus(id int64, status model.BookStatus) error {
-	if !status.IsValid() {
-		return fmt.Errorf("invalid status provided: %s", status)
+// GetBooksPage retrieves one page of the books matching the filter in the
+// requested order, together with the total number of matching books.
+func (s *SQLiteBookStore) GetBooksPage(ctx context.Context, opts ListOptions) ([]model.Book, int, error) {
+	if opts.Sort == "" {
+		opts.Sort = SortTitle
+	}
+	if !opts.Sort.IsValid() {
+		return nil, 0, invalidf("invalid sort field: %s", opts.Sort)
+	}
+	if opts.Limit < 0 || opts.Offset < 0 {
+		return nil, 0, invalidf("limit and offset must not be negative")
+	}
+	direction := "ASC"
+	if opts.Desc {
+		direction = "DESC"
 	}
 
-	query := `UPDATE books SET status = ? WHERE id = ?;`
-	slog.Info("SQL: Executing UpdateBookStatus query", "status", status, "id", id)
-
-	stmt, err := s.DB.Prepare(query)
-	if err != nil {
-		slog.Error("SQL Error: Preparing UpdateBookStatus statement failed", "error", err)
-		return fmt.Errorf("failed to prepare update status statement: %w", err)
+	where, args := opts.Filter.where(ctx, s.dialect)
+	var total int
+	if err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM books`+where+`;`, args...).Scan(&total); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Counting books failed", "error", err)
+		return nil, 0, fmt.Errorf("failed to count books: %w", err)
 	}
-	defer stmt.Close()
 
-	res, err := stmt.Exec(status, id)
-	if err != nil {
-		slog.Error("SQL Error: Executing UpdateBookStatus statement failed", "error", err)
-		return fmt.Errorf("failed to execute update status statement: %w", err)
+	var limit interface{} = opts.Limit
+	if opts.Limit == 0 {
+		limit = s.dialect.noLimit()
 	}
+	order := fmt.Sprintf(orderBy[opts.Sort], direction)
+	if opts.FavoritesFirst {
+		order = "favorite DESC, " + order
+	}
+	query := `SELECT ` + bookColumns + ` FROM books` + where + ` ORDER BY ` + order + ` LIMIT ? OFFSET ?;`
+	slog.InfoContext(ctx, "SQL: Executing GetBooksPage query", "filter", opts.Filter, "limit", opts.Limit, "offset", opts.Offset, "sort", opts.Sort, "desc", opts.Desc,
+		"favoritesFirst", opts.FavoritesFirst)
 
-	rowsAffected, err := res.RowsAffected()
+	rows, err := s.DB.QueryContext(ctx, query, append(args, limit, opts.Offset)...)
 	if err != nil {
-		slog.Error("SQL Error: Failed to get rows affected for UpdateBookStatus", "error", err)
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		slog.ErrorContext(ctx, "SQL Error: Executing GetBooksPage query failed", "error", err)
+		return nil, 0, fmt.Errorf("failed to query books: %w", err)
 	}
+	defer rows.Close()
 
-	if rowsAffected == 0 {
-		slog.Info("SQL: No book found to update status", "id", id)
-		return fmt.Errorf("book with ID %d not found", id) // Consider ErrNotFound
+	books := []model.Book{}
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			slog.ErrorContext(ctx, "SQL Error: Scanning book row failed", "error", err)
+			return nil, 0, fmt.Errorf("failed to scan book row: %w", err)
+		}
+		books = append(books, *book)
+	}
+	if err = rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Error during row iteration", "error", err)
+		return nil, 0, fmt.Errorf("error iterating book rows: %w", err)
 	}
 
-	slog.Info("SQL: Successfully updated status for book", "id", id)
-	return nil
+	slog.InfoContext(ctx, "SQL: Retrieved book page", "count", len(books), "total", total)
+	return books, total, nil
 }
 
-// UpdateBookType updates the type of a specific book.
-func (s *SQLiteBookStore) UpdateBookType(id int64, bookType model.BookType) error {
-	if !bookType.IsValid() {
-		return fmt.Errorf("invalid book type provided: %s", bookType)
-	}
+// GetBookByID retrieves a single book by its ID.
+func (s *SQLiteBookStore) GetBookByID(ctx context.Context, id int64) (*model.Book, error) {
+	owned, args := shelved(ctx, "")
+	query := `SELECT ` + bookColumns + ` FROM books WHERE id = ? AND ` + owned + `;`
+	slog.InfoContext(ctx, "SQL: Executing GetBookByID query", "id", id)
 
-	query := `UPDATE books SET type = ? WHERE id = ?;`
-	slog.Info("SQL: Executing UpdateBookType query", "type", bookType, "id", id)
+	row := s.DB.QueryRowContext(ctx, query, append([]interface{}{id}, args...)...)
 
-	stmt, err := s.DB.Prepare(query)
+	book, err := scanBook(row)
 	if err != nil {
-		slog.Error("SQL Error: Preparing UpdateBookType statement failed", "error", err)
-		return fmt.Errorf("failed to prepare update type statement: %w", err)
+		if err == sql.ErrNoRows {
+			slog.InfoContext(ctx, "SQL: No book found", "id", id)
+			return nil, fmt.Errorf("book with ID %d %w", id, ErrNotFound)
+		}
+		slog.ErrorContext(ctx, "SQL Error: Scanning book row failed", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to scan book row for ID %d: %w", id, err)
 	}
-	defer stmt.Close()
 
-	res, err := stmt.Exec(bookType, id)
+	slog.InfoContext(ctx, "SQL: Retrieved book", "id", id)
+	return book, nil
+}
+
+// UpdateBookStatus updates the status of a specific book. Moving a book to
+// Currently Reading starts a new read, stamping date_started; moving it to Read
+// stamps date_finished and adds the read to the book's reading history.
+func (s *SQLiteBookStore) UpdateBookStatus(ctx context.Context, id int64, status model.BookStatus) error {
+	if !status.IsValid() {
+		return invalidf("invalid status provided: %s", status)
+	}
+	return s.UpdateBook(ctx, id, model.BookPatch{Status: model.Some(status)})
+}
+
+// UpdateBook changes the fields of a book that patch sets and leaves the rest
+// as they are. A status change updates the reading dates and history the same
+// way as UpdateBookStatus, and a new cover URL detaches the cached copy of the
+// previous cover.
+func (s *SQLiteBookStore) UpdateBook(ctx context.Context, id int64, patch model.BookPatch) error {
+	if err := patch.Validate(); err != nil {
+		return &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
+	}
+
+	slog.InfoContext(ctx, "SQL: Executing UpdateBook query", "id", id)
+	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
-		slog.Error("SQL Error: Executing UpdateBookType statement failed", "error", err)
-		return fmt.Errorf("failed to execute update type statement: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := res.RowsAffected()
+	owned, ownerArgs := shelved(ctx, "")
+	book, err := scanBook(tx.QueryRowContext(ctx, `SELECT `+bookColumns+` FROM books WHERE id = ? AND `+owned+`;`,
+		append([]interface{}{id}, ownerArgs...)...))
+	if err == sql.ErrNoRows {
+		slog.InfoContext(ctx, "SQL: No book found to update", "id", id)
+		return fmt.Errorf("book with ID %d %w", id, ErrNotFound)
+	}
 	if err != nil {
-		slog.Error("SQL Error: Failed to get rows affected for UpdateBookType", "error", err)
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		slog.ErrorContext(ctx, "SQL Error: Loading book for UpdateBook failed", "error", err)
+		return fmt.Errorf("failed to load book: %w", err)
+	}
+	if patch.IsEmpty() {
+		return nil
+	}
+	previous := *book
+	if err := patch.Apply(book); err != nil {
+		return &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
 	}
 
-	if rowsAffected == 0 {
-		slog.Info("SQL: No book found to update type", "id", id)
-		return fmt.Errorf("book with ID %d not found", id)
+	var sets []string
+	var args []interface{}
+	set := func(column string, value interface{}) {
+		sets = append(sets, column+" = ?")
+		args = append(args, value)
+	}
+	if patch.Title.Set {
+		set("title", book.Title)
+	}
+	if patch.Title.Set || patch.Subtitle.Set {
+		// A new title may carry its own subtitle
+		set("subtitle", book.Subtitle)
+	}
+	if patch.Author.Set {
+		set("author", book.Author)
+	}
+	if patch.ISBN.Set {
+		set("isbn", book.ISBN)
+	}
+	if patch.Type.Set {
+		set("type", book.Type)
+	}
+	if patch.Rating.Set {
+		set("rating", book.Rating)
+	}
+	if patch.Comments.Set {
+		set("comments", book.Comments)
+	}
+	if patch.Description.Set {
+		set("description", cleanDescription(book.Description))
+	}
+	if patch.CoverURL.Set {
+		set("cover_url", book.CoverURL)
+		if previous.CoverURL == nil || book.CoverURL == nil || *previous.CoverURL != *book.CoverURL {
+			sets = append(sets, "cover_hash = NULL")
+		}
+	}
+	if patch.Series.Set || patch.SeriesIndex.Set {
+		set("series", book.Series)
+		set("series_index", book.SeriesIndex)
+	}
+	if patch.PublishYear.Set {
+		set("publish_year", book.PublishYear)
+	}
+	if patch.Edition.Set {
+		set("edition", book.Edition)
+	}
+	if patch.PageCount.Set {
+		set("page_count", book.PageCount)
+	}
+	if patch.Publisher.Set {
+		set("publisher", book.Publisher)
+	}
+	if patch.Language.Set {
+		set("language", book.Language)
+	}
+	if patch.CourseCode.Set {
+		set("course_code", book.CourseCode)
+	}
+	if patch.Semester.Set {
+		set("semester", book.Semester)
+	}
+	if patch.ReadingMode.Set {
+		set("reading_mode", book.ReadingMode)
+	}
+	if patch.PublishOptOut.Set {
+		set("publish_opt_out", book.PublishOptOut)
+	}
+	if patch.CommentsSpoiler.Set {
+		set("comments_spoiler", book.CommentsSpoiler)
+	}
+	if patch.Translated.Set {
+		set("translated", book.Translated)
+	}
+	if patch.Favorite.Set {
+		set("favorite", book.Favorite)
+	}
+	if patch.Label.Set {
+		set("label", book.Label)
+	}
+	if patch.Archived.Set {
+		set("archived", book.Archived)
+	}
+	if patch.SourceRating.Set {
+		if source := book.SourceRating; source != nil {
+			set("source_rating", source.Value)
+			set("source_rating_max", source.Max)
+			set("source_rating_provider", source.Provider)
+		} else {
+			sets = append(sets, "source_rating = NULL", "source_rating_max = NULL", "source_rating_provider = NULL")
+		}
 	}
 
-	slog.Info("SQL: Successfully updated type for book", "id", id)
-	return nil
-}
+	now := time.Now().UTC()
+	if patch.Status.Set {
+		set("status", book.Status)
+		if book.Status != previous.Status {
+			// Progress belongs to a read, and a new status ends or starts one
+			sets = append(sets, "current_page = NULL", "progress_percent = NULL")
+			switch book.Status {
+			case model.StatusCurrentlyReading:
+				set("date_started", now)
+				set("date_finished", nil)
+			case model.StatusRead:
+				// A start date left over from an earlier, finished read doesn't belong to this one
+				read := &model.Read{BookID: id, DateFinished: now}
+				if previous.DateStarted != nil && previous.DateFinished == nil {
+					read.DateStarted = previous.DateStarted
+				}
+				set("date_started", read.DateStarted)
+				set("date_finished", now)
+				if err := insertRead(ctx, tx, read); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	set("updated_at", now)
 
-// UpdateBookDetails updates the rating, comments, series info of a specific book.
-// It handles NULL values correctly.
-func (s *SQLiteBookStore) UpdateBookDetails(id int64, rating *int, comments *string, series *string, seriesIndex *int) error {
-	// Validate rating if provided
-	if rating != nil && (*rating < 1 || *rating > 10) {
-		return fmt.Errorf("rating must be between 1 and 10")
+	query := `UPDATE books SET ` + strings.Join(sets, ", ") + ` WHERE id = ?;`
+	if _, err := tx.ExecContext(ctx, query, append(args, id)...); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing UpdateBook statement failed", "error", err)
+		return fmt.Errorf("failed to execute update book statement: %w", classify(err))
+	}
+	if patch.Author.Set {
+		if err := linkBookAuthors(ctx, tx, id, book.Author); err != nil {
+			return err
+		}
+	}
+	updated, err := s.getBookTx(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if err := recordBookUpdate(ctx, tx, &previous, updated); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit book update: %w", err)
 	}
 
-	query := `UPDATE books SET rating = ?, comments = ?, series = ?, series_index = ? WHERE id = ?;`
-	slog.Info("SQL: Executing UpdateBookDetails query", "rating", rating, "comments", comments, "series", series, "seriesIndex", seriesIndex, "id", id)
+	slog.InfoContext(ctx, "SQL: Successfully updated book", "id", id, "fields", len(sets)-1)
 
-	stmt, err := s.DB.Prepare(query)
-	if err != nil {
-		slog.Error("SQL Error: Preparing UpdateBookDetails statement failed", "error", err)
-		return fmt.Errorf("failed to prepare update details statement: %w", err)
+	if patch.Status.Set {
+		if book, err := s.GetBookByID(ctx, id); err == nil {
+			kind := model.ActivityStatusChanged
+			if book.Status == model.StatusRead {
+				kind = model.ActivityBookFinished
+			}
+			s.recordBookActivity(ctx, kind, book)
+		}
 	}
-	defer stmt.Close()
+	return nil
+}
 
-	// Handle potential nil values for parameters when passing to Exec
-	var sqlRating interface{}
-	if rating != nil {
-		sqlRating = *rating
-	} else {
-		sqlRating = nil // This will be translated to NULL by the driver
+// UpdateBookCover replaces the cover image URL of a specific book. Any cached
+// copy of the previous cover is detached so the new one gets cached.
+func (s *SQLiteBookStore) UpdateBookCover(ctx context.Context, id int64, coverURL *string) error {
+	slog.InfoContext(ctx, "SQL: Executing UpdateBookCover query", "coverURL", coverURL, "id", id)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	var sqlComments interface{}
-	if comments != nil {
-		sqlComments = *comments
-	} else {
-		sqlComments = nil // This will be translated to NULL by the driver
+	book, err := s.getBookTx(ctx, tx, id)
+	if err != nil {
+		slog.InfoContext(ctx, "SQL: No book found to update cover", "id", id)
+		return err
+	}
+	query := `UPDATE books SET cover_url = ?, cover_hash = NULL, updated_at = ? WHERE id = ?;`
+	if _, err := tx.ExecContext(ctx, query, coverURL, time.Now().UTC(), id); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing UpdateBookCover statement failed", "error", err)
+		return fmt.Errorf("failed to execute update cover statement: %w", classify(err))
 	}
-	
-	var sqlSeries interface{}
-	if series != nil {
-		sqlSeries = *series
-	} else {
-		sqlSeries = nil
+	updated := *book
+	updated.CoverURL = coverURL
+	if err := recordBookUpdate(ctx, tx, book, &updated); err != nil {
+		return err
 	}
-	
-	var sqlSeriesIndex interface{}
-	if seriesIndex != nil {
-		sqlSeriesIndex = *seriesIndex
-	} else {
-		sqlSeriesIndex = nil
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit cover update: %w", err)
 	}
 
-	res, err := stmt.Exec(sqlRating, sqlComments, sqlSeries, sqlSeriesIndex, id)
+	slog.InfoContext(ctx, "SQL: Successfully updated cover for book", "id", id)
+	return nil
+}
+
+// DeleteBook moves a book to the trash, where RestoreBook can bring it back,
+// leaving a tombstone so differential exports can report the deletion. Its
+// tags and reading history are kept until PurgeBook deletes it for good.
+func (s *SQLiteBookStore) DeleteBook(ctx context.Context, id int64) error {
+	book, err := s.GetBookByID(ctx, id)
 	if err != nil {
-		slog.Error("SQL Error: Executing UpdateBookDetails statement failed", "error", err)
-		return fmt.Errorf("failed to execute update details statement: %w", err)
+		return err
 	}
 
-	rowsAffected, err := res.RowsAffected()
+	slog.InfoContext(ctx, "SQL: Executing DeleteBook statement", "id", id)
+	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
-		slog.Error("SQL Error: Failed to get rows affected for UpdateBookDetails", "error", err)
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-
-	if rowsAffected == 0 {
-		slog.Info("SQL: No book found to update details", "id", id)
-		return fmt.Errorf("book with ID %d not found", id) // Consider ErrNotFound
+	defer tx.Rollback()
+	now := time.Now().UTC()
+	if _, err := tx.ExecContext(ctx, `UPDATE books SET deleted_at = ?, updated_at = ? WHERE id = ?;`, now, now, id); err != nil {
+		return fmt.Errorf("failed to move book to the trash: %w", err)
 	}
-
-	slog.Info("SQL: Successfully updated details for book", "id", id)
-	return nil
+	if err := recordTombstone(ctx, tx, book); err != nil {
+		return err
+	}
+	if err := recordBookEvent(ctx, tx, &model.BookEvent{BookID: id, Kind: model.EventDeleted}); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// DeleteBook removes a book from the database by its ID.
-func (s *SQLiteBookStore) DeleteBook(id int64) error {
-	query := `DELETE FROM books WHERE id = ?;`
+// deleteBook deletes a book with its tags, reading history and notes, and
+// leaves a tombstone for differential exports.
+func deleteBook(ctx context.Context, tx execer, book *model.Book) error {
+	id := book.ID
+	// Foreign keys may be off (they are per connection), so what belongs to the book is removed explicitly
+	if _, err := tx.ExecContext(ctx, `DELETE FROM book_tags WHERE book_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to untag book: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM collection_books WHERE book_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to remove book from collections: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM book_authors WHERE book_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to unlink book authors: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM reads WHERE book_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to delete reading history: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM reading_progress WHERE book_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to delete reading progress: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM book_notes WHERE book_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to delete notes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM quotes WHERE book_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to delete quotes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM loans WHERE book_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to delete loans: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM gift_claims WHERE book_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to delete gift claims: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE disposals SET book_id = NULL WHERE book_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to unlink disposals: %w", err)
+	}
 
-	result, err := s.DB.Exec(query, id)
+	result, err := tx.ExecContext(ctx, `DELETE FROM books WHERE id = ?;`, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete book: %w", err)
 	}
@@ -371,8 +1048,19 @@ func (s *SQLiteBookStore) DeleteBook(id int64) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("book with ID %d not found", id)
+		return fmt.Errorf("book with ID %d %w", id, ErrNotFound)
 	}
 
+	return recordTombstone(ctx, tx, book)
+}
+
+// recordTombstone leaves a tombstone for a deleted book, for differential exports.
+func recordTombstone(ctx context.Context, tx execer, book *model.Book) error {
+	if _, err := tx.ExecContext(ctx, `INSERT INTO book_tombstones (book_id, open_library_id, title, deleted_at, user_id) VALUES (?, ?, ?, ?, ?)
+        ON CONFLICT(book_id) DO UPDATE SET open_library_id = excluded.open_library_id, title = excluded.title,
+            deleted_at = excluded.deleted_at, user_id = excluded.user_id;`,
+		book.ID, book.OpenLibraryID, book.Title, time.Now().UTC(), book.UserID); err != nil {
+		return fmt.Errorf("failed to record book deletion: %w", classify(err))
+	}
 	return nil
 }
@@ -1,12 +1,17 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/ericdahl/bookshelf/internal/model"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 // setupTestDB creates a new in-memory SQLite database for testing
@@ -32,8 +37,13 @@ func teardownTestDB(db *sql.DB) {
 	db.Close()
 }
 
+// testBooks counts the books made by createTestBook, each of which gets its own
+// ISBN since books may not share one.
+var testBooks int
+
 // createTestBook returns a sample book for testing
 func createTestBook() *model.Book {
+	testBooks++
 	comments := "Test comments"
 	rating := 8
 	coverURL := "http://example.com/cover.jpg"
@@ -41,7 +51,7 @@ func createTestBook() *model.Book {
 		Title:         "Test Book",
 		Author:        "Test Author",
 		OpenLibraryID: "OL12345M",
-		ISBN:          "9781234567890",
+		ISBN:          fmt.Sprintf("978%010d", testBooks),
 		Status:        model.StatusWantToRead,
 		Rating:        &rating,
 		Comments:      &comments,
@@ -51,11 +61,12 @@ func createTestBook() *model.Book {
 
 // TestAddBook tests adding a book to the database
 func TestAddBook(t *testing.T) {
+	ctx := context.Background()
 	db, store := setupTestDB(t)
 	defer teardownTestDB(db)
 
 	book := createTestBook()
-	id, err := store.AddBook(book)
+	id, err := store.AddBook(ctx, book)
 	if err != nil {
 		t.Fatalf("AddBook failed: %v", err)
 	}
@@ -71,7 +82,7 @@ func TestAddBook(t *testing.T) {
 	// Test adding book with invalid status
 	invalidBook := createTestBook()
 	invalidBook.Status = "Invalid Status"
-	_, err = store.AddBook(invalidBook)
+	_, err = store.AddBook(ctx, invalidBook)
 	if err == nil {
 		t.Errorf("Expected error when adding book with invalid status")
 	}
@@ -81,27 +92,103 @@ func TestAddBook(t *testing.T) {
 	invalidBook = createTestBook()
 	invalidBook.OpenLibraryID = "OL67890M" // Different ID to avoid uniqueness constraint
 	invalidBook.Rating = &invalidRating
-	_, err = store.AddBook(invalidBook)
+	_, err = store.AddBook(ctx, invalidBook)
 	if err == nil {
 		t.Errorf("Expected error when adding book with invalid rating")
 	}
 
 	// Test uniqueness constraint
 	duplicateBook := createTestBook()
-	_, err = store.AddBook(duplicateBook)
-	if err == nil {
-		t.Errorf("Expected error when adding book with duplicate OpenLibraryID")
+	_, err = store.AddBook(ctx, duplicateBook)
+	var dup *DuplicateBookError
+	if !errors.As(err, &dup) || !errors.Is(err, ErrDuplicate) || dup.ExistingID != book.ID || dup.Field != "open_library_id" {
+		t.Errorf("Expected a duplicate OpenLibraryID pointing at book %d, got %v", book.ID, err)
+	}
+	sameISBN := createTestBook()
+	sameISBN.OpenLibraryID, sameISBN.ISBN = "OL54321M", book.ISBN
+	_, err = store.AddBook(ctx, sameISBN)
+	if !errors.As(err, &dup) || dup.ExistingID != book.ID || dup.Field != "isbn" {
+		t.Errorf("Expected a duplicate ISBN pointing at book %d, got %v", book.ID, err)
+	}
+	// Books without an ISBN don't clash
+	for _, id := range []string{"OL1M", "OL2M"} {
+		noISBN := createTestBook()
+		noISBN.OpenLibraryID, noISBN.ISBN = id, ""
+		if _, err := store.AddBook(ctx, noISBN); err != nil {
+			t.Errorf("Adding a book without an ISBN failed: %v", err)
+		}
+	}
+}
+
+// TestBatchAddBooks tests that a batch is added in one transaction
+func TestBatchAddBooks(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	finished := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	batch := make([]*model.Book, 3)
+	for i := range batch {
+		batch[i] = createTestBook()
+		batch[i].OpenLibraryID = fmt.Sprintf("OLBATCH%dM", i)
+	}
+	batch[2].DateFinished = &finished
+	batch[2].Status = model.StatusRead
+	if err := store.BatchAddBooks(ctx, batch); err != nil {
+		t.Fatalf("BatchAddBooks failed: %v", err)
+	}
+	for _, book := range batch {
+		if book.ID <= 0 {
+			t.Errorf("Expected an ID for %s, got %d", book.OpenLibraryID, book.ID)
+		}
+	}
+	if reads, _ := store.GetReads(ctx, batch[2].ID); len(reads) != 1 {
+		t.Errorf("Expected the finished book's read to be logged, got %d reads", len(reads))
+	}
+
+	// A duplicate halfway through rolls the whole batch back
+	failing := []*model.Book{createTestBook(), createTestBook()}
+	failing[0].OpenLibraryID = "OLNEW1M"
+	failing[1].OpenLibraryID = batch[0].OpenLibraryID
+	err := store.BatchAddBooks(ctx, failing)
+	if !errors.Is(err, ErrDuplicate) || !strings.Contains(err.Error(), "book 2") {
+		t.Errorf("Expected ErrDuplicate naming book 2, got %v", err)
+	}
+	if failing[0].ID != 0 {
+		t.Errorf("Expected no ID after a rollback, got %d", failing[0].ID)
+	}
+	books, _ := store.GetBooks(ctx)
+	if len(books) != 3 {
+		t.Errorf("Expected the failed batch to add nothing, got %d books", len(books))
+	}
+
+	invalid := []*model.Book{createTestBook()}
+	invalid[0].Status = "Invalid Status"
+	if err := store.BatchAddBooks(ctx, invalid); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation, got %v", err)
+	}
+	// A dry run fails as the batch would, and otherwise adds nothing
+	failing[0].Status = ""
+	if err := store.BatchAddBooks(WithDryRun(ctx), failing); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected a dry run to find the duplicate, got %v", err)
+	}
+	if err := store.BatchAddBooks(WithDryRun(ctx), failing[:1]); err != nil || failing[0].ID != 0 || failing[0].Status != model.StatusWantToRead {
+		t.Errorf("Expected a dry run to apply defaults without an ID, got %+v, %v", failing[0], err)
+	}
+	if books, _ := store.GetBooks(ctx); len(books) != 3 {
+		t.Errorf("Expected a dry run to add nothing, got %d books", len(books))
 	}
 }
 
 // TestGetBooks tests retrieving all books from the database
 func TestGetBooks(t *testing.T) {
+	ctx := context.Background()
 	db, store := setupTestDB(t)
 	defer teardownTestDB(db)
 
 	// Add test books
 	book1 := createTestBook()
-	_, err := store.AddBook(book1)
+	_, err := store.AddBook(ctx, book1)
 	if err != nil {
 		t.Fatalf("Failed to add test book 1: %v", err)
 	}
@@ -110,13 +197,13 @@ func TestGetBooks(t *testing.T) {
 	book2.Title = "Test Book 2"
 	book2.OpenLibraryID = "OL67890M"
 	book2.Status = model.StatusCurrentlyReading
-	_, err = store.AddBook(book2)
+	_, err = store.AddBook(ctx, book2)
 	if err != nil {
 		t.Fatalf("Failed to add test book 2: %v", err)
 	}
 
 	// Test GetBooks
-	books, err := store.GetBooks()
+	books, err := store.GetBooks(ctx)
 	if err != nil {
 		t.Fatalf("GetBooks failed: %v", err)
 	}
@@ -124,22 +211,343 @@ func TestGetBooks(t *testing.T) {
 	if len(books) != 2 {
 		t.Errorf("Expected 2 books, got %d", len(books))
 	}
+
+	// A canceled context stops the query
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := store.GetBooks(canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetBooks with a canceled context: expected context.Canceled, got %v", err)
+	}
+}
+
+// TestGetBooksPage tests paging and sorting books
+func TestGetBooksPage(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	ratings := map[string]int{"Charlie": 9, "Alpha": 5}
+	for i, title := range []string{"Charlie", "Alpha", "Bravo"} {
+		book := createTestBook()
+		book.Title = title
+		book.Author = "Author " + string(rune('Z'-i))
+		book.OpenLibraryID = "OLPAGE" + title
+		book.Rating = nil
+		if r, ok := ratings[title]; ok {
+			book.Rating = &r
+		}
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("Failed to add %s: %v", title, err)
+		}
+	}
+
+	titles := func(books []model.Book) string {
+		var out []string
+		for _, b := range books {
+			out = append(out, b.Title)
+		}
+		return strings.Join(out, ",")
+	}
+
+	for _, tc := range []struct {
+		opts ListOptions
+		want string
+	}{
+		{ListOptions{}, "Alpha,Bravo,Charlie"},
+		{ListOptions{Limit: 2}, "Alpha,Bravo"},
+		{ListOptions{Limit: 2, Offset: 2}, "Charlie"},
+		{ListOptions{Sort: SortTitle, Desc: true}, "Charlie,Bravo,Alpha"},
+		{ListOptions{Sort: SortAuthor}, "Bravo,Alpha,Charlie"},
+		{ListOptions{Sort: SortRating, Desc: true}, "Charlie,Alpha,Bravo"},
+		{ListOptions{Sort: SortRating}, "Alpha,Charlie,Bravo"},
+		{ListOptions{Sort: SortAdded, Desc: true, Limit: 1}, "Bravo"},
+	} {
+		books, total, err := store.GetBooksPage(ctx, tc.opts)
+		if err != nil {
+			t.Fatalf("GetBooksPage(%+v) failed: %v", tc.opts, err)
+		}
+		if got := titles(books); got != tc.want || total != 3 {
+			t.Errorf("GetBooksPage(%+v) = %s (total %d), want %s (total 3)", tc.opts, got, total, tc.want)
+		}
+	}
+
+	if _, _, err := store.GetBooksPage(ctx, ListOptions{Sort: "isbn"}); err == nil {
+		t.Error("Expected an error for an unsupported sort field")
+	}
+}
+
+// TestGetBooksPageFilter tests filtering books in SQL
+func TestGetBooksPageFilter(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	for _, b := range []struct {
+		title, author string
+		status        model.BookStatus
+		bookType      model.BookType
+		rating        int
+	}{
+		{"Dune", "Frank Herbert", model.StatusRead, model.TypeAudiobook, 9},
+		{"Emma", "Jane Austen", model.StatusRead, model.TypeBook, 7},
+		{"Persuasion", "Jane Austen", model.StatusWantToRead, model.TypeAudiobook, 0},
+		{"Odd", "100%_Real", model.StatusRead, model.TypeBook, 8},
+	} {
+		book := createTestBook()
+		book.Title, book.Author, book.Status, book.Type = b.title, b.author, b.status, b.bookType
+		book.OpenLibraryID = "OLFILTER" + b.title
+		book.Rating = nil
+		if b.rating > 0 {
+			r := b.rating
+			book.Rating = &r
+		}
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("Failed to add %s: %v", b.title, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		filter BookFilter
+		want   string
+	}{
+		{BookFilter{}, "Dune,Emma,Odd,Persuasion"},
+		{BookFilter{Status: model.StatusRead, Type: model.TypeAudiobook, MinRating: 8}, "Dune"},
+		{BookFilter{Author: "austen"}, "Emma,Persuasion"},
+		{BookFilter{MinRating: 8}, "Dune,Odd"},
+		{BookFilter{Author: "%_"}, "Odd"},
+		{BookFilter{Type: model.TypeAudiobook, MinRating: 1}, "Dune"},
+	} {
+		books, total, err := store.GetBooksPage(ctx, ListOptions{Filter: tc.filter})
+		if err != nil {
+			t.Fatalf("GetBooksPage(%+v) failed: %v", tc.filter, err)
+		}
+		var titles []string
+		for _, b := range books {
+			titles = append(titles, b.Title)
+		}
+		if got := strings.Join(titles, ","); got != tc.want || total != len(books) {
+			t.Errorf("Filter %+v = %s (total %d), want %s", tc.filter, got, total, tc.want)
+		}
+	}
+
+	// The total counts every match, not just the page
+	books, total, err := store.GetBooksPage(ctx, ListOptions{Filter: BookFilter{Status: model.StatusRead}, Limit: 1})
+	if err != nil || len(books) != 1 || total != 3 {
+		t.Errorf("Expected 1 of 3 read books, got %d of %d (%v)", len(books), total, err)
+	}
+}
+
+func TestGetBooksPageFilterSource(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	before := time.Now().Add(-time.Second)
+	for i, source := range []model.BookSource{model.BookSourceGoodreads, model.BookSourceGoodreads, model.BookSourceManual, ""} {
+		book := createTestBook()
+		book.OpenLibraryID = fmt.Sprintf("OLSOURCE%dM", i)
+		book.Source = source
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+		if book.AddedAt == nil || book.AddedAt.Before(before) {
+			t.Errorf("Expected the time the book was added, got %v", book.AddedAt)
+		}
+	}
+	if _, err := store.AddBook(ctx, &model.Book{Title: "Bad", Author: "A", OpenLibraryID: "OLBAD", Source: "amazon"}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected an unknown source to be invalid, got %v", err)
+	}
+
+	after := time.Now().Add(time.Second)
+	for _, tc := range []struct {
+		filter BookFilter
+		want   int
+	}{
+		{BookFilter{Source: model.BookSourceGoodreads}, 2},
+		{BookFilter{Source: model.BookSourceGoodreads, AddedAfter: &before, AddedBefore: &after}, 2},
+		{BookFilter{Source: model.BookSourceGoodreads, AddedAfter: &after}, 0},
+		{BookFilter{AddedBefore: &before}, 0},
+		{BookFilter{Source: model.BookSourceISBNScan}, 0},
+	} {
+		books, _, err := store.GetBooksPage(ctx, ListOptions{Filter: tc.filter})
+		if err != nil || len(books) != tc.want {
+			t.Errorf("Filter %+v: expected %d books, got %d (%v)", tc.filter, tc.want, len(books), err)
+		}
+		for _, book := range books {
+			if book.Source != tc.filter.Source || book.AddedAt == nil {
+				t.Errorf("Filter %+v: got book from %q added %v", tc.filter, book.Source, book.AddedAt)
+			}
+		}
+	}
+}
+
+// TestGetBooksPageFavorites tests filtering and sorting by the favorite flag
+func TestGetBooksPageFavorites(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	for _, title := range []string{"Alpha", "Bravo", "Charlie"} {
+		book := createTestBook()
+		book.Title = title
+		book.OpenLibraryID = "OLFAVORITE" + title
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("Failed to add %s: %v", title, err)
+		}
+		if title == "Bravo" {
+			if err := store.UpdateBook(ctx, book.ID, model.BookPatch{Favorite: model.Some(true)}); err != nil {
+				t.Fatalf("Failed to favorite %s: %v", title, err)
+			}
+			if got, _ := store.GetBookByID(ctx, book.ID); got == nil || !got.Favorite {
+				t.Errorf("Expected %s to be a favorite, got %+v", title, got)
+			}
+		}
+	}
+
+	yes, no := true, false
+	for _, tc := range []struct {
+		opts ListOptions
+		want string
+	}{
+		{ListOptions{Filter: BookFilter{Favorite: &yes}}, "Bravo"},
+		{ListOptions{Filter: BookFilter{Favorite: &no}}, "Alpha,Charlie"},
+		{ListOptions{FavoritesFirst: true}, "Bravo,Alpha,Charlie"},
+		{ListOptions{FavoritesFirst: true, Desc: true}, "Bravo,Charlie,Alpha"},
+	} {
+		books, _, err := store.GetBooksPage(ctx, tc.opts)
+		if err != nil {
+			t.Fatalf("GetBooksPage(%+v) failed: %v", tc.opts, err)
+		}
+		var titles []string
+		for _, b := range books {
+			titles = append(titles, b.Title)
+		}
+		if got := strings.Join(titles, ","); got != tc.want {
+			t.Errorf("GetBooksPage(%+v) = %s, want %s", tc.opts, got, tc.want)
+		}
+	}
+}
+
+// TestGetBooksPageLabel tests setting labels and filtering by them
+func TestArchivedBooks(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	finished := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var ids []int64
+	for _, title := range []string{"Kept", "Sold"} {
+		book := createTestBook()
+		book.Title, book.OpenLibraryID = title, "OLARCHIVE"+title
+		book.Status, book.DateFinished = model.StatusRead, &finished
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("Failed to add %s: %v", title, err)
+		}
+		ids = append(ids, book.ID)
+	}
+	if err := store.UpdateBook(ctx, ids[1], model.BookPatch{Archived: model.Some(true)}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+
+	list := func(archived ArchivedFilter) []model.Book {
+		books, _, err := store.GetBooksPage(ctx, ListOptions{Filter: BookFilter{Archived: archived}})
+		if err != nil {
+			t.Fatalf("GetBooksPage(%q) failed: %v", archived, err)
+		}
+		return books
+	}
+	if books := list(ArchivedHidden); len(books) != 1 || books[0].ID != ids[0] {
+		t.Errorf("Expected the archived book hidden by default, got %+v", books)
+	}
+	if books := list(ArchivedOnly); len(books) != 1 || books[0].ID != ids[1] || !books[0].Archived {
+		t.Errorf("Expected only the archived book, got %+v", books)
+	}
+	if books := list(ArchivedIncluded); len(books) != 2 {
+		t.Errorf("Expected both books, got %d", len(books))
+	}
+	if reads, _ := store.GetReads(ctx, ids[1]); len(reads) != 1 {
+		t.Errorf("Expected the archived book to keep its reading history, got %+v", reads)
+	}
+
+	stats, err := store.GetReadingStats(ctx, 2024)
+	if err != nil || stats.Books != 1 {
+		t.Errorf("Expected the archived book left out of stats, got %+v, %v", stats, err)
+	}
+	store.ArchivedInStats = true
+	if stats, _ := store.GetReadingStats(ctx, 2024); stats.Books != 2 {
+		t.Errorf("Expected the archived book counted when configured, got %d books", stats.Books)
+	}
+}
+
+func TestGetBooksPageLabel(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	labels := map[string]string{"Alpha": "📚", "Bravo": "#FA0", "Charlie": ""}
+	for _, title := range []string{"Alpha", "Bravo", "Charlie"} {
+		book := createTestBook()
+		book.Title = title
+		book.OpenLibraryID = "OLLABEL" + title
+		label := labels[title]
+		book.Label = &label
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("Failed to add %s: %v", title, err)
+		}
+	}
+	bad := "sci-fi"
+	if _, err := store.AddBook(ctx, &model.Book{Title: "Bad", Author: "A", OpenLibraryID: "OLBAD", Label: &bad}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a label that is neither an emoji nor a color to be invalid, got %v", err)
+	}
+
+	count := func(label string) int {
+		books, _, err := store.GetBooksPage(ctx, ListOptions{Filter: BookFilter{Label: label}})
+		if err != nil {
+			t.Fatalf("GetBooksPage(%q) failed: %v", label, err)
+		}
+		return len(books)
+	}
+	if n := count("📚"); n != 1 {
+		t.Errorf("Expected 1 book labelled 📚, got %d", n)
+	}
+	if n := count("#ffaa00"); n != 1 {
+		t.Errorf("Expected the short color to match its long form, got %d", n)
+	}
+
+	books, _, _ := store.GetBooksPage(ctx, ListOptions{Filter: BookFilter{Label: "📚"}})
+	id := books[0].ID
+	if err := store.UpdateBook(ctx, id, model.BookPatch{Label: model.Some("#FA0")}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+	if n := count("#fa0"); n != 2 {
+		t.Errorf("Expected 2 orange books, got %d", n)
+	}
+	if err := store.UpdateBook(ctx, id, model.BookPatch{Label: model.Optional[string]{Set: true}}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+	if book, _ := store.GetBookByID(ctx, id); book == nil || book.Label != nil {
+		t.Errorf("Expected the label to be cleared, got %+v", book)
+	}
+	if err := store.UpdateBook(ctx, id, model.BookPatch{Label: model.Some("fiction")}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected an invalid label to be rejected, got %v", err)
+	}
 }
 
 // TestGetBookByID tests retrieving a specific book by ID
 func TestGetBookByID(t *testing.T) {
+	ctx := context.Background()
 	db, store := setupTestDB(t)
 	defer teardownTestDB(db)
 
 	// Add a test book
 	book := createTestBook()
-	id, err := store.AddBook(book)
+	id, err := store.AddBook(ctx, book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
 
 	// Test getting the book by ID
-	retrievedBook, err := store.GetBookByID(id)
+	retrievedBook, err := store.GetBookByID(ctx, id)
 	if err != nil {
 		t.Fatalf("GetBookByID failed: %v", err)
 	}
@@ -173,7 +581,7 @@ func TestGetBookByID(t *testing.T) {
 	}
 
 	// Test getting non-existent book
-	_, err = store.GetBookByID(999)
+	_, err = store.GetBookByID(ctx, 999)
 	if err == nil {
 		t.Errorf("Expected error when getting non-existent book")
 	}
@@ -181,24 +589,25 @@ func TestGetBookByID(t *testing.T) {
 
 // TestUpdateBookStatus tests updating a book's status
 func TestUpdateBookStatus(t *testing.T) {
+	ctx := context.Background()
 	db, store := setupTestDB(t)
 	defer teardownTestDB(db)
 
 	// Add a test book
 	book := createTestBook()
-	id, err := store.AddBook(book)
+	id, err := store.AddBook(ctx, book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
 
 	// Test updating status
-	err = store.UpdateBookStatus(id, model.StatusCurrentlyReading)
+	err = store.UpdateBookStatus(ctx, id, model.StatusCurrentlyReading)
 	if err != nil {
 		t.Fatalf("UpdateBookStatus failed: %v", err)
 	}
 
 	// Verify the update
-	updatedBook, err := store.GetBookByID(id)
+	updatedBook, err := store.GetBookByID(ctx, id)
 	if err != nil {
 		t.Fatalf("Failed to get book after update: %v", err)
 	}
@@ -208,13 +617,13 @@ func TestUpdateBookStatus(t *testing.T) {
 	}
 
 	// Test updating with invalid status
-	err = store.UpdateBookStatus(id, "Invalid Status")
+	err = store.UpdateBookStatus(ctx, id, "Invalid Status")
 	if err == nil {
 		t.Errorf("Expected error when updating with invalid status")
 	}
 
 	// Test updating non-existent book
-	err = store.UpdateBookStatus(999, model.StatusRead)
+	err = store.UpdateBookStatus(ctx, 999, model.StatusRead)
 	if err == nil {
 		t.Errorf("Expected error when updating non-existent book")
 	}
@@ -222,12 +631,13 @@ func TestUpdateBookStatus(t *testing.T) {
 
 // TestUpdateBookDetails tests updating a book's rating and comments
 func TestUpdateBookDetails(t *testing.T) {
+	ctx := context.Background()
 	db, store := setupTestDB(t)
 	defer teardownTestDB(db)
 
 	// Add a test book
 	book := createTestBook()
-	id, err := store.AddBook(book)
+	id, err := store.AddBook(ctx, book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -235,15 +645,13 @@ func TestUpdateBookDetails(t *testing.T) {
 	// Test updating details
 	newRating := 10
 	newComments := "Updated comments"
-	var series *string
-	var seriesIndex *int
-	err = store.UpdateBookDetails(id, &newRating, &newComments, series, seriesIndex)
+	err = store.UpdateBook(ctx, id, model.BookPatch{Rating: model.Some(newRating), Comments: model.Some(newComments)})
 	if err != nil {
-		t.Fatalf("UpdateBookDetails failed: %v", err)
+		t.Fatalf("UpdateBook failed: %v", err)
 	}
 
 	// Verify the update
-	updatedBook, err := store.GetBookByID(id)
+	updatedBook, err := store.GetBookByID(ctx, id)
 	if err != nil {
 		t.Fatalf("Failed to get book after update: %v", err)
 	}
@@ -256,13 +664,13 @@ func TestUpdateBookDetails(t *testing.T) {
 	}
 
 	// Test clearing details (setting to null)
-	err = store.UpdateBookDetails(id, nil, nil, nil, nil)
+	err = store.UpdateBook(ctx, id, model.BookPatch{Rating: model.Null[int](), Comments: model.Null[string]()})
 	if err != nil {
-		t.Fatalf("UpdateBookDetails with nil values failed: %v", err)
+		t.Fatalf("UpdateBook with null values failed: %v", err)
 	}
 
 	// Verify nulls were set
-	updatedBook, err = store.GetBookByID(id)
+	updatedBook, err = store.GetBookByID(ctx, id)
 	if err != nil {
 		t.Fatalf("Failed to get book after update: %v", err)
 	}
@@ -276,45 +684,276 @@ func TestUpdateBookDetails(t *testing.T) {
 
 	// Test with invalid rating
 	invalidRating := 11
-	err = store.UpdateBookDetails(id, &invalidRating, nil, nil, nil)
+	err = store.UpdateBook(ctx, id, model.BookPatch{Rating: model.Some(invalidRating)})
 	if err == nil {
 		t.Errorf("Expected error when updating with invalid rating")
 	}
 
 	// Test updating non-existent book
-	err = store.UpdateBookDetails(999, &newRating, &newComments, nil, nil)
+	err = store.UpdateBook(ctx, 999, model.BookPatch{Rating: model.Some(newRating), Comments: model.Some(newComments)})
 	if err == nil {
 		t.Errorf("Expected error when updating non-existent book")
 	}
 }
 
+// TestUpdateBookPartial tests that a patch only changes the fields it sets
+func TestUpdateBookPartial(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	series, index := "Dune", 1
+	book.Series, book.SeriesIndex = &series, &index
+	id, err := store.AddBook(ctx, book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE books SET cover_hash = 'abc' WHERE id = ?`, id); err != nil {
+		t.Fatalf("Failed to set cover hash: %v", err)
+	}
+
+	// The same cover URL keeps the cached copy
+	if err := store.UpdateBook(ctx, id, model.BookPatch{Title: model.Some(" Dune "), CoverURL: model.Of(book.CoverURL)}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+	got, _ := store.GetBookByID(ctx, id)
+	if got.Title != "Dune" || got.Author != book.Author || *got.Rating != *book.Rating || *got.Comments != *book.Comments ||
+		*got.SeriesIndex != 1 || got.CoverHash == nil || got.Status != model.StatusWantToRead {
+		t.Errorf("Unexpected book after changing the title: %+v", got)
+	}
+
+	// Clearing the series clears the position; a new cover detaches the cached one
+	if err := store.UpdateBook(ctx, id, model.BookPatch{Series: model.Null[string](), CoverURL: model.Some("http://example.com/new.jpg")}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+	got, _ = store.GetBookByID(ctx, id)
+	if got.Series != nil || got.SeriesIndex != nil || got.CoverHash != nil || *got.CoverURL != "http://example.com/new.jpg" || got.Rating == nil {
+		t.Errorf("Unexpected book after clearing the series: %+v", got)
+	}
+
+	// A status change logs the read like UpdateBookStatus
+	if err := store.UpdateBook(ctx, id, model.BookPatch{Status: model.Some(model.StatusRead), Rating: model.Some(9)}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+	got, _ = store.GetBookByID(ctx, id)
+	reads, _ := store.GetReads(ctx, id)
+	if got.Status != model.StatusRead || got.DateFinished == nil || *got.Rating != 9 || len(reads) != 1 {
+		t.Errorf("Unexpected book after finishing it: %+v, %d reads", got, len(reads))
+	}
+
+	// A new title is split unless the subtitle is given too
+	if err := store.UpdateBook(ctx, id, model.BookPatch{Title: model.Some("Dune: Deluxe Edition")}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+	if got, _ = store.GetBookByID(ctx, id); got.Title != "Dune" || got.Subtitle == nil || *got.Subtitle != "Deluxe Edition" {
+		t.Errorf("Expected the new title split, got %q / %v", got.Title, got.Subtitle)
+	}
+	if err := store.UpdateBook(ctx, id, model.BookPatch{Title: model.Some("Star Wars: Thrawn"), Subtitle: model.Null[string]()}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+	if got, _ = store.GetBookByID(ctx, id); got.Title != "Star Wars: Thrawn" || got.Subtitle != nil {
+		t.Errorf("Expected the title kept whole, got %q / %v", got.Title, got.Subtitle)
+	}
+
+	for name, patch := range map[string]model.BookPatch{
+		"clear title":           {Title: model.Null[string]()},
+		"blank title":           {Title: model.Some("  ")},
+		"clear status":          {Status: model.Null[model.BookStatus]()},
+		"invalid type":          {Type: model.Some[model.BookType]("scroll")},
+		"position, no series":   {SeriesIndex: model.Some(2)},
+		"zero position":         {Series: model.Some("Dune"), SeriesIndex: model.Some(0)},
+		"series cleared, index": {Series: model.Some(""), SeriesIndex: model.Some(2)},
+	} {
+		if err := store.UpdateBook(ctx, id, patch); !errors.Is(err, ErrValidation) {
+			t.Errorf("%s: expected ErrValidation, got %v", name, err)
+		}
+	}
+	if err := store.UpdateBook(ctx, 999, model.BookPatch{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Empty patch of a missing book: expected ErrNotFound, got %v", err)
+	}
+}
+
 // TestDeleteBook tests deleting a book from the database
 func TestDeleteBook(t *testing.T) {
+	ctx := context.Background()
 	db, store := setupTestDB(t)
 	defer teardownTestDB(db)
 
 	// Add a test book
 	book := createTestBook()
-	id, err := store.AddBook(book)
+	id, err := store.AddBook(ctx, book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
 
 	// Test deleting the book
-	err = store.DeleteBook(id)
+	err = store.DeleteBook(ctx, id)
 	if err != nil {
 		t.Fatalf("DeleteBook failed: %v", err)
 	}
 
 	// Verify the book was deleted
-	_, err = store.GetBookByID(id)
+	_, err = store.GetBookByID(ctx, id)
 	if err == nil {
 		t.Errorf("Expected error when getting deleted book")
 	}
 
 	// Test deleting non-existent book
-	err = store.DeleteBook(999)
+	err = store.DeleteBook(ctx, 999)
 	if err == nil {
 		t.Errorf("Expected error when deleting non-existent book")
 	}
-}
\ No newline at end of file
+}
+
+// TestUpdateBookStudyInfo tests updating a book's textbook fields
+func TestUpdateBookStudyInfo(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	id, err := store.AddBook(ctx, book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+
+	// New books default to leisure reading
+	if book.ReadingMode != model.ModeLeisure {
+		t.Errorf("Expected default reading mode %s, got %s", model.ModeLeisure, book.ReadingMode)
+	}
+
+	edition := 2
+	courseCode := "MATH 201"
+	semester := "Spring 2026"
+	err = store.UpdateBook(ctx, id, model.BookPatch{
+		Edition:     model.Some(edition),
+		CourseCode:  model.Some(courseCode),
+		Semester:    model.Some(semester),
+		ReadingMode: model.Some(model.ModeReference),
+	})
+	if err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+
+	updatedBook, err := store.GetBookByID(ctx, id)
+	if err != nil {
+		t.Fatalf("Failed to get book after update: %v", err)
+	}
+	if updatedBook.ReadingMode != model.ModeReference {
+		t.Errorf("Expected reading mode %s, got %s", model.ModeReference, updatedBook.ReadingMode)
+	}
+	if !reflect.DeepEqual(updatedBook.Edition, &edition) {
+		t.Errorf("Expected edition %d, got %v", edition, updatedBook.Edition)
+	}
+	if !reflect.DeepEqual(updatedBook.CourseCode, &courseCode) {
+		t.Errorf("Expected course code %s, got %v", courseCode, updatedBook.CourseCode)
+	}
+	if !reflect.DeepEqual(updatedBook.Semester, &semester) {
+		t.Errorf("Expected semester %s, got %v", semester, updatedBook.Semester)
+	}
+
+	// Test with invalid reading mode
+	err = store.UpdateBook(ctx, id, model.BookPatch{ReadingMode: model.Some[model.ReadingMode]("skimming")})
+	if err == nil {
+		t.Errorf("Expected error when updating with invalid reading mode")
+	}
+
+	// Test updating non-existent book
+	err = store.UpdateBook(ctx, 999, model.BookPatch{ReadingMode: model.Some(model.ModeLeisure)})
+	if err == nil {
+		t.Errorf("Expected error when updating non-existent book")
+	}
+}
+
+// TestCreateSchemaAddsMissingColumns tests that tables from older versions are upgraded in place
+func TestCreateSchemaAddsMissingColumns(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	// Original schema without type, series or textbook columns
+	_, err = db.Exec(`CREATE TABLE books (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        title TEXT NOT NULL,
+        author TEXT NOT NULL,
+        open_library_id TEXT NOT NULL UNIQUE,
+        isbn TEXT,
+        status TEXT NOT NULL,
+        rating INTEGER,
+        comments TEXT,
+        cover_url TEXT
+    );
+    INSERT INTO books (title, author, open_library_id, status) VALUES ('Old Book', 'Old Author', 'OLOLD1M', 'Read');`)
+	if err != nil {
+		t.Fatalf("Failed to create legacy schema: %v", err)
+	}
+
+	if err := CreateSchema(db); err != nil {
+		t.Fatalf("CreateSchema failed on legacy schema: %v", err)
+	}
+
+	store := NewSQLiteBookStore(db)
+	books, err := store.GetBooks(context.Background())
+	if err != nil {
+		t.Fatalf("GetBooks failed after upgrade: %v", err)
+	}
+	if len(books) != 1 {
+		t.Fatalf("Expected 1 book, got %d", len(books))
+	}
+	if books[0].Type != model.TypeBook {
+		t.Errorf("Expected default type %s, got %s", model.TypeBook, books[0].Type)
+	}
+	if books[0].ReadingMode != model.ModeLeisure {
+		t.Errorf("Expected default reading mode %s, got %s", model.ModeLeisure, books[0].ReadingMode)
+	}
+
+	// Running it again must be a no-op
+	if err := CreateSchema(db); err != nil {
+		t.Fatalf("CreateSchema failed on second run: %v", err)
+	}
+}
+
+func TestBookPublication(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	publisher, language := " Ace  Books ", "EN"
+	book.Publisher, book.Language = &publisher, &language
+	id, err := store.AddBook(ctx, book)
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	got, err := store.GetBookByID(ctx, id)
+	if err != nil || got.Publisher == nil || *got.Publisher != "Ace Books" || got.Language == nil || *got.Language != "en" {
+		t.Fatalf("Expected the publisher and language stored normalized, got %+v, %v", got, err)
+	}
+
+	if err := store.UpdateBook(ctx, id, model.BookPatch{Publisher: model.Optional[string]{Set: true}, Language: model.Some("eng")}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+	if got, _ := store.GetBookByID(ctx, id); got.Publisher != nil || *got.Language != "eng" {
+		t.Errorf("Expected the publisher cleared and the language changed, got %+v", got)
+	}
+	if err := store.UpdateBook(ctx, id, model.BookPatch{Language: model.Some("English")}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a language that isn't a code to be rejected, got %v", err)
+	}
+
+	// A merge fills in the publisher from the duplicate
+	duplicate := createTestBook()
+	duplicate.OpenLibraryID, duplicate.Publisher = "OL99999M", &publisher
+	duplicateID, err := store.AddBook(ctx, duplicate)
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if _, err := store.MergeBooks(ctx, id, duplicateID); err != nil {
+		t.Fatalf("MergeBooks failed: %v", err)
+	}
+	if got, _ := store.GetBookByID(ctx, id); got.Publisher == nil || *got.Publisher != "Ace Books" || *got.Language != "eng" {
+		t.Errorf("Expected the merged book to get the duplicate's publisher and keep its language, got %+v", got)
+	}
+}
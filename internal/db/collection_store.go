@@ -0,0 +1,223 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// CollectionStore defines the database operations for collections, the
+// user-defined shelves, and the books in them.
+type CollectionStore interface {
+	GetCollections(ctx context.Context) ([]model.Collection, error)
+	GetCollection(ctx context.Context, id int64) (*model.Collection, error)
+	AddCollection(ctx context.Context, collection *model.Collection) error
+	UpdateCollection(ctx context.Context, collection *model.Collection) error
+	DeleteCollection(ctx context.Context, id int64) error
+	GetBookCollections(ctx context.Context, bookID int64) ([]model.Collection, error)
+	AddBookToCollection(ctx context.Context, collectionID, bookID int64) error
+	RemoveBookFromCollection(ctx context.Context, collectionID, bookID int64) error
+}
+
+// collectionColumns selects a collection with the number of its books not in
+// the trash.
+const collectionColumns = `collections.id, collections.name, collections.description,
+        (SELECT COUNT(*) FROM collection_books JOIN books ON books.id = collection_books.book_id
+            WHERE collection_books.collection_id = collections.id AND books.deleted_at IS NULL),
+        collections.created_at, collections.updated_at`
+
+func scanCollection(row rowScanner) (*model.Collection, error) {
+	var c model.Collection
+	var description sql.NullString
+	if err := row.Scan(&c.ID, &c.Name, &description, &c.BookCount, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if description.Valid {
+		c.Description = &description.String
+	}
+	return &c, nil
+}
+
+// collectionError explains a failure to save a collection, naming the
+// collection a duplicate name clashes with.
+func collectionError(err error, collection *model.Collection, action string) error {
+	err = classify(err)
+	if errors.Is(err, ErrDuplicate) {
+		return &storeError{kind: ErrDuplicate, msg: fmt.Sprintf("a collection named %q already exists", collection.Name), cause: err}
+	}
+	return fmt.Errorf("failed to %s collection: %w", action, err)
+}
+
+// queryCollections runs a query selecting collectionColumns.
+func (s *SQLiteBookStore) queryCollections(ctx context.Context, query string, args ...interface{}) ([]model.Collection, error) {
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing collection query failed", "error", err)
+		return nil, fmt.Errorf("failed to query collections: %w", err)
+	}
+	defer rows.Close()
+
+	collections := []model.Collection{}
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan collection row: %w", err)
+		}
+		collections = append(collections, *collection)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating collection rows: %w", err)
+	}
+	return collections, nil
+}
+
+// GetCollections returns every collection with the number of books in it, by
+// name. Empty collections are included with a count of zero.
+func (s *SQLiteBookStore) GetCollections(ctx context.Context) ([]model.Collection, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetCollections query")
+	owned, args := ownedBy(ctx, "collections.user_id")
+	return s.queryCollections(ctx, `SELECT `+collectionColumns+` FROM collections WHERE `+owned+` ORDER BY collections.name, collections.id;`, args...)
+}
+
+// GetCollection returns one collection.
+func (s *SQLiteBookStore) GetCollection(ctx context.Context, id int64) (*model.Collection, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetCollection query", "id", id)
+	owned, args := ownedBy(ctx, "collections.user_id")
+	collection, err := scanCollection(s.DB.QueryRowContext(ctx, `SELECT `+collectionColumns+` FROM collections WHERE collections.id = ? AND `+owned+`;`,
+		append([]interface{}{id}, args...)...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("collection with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	return collection, nil
+}
+
+// AddCollection stores an empty collection and sets its ID and times.
+func (s *SQLiteBookStore) AddCollection(ctx context.Context, collection *model.Collection) error {
+	if err := collection.Validate(); err != nil {
+		return &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
+	}
+	slog.InfoContext(ctx, "SQL: Executing AddCollection query", "name", collection.Name)
+	collection.CreatedAt = time.Now().UTC()
+	collection.UpdatedAt = collection.CreatedAt
+	collection.BookCount = 0
+	if err := s.DB.QueryRowContext(ctx, `INSERT INTO collections (user_id, name, description, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?) RETURNING id;`,
+		owner(ctx), collection.Name, collection.Description, collection.CreatedAt, collection.UpdatedAt).Scan(&collection.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddCollection statement failed", "error", err)
+		return collectionError(err, collection, "add")
+	}
+	return nil
+}
+
+// UpdateCollection replaces a collection's name and description, and sets
+// its book count and times. The books in it are kept.
+func (s *SQLiteBookStore) UpdateCollection(ctx context.Context, collection *model.Collection) error {
+	if err := collection.Validate(); err != nil {
+		return &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
+	}
+	slog.InfoContext(ctx, "SQL: Executing UpdateCollection query", "id", collection.ID, "name", collection.Name)
+	owned, args := ownedBy(ctx, "user_id")
+	res, err := s.DB.ExecContext(ctx, `UPDATE collections SET name = ?, description = ?, updated_at = ? WHERE id = ? AND `+owned+`;`,
+		append([]interface{}{collection.Name, collection.Description, time.Now().UTC(), collection.ID}, args...)...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing UpdateCollection statement failed", "error", err)
+		return collectionError(err, collection, "update")
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("collection with ID %d %w", collection.ID, ErrNotFound)
+	}
+	updated, err := s.GetCollection(ctx, collection.ID)
+	if err != nil {
+		return err
+	}
+	*collection = *updated
+	return nil
+}
+
+// DeleteCollection deletes a collection. The books in it are kept.
+func (s *SQLiteBookStore) DeleteCollection(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "SQL: Executing DeleteCollection query", "id", id)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	owned, args := ownedBy(ctx, "user_id")
+	res, err := tx.ExecContext(ctx, `DELETE FROM collections WHERE id = ? AND `+owned+`;`, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("collection with ID %d %w", id, ErrNotFound)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM collection_books WHERE collection_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to empty collection: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetBookCollections returns the collections a book is in, by name.
+func (s *SQLiteBookStore) GetBookCollections(ctx context.Context, bookID int64) ([]model.Collection, error) {
+	if _, err := s.GetBookByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "SQL: Executing GetBookCollections query", "bookID", bookID)
+	return s.queryCollections(ctx, `SELECT `+collectionColumns+` FROM collections
+        WHERE collections.id IN (SELECT collection_id FROM collection_books WHERE book_id = ?)
+        ORDER BY collections.name, collections.id;`, bookID)
+}
+
+// AddBookToCollection puts a book in a collection. Adding a book twice is
+// not an error.
+func (s *SQLiteBookStore) AddBookToCollection(ctx context.Context, collectionID, bookID int64) error {
+	if _, err := s.GetCollection(ctx, collectionID); err != nil {
+		return err
+	}
+	if _, err := s.GetBookByID(ctx, bookID); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing AddBookToCollection query", "collectionID", collectionID, "bookID", bookID)
+	if _, err := s.DB.ExecContext(ctx, `INSERT INTO collection_books (collection_id, book_id, added_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING;`,
+		collectionID, bookID, time.Now().UTC()); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddBookToCollection statement failed", "error", err)
+		return fmt.Errorf("failed to add book to collection: %w", classify(err))
+	}
+	return nil
+}
+
+// RemoveBookFromCollection takes a book out of a collection.
+func (s *SQLiteBookStore) RemoveBookFromCollection(ctx context.Context, collectionID, bookID int64) error {
+	if _, err := s.GetCollection(ctx, collectionID); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing RemoveBookFromCollection query", "collectionID", collectionID, "bookID", bookID)
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM collection_books WHERE collection_id = ? AND book_id = ?;`, collectionID, bookID)
+	if err != nil {
+		return fmt.Errorf("failed to remove book from collection: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("book with ID %d in collection %d %w", bookID, collectionID, ErrNotFound)
+	}
+	return nil
+}
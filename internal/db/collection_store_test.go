@@ -0,0 +1,178 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestCollections(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	description := "  For the summer "
+	beach := model.Collection{Name: " Beach reads ", Description: &description}
+	if err := store.AddCollection(ctx, &beach); err != nil || beach.ID == 0 || beach.Name != "Beach reads" || *beach.Description != "For the summer" {
+		t.Fatalf("AddCollection failed: %+v, %v", beach, err)
+	}
+	tbr := model.Collection{Name: "2025 TBR"}
+	if err := store.AddCollection(ctx, &tbr); err != nil {
+		t.Fatalf("AddCollection failed: %v", err)
+	}
+	if err := store.AddCollection(ctx, &model.Collection{Name: "2025 TBR"}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected a duplicate name to be rejected, got %v", err)
+	}
+	if err := store.AddCollection(ctx, &model.Collection{Name: " "}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected an empty name to be rejected, got %v", err)
+	}
+
+	var ids []int64
+	for i, status := range []model.BookStatus{model.StatusRead, model.StatusWantToRead, model.StatusRead} {
+		book := createTestBook()
+		book.OpenLibraryID = "OL" + string(rune('A'+i)) + "M"
+		book.Status = status
+		id, err := store.AddBook(ctx, book)
+		if err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	for _, id := range ids[:2] {
+		if err := store.AddBookToCollection(ctx, beach.ID, id); err != nil {
+			t.Fatalf("AddBookToCollection failed: %v", err)
+		}
+	}
+	if err := store.AddBookToCollection(ctx, beach.ID, ids[0]); err != nil {
+		t.Errorf("Expected adding a book twice to be allowed, got %v", err)
+	}
+	if err := store.AddBookToCollection(ctx, tbr.ID, ids[1]); err != nil {
+		t.Fatalf("AddBookToCollection failed: %v", err)
+	}
+	if err := store.AddBookToCollection(ctx, beach.ID, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found for a missing book, got %v", err)
+	}
+	if err := store.AddBookToCollection(ctx, 999, ids[0]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found for a missing collection, got %v", err)
+	}
+
+	collections, err := store.GetCollections(ctx)
+	if err != nil || len(collections) != 2 || collections[0].Name != "2025 TBR" || collections[0].BookCount != 1 || collections[1].BookCount != 2 {
+		t.Fatalf("Expected both collections by name with their counts, got %+v, %v", collections, err)
+	}
+	if in, err := store.GetBookCollections(ctx, ids[1]); err != nil || len(in) != 2 {
+		t.Errorf("GetBookCollections: got %+v, %v", in, err)
+	}
+
+	// The filter is orthogonal to status
+	books, total, err := store.GetBooksPage(ctx, ListOptions{Filter: BookFilter{Collection: beach.ID, Status: model.StatusRead}})
+	if err != nil || total != 1 || books[0].ID != ids[0] {
+		t.Errorf("Expected the read book of the collection, got %d books, %v", total, err)
+	}
+	if _, total, _ := store.GetBooksPage(ctx, ListOptions{Filter: BookFilter{Collection: beach.ID}}); total != 2 {
+		t.Errorf("Expected both books of the collection, got %d", total)
+	}
+
+	// Books in the trash aren't counted
+	if err := store.DeleteBook(ctx, ids[0]); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if got, _ := store.GetCollection(ctx, beach.ID); got.BookCount != 1 {
+		t.Errorf("Expected the trashed book not to be counted, got %d", got.BookCount)
+	}
+
+	beach.Name, beach.Description = "Summer", nil
+	if err := store.UpdateCollection(ctx, &beach); err != nil || beach.BookCount != 1 || beach.Description != nil {
+		t.Fatalf("UpdateCollection failed: %+v, %v", beach, err)
+	}
+	beach.Name = "2025 TBR"
+	if err := store.UpdateCollection(ctx, &beach); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected renaming to a taken name to be rejected, got %v", err)
+	}
+
+	if err := store.RemoveBookFromCollection(ctx, tbr.ID, ids[1]); err != nil {
+		t.Fatalf("RemoveBookFromCollection failed: %v", err)
+	}
+	if err := store.RemoveBookFromCollection(ctx, tbr.ID, ids[1]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found removing twice, got %v", err)
+	}
+
+	// Collections are per user
+	alice := model.User{Username: "alice", PasswordHash: "hash"}
+	if err := store.AddUser(ctx, &alice); err != nil {
+		t.Fatalf("AddUser failed: %v", err)
+	}
+	bob := model.User{Username: "bob", PasswordHash: "hash"}
+	if err := store.AddUser(ctx, &bob); err != nil {
+		t.Fatalf("AddUser failed: %v", err)
+	}
+	if err := store.AddCollection(WithUser(ctx, bob.ID), &model.Collection{Name: "2025 TBR"}); err != nil {
+		t.Errorf("Expected another user to reuse a name, got %v", err)
+	}
+	if got, _ := store.GetCollections(WithUser(ctx, alice.ID)); len(got) != 2 {
+		t.Errorf("Expected the first user to adopt the collections, got %+v", got)
+	}
+	if err := store.AddBookToCollection(WithUser(ctx, bob.ID), tbr.ID, ids[2]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected another user's collection to be hidden, got %v", err)
+	}
+
+	if err := store.DeleteCollection(ctx, beach.ID); err != nil {
+		t.Fatalf("DeleteCollection failed: %v", err)
+	}
+	if err := store.DeleteCollection(ctx, beach.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found deleting twice, got %v", err)
+	}
+	if _, err := store.GetBookByID(ctx, ids[1]); err != nil {
+		t.Errorf("Expected the books of a deleted collection to be kept, got %v", err)
+	}
+}
+
+func TestMergeBooksMovesCollections(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	bookID, err := store.AddBook(ctx, book)
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	duplicate := createTestBook()
+	duplicate.OpenLibraryID = "OL99999M"
+	duplicateID, err := store.AddBook(ctx, duplicate)
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	shared, only := model.Collection{Name: "Shared"}, model.Collection{Name: "Duplicate only"}
+	for _, c := range []*model.Collection{&shared, &only} {
+		if err := store.AddCollection(ctx, c); err != nil {
+			t.Fatalf("AddCollection failed: %v", err)
+		}
+		if err := store.AddBookToCollection(ctx, c.ID, duplicateID); err != nil {
+			t.Fatalf("AddBookToCollection failed: %v", err)
+		}
+	}
+	if err := store.AddBookToCollection(ctx, shared.ID, bookID); err != nil {
+		t.Fatalf("AddBookToCollection failed: %v", err)
+	}
+
+	merge, err := store.MergeBooks(ctx, bookID, duplicateID)
+	if err != nil {
+		t.Fatalf("MergeBooks failed: %v", err)
+	}
+	if in, _ := store.GetBookCollections(ctx, bookID); len(in) != 2 {
+		t.Errorf("Expected the kept book in both collections, got %+v", in)
+	}
+
+	if _, err := store.UnmergeBooks(ctx, merge.ID); err != nil {
+		t.Fatalf("UnmergeBooks failed: %v", err)
+	}
+	if in, _ := store.GetBookCollections(ctx, bookID); len(in) != 1 || in[0].ID != shared.ID {
+		t.Errorf("Expected the kept book back in its own collection, got %+v", in)
+	}
+	if in, _ := store.GetBookCollections(ctx, duplicateID); len(in) != 2 {
+		t.Errorf("Expected the duplicate back in both collections, got %+v", in)
+	}
+}
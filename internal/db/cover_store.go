@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// CoverStore defines the database operations for the local cover cache.
+type CoverStore interface {
+	SaveCoverImage(ctx context.Context, image *model.CoverImage) (bool, error)
+	GetCoverImage(ctx context.Context, hash string) (*model.CoverImage, error)
+	SetBookCoverHash(ctx context.Context, bookID int64, hash *string) error
+	DeleteUnreferencedCoverImages(ctx context.Context) ([]string, error)
+	GetCoverCacheStats(ctx context.Context) (model.CoverCacheStats, error)
+}
+
+// SaveCoverImage records a cached image. Returns false when an image with the
+// same hash was already stored, i.e., the new cover was a duplicate.
+func (s *SQLiteBookStore) SaveCoverImage(ctx context.Context, image *model.CoverImage) (bool, error) {
+	if image.CreatedAt.IsZero() {
+		image.CreatedAt = time.Now().UTC()
+	}
+	slog.InfoContext(ctx, "SQL: Executing SaveCoverImage query", "hash", image.Hash, "size", image.Size)
+	res, err := s.DB.ExecContext(ctx, `INSERT INTO cover_images (hash, content_type, size, width, height, blurhash, lqip, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING;`,
+		image.Hash, image.ContentType, image.Size, image.Width, image.Height, image.Blurhash, image.LQIP, image.CreatedAt)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing SaveCoverImage statement failed", "error", err)
+		return false, fmt.Errorf("failed to save cover image: %w", classify(err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetCoverImage returns a cached image by hash.
+func (s *SQLiteBookStore) GetCoverImage(ctx context.Context, hash string) (*model.CoverImage, error) {
+	slog.DebugContext(ctx, "SQL: Executing GetCoverImage query", "hash", hash)
+	var image model.CoverImage
+	err := s.DB.QueryRowContext(ctx, `SELECT hash, content_type, size, width, height, blurhash, lqip, created_at FROM cover_images WHERE hash = ?;`, hash).
+		Scan(&image.Hash, &image.ContentType, &image.Size, &image.Width, &image.Height, &image.Blurhash, &image.LQIP, &image.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("cover image %s %w", hash, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to scan cover image row: %w", err)
+	}
+	return &image, nil
+}
+
+// SetBookCoverHash points a book at a cached image, or detaches it when hash is nil.
+func (s *SQLiteBookStore) SetBookCoverHash(ctx context.Context, bookID int64, hash *string) error {
+	slog.InfoContext(ctx, "SQL: Executing SetBookCoverHash query", "id", bookID, "hash", hash)
+	res, err := s.DB.ExecContext(ctx, `UPDATE books SET cover_hash = ? WHERE id = ?;`, hash, bookID)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing SetBookCoverHash statement failed", "error", err)
+		return fmt.Errorf("failed to set book cover hash: %w", classify(err))
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("book with ID %d %w", bookID, ErrNotFound)
+	}
+	return nil
+}
+
+// DeleteUnreferencedCoverImages removes images no book points at and returns
+// their hashes, so the caller can delete the files.
+func (s *SQLiteBookStore) DeleteUnreferencedCoverImages(ctx context.Context) ([]string, error) {
+	slog.InfoContext(ctx, "SQL: Executing DeleteUnreferencedCoverImages")
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT hash FROM cover_images
+        WHERE hash NOT IN (SELECT cover_hash FROM books WHERE cover_hash IS NOT NULL);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unreferenced cover images: %w", err)
+	}
+	hashes := []string{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan cover image row: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cover image rows: %w", err)
+	}
+
+	for _, hash := range hashes {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM cover_images WHERE hash = ?;`, hash); err != nil {
+			return nil, fmt.Errorf("failed to delete cover image: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit cover image cleanup: %w", err)
+	}
+	return hashes, nil
+}
+
+// GetCoverCacheStats returns the size of the cover cache.
+func (s *SQLiteBookStore) GetCoverCacheStats(ctx context.Context) (model.CoverCacheStats, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetCoverCacheStats query")
+	var stats model.CoverCacheStats
+	err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(size), 0),
+            (SELECT COUNT(*) FROM books WHERE cover_hash IS NOT NULL)
+        FROM cover_images;`).Scan(&stats.Images, &stats.Bytes, &stats.References)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetCoverCacheStats query failed", "error", err)
+		return stats, fmt.Errorf("failed to get cover cache stats: %w", err)
+	}
+	return stats, nil
+}
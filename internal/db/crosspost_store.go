@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// CrosspostStore defines the database operations for cross-posting accounts.
+type CrosspostStore interface {
+	AddCrosspostAccount(ctx context.Context, account *model.CrosspostAccount) (int64, error)
+	GetCrosspostAccounts(ctx context.Context) ([]model.CrosspostAccount, error)
+	DeleteCrosspostAccount(ctx context.Context, id int64) error
+}
+
+// AddCrosspostAccount inserts a cross-posting account. The token must already be encrypted.
+func (s *SQLiteBookStore) AddCrosspostAccount(ctx context.Context, account *model.CrosspostAccount) (int64, error) {
+	if !account.Provider.IsValid() {
+		return 0, invalidf("invalid cross-posting provider: %s", account.Provider)
+	}
+	if account.CreatedAt.IsZero() {
+		account.CreatedAt = time.Now().UTC()
+	}
+
+	// The encrypted token is deliberately left out of the log line
+	slog.InfoContext(ctx, "SQL: Executing AddCrosspostAccount query", "provider", account.Provider, "instance", account.InstanceURL, "handle", account.Handle)
+	if err := s.DB.QueryRowContext(ctx, `INSERT INTO crosspost_accounts (provider, instance_url, handle, token_encrypted, template, enabled, created_at, user_id)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;`,
+		account.Provider, account.InstanceURL, account.Handle, account.EncryptedToken, account.Template, account.Enabled, account.CreatedAt,
+		owner(ctx)).Scan(&account.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddCrosspostAccount statement failed", "error", err)
+		return 0, fmt.Errorf("failed to add cross-posting account: %w", classify(err))
+	}
+	return account.ID, nil
+}
+
+// GetCrosspostAccounts returns all cross-posting accounts, including their encrypted tokens.
+func (s *SQLiteBookStore) GetCrosspostAccounts(ctx context.Context) ([]model.CrosspostAccount, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetCrosspostAccounts query")
+	owned, args := ownedBy(ctx, "user_id")
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, provider, instance_url, handle, token_encrypted, template, enabled, created_at
+        FROM crosspost_accounts WHERE `+owned+` ORDER BY id;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetCrosspostAccounts query failed", "error", err)
+		return nil, fmt.Errorf("failed to query cross-posting accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []model.CrosspostAccount{}
+	for rows.Next() {
+		var a model.CrosspostAccount
+		if err := rows.Scan(&a.ID, &a.Provider, &a.InstanceURL, &a.Handle, &a.EncryptedToken, &a.Template, &a.Enabled, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan cross-posting account row: %w", err)
+		}
+		accounts = append(accounts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cross-posting account rows: %w", err)
+	}
+	return accounts, nil
+}
+
+// DeleteCrosspostAccount removes a cross-posting account.
+func (s *SQLiteBookStore) DeleteCrosspostAccount(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "SQL: Executing DeleteCrosspostAccount query", "id", id)
+	owned, args := ownedBy(ctx, "user_id")
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM crosspost_accounts WHERE id = ? AND `+owned+`;`, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete cross-posting account: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("cross-posting account with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
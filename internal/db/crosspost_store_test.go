@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestCrosspostAccounts tests adding, listing and deleting cross-posting accounts
+func TestCrosspostAccounts(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	account := &model.CrosspostAccount{
+		Provider:       model.ProviderBluesky,
+		InstanceURL:    "https://bsky.social",
+		Handle:         "reader.bsky.social",
+		Template:       model.DefaultCrosspostTemplate,
+		Enabled:        true,
+		EncryptedToken: "sealed",
+	}
+	id, err := store.AddCrosspostAccount(ctx, account)
+	if err != nil {
+		t.Fatalf("AddCrosspostAccount failed: %v", err)
+	}
+
+	accounts, err := store.GetCrosspostAccounts(ctx)
+	if err != nil {
+		t.Fatalf("GetCrosspostAccounts failed: %v", err)
+	}
+	if len(accounts) != 1 || accounts[0].ID != id || accounts[0].EncryptedToken != "sealed" || !accounts[0].Enabled {
+		t.Errorf("Unexpected accounts: %+v", accounts)
+	}
+
+	if _, err := store.AddCrosspostAccount(ctx, &model.CrosspostAccount{Provider: "myspace"}); err == nil {
+		t.Error("Expected error for invalid provider")
+	}
+
+	if err := store.DeleteCrosspostAccount(ctx, id); err != nil {
+		t.Fatalf("DeleteCrosspostAccount failed: %v", err)
+	}
+	if err := store.DeleteCrosspostAccount(ctx, id); err == nil {
+		t.Error("Expected not found error when deleting twice")
+	}
+}
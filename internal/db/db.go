@@ -4,14 +4,76 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3" // SQLite driver
+	"github.com/mattn/go-sqlite3"
 )
 
-// InitDB initializes the SQLite database connection and creates the necessary tables if they don't exist.
+// SQLiteOptions tune the connections to a SQLite database.
+type SQLiteOptions struct {
+	// JournalMode is how SQLite journals writes: "wal", the default, lets
+	// requests read while another writes, and "delete", SQLite's own default,
+	// suits databases on network file systems, where WAL doesn't work.
+	JournalMode string
+	// BusyTimeout is how long a write waits for the one under way to finish
+	// before failing with "database is locked".
+	BusyTimeout time.Duration
+	// MaxOpenConns caps the connections open at once. SQLite writes one
+	// transaction at a time however many there are; more let reads go on
+	// alongside.
+	MaxOpenConns int
+}
+
+// DefaultSQLiteOptions are the options InitDB opens databases with.
+var DefaultSQLiteOptions = SQLiteOptions{JournalMode: "wal", BusyTimeout: 5 * time.Second, MaxOpenConns: 4}
+
+// journalModes are the journal modes SQLiteOptions accept.
+var journalModes = map[string]bool{"wal": true, "delete": true, "truncate": true, "persist": true}
+
+// Validate returns an error unless the options can be used.
+func (o SQLiteOptions) Validate() error {
+	if !journalModes[strings.ToLower(o.JournalMode)] {
+		return fmt.Errorf("journal mode must be 'wal', 'delete', 'truncate' or 'persist', got '%s'", o.JournalMode)
+	}
+	if o.BusyTimeout < 0 {
+		return fmt.Errorf("busy timeout must not be negative, got %v", o.BusyTimeout)
+	}
+	if o.MaxOpenConns < 1 {
+		return fmt.Errorf("there must be at least 1 open connection, got %d", o.MaxOpenConns)
+	}
+	return nil
+}
+
+// dsn returns the data source name of the database file at path with the
+// options, and foreign keys enforced. Transactions begin immediately, taking
+// the write lock up front: one that read first and then wrote would fail at
+// once, without waiting out the busy timeout, when another wrote meanwhile.
+func (o SQLiteOptions) dsn(path string) string {
+	params := url.Values{
+		"_foreign_keys": {"on"},
+		"_journal_mode": {strings.ToUpper(o.JournalMode)},
+		"_busy_timeout": {strconv.FormatInt(o.BusyTimeout.Milliseconds(), 10)},
+		"_txlock":       {"immediate"},
+	}
+	return path + "?" + params.Encode()
+}
+
+// InitDB initializes the SQLite database connection with DefaultSQLiteOptions and creates the necessary tables if they don't exist.
 func InitDB(dataSourceName string) (*sql.DB, error) {
+	return InitDBWithOptions(dataSourceName, DefaultSQLiteOptions)
+}
+
+// InitDBWithOptions initializes the SQLite database connection with opts and creates the necessary tables if they don't exist.
+func InitDBWithOptions(dataSourceName string, opts SQLiteOptions) (*sql.DB, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
 	// Ensure the directory for the database file exists
 	dir := filepath.Dir(dataSourceName)
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
@@ -23,15 +85,16 @@ func InitDB(dataSourceName string) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to check database directory %s: %w", dir, err)
 	}
 
-
-	slog.Info("Initializing database connection", "dataSourceName", dataSourceName)
-	db, err := sql.Open("sqlite3", dataSourceName+"?_foreign_keys=on") // Enable foreign key support if needed later
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
+	slog.Info("Initializing database connection", "dataSourceName", dataSourceName, "journalMode", opts.JournalMode,
+		"busyTimeout", opts.BusyTimeout, "maxOpenConns", opts.MaxOpenConns)
+	// Opened through a connector, so queries are timed for /metrics
+	db := sql.OpenDB(timedConnector{dsnConnector{opts.dsn(dataSourceName), &sqlite3.SQLiteDriver{}}})
+	// Idle connections are kept rather than closed and reopened
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxOpenConns)
 
 	// Check the connection
-	if err = db.Ping(); err != nil {
+	if err := db.Ping(); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -39,7 +102,7 @@ func InitDB(dataSourceName string) (*sql.DB, error) {
 	slog.Info("Database connection successful")
 
 	// Create tables if they don't exist
-	if err = CreateSchema(db); err != nil {
+	if err := CreateSchema(db); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to create database schema: %w", err)
 	}
@@ -48,34 +111,129 @@ func InitDB(dataSourceName string) (*sql.DB, error) {
 	return db, nil
 }
 
-// CreateSchema defines and executes the SQL statements to create the database tables.
-// Exported for testing purposes.
+// CreateSchema brings the database schema up to date by applying the SQLite
+// migrations it has not had yet. Exported for testing purposes.
 func CreateSchema(db *sql.DB) error {
-	// Use TEXT for status, INTEGER for rating (nullable), TEXT for comments (nullable)
-	// Use TEXT for OpenLibraryID and ISBN
-	// Add UNIQUE constraint on OpenLibraryID to prevent duplicates? Or handle in application logic.
-	schema := `
-    CREATE TABLE IF NOT EXISTS books (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        title TEXT NOT NULL,
-        author TEXT NOT NULL,
-        open_library_id TEXT NOT NULL UNIQUE,
-        isbn TEXT,
-        status TEXT NOT NULL CHECK(status IN ('Want to Read', 'Currently Reading', 'Read')),
-        type TEXT NOT NULL DEFAULT 'book' CHECK(type IN ('book', 'audiobook')),
-        rating INTEGER CHECK(rating IS NULL OR (rating >= 1 AND rating <= 10)),
-        comments TEXT,
-        cover_url TEXT,
-        series TEXT,
-        series_index INTEGER
-    );
-    `
-	slog.Info("Executing schema creation SQL")
-	_, err := db.Exec(schema)
-	if err != nil {
-		slog.Error("Error executing schema SQL", "error", err)
-		return fmt.Errorf("failed to execute schema creation: %w", err)
+	// Databases from before versioned migrations may lack columns added since
+	// their tables were created, which the first migration doesn't add.
+	var legacy int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'books'
+        AND NOT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations');`).Scan(&legacy); err != nil {
+		return fmt.Errorf("failed to inspect schema: %w", err)
 	}
+	if legacy > 0 {
+		if err := upgradeLegacySchema(db); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("Executing schema migrations")
+	if err := migrate(db, backendMigrations("sqlite")); err != nil {
+		return err
+	}
+	// The search index depends on how the SQLite driver was built, so it is
+	// created here rather than in a migration.
+	if err := createSearchIndex(db); err != nil {
+		return err
+	}
+
 	slog.Info("Schema execution successful")
 	return nil
-}
\ No newline at end of file
+}
+
+// upgradeLegacySchema adds the columns introduced before versioned
+// migrations to the tables of an older database.
+func upgradeLegacySchema(db *sql.DB) error {
+	slog.Info("Upgrading database created before schema migrations")
+	if err := addMissingColumns(db, "books", bookColumnDefs); err != nil {
+		return err
+	}
+	if err := addMissingColumns(db, "cover_images", coverImageColumnDefs); err != nil {
+		return err
+	}
+	// Books from before change tracking count as changed now, so the next
+	// differential export includes them rather than silently skipping them.
+	if _, err := db.Exec(`UPDATE books SET updated_at = ? WHERE updated_at IS NULL;`, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to backfill book change times: %w", err)
+	}
+	return nil
+}
+
+// columnDef describes a column that may need to be added to an existing table.
+type columnDef struct {
+	Name       string
+	Definition string
+}
+
+// bookColumnDefs lists columns added to the books table after its initial
+// release and before versioned migrations. Later columns are migrations.
+var bookColumnDefs = []columnDef{
+	{"type", "TEXT NOT NULL DEFAULT 'book' CHECK(type IN ('book', 'audiobook'))"},
+	{"series", "TEXT"},
+	{"series_index", "INTEGER"},
+	{"edition", "INTEGER CHECK(edition IS NULL OR edition > 0)"},
+	{"course_code", "TEXT"},
+	{"semester", "TEXT"},
+	{"reading_mode", "TEXT NOT NULL DEFAULT 'leisure' CHECK(reading_mode IN ('leisure', 'reference'))"},
+	{"publish_opt_out", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"comments_spoiler", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"publish_year", "INTEGER"},
+	{"updated_at", "DATETIME"},
+	{"cover_hash", "TEXT"},
+	{"date_started", "DATETIME"},
+	{"date_finished", "DATETIME"},
+	{"description", "TEXT"},
+	{"subtitle", "TEXT"},
+	{"translated", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"page_count", "INTEGER CHECK(page_count IS NULL OR page_count > 0)"},
+}
+
+// coverImageColumnDefs lists columns added to the cover_images table after
+// its initial release and before versioned migrations.
+var coverImageColumnDefs = []columnDef{
+	{"width", "INTEGER NOT NULL DEFAULT 0"},
+	{"height", "INTEGER NOT NULL DEFAULT 0"},
+	{"blurhash", "TEXT NOT NULL DEFAULT ''"},
+	{"lqip", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// addMissingColumns adds each column in defs that is not already present on
+// table. A table that doesn't exist yet is left to the migrations.
+func addMissingColumns(db *sql.DB, table string, defs []columnDef) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s);", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var cid int
+		var name, colType string
+		var notNull, pk int
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan table info for %s: %w", table, err)
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read table info for %s: %w", table, err)
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+
+	for _, def := range defs {
+		if existing[def.Name] {
+			continue
+		}
+		slog.Info("Adding missing column", "table", table, "column", def.Name)
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, def.Name, def.Definition)
+		if _, err := db.Exec(stmt); err != nil {
+			slog.Error("Error adding column", "table", table, "column", def.Name, "error", err)
+			return fmt.Errorf("failed to add column %s to %s: %w", def.Name, table, err)
+		}
+	}
+	return nil
+}
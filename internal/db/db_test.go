@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestInitDBWithOptions tests that connections are opened with the journal
+// mode, busy timeout and foreign keys the options give.
+func TestInitDBWithOptions(t *testing.T) {
+	for _, opts := range []SQLiteOptions{DefaultSQLiteOptions, {JournalMode: "DELETE", BusyTimeout: 250 * time.Millisecond, MaxOpenConns: 1}} {
+		db, err := InitDBWithOptions(filepath.Join(t.TempDir(), "books.db"), opts)
+		if err != nil {
+			t.Fatalf("InitDBWithOptions(%+v) failed: %v", opts, err)
+		}
+		var mode string
+		var timeout, foreignKeys int
+		if err := db.QueryRow(`PRAGMA journal_mode;`).Scan(&mode); err != nil {
+			t.Fatalf("Failed to read the journal mode: %v", err)
+		}
+		if err := db.QueryRow(`PRAGMA busy_timeout;`).Scan(&timeout); err != nil {
+			t.Fatalf("Failed to read the busy timeout: %v", err)
+		}
+		if err := db.QueryRow(`PRAGMA foreign_keys;`).Scan(&foreignKeys); err != nil {
+			t.Fatalf("Failed to read foreign_keys: %v", err)
+		}
+		if !strings.EqualFold(mode, opts.JournalMode) || timeout != int(opts.BusyTimeout.Milliseconds()) || foreignKeys != 1 {
+			t.Errorf("%+v: got journal mode %s, busy timeout %dms and foreign_keys %d", opts, mode, timeout, foreignKeys)
+		}
+		if got := db.Stats().MaxOpenConnections; got != opts.MaxOpenConns {
+			t.Errorf("%+v: got %d connections at most", opts, got)
+		}
+		db.Close()
+	}
+
+	for _, opts := range []SQLiteOptions{
+		{JournalMode: "off", BusyTimeout: time.Second, MaxOpenConns: 1},
+		{JournalMode: "wal", BusyTimeout: -time.Second, MaxOpenConns: 1},
+		{JournalMode: "wal", BusyTimeout: time.Second, MaxOpenConns: 0},
+	} {
+		if _, err := InitDBWithOptions(filepath.Join(t.TempDir(), "books.db"), opts); err == nil {
+			t.Errorf("InitDBWithOptions(%+v): expected an error", opts)
+		}
+	}
+}
+
+// TestConcurrentWrites tests that books can be added and moved between
+// shelves from many goroutines at once without "database is locked" errors.
+func TestConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	db, err := InitDB(filepath.Join(t.TempDir(), "books.db"))
+	if err != nil {
+		t.Fatalf("InitDB failed: %v", err)
+	}
+	defer db.Close()
+	store := NewSQLiteBookStore(db)
+
+	const workers, books = 8, 10
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*books*2)
+	for w := 0; w < workers; w++ {
+		batch := make([]*model.Book, books)
+		for i := range batch {
+			batch[i] = createTestBook()
+			batch[i].OpenLibraryID = fmt.Sprintf("OL%d-%dM", w, i)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, book := range batch {
+				id, err := store.AddBook(ctx, book)
+				if err != nil {
+					errs <- fmt.Errorf("AddBook: %w", err)
+					continue
+				}
+				if err := store.UpdateBookStatus(ctx, id, model.StatusCurrentlyReading); err != nil {
+					errs <- fmt.Errorf("UpdateBookStatus: %w", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	all, err := store.GetBooks(ctx)
+	if err != nil {
+		t.Fatalf("GetBooks failed: %v", err)
+	}
+	reading := 0
+	for _, book := range all {
+		if book.Status == model.StatusCurrentlyReading {
+			reading++
+		}
+	}
+	if len(all) != workers*books || reading != workers*books {
+		t.Errorf("Expected %d books, all being read, got %d books, %d being read", workers*books, len(all), reading)
+	}
+}
@@ -0,0 +1,254 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// DeletionStore defines the database operations for deleting books by
+// filter, which takes two steps: a preview, whose token then confirms the
+// deletion.
+type DeletionStore interface {
+	PreviewBulkDeletion(ctx context.Context, filter BookFilter) (*model.DeletionPreview, error)
+	DeleteBooks(ctx context.Context, filter BookFilter, query, token string) (*model.BulkDeletion, error)
+	GetBulkDeletions(ctx context.Context) ([]model.BulkDeletion, error)
+	UndoBulkDeletion(ctx context.Context, id int64) (*model.BulkDeletion, error)
+}
+
+const bulkDeletionColumns = `id, filter, book_count, created_at, undone_at,
+        (SELECT COUNT(*) FROM books WHERE books.bulk_deletion_id = bulk_deletions.id AND books.deleted_at IS NOT NULL)`
+
+func scanBulkDeletion(row rowScanner) (*model.BulkDeletion, error) {
+	var deletion model.BulkDeletion
+	var undoneAt sql.NullTime
+	if err := row.Scan(&deletion.ID, &deletion.Filter, &deletion.BookCount, &deletion.CreatedAt, &undoneAt, &deletion.InTrash); err != nil {
+		return nil, err
+	}
+	if undoneAt.Valid {
+		deletion.UndoneAt = &undoneAt.Time
+	}
+	return &deletion, nil
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// selectForDeletion returns the books on the bookshelf matching filter, in
+// order of ID. An empty filter is refused, as it would select the whole bookshelf.
+func (s *SQLiteBookStore) selectForDeletion(ctx context.Context, db querier, filter BookFilter) ([]model.Book, error) {
+	if filter == (BookFilter{}) {
+		return nil, invalidf("a filter is required to delete books by filter")
+	}
+	where, args := filter.where(ctx, s.dialect)
+	rows, err := db.QueryContext(ctx, `SELECT `+bookColumns+` FROM books`+where+` ORDER BY id;`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query books to delete: %w", err)
+	}
+	defer rows.Close()
+
+	books := []model.Book{}
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan book row: %w", err)
+		}
+		books = append(books, *book)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating book rows: %w", err)
+	}
+	return books, nil
+}
+
+// deletionToken fingerprints the books a deletion selects: their IDs and
+// when each last changed, so a book added, changed or removed since the
+// preview invalidates it.
+func deletionToken(books []model.Book) string {
+	hash := sha256.New()
+	for _, book := range books {
+		hash.Write([]byte(strconv.FormatInt(book.ID, 10)))
+		if book.UpdatedAt != nil {
+			hash.Write([]byte("@" + book.UpdatedAt.UTC().Format(time.RFC3339Nano)))
+		}
+		hash.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:32]
+}
+
+// PreviewBulkDeletion returns the books DeleteBooks would move to the trash
+// for filter, with the token that confirms it.
+func (s *SQLiteBookStore) PreviewBulkDeletion(ctx context.Context, filter BookFilter) (*model.DeletionPreview, error) {
+	slog.InfoContext(ctx, "SQL: Executing PreviewBulkDeletion query", "filter", filter)
+	books, err := s.selectForDeletion(ctx, s.DB, filter)
+	if err != nil {
+		return nil, err
+	}
+	preview := &model.DeletionPreview{Count: len(books), Books: make([]model.DeletedBook, len(books)), Token: deletionToken(books)}
+	for i, book := range books {
+		preview.Books[i] = model.DeletedBook{ID: book.ID, Title: book.Title}
+	}
+	return preview, nil
+}
+
+// DeleteBooks moves the books matching filter to the trash in one
+// transaction, as a bulk deletion that records query, the filter as the
+// client gave it. token must be the one PreviewBulkDeletion gave for the same books;
+// if they changed since, nothing is deleted and ErrConflict is returned.
+func (s *SQLiteBookStore) DeleteBooks(ctx context.Context, filter BookFilter, query, token string) (*model.BulkDeletion, error) {
+	slog.InfoContext(ctx, "SQL: Executing DeleteBooks", "filter", filter)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	books, err := s.selectForDeletion(ctx, tx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(books) == 0 {
+		return nil, invalidf("no books match the filter")
+	}
+	if token != deletionToken(books) {
+		return nil, &storeError{kind: ErrConflict, msg: "the books matching the filter changed since the preview; preview the deletion again"}
+	}
+
+	now := time.Now().UTC()
+	deletion := &model.BulkDeletion{Filter: query, CreatedAt: now, BookCount: len(books), InTrash: len(books)}
+	if err := tx.QueryRowContext(ctx, `INSERT INTO bulk_deletions (user_id, filter, book_count, created_at) VALUES (?, ?, ?, ?) RETURNING id;`,
+		owner(ctx), deletion.Filter, deletion.BookCount, deletion.CreatedAt).Scan(&deletion.ID); err != nil {
+		return nil, fmt.Errorf("failed to add bulk deletion: %w", classify(err))
+	}
+	for i := range books {
+		book := &books[i]
+		if _, err := tx.ExecContext(ctx, `UPDATE books SET deleted_at = ?, updated_at = ?, bulk_deletion_id = ? WHERE id = ?;`,
+			now, now, deletion.ID, book.ID); err != nil {
+			return nil, fmt.Errorf("failed to move book to the trash: %w", err)
+		}
+		if err := recordTombstone(ctx, tx, book); err != nil {
+			return nil, err
+		}
+		if err := recordBookEvent(ctx, tx, &model.BookEvent{BookID: book.ID, Kind: model.EventDeleted}); err != nil {
+			return nil, err
+		}
+	}
+	if err := commit(ctx, tx); err != nil {
+		return nil, fmt.Errorf("failed to commit deletion: %w", err)
+	}
+	slog.InfoContext(ctx, "SQL: Deleted books by filter", "id", deletion.ID, "count", deletion.BookCount)
+	return deletion, nil
+}
+
+// GetBulkDeletions returns every bulk deletion, newest first.
+func (s *SQLiteBookStore) GetBulkDeletions(ctx context.Context) ([]model.BulkDeletion, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetBulkDeletions query")
+	owned, args := ownedBy(ctx, "user_id")
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+bulkDeletionColumns+` FROM bulk_deletions WHERE `+owned+`
+        ORDER BY created_at DESC, id DESC;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetBulkDeletions query failed", "error", err)
+		return nil, fmt.Errorf("failed to query bulk deletions: %w", err)
+	}
+	defer rows.Close()
+
+	deletions := []model.BulkDeletion{}
+	for rows.Next() {
+		deletion, err := scanBulkDeletion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bulk deletion row: %w", err)
+		}
+		deletions = append(deletions, *deletion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bulk deletion rows: %w", err)
+	}
+	return deletions, nil
+}
+
+func getBulkDeletion(ctx context.Context, db execer, id int64) (*model.BulkDeletion, error) {
+	owned, args := ownedBy(ctx, "user_id")
+	deletion, err := scanBulkDeletion(db.QueryRowContext(ctx, `SELECT `+bulkDeletionColumns+` FROM bulk_deletions WHERE id = ? AND `+owned+`;`,
+		append([]interface{}{id}, args...)...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("bulk deletion with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bulk deletion: %w", err)
+	}
+	return deletion, nil
+}
+
+// UndoBulkDeletion restores the books of a bulk deletion that are still in
+// the trash, as RestoreBook would. Books restored or purged since are left
+// alone. A deletion can only be undone once.
+func (s *SQLiteBookStore) UndoBulkDeletion(ctx context.Context, id int64) (*model.BulkDeletion, error) {
+	slog.InfoContext(ctx, "SQL: Executing UndoBulkDeletion", "id", id)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deletion, err := getBulkDeletion(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if deletion.UndoneAt != nil {
+		return nil, invalidf("bulk deletion %d was already undone", id)
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM books WHERE bulk_deletion_id = ? AND deleted_at IS NOT NULL ORDER BY id;`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted books: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var bookID int64
+		if err := rows.Scan(&bookID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan book ID: %w", err)
+		}
+		ids = append(ids, bookID)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("error iterating deleted books: %w", err)
+	}
+	rows.Close()
+
+	now := time.Now().UTC()
+	for _, bookID := range ids {
+		if _, err := tx.ExecContext(ctx, `UPDATE books SET deleted_at = NULL, bulk_deletion_id = NULL, updated_at = ? WHERE id = ?;`, now, bookID); err != nil {
+			return nil, fmt.Errorf("failed to restore book: %w", classify(err))
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM book_tombstones WHERE book_id = ?;`, bookID); err != nil {
+			return nil, fmt.Errorf("failed to remove book tombstone: %w", err)
+		}
+		if err := recordBookEvent(ctx, tx, &model.BookEvent{BookID: bookID, Kind: model.EventRestored}); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE bulk_deletions SET undone_at = ? WHERE id = ?;`, now, id); err != nil {
+		return nil, fmt.Errorf("failed to mark bulk deletion undone: %w", err)
+	}
+	if deletion, err = getBulkDeletion(ctx, tx, id); err != nil {
+		return nil, err
+	}
+	deletion.Restored = len(ids)
+	if err := commit(ctx, tx); err != nil {
+		return nil, fmt.Errorf("failed to commit undo: %w", err)
+	}
+	slog.InfoContext(ctx, "SQL: Undid bulk deletion", "id", id, "restored", len(ids))
+	return deletion, nil
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestBulkDeletion(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	var books []*model.Book
+	for i, status := range []model.BookStatus{model.StatusRead, model.StatusRead, model.StatusWantToRead} {
+		book := createTestBook()
+		book.Status = status
+		book.OpenLibraryID = fmt.Sprintf("OLBULK%dM", i)
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+		books = append(books, book)
+	}
+	read := BookFilter{Status: model.StatusRead}
+
+	if _, err := store.PreviewBulkDeletion(ctx, BookFilter{}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a filter to be required, got %v", err)
+	}
+	preview, err := store.PreviewBulkDeletion(ctx, read)
+	if err != nil || preview.Count != 2 || preview.Books[0].ID != books[0].ID || preview.Token == "" {
+		t.Fatalf("Expected a preview of the 2 read books, got %+v, %v", preview, err)
+	}
+	if _, err := store.DeleteBooks(ctx, read, "status=read", "bogus"); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected a wrong token to be refused, got %v", err)
+	}
+
+	// A change to a matching book invalidates the token
+	if err := store.UpdateBook(ctx, books[1].ID, model.BookPatch{Comments: model.Some("Changed")}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+	if _, err := store.DeleteBooks(ctx, read, "status=read", preview.Token); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected a stale token to be refused, got %v", err)
+	}
+	if remaining, _ := store.GetBooks(ctx); len(remaining) != 3 {
+		t.Fatalf("Expected nothing deleted, got %d books", len(remaining))
+	}
+
+	preview, _ = store.PreviewBulkDeletion(ctx, read)
+	deletion, err := store.DeleteBooks(ctx, read, "status=read", preview.Token)
+	if err != nil || deletion.BookCount != 2 || deletion.InTrash != 2 || deletion.Filter != "status=read" {
+		t.Fatalf("Expected 2 books deleted, got %+v, %v", deletion, err)
+	}
+	if remaining, _ := store.GetBooks(ctx); len(remaining) != 1 || remaining[0].ID != books[2].ID {
+		t.Errorf("Expected only the unread book left, got %+v", remaining)
+	}
+	if trash, _ := store.GetTrash(ctx); len(trash) != 2 {
+		t.Errorf("Expected the deleted books in the trash, got %d", len(trash))
+	}
+	if _, err := store.DeleteBooks(ctx, read, "status=read", preview.Token); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected nothing left to delete, got %v", err)
+	}
+
+	// Books purged since are left out of the undo
+	if err := store.PurgeBook(ctx, books[0].ID); err != nil {
+		t.Fatalf("PurgeBook failed: %v", err)
+	}
+	undone, err := store.UndoBulkDeletion(ctx, deletion.ID)
+	if err != nil || undone.UndoneAt == nil || undone.Restored != 1 || undone.InTrash != 0 {
+		t.Fatalf("Expected the deletion undone, got %+v, %v", undone, err)
+	}
+	if book, err := store.GetBookByID(ctx, books[1].ID); err != nil || book.DeletedAt != nil {
+		t.Errorf("Expected the book restored, got %+v, %v", book, err)
+	}
+	if _, err := store.UndoBulkDeletion(ctx, deletion.ID); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a second undo to be refused, got %v", err)
+	}
+	if _, err := store.UndoBulkDeletion(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if deletions, err := store.GetBulkDeletions(ctx); err != nil || len(deletions) != 1 || deletions[0].BookCount != 2 {
+		t.Errorf("Expected the deletion listed, got %+v, %v", deletions, err)
+	}
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/htmltext"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// DescriptionStore defines the maintenance operations on book descriptions.
+type DescriptionStore interface {
+	CleanDescriptions(ctx context.Context) (DescriptionCleanup, error)
+}
+
+// DescriptionCleanup reports the outcome of re-cleaning stored descriptions.
+type DescriptionCleanup struct {
+	Checked int `json:"checked"` // Books with a description
+	Cleaned int `json:"cleaned"` // Descriptions that changed
+	// Changes lists each change in a dry run
+	Changes []BookChange `json:"changes,omitempty"`
+}
+
+// cleanDescription converts a provider description to Markdown. Descriptions
+// with no text left are dropped.
+func cleanDescription(description *string) *string {
+	if description == nil {
+		return nil
+	}
+	cleaned := htmltext.Clean(*description)
+	if cleaned == "" {
+		return nil
+	}
+	return &cleaned
+}
+
+// CleanDescriptions runs every stored description through the cleaner again,
+// so books added before it existed, or before it improved, are tidied too.
+// Changed books count as updated for differential exports.
+func (s *SQLiteBookStore) CleanDescriptions(ctx context.Context) (DescriptionCleanup, error) {
+	var report DescriptionCleanup
+	slog.InfoContext(ctx, "SQL: Executing CleanDescriptions query")
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, description FROM books WHERE description IS NOT NULL;`)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing CleanDescriptions query failed", "error", err)
+		return report, fmt.Errorf("failed to query descriptions: %w", err)
+	}
+	changed := make(map[int64]*string)
+	descriptions := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var description string
+		if err := rows.Scan(&id, &description); err != nil {
+			rows.Close()
+			return report, fmt.Errorf("failed to scan description row: %w", err)
+		}
+		report.Checked++
+		if cleaned := cleanDescription(&description); cleaned == nil || *cleaned != description {
+			changed[id] = cleaned
+			descriptions[id] = description
+			if IsDryRun(ctx) {
+				report.Changes = append(report.Changes, BookChange{BookID: id, Field: "description", From: &description, To: cleaned})
+			}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return report, fmt.Errorf("error iterating description rows: %w", err)
+	}
+	if len(changed) == 0 {
+		return report, nil
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return report, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	now := time.Now().UTC()
+	for id, description := range changed {
+		if _, err := tx.ExecContext(ctx, `UPDATE books SET description = ?, updated_at = ? WHERE id = ?;`, description, now, id); err != nil {
+			return report, fmt.Errorf("failed to update description of book %d: %w", id, err)
+		}
+		before := descriptions[id]
+		if err := recordBookUpdate(ctx, tx, &model.Book{ID: id, Description: &before}, &model.Book{ID: id, Description: description}); err != nil {
+			return report, err
+		}
+	}
+	if err := commit(ctx, tx); err != nil {
+		return report, fmt.Errorf("failed to commit descriptions: %w", err)
+	}
+	report.Cleaned = len(changed)
+	slog.InfoContext(ctx, "SQL: Cleaned book descriptions", "checked", report.Checked, "cleaned", report.Cleaned)
+	return report, nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCleanDescriptions(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	raw := "<p>A <b>classic</b> tale &amp; more.</p><p>Second paragraph.</p>"
+	book.Description = &raw
+	if _, err := store.AddBook(ctx, book); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	got, _ := store.GetBookByID(ctx, book.ID)
+	want := "A **classic** tale & more.\n\nSecond paragraph."
+	if got.Description == nil || *got.Description != want {
+		t.Fatalf("Expected the description cleaned on add, got %v", got.Description)
+	}
+
+	// Rows stored before cleaning existed are fixed by the re-clean job
+	other := createTestBook()
+	other.OpenLibraryID = "OL2M"
+	if _, err := store.AddBook(ctx, other); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE books SET description = ? WHERE id = ?;`, "Line one<br/>Line&nbsp;two", other.ID); err != nil {
+		t.Fatalf("Failed to store a raw description: %v", err)
+	}
+	empty := createTestBook()
+	empty.OpenLibraryID = "OL3M"
+	if _, err := store.AddBook(ctx, empty); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE books SET description = ? WHERE id = ?;`, "<p> </p>", empty.ID); err != nil {
+		t.Fatalf("Failed to store a raw description: %v", err)
+	}
+
+	report, err := store.CleanDescriptions(ctx)
+	if err != nil {
+		t.Fatalf("CleanDescriptions failed: %v", err)
+	}
+	if report.Checked != 3 || report.Cleaned != 2 {
+		t.Errorf("Expected 3 checked and 2 cleaned, got %+v", report)
+	}
+	if got, _ := store.GetBookByID(ctx, other.ID); got.Description == nil || *got.Description != "Line one\nLine two" {
+		t.Errorf("Unexpected re-cleaned description %v", got.Description)
+	}
+	if got, _ := store.GetBookByID(ctx, empty.ID); got.Description != nil {
+		t.Errorf("Expected an empty description to be dropped, got %q", *got.Description)
+	}
+
+	if report, err := store.CleanDescriptions(ctx); err != nil || report.Cleaned != 0 {
+		t.Errorf("Cleaning twice: got %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,52 @@
+package db
+
+// dialect describes the SQL that differs between SQLite and PostgreSQL. Most
+// queries are written in the SQL both accept and need nothing from it.
+type dialect struct {
+	postgres bool
+}
+
+var postgresDialect = dialect{postgres: true}
+
+// like returns the operator for a case-insensitive LIKE match.
+func (d dialect) like() string {
+	if d.postgres {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// noLimit returns the LIMIT argument that returns every row.
+func (d dialect) noLimit() interface{} {
+	if d.postgres {
+		return nil // LIMIT NULL
+	}
+	return -1 // SQLite treats a negative LIMIT as no limit
+}
+
+// monthDay returns an expression for the month and day (MM-DD) of the
+// timestamp column col, in UTC.
+func (d dialect) monthDay(col string) string {
+	if d.postgres {
+		return "to_char(" + col + " AT TIME ZONE 'UTC', 'MM-DD')"
+	}
+	return "strftime('%m-%d', " + col + ")"
+}
+
+// year returns an expression for the four-digit year of the timestamp column
+// col, in UTC.
+func (d dialect) year(col string) string {
+	if d.postgres {
+		return "to_char(" + col + " AT TIME ZONE 'UTC', 'YYYY')"
+	}
+	return "strftime('%Y', " + col + ")"
+}
+
+// yearMonth returns an expression for the year and month (YYYY-MM) of the
+// timestamp column col, in UTC.
+func (d dialect) yearMonth(col string) string {
+	if d.postgres {
+		return "to_char(" + col + " AT TIME ZONE 'UTC', 'YYYY-MM')"
+	}
+	return "strftime('%Y-%m', " + col + ")"
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// DisposalStore defines the database operations for the ledger of books that
+// left the collection for good.
+type DisposalStore interface {
+	AddDisposal(ctx context.Context, disposal *model.Disposal) error
+	GetDisposals(ctx context.Context, year int) ([]model.Disposal, error)
+	DeleteDisposal(ctx context.Context, id int64) error
+	GetDisposalReport(ctx context.Context, year int) (*model.DisposalReport, error)
+}
+
+const disposalColumns = `id, book_id, title, author, kind, recipient, reason, disposed_on, created_at`
+
+func scanDisposal(row rowScanner) (*model.Disposal, error) {
+	var d model.Disposal
+	var bookID sql.NullInt64
+	var recipient, reason sql.NullString
+	if err := row.Scan(&d.ID, &bookID, &d.Title, &d.Author, &d.Kind, &recipient, &reason, &d.DisposedOn, &d.CreatedAt); err != nil {
+		return nil, err
+	}
+	if bookID.Valid {
+		d.BookID = &bookID.Int64
+	}
+	if recipient.Valid {
+		d.Recipient = &recipient.String
+	}
+	if reason.Valid {
+		d.Reason = &reason.String
+	}
+	return &d, nil
+}
+
+// AddDisposal records a book leaving the collection and sets the disposal's
+// ID, title, author and creation time. The book is archived along with it;
+// deleting the disposal later leaves the book archived.
+func (s *SQLiteBookStore) AddDisposal(ctx context.Context, disposal *model.Disposal) error {
+	if disposal.BookID == nil {
+		return invalidf("book_id is required")
+	}
+	if err := disposal.Validate(); err != nil {
+		return &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
+	}
+	slog.InfoContext(ctx, "SQL: Executing AddDisposal", "bookID", *disposal.BookID, "kind", disposal.Kind)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	book, err := s.getBookTx(ctx, tx, *disposal.BookID)
+	if err != nil {
+		return err
+	}
+	disposal.Title, disposal.Author = book.Title, book.Author
+	disposal.CreatedAt = time.Now().UTC()
+	if err := tx.QueryRowContext(ctx, `INSERT INTO disposals (user_id, book_id, title, author, kind, recipient, reason, disposed_on, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;`,
+		owner(ctx), book.ID, disposal.Title, disposal.Author, disposal.Kind, disposal.Recipient, disposal.Reason,
+		disposal.DisposedOn, disposal.CreatedAt).Scan(&disposal.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Inserting disposal failed", "error", err)
+		return fmt.Errorf("failed to add disposal: %w", classify(err))
+	}
+	if !book.Archived {
+		if _, err := tx.ExecContext(ctx, `UPDATE books SET archived = ?, updated_at = ? WHERE id = ?;`, true, disposal.CreatedAt, book.ID); err != nil {
+			slog.ErrorContext(ctx, "SQL Error: Archiving disposed book failed", "error", err)
+			return fmt.Errorf("failed to archive book: %w", classify(err))
+		}
+		updated := *book
+		updated.Archived = true
+		if err := recordBookUpdate(ctx, tx, book, &updated); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit disposal: %w", err)
+	}
+	return nil
+}
+
+// GetDisposals returns the disposals of a year, or every disposal when year
+// is 0, newest first.
+func (s *SQLiteBookStore) GetDisposals(ctx context.Context, year int) ([]model.Disposal, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetDisposals query", "year", year)
+	owned, args := ownedBy(ctx, "user_id")
+	if year != 0 {
+		owned += ` AND disposed_on >= ? AND disposed_on <= ?`
+		args = append(args, fmt.Sprintf("%04d-01-01", year), fmt.Sprintf("%04d-12-31", year))
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+disposalColumns+` FROM disposals WHERE `+owned+`
+        ORDER BY disposed_on DESC, id DESC;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetDisposals query failed", "error", err)
+		return nil, fmt.Errorf("failed to query disposals: %w", err)
+	}
+	defer rows.Close()
+
+	disposals := []model.Disposal{}
+	for rows.Next() {
+		d, err := scanDisposal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan disposal row: %w", err)
+		}
+		disposals = append(disposals, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating disposal rows: %w", err)
+	}
+	return disposals, nil
+}
+
+// DeleteDisposal removes a disposal from the ledger, such as one recorded by
+// mistake. Its book stays archived.
+func (s *SQLiteBookStore) DeleteDisposal(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "SQL: Executing DeleteDisposal query", "id", id)
+	owned, args := ownedBy(ctx, "user_id")
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM disposals WHERE id = ? AND `+owned+`;`, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete disposal: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("disposal with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// GetDisposalReport sums up the disposals of a year, or of every year when
+// year is 0, by kind and by recipient. Disposals without a recipient are
+// only counted by kind.
+func (s *SQLiteBookStore) GetDisposalReport(ctx context.Context, year int) (*model.DisposalReport, error) {
+	disposals, err := s.GetDisposals(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+	report := &model.DisposalReport{Total: len(disposals), Kinds: []model.StatCount{}, Recipients: []model.StatCount{}, Disposals: disposals}
+	if year != 0 {
+		report.Year = &year
+	}
+	kinds, recipients := map[model.DisposalKind]int{}, map[string]int{}
+	for _, d := range disposals {
+		kinds[d.Kind]++
+		if d.Recipient != nil {
+			recipients[*d.Recipient]++
+		}
+	}
+	for _, kind := range model.DisposalKinds {
+		report.Kinds = append(report.Kinds, statCount(string(kind), kinds[kind], report.Total))
+	}
+	for recipient, books := range recipients {
+		report.Recipients = append(report.Recipients, statCount(recipient, books, report.Total))
+	}
+	sort.Slice(report.Recipients, func(i, j int) bool {
+		a, b := report.Recipients[i], report.Recipients[j]
+		return a.Books > b.Books || a.Books == b.Books && a.Value < b.Value
+	})
+	return report, nil
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestDisposals(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	if _, err := store.AddBook(ctx, book); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	other := createTestBook()
+	other.OpenLibraryID, other.Title = "OLDISPOSEDM", "Another Book"
+	if _, err := store.AddBook(ctx, other); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+
+	recipient, reason := "  Alice  ", " "
+	gift := &model.Disposal{BookID: &book.ID, Kind: model.DisposalGifted, Recipient: &recipient, Reason: &reason, DisposedOn: "2024-12-24"}
+	if err := store.AddDisposal(ctx, gift); err != nil {
+		t.Fatalf("AddDisposal failed: %v", err)
+	}
+	if gift.ID == 0 || gift.Title != book.Title || gift.Author != book.Author || *gift.Recipient != "Alice" || gift.Reason != nil {
+		t.Errorf("Expected a trimmed disposal of the book, got %+v", gift)
+	}
+	if got, _ := store.GetBookByID(ctx, book.ID); !got.Archived {
+		t.Error("Expected the disposed book to be archived")
+	}
+	donation := &model.Disposal{BookID: &other.ID, Kind: model.DisposalDonated}
+	if err := store.AddDisposal(ctx, donation); err != nil || donation.DisposedOn == "" {
+		t.Fatalf("Expected the disposal dated today, got %+v, %v", donation, err)
+	}
+	for _, bad := range []*model.Disposal{{BookID: &book.ID}, {BookID: &book.ID, Kind: "burned"}, {BookID: &book.ID, Kind: model.DisposalSold, DisposedOn: "Dec 24"}, {Kind: model.DisposalSold}} {
+		if err := store.AddDisposal(ctx, bad); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected %+v to be invalid, got %v", bad, err)
+		}
+	}
+	missing := int64(999)
+	if err := store.AddDisposal(ctx, &model.Disposal{BookID: &missing, Kind: model.DisposalLost}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a disposal of a missing book to be not found, got %v", err)
+	}
+
+	disposals, err := store.GetDisposals(ctx, 0)
+	if err != nil || len(disposals) != 2 || disposals[0].ID != donation.ID {
+		t.Fatalf("Expected both disposals, newest first, got %+v, %v", disposals, err)
+	}
+	if disposals, _ := store.GetDisposals(ctx, 2024); len(disposals) != 1 || disposals[0].ID != gift.ID {
+		t.Errorf("Expected only the 2024 disposal, got %+v", disposals)
+	}
+
+	report, err := store.GetDisposalReport(ctx, 2024)
+	if err != nil {
+		t.Fatalf("GetDisposalReport failed: %v", err)
+	}
+	if report.Total != 1 || len(report.Kinds) != len(model.DisposalKinds) || report.Kinds[0].Books != 1 || report.Kinds[0].Percent != 100 {
+		t.Errorf("Expected one gift in 2024, got %+v", report)
+	}
+	if len(report.Recipients) != 1 || report.Recipients[0].Value != "Alice" {
+		t.Errorf("Expected Alice as the only recipient, got %+v", report.Recipients)
+	}
+
+	// The ledger outlives the book
+	if err := store.DeleteBook(ctx, book.ID); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if err := store.PurgeBook(ctx, book.ID); err != nil {
+		t.Fatalf("PurgeBook failed: %v", err)
+	}
+	if disposals, _ := store.GetDisposals(ctx, 2024); len(disposals) != 1 || disposals[0].BookID != nil || disposals[0].Title != book.Title {
+		t.Errorf("Expected the disposal kept without its book, got %+v", disposals)
+	}
+
+	if err := store.DeleteDisposal(ctx, donation.ID); err != nil {
+		t.Fatalf("DeleteDisposal failed: %v", err)
+	}
+	if err := store.DeleteDisposal(ctx, donation.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a deleted disposal to be not found, got %v", err)
+	}
+	if got, _ := store.GetBookByID(ctx, other.ID); !got.Archived {
+		t.Error("Expected the book to stay archived once its disposal is deleted")
+	}
+}
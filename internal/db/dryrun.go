@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+)
+
+type dryRunContextKey struct{}
+
+// WithDryRun returns a context in which adding books (AddBook, BatchAddBooks
+// and ImportBooks) and the bulk operations (RollbackImportBatch,
+// CleanDescriptions and SplitSubtitles) do everything but commit: their
+// changes are made and checked in a transaction that is then rolled back, so
+// they report what they would have done and fail as they would have failed.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunContextKey{}, true)
+}
+
+// IsDryRun reports whether ctx is a dry run.
+func IsDryRun(ctx context.Context) bool {
+	dry, _ := ctx.Value(dryRunContextKey{}).(bool)
+	return dry
+}
+
+// commit commits tx, or rolls it back in a dry run.
+func commit(ctx context.Context, tx *sql.Tx) error {
+	if IsDryRun(ctx) {
+		slog.InfoContext(ctx, "SQL: Dry run, rolling back")
+		return tx.Rollback()
+	}
+	return tx.Commit()
+}
+
+// BookChange is a change a bulk operation made, or would make in a dry run,
+// to one field of a book.
+type BookChange struct {
+	BookID int64   `json:"book_id"`
+	Field  string  `json:"field"`
+	From   *string `json:"from"`
+	To     *string `json:"to"`
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Store methods wrap these errors so callers can tell why an operation failed
+// with errors.Is instead of matching on messages.
+var (
+	// ErrNotFound means the record being read, changed or deleted does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate means a record with the same unique key already exists.
+	ErrDuplicate = errors.New("duplicates an existing record")
+	// ErrValidation means a value was rejected, by the store or by a CHECK or
+	// NOT NULL constraint.
+	ErrValidation = errors.New("invalid value")
+	// ErrForeignKey means the record refers to another record that does not exist.
+	ErrForeignKey = errors.New("refers to a missing record")
+	// ErrConflict means the records changed since the client last read them.
+	ErrConflict = errors.New("changed since it was read")
+	// ErrUnsupported means the database backend can't do what was asked,
+	// such as online backups of PostgreSQL.
+	ErrUnsupported = errors.New("not supported by this database")
+)
+
+// classify wraps a SQLite or PostgreSQL constraint violation with the matching
+// store error. Other errors are returned unchanged.
+func classify(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505": // unique_violation
+			return fmt.Errorf("%w: %w", ErrDuplicate, err)
+		case "23514", "23502": // check_violation, not_null_violation
+			return fmt.Errorf("%w: %w", ErrValidation, err)
+		case "23503": // foreign_key_violation
+			return fmt.Errorf("%w: %w", ErrForeignKey, err)
+		default:
+			return err
+		}
+	}
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code != sqlite3.ErrConstraint {
+		return err
+	}
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		return fmt.Errorf("%w: %w", ErrDuplicate, err)
+	case sqlite3.ErrConstraintCheck, sqlite3.ErrConstraintNotNull:
+		return fmt.Errorf("%w: %w", ErrValidation, err)
+	case sqlite3.ErrConstraintForeignKey:
+		return fmt.Errorf("%w: %w", ErrForeignKey, err)
+	default:
+		return err
+	}
+}
+
+// storeError is an error of a known kind with its own message, for failures
+// detected by the store itself rather than by SQLite.
+type storeError struct {
+	kind  error
+	msg   string
+	cause error
+}
+
+func (e *storeError) Error() string { return e.msg }
+
+func (e *storeError) Unwrap() []error {
+	if e.cause == nil {
+		return []error{e.kind}
+	}
+	return []error{e.kind, e.cause}
+}
+
+// invalidf returns an ErrValidation error with a formatted message.
+func invalidf(format string, args ...interface{}) error {
+	return &storeError{kind: ErrValidation, msg: fmt.Sprintf(format, args...)}
+}
+
+// DuplicateBookError is the ErrDuplicate of a book being added that is
+// already on the bookshelf, with the same Open Library ID or ISBN.
+type DuplicateBookError struct {
+	ExistingID int64  // The book already on the bookshelf
+	Field      string // "open_library_id" or "isbn"
+	Value      string
+	Trashed    bool // The book is in the trash, to be restored rather than added again
+}
+
+func (e *DuplicateBookError) Error() string {
+	if e.Trashed {
+		return fmt.Sprintf("a book with %s %s is in the trash (ID %d); restore it instead", e.Field, e.Value, e.ExistingID)
+	}
+	return fmt.Sprintf("a book with %s %s is already on the bookshelf (ID %d)", e.Field, e.Value, e.ExistingID)
+}
+
+func (e *DuplicateBookError) Unwrap() error { return ErrDuplicate }
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestStoreErrorKinds(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	defer teardownTestDB(db)
+	if err := CreateSchema(db); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	store := NewSQLiteBookStore(db)
+
+	book := createTestBook()
+	if _, err := store.AddBook(ctx, book); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+
+	rating := 11
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"missing book", store.UpdateBookStatus(ctx, book.ID+1, model.StatusRead), ErrNotFound},
+		{"missing cover", func() error { _, err := store.GetCoverImage(ctx, "abc"); return err }(), ErrNotFound},
+		{"missing review entry", store.ResolvePendingMatch(ctx, 99, model.MatchSkipped, nil), ErrNotFound},
+		{"duplicate Open Library ID", func() error { _, err := store.AddBook(ctx, createTestBook()); return err }(), ErrDuplicate},
+		{"rating out of range", store.UpdateBook(ctx, book.ID, model.BookPatch{Rating: model.Some(rating)}), ErrValidation},
+		{"bad status", store.UpdateBookStatus(ctx, book.ID, "Lost"), ErrValidation},
+		{"CHECK constraint", func() error {
+			_, err := store.DB.Exec(`UPDATE books SET status = 'Lost' WHERE id = ?;`, book.ID)
+			return classify(err)
+		}(), ErrValidation},
+		{"link to missing account", store.SaveSyncLink(ctx, model.SyncLink{AccountID: 99, BookID: book.ID, RemoteID: "r1", Status: model.StatusRead}), ErrForeignKey},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.err, tt.want)
+		}
+	}
+
+	// Errors keep their messages, and validation failures from the model stay inspectable
+	if err := store.UpdateBookStatus(ctx, book.ID+1, model.StatusRead); err.Error() != "book with ID 2 not found" {
+		t.Errorf("Unexpected not found message: %v", err)
+	}
+	_, err = store.AddBook(ctx, &model.Book{Title: "T", Author: "A", OpenLibraryID: "OL2M", Status: model.StatusRead, Rating: &rating})
+	var validationErr *model.ValidationError
+	if !errors.Is(err, ErrValidation) || !errors.As(err, &validationErr) {
+		t.Errorf("Expected a model validation error, got %v", err)
+	}
+}
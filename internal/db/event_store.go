@@ -0,0 +1,130 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// EventStore defines the database operations for the audit log of books.
+type EventStore interface {
+	GetBookEvents(ctx context.Context, bookID int64) ([]model.BookEvent, error)
+}
+
+// unaudited are the fields of a book left out of the changes of an event:
+// bookkeeping that changes with every event, the cover cache, and reading
+// progress, notes and loans, which have a history of their own.
+var unaudited = map[string]bool{
+	"id": true, "updated_at": true, "added_at": true, "deleted_at": true, "import_batch_id": true,
+	"cover_hash": true, "cover_blurhash": true, "cover_lqip": true, "current_page": true, "progress_percent": true,
+	"note_count": true, "lent_out": true,
+}
+
+// diffBooks returns the fields that differ between two versions of a book,
+// in the order Book declares them.
+func diffBooks(before, after *model.Book) []model.FieldChange {
+	changes := []model.FieldChange{}
+	b, a := reflect.ValueOf(before).Elem(), reflect.ValueOf(after).Elem()
+	for i := 0; i < b.NumField(); i++ {
+		name := strings.Split(b.Type().Field(i).Tag.Get("json"), ",")[0]
+		if name == "-" || unaudited[name] {
+			continue
+		}
+		from, _ := json.Marshal(b.Field(i).Interface())
+		to, _ := json.Marshal(a.Field(i).Interface())
+		if !bytes.Equal(from, to) {
+			changes = append(changes, model.FieldChange{Field: name, From: from, To: to})
+		}
+	}
+	return changes
+}
+
+// recordBookEvent adds an event to the audit log of a book, as made by the
+// user ctx is scoped to. It takes the transaction of the change, so the log
+// has the change exactly when the books table does, and must come before a
+// book is deleted for good, as the log takes the book's owner from its row.
+// An update that changed nothing isn't recorded. The event is queued for the
+// owner's webhooks along with it.
+func recordBookEvent(ctx context.Context, tx execer, event *model.BookEvent) error {
+	if event.Kind == model.EventUpdated && len(event.Changes) == 0 {
+		return nil
+	}
+	var changes interface{}
+	if len(event.Changes) > 0 {
+		data, err := json.Marshal(event.Changes)
+		if err != nil {
+			return fmt.Errorf("failed to encode book changes: %w", err)
+		}
+		changes = string(data)
+	}
+	event.UserID, event.OccurredAt = owner(ctx), time.Now().UTC()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO book_events (user_id, actor_id, book_id, kind, related_book_id, changes, occurred_at)
+        VALUES ((SELECT user_id FROM books WHERE id = ?), ?, ?, ?, ?, ?, ?);`,
+		event.BookID, event.UserID, event.BookID, event.Kind, event.RelatedBookID, changes, event.OccurredAt); err != nil {
+		return fmt.Errorf("failed to record book event: %w", classify(err))
+	}
+	return queueWebhookDeliveries(ctx, tx, event)
+}
+
+// recordBookUpdate records the changes between two versions of a book.
+func recordBookUpdate(ctx context.Context, tx execer, before, after *model.Book) error {
+	return recordBookEvent(ctx, tx, &model.BookEvent{BookID: after.ID, Kind: model.EventUpdated, Changes: diffBooks(before, after)})
+}
+
+// GetBookEvents returns the audit log of a book, oldest first. The log is
+// kept for books in the trash and purged books; a book with neither a log
+// nor a record is not found.
+func (s *SQLiteBookStore) GetBookEvents(ctx context.Context, bookID int64) ([]model.BookEvent, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetBookEvents query", "bookID", bookID)
+	owned, args := ownedBy(ctx, "book_events.user_id")
+	rows, err := s.DB.QueryContext(ctx, `SELECT book_events.id, book_events.kind, book_events.actor_id, COALESCE(users.username, ''),
+            book_events.related_book_id, book_events.changes, book_events.occurred_at
+        FROM book_events LEFT JOIN users ON users.id = book_events.actor_id
+        WHERE book_events.book_id = ? AND `+owned+` ORDER BY book_events.occurred_at, book_events.id;`,
+		append([]interface{}{bookID}, args...)...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetBookEvents query failed", "error", err)
+		return nil, fmt.Errorf("failed to query book events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []model.BookEvent{}
+	for rows.Next() {
+		event := model.BookEvent{BookID: bookID}
+		var changes sql.NullString
+		if err := rows.Scan(&event.ID, &event.Kind, &event.UserID, &event.Username, &event.RelatedBookID, &changes, &event.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan book event row: %w", err)
+		}
+		if changes.Valid {
+			if err := json.Unmarshal([]byte(changes.String), &event.Changes); err != nil {
+				return nil, fmt.Errorf("failed to decode book changes: %w", err)
+			}
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating book event rows: %w", err)
+	}
+	if len(events) > 0 {
+		return events, nil
+	}
+
+	owned, args = ownedBy(ctx, "user_id")
+	var exists bool
+	if err := s.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM books WHERE id = ? AND `+owned+`);`,
+		append([]interface{}{bookID}, args...)...).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up book: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("book with ID %d %w", bookID, ErrNotFound)
+	}
+	return events, nil
+}
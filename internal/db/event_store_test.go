@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestBookEvents(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	if _, err := store.AddBook(ctx, book); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if err := store.UpdateBook(ctx, book.ID, model.BookPatch{Rating: model.Some(9), PageCount: model.Some(412)}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+	// Setting a field to the value it has is not a change
+	if err := store.UpdateBook(ctx, book.ID, model.BookPatch{Rating: model.Some(9)}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+	if err := store.AddRead(ctx, &model.Read{BookID: book.ID, DateFinished: time.Date(2001, 1, 2, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatalf("AddRead failed: %v", err)
+	}
+	if err := store.DeleteBook(ctx, book.ID); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if err := store.RestoreBook(ctx, book.ID); err != nil {
+		t.Fatalf("RestoreBook failed: %v", err)
+	}
+
+	events, err := store.GetBookEvents(ctx, book.ID)
+	if err != nil {
+		t.Fatalf("GetBookEvents failed: %v", err)
+	}
+	kinds := []model.BookEventKind{model.EventCreated, model.EventUpdated, model.EventUpdated, model.EventDeleted, model.EventRestored}
+	if len(events) != len(kinds) {
+		t.Fatalf("Expected %d events, got %+v", len(kinds), events)
+	}
+	for i, kind := range kinds {
+		if events[i].Kind != kind || events[i].BookID != book.ID || events[i].OccurredAt.IsZero() {
+			t.Errorf("Event %d: expected %s, got %+v", i, kind, events[i])
+		}
+	}
+	changes := events[1].Changes
+	if len(changes) != 2 || changes[0].Field != "rating" || string(changes[0].From) != "8" || string(changes[0].To) != "9" ||
+		changes[1].Field != "page_count" || string(changes[1].From) != "null" || string(changes[1].To) != "412" {
+		t.Errorf("Expected the rating and page count changes, got %+v", changes)
+	}
+	if changes := events[2].Changes; len(changes) != 1 || changes[0].Field != "date_finished" {
+		t.Errorf("Expected the read to change the finish date, got %+v", changes)
+	}
+
+	// A failed change leaves no event
+	if err := store.UpdateBook(ctx, book.ID, model.BookPatch{Rating: model.Some(11)}); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+
+	// The log outlives the book, and a merged duplicate's log says where it went
+	duplicate := createTestBook()
+	duplicate.OpenLibraryID = "OL99999M"
+	if _, err := store.AddBook(ctx, duplicate); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if _, err := store.MergeBooks(ctx, book.ID, duplicate.ID); err != nil {
+		t.Fatalf("MergeBooks failed: %v", err)
+	}
+	events, _ = store.GetBookEvents(ctx, duplicate.ID)
+	if len(events) != 2 || events[1].Kind != model.EventMerged || events[1].RelatedBookID == nil || *events[1].RelatedBookID != book.ID {
+		t.Errorf("Expected the duplicate's log to end with the merge, got %+v", events)
+	}
+	if err := store.DeleteBook(ctx, book.ID); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if err := store.PurgeBook(ctx, book.ID); err != nil {
+		t.Fatalf("PurgeBook failed: %v", err)
+	}
+	if events, err := store.GetBookEvents(ctx, book.ID); err != nil || len(events) != 8 || events[7].Kind != model.EventPurged {
+		t.Errorf("Expected the purged book's log, got %+v, %v", events, err)
+	}
+
+	if _, err := store.GetBookEvents(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown book, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// ExportStore defines the database operations behind full and differential exports.
+type ExportStore interface {
+	GetBooksChangedSince(ctx context.Context, since time.Time) ([]model.Book, error)
+	GetTombstonesSince(ctx context.Context, since time.Time) ([]model.BookTombstone, error)
+	RecordExport(ctx context.Context, run *model.ExportRun) error
+	GetExportByID(ctx context.Context, id int64) (*model.ExportRun, error)
+}
+
+// GetBooksChangedSince returns books added or changed after since, oldest change first.
+func (s *SQLiteBookStore) GetBooksChangedSince(ctx context.Context, since time.Time) ([]model.Book, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetBooksChangedSince query", "since", since)
+	owned, args := shelved(ctx, "")
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+bookColumns+` FROM books WHERE updated_at > ? AND `+owned+` ORDER BY updated_at, id;`,
+		append([]interface{}{since.UTC()}, args...)...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetBooksChangedSince query failed", "error", err)
+		return nil, fmt.Errorf("failed to query changed books: %w", err)
+	}
+	defer rows.Close()
+
+	books := []model.Book{}
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan book row: %w", err)
+		}
+		books = append(books, *book)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating book rows: %w", err)
+	}
+	return books, nil
+}
+
+// GetTombstonesSince returns books deleted after since, oldest first.
+func (s *SQLiteBookStore) GetTombstonesSince(ctx context.Context, since time.Time) ([]model.BookTombstone, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetTombstonesSince query", "since", since)
+	owned, args := ownedBy(ctx, "user_id")
+	rows, err := s.DB.QueryContext(ctx, `SELECT book_id, open_library_id, title, deleted_at FROM book_tombstones
+        WHERE deleted_at > ? AND `+owned+` ORDER BY deleted_at, book_id;`, append([]interface{}{since.UTC()}, args...)...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetTombstonesSince query failed", "error", err)
+		return nil, fmt.Errorf("failed to query book tombstones: %w", err)
+	}
+	defer rows.Close()
+
+	tombstones := []model.BookTombstone{}
+	for rows.Next() {
+		var t model.BookTombstone
+		if err := rows.Scan(&t.BookID, &t.OpenLibraryID, &t.Title, &t.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan book tombstone row: %w", err)
+		}
+		tombstones = append(tombstones, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating book tombstone rows: %w", err)
+	}
+	return tombstones, nil
+}
+
+// RecordExport inserts an export run and sets its ID.
+func (s *SQLiteBookStore) RecordExport(ctx context.Context, run *model.ExportRun) error {
+	slog.InfoContext(ctx, "SQL: Executing RecordExport query", "format", run.Format, "since", run.Since)
+	if err := s.DB.QueryRowContext(ctx, `INSERT INTO export_runs (format, since, exported_at, books, deleted) VALUES (?, ?, ?, ?, ?) RETURNING id;`,
+		run.Format, run.Since, run.ExportedAt.UTC(), run.Books, run.Deleted).Scan(&run.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing RecordExport statement failed", "error", err)
+		return fmt.Errorf("failed to record export: %w", classify(err))
+	}
+	return nil
+}
+
+// GetExportByID returns a recorded export run.
+func (s *SQLiteBookStore) GetExportByID(ctx context.Context, id int64) (*model.ExportRun, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetExportByID query", "id", id)
+	var run model.ExportRun
+	var since sql.NullTime
+	err := s.DB.QueryRowContext(ctx, `SELECT id, format, since, exported_at, books, deleted FROM export_runs WHERE id = ?;`, id).
+		Scan(&run.ID, &run.Format, &since, &run.ExportedAt, &run.Books, &run.Deleted)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("export with ID %d %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to scan export row for ID %d: %w", id, err)
+	}
+	if since.Valid {
+		run.Since = &since.Time
+	}
+	return &run, nil
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// FediverseFollower is a remote ActivityPub actor following one of this instance's actors.
+type FediverseFollower struct {
+	ID        int64     `json:"id"`
+	ActorID   string    `json:"actor_id"`
+	InboxURL  string    `json:"inbox_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FederationStore defines the database operations backing the ActivityPub actors:
+// a user's, or for a nil user the library's, which publishes the books without an owner.
+type FederationStore interface {
+	GetSetting(ctx context.Context, key string) (string, bool, error)
+	SetSetting(ctx context.Context, key, value string) error
+	AddFediverseFollower(ctx context.Context, userID *int64, actorID, inboxURL string) error
+	RemoveFediverseFollower(ctx context.Context, userID *int64, actorID string) error
+	GetFediverseFollowers(ctx context.Context, userID *int64) ([]FediverseFollower, error)
+	GetActivityPubKey(ctx context.Context, userID int64) (string, bool, error)
+	SetActivityPubKey(ctx context.Context, userID int64, pemData string) error
+}
+
+// GetSetting returns the value stored for key and whether it exists.
+func (s *SQLiteBookStore) GetSetting(ctx context.Context, key string) (string, bool, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetSetting query", "key", key)
+	var value string
+	err := s.DB.QueryRowContext(ctx, `SELECT value FROM settings WHERE key = ?;`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetSetting query failed", "key", key, "error", err)
+		return "", false, fmt.Errorf("failed to get setting %s: %w", key, err)
+	}
+	return value, true, nil
+}
+
+// SetSetting inserts or replaces the value stored for key.
+func (s *SQLiteBookStore) SetSetting(ctx context.Context, key, value string) error {
+	// Values may be secrets (e.g., private keys), so only the key is logged
+	slog.InfoContext(ctx, "SQL: Executing SetSetting query", "key", key)
+	_, err := s.DB.ExecContext(ctx, `INSERT INTO settings (key, value) VALUES (?, ?)
+        ON CONFLICT(key) DO UPDATE SET value = excluded.value;`, key, value)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing SetSetting statement failed", "key", key, "error", err)
+		return fmt.Errorf("failed to set setting %s: %w", key, err)
+	}
+	return nil
+}
+
+// actorOwner returns the condition matching the rows of the actor of userID.
+// Unlike ownedBy, a nil user matches the library's actor's followers, which
+// the first user adopts with the library but who follow it rather than the
+// user's own actor.
+func actorOwner(userID *int64) (string, []interface{}) {
+	if userID == nil {
+		return "library = ?", []interface{}{true}
+	}
+	return "user_id = ? AND library = ?", []interface{}{*userID, false}
+}
+
+// AddFediverseFollower records a remote follower of the actor of userID, updating
+// its inbox if it already follows it. The library's actor's followers belong
+// to the first user, who has its books, once there is one.
+func (s *SQLiteBookStore) AddFediverseFollower(ctx context.Context, userID *int64, actorID, inboxURL string) error {
+	slog.InfoContext(ctx, "SQL: Executing AddFediverseFollower query", "actorID", actorID, "inbox", inboxURL)
+	_, err := s.DB.ExecContext(ctx, `INSERT INTO fediverse_followers (user_id, library, actor_id, inbox_url, created_at)
+        VALUES (COALESCE(?, (SELECT MIN(id) FROM users)), ?, ?, ?, ?)
+        ON CONFLICT (COALESCE(user_id, 0), library, actor_id) DO UPDATE SET inbox_url = excluded.inbox_url;`,
+		userID, userID == nil, actorID, inboxURL, time.Now().UTC())
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddFediverseFollower statement failed", "error", err)
+		return fmt.Errorf("failed to add follower: %w", classify(err))
+	}
+	return nil
+}
+
+// RemoveFediverseFollower deletes a remote follower of the actor of userID.
+// Removing an unknown follower is not an error.
+func (s *SQLiteBookStore) RemoveFediverseFollower(ctx context.Context, userID *int64, actorID string) error {
+	slog.InfoContext(ctx, "SQL: Executing RemoveFediverseFollower query", "actorID", actorID)
+	owned, args := actorOwner(userID)
+	if _, err := s.DB.ExecContext(ctx, `DELETE FROM fediverse_followers WHERE actor_id = ? AND `+owned+`;`,
+		append([]interface{}{actorID}, args...)...); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing RemoveFediverseFollower statement failed", "error", err)
+		return fmt.Errorf("failed to remove follower: %w", err)
+	}
+	return nil
+}
+
+// GetFediverseFollowers returns the remote followers of the actor of userID.
+func (s *SQLiteBookStore) GetFediverseFollowers(ctx context.Context, userID *int64) ([]FediverseFollower, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetFediverseFollowers query")
+	owned, args := actorOwner(userID)
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, actor_id, inbox_url, created_at FROM fediverse_followers WHERE `+owned+` ORDER BY id;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetFediverseFollowers query failed", "error", err)
+		return nil, fmt.Errorf("failed to query followers: %w", err)
+	}
+	defer rows.Close()
+
+	followers := []FediverseFollower{}
+	for rows.Next() {
+		var f FediverseFollower
+		if err := rows.Scan(&f.ID, &f.ActorID, &f.InboxURL, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan follower row: %w", err)
+		}
+		followers = append(followers, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating follower rows: %w", err)
+	}
+	return followers, nil
+}
+
+// GetActivityPubKey returns the PEM private key of the actor of userID and
+// whether it has one yet.
+func (s *SQLiteBookStore) GetActivityPubKey(ctx context.Context, userID int64) (string, bool, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetActivityPubKey query", "userID", userID)
+	var pemData string
+	err := s.DB.QueryRowContext(ctx, `SELECT private_key FROM activitypub_keys WHERE user_id = ?;`, userID).Scan(&pemData)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetActivityPubKey query failed", "error", err)
+		return "", false, fmt.Errorf("failed to get ActivityPub key of user %d: %w", userID, err)
+	}
+	return pemData, true, nil
+}
+
+// SetActivityPubKey stores the PEM private key of the actor of userID,
+// replacing any it had.
+func (s *SQLiteBookStore) SetActivityPubKey(ctx context.Context, userID int64, pemData string) error {
+	// The key is a secret, so only the user is logged
+	slog.InfoContext(ctx, "SQL: Executing SetActivityPubKey query", "userID", userID)
+	_, err := s.DB.ExecContext(ctx, `INSERT INTO activitypub_keys (user_id, private_key, created_at) VALUES (?, ?, ?)
+        ON CONFLICT(user_id) DO UPDATE SET private_key = excluded.private_key;`, userID, pemData, time.Now().UTC())
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing SetActivityPubKey statement failed", "error", err)
+		return fmt.Errorf("failed to set ActivityPub key of user %d: %w", userID, classify(err))
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// GoalStore defines the database operations for reading goals, the number of
+// books to finish in a year.
+type GoalStore interface {
+	GetReadingGoals(ctx context.Context) ([]model.GoalProgress, error)
+	GetReadingGoal(ctx context.Context, year int) (*model.GoalProgress, error)
+	SetReadingGoal(ctx context.Context, goal *model.ReadingGoal) error
+	DeleteReadingGoal(ctx context.Context, year int) error
+}
+
+// GetReadingGoals returns every reading goal with its progress, latest year
+// first.
+func (s *SQLiteBookStore) GetReadingGoals(ctx context.Context) ([]model.GoalProgress, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetReadingGoals query")
+	owned, args := ownedBy(ctx, "user_id")
+	rows, err := s.DB.QueryContext(ctx, `SELECT year, target, created_at, updated_at FROM reading_goals WHERE `+owned+`
+        ORDER BY year DESC;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetReadingGoals query failed", "error", err)
+		return nil, fmt.Errorf("failed to query reading goals: %w", err)
+	}
+	var goals []model.ReadingGoal
+	for rows.Next() {
+		var g model.ReadingGoal
+		if err := rows.Scan(&g.Year, &g.Target, &g.CreatedAt, &g.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan reading goal row: %w", err)
+		}
+		goals = append(goals, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reading goal rows: %w", err)
+	}
+
+	vacations, err := s.GetVacations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	progress := []model.GoalProgress{}
+	for _, g := range goals {
+		p, err := s.goalProgress(ctx, g, vacations)
+		if err != nil {
+			return nil, err
+		}
+		progress = append(progress, p)
+	}
+	return progress, nil
+}
+
+// GetReadingGoal returns the reading goal of a year with its progress.
+func (s *SQLiteBookStore) GetReadingGoal(ctx context.Context, year int) (*model.GoalProgress, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetReadingGoal query", "year", year)
+	owned, args := ownedBy(ctx, "user_id")
+	g := model.ReadingGoal{Year: year}
+	err := s.DB.QueryRowContext(ctx, `SELECT target, created_at, updated_at FROM reading_goals WHERE year = ? AND `+owned+`;`,
+		append([]interface{}{year}, args...)...).Scan(&g.Target, &g.CreatedAt, &g.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("reading goal for %d %w", year, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reading goal: %w", err)
+	}
+	vacations, err := s.GetVacations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.goalProgress(ctx, g, vacations)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// goalProgress counts the leisure reads finished in the goal's year, which
+// the database aggregates, and paces them against the goal.
+func (s *SQLiteBookStore) goalProgress(ctx context.Context, goal model.ReadingGoal, vacations []model.Vacation) (model.GoalProgress, error) {
+	from := time.Date(goal.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	counted, args := s.counted(ctx, "books")
+	var finished int
+	if err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM reads JOIN books ON books.id = reads.book_id
+        WHERE reads.date_finished >= ? AND reads.date_finished < ? AND `+counted+`;`,
+		append([]interface{}{from, from.AddDate(1, 0, 0)}, args...)...).Scan(&finished); err != nil {
+		return model.GoalProgress{}, fmt.Errorf("failed to count finished books: %w", err)
+	}
+	return goal.Progress(finished, vacations, time.Now()), nil
+}
+
+// SetReadingGoal sets the target of a year's reading goal, adding the goal if
+// the year has none, and sets its times.
+func (s *SQLiteBookStore) SetReadingGoal(ctx context.Context, goal *model.ReadingGoal) error {
+	if err := goal.Validate(); err != nil {
+		return &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
+	}
+	slog.InfoContext(ctx, "SQL: Executing SetReadingGoal statement", "year", goal.Year, "target", goal.Target)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	owned, args := ownedBy(ctx, "user_id")
+	goal.UpdatedAt = time.Now().UTC()
+	err = tx.QueryRowContext(ctx, `UPDATE reading_goals SET target = ?, updated_at = ? WHERE year = ? AND `+owned+` RETURNING created_at;`,
+		append([]interface{}{goal.Target, goal.UpdatedAt, goal.Year}, args...)...).Scan(&goal.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		goal.CreatedAt = goal.UpdatedAt
+		_, err = tx.ExecContext(ctx, `INSERT INTO reading_goals (user_id, year, target, created_at, updated_at) VALUES (?, ?, ?, ?, ?);`,
+			owner(ctx), goal.Year, goal.Target, goal.CreatedAt, goal.UpdatedAt)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing SetReadingGoal statement failed", "error", err)
+		return fmt.Errorf("failed to set reading goal: %w", classify(err))
+	}
+	return tx.Commit()
+}
+
+// DeleteReadingGoal removes the reading goal of a year.
+func (s *SQLiteBookStore) DeleteReadingGoal(ctx context.Context, year int) error {
+	slog.InfoContext(ctx, "SQL: Executing DeleteReadingGoal query", "year", year)
+	owned, args := ownedBy(ctx, "user_id")
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM reading_goals WHERE year = ? AND `+owned+`;`, append([]interface{}{year}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete reading goal: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("reading goal for %d %w", year, ErrNotFound)
+	}
+	return nil
+}
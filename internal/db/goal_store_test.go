@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestReadingGoals(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	for i, finished := range []time.Time{
+		time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		book := createTestBook()
+		book.OpenLibraryID = fmt.Sprintf("OL%dM", i)
+		book.Status, book.DateFinished = model.StatusRead, &finished
+		if i == 1 {
+			book.ReadingMode = model.ModeReference
+		}
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+	}
+
+	goal := model.ReadingGoal{Year: 2024, Target: 10}
+	if err := store.SetReadingGoal(ctx, &goal); err != nil || goal.CreatedAt.IsZero() {
+		t.Fatalf("SetReadingGoal failed: %+v, %v", goal, err)
+	}
+	created := goal.CreatedAt
+	goal.Target = 2
+	if err := store.SetReadingGoal(ctx, &goal); err != nil || !goal.CreatedAt.Equal(created) {
+		t.Fatalf("Changing a goal failed: %+v, %v", goal, err)
+	}
+	if err := store.SetReadingGoal(ctx, &model.ReadingGoal{Year: 2025, Target: 24}); err != nil {
+		t.Fatalf("SetReadingGoal failed: %v", err)
+	}
+	for _, bad := range []model.ReadingGoal{{Year: 2025}, {Year: 0, Target: 5}, {Year: 2025, Target: model.MaxGoalTarget + 1}} {
+		if err := store.SetReadingGoal(ctx, &bad); !errors.Is(err, ErrValidation) {
+			t.Errorf("SetReadingGoal(%+v): expected a validation error, got %v", bad, err)
+		}
+	}
+
+	// Reference books don't count
+	progress, err := store.GetReadingGoal(ctx, 2024)
+	if err != nil || progress.Target != 2 || progress.Finished != 1 || progress.Remaining != 1 || progress.Completed || progress.Behind != 1 {
+		t.Errorf("Unexpected progress for 2024: %+v, %v", progress, err)
+	}
+	goals, err := store.GetReadingGoals(ctx)
+	if err != nil || len(goals) != 2 || goals[0].Year != 2025 || goals[0].Finished != 1 || goals[1].Year != 2024 {
+		t.Errorf("Expected both goals, latest first, got %+v, %v", goals, err)
+	}
+	if _, err := store.GetReadingGoal(ctx, 2023); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected no goal for 2023, got %v", err)
+	}
+
+	if err := store.DeleteReadingGoal(ctx, 2024); err != nil {
+		t.Fatalf("DeleteReadingGoal failed: %v", err)
+	}
+	if err := store.DeleteReadingGoal(ctx, 2024); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found deleting twice, got %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// ImportStore defines the database operations for import batches, which
+// group the books an import added so the import can be undone.
+type ImportStore interface {
+	ImportBooks(ctx context.Context, batch *model.ImportBatch, books []*model.Book) error
+	AddImportBatch(ctx context.Context, batch *model.ImportBatch) error
+	GetImportBatches(ctx context.Context) ([]model.ImportBatch, error)
+	GetImportBatchByID(ctx context.Context, id int64) (*model.ImportBatch, error)
+	DeleteImportBatch(ctx context.Context, id int64) error
+	RollbackImportBatch(ctx context.Context, id int64, force bool) (*model.ImportRollback, error)
+}
+
+const importBatchColumns = `id, source, name, created_at, rolled_back_at,
+        (SELECT COUNT(*) FROM books WHERE books.import_batch_id = import_batches.id AND books.deleted_at IS NULL)`
+
+// insertImportBatch stores a batch for userID and sets its ID and creation time.
+func insertImportBatch(ctx context.Context, db execer, batch *model.ImportBatch, userID *int64) error {
+	if !batch.Source.IsValid() {
+		return invalidf("invalid import source: %s", batch.Source)
+	}
+	slog.InfoContext(ctx, "SQL: Executing AddImportBatch query", "source", batch.Source, "name", batch.Name)
+	batch.CreatedAt = time.Now().UTC()
+	if err := db.QueryRowContext(ctx, `INSERT INTO import_batches (source, name, created_at, user_id) VALUES (?, ?, ?, ?) RETURNING id;`,
+		batch.Source, batch.Name, batch.CreatedAt, userID).Scan(&batch.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddImportBatch statement failed", "error", err)
+		return fmt.Errorf("failed to add import batch: %w", classify(err))
+	}
+	return nil
+}
+
+// ImportBooks adds books like BatchAddBooks, as a new batch. The batch is
+// only created if every book is added.
+func (s *SQLiteBookStore) ImportBooks(ctx context.Context, batch *model.ImportBatch, books []*model.Book) error {
+	slog.InfoContext(ctx, "SQL: Executing ImportBooks", "count", len(books), "source", batch.Source)
+	failed, err := s.addBooks(ctx, books, batch)
+	if err != nil && failed >= 0 {
+		return fmt.Errorf("book %d (%q): %w", failed+1, books[failed].Title, err)
+	}
+	if err == nil && !IsDryRun(ctx) {
+		batch.BookCount = len(books)
+	}
+	return err
+}
+
+// AddImportBatch stores an empty batch and sets its ID and creation time, for
+// imports that add their books one at a time by setting their ImportBatchID.
+func (s *SQLiteBookStore) AddImportBatch(ctx context.Context, batch *model.ImportBatch) error {
+	return insertImportBatch(ctx, s.DB, batch, owner(ctx))
+}
+
+func scanImportBatch(row rowScanner) (*model.ImportBatch, error) {
+	var batch model.ImportBatch
+	var rolledBackAt sql.NullTime
+	if err := row.Scan(&batch.ID, &batch.Source, &batch.Name, &batch.CreatedAt, &rolledBackAt, &batch.BookCount); err != nil {
+		return nil, err
+	}
+	if rolledBackAt.Valid {
+		batch.RolledBackAt = &rolledBackAt.Time
+	}
+	return &batch, nil
+}
+
+// GetImportBatches returns every import batch, newest first.
+func (s *SQLiteBookStore) GetImportBatches(ctx context.Context) ([]model.ImportBatch, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetImportBatches query")
+	owned, args := ownedBy(ctx, "user_id")
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+importBatchColumns+` FROM import_batches WHERE `+owned+`
+        ORDER BY created_at DESC, id DESC;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetImportBatches query failed", "error", err)
+		return nil, fmt.Errorf("failed to query import batches: %w", err)
+	}
+	defer rows.Close()
+
+	batches := []model.ImportBatch{}
+	for rows.Next() {
+		batch, err := scanImportBatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan import batch row: %w", err)
+		}
+		batches = append(batches, *batch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating import batch rows: %w", err)
+	}
+	return batches, nil
+}
+
+// GetImportBatchByID returns one import batch.
+func (s *SQLiteBookStore) GetImportBatchByID(ctx context.Context, id int64) (*model.ImportBatch, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetImportBatchByID query", "id", id)
+	return s.getImportBatch(ctx, s.DB, id)
+}
+
+func (s *SQLiteBookStore) getImportBatch(ctx context.Context, db execer, id int64) (*model.ImportBatch, error) {
+	owned, args := ownedBy(ctx, "user_id")
+	batch, err := scanImportBatch(db.QueryRowContext(ctx, `SELECT `+importBatchColumns+` FROM import_batches WHERE id = ? AND `+owned+`;`,
+		append([]interface{}{id}, args...)...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("import batch with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import batch: %w", err)
+	}
+	return batch, nil
+}
+
+// DeleteImportBatch forgets a batch. Its books stay on the bookshelf but can
+// no longer be rolled back together.
+func (s *SQLiteBookStore) DeleteImportBatch(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "SQL: Executing DeleteImportBatch query", "id", id)
+	owned, args := ownedBy(ctx, "user_id")
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM import_batches WHERE id = ? AND `+owned+`;`, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete import batch: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("import batch with ID %d %w", id, ErrNotFound)
+	}
+	// Foreign keys may be off (they are per connection), so the books are let go explicitly
+	if _, err := tx.ExecContext(ctx, `UPDATE books SET import_batch_id = NULL WHERE import_batch_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to release imported books: %w", err)
+	}
+	return tx.Commit()
+}
+
+// RollbackImportBatch deletes the books a batch added for good, as
+// PurgeBook would, whether or not they are in the trash.
+// Books changed or tagged since the import are kept and reported, unless
+// force is set. The batch is marked rolled back and keeps any kept books, so
+// a forced rollback can follow. A dry run reports the books that would be
+// deleted and kept.
+func (s *SQLiteBookStore) RollbackImportBatch(ctx context.Context, id int64, force bool) (*model.ImportRollback, error) {
+	slog.InfoContext(ctx, "SQL: Executing RollbackImportBatch", "id", id, "force", force)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := s.getImportBatch(ctx, tx, id); err != nil {
+		return nil, err
+	}
+
+	// Reading history logged since the import bumps updated_at, so it counts as a change
+	rows, err := tx.QueryContext(ctx, `SELECT `+bookColumns+`, EXISTS (SELECT 1 FROM book_tags WHERE book_tags.book_id = books.id)
+        FROM books WHERE import_batch_id = ? ORDER BY id;`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query imported books: %w", err)
+	}
+	type imported struct {
+		book    *model.Book
+		changed bool
+	}
+	var books []imported
+	for rows.Next() {
+		var tagged bool
+		book, err := scanBook(taggedScanner{rows, &tagged})
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan imported book: %w", err)
+		}
+		edited := book.UpdatedAt != nil && book.AddedAt != nil && book.UpdatedAt.After(*book.AddedAt)
+		books = append(books, imported{book, tagged || edited})
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("error iterating imported books: %w", err)
+	}
+	rows.Close()
+
+	result := &model.ImportRollback{Deleted: []model.RolledBackBook{}, Kept: []model.RolledBackBook{}}
+	for _, b := range books {
+		rolled := model.RolledBackBook{ID: b.book.ID, Title: b.book.Title}
+		if b.changed && !force {
+			result.Kept = append(result.Kept, rolled)
+			continue
+		}
+		if err := recordBookEvent(ctx, tx, &model.BookEvent{BookID: b.book.ID, Kind: model.EventPurged}); err != nil {
+			return nil, err
+		}
+		if err := deleteBook(ctx, tx, b.book); err != nil {
+			return nil, err
+		}
+		result.Deleted = append(result.Deleted, rolled)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE import_batches SET rolled_back_at = ? WHERE id = ?;`, time.Now().UTC(), id); err != nil {
+		return nil, fmt.Errorf("failed to mark import batch rolled back: %w", err)
+	}
+	batch, err := s.getImportBatch(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := commit(ctx, tx); err != nil {
+		return nil, fmt.Errorf("failed to commit rollback: %w", err)
+	}
+	result.Batch = *batch
+	slog.InfoContext(ctx, "SQL: Rolled back import batch", "id", id, "deleted", len(result.Deleted), "kept", len(result.Kept))
+	return result, nil
+}
+
+// taggedScanner scans a book row followed by one extra column into tagged.
+type taggedScanner struct {
+	rows   *sql.Rows
+	tagged *bool
+}
+
+func (t taggedScanner) Scan(dest ...interface{}) error {
+	return t.rows.Scan(append(dest, t.tagged)...)
+}
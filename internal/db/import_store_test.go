@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestRollbackImportBatch(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	manual := createTestBook()
+	if _, err := store.AddBook(ctx, manual); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	var books []*model.Book
+	for _, id := range []string{"OL1M", "OL2M", "OL3M"} {
+		book := createTestBook()
+		book.Title, book.OpenLibraryID, book.Source = "Imported "+id, id, model.BookSourceGoodreads
+		books = append(books, book)
+	}
+	batch := &model.ImportBatch{Source: model.BookSourceGoodreads, Name: "goodreads_library_export.csv"}
+	if err := store.ImportBooks(ctx, batch, books); err != nil || batch.ID == 0 || batch.BookCount != 3 {
+		t.Fatalf("ImportBooks failed: %+v, %v", batch, err)
+	}
+	if books[0].ImportBatchID == nil || *books[0].ImportBatchID != batch.ID {
+		t.Fatalf("Expected the books to join the batch, got %v", books[0].ImportBatchID)
+	}
+
+	// A rejected book leaves neither books nor a batch behind
+	bad := &model.ImportBatch{Source: model.BookSourceGoodreads}
+	duplicate := createTestBook()
+	if err := store.ImportBooks(ctx, bad, []*model.Book{{Title: "Fresh", Author: "A", OpenLibraryID: "OL9M"}, duplicate}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected a duplicate error, got %v", err)
+	}
+	if batches, err := store.GetImportBatches(ctx); err != nil || len(batches) != 1 || batches[0].BookCount != 3 {
+		t.Fatalf("Expected only the imported batch, got %+v, %v", batches, err)
+	}
+
+	// Books changed or tagged since the import are kept
+	if err := store.UpdateBookStatus(ctx, books[0].ID, model.StatusRead); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	if _, err := store.AddBookTag(ctx, books[1].ID, "favourites"); err != nil {
+		t.Fatalf("AddBookTag failed: %v", err)
+	}
+	rollback, err := store.RollbackImportBatch(ctx, batch.ID, false)
+	if err != nil {
+		t.Fatalf("RollbackImportBatch failed: %v", err)
+	}
+	if len(rollback.Deleted) != 1 || rollback.Deleted[0].ID != books[2].ID || len(rollback.Kept) != 2 {
+		t.Errorf("Expected only the untouched book to be deleted, got %+v", rollback)
+	}
+	if rollback.Batch.RolledBackAt == nil || rollback.Batch.BookCount != 2 {
+		t.Errorf("Expected the batch to be rolled back with two books left, got %+v", rollback.Batch)
+	}
+	if _, err := store.GetBookByID(ctx, books[2].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the rolled back book to be gone, got %v", err)
+	}
+
+	rollback, err = store.RollbackImportBatch(ctx, batch.ID, true)
+	if err != nil || len(rollback.Deleted) != 2 || len(rollback.Kept) != 0 || rollback.Batch.BookCount != 0 {
+		t.Errorf("Expected a forced rollback to delete the rest, got %+v, %v", rollback, err)
+	}
+	if all, err := store.GetBooks(ctx); err != nil || len(all) != 1 || all[0].ID != manual.ID {
+		t.Errorf("Expected only the manually added book to remain, got %+v, %v", all, err)
+	}
+	if _, err := store.RollbackImportBatch(ctx, 999, false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown batch, got %v", err)
+	}
+}
+
+func TestDeleteImportBatch(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	batch := &model.ImportBatch{Source: model.BookSourceListImport, Name: "Summer reading"}
+	if err := store.AddImportBatch(ctx, batch); err != nil || batch.ID == 0 {
+		t.Fatalf("AddImportBatch failed: %+v, %v", batch, err)
+	}
+	if err := store.AddImportBatch(ctx, &model.ImportBatch{Source: "csv"}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a validation error for an unknown source, got %v", err)
+	}
+	book := createTestBook()
+	book.ImportBatchID = &batch.ID
+	if _, err := store.AddBook(ctx, book); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if got, err := store.GetImportBatchByID(ctx, batch.ID); err != nil || got.BookCount != 1 || got.Name != "Summer reading" {
+		t.Fatalf("Expected the batch with its book, got %+v, %v", got, err)
+	}
+
+	if err := store.DeleteImportBatch(ctx, batch.ID); err != nil {
+		t.Fatalf("DeleteImportBatch failed: %v", err)
+	}
+	kept, err := store.GetBookByID(ctx, book.ID)
+	if err != nil || kept.ImportBatchID != nil {
+		t.Errorf("Expected the book to stay without a batch, got %+v, %v", kept, err)
+	}
+	if err := store.DeleteImportBatch(ctx, batch.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+}
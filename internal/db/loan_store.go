@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// LoanStore defines the database operations for lending physical books.
+type LoanStore interface {
+	LendBook(ctx context.Context, loan *model.Loan) error
+	ReturnBook(ctx context.Context, bookID int64, returnedOn string) (*model.Loan, error)
+	GetLoans(ctx context.Context, bookID int64) ([]model.Loan, error)
+	GetOutstandingLoans(ctx context.Context) ([]model.OutstandingLoan, error)
+}
+
+const loanColumns = `loans.id, loans.book_id, loans.borrower, loans.lent_on, loans.due_on, loans.returned_on, loans.created_at`
+
+func scanLoan(row rowScanner, extra ...interface{}) (*model.Loan, error) {
+	var loan model.Loan
+	var dueOn, returnedOn sql.NullString
+	if err := row.Scan(append([]interface{}{&loan.ID, &loan.BookID, &loan.Borrower, &loan.LentOn, &dueOn, &returnedOn, &loan.CreatedAt}, extra...)...); err != nil {
+		return nil, err
+	}
+	if dueOn.Valid {
+		loan.DueOn = &dueOn.String
+	}
+	if returnedOn.Valid {
+		loan.ReturnedOn = &returnedOn.String
+	}
+	loan.Overdue = loan.IsOverdue(time.Now())
+	return &loan, nil
+}
+
+// LendBook records a book being lent out and sets the loan's ID and creation
+// time. A book already lent out is ErrDuplicate until it is returned.
+func (s *SQLiteBookStore) LendBook(ctx context.Context, loan *model.Loan) error {
+	if err := loan.Validate(); err != nil {
+		return &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
+	}
+	book, err := s.GetBookByID(ctx, loan.BookID)
+	if err != nil {
+		return err
+	}
+	if book.LentOut {
+		return &storeError{kind: ErrDuplicate, msg: fmt.Sprintf("book with ID %d is already lent out; return it first", book.ID)}
+	}
+	slog.InfoContext(ctx, "SQL: Executing LendBook query", "bookID", loan.BookID)
+	loan.CreatedAt = time.Now().UTC()
+	if err := s.DB.QueryRowContext(ctx, `INSERT INTO loans (book_id, borrower, lent_on, due_on, created_at) VALUES (?, ?, ?, ?, ?) RETURNING id;`,
+		loan.BookID, loan.Borrower, loan.LentOn, loan.DueOn, loan.CreatedAt).Scan(&loan.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Inserting loan failed", "error", err)
+		return fmt.Errorf("failed to lend book: %w", classify(err))
+	}
+	loan.Overdue = loan.IsOverdue(time.Now())
+	return nil
+}
+
+// ReturnBook closes the outstanding loan of a book on returnedOn, today in
+// UTC when empty, and returns the loan. A book that isn't lent out is
+// ErrNotFound.
+func (s *SQLiteBookStore) ReturnBook(ctx context.Context, bookID int64, returnedOn string) (*model.Loan, error) {
+	if returnedOn == "" {
+		returnedOn = time.Now().UTC().Format(model.DateLayout)
+	}
+	if _, err := time.Parse(model.DateLayout, returnedOn); err != nil {
+		return nil, invalidf("returned_on must be a date formatted as YYYY-MM-DD")
+	}
+	if _, err := s.GetBookByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "SQL: Executing ReturnBook", "bookID", bookID)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	loan, err := scanLoan(tx.QueryRowContext(ctx, `SELECT `+loanColumns+` FROM loans WHERE book_id = ? AND returned_on IS NULL;`, bookID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("outstanding loan of book with ID %d %w", bookID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get loan: %w", err)
+	}
+	if returnedOn < loan.LentOn {
+		return nil, invalidf("returned_on must not be before lent_on (%s)", loan.LentOn)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE loans SET returned_on = ? WHERE id = ?;`, returnedOn, loan.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Returning loan failed", "error", err)
+		return nil, fmt.Errorf("failed to return book: %w", classify(err))
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit return: %w", err)
+	}
+	loan.ReturnedOn, loan.Overdue = &returnedOn, false
+	return loan, nil
+}
+
+// GetLoans returns every loan of a book, the latest first.
+func (s *SQLiteBookStore) GetLoans(ctx context.Context, bookID int64) ([]model.Loan, error) {
+	if _, err := s.GetBookByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "SQL: Executing GetLoans query", "bookID", bookID)
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+loanColumns+` FROM loans WHERE book_id = ? ORDER BY lent_on DESC, id DESC;`, bookID)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetLoans query failed", "error", err)
+		return nil, fmt.Errorf("failed to query loans: %w", err)
+	}
+	defer rows.Close()
+
+	loans := []model.Loan{}
+	for rows.Next() {
+		loan, err := scanLoan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan loan row: %w", err)
+		}
+		loans = append(loans, *loan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating loan rows: %w", err)
+	}
+	return loans, nil
+}
+
+// GetOutstandingLoans returns the loans not yet returned of the books on the
+// shelf, the longest out first.
+func (s *SQLiteBookStore) GetOutstandingLoans(ctx context.Context) ([]model.OutstandingLoan, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetOutstandingLoans query")
+	owned, args := shelved(ctx, "books")
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+loanColumns+`, books.title, books.author
+        FROM loans JOIN books ON books.id = loans.book_id
+        WHERE loans.returned_on IS NULL AND `+owned+` ORDER BY loans.lent_on, loans.id;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetOutstandingLoans query failed", "error", err)
+		return nil, fmt.Errorf("failed to query loans: %w", err)
+	}
+	defer rows.Close()
+
+	loans := []model.OutstandingLoan{}
+	for rows.Next() {
+		var outstanding model.OutstandingLoan
+		loan, err := scanLoan(rows, &outstanding.Title, &outstanding.Author)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan loan row: %w", err)
+		}
+		outstanding.Loan = *loan
+		loans = append(loans, outstanding)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating loan rows: %w", err)
+	}
+	return loans, nil
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestLoans(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	if _, err := store.AddBook(ctx, book); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+
+	due := "2020-02-01"
+	loan := &model.Loan{BookID: book.ID, Borrower: "  Alice  ", LentOn: "2020-01-01", DueOn: &due}
+	if err := store.LendBook(ctx, loan); err != nil {
+		t.Fatalf("LendBook failed: %v", err)
+	}
+	if loan.ID == 0 || loan.Borrower != "Alice" || !loan.Overdue {
+		t.Errorf("Expected an overdue loan to Alice, got %+v", loan)
+	}
+	if got, _ := store.GetBookByID(ctx, book.ID); !got.LentOut {
+		t.Error("Expected the book to be lent out")
+	}
+	if err := store.LendBook(ctx, &model.Loan{BookID: book.ID, Borrower: "Bob"}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected lending a lent out book to be a duplicate, got %v", err)
+	}
+	early := "2019-12-31"
+	for _, bad := range []*model.Loan{{BookID: book.ID}, {BookID: book.ID, Borrower: "x", LentOn: "Jan 1"}, {BookID: book.ID, Borrower: "x", LentOn: "2020-01-01", DueOn: &early}} {
+		if err := store.LendBook(ctx, bad); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected %+v to be invalid, got %v", bad, err)
+		}
+	}
+	if err := store.LendBook(ctx, &model.Loan{BookID: 999, Borrower: "x"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected lending a missing book to be not found, got %v", err)
+	}
+
+	outstanding, err := store.GetOutstandingLoans(ctx)
+	if err != nil || len(outstanding) != 1 || outstanding[0].ID != loan.ID || outstanding[0].Title != book.Title {
+		t.Fatalf("Expected the loan outstanding with its book, got %+v, %v", outstanding, err)
+	}
+
+	if _, err := store.ReturnBook(ctx, book.ID, "2019-12-01"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a return before the loan to be invalid, got %v", err)
+	}
+	returned, err := store.ReturnBook(ctx, book.ID, "2020-01-15")
+	if err != nil || returned.ID != loan.ID || returned.ReturnedOn == nil || *returned.ReturnedOn != "2020-01-15" || returned.Overdue {
+		t.Fatalf("Expected the loan returned, got %+v, %v", returned, err)
+	}
+	if _, err := store.ReturnBook(ctx, book.ID, ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected returning a book not lent out to be not found, got %v", err)
+	}
+	if got, _ := store.GetBookByID(ctx, book.ID); got.LentOut {
+		t.Error("Expected the returned book not to be lent out")
+	}
+	if outstanding, _ := store.GetOutstandingLoans(ctx); len(outstanding) != 0 {
+		t.Errorf("Expected no outstanding loans, got %+v", outstanding)
+	}
+
+	// It can be lent again once returned
+	if err := store.LendBook(ctx, &model.Loan{BookID: book.ID, Borrower: "Bob"}); err != nil {
+		t.Fatalf("LendBook failed: %v", err)
+	}
+	loans, err := store.GetLoans(ctx, book.ID)
+	if err != nil || len(loans) != 2 || loans[0].Borrower != "Bob" || loans[1].ReturnedOn == nil {
+		t.Errorf("Expected both loans, the latest first, got %+v, %v", loans, err)
+	}
+}
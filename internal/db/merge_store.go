@@ -0,0 +1,456 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// MergeStore defines the database operations for merging duplicate books and
+// undoing merges.
+type MergeStore interface {
+	MergeBooks(ctx context.Context, bookID, duplicateID int64) (*model.BookMerge, error)
+	GetBookMerges(ctx context.Context) ([]model.BookMerge, error)
+	UnmergeBooks(ctx context.Context, id int64) (*model.BookMerge, error)
+}
+
+// mergeFields are the fields a merge fills in on the kept book from the
+// duplicate, by column, which is also the JSON name. The first column of a
+// group decides whether the group is filled, so a cover URL comes with its
+// cached copy and a series with its position.
+var mergeFields = [][]string{
+	{"subtitle"}, {"isbn"}, {"rating"}, {"comments"}, {"description"}, {"cover_url", "cover_hash"},
+	{"series", "series_index"}, {"publish_year"}, {"edition"}, {"page_count"}, {"publisher"}, {"language"}, {"course_code"}, {"semester"}, {"favorite"}, {"label"},
+}
+
+// mergeSnapshot is what book_merges.snapshot holds: the merge as reported,
+// plus what moved from the duplicate to the kept book.
+type mergeSnapshot struct {
+	Before           model.Book `json:"before"`
+	Duplicate        model.Book `json:"duplicate"`
+	Filled           []string   `json:"filled"`
+	Reads            []int64    `json:"reads"`             // Reads of the duplicate, moved to the kept book
+	Notes            []int64    `json:"notes"`             // Notes of the duplicate, moved to the kept book
+	Quotes           []int64    `json:"quotes"`            // Quotes of the duplicate, moved to the kept book
+	Loans            []int64    `json:"loans"`             // Loans of the duplicate, moved to the kept book
+	Tags             []int64    `json:"tags"`              // Tags of the duplicate
+	AddedTags        []int64    `json:"added_tags"`        // Tags of the duplicate the kept book didn't have
+	Collections      []int64    `json:"collections"`       // Collections the duplicate was in
+	AddedCollections []int64    `json:"added_collections"` // Collections of the duplicate the kept book wasn't in
+	SyncLinks        []int64    `json:"sync_links"`        // Tracker accounts whose link moved to the kept book
+}
+
+// bookField returns the field of book with the JSON name name.
+func bookField(book *model.Book, name string) reflect.Value {
+	v := reflect.ValueOf(book).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0] == name {
+			return v.Field(i)
+		}
+	}
+	panic("no book field " + name)
+}
+
+// updateBookFields sets the given columns of a stored book to the values of book.
+func updateBookFields(ctx context.Context, tx execer, book *model.Book, columns []string) error {
+	if len(columns) == 0 {
+		return nil
+	}
+	set := make([]string, len(columns))
+	args := make([]interface{}, 0, len(columns)+2)
+	for i, column := range columns {
+		set[i] = column + " = ?"
+		args = append(args, bookField(book, column).Interface())
+	}
+	args = append(args, time.Now().UTC(), book.ID)
+	if _, err := tx.ExecContext(ctx, `UPDATE books SET `+strings.Join(set, ", ")+`, updated_at = ? WHERE id = ?;`, args...); err != nil {
+		return fmt.Errorf("failed to update merged book: %w", classify(err))
+	}
+	return nil
+}
+
+// queryIDs returns the single int64 column of a query.
+func queryIDs(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *SQLiteBookStore) getBookTx(ctx context.Context, tx *sql.Tx, id int64) (*model.Book, error) {
+	owned, args := shelved(ctx, "")
+	book, err := scanBook(tx.QueryRowContext(ctx, `SELECT `+bookColumns+` FROM books WHERE id = ? AND `+owned+`;`,
+		append([]interface{}{id}, args...)...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("book with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book: %w", err)
+	}
+	return book, nil
+}
+
+// MergeBooks merges a duplicate into a book. The book keeps its own fields
+// and takes those it lacks from the duplicate; it gains the duplicate's
+// reading history, tags, collections and tracker links, and the duplicate is
+// deleted for good, as PurgeBook would. Both records are kept in the merge,
+// for UnmergeBooks.
+func (s *SQLiteBookStore) MergeBooks(ctx context.Context, bookID, duplicateID int64) (*model.BookMerge, error) {
+	slog.InfoContext(ctx, "SQL: Executing MergeBooks", "bookID", bookID, "duplicateID", duplicateID)
+	if bookID == duplicateID {
+		return nil, invalidf("a book cannot be merged into itself")
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	book, err := s.getBookTx(ctx, tx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	duplicate, err := s.getBookTx(ctx, tx, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := mergeSnapshot{Before: *book, Duplicate: *duplicate, Filled: []string{}}
+	if snapshot.Reads, err = queryIDs(ctx, tx, `SELECT id FROM reads WHERE book_id = ? ORDER BY id;`, duplicateID); err != nil {
+		return nil, fmt.Errorf("failed to query reads: %w", err)
+	}
+	if snapshot.Notes, err = queryIDs(ctx, tx, `SELECT id FROM book_notes WHERE book_id = ? ORDER BY id;`, duplicateID); err != nil {
+		return nil, fmt.Errorf("failed to query notes: %w", err)
+	}
+	if snapshot.Quotes, err = queryIDs(ctx, tx, `SELECT id FROM quotes WHERE book_id = ? ORDER BY id;`, duplicateID); err != nil {
+		return nil, fmt.Errorf("failed to query quotes: %w", err)
+	}
+	if snapshot.Loans, err = queryIDs(ctx, tx, `SELECT id FROM loans WHERE book_id = ? ORDER BY id;`, duplicateID); err != nil {
+		return nil, fmt.Errorf("failed to query loans: %w", err)
+	}
+	if snapshot.Tags, err = queryIDs(ctx, tx, `SELECT tag_id FROM book_tags WHERE book_id = ? ORDER BY tag_id;`, duplicateID); err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	if snapshot.AddedTags, err = queryIDs(ctx, tx, `SELECT tag_id FROM book_tags WHERE book_id = ?
+        AND tag_id NOT IN (SELECT tag_id FROM book_tags WHERE book_id = ?) ORDER BY tag_id;`, duplicateID, bookID); err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	if snapshot.Collections, err = queryIDs(ctx, tx, `SELECT collection_id FROM collection_books WHERE book_id = ? ORDER BY collection_id;`, duplicateID); err != nil {
+		return nil, fmt.Errorf("failed to query collections: %w", err)
+	}
+	if snapshot.AddedCollections, err = queryIDs(ctx, tx, `SELECT collection_id FROM collection_books WHERE book_id = ?
+        AND collection_id NOT IN (SELECT collection_id FROM collection_books WHERE book_id = ?) ORDER BY collection_id;`, duplicateID, bookID); err != nil {
+		return nil, fmt.Errorf("failed to query collections: %w", err)
+	}
+	if snapshot.SyncLinks, err = queryIDs(ctx, tx, `SELECT account_id FROM sync_links WHERE book_id = ?
+        AND account_id NOT IN (SELECT account_id FROM sync_links WHERE book_id = ?) ORDER BY account_id;`, duplicateID, bookID); err != nil {
+		return nil, fmt.Errorf("failed to query tracker links: %w", err)
+	}
+
+	var columns []string
+	for _, group := range mergeFields {
+		if !bookField(book, group[0]).IsZero() || bookField(duplicate, group[0]).IsZero() {
+			continue
+		}
+		for _, column := range group {
+			bookField(book, column).Set(bookField(duplicate, column))
+		}
+		snapshot.Filled = append(snapshot.Filled, group[0])
+		columns = append(columns, group...)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE reads SET book_id = ? WHERE book_id = ?;`, bookID, duplicateID); err != nil {
+		return nil, fmt.Errorf("failed to move reading history: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE book_notes SET book_id = ? WHERE book_id = ?;`, bookID, duplicateID); err != nil {
+		return nil, fmt.Errorf("failed to move notes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE quotes SET book_id = ? WHERE book_id = ?;`, bookID, duplicateID); err != nil {
+		return nil, fmt.Errorf("failed to move quotes: %w", err)
+	}
+	// Both books being lent out fails here, as a book has one outstanding loan at most
+	if _, err := tx.ExecContext(ctx, `UPDATE loans SET book_id = ? WHERE book_id = ?;`, bookID, duplicateID); err != nil {
+		return nil, fmt.Errorf("failed to move loans: %w", classify(err))
+	}
+	for _, tagID := range snapshot.AddedTags {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO book_tags (book_id, tag_id) VALUES (?, ?);`, bookID, tagID); err != nil {
+			return nil, fmt.Errorf("failed to move tag: %w", classify(err))
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE collection_books SET book_id = ? WHERE book_id = ?
+        AND collection_id NOT IN (SELECT collection_id FROM collection_books WHERE book_id = ?);`, bookID, duplicateID, bookID); err != nil {
+		return nil, fmt.Errorf("failed to move collections: %w", err)
+	}
+	for _, accountID := range snapshot.SyncLinks {
+		if _, err := tx.ExecContext(ctx, `UPDATE sync_links SET book_id = ? WHERE book_id = ? AND account_id = ?;`, bookID, duplicateID, accountID); err != nil {
+			return nil, fmt.Errorf("failed to move tracker link: %w", err)
+		}
+	}
+	// The duplicate goes first, so the fields it gives up are free to take
+	if err := recordBookEvent(ctx, tx, &model.BookEvent{BookID: duplicateID, Kind: model.EventMerged, RelatedBookID: &bookID}); err != nil {
+		return nil, err
+	}
+	if err := deleteBook(ctx, tx, duplicate); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sync_links WHERE book_id = ?;`, duplicateID); err != nil {
+		return nil, fmt.Errorf("failed to delete tracker links: %w", err)
+	}
+	if err := updateBookFields(ctx, tx, book, columns); err != nil {
+		return nil, err
+	}
+	if err := syncBookDates(ctx, tx, bookID); err != nil {
+		return nil, err
+	}
+	merged, err := s.getBookTx(ctx, tx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordBookEvent(ctx, tx, &model.BookEvent{BookID: bookID, Kind: model.EventMerged, RelatedBookID: &duplicateID,
+		Changes: diffBooks(&snapshot.Before, merged)}); err != nil {
+		return nil, err
+	}
+
+	merge := &model.BookMerge{BookID: bookID, DuplicateID: duplicateID, Before: snapshot.Before, Duplicate: snapshot.Duplicate,
+		Filled: snapshot.Filled, MergedAt: time.Now().UTC()}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode merge: %w", err)
+	}
+	if err := tx.QueryRowContext(ctx, `INSERT INTO book_merges (book_id, duplicate_id, snapshot, merged_at, user_id) VALUES (?, ?, ?, ?, ?) RETURNING id;`,
+		bookID, duplicateID, string(data), merge.MergedAt, book.UserID).Scan(&merge.ID); err != nil {
+		return nil, fmt.Errorf("failed to record merge: %w", classify(err))
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit merge: %w", err)
+	}
+	slog.InfoContext(ctx, "SQL: Merged books", "id", merge.ID, "bookID", bookID, "duplicateID", duplicateID, "filled", merge.Filled)
+	return merge, nil
+}
+
+const bookMergeColumns = `id, book_id, duplicate_id, snapshot, merged_at, unmerged_at, user_id`
+
+func scanBookMerge(row rowScanner) (*model.BookMerge, *mergeSnapshot, *int64, error) {
+	var merge model.BookMerge
+	var data string
+	var unmergedAt sql.NullTime
+	var userID sql.NullInt64
+	if err := row.Scan(&merge.ID, &merge.BookID, &merge.DuplicateID, &data, &merge.MergedAt, &unmergedAt, &userID); err != nil {
+		return nil, nil, nil, err
+	}
+	var snapshot mergeSnapshot
+	if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to decode merge %d: %w", merge.ID, err)
+	}
+	merge.Before, merge.Duplicate, merge.Filled = snapshot.Before, snapshot.Duplicate, snapshot.Filled
+	if unmergedAt.Valid {
+		merge.UnmergedAt = &unmergedAt.Time
+	}
+	var owner *int64
+	if userID.Valid {
+		owner = &userID.Int64
+	}
+	return &merge, &snapshot, owner, nil
+}
+
+// GetBookMerges returns every merge, newest first.
+func (s *SQLiteBookStore) GetBookMerges(ctx context.Context) ([]model.BookMerge, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetBookMerges query")
+	owned, args := ownedBy(ctx, "user_id")
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+bookMergeColumns+` FROM book_merges WHERE `+owned+`
+        ORDER BY merged_at DESC, id DESC;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetBookMerges query failed", "error", err)
+		return nil, fmt.Errorf("failed to query book merges: %w", err)
+	}
+	defer rows.Close()
+
+	merges := []model.BookMerge{}
+	for rows.Next() {
+		merge, _, _, err := scanBookMerge(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan book merge row: %w", err)
+		}
+		merges = append(merges, *merge)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating book merge rows: %w", err)
+	}
+	return merges, nil
+}
+
+// UnmergeBooks undoes a merge. The duplicate is restored with its ID, fields,
+// reading history, tags, collections and tracker links, and the kept book
+// gets back the fields it was given, unless they were changed since. A merge
+// can only be undone once, and not after the kept book was deleted.
+func (s *SQLiteBookStore) UnmergeBooks(ctx context.Context, id int64) (*model.BookMerge, error) {
+	slog.InfoContext(ctx, "SQL: Executing UnmergeBooks", "id", id)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	owned, args := ownedBy(ctx, "user_id")
+	merge, snapshot, userID, err := scanBookMerge(tx.QueryRowContext(ctx, `SELECT `+bookMergeColumns+` FROM book_merges WHERE id = ? AND `+owned+`;`,
+		append([]interface{}{id}, args...)...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("book merge with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book merge: %w", err)
+	}
+	if merge.UnmergedAt != nil {
+		return nil, invalidf("merge %d was already undone", id)
+	}
+	book, err := s.getBookTx(ctx, tx, merge.BookID)
+	if err != nil {
+		return nil, err
+	}
+	before := *book
+
+	var columns []string
+	for _, field := range snapshot.Filled {
+		for _, group := range mergeFields {
+			if group[0] != field {
+				continue
+			}
+			changed := false
+			for _, column := range group {
+				changed = changed || !reflect.DeepEqual(bookField(book, column).Interface(), bookField(&snapshot.Duplicate, column).Interface())
+			}
+			if changed {
+				continue
+			}
+			for _, column := range group {
+				bookField(book, column).Set(bookField(&snapshot.Before, column))
+			}
+			columns = append(columns, group...)
+		}
+	}
+	if err := updateBookFields(ctx, tx, book, columns); err != nil {
+		return nil, err
+	}
+
+	d := snapshot.Duplicate
+	var sourceRating, sourceRatingMax *float64
+	var sourceRatingProvider *string
+	if d.SourceRating != nil {
+		provider := string(d.SourceRating.Provider)
+		sourceRating, sourceRatingMax, sourceRatingProvider = &d.SourceRating.Value, &d.SourceRating.Max, &provider
+	}
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO books (id, title, author, open_library_id, isbn, status, type, rating, comments, cover_url,
+            series, series_index, edition, course_code, semester, reading_mode, publish_opt_out, comments_spoiler, publish_year, updated_at, cover_hash,
+            date_started, date_finished, description, subtitle, translated, page_count, user_id,
+            source_rating, source_rating_max, source_rating_provider, source, added_at, favorite, label, current_page, progress_percent, archived,
+            publisher, language, import_batch_id)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+            (SELECT id FROM import_batches WHERE id = ?));`,
+		d.ID, d.Title, d.Author, d.OpenLibraryID, d.ISBN, d.Status, d.Type, d.Rating, d.Comments, d.CoverURL,
+		d.Series, d.SeriesIndex, d.Edition, d.CourseCode, d.Semester, d.ReadingMode, d.PublishOptOut, d.CommentsSpoiler, d.PublishYear,
+		time.Now().UTC(), d.CoverHash, utcTime(d.DateStarted), utcTime(d.DateFinished), d.Description, d.Subtitle, d.Translated, d.PageCount, userID,
+		sourceRating, sourceRatingMax, sourceRatingProvider, sourceValue(d.Source), utcTime(d.AddedAt), d.Favorite, d.Label, d.CurrentPage, d.ProgressPercent, d.Archived,
+		d.Publisher, d.Language, d.ImportBatchID); err != nil {
+		return nil, fmt.Errorf("failed to restore book: %w", classify(err))
+	}
+	if err := linkBookAuthors(ctx, tx, d.ID, d.Author); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM book_tombstones WHERE book_id = ?;`, d.ID); err != nil {
+		return nil, fmt.Errorf("failed to remove book tombstone: %w", err)
+	}
+	for _, readID := range snapshot.Reads {
+		if _, err := tx.ExecContext(ctx, `UPDATE reads SET book_id = ? WHERE id = ? AND book_id = ?;`, d.ID, readID, book.ID); err != nil {
+			return nil, fmt.Errorf("failed to move reading history: %w", err)
+		}
+	}
+	for _, noteID := range snapshot.Notes {
+		if _, err := tx.ExecContext(ctx, `UPDATE book_notes SET book_id = ? WHERE id = ? AND book_id = ?;`, d.ID, noteID, book.ID); err != nil {
+			return nil, fmt.Errorf("failed to move notes: %w", err)
+		}
+	}
+	for _, quoteID := range snapshot.Quotes {
+		if _, err := tx.ExecContext(ctx, `UPDATE quotes SET book_id = ? WHERE id = ? AND book_id = ?;`, d.ID, quoteID, book.ID); err != nil {
+			return nil, fmt.Errorf("failed to move quotes: %w", err)
+		}
+	}
+	for _, loanID := range snapshot.Loans {
+		if _, err := tx.ExecContext(ctx, `UPDATE loans SET book_id = ? WHERE id = ? AND book_id = ?;`, d.ID, loanID, book.ID); err != nil {
+			return nil, fmt.Errorf("failed to move loans: %w", err)
+		}
+	}
+	for _, tagID := range snapshot.AddedTags {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM book_tags WHERE book_id = ? AND tag_id = ?;`, book.ID, tagID); err != nil {
+			return nil, fmt.Errorf("failed to untag book: %w", err)
+		}
+	}
+	// Tags deleted since the merge stay deleted
+	for _, tagID := range snapshot.Tags {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO book_tags (book_id, tag_id) SELECT ?, id FROM tags WHERE id = ?
+            ON CONFLICT DO NOTHING;`, d.ID, tagID); err != nil {
+			return nil, fmt.Errorf("failed to tag book: %w", classify(err))
+		}
+	}
+	for _, collectionID := range snapshot.AddedCollections {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM collection_books WHERE collection_id = ? AND book_id = ?;`, collectionID, book.ID); err != nil {
+			return nil, fmt.Errorf("failed to remove book from collection: %w", err)
+		}
+	}
+	// Collections deleted since the merge stay deleted
+	for _, collectionID := range snapshot.Collections {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO collection_books (collection_id, book_id, added_at) SELECT id, ?, ? FROM collections WHERE id = ?
+            ON CONFLICT DO NOTHING;`, d.ID, time.Now().UTC(), collectionID); err != nil {
+			return nil, fmt.Errorf("failed to add book to collection: %w", classify(err))
+		}
+	}
+	for _, accountID := range snapshot.SyncLinks {
+		if _, err := tx.ExecContext(ctx, `UPDATE sync_links SET book_id = ? WHERE book_id = ? AND account_id = ?;`, d.ID, book.ID, accountID); err != nil {
+			return nil, fmt.Errorf("failed to move tracker link: %w", err)
+		}
+	}
+	for _, bookID := range []int64{book.ID, d.ID} {
+		if err := syncBookDates(ctx, tx, bookID); err != nil {
+			return nil, err
+		}
+	}
+	unmerged, err := s.getBookTx(ctx, tx, book.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordBookEvent(ctx, tx, &model.BookEvent{BookID: book.ID, Kind: model.EventUnmerged, RelatedBookID: &d.ID,
+		Changes: diffBooks(&before, unmerged)}); err != nil {
+		return nil, err
+	}
+	if err := recordBookEvent(ctx, tx, &model.BookEvent{BookID: d.ID, Kind: model.EventUnmerged, RelatedBookID: &book.ID}); err != nil {
+		return nil, err
+	}
+
+	unmergedAt := time.Now().UTC()
+	if _, err := tx.ExecContext(ctx, `UPDATE book_merges SET unmerged_at = ? WHERE id = ?;`, unmergedAt, id); err != nil {
+		return nil, fmt.Errorf("failed to mark merge undone: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit unmerge: %w", err)
+	}
+	merge.UnmergedAt = &unmergedAt
+	slog.InfoContext(ctx, "SQL: Unmerged books", "id", id, "bookID", merge.BookID, "duplicateID", merge.DuplicateID)
+	return merge, nil
+}
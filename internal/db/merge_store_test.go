@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestMergeBooks(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	if _, err := store.AddBook(ctx, book); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	description, pages := "A desert planet.", 412
+	duplicate := createTestBook()
+	duplicate.OpenLibraryID, duplicate.ISBN, duplicate.Rating = "OL99999M", "", nil
+	duplicate.Description, duplicate.PageCount = &description, &pages
+	if _, err := store.AddBook(ctx, duplicate); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	read := &model.Read{BookID: duplicate.ID, DateFinished: time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)}
+	if err := store.AddRead(ctx, read); err != nil {
+		t.Fatalf("AddRead failed: %v", err)
+	}
+	if _, err := store.AddBookTag(ctx, duplicate.ID, "favourites"); err != nil {
+		t.Fatalf("AddBookTag failed: %v", err)
+	}
+
+	merge, err := store.MergeBooks(ctx, book.ID, duplicate.ID)
+	if err != nil {
+		t.Fatalf("MergeBooks failed: %v", err)
+	}
+	if len(merge.Filled) != 2 || merge.Filled[0] != "description" || merge.Filled[1] != "page_count" || merge.Duplicate.ID != duplicate.ID {
+		t.Errorf("Expected the description and page count to be filled, got %+v", merge)
+	}
+	merged, err := store.GetBookByID(ctx, book.ID)
+	if err != nil || merged.Description == nil || *merged.Description != description || *merged.Rating != 8 || merged.ISBN != book.ISBN ||
+		merged.DateFinished == nil || !merged.DateFinished.Equal(read.DateFinished) {
+		t.Fatalf("Expected the kept book to gain the duplicate's fields and read, got %+v, %v", merged, err)
+	}
+	if tags, _ := store.GetBookTags(ctx, book.ID); len(tags) != 1 {
+		t.Errorf("Expected the duplicate's tag to move, got %+v", tags)
+	}
+	if _, err := store.GetBookByID(ctx, duplicate.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the duplicate to be deleted, got %v", err)
+	}
+	if merges, err := store.GetBookMerges(ctx); err != nil || len(merges) != 1 || merges[0].ID != merge.ID {
+		t.Errorf("Expected the merge in the history, got %+v, %v", merges, err)
+	}
+
+	// A field edited since the merge keeps the edit
+	if err := store.UpdateBook(ctx, book.ID, model.BookPatch{PageCount: model.Some(400)}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+	unmerged, err := store.UnmergeBooks(ctx, merge.ID)
+	if err != nil || unmerged.UnmergedAt == nil {
+		t.Fatalf("UnmergeBooks failed: %+v, %v", unmerged, err)
+	}
+	restored, err := store.GetBookByID(ctx, duplicate.ID)
+	if err != nil || restored.OpenLibraryID != "OL99999M" || *restored.PageCount != pages || restored.DateFinished == nil {
+		t.Fatalf("Expected the duplicate back with its read, got %+v, %v", restored, err)
+	}
+	if reads, _ := store.GetReads(ctx, duplicate.ID); len(reads) != 1 || reads[0].ID != read.ID {
+		t.Errorf("Expected the read to move back, got %+v", reads)
+	}
+	if tags, _ := store.GetBookTags(ctx, duplicate.ID); len(tags) != 1 {
+		t.Errorf("Expected the duplicate's tag back, got %+v", tags)
+	}
+	if tags, _ := store.GetBookTags(ctx, book.ID); len(tags) != 0 {
+		t.Errorf("Expected the kept book to lose the tag it gained, got %+v", tags)
+	}
+	kept, _ := store.GetBookByID(ctx, book.ID)
+	if kept.Description != nil || kept.PageCount == nil || *kept.PageCount != 400 || kept.DateFinished != nil {
+		t.Errorf("Expected the kept book's own fields back and the edit kept, got %+v", kept)
+	}
+	if tombstones, _ := store.GetTombstonesSince(ctx, time.Time{}); len(tombstones) != 0 {
+		t.Errorf("Expected the restored book's tombstone to be removed, got %+v", tombstones)
+	}
+
+	if _, err := store.UnmergeBooks(ctx, merge.ID); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a validation error undoing twice, got %v", err)
+	}
+	if _, err := store.UnmergeBooks(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown merge, got %v", err)
+	}
+	if _, err := store.MergeBooks(ctx, book.ID, book.ID); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a validation error merging a book into itself, got %v", err)
+	}
+	if _, err := store.MergeBooks(ctx, book.ID, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown duplicate, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/metrics"
+)
+
+// queryDuration times the queries of the stores opened with InitDB and
+// InitPostgresDB, from when they are sent until their rows are closed.
+var queryDuration = metrics.NewHistogram("bookshelf_db_query_duration_seconds",
+	"Duration of database queries, by statement and the table they start with.", metrics.DefBuckets, "operation", "table")
+
+// queryTable finds the first table a statement reads or writes.
+var queryTable = regexp.MustCompile(`(?i)\b(?:FROM|INTO|UPDATE|TABLE)\s+([a-z_][a-z0-9_]*)`)
+
+// queryLabels returns the operation of query, such as "select", and its
+// first table, keeping the number of series small whatever queries are sent.
+func queryLabels(query string) (operation, table string) {
+	query = strings.TrimSpace(query)
+	operation, _, _ = strings.Cut(strings.ToLower(query), " ")
+	switch operation {
+	case "select", "insert", "update", "delete", "with", "create", "alter", "drop", "pragma", "begin", "commit", "rollback":
+	default:
+		operation = "other"
+	}
+	table = "none"
+	if m := queryTable.FindStringSubmatch(query); m != nil {
+		table = strings.ToLower(m[1])
+	}
+	return operation, table
+}
+
+// observeQuery records how long query, run with params parameters, took
+// since start, logging it in SlowQueries if it took long enough.
+func observeQuery(ctx context.Context, query string, params int, start time.Time) {
+	took := time.Since(start)
+	operation, table := queryLabels(query)
+	queryDuration.Observe(took.Seconds(), operation, table)
+	SlowQueries.observe(ctx, query, params, start, took)
+}
+
+// dsnConnector opens connections of a driver by data source name, for
+// drivers without a driver.Connector of their own.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(ctx context.Context) (driver.Conn, error) { return c.driver.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                            { return c.driver }
+
+// timedConnector opens connections whose queries are timed in
+// queryDuration.
+type timedConnector struct {
+	driver.Connector
+}
+
+func (c timedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &timedConn{conn}, nil
+}
+
+// timedConn is a driver connection that times queries. Like rebindConn, it
+// forwards the optional driver interfaces the SQLite and PostgreSQL drivers
+// implement.
+type timedConn struct {
+	driver.Conn
+}
+
+func (c *timedConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &timedStmt{stmt, query}, nil
+}
+
+func (c *timedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	p, ok := c.Conn.(driver.ConnPrepareContext)
+	if !ok {
+		return c.Prepare(query)
+	}
+	stmt, err := p.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &timedStmt{stmt, query}, nil
+}
+
+func (c *timedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	defer observeQuery(ctx, query, len(args), time.Now())
+	return e.ExecContext(ctx, query, args)
+}
+
+func (c *timedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	if err != nil {
+		observeQuery(ctx, query, len(args), start)
+		return nil, err
+	}
+	return &timedRows{rows, ctx, query, len(args), start}, nil
+}
+
+func (c *timedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *timedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *timedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *timedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *timedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// timedStmt is a prepared statement whose executions are timed.
+type timedStmt struct {
+	driver.Stmt
+	query string
+}
+
+func (s *timedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	defer observeQuery(ctx, s.query, len(args), time.Now())
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return e.ExecContext(ctx, args)
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Exec(values)
+}
+
+func (s *timedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			rows, err = s.Stmt.Query(values)
+		}
+	}
+	if err != nil {
+		observeQuery(ctx, s.query, len(args), start)
+		return nil, err
+	}
+	return &timedRows{rows, ctx, s.query, len(args), start}, nil
+}
+
+// namedValues returns the values of positional args, for drivers predating
+// contexts.
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, driver.ErrSkip
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+// timedRows records the duration of its query when closed, as SQLite only
+// runs a query as its rows are read.
+type timedRows struct {
+	driver.Rows
+	ctx    context.Context // Of the query, for the slow query log
+	query  string
+	params int
+	start  time.Time
+}
+
+func (r *timedRows) Close() error {
+	observeQuery(r.ctx, r.query, r.params, r.start)
+	return r.Rows.Close()
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestQueryLabels(t *testing.T) {
+	for query, want := range map[string][2]string{
+		"SELECT id FROM books WHERE id = ?;":                              {"select", "books"},
+		"\n        INSERT INTO book_tags (book_id, tag_id) VALUES (?, ?)": {"insert", "book_tags"},
+		"UPDATE books SET title = ? WHERE id = ?":                         {"update", "books"},
+		"DELETE FROM sessions WHERE expires_at <= ?":                      {"delete", "sessions"},
+		"VACUUM": {"other", "none"},
+	} {
+		if operation, table := queryLabels(query); operation != want[0] || table != want[1] {
+			t.Errorf("queryLabels(%q) = %s, %s, want %s, %s", query, operation, table, want[0], want[1])
+		}
+	}
+}
+
+// TestTimedQueries tests that queries on a database opened with InitDB are
+// timed, including ones in transactions and prepared statements.
+func TestTimedQueries(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "books.db"))
+	if err != nil {
+		t.Fatalf("InitDB failed: %v", err)
+	}
+	defer db.Close()
+	store := NewSQLiteBookStore(db)
+
+	selects, inserts := queryDuration.Count("select", "books"), queryDuration.Count("insert", "books")
+	if _, err := store.AddBook(context.Background(), createTestBook()); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if _, _, err := store.GetBooksPage(context.Background(), ListOptions{}); err != nil {
+		t.Fatalf("GetBooksPage failed: %v", err)
+	}
+	if queryDuration.Count("select", "books") <= selects || queryDuration.Count("insert", "books") <= inserts {
+		t.Errorf("Expected the queries timed, got %d selects and %d inserts", queryDuration.Count("select", "books")-selects,
+			queryDuration.Count("insert", "books")-inserts)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	stmt, err := tx.Prepare(`SELECT COUNT(*) FROM tags WHERE name = ?;`)
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	var n int
+	if err := stmt.QueryRow("fiction").Scan(&n); err != nil {
+		t.Fatalf("QueryRow failed: %v", err)
+	}
+	stmt.Close()
+	tx.Rollback()
+	if queryDuration.Count("select", "tags") == 0 {
+		t.Error("Expected the prepared statement timed")
+	}
+}
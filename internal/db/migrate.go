@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles holds the schema migrations of each backend, in
+// migrations/<backend>/NNNN_description.sql. A schema change is a new file
+// with the next number in every backend's directory; applied files must never
+// be edited, since databases that ran them won't run them again.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Migration is one numbered schema change.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// noForeignKeys is the first line of a SQLite migration that rebuilds a
+// table other tables refer to. Dropping the old table would otherwise cascade
+// to the rows referring to it, and SQLite ignores the foreign_keys pragma
+// inside a transaction, so it is turned off around the migration instead.
+// Such a migration must copy the table's rows with their IDs, so references
+// stay valid without being checked.
+const noForeignKeys = "-- migrate: foreign_keys=off"
+
+var migrationName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.sql$`)
+
+// loadMigrations reads the migrations in dir of fsys, in version order.
+// Versions must be unique and start at 1 without gaps.
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	var migrations []Migration
+	for _, entry := range entries {
+		m := migrationName.FindStringSubmatch(entry.Name())
+		if m == nil || entry.IsDir() {
+			return nil, fmt.Errorf("unexpected file %s in migrations, want NNNN_description.sql", entry.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: m[2], SQL: string(body)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %04d_%s is out of sequence, want version %d", m.Version, m.Name, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// backendMigrations returns the embedded migrations of a backend directory.
+func backendMigrations(backend string) []Migration {
+	migrations, err := loadMigrations(migrationFiles, path.Join("migrations", backend))
+	if err != nil {
+		panic(err) // The files are compiled in, so this is a build mistake
+	}
+	return migrations
+}
+
+// LatestSchemaVersion returns the version of the newest migration for SQLite
+// or, when postgres is true, PostgreSQL.
+func LatestSchemaVersion(postgres bool) int {
+	backend := "sqlite"
+	if postgres {
+		backend = "postgres"
+	}
+	migrations := backendMigrations(backend)
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaVersion returns the version of the newest migration applied to db,
+// or 0 if none has been.
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	return schemaVersion(ctx, db)
+}
+
+// schemaVersion is SchemaVersion on a database or one of its connections.
+func schemaVersion(ctx context.Context, db interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations;`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// migrate applies the migrations db has not had yet, each in its own
+// transaction together with its row in schema_migrations. A database with a
+// newer schema than the migrations know of is refused rather than used.
+func migrate(db *sql.DB, migrations []Migration) error {
+	// Pragmas are per connection, so every statement runs on the same one
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a connection for migrations: %w", err)
+	}
+	defer conn.Close()
+
+	// Type names both SQLite and PostgreSQL accept
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+        version INTEGER PRIMARY KEY,
+        name TEXT NOT NULL,
+        applied_at TIMESTAMP NOT NULL
+    );`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	current, err := schemaVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if latest := len(migrations); current > latest {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d)", current, latest)
+	}
+
+	for _, m := range migrations[current:] {
+		slog.InfoContext(ctx, "Applying schema migration", "version", m.Version, "name", m.Name)
+		if err := applyMigration(ctx, conn, m); err != nil {
+			slog.ErrorContext(ctx, "Error applying schema migration", "version", m.Version, "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// applyMigration runs one migration on conn and records it.
+func applyMigration(ctx context.Context, conn *sql.Conn, m Migration) error {
+	if strings.HasPrefix(m.SQL, noForeignKeys) {
+		var enabled bool
+		if err := conn.QueryRowContext(ctx, `PRAGMA foreign_keys;`).Scan(&enabled); err != nil {
+			return fmt.Errorf("failed to read foreign_keys for migration %d: %w", m.Version, err)
+		}
+		if enabled {
+			if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF;`); err != nil {
+				return fmt.Errorf("failed to turn off foreign keys for migration %d: %w", m.Version, err)
+			}
+			defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON;`)
+		}
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", m.Version, err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return fmt.Errorf("failed to apply migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?);`,
+		m.Version, m.Name, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.Version, err)
+	}
+	return nil
+}
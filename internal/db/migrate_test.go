@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCreateSchemaMigrations(t *testing.T) {
+	ctx := context.Background()
+	db, _ := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	latest := LatestSchemaVersion(false)
+	if version, err := SchemaVersion(ctx, db); err != nil || version != latest || latest < 1 {
+		t.Fatalf("Expected schema version %d, got %d, %v", latest, version, err)
+	}
+	// Running again applies nothing
+	if err := CreateSchema(db); err != nil {
+		t.Fatalf("CreateSchema failed the second time: %v", err)
+	}
+	var applied int
+	db.QueryRow(`SELECT COUNT(*) FROM schema_migrations;`).Scan(&applied)
+	if applied != latest {
+		t.Errorf("Expected %d recorded migrations, got %d", latest, applied)
+	}
+	if LatestSchemaVersion(true) != latest {
+		t.Errorf("Expected the PostgreSQL migrations to keep pace with SQLite's, got %d and %d", LatestSchemaVersion(true), latest)
+	}
+}
+
+func TestCreateSchemaUpgradesLegacyDatabase(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	defer teardownTestDB(db)
+	// The books table of the first release
+	if _, err := db.Exec(`CREATE TABLE books (
+            id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT NOT NULL, author TEXT NOT NULL,
+            open_library_id TEXT NOT NULL UNIQUE, isbn TEXT,
+            status TEXT NOT NULL CHECK(status IN ('Want to Read', 'Currently Reading', 'Read')),
+            rating INTEGER, comments TEXT, cover_url TEXT);
+        INSERT INTO books (title, author, open_library_id, status) VALUES ('Dune', 'Frank Herbert', 'OL1M', 'Read');`); err != nil {
+		t.Fatalf("Failed to create legacy schema: %v", err)
+	}
+
+	if err := CreateSchema(db); err != nil {
+		t.Fatalf("CreateSchema failed on a legacy database: %v", err)
+	}
+	store := NewSQLiteBookStore(db)
+	books, err := store.GetBooks(ctx)
+	if err != nil || len(books) != 1 || books[0].Type != "book" || books[0].UpdatedAt == nil {
+		t.Fatalf("Expected the legacy book with defaults for new columns, got %+v, %v", books, err)
+	}
+	if found, err := store.SearchBooks(ctx, "dune"); err != nil || len(found) != 1 {
+		t.Errorf("Expected the legacy book to be searchable, got %+v, %v", found, err)
+	}
+	if version, _ := SchemaVersion(ctx, db); version != LatestSchemaVersion(false) {
+		t.Errorf("Expected the legacy database at the latest version, got %d", version)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	defer teardownTestDB(db)
+
+	fsys := fstest.MapFS{
+		"m/0001_widgets.sql":  {Data: []byte(`CREATE TABLE widgets (id INTEGER PRIMARY KEY);`)},
+		"m/0002_gadgets.sql":  {Data: []byte(`CREATE TABLE gadgets (id INTEGER PRIMARY KEY);`)},
+		"m/0003_broken.sql":   {Data: []byte(`CREATE TABLE sprockets (id INTEGER PRIMARY KEY); ALTER TABLE nothing ADD COLUMN x TEXT;`)},
+		"gap/0001_a.sql":      {Data: []byte(`SELECT 1;`)},
+		"gap/0003_c.sql":      {Data: []byte(`SELECT 1;`)},
+		"badname/widgets.sql": {Data: []byte(`SELECT 1;`)},
+	}
+	migrations, err := loadMigrations(fsys, "m")
+	if err != nil || len(migrations) != 3 || migrations[1].Version != 2 || migrations[1].Name != "gadgets" {
+		t.Fatalf("Unexpected migrations %+v, %v", migrations, err)
+	}
+	for _, dir := range []string{"gap", "badname"} {
+		if _, err := loadMigrations(fsys, dir); err == nil {
+			t.Errorf("Expected an error loading %s", dir)
+		}
+	}
+
+	// A failing migration is rolled back and not recorded; earlier ones stay
+	if err := migrate(db, migrations); err == nil || !strings.Contains(err.Error(), "0003_broken") {
+		t.Errorf("Expected migration 3 to fail, got %v", err)
+	}
+	if version, _ := SchemaVersion(ctx, db); version != 2 {
+		t.Errorf("Expected version 2 after the failure, got %d", version)
+	}
+	var sprockets int
+	db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'sprockets';`).Scan(&sprockets)
+	if sprockets != 0 {
+		t.Errorf("Expected the failed migration to be rolled back")
+	}
+
+	if err := migrate(db, migrations[:1]); err == nil {
+		t.Errorf("Expected a database newer than the migrations to be refused")
+	}
+}
+
+func TestMigrateRebuildKeepsReferencingRows(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "books.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer teardownTestDB(db)
+	// A database from before accounts, whose books have reads and tags
+	if err := migrate(db, backendMigrations("sqlite")[:1]); err != nil {
+		t.Fatalf("Failed to apply the first migration: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO books (id, title, author, open_library_id, status) VALUES (7, 'Dune', 'Frank Herbert', 'OL1M', 'Read');
+        INSERT INTO reads (book_id, date_finished, created_at) VALUES (7, '2025-01-01', '2025-01-01');
+        INSERT INTO tags (id, name, created_at) VALUES (3, 'sf', '2025-01-01');
+        INSERT INTO book_tags (book_id, tag_id) VALUES (7, 3);`); err != nil {
+		t.Fatalf("Failed to fill database: %v", err)
+	}
+
+	if err := CreateSchema(db); err != nil {
+		t.Fatalf("CreateSchema failed: %v", err)
+	}
+	store := NewSQLiteBookStore(db)
+	if reads, err := store.GetReads(ctx, 7); err != nil || len(reads) != 1 {
+		t.Errorf("Expected the book's read to survive the books rebuild, got %+v, %v", reads, err)
+	}
+	if tags, err := store.GetBookTags(ctx, 7); err != nil || len(tags) != 1 || tags[0].ID != 3 {
+		t.Errorf("Expected the book's tag to survive the tags rebuild, got %+v, %v", tags, err)
+	}
+	var enabled bool
+	conn, _ := db.Conn(ctx)
+	defer conn.Close()
+	if conn.QueryRowContext(ctx, `PRAGMA foreign_keys;`).Scan(&enabled); !enabled {
+		t.Errorf("Expected foreign keys to be turned back on after the migration")
+	}
+}
+
+func TestMigrateUniqueISBN(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	defer teardownTestDB(db)
+	// A book added twice before ISBNs had to be unique
+	if err := migrate(db, backendMigrations("sqlite")[:7]); err != nil {
+		t.Fatalf("Failed to apply the earlier migrations: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO books (id, title, author, open_library_id, isbn, status) VALUES
+            (1, 'Dune', 'Frank Herbert', 'OL1M', '9780441013593', 'Read'), (2, 'Dune', 'Frank Herbert', 'OL2M', '9780441013593', 'Read'),
+            (3, 'Emma', 'Jane Austen', 'OL3M', '', 'Read'), (4, 'Emma', 'Jane Austen', 'OL4M', '', 'Read');`); err != nil {
+		t.Fatalf("Failed to fill database: %v", err)
+	}
+
+	if err := CreateSchema(db); err != nil {
+		t.Fatalf("CreateSchema failed: %v", err)
+	}
+	store := NewSQLiteBookStore(db)
+	first, _ := store.GetBookByID(ctx, 1)
+	second, _ := store.GetBookByID(ctx, 2)
+	if first == nil || first.ISBN != "9780441013593" || second == nil || second.ISBN != "" {
+		t.Errorf("Expected only the earliest copy to keep the ISBN, got %+v and %+v", first, second)
+	}
+}
+
+func TestMigrateBookAuthors(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	defer teardownTestDB(db)
+	// Books naming their authors only in the author field, one with a profile
+	if err := migrate(db, backendMigrations("sqlite")[:23]); err != nil {
+		t.Fatalf("Failed to apply the earlier migrations: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO books (id, title, author, open_library_id, status) VALUES
+            (1, 'Good Omens', 'Terry Pratchett,  Neil Gaiman ', 'OL1M', 'Read'), (2, 'Coraline', 'neil gaiman', 'OL2M', 'Read');
+        INSERT INTO authors (id, name, gender, updated_at) VALUES (5, 'Neil Gaiman', 'man', '2025-01-01');`); err != nil {
+		t.Fatalf("Failed to fill database: %v", err)
+	}
+
+	if err := CreateSchema(db); err != nil {
+		t.Fatalf("CreateSchema failed: %v", err)
+	}
+	store := NewSQLiteBookStore(db)
+	authors, err := store.GetAuthors(ctx)
+	if err != nil || len(authors) != 2 || authors[0].ID != 5 || authors[0].BookCount != 2 || authors[1].Name != "Terry Pratchett" || authors[1].BookCount != 1 {
+		t.Fatalf("Expected both authors linked to their books, got %+v, %v", authors, err)
+	}
+	if books, err := store.GetBooksByAuthor(ctx, 5); err != nil || len(books) != 2 {
+		t.Errorf("Expected both books of the profiled author, got %+v, %v", books, err)
+	}
+}
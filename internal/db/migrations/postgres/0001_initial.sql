@@ -0,0 +1,197 @@
+-- The schema when versioned migrations were introduced. Every statement is
+-- IF NOT EXISTS, so databases created before then are brought up to date
+-- rather than failing on the tables they already have.
+
+CREATE EXTENSION IF NOT EXISTS citext;
+
+CREATE TABLE IF NOT EXISTS books (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    title TEXT NOT NULL,
+    subtitle TEXT,
+    author TEXT NOT NULL,
+    open_library_id TEXT NOT NULL UNIQUE,
+    isbn TEXT,
+    status TEXT NOT NULL CHECK(status IN ('Want to Read', 'Currently Reading', 'Read')),
+    type TEXT NOT NULL DEFAULT 'book' CHECK(type IN ('book', 'audiobook')),
+    rating INTEGER CHECK(rating IS NULL OR (rating >= 1 AND rating <= 10)),
+    comments TEXT,
+    cover_url TEXT,
+    series TEXT,
+    series_index INTEGER,
+    edition INTEGER CHECK(edition IS NULL OR edition > 0),
+    page_count INTEGER CHECK(page_count IS NULL OR page_count > 0),
+    course_code TEXT,
+    semester TEXT,
+    reading_mode TEXT NOT NULL DEFAULT 'leisure' CHECK(reading_mode IN ('leisure', 'reference')),
+    publish_opt_out BOOLEAN NOT NULL DEFAULT FALSE,
+    comments_spoiler BOOLEAN NOT NULL DEFAULT FALSE,
+    translated BOOLEAN NOT NULL DEFAULT FALSE,
+    publish_year INTEGER,
+    updated_at TIMESTAMPTZ,
+    cover_hash TEXT,
+    date_started TIMESTAMPTZ,
+    date_finished TIMESTAMPTZ,
+    description TEXT
+);
+-- Must match postgresSearchDocument for searches to use the index
+CREATE INDEX IF NOT EXISTS idx_books_search ON books USING GIN ((to_tsvector('simple', title || COALESCE(': ' || subtitle, '') || ' ' || author || ' ' || COALESCE(comments, ''))));
+
+CREATE TABLE IF NOT EXISTS cover_images (
+    hash TEXT PRIMARY KEY,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    width INTEGER NOT NULL DEFAULT 0,
+    height INTEGER NOT NULL DEFAULT 0,
+    blurhash TEXT NOT NULL DEFAULT '',
+    lqip TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS book_tombstones (
+    book_id BIGINT PRIMARY KEY,
+    open_library_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    deleted_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_book_tombstones_deleted_at ON book_tombstones(deleted_at);
+
+CREATE TABLE IF NOT EXISTS export_runs (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    format TEXT NOT NULL,
+    since TIMESTAMPTZ,
+    exported_at TIMESTAMPTZ NOT NULL,
+    books INTEGER NOT NULL,
+    deleted INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS follows (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    feed_url TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    last_fetched_at TIMESTAMPTZ,
+    last_error TEXT
+);
+
+CREATE TABLE IF NOT EXISTS activities (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    follow_id BIGINT REFERENCES follows(id) ON DELETE CASCADE,
+    remote_id TEXT,
+    book_id BIGINT,
+    kind TEXT NOT NULL,
+    title TEXT NOT NULL,
+    author TEXT,
+    status TEXT,
+    url TEXT,
+    summary TEXT NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    UNIQUE(follow_id, remote_id)
+);
+CREATE INDEX IF NOT EXISTS idx_activities_occurred_at ON activities(occurred_at);
+
+CREATE TABLE IF NOT EXISTS settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS crosspost_accounts (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    provider TEXT NOT NULL CHECK(provider IN ('mastodon', 'bluesky')),
+    instance_url TEXT NOT NULL,
+    handle TEXT NOT NULL,
+    token_encrypted TEXT NOT NULL,
+    template TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sync_accounts (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    provider TEXT NOT NULL CHECK(provider IN ('hardcover', 'goodreads')),
+    remote_user TEXT NOT NULL,
+    token_encrypted TEXT NOT NULL DEFAULT '',
+    conflict_policy TEXT NOT NULL DEFAULT 'local' CHECK(conflict_policy IN ('local', 'remote')),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL,
+    last_synced_at TIMESTAMPTZ,
+    last_error TEXT
+);
+
+CREATE TABLE IF NOT EXISTS sync_links (
+    account_id BIGINT NOT NULL REFERENCES sync_accounts(id) ON DELETE CASCADE,
+    book_id BIGINT NOT NULL,
+    remote_id TEXT NOT NULL,
+    status TEXT NOT NULL,
+    rating INTEGER,
+    PRIMARY KEY (account_id, book_id),
+    UNIQUE(account_id, remote_id)
+);
+
+CREATE TABLE IF NOT EXISTS sync_log (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    account_id BIGINT NOT NULL,
+    book_id BIGINT,
+    direction TEXT NOT NULL,
+    message TEXT NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_sync_log_occurred_at ON sync_log(occurred_at);
+
+CREATE TABLE IF NOT EXISTS pending_matches (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    source TEXT NOT NULL,
+    source_ref TEXT NOT NULL,
+    item_key TEXT NOT NULL,
+    item TEXT NOT NULL,
+    candidates TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'linked', 'created', 'skipped')),
+    book_id BIGINT,
+    created_at TIMESTAMPTZ NOT NULL,
+    resolved_at TIMESTAMPTZ,
+    UNIQUE(source, source_ref, item_key)
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name CITEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS book_tags (
+    book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    tag_id BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (book_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS idx_book_tags_tag_id ON book_tags(tag_id);
+
+CREATE TABLE IF NOT EXISTS reads (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    date_started TIMESTAMPTZ,
+    date_finished TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_reads_book_id ON reads(book_id, date_finished);
+
+CREATE TABLE IF NOT EXISTS authors (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name CITEXT NOT NULL UNIQUE,
+    gender TEXT NOT NULL DEFAULT '' CHECK(gender IN ('', 'woman', 'man', 'nonbinary')),
+    nationality TEXT,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS fediverse_followers (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    actor_id TEXT NOT NULL UNIQUE,
+    inbox_url TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS vacations (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    start_date TEXT NOT NULL,
+    end_date TEXT NOT NULL CHECK(end_date >= start_date),
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL
+);
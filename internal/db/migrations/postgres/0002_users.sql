@@ -0,0 +1,33 @@
+-- Accounts, each with a library of its own. Books, tags, vacations and linked
+-- accounts get an owner; rows from before accounts have none until the first
+-- user registers and adopts them.
+
+CREATE TABLE users (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    username CITEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE sessions (
+    token_hash TEXT PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+
+-- An Open Library edition and a tag name are now unique per owner
+ALTER TABLE books ADD COLUMN user_id BIGINT REFERENCES users(id);
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_open_library_id_key;
+CREATE UNIQUE INDEX idx_books_open_library_id ON books(COALESCE(user_id, 0), open_library_id);
+CREATE INDEX idx_books_user_id ON books(user_id);
+
+ALTER TABLE tags ADD COLUMN user_id BIGINT REFERENCES users(id);
+ALTER TABLE tags DROP CONSTRAINT IF EXISTS tags_name_key;
+CREATE UNIQUE INDEX idx_tags_name ON tags(COALESCE(user_id, 0), name);
+
+ALTER TABLE book_tombstones ADD COLUMN user_id BIGINT REFERENCES users(id);
+ALTER TABLE vacations ADD COLUMN user_id BIGINT REFERENCES users(id);
+ALTER TABLE sync_accounts ADD COLUMN user_id BIGINT REFERENCES users(id);
+ALTER TABLE crosspost_accounts ADD COLUMN user_id BIGINT REFERENCES users(id);
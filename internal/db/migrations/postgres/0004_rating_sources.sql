@@ -0,0 +1,9 @@
+-- Ratings imported from trackers are mapped to the 1-10 scale by each
+-- account's rules, and the rating as the tracker gave it is kept on the book.
+
+ALTER TABLE sync_accounts ADD COLUMN rating_mapping TEXT NOT NULL DEFAULT 'proportional' CHECK(rating_mapping IN ('proportional', 'linear'));
+ALTER TABLE sync_accounts ADD COLUMN rating_rounding TEXT NOT NULL DEFAULT 'nearest' CHECK(rating_rounding IN ('nearest', 'up', 'down'));
+
+ALTER TABLE books ADD COLUMN source_rating DOUBLE PRECISION;
+ALTER TABLE books ADD COLUMN source_rating_max DOUBLE PRECISION;
+ALTER TABLE books ADD COLUMN source_rating_provider TEXT;
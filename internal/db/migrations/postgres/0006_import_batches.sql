@@ -0,0 +1,15 @@
+-- Every import is a batch, and books remember the batch that added them, so
+-- a botched import can be rolled back as a whole.
+
+CREATE TABLE import_batches (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    source TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    rolled_back_at TIMESTAMPTZ
+);
+CREATE INDEX idx_import_batches_user_id ON import_batches(user_id);
+
+ALTER TABLE books ADD COLUMN import_batch_id BIGINT REFERENCES import_batches(id) ON DELETE SET NULL;
+CREATE INDEX idx_books_import_batch_id ON books(import_batch_id);
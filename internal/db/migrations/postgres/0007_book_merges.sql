@@ -0,0 +1,13 @@
+-- Merging a duplicate into a book keeps snapshots of both records and of what
+-- moved between them, so a wrong merge can be undone.
+
+CREATE TABLE book_merges (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    book_id BIGINT NOT NULL,
+    duplicate_id BIGINT NOT NULL,
+    snapshot TEXT NOT NULL,
+    merged_at TIMESTAMPTZ NOT NULL,
+    unmerged_at TIMESTAMPTZ
+);
+CREATE INDEX idx_book_merges_user_id ON book_merges(user_id, merged_at);
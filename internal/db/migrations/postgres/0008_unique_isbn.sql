@@ -0,0 +1,8 @@
+-- A book can be on a bookshelf only once, by ISBN as by Open Library ID.
+-- Books added more than once keep the ISBN on their earliest copy only; the
+-- copies can be found with GET /api/books/duplicates and merged.
+
+UPDATE books SET isbn = NULL WHERE isbn != '' AND EXISTS (
+    SELECT 1 FROM books AS earlier WHERE earlier.isbn = books.isbn
+        AND COALESCE(earlier.user_id, 0) = COALESCE(books.user_id, 0) AND earlier.id < books.id);
+CREATE UNIQUE INDEX idx_books_isbn ON books(COALESCE(user_id, 0), isbn) WHERE isbn != '';
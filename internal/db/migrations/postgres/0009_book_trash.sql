@@ -0,0 +1,5 @@
+-- Deleting a book moves it to the trash, from which it can be restored or
+-- purged. Books in the trash keep their reads and tags until purged.
+
+ALTER TABLE books ADD COLUMN deleted_at TIMESTAMPTZ;
+CREATE INDEX idx_books_deleted_at ON books(user_id, deleted_at);
@@ -0,0 +1,15 @@
+-- The audit log of every change to a book, written in the same transaction
+-- as the change. user_id is the book's owner and actor_id who made the change.
+-- Events have no foreign key to books, so they outlive a purge.
+
+CREATE TABLE book_events (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    actor_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    book_id BIGINT NOT NULL,
+    kind TEXT NOT NULL,
+    related_book_id BIGINT,
+    changes TEXT,
+    occurred_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_book_events_book_id ON book_events(book_id, occurred_at);
@@ -0,0 +1,17 @@
+-- Named views of the book list, kept on the server so every device shows the
+-- same ones. filters is a JSON object of book list query parameters and
+-- fields a JSON array of field names; sort and sort_order are empty for the
+-- default order. Names are unique per user.
+
+CREATE TABLE shelf_presets (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    filters TEXT NOT NULL,
+    sort TEXT NOT NULL,
+    sort_order TEXT NOT NULL,
+    fields TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX idx_shelf_presets_name ON shelf_presets(COALESCE(user_id, 0), name);
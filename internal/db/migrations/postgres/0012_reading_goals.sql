@@ -0,0 +1,12 @@
+-- The number of books to finish in a year. There is one goal per year and
+-- user.
+
+CREATE TABLE reading_goals (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    year INTEGER NOT NULL,
+    target INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX idx_reading_goals_year ON reading_goals(COALESCE(user_id, 0), year);
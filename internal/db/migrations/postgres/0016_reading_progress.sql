@@ -0,0 +1,14 @@
+-- Reading progress: the page reached in a book being read, or how far
+-- through it as a percentage, with a history of updates to measure the pace.
+
+ALTER TABLE books ADD COLUMN current_page BIGINT;
+ALTER TABLE books ADD COLUMN progress_percent DOUBLE PRECISION;
+
+CREATE TABLE reading_progress (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    page BIGINT,
+    percent DOUBLE PRECISION,
+    recorded_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_reading_progress_book_id ON reading_progress(book_id, recorded_at);
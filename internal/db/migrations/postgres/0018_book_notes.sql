@@ -0,0 +1,15 @@
+-- Notes and highlights: any number of timestamped notes per book, each with
+-- an optional page or location, for books the single comments field can't
+-- hold the notes of.
+
+CREATE TABLE book_notes (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL DEFAULT 'note' CHECK(kind IN ('note', 'highlight')),
+    body TEXT NOT NULL,
+    page BIGINT,
+    location TEXT,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_book_notes_book_id ON book_notes(book_id, created_at);
@@ -0,0 +1,11 @@
+-- Quotes: favorite passages collected per book, one picked at random for
+-- the dashboard.
+
+CREATE TABLE quotes (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    page BIGINT,
+    added_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_quotes_book_id ON quotes(book_id, added_at);
@@ -0,0 +1,14 @@
+-- Loans: physical books lent to someone, with an optional due date. A book
+-- has at most one loan not yet returned, which makes it lent out.
+
+CREATE TABLE loans (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    borrower TEXT NOT NULL,
+    lent_on TEXT NOT NULL,
+    due_on TEXT,
+    returned_on TEXT,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_loans_book_id ON loans(book_id, lent_on);
+CREATE UNIQUE INDEX idx_loans_outstanding ON loans(book_id) WHERE returned_on IS NULL;
@@ -0,0 +1,13 @@
+-- Market values: samples of what books are worth, by ISBN, taken
+-- periodically from a value source for a price history. Prices are the
+-- same for everyone, so samples belong to no user.
+
+CREATE TABLE market_values (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    isbn TEXT NOT NULL,
+    value DOUBLE PRECISION NOT NULL CHECK(value >= 0),
+    currency TEXT NOT NULL,
+    source TEXT NOT NULL,
+    sampled_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_market_values_isbn ON market_values(isbn, sampled_at);
@@ -0,0 +1,25 @@
+-- Book authors: every author a book names gets a row in authors, with or
+-- without a profile, and book_authors links books to them in the order of
+-- the book's author field, so co-written books belong to each of their
+-- authors. The author field is kept as the book's display text.
+
+CREATE TABLE book_authors (
+    book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    author_id BIGINT NOT NULL REFERENCES authors(id) ON DELETE CASCADE,
+    position BIGINT NOT NULL,
+    PRIMARY KEY (book_id, author_id)
+);
+CREATE INDEX idx_book_authors_author_id ON book_authors(author_id);
+
+-- Split the author fields of the existing books on commas
+INSERT INTO authors (name, updated_at)
+SELECT DISTINCT btrim(n.name)::citext, now()
+FROM books b CROSS JOIN LATERAL unnest(string_to_array(b.author, ',')) AS n(name)
+WHERE btrim(n.name) <> ''
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO book_authors (book_id, author_id, position)
+SELECT b.id, a.id, min(n.position) - 1
+FROM books b CROSS JOIN LATERAL unnest(string_to_array(b.author, ',')) WITH ORDINALITY AS n(name, position)
+JOIN authors a ON a.name = btrim(n.name)::citext
+GROUP BY b.id, a.id;
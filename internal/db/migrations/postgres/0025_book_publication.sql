@@ -0,0 +1,5 @@
+-- Publication details: the publisher and language of the edition owned,
+-- as entered or filled in from the metadata providers.
+
+ALTER TABLE books ADD COLUMN publisher TEXT;
+ALTER TABLE books ADD COLUMN language TEXT;
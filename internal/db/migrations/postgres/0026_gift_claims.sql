@@ -0,0 +1,19 @@
+-- Gift coordination: a user shares their Want to Read shelf with household
+-- members, who claim books on it to give, unseen by the owner. A book has at
+-- most one claim.
+
+CREATE TABLE wishlist_members (
+    owner_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    member_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (owner_id, member_id)
+);
+CREATE INDEX idx_wishlist_members_member_id ON wishlist_members(member_id);
+
+CREATE TABLE gift_claims (
+    book_id BIGINT PRIMARY KEY REFERENCES books(id) ON DELETE CASCADE,
+    member_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purchased BOOLEAN NOT NULL DEFAULT FALSE,
+    claimed_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_gift_claims_member_id ON gift_claims(member_id);
@@ -0,0 +1,17 @@
+-- Followed feeds and the timeline get an owner, like the rest of the library.
+-- Rows from before go to the first user, who adopted the library, or wait
+-- for them to register.
+
+-- A feed URL is now unique per owner
+ALTER TABLE follows ADD COLUMN user_id BIGINT REFERENCES users(id);
+UPDATE follows SET user_id = (SELECT MIN(id) FROM users);
+ALTER TABLE follows DROP CONSTRAINT IF EXISTS follows_feed_url_key;
+CREATE UNIQUE INDEX idx_follows_feed_url ON follows(COALESCE(user_id, 0), feed_url);
+
+-- A book's entries belong to the book's owner, those of feeds to the follower
+ALTER TABLE activities ADD COLUMN user_id BIGINT REFERENCES users(id);
+UPDATE activities SET user_id = COALESCE(
+    (SELECT user_id FROM books WHERE books.id = activities.book_id AND activities.follow_id IS NULL),
+    (SELECT user_id FROM follows WHERE follows.id = activities.follow_id),
+    (SELECT MIN(id) FROM users));
+CREATE INDEX idx_activities_user_id ON activities(user_id, occurred_at);
@@ -0,0 +1,14 @@
+-- The review queue gets an owner, like the rest of the library. Entries from
+-- before go to the owner of their sync account or book, or else to the first
+-- user, or wait for them to register.
+
+-- An entry is now unique per owner
+ALTER TABLE pending_matches ADD COLUMN user_id BIGINT REFERENCES users(id);
+UPDATE pending_matches SET user_id = COALESCE(
+    CASE source
+        WHEN 'tracker_sync' THEN (SELECT user_id FROM sync_accounts WHERE CAST(sync_accounts.id AS TEXT) = source_ref)
+        WHEN 'backfill' THEN (SELECT user_id FROM books WHERE CAST(books.id AS TEXT) = source_ref)
+    END,
+    (SELECT MIN(id) FROM users));
+ALTER TABLE pending_matches DROP CONSTRAINT IF EXISTS pending_matches_source_source_ref_item_key_key;
+CREATE UNIQUE INDEX idx_pending_matches_item ON pending_matches(COALESCE(user_id, 0), source, source_ref, item_key);
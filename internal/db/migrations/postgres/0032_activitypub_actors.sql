@@ -0,0 +1,15 @@
+-- Each user gets an ActivityPub actor of their own, with followers and a
+-- signing key of its own. Followers from before stay with the library's
+-- actor, which publishes the books without an owner.
+
+-- A follower is now unique per actor
+ALTER TABLE fediverse_followers ADD COLUMN user_id BIGINT REFERENCES users(id);
+ALTER TABLE fediverse_followers DROP CONSTRAINT IF EXISTS fediverse_followers_actor_id_key;
+CREATE UNIQUE INDEX idx_fediverse_followers_actor_id ON fediverse_followers(COALESCE(user_id, 0), actor_id);
+
+-- The library's key stays in settings
+CREATE TABLE activitypub_keys (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id),
+    private_key TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
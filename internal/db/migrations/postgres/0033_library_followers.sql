@@ -0,0 +1,10 @@
+-- The first user adopts the followers of the library's actor along with its
+-- books, and the actor goes on publishing that user's books to them. Its
+-- followers are marked, to tell them from those of the user's own actor.
+ALTER TABLE fediverse_followers ADD COLUMN library BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE fediverse_followers SET library = TRUE WHERE user_id IS NULL;
+
+-- They can be the user's own actor's followers too
+DROP INDEX idx_fediverse_followers_actor_id;
+UPDATE fediverse_followers SET user_id = (SELECT MIN(id) FROM users) WHERE user_id IS NULL;
+CREATE UNIQUE INDEX idx_fediverse_followers_actor_id ON fediverse_followers(COALESCE(user_id, 0), library, actor_id);
@@ -0,0 +1,3 @@
+-- Users choose whether their activity feed is public. It isn't until they
+-- turn it on, the first user's /feed.json included.
+ALTER TABLE users ADD COLUMN public_feed BOOLEAN NOT NULL DEFAULT FALSE;
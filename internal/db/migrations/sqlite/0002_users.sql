@@ -0,0 +1,79 @@
+-- migrate: foreign_keys=off
+-- Accounts, each with a library of its own. Books, tags, vacations and linked
+-- accounts get an owner; rows from before accounts have none until the first
+-- user registers and adopts them.
+
+CREATE TABLE users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    password_hash TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE TABLE sessions (
+    token_hash TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+
+-- An Open Library edition and a tag name are now unique per owner. SQLite
+-- can't drop a UNIQUE column constraint, so both tables are rebuilt.
+CREATE TABLE books_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id),
+    title TEXT NOT NULL,
+    subtitle TEXT,
+    author TEXT NOT NULL,
+    open_library_id TEXT NOT NULL,
+    isbn TEXT,
+    status TEXT NOT NULL CHECK(status IN ('Want to Read', 'Currently Reading', 'Read')),
+    type TEXT NOT NULL DEFAULT 'book' CHECK(type IN ('book', 'audiobook')),
+    rating INTEGER CHECK(rating IS NULL OR (rating >= 1 AND rating <= 10)),
+    comments TEXT,
+    cover_url TEXT,
+    series TEXT,
+    series_index INTEGER,
+    edition INTEGER CHECK(edition IS NULL OR edition > 0),
+    page_count INTEGER CHECK(page_count IS NULL OR page_count > 0),
+    course_code TEXT,
+    semester TEXT,
+    reading_mode TEXT NOT NULL DEFAULT 'leisure' CHECK(reading_mode IN ('leisure', 'reference')),
+    publish_opt_out BOOLEAN NOT NULL DEFAULT 0,
+    comments_spoiler BOOLEAN NOT NULL DEFAULT 0,
+    translated BOOLEAN NOT NULL DEFAULT 0,
+    publish_year INTEGER,
+    updated_at DATETIME,
+    cover_hash TEXT,
+    date_started DATETIME,
+    date_finished DATETIME,
+    description TEXT
+);
+INSERT INTO books_new (id, title, subtitle, author, open_library_id, isbn, status, type, rating, comments, cover_url,
+        series, series_index, edition, page_count, course_code, semester, reading_mode, publish_opt_out,
+        comments_spoiler, translated, publish_year, updated_at, cover_hash, date_started, date_finished, description)
+    SELECT id, title, subtitle, author, open_library_id, isbn, status, type, rating, comments, cover_url,
+        series, series_index, edition, page_count, course_code, semester, reading_mode, publish_opt_out,
+        comments_spoiler, translated, publish_year, updated_at, cover_hash, date_started, date_finished, description
+    FROM books;
+DROP TABLE books;
+ALTER TABLE books_new RENAME TO books;
+CREATE UNIQUE INDEX idx_books_open_library_id ON books(COALESCE(user_id, 0), open_library_id);
+CREATE INDEX idx_books_user_id ON books(user_id);
+
+CREATE TABLE tags_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id),
+    name TEXT NOT NULL COLLATE NOCASE,
+    created_at DATETIME NOT NULL
+);
+INSERT INTO tags_new (id, name, created_at) SELECT id, name, created_at FROM tags;
+DROP TABLE tags;
+ALTER TABLE tags_new RENAME TO tags;
+CREATE UNIQUE INDEX idx_tags_name ON tags(COALESCE(user_id, 0), name);
+
+ALTER TABLE book_tombstones ADD COLUMN user_id INTEGER REFERENCES users(id);
+ALTER TABLE vacations ADD COLUMN user_id INTEGER REFERENCES users(id);
+ALTER TABLE sync_accounts ADD COLUMN user_id INTEGER REFERENCES users(id);
+ALTER TABLE crosspost_accounts ADD COLUMN user_id INTEGER REFERENCES users(id);
@@ -0,0 +1,13 @@
+-- API keys let scripts act as a user without logging in. Only a hash of each
+-- key is kept, with its first characters to tell keys apart.
+
+CREATE TABLE api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME
+);
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
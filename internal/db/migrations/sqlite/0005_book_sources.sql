@@ -0,0 +1,7 @@
+-- Where and when each book was added, so an import can be audited and
+-- cleaned up later. Books from before are left without either.
+
+ALTER TABLE books ADD COLUMN source TEXT CHECK(source IS NULL OR source IN ('manual', 'api', 'isbn_scan', 'goodreads_import', 'list_import'));
+ALTER TABLE books ADD COLUMN added_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_books_source ON books(user_id, source);
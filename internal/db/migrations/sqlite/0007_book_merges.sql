@@ -0,0 +1,13 @@
+-- Merging a duplicate into a book keeps snapshots of both records and of what
+-- moved between them, so a wrong merge can be undone.
+
+CREATE TABLE book_merges (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    book_id INTEGER NOT NULL,
+    duplicate_id INTEGER NOT NULL,
+    snapshot TEXT NOT NULL,
+    merged_at DATETIME NOT NULL,
+    unmerged_at DATETIME
+);
+CREATE INDEX idx_book_merges_user_id ON book_merges(user_id, merged_at);
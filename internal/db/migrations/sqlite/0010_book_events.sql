@@ -0,0 +1,15 @@
+-- The audit log of every change to a book, written in the same transaction
+-- as the change. user_id is the book's owner and actor_id who made the change.
+-- Events have no foreign key to books, so they outlive a purge.
+
+CREATE TABLE book_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    book_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    related_book_id INTEGER,
+    changes TEXT,
+    occurred_at DATETIME NOT NULL
+);
+CREATE INDEX idx_book_events_book_id ON book_events(book_id, occurred_at);
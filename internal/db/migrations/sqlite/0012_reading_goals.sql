@@ -0,0 +1,12 @@
+-- The number of books to finish in a year. There is one goal per year and
+-- user.
+
+CREATE TABLE reading_goals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    year INTEGER NOT NULL,
+    target INTEGER NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX idx_reading_goals_year ON reading_goals(COALESCE(user_id, 0), year);
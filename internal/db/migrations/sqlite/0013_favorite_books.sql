@@ -0,0 +1,5 @@
+-- Favorites are the books a reader loves, whatever their rating. They can be
+-- listed on their own and sorted ahead of the rest.
+
+ALTER TABLE books ADD COLUMN favorite BOOLEAN NOT NULL DEFAULT 0;
+CREATE INDEX idx_books_favorite ON books(user_id, favorite);
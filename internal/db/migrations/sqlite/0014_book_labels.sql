@@ -0,0 +1,5 @@
+-- A label is an emoji or a color given to a book for grouping books at a
+-- glance. Unlike tags, a book has at most one.
+
+ALTER TABLE books ADD COLUMN label TEXT;
+CREATE INDEX idx_books_label ON books(user_id, label);
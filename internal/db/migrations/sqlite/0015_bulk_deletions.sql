@@ -0,0 +1,15 @@
+-- Deleting books by filter moves them to the trash together, and books
+-- remember the deletion, so it can be undone as a whole.
+
+CREATE TABLE bulk_deletions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    filter TEXT NOT NULL,
+    book_count INTEGER NOT NULL,
+    created_at DATETIME NOT NULL,
+    undone_at DATETIME
+);
+CREATE INDEX idx_bulk_deletions_user_id ON bulk_deletions(user_id);
+
+ALTER TABLE books ADD COLUMN bulk_deletion_id INTEGER REFERENCES bulk_deletions(id) ON DELETE SET NULL;
+CREATE INDEX idx_books_bulk_deletion_id ON books(bulk_deletion_id);
@@ -0,0 +1,14 @@
+-- Reading progress: the page reached in a book being read, or how far
+-- through it as a percentage, with a history of updates to measure the pace.
+
+ALTER TABLE books ADD COLUMN current_page INTEGER;
+ALTER TABLE books ADD COLUMN progress_percent REAL;
+
+CREATE TABLE reading_progress (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    page INTEGER,
+    percent REAL,
+    recorded_at DATETIME NOT NULL
+);
+CREATE INDEX idx_reading_progress_book_id ON reading_progress(book_id, recorded_at);
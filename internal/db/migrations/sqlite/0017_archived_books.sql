@@ -0,0 +1,5 @@
+-- Archived books are no longer owned, such as books sold or given away.
+-- They are hidden from book lists and reading stats, but keep their history.
+
+ALTER TABLE books ADD COLUMN archived BOOLEAN NOT NULL DEFAULT 0;
+CREATE INDEX idx_books_archived ON books(user_id, archived);
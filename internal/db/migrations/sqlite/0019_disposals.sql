@@ -0,0 +1,18 @@
+-- Disposals: a ledger of books that left the collection for good (gifted,
+-- donated, sold, discarded or lost), with the title and author kept so an
+-- entry outlives its book.
+
+CREATE TABLE disposals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    book_id INTEGER REFERENCES books(id) ON DELETE SET NULL,
+    title TEXT NOT NULL,
+    author TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK(kind IN ('gifted', 'donated', 'sold', 'discarded', 'lost')),
+    recipient TEXT,
+    reason TEXT,
+    disposed_on TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX idx_disposals_user_id ON disposals(user_id, disposed_on);
+CREATE INDEX idx_disposals_book_id ON disposals(book_id);
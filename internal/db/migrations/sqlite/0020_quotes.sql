@@ -0,0 +1,11 @@
+-- Quotes: favorite passages collected per book, one picked at random for
+-- the dashboard.
+
+CREATE TABLE quotes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    page INTEGER,
+    added_at DATETIME NOT NULL
+);
+CREATE INDEX idx_quotes_book_id ON quotes(book_id, added_at);
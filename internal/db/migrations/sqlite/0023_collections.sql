@@ -0,0 +1,21 @@
+-- Collections: user-defined shelves such as "2025 TBR", orthogonal to a
+-- book's status. Names are unique per user; a book is in a collection at
+-- most once.
+
+CREATE TABLE collections (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX idx_collections_name ON collections(COALESCE(user_id, 0), name);
+
+CREATE TABLE collection_books (
+    collection_id INTEGER NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    added_at DATETIME NOT NULL,
+    PRIMARY KEY (collection_id, book_id)
+);
+CREATE INDEX idx_collection_books_book_id ON collection_books(book_id);
@@ -0,0 +1,31 @@
+-- Book authors: every author a book names gets a row in authors, with or
+-- without a profile, and book_authors links books to them in the order of
+-- the book's author field, so co-written books belong to each of their
+-- authors. The author field is kept as the book's display text.
+
+CREATE TABLE book_authors (
+    book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    author_id INTEGER NOT NULL REFERENCES authors(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (book_id, author_id)
+);
+CREATE INDEX idx_book_authors_author_id ON book_authors(author_id);
+
+-- Split the author fields of the existing books on commas
+CREATE TEMP TABLE split_authors AS
+WITH RECURSIVE split(book_id, position, name, rest) AS (
+    SELECT id, 0, '', author || ',' FROM books
+    UNION ALL
+    SELECT book_id, position + 1, trim(substr(rest, 1, instr(rest, ',') - 1)), substr(rest, instr(rest, ',') + 1)
+    FROM split WHERE rest != ''
+)
+SELECT book_id, position, name FROM split WHERE name != '';
+
+INSERT OR IGNORE INTO authors (name, updated_at)
+SELECT name, CURRENT_TIMESTAMP FROM split_authors ORDER BY book_id, position;
+
+INSERT OR IGNORE INTO book_authors (book_id, author_id, position)
+SELECT s.book_id, a.id, s.position FROM split_authors s JOIN authors a ON a.name = s.name
+ORDER BY s.book_id, s.position;
+
+DROP TABLE split_authors;
@@ -0,0 +1,32 @@
+-- Webhooks: endpoints a user registers to be sent signed book lifecycle
+-- events, with the deliveries queued for them in the transaction of each
+-- change. A delivery is retried with backoff until it succeeds or is given up
+-- on, and kept as the webhook's delivery log.
+
+CREATE TABLE webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    events TEXT NOT NULL, -- Comma-separated, e.g. book.created,book.finished
+    secret_encrypted TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT 1,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX idx_webhooks_user_id ON webhooks(user_id);
+
+CREATE TABLE webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event TEXT NOT NULL,
+    book_id INTEGER NOT NULL, -- Not a foreign key: the log outlives the book
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME,
+    response_status INTEGER,
+    error TEXT,
+    created_at DATETIME NOT NULL,
+    delivered_at DATETIME
+);
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, id);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
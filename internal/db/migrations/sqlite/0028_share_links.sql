@@ -0,0 +1,19 @@
+-- Share links: tokenized, read-only URLs to a filtered view of a user's
+-- library, for people without an account. Only a hash of each token is kept,
+-- with its first characters to tell links apart, as for API keys.
+
+CREATE TABLE share_links (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    status TEXT, -- Only books on this shelf; NULL for every shelf
+    tag TEXT, -- Only books with this tag; NULL for any
+    include_ratings BOOLEAN NOT NULL DEFAULT 0,
+    include_comments BOOLEAN NOT NULL DEFAULT 0,
+    expires_at DATETIME,
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME
+);
+CREATE INDEX idx_share_links_user_id ON share_links(user_id);
@@ -0,0 +1,24 @@
+-- Passkeys: WebAuthn credentials users log in with instead of a password.
+-- Credential IDs and COSE public keys are kept base64url encoded. Every
+-- registration and login answers a one-use challenge, kept until then or
+-- until it expires; login challenges belong to no user.
+
+CREATE TABLE passkeys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    credential_id TEXT NOT NULL UNIQUE,
+    public_key TEXT NOT NULL,
+    sign_count INTEGER NOT NULL DEFAULT 0,
+    transports TEXT NOT NULL DEFAULT '', -- Comma-separated
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME
+);
+CREATE INDEX idx_passkeys_user_id ON passkeys(user_id);
+
+CREATE TABLE passkey_challenges (
+    challenge TEXT PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('register', 'login')),
+    expires_at DATETIME NOT NULL
+);
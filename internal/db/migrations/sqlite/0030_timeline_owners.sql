@@ -0,0 +1,29 @@
+-- migrate: foreign_keys=off
+-- Followed feeds and the timeline get an owner, like the rest of the library.
+-- Rows from before go to the first user, who adopted the library, or wait
+-- for them to register.
+
+-- A feed URL is now unique per owner. SQLite can't drop a UNIQUE column
+-- constraint, so the table is rebuilt.
+CREATE TABLE follows_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id),
+    feed_url TEXT NOT NULL,
+    name TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    last_fetched_at DATETIME,
+    last_error TEXT
+);
+INSERT INTO follows_new (id, user_id, feed_url, name, created_at, last_fetched_at, last_error)
+    SELECT id, (SELECT MIN(id) FROM users), feed_url, name, created_at, last_fetched_at, last_error FROM follows;
+DROP TABLE follows;
+ALTER TABLE follows_new RENAME TO follows;
+CREATE UNIQUE INDEX idx_follows_feed_url ON follows(COALESCE(user_id, 0), feed_url);
+
+-- A book's entries belong to the book's owner, those of feeds to the follower
+ALTER TABLE activities ADD COLUMN user_id INTEGER REFERENCES users(id);
+UPDATE activities SET user_id = COALESCE(
+    (SELECT user_id FROM books WHERE books.id = activities.book_id AND activities.follow_id IS NULL),
+    (SELECT user_id FROM follows WHERE follows.id = activities.follow_id),
+    (SELECT MIN(id) FROM users));
+CREATE INDEX idx_activities_user_id ON activities(user_id, occurred_at);
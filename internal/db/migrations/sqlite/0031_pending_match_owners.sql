@@ -0,0 +1,31 @@
+-- The review queue gets an owner, like the rest of the library. Entries from
+-- before go to the owner of their sync account or book, or else to the first
+-- user, or wait for them to register.
+
+-- An entry is now unique per owner. SQLite can't drop a UNIQUE table
+-- constraint, so the table is rebuilt.
+CREATE TABLE pending_matches_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id),
+    source TEXT NOT NULL,
+    source_ref TEXT NOT NULL,
+    item_key TEXT NOT NULL,
+    item TEXT NOT NULL,
+    candidates TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'linked', 'created', 'skipped')),
+    book_id INTEGER,
+    created_at DATETIME NOT NULL,
+    resolved_at DATETIME
+);
+INSERT INTO pending_matches_new (id, user_id, source, source_ref, item_key, item, candidates, status, book_id, created_at, resolved_at)
+    SELECT id, COALESCE(
+            CASE source
+                WHEN 'tracker_sync' THEN (SELECT user_id FROM sync_accounts WHERE CAST(sync_accounts.id AS TEXT) = source_ref)
+                WHEN 'backfill' THEN (SELECT user_id FROM books WHERE CAST(books.id AS TEXT) = source_ref)
+            END,
+            (SELECT MIN(id) FROM users)),
+        source, source_ref, item_key, item, candidates, status, book_id, created_at, resolved_at
+    FROM pending_matches;
+DROP TABLE pending_matches;
+ALTER TABLE pending_matches_new RENAME TO pending_matches;
+CREATE UNIQUE INDEX idx_pending_matches_item ON pending_matches(COALESCE(user_id, 0), source, source_ref, item_key);
@@ -0,0 +1,25 @@
+-- Each user gets an ActivityPub actor of their own, with followers and a
+-- signing key of its own. Followers from before stay with the library's
+-- actor, which publishes the books without an owner.
+
+-- A follower is now unique per actor. SQLite can't drop a UNIQUE column
+-- constraint, so the table is rebuilt.
+CREATE TABLE fediverse_followers_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id),
+    actor_id TEXT NOT NULL,
+    inbox_url TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+INSERT INTO fediverse_followers_new (id, actor_id, inbox_url, created_at)
+    SELECT id, actor_id, inbox_url, created_at FROM fediverse_followers;
+DROP TABLE fediverse_followers;
+ALTER TABLE fediverse_followers_new RENAME TO fediverse_followers;
+CREATE UNIQUE INDEX idx_fediverse_followers_actor_id ON fediverse_followers(COALESCE(user_id, 0), actor_id);
+
+-- The library's key stays in settings
+CREATE TABLE activitypub_keys (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id),
+    private_key TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
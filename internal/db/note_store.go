@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// NoteStore defines the database operations for the notes and highlights
+// taken on a book.
+type NoteStore interface {
+	GetNotes(ctx context.Context, bookID int64) ([]model.Note, error)
+	AddNote(ctx context.Context, note *model.Note) error
+	UpdateNote(ctx context.Context, note *model.Note) error
+	DeleteNote(ctx context.Context, bookID, noteID int64) error
+}
+
+const noteColumns = `id, book_id, kind, body, page, location, created_at, updated_at`
+
+func scanNote(row rowScanner) (*model.Note, error) {
+	var note model.Note
+	var page sql.NullInt64
+	var location sql.NullString
+	if err := row.Scan(&note.ID, &note.BookID, &note.Kind, &note.Body, &page, &location, &note.CreatedAt, &note.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if page.Valid {
+		p := int(page.Int64)
+		note.Page = &p
+	}
+	if location.Valid {
+		note.Location = &location.String
+	}
+	return &note, nil
+}
+
+// GetNotes returns the notes of a book, oldest first.
+func (s *SQLiteBookStore) GetNotes(ctx context.Context, bookID int64) ([]model.Note, error) {
+	if _, err := s.GetBookByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "SQL: Executing GetNotes query", "bookID", bookID)
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+noteColumns+` FROM book_notes WHERE book_id = ? ORDER BY created_at, id;`, bookID)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetNotes query failed", "error", err)
+		return nil, fmt.Errorf("failed to query notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := []model.Note{}
+	for rows.Next() {
+		note, err := scanNote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan note row: %w", err)
+		}
+		notes = append(notes, *note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating note rows: %w", err)
+	}
+	return notes, nil
+}
+
+// AddNote adds a note to a book and sets its ID and timestamps.
+func (s *SQLiteBookStore) AddNote(ctx context.Context, note *model.Note) error {
+	if err := note.Validate(); err != nil {
+		return &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
+	}
+	if _, err := s.GetBookByID(ctx, note.BookID); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing AddNote query", "bookID", note.BookID)
+	now := time.Now().UTC()
+	if err := s.DB.QueryRowContext(ctx, `INSERT INTO book_notes (book_id, kind, body, page, location, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id;`, note.BookID, note.Kind, note.Body, note.Page, note.Location, now, now).Scan(&note.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Inserting note failed", "error", err)
+		return fmt.Errorf("failed to add note: %w", classify(err))
+	}
+	note.CreatedAt, note.UpdatedAt = now, now
+	return nil
+}
+
+// UpdateNote replaces the kind, body and place of a note of a book, keeping
+// when it was taken, and sets its timestamps.
+func (s *SQLiteBookStore) UpdateNote(ctx context.Context, note *model.Note) error {
+	if err := note.Validate(); err != nil {
+		return &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
+	}
+	if _, err := s.GetBookByID(ctx, note.BookID); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing UpdateNote query", "bookID", note.BookID, "noteID", note.ID)
+	now := time.Now().UTC()
+	err := s.DB.QueryRowContext(ctx, `UPDATE book_notes SET kind = ?, body = ?, page = ?, location = ?, updated_at = ?
+        WHERE id = ? AND book_id = ? RETURNING created_at;`,
+		note.Kind, note.Body, note.Page, note.Location, now, note.ID, note.BookID).Scan(&note.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("note %d of book with ID %d %w", note.ID, note.BookID, ErrNotFound)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Updating note failed", "error", err)
+		return fmt.Errorf("failed to update note: %w", classify(err))
+	}
+	note.UpdatedAt = now
+	return nil
+}
+
+// DeleteNote removes a note from a book.
+func (s *SQLiteBookStore) DeleteNote(ctx context.Context, bookID, noteID int64) error {
+	if _, err := s.GetBookByID(ctx, bookID); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing DeleteNote query", "bookID", bookID, "noteID", noteID)
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM book_notes WHERE id = ? AND book_id = ?;`, noteID, bookID)
+	if err != nil {
+		return fmt.Errorf("failed to delete note: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("note %d of book with ID %d %w", noteID, bookID, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestBookNotes(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	if _, err := store.AddBook(ctx, book); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+
+	page, location := 42, "  "
+	note := &model.Note{BookID: book.ID, Body: "  The answer.  ", Page: &page, Location: &location}
+	if err := store.AddNote(ctx, note); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+	if note.ID == 0 || note.Kind != model.NoteKindNote || note.Body != "The answer." || note.Location != nil || note.CreatedAt.IsZero() {
+		t.Errorf("Expected a trimmed note with its ID, got %+v", note)
+	}
+	highlight := &model.Note{BookID: book.ID, Kind: model.NoteKindHighlight, Body: "Don't panic."}
+	if err := store.AddNote(ctx, highlight); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+	for _, bad := range []*model.Note{{BookID: book.ID}, {BookID: book.ID, Body: "x", Kind: "quote"}, {BookID: book.ID, Body: "x", Page: new(int)}} {
+		if err := store.AddNote(ctx, bad); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected %+v to be invalid, got %v", bad, err)
+		}
+	}
+	if err := store.AddNote(ctx, &model.Note{BookID: 999, Body: "x"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a note on a missing book to be not found, got %v", err)
+	}
+
+	notes, err := store.GetNotes(ctx, book.ID)
+	if err != nil || len(notes) != 2 || notes[0].ID != note.ID || notes[1].Kind != model.NoteKindHighlight {
+		t.Fatalf("Expected both notes, oldest first, got %+v, %v", notes, err)
+	}
+	if got, _ := store.GetBookByID(ctx, book.ID); got.NoteCount != 2 {
+		t.Errorf("Expected the book to count 2 notes, got %d", got.NoteCount)
+	}
+
+	note.Body, note.Page = "Forty-two.", nil
+	if err := store.UpdateNote(ctx, note); err != nil {
+		t.Fatalf("UpdateNote failed: %v", err)
+	}
+	notes, _ = store.GetNotes(ctx, book.ID)
+	if notes[0].Body != "Forty-two." || notes[0].Page != nil || notes[0].UpdatedAt.Before(notes[0].CreatedAt) {
+		t.Errorf("Expected the note replaced, got %+v", notes[0])
+	}
+	if err := store.UpdateNote(ctx, &model.Note{ID: 999, BookID: book.ID, Body: "x"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a missing note to be not found, got %v", err)
+	}
+
+	if err := store.DeleteNote(ctx, book.ID, highlight.ID); err != nil {
+		t.Fatalf("DeleteNote failed: %v", err)
+	}
+	if err := store.DeleteNote(ctx, book.ID, highlight.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a deleted note to be not found, got %v", err)
+	}
+
+	// Merging a duplicate keeps its notes and quotes, on the kept book
+	duplicate := createTestBook()
+	duplicate.OpenLibraryID = "OLNOTEDUPM"
+	if _, err := store.AddBook(ctx, duplicate); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if err := store.AddNote(ctx, &model.Note{BookID: duplicate.ID, Body: "From the duplicate"}); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+	if err := store.AddQuote(ctx, &model.Quote{BookID: duplicate.ID, Text: "From the duplicate"}); err != nil {
+		t.Fatalf("AddQuote failed: %v", err)
+	}
+	merge, err := store.MergeBooks(ctx, book.ID, duplicate.ID)
+	if err != nil {
+		t.Fatalf("MergeBooks failed: %v", err)
+	}
+	if notes, _ := store.GetNotes(ctx, book.ID); len(notes) != 2 {
+		t.Errorf("Expected the duplicate's note moved to the kept book, got %+v", notes)
+	}
+	if quotes, _ := store.GetQuotes(ctx, book.ID); len(quotes) != 1 {
+		t.Errorf("Expected the duplicate's quote moved to the kept book, got %+v", quotes)
+	}
+	if _, err := store.UnmergeBooks(ctx, merge.ID); err != nil {
+		t.Fatalf("UnmergeBooks failed: %v", err)
+	}
+	if notes, _ := store.GetNotes(ctx, duplicate.ID); len(notes) != 1 {
+		t.Errorf("Expected the note back on the restored duplicate, got %+v", notes)
+	}
+	if quotes, _ := store.GetQuotes(ctx, duplicate.ID); len(quotes) != 1 {
+		t.Errorf("Expected the quote back on the restored duplicate, got %+v", quotes)
+	}
+}
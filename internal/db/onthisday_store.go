@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// OnThisDayStore defines the lookup of books finished or added on the same day
+// in earlier years.
+type OnThisDayStore interface {
+	GetOnThisDay(ctx context.Context, day time.Time) (model.OnThisDay, error)
+}
+
+// extraScanner scans a book row followed by extra columns.
+type extraScanner struct {
+	row   rowScanner
+	extra []interface{}
+}
+
+func (e extraScanner) Scan(dest ...interface{}) error {
+	return e.row.Scan(append(dest, e.extra...)...)
+}
+
+// GetOnThisDay returns the reads finished and the books added on day's month
+// and day in years before day's, in UTC. On 28 February of a year without a
+// leap day, 29 February is included too. A book read on the day in several
+// years is listed once for each.
+func (s *SQLiteBookStore) GetOnThisDay(ctx context.Context, day time.Time) (model.OnThisDay, error) {
+	day = day.UTC()
+	days := []string{day.Format("01-02")}
+	if day.Month() == time.February && day.Day() == 28 && time.Date(day.Year(), time.February, 29, 0, 0, 0, 0, time.UTC).Month() != time.February {
+		days = append(days, "02-29")
+	}
+	year := fmt.Sprintf("%04d", day.Year())
+
+	owned, ownerArgs := shelved(ctx, "books")
+	var result model.OnThisDay
+	var err error
+	slog.InfoContext(ctx, "SQL: Executing GetOnThisDay query", "day", days[0])
+	if result.Finished, err = s.queryMemories(ctx, day, `SELECT `+bookColumns+`, happened.happened_at FROM books
+        JOIN (SELECT book_id, date_finished AS happened_at FROM reads) happened ON happened.book_id = books.id
+        WHERE `+s.dialect.monthDay("happened.happened_at")+` IN (?, ?) AND `+s.dialect.year("happened.happened_at")+` < ? AND `+owned+`
+        ORDER BY happened.happened_at DESC, books.id;`, append([]interface{}{days[0], days[len(days)-1], year}, ownerArgs...)...); err != nil {
+		return result, err
+	}
+	if result.Added, err = s.queryMemories(ctx, day, `SELECT `+bookColumns+`, happened.happened_at FROM books
+        JOIN (SELECT book_id, occurred_at AS happened_at FROM activities WHERE kind = ?) happened ON happened.book_id = books.id
+        WHERE `+s.dialect.monthDay("happened.happened_at")+` IN (?, ?) AND `+s.dialect.year("happened.happened_at")+` < ? AND `+owned+`
+        ORDER BY happened.happened_at DESC, books.id;`,
+		append([]interface{}{model.ActivityBookAdded, days[0], days[len(days)-1], year}, ownerArgs...)...); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// queryMemories runs a query selecting the book columns followed by when
+// something happened to the book.
+func (s *SQLiteBookStore) queryMemories(ctx context.Context, day time.Time, query string, args ...interface{}) ([]model.Memory, error) {
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetOnThisDay query failed", "error", err)
+		return nil, fmt.Errorf("failed to query memories: %w", err)
+	}
+	defer rows.Close()
+
+	memories := []model.Memory{}
+	for rows.Next() {
+		var at time.Time
+		book, err := scanBook(extraScanner{row: rows, extra: []interface{}{&at}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan book row: %w", err)
+		}
+		memories = append(memories, model.Memory{Book: *book, Date: at, YearsAgo: day.Year() - at.UTC().Year()})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating book rows: %w", err)
+	}
+	return memories, nil
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestGetOnThisDay(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	add := func(olid string, finished ...time.Time) *model.Book {
+		t.Helper()
+		book := createTestBook()
+		book.OpenLibraryID, book.Status = olid, model.StatusRead
+		book.DateFinished = &finished[0]
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+		for _, f := range finished[1:] {
+			if err := store.AddRead(ctx, &model.Read{BookID: book.ID, DateFinished: f}); err != nil {
+				t.Fatalf("AddRead failed: %v", err)
+			}
+		}
+		return book
+	}
+	day := time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC)
+	reread := add("OL1M", time.Date(2019, 3, 14, 22, 0, 0, 0, time.UTC), time.Date(2023, 3, 14, 1, 0, 0, 0, time.UTC))
+	add("OL2M", time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+	add("OL3M", time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)) // This year does not count
+	added := add("OL4M", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err := store.RecordActivity(ctx, &model.Activity{BookID: &added.ID, Kind: model.ActivityBookAdded, Title: added.Title,
+		OccurredAt: time.Date(2021, 3, 14, 12, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatalf("RecordActivity failed: %v", err)
+	}
+
+	got, err := store.GetOnThisDay(ctx, day)
+	if err != nil {
+		t.Fatalf("GetOnThisDay failed: %v", err)
+	}
+	if len(got.Finished) != 2 || got.Finished[0].Book.ID != reread.ID || got.Finished[0].YearsAgo != 2 || got.Finished[1].YearsAgo != 6 {
+		t.Errorf("Expected both reads of the re-read book, newest first, got %+v", got.Finished)
+	}
+	if len(got.Added) != 1 || got.Added[0].Book.ID != added.ID || got.Added[0].YearsAgo != 4 ||
+		!got.Added[0].Date.Equal(time.Date(2021, 3, 14, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the book added in 2021, got %+v", got.Added)
+	}
+
+	// Leap day reads show up on 28 February when there is no leap day
+	leap := add("OL5M", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC))
+	got, _ = store.GetOnThisDay(ctx, time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC))
+	if len(got.Finished) != 1 || got.Finished[0].Book.ID != leap.ID {
+		t.Errorf("Expected the leap day read on 28 February, got %+v", got.Finished)
+	}
+	if got, _ = store.GetOnThisDay(ctx, time.Date(2028, 2, 28, 0, 0, 0, 0, time.UTC)); len(got.Finished) != 0 {
+		t.Errorf("Expected no leap day read on 28 February of a leap year, got %+v", got.Finished)
+	}
+}
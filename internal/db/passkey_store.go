@@ -0,0 +1,210 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// PasskeyCeremony is what a passkey challenge is for.
+type PasskeyCeremony string
+
+const (
+	PasskeyRegister PasskeyCeremony = "register"
+	PasskeyLogin    PasskeyCeremony = "login"
+)
+
+// PasskeyStore manages users' passkeys and the one-use challenges they are
+// registered and logged in with.
+type PasskeyStore interface {
+	// AddPasskeyChallenge stores a challenge for a ceremony of userID, nil for
+	// a login by whoever answers it, until expiresAt. Expired challenges are
+	// removed.
+	AddPasskeyChallenge(ctx context.Context, challenge string, userID *int64, ceremony PasskeyCeremony, expiresAt time.Time) error
+	// ConsumePasskeyChallenge removes a challenge for ceremony and returns
+	// the user it is for. Unknown, used and expired challenges are not found.
+	ConsumePasskeyChallenge(ctx context.Context, challenge string, ceremony PasskeyCeremony) (*int64, error)
+	// AddPasskey stores a passkey of passkey.UserID and sets its ID and
+	// creation time.
+	AddPasskey(ctx context.Context, passkey *model.Passkey) error
+	// GetPasskeys returns the passkeys, newest first.
+	GetPasskeys(ctx context.Context) ([]model.Passkey, error)
+	// GetPasskeyByCredentialID returns the passkey with the base64url
+	// credential ID, regardless of the user of ctx.
+	GetPasskeyByCredentialID(ctx context.Context, credentialID string) (*model.Passkey, error)
+	// UpdatePasskeyUse records a login with a passkey and its authenticator's
+	// signature counter.
+	UpdatePasskeyUse(ctx context.Context, id int64, signCount uint32) error
+	// DeletePasskey removes a passkey.
+	DeletePasskey(ctx context.Context, id int64) error
+}
+
+const passkeyColumns = `id, user_id, name, credential_id, public_key, sign_count, transports, created_at, last_used_at`
+
+// scanPasskey reads a passkeys row of passkeyColumns.
+func scanPasskey(row interface{ Scan(...interface{}) error }) (model.Passkey, error) {
+	var passkey model.Passkey
+	var publicKey, transports string
+	var signCount int64
+	var lastUsed sql.NullTime
+	if err := row.Scan(&passkey.ID, &passkey.UserID, &passkey.Name, &passkey.CredentialID, &publicKey, &signCount, &transports,
+		&passkey.CreatedAt, &lastUsed); err != nil {
+		return passkey, err
+	}
+	key, err := base64.RawURLEncoding.DecodeString(publicKey)
+	if err != nil {
+		return passkey, fmt.Errorf("invalid public key of passkey %d: %w", passkey.ID, err)
+	}
+	passkey.PublicKey, passkey.SignCount = key, uint32(signCount)
+	passkey.Transports = []string{}
+	if transports != "" {
+		passkey.Transports = strings.Split(transports, ",")
+	}
+	if lastUsed.Valid {
+		passkey.LastUsedAt = &lastUsed.Time
+	}
+	return passkey, nil
+}
+
+// AddPasskeyChallenge stores a challenge for a ceremony of userID, nil for a
+// login by whoever answers it, until expiresAt. Expired challenges are
+// removed.
+func (s *SQLiteBookStore) AddPasskeyChallenge(ctx context.Context, challenge string, userID *int64, ceremony PasskeyCeremony, expiresAt time.Time) error {
+	slog.InfoContext(ctx, "SQL: Executing AddPasskeyChallenge query", "ceremony", ceremony)
+	if _, err := s.DB.ExecContext(ctx, `DELETE FROM passkey_challenges WHERE expires_at <= ?;`, time.Now().UTC()); err != nil {
+		slog.WarnContext(ctx, "Failed to remove expired passkey challenges", "error", err)
+	}
+	if _, err := s.DB.ExecContext(ctx, `INSERT INTO passkey_challenges (challenge, user_id, kind, expires_at) VALUES (?, ?, ?, ?);`,
+		challenge, userID, string(ceremony), expiresAt.UTC()); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddPasskeyChallenge statement failed", "error", err)
+		return fmt.Errorf("failed to add passkey challenge: %w", classify(err))
+	}
+	return nil
+}
+
+// ConsumePasskeyChallenge removes a challenge for ceremony and returns the
+// user it is for. Unknown, used and expired challenges are not found.
+func (s *SQLiteBookStore) ConsumePasskeyChallenge(ctx context.Context, challenge string, ceremony PasskeyCeremony) (*int64, error) {
+	slog.InfoContext(ctx, "SQL: Executing ConsumePasskeyChallenge query", "ceremony", ceremony)
+	var userID sql.NullInt64
+	var expiresAt time.Time
+	// Deleting it first means a challenge can only be answered once
+	err := s.DB.QueryRowContext(ctx, `DELETE FROM passkey_challenges WHERE challenge = ? AND kind = ? RETURNING user_id, expires_at;`,
+		challenge, string(ceremony)).Scan(&userID, &expiresAt)
+	if err == sql.ErrNoRows || (err == nil && !expiresAt.After(time.Now())) {
+		return nil, fmt.Errorf("passkey challenge %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume passkey challenge: %w", err)
+	}
+	if userID.Valid {
+		return &userID.Int64, nil
+	}
+	return nil, nil
+}
+
+// AddPasskey stores a passkey of passkey.UserID and sets its ID and creation
+// time.
+func (s *SQLiteBookStore) AddPasskey(ctx context.Context, passkey *model.Passkey) error {
+	if passkey.Name == "" {
+		return invalidf("passkey name is required")
+	}
+	if passkey.CredentialID == "" || len(passkey.PublicKey) == 0 {
+		return invalidf("passkey credential ID and public key are required")
+	}
+	slog.InfoContext(ctx, "SQL: Executing AddPasskey query", "user", passkey.UserID, "name", passkey.Name)
+	passkey.CreatedAt = time.Now().UTC()
+	if passkey.Transports == nil {
+		passkey.Transports = []string{}
+	}
+	if err := s.DB.QueryRowContext(ctx, `INSERT INTO passkeys (user_id, name, credential_id, public_key, sign_count, transports, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id;`,
+		passkey.UserID, passkey.Name, passkey.CredentialID, base64.RawURLEncoding.EncodeToString(passkey.PublicKey),
+		int64(passkey.SignCount), strings.Join(passkey.Transports, ","), passkey.CreatedAt).Scan(&passkey.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddPasskey statement failed", "error", err)
+		return fmt.Errorf("failed to add passkey: %w", classify(err))
+	}
+	return nil
+}
+
+// GetPasskeys returns the passkeys, newest first.
+func (s *SQLiteBookStore) GetPasskeys(ctx context.Context) ([]model.Passkey, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetPasskeys query")
+	owned, args := ownedBy(ctx, "user_id")
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+passkeyColumns+` FROM passkeys WHERE `+owned+`
+        ORDER BY created_at DESC, id DESC;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetPasskeys query failed", "error", err)
+		return nil, fmt.Errorf("failed to query passkeys: %w", err)
+	}
+	defer rows.Close()
+
+	passkeys := []model.Passkey{}
+	for rows.Next() {
+		passkey, err := scanPasskey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan passkey row: %w", err)
+		}
+		passkeys = append(passkeys, passkey)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating passkey rows: %w", err)
+	}
+	return passkeys, nil
+}
+
+// GetPasskeyByCredentialID returns the passkey with the base64url credential
+// ID, regardless of the user of ctx.
+func (s *SQLiteBookStore) GetPasskeyByCredentialID(ctx context.Context, credentialID string) (*model.Passkey, error) {
+	passkey, err := scanPasskey(s.DB.QueryRowContext(ctx, `SELECT `+passkeyColumns+` FROM passkeys WHERE credential_id = ?;`, credentialID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("passkey %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get passkey: %w", err)
+	}
+	return &passkey, nil
+}
+
+// UpdatePasskeyUse records a login with a passkey and its authenticator's
+// signature counter.
+func (s *SQLiteBookStore) UpdatePasskeyUse(ctx context.Context, id int64, signCount uint32) error {
+	slog.InfoContext(ctx, "SQL: Executing UpdatePasskeyUse query", "id", id)
+	res, err := s.DB.ExecContext(ctx, `UPDATE passkeys SET sign_count = ?, last_used_at = ? WHERE id = ?;`,
+		int64(signCount), time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update passkey: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("passkey with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// DeletePasskey removes a passkey.
+func (s *SQLiteBookStore) DeletePasskey(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "SQL: Executing DeletePasskey query", "id", id)
+	owned, args := ownedBy(ctx, "user_id")
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM passkeys WHERE id = ? AND `+owned+`;`, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete passkey: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("passkey with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestPasskeys(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	var users []model.User
+	for _, username := range []string{"alice", "bob"} {
+		user := model.User{Username: username, PasswordHash: "hash"}
+		if err := store.AddUser(ctx, &user); err != nil {
+			t.Fatalf("AddUser failed: %v", err)
+		}
+		users = append(users, user)
+	}
+	alice, bob := WithUser(ctx, users[0].ID), WithUser(ctx, users[1].ID)
+
+	// Challenges are answered once, for the ceremony they were made for
+	future := time.Now().Add(time.Minute)
+	if err := store.AddPasskeyChallenge(ctx, "register-1", &users[0].ID, PasskeyRegister, future); err != nil {
+		t.Fatalf("AddPasskeyChallenge failed: %v", err)
+	}
+	if err := store.AddPasskeyChallenge(ctx, "login-1", nil, PasskeyLogin, future); err != nil {
+		t.Fatalf("AddPasskeyChallenge failed: %v", err)
+	}
+	if err := store.AddPasskeyChallenge(ctx, "old", nil, PasskeyLogin, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("AddPasskeyChallenge failed: %v", err)
+	}
+	if _, err := store.ConsumePasskeyChallenge(ctx, "register-1", PasskeyLogin); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a registration challenge not to log in, got %v", err)
+	}
+	if userID, err := store.ConsumePasskeyChallenge(ctx, "register-1", PasskeyRegister); err != nil || userID == nil || *userID != users[0].ID {
+		t.Errorf("ConsumePasskeyChallenge = %v, %v", userID, err)
+	}
+	if userID, err := store.ConsumePasskeyChallenge(ctx, "login-1", PasskeyLogin); err != nil || userID != nil {
+		t.Errorf("ConsumePasskeyChallenge = %v, %v", userID, err)
+	}
+	for _, challenge := range []string{"register-1", "login-1", "old"} {
+		if _, err := store.ConsumePasskeyChallenge(ctx, challenge, PasskeyLogin); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected %s not found, got %v", challenge, err)
+		}
+	}
+
+	if err := store.AddPasskey(alice, &model.Passkey{UserID: users[0].ID, CredentialID: "cred"}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a passkey without a name or key to be rejected, got %v", err)
+	}
+	passkey := &model.Passkey{UserID: users[0].ID, Name: "Laptop", CredentialID: "cred-1", PublicKey: []byte{0xa5, 0x01}, Transports: []string{"internal", "hybrid"}}
+	if err := store.AddPasskey(alice, passkey); err != nil {
+		t.Fatalf("AddPasskey failed: %v", err)
+	}
+	if err := store.AddPasskey(bob, &model.Passkey{UserID: users[1].ID, Name: "Phone", CredentialID: "cred-1", PublicKey: []byte{1}}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected a credential to be registered once, got %v", err)
+	}
+
+	found, err := store.GetPasskeyByCredentialID(bob, "cred-1")
+	if err != nil || found.ID != passkey.ID || found.UserID != users[0].ID || string(found.PublicKey) != "\xa5\x01" || len(found.Transports) != 2 {
+		t.Fatalf("GetPasskeyByCredentialID = %+v, %v", found, err)
+	}
+	if err := store.UpdatePasskeyUse(ctx, passkey.ID, 7); err != nil {
+		t.Fatalf("UpdatePasskeyUse failed: %v", err)
+	}
+	passkeys, err := store.GetPasskeys(alice)
+	if err != nil || len(passkeys) != 1 || passkeys[0].SignCount != 7 || passkeys[0].LastUsedAt == nil {
+		t.Fatalf("Unexpected passkeys %+v, %v", passkeys, err)
+	}
+	if passkeys, err := store.GetPasskeys(bob); err != nil || len(passkeys) != 0 {
+		t.Errorf("Expected bob to have no passkeys, got %+v, %v", passkeys, err)
+	}
+	if err := store.DeletePasskey(bob, passkey.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected bob not to remove alice's passkey, got %v", err)
+	}
+	if err := store.DeletePasskey(alice, passkey.ID); err != nil {
+		t.Fatalf("DeletePasskey failed: %v", err)
+	}
+	if _, err := store.GetPasskeyByCredentialID(ctx, "cred-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a removed passkey not found, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// PendingMatchStore defines the database operations for the manual review queue.
+type PendingMatchStore interface {
+	AddPendingMatch(ctx context.Context, match *model.PendingMatch) (bool, error)
+	GetPendingMatches(ctx context.Context, status model.PendingMatchStatus) ([]model.PendingMatch, error)
+	GetPendingMatchByID(ctx context.Context, id int64) (*model.PendingMatch, error)
+	ResolvePendingMatch(ctx context.Context, id int64, status model.PendingMatchStatus, bookID *int64) error
+}
+
+// AddPendingMatch queues an ambiguous match for review by the user of ctx. Items
+// they already queued for the same source (pending or previously resolved) are
+// ignored; the returned bool reports whether a new entry was created.
+func (s *SQLiteBookStore) AddPendingMatch(ctx context.Context, match *model.PendingMatch) (bool, error) {
+	if match.CreatedAt.IsZero() {
+		match.CreatedAt = time.Now().UTC()
+	}
+	match.Status = model.MatchPending
+
+	item, err := json.Marshal(match.Item)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode pending item: %w", err)
+	}
+	candidates, err := json.Marshal(match.Candidates)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode candidates: %w", err)
+	}
+
+	slog.InfoContext(ctx, "SQL: Executing AddPendingMatch query", "source", match.Source, "sourceRef", match.SourceRef, "title", match.Item.Title)
+	err = s.DB.QueryRowContext(ctx, `INSERT INTO pending_matches (user_id, source, source_ref, item_key, item, candidates, status, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING RETURNING id;`,
+		owner(ctx), match.Source, match.SourceRef, match.ItemKey, string(item), string(candidates), match.Status, match.CreatedAt).Scan(&match.ID)
+	if err == sql.ErrNoRows {
+		return false, nil // Already queued
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddPendingMatch statement failed", "error", err)
+		return false, fmt.Errorf("failed to add pending match: %w", classify(err))
+	}
+	return true, nil
+}
+
+const pendingMatchColumns = `id, source, source_ref, item_key, item, candidates, status, book_id, created_at, resolved_at`
+
+func scanPendingMatch(row rowScanner) (*model.PendingMatch, error) {
+	var m model.PendingMatch
+	var item, candidates string
+	var bookID sql.NullInt64
+	var resolvedAt sql.NullTime
+	if err := row.Scan(&m.ID, &m.Source, &m.SourceRef, &m.ItemKey, &item, &candidates, &m.Status, &bookID,
+		&m.CreatedAt, &resolvedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(item), &m.Item); err != nil {
+		return nil, fmt.Errorf("failed to decode pending item: %w", err)
+	}
+	if err := json.Unmarshal([]byte(candidates), &m.Candidates); err != nil {
+		return nil, fmt.Errorf("failed to decode candidates: %w", err)
+	}
+	if bookID.Valid {
+		m.BookID = &bookID.Int64
+	}
+	if resolvedAt.Valid {
+		m.ResolvedAt = &resolvedAt.Time
+	}
+	return &m, nil
+}
+
+// GetPendingMatches returns the review queue entries of the user of ctx with the
+// given status, oldest first. An empty status returns all their entries.
+func (s *SQLiteBookStore) GetPendingMatches(ctx context.Context, status model.PendingMatchStatus) ([]model.PendingMatch, error) {
+	owned, args := ownedBy(ctx, "user_id")
+	query := `SELECT ` + pendingMatchColumns + ` FROM pending_matches WHERE ` + owned
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at, id;`
+	slog.InfoContext(ctx, "SQL: Executing GetPendingMatches query", "status", status)
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetPendingMatches query failed", "error", err)
+		return nil, fmt.Errorf("failed to query pending matches: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []model.PendingMatch{}
+	for rows.Next() {
+		m, err := scanPendingMatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pending match row: %w", err)
+		}
+		matches = append(matches, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pending match rows: %w", err)
+	}
+	return matches, nil
+}
+
+// GetPendingMatchByID returns a single review queue entry of the user of ctx.
+func (s *SQLiteBookStore) GetPendingMatchByID(ctx context.Context, id int64) (*model.PendingMatch, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetPendingMatchByID query", "id", id)
+	owned, args := ownedBy(ctx, "user_id")
+	m, err := scanPendingMatch(s.DB.QueryRowContext(ctx, `SELECT `+pendingMatchColumns+` FROM pending_matches WHERE id = ? AND `+owned+`;`,
+		append([]interface{}{id}, args...)...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("pending match with ID %d %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to scan pending match row for ID %d: %w", id, err)
+	}
+	return m, nil
+}
+
+// ResolvePendingMatch records the reviewer's decision on a pending entry of the
+// user of ctx.
+func (s *SQLiteBookStore) ResolvePendingMatch(ctx context.Context, id int64, status model.PendingMatchStatus, bookID *int64) error {
+	if !status.IsValid() || status == model.MatchPending {
+		return invalidf("invalid resolution status: %s", status)
+	}
+	slog.InfoContext(ctx, "SQL: Executing ResolvePendingMatch query", "id", id, "status", status)
+	owned, args := ownedBy(ctx, "user_id")
+	res, err := s.DB.ExecContext(ctx, `UPDATE pending_matches SET status = ?, book_id = ?, resolved_at = ? WHERE id = ? AND status = ? AND `+owned+`;`,
+		append([]interface{}{status, bookID, time.Now().UTC(), id, model.MatchPending}, args...)...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing ResolvePendingMatch statement failed", "error", err)
+		return fmt.Errorf("failed to resolve pending match: %w", classify(err))
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("unresolved pending match with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestPendingMatches tests queueing and resolving review entries
+func TestPendingMatches(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	pending := &model.PendingMatch{
+		Source:     model.SourceListImport,
+		SourceRef:  "Beach reads",
+		ItemKey:    "OL1W",
+		Item:       model.PendingItem{Title: "Emma", OpenLibraryID: "OL1W", Status: model.StatusWantToRead},
+		Candidates: []model.PendingCandidate{{BookID: 7, Title: "Emma", Author: "Jane Austen", Score: 0.85}},
+	}
+	queued, err := store.AddPendingMatch(ctx, pending)
+	if err != nil || !queued {
+		t.Fatalf("AddPendingMatch failed: queued=%v err=%v", queued, err)
+	}
+
+	again := *pending
+	if queued, err := store.AddPendingMatch(ctx, &again); err != nil || queued {
+		t.Errorf("Expected the same item not to be queued twice: queued=%v err=%v", queued, err)
+	}
+
+	matches, err := store.GetPendingMatches(ctx, model.MatchPending)
+	if err != nil {
+		t.Fatalf("GetPendingMatches failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Item.Title != "Emma" || len(matches[0].Candidates) != 1 || matches[0].Candidates[0].BookID != 7 {
+		t.Fatalf("Unexpected pending matches: %+v", matches)
+	}
+
+	bookID := int64(7)
+	if err := store.ResolvePendingMatch(ctx, pending.ID, model.MatchLinked, &bookID); err != nil {
+		t.Fatalf("ResolvePendingMatch failed: %v", err)
+	}
+	if err := store.ResolvePendingMatch(ctx, pending.ID, model.MatchSkipped, nil); err == nil {
+		t.Error("Expected error when resolving twice")
+	}
+	if err := store.ResolvePendingMatch(ctx, pending.ID, model.MatchPending, nil); err == nil {
+		t.Error("Expected error for pending as a resolution")
+	}
+
+	resolved, err := store.GetPendingMatchByID(ctx, pending.ID)
+	if err != nil {
+		t.Fatalf("GetPendingMatchByID failed: %v", err)
+	}
+	if resolved.Status != model.MatchLinked || resolved.BookID == nil || *resolved.BookID != 7 || resolved.ResolvedAt == nil {
+		t.Errorf("Unexpected resolved match: %+v", resolved)
+	}
+	if matches, _ := store.GetPendingMatches(ctx, model.MatchPending); len(matches) != 0 {
+		t.Errorf("Expected no pending matches, got %d", len(matches))
+	}
+}
@@ -0,0 +1,246 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/lib/pq" // PostgreSQL driver
+)
+
+// InitPostgresDB connects to the PostgreSQL database at dsn (a postgres:// URL
+// or key=value connection string) and creates the necessary tables if they
+// don't exist. Queries on the returned handle use SQLite's ? placeholders,
+// which are rewritten to PostgreSQL's $1, $2, ... before they are sent.
+func InitPostgresDB(dsn string) (*sql.DB, error) {
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	slog.Info("Initializing PostgreSQL database connection")
+	db := sql.OpenDB(timedConnector{rebindConnector{connector}})
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	slog.Info("Database connection successful")
+
+	if err = CreatePostgresSchema(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create database schema: %w", err)
+	}
+	slog.Info("Database schema verified/created")
+	return db, nil
+}
+
+// CreatePostgresSchema brings the PostgreSQL schema up to date by applying
+// the migrations it has not had yet. They match the SQLite ones, except that
+// names SQLite compares ignoring case use the citext extension.
+func CreatePostgresSchema(db *sql.DB) error {
+	slog.Info("Executing PostgreSQL schema migrations")
+	if err := migrate(db, backendMigrations("postgres")); err != nil {
+		return err
+	}
+	slog.Info("Schema execution successful")
+	return nil
+}
+
+// PostgresBookStore implements the BookStore interface using PostgreSQL. It
+// runs the same queries as SQLiteBookStore, which keep to the SQL both
+// databases accept, except for full-text search.
+type PostgresBookStore struct {
+	*SQLiteBookStore
+}
+
+// NewPostgresBookStore creates a new PostgresBookStore on a database opened
+// with InitPostgresDB.
+func NewPostgresBookStore(db *sql.DB) *PostgresBookStore {
+	return &PostgresBookStore{&SQLiteBookStore{DB: db, dialect: postgresDialect}}
+}
+
+// postgresSearchDocument is the text search vector of a book: its full title,
+// author and comments. The GIN index created by the first migration is built
+// on this exact expression, so queries must repeat it for the index to be used.
+const postgresSearchDocument = `to_tsvector('simple', title || COALESCE(': ' || subtitle, '') || ' ' || author || ' ' || COALESCE(comments, ''))`
+
+// SearchBooks finds books whose title, author or comments contain every word
+// of query, like SQLiteBookStore.SearchBooks, ranked with ts_rank. Unlike
+// SQLite's index, PostgreSQL's 'simple' configuration keeps diacritics, so
+// "Bronte" does not find "Brontë".
+func (s *PostgresBookStore) SearchBooks(ctx context.Context, query string) ([]model.Book, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []model.Book{}, nil
+	}
+	slog.InfoContext(ctx, "SQL: Executing SearchBooks query", logging.Redact("query", query))
+
+	vocabulary, err := s.searchVocabulary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	expression := searchExpression(terms, vocabulary, func(term string, prefix bool) string {
+		if prefix {
+			return term + ":*"
+		}
+		return term
+	}, " | ", " & ")
+
+	owned, args := shelved(ctx, "")
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+bookColumns+` FROM books, to_tsquery('simple', ?) search_query
+        WHERE `+postgresSearchDocument+` @@ search_query AND `+owned+`
+        ORDER BY ts_rank(`+postgresSearchDocument+`, search_query) DESC, title, subtitle, id LIMIT ?;`,
+		append(append([]interface{}{expression}, args...), searchResultLimit)...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing SearchBooks query failed", "error", err)
+		return nil, fmt.Errorf("failed to search books: %w", err)
+	}
+	defer rows.Close()
+
+	books := []model.Book{}
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan book row: %w", err)
+		}
+		books = append(books, *book)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating book rows: %w", err)
+	}
+	slog.InfoContext(ctx, "SQL: Search complete", logging.Redact("query", query), "matches", len(books))
+	return books, nil
+}
+
+// searchVocabulary returns every word in the books' search vectors.
+func (s *PostgresBookStore) searchVocabulary(ctx context.Context) (map[string]bool, error) {
+	documents := `SELECT ` + postgresSearchDocument + ` FROM books`
+	rows, err := s.DB.QueryContext(ctx, `SELECT word FROM ts_stat('`+strings.ReplaceAll(documents, `'`, `''`)+`');`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read search vocabulary: %w", err)
+	}
+	defer rows.Close()
+	vocabulary := make(map[string]bool)
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			return nil, fmt.Errorf("failed to scan search term: %w", err)
+		}
+		vocabulary[term] = true
+	}
+	return vocabulary, rows.Err()
+}
+
+// rebind rewrites the ? placeholders in query as $1, $2, ... Question marks in
+// string literals, quoted identifiers and comments are left alone.
+func rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			// A doubled quote inside a literal reads as two adjacent literals
+			b.WriteString(query[i : i+end+2])
+			i += end + 1
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// rebindConnector opens connections that rebind every query before passing
+// it to the PostgreSQL driver.
+type rebindConnector struct {
+	driver.Connector
+}
+
+func (c rebindConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &rebindConn{conn}, nil
+}
+
+// rebindConn is a driver connection that rebinds queries. It forwards the
+// optional driver interfaces the PostgreSQL driver implements.
+type rebindConn struct {
+	driver.Conn
+}
+
+func (c *rebindConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(rebind(query))
+}
+
+func (c *rebindConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, rebind(query))
+	}
+	return c.Prepare(query)
+}
+
+func (c *rebindConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := c.Conn.(driver.ExecerContext); ok {
+		return e.ExecContext(ctx, rebind(query), args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *rebindConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		return q.QueryContext(ctx, rebind(query), args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *rebindConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *rebindConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *rebindConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *rebindConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT * FROM books WHERE id = ?;", "SELECT * FROM books WHERE id = $1;"},
+		{"INSERT INTO tags (name, created_at) VALUES (?, ?)", "INSERT INTO tags (name, created_at) VALUES ($1, $2)"},
+		{`author ILIKE ? ESCAPE '\' AND rating >= ?`, `author ILIKE $1 ESCAPE '\' AND rating >= $2`},
+		{"SELECT 'what?', \"col?\" FROM t WHERE a = ?", "SELECT 'what?', \"col?\" FROM t WHERE a = $1"},
+		{"SELECT 'it''s?' WHERE a = ?", "SELECT 'it''s?' WHERE a = $1"},
+		{"SELECT a -- why?\nFROM t WHERE b = ?", "SELECT a -- why?\nFROM t WHERE b = $1"},
+		{"SELECT 'unterminated ?", "SELECT 'unterminated ?"},
+	}
+	for _, tt := range tests {
+		if got := rebind(tt.query); got != tt.want {
+			t.Errorf("rebind(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+// TestPostgresBookStore runs against the PostgreSQL database named by
+// BOOKSHELF_TEST_POSTGRES_DSN and is skipped when it is unset. It drops and
+// recreates every table, so the database should be a scratch one.
+func TestPostgresBookStore(t *testing.T) {
+	dsn := os.Getenv("BOOKSHELF_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("BOOKSHELF_TEST_POSTGRES_DSN is not set")
+	}
+	ctx := context.Background()
+	database, err := InitPostgresDB(dsn)
+	if err != nil {
+		t.Fatalf("InitPostgresDB failed: %v", err)
+	}
+	defer database.Close()
+	if _, err := database.Exec(`DROP TABLE IF EXISTS books, cover_images, book_tombstones, export_runs, follows, activities, settings,
+        crosspost_accounts, sync_accounts, sync_links, sync_log, pending_matches, tags, book_tags, reads, authors, fediverse_followers, vacations, users, sessions, api_keys, import_batches, book_merges, book_events, shelf_presets, reading_goals, bulk_deletions, reading_progress, book_notes, disposals, quotes, loans, market_values, collections, collection_books, book_authors, wishlist_members, gift_claims, webhooks, webhook_deliveries, share_links, passkeys, passkey_challenges, activitypub_keys, schema_migrations CASCADE;`); err != nil {
+		t.Fatalf("Failed to drop tables: %v", err)
+	}
+	if err := CreatePostgresSchema(database); err != nil {
+		t.Fatalf("CreatePostgresSchema failed: %v", err)
+	}
+	store := NewPostgresBookStore(database)
+
+	book := createTestBook()
+	id, err := store.AddBook(ctx, book)
+	if err != nil || id == 0 {
+		t.Fatalf("AddBook failed: %d, %v", id, err)
+	}
+	if _, err := store.AddBook(ctx, createTestBook()); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected a duplicate error, got %v", err)
+	}
+	if err := store.UpdateBookStatus(ctx, id, model.StatusRead); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	if _, err := store.AddBookTag(ctx, id, "Classics"); err != nil {
+		t.Fatalf("AddBookTag failed: %v", err)
+	}
+
+	books, total, err := store.GetBooksPage(ctx, ListOptions{Filter: BookFilter{Author: "TEST", Tag: "classics"}})
+	if err != nil || total != 1 || len(books) != 1 || books[0].ID != id {
+		t.Errorf("Expected the book from a case-insensitive filter, got %d %+v, %v", total, books, err)
+	}
+	reads, err := store.GetReads(ctx, id)
+	if err != nil || len(reads) != 1 {
+		t.Errorf("Expected the read logged on finishing, got %+v, %v", reads, err)
+	}
+
+	for _, query := range []string{"test", "tes", "tesst book"} {
+		found, err := store.SearchBooks(ctx, query)
+		if err != nil || len(found) != 1 || found[0].ID != id {
+			t.Errorf("SearchBooks(%q): got %+v, %v", query, found, err)
+		}
+	}
+
+	if err := store.DeleteBook(ctx, id); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if _, err := store.GetBookByID(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the book to be gone, got %v", err)
+	}
+}
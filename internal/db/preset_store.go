@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// PresetStore defines the database operations for shelf presets, the named
+// views of the book list.
+type PresetStore interface {
+	GetShelfPresets(ctx context.Context) ([]model.ShelfPreset, error)
+	GetShelfPreset(ctx context.Context, id int64) (*model.ShelfPreset, error)
+	AddShelfPreset(ctx context.Context, preset *model.ShelfPreset) error
+	UpdateShelfPreset(ctx context.Context, preset *model.ShelfPreset) error
+	DeleteShelfPreset(ctx context.Context, id int64) error
+}
+
+const presetColumns = `id, name, filters, sort, sort_order, fields, created_at, updated_at`
+
+func scanShelfPreset(row rowScanner) (*model.ShelfPreset, error) {
+	var p model.ShelfPreset
+	var filters, fields string
+	if err := row.Scan(&p.ID, &p.Name, &filters, &p.Sort, &p.Order, &fields, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(filters), &p.Filters); err != nil {
+		return nil, fmt.Errorf("failed to decode preset filters: %w", err)
+	}
+	if err := json.Unmarshal([]byte(fields), &p.Fields); err != nil {
+		return nil, fmt.Errorf("failed to decode preset fields: %w", err)
+	}
+	return &p, nil
+}
+
+// encodeShelfPreset validates a preset and returns its filters and fields as
+// stored.
+func encodeShelfPreset(preset *model.ShelfPreset) (filters, fields string, err error) {
+	if err := preset.Validate(); err != nil {
+		return "", "", &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
+	}
+	f, err := json.Marshal(preset.Filters)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode preset filters: %w", err)
+	}
+	v, err := json.Marshal(preset.Fields)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode preset fields: %w", err)
+	}
+	return string(f), string(v), nil
+}
+
+// presetError explains a failure to save a preset, naming the preset a
+// duplicate name clashes with.
+func presetError(err error, preset *model.ShelfPreset, action string) error {
+	err = classify(err)
+	if errors.Is(err, ErrDuplicate) {
+		return &storeError{kind: ErrDuplicate, msg: fmt.Sprintf("a preset named %q already exists", preset.Name), cause: err}
+	}
+	return fmt.Errorf("failed to %s shelf preset: %w", action, err)
+}
+
+// GetShelfPresets returns every shelf preset, by name.
+func (s *SQLiteBookStore) GetShelfPresets(ctx context.Context) ([]model.ShelfPreset, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetShelfPresets query")
+	owned, args := ownedBy(ctx, "user_id")
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+presetColumns+` FROM shelf_presets WHERE `+owned+` ORDER BY name, id;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetShelfPresets query failed", "error", err)
+		return nil, fmt.Errorf("failed to query shelf presets: %w", err)
+	}
+	defer rows.Close()
+
+	presets := []model.ShelfPreset{}
+	for rows.Next() {
+		preset, err := scanShelfPreset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan shelf preset row: %w", err)
+		}
+		presets = append(presets, *preset)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shelf preset rows: %w", err)
+	}
+	return presets, nil
+}
+
+// GetShelfPreset returns one shelf preset.
+func (s *SQLiteBookStore) GetShelfPreset(ctx context.Context, id int64) (*model.ShelfPreset, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetShelfPreset query", "id", id)
+	owned, args := ownedBy(ctx, "user_id")
+	preset, err := scanShelfPreset(s.DB.QueryRowContext(ctx, `SELECT `+presetColumns+` FROM shelf_presets WHERE id = ? AND `+owned+`;`,
+		append([]interface{}{id}, args...)...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("shelf preset with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shelf preset: %w", err)
+	}
+	return preset, nil
+}
+
+// AddShelfPreset stores a shelf preset and sets its ID and times.
+func (s *SQLiteBookStore) AddShelfPreset(ctx context.Context, preset *model.ShelfPreset) error {
+	filters, fields, err := encodeShelfPreset(preset)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing AddShelfPreset query", "name", preset.Name)
+	preset.CreatedAt = time.Now().UTC()
+	preset.UpdatedAt = preset.CreatedAt
+	if err := s.DB.QueryRowContext(ctx, `INSERT INTO shelf_presets (user_id, name, filters, sort, sort_order, fields, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;`,
+		owner(ctx), preset.Name, filters, preset.Sort, preset.Order, fields,
+		preset.CreatedAt, preset.UpdatedAt).Scan(&preset.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddShelfPreset statement failed", "error", err)
+		return presetError(err, preset, "add")
+	}
+	return nil
+}
+
+// UpdateShelfPreset replaces a shelf preset's name, filters, sort order and
+// fields, and sets its creation and update times.
+func (s *SQLiteBookStore) UpdateShelfPreset(ctx context.Context, preset *model.ShelfPreset) error {
+	filters, fields, err := encodeShelfPreset(preset)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing UpdateShelfPreset query", "id", preset.ID, "name", preset.Name)
+	owned, args := ownedBy(ctx, "user_id")
+	preset.UpdatedAt = time.Now().UTC()
+	err = s.DB.QueryRowContext(ctx, `UPDATE shelf_presets SET name = ?, filters = ?, sort = ?, sort_order = ?, fields = ?, updated_at = ?
+        WHERE id = ? AND `+owned+` RETURNING created_at;`,
+		append([]interface{}{preset.Name, filters, preset.Sort, preset.Order, fields,
+			preset.UpdatedAt, preset.ID}, args...)...).Scan(&preset.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("shelf preset with ID %d %w", preset.ID, ErrNotFound)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing UpdateShelfPreset statement failed", "error", err)
+		return presetError(err, preset, "update")
+	}
+	return nil
+}
+
+// DeleteShelfPreset removes a shelf preset.
+func (s *SQLiteBookStore) DeleteShelfPreset(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "SQL: Executing DeleteShelfPreset query", "id", id)
+	owned, args := ownedBy(ctx, "user_id")
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM shelf_presets WHERE id = ? AND `+owned+`;`, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete shelf preset: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("shelf preset with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestShelfPresets(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	unrated := model.ShelfPreset{Name: "  Unrated reads ", Filters: map[string]string{"status": "read", "missing": "rating"},
+		Sort: "added", Order: "DESC", Fields: []string{"title", "author"}}
+	if err := store.AddShelfPreset(ctx, &unrated); err != nil || unrated.ID == 0 || unrated.Name != "Unrated reads" || unrated.Order != "desc" {
+		t.Fatalf("AddShelfPreset failed: %+v, %v", unrated, err)
+	}
+	all := model.ShelfPreset{Name: "All"}
+	if err := store.AddShelfPreset(ctx, &all); err != nil {
+		t.Fatalf("AddShelfPreset failed: %v", err)
+	}
+	if err := store.AddShelfPreset(ctx, &model.ShelfPreset{Name: "All"}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected a duplicate name to be rejected, got %v", err)
+	}
+	for _, bad := range []model.ShelfPreset{{Name: " "}, {Name: "Sorted", Order: "sideways"}} {
+		if err := store.AddShelfPreset(ctx, &bad); !errors.Is(err, ErrValidation) {
+			t.Errorf("AddShelfPreset(%+v): expected a validation error, got %v", bad, err)
+		}
+	}
+
+	presets, err := store.GetShelfPresets(ctx)
+	if err != nil || len(presets) != 2 || presets[0].Name != "All" || len(presets[0].Filters) != 0 || presets[0].Fields == nil {
+		t.Fatalf("Expected both presets by name, got %+v, %v", presets, err)
+	}
+	got, err := store.GetShelfPreset(ctx, unrated.ID)
+	if err != nil || got.Filters["missing"] != "rating" || got.Sort != "added" || len(got.Fields) != 2 {
+		t.Errorf("GetShelfPreset: got %+v, %v", got, err)
+	}
+
+	unrated.Name, unrated.Fields = "Rate these", nil
+	if err := store.UpdateShelfPreset(ctx, &unrated); err != nil || !unrated.CreatedAt.Equal(got.CreatedAt) {
+		t.Fatalf("UpdateShelfPreset failed: %+v, %v", unrated, err)
+	}
+	if got, _ := store.GetShelfPreset(ctx, unrated.ID); got.Name != "Rate these" || len(got.Fields) != 0 {
+		t.Errorf("Expected the update to be saved, got %+v", got)
+	}
+	unrated.Name = "All"
+	if err := store.UpdateShelfPreset(ctx, &unrated); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected renaming to a taken name to be rejected, got %v", err)
+	}
+
+	// Presets are per user
+	alice := model.User{Username: "alice", PasswordHash: "hash"}
+	if err := store.AddUser(ctx, &alice); err != nil {
+		t.Fatalf("AddUser failed: %v", err)
+	}
+	bob := model.User{Username: "bob", PasswordHash: "hash"}
+	if err := store.AddUser(ctx, &bob); err != nil {
+		t.Fatalf("AddUser failed: %v", err)
+	}
+	if err := store.AddShelfPreset(WithUser(ctx, bob.ID), &model.ShelfPreset{Name: "All"}); err != nil {
+		t.Errorf("Expected another user to reuse a name, got %v", err)
+	}
+	if presets, _ := store.GetShelfPresets(WithUser(ctx, alice.ID)); len(presets) != 2 {
+		t.Errorf("Expected the first user to adopt the presets, got %+v", presets)
+	}
+	if _, err := store.GetShelfPreset(WithUser(ctx, bob.ID), all.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected another user's preset to be hidden, got %v", err)
+	}
+
+	if err := store.DeleteShelfPreset(ctx, all.ID); err != nil {
+		t.Fatalf("DeleteShelfPreset failed: %v", err)
+	}
+	if err := store.DeleteShelfPreset(ctx, all.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found deleting twice, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// ProgressStore defines the database operations for the reading progress of
+// books being read.
+type ProgressStore interface {
+	SetReadingProgress(ctx context.Context, bookID int64, update model.ProgressUpdate) error
+	GetReadingProgress(ctx context.Context, bookID int64) (*model.ReadingProgress, error)
+}
+
+// SetReadingProgress records how far through a Currently Reading book the
+// reader is, as the book's current page and percentage and as an entry in
+// its progress history.
+func (s *SQLiteBookStore) SetReadingProgress(ctx context.Context, bookID int64, update model.ProgressUpdate) error {
+	slog.InfoContext(ctx, "SQL: Executing SetReadingProgress", "bookID", bookID)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	book, err := s.getBookTx(ctx, tx, bookID)
+	if err != nil {
+		return err
+	}
+	if book.Status != model.StatusCurrentlyReading {
+		return invalidf("reading progress is only tracked for books being read")
+	}
+	page, percent, err := update.Resolve(book.PageCount)
+	if err != nil {
+		return &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
+	}
+
+	now := time.Now().UTC()
+	if _, err := tx.ExecContext(ctx, `UPDATE books SET current_page = ?, progress_percent = ?, updated_at = ? WHERE id = ?;`,
+		page, percent, now, bookID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing SetReadingProgress statement failed", "error", err)
+		return fmt.Errorf("failed to update reading progress: %w", classify(err))
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO reading_progress (book_id, page, percent, recorded_at) VALUES (?, ?, ?, ?);`,
+		bookID, page, percent, now); err != nil {
+		return fmt.Errorf("failed to record reading progress: %w", classify(err))
+	}
+	if err := commit(ctx, tx); err != nil {
+		return fmt.Errorf("failed to commit reading progress: %w", err)
+	}
+	return nil
+}
+
+// GetReadingProgress returns the progress history of the current or latest
+// read of a book, oldest first, with the pace it shows.
+func (s *SQLiteBookStore) GetReadingProgress(ctx context.Context, bookID int64) (*model.ReadingProgress, error) {
+	book, err := s.GetBookByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "SQL: Executing GetReadingProgress query", "bookID", bookID)
+	var since time.Time
+	if book.DateStarted != nil {
+		since = book.DateStarted.UTC()
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, page, percent, recorded_at FROM reading_progress
+        WHERE book_id = ? AND recorded_at >= ? ORDER BY recorded_at, id;`, bookID, since)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetReadingProgress query failed", "error", err)
+		return nil, fmt.Errorf("failed to query reading progress: %w", err)
+	}
+	defer rows.Close()
+
+	progress := &model.ReadingProgress{BookID: bookID, Entries: []model.ProgressEntry{}}
+	for rows.Next() {
+		var entry model.ProgressEntry
+		var page sql.NullInt64
+		var percent sql.NullFloat64
+		if err := rows.Scan(&entry.ID, &page, &percent, &entry.RecordedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reading progress row: %w", err)
+		}
+		if page.Valid {
+			p := int(page.Int64)
+			entry.Page = &p
+		}
+		if percent.Valid {
+			entry.Percent = &percent.Float64
+		}
+		progress.Entries = append(progress.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reading progress rows: %w", err)
+	}
+	progress.MeasurePace()
+	return progress, nil
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestReadingProgress(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	pages := 300
+	book.PageCount = &pages
+	if _, err := store.AddBook(ctx, book); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	page := func(n int) model.ProgressUpdate { return model.ProgressUpdate{Page: &n} }
+
+	if err := store.SetReadingProgress(ctx, book.ID, page(10)); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected progress to be refused for a book not being read, got %v", err)
+	}
+	if err := store.UpdateBookStatus(ctx, book.ID, model.StatusCurrentlyReading); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	percent := 40.0
+	for _, update := range []model.ProgressUpdate{page(-1), page(301), {Page: &pages, Percent: &percent}, {}} {
+		if err := store.SetReadingProgress(ctx, book.ID, update); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected %+v to be refused, got %v", update, err)
+		}
+	}
+
+	if err := store.SetReadingProgress(ctx, book.ID, page(150)); err != nil {
+		t.Fatalf("SetReadingProgress failed: %v", err)
+	}
+	got, _ := store.GetBookByID(ctx, book.ID)
+	if got.CurrentPage == nil || *got.CurrentPage != 150 || got.ProgressPercent == nil || *got.ProgressPercent != 50 {
+		t.Errorf("Expected page 150 at 50%%, got %v, %v", got.CurrentPage, got.ProgressPercent)
+	}
+
+	// Two days spent reading the next 100 pages
+	if _, err := db.Exec(`UPDATE reading_progress SET recorded_at = ? WHERE book_id = ?;`, time.Now().UTC().Add(-48*time.Hour), book.ID); err != nil {
+		t.Fatalf("Backdating progress failed: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE books SET date_started = ? WHERE id = ?;`, time.Now().UTC().Add(-72*time.Hour), book.ID); err != nil {
+		t.Fatalf("Backdating start failed: %v", err)
+	}
+	percent = 250.0 / 3
+	if err := store.SetReadingProgress(ctx, book.ID, model.ProgressUpdate{Percent: &percent}); err != nil {
+		t.Fatalf("SetReadingProgress failed: %v", err)
+	}
+	progress, err := store.GetReadingProgress(ctx, book.ID)
+	if err != nil || len(progress.Entries) != 2 {
+		t.Fatalf("Expected 2 progress entries, got %+v, %v", progress, err)
+	}
+	last := progress.Entries[1]
+	if *last.Page != 250 || *last.Percent != 83.3 || last.PagesPerDay == nil || *last.PagesPerDay != 50 {
+		t.Errorf("Expected page 250 at 83.3%% and 50 pages a day, got %+v", last)
+	}
+	if progress.PagesPerDay == nil || *progress.PagesPerDay != 50 {
+		t.Errorf("Expected a pace of 50 pages a day, got %v", progress.PagesPerDay)
+	}
+
+	// Progress belongs to the read, so finishing the book clears it
+	if err := store.UpdateBookStatus(ctx, book.ID, model.StatusRead); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	got, _ = store.GetBookByID(ctx, book.ID)
+	if got.CurrentPage != nil || got.ProgressPercent != nil {
+		t.Errorf("Expected progress cleared once read, got %v, %v", got.CurrentPage, got.ProgressPercent)
+	}
+	if err := store.DeleteBook(ctx, book.ID); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if err := store.PurgeBook(ctx, book.ID); err != nil {
+		t.Fatalf("PurgeBook failed: %v", err)
+	}
+	var left int
+	db.QueryRow(`SELECT COUNT(*) FROM reading_progress;`).Scan(&left)
+	if left != 0 {
+		t.Errorf("Expected the progress history purged with the book, got %d entries", left)
+	}
+}
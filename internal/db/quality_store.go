@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// QualityStore defines the database operations for the data quality report.
+type QualityStore interface {
+	GetDataQuality(ctx context.Context) (*model.DataQuality, error)
+}
+
+// GetDataQuality counts the books missing an ISBN, a cover or a page count,
+// and the read books without a rating, using the conditions of
+// BookFilter.Missing. It also finds the series with missing positions; a
+// series counts from 1, so a prequel numbered 0 is never missing.
+func (s *SQLiteBookStore) GetDataQuality(ctx context.Context) (*model.DataQuality, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetDataQuality query")
+	owned, args := shelved(ctx, "")
+	count := func(cond string) string { return "COALESCE(SUM(CASE WHEN " + cond + " THEN 1 ELSE 0 END), 0)" }
+	report := &model.DataQuality{SeriesGaps: []model.SeriesGap{}}
+	if err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*), `+count(MissingFields["isbn"])+`, `+count(MissingFields["cover"])+`,
+            `+count(MissingFields["page_count"])+`, `+count("status = ? AND "+MissingFields["rating"])+`
+        FROM books WHERE `+owned+`;`, append([]interface{}{model.StatusRead}, args...)...).Scan(
+		&report.Books, &report.MissingISBN, &report.MissingCover, &report.MissingPageCount, &report.UnratedRead); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetDataQuality query failed", "error", err)
+		return nil, fmt.Errorf("failed to count incomplete books: %w", err)
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT series, series_index FROM books
+        WHERE series IS NOT NULL AND series != '' AND series_index IS NOT NULL AND `+owned+`
+        ORDER BY LOWER(series), series, series_index;`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query series: %w", err)
+	}
+	defer rows.Close()
+	type seriesPositions struct {
+		name      string
+		books     int
+		positions map[int]bool
+		highest   int
+	}
+	var all []*seriesPositions
+	for rows.Next() {
+		var name string
+		var index int
+		if err := rows.Scan(&name, &index); err != nil {
+			return nil, fmt.Errorf("failed to scan series row: %w", err)
+		}
+		// Names differing only in case are the same series, as for BookFilter.Series
+		if n := len(all); n == 0 || !strings.EqualFold(all[n-1].name, name) {
+			all = append(all, &seriesPositions{name: name, positions: map[int]bool{}})
+		}
+		last := all[len(all)-1]
+		last.books++
+		last.positions[index] = true
+		last.highest = max(last.highest, index)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating series rows: %w", err)
+	}
+	for _, series := range all {
+		gap := model.SeriesGap{Series: series.name, Books: series.books}
+		for i := 1; i < series.highest; i++ {
+			if !series.positions[i] {
+				gap.Missing = append(gap.Missing, i)
+			}
+		}
+		if len(gap.Missing) > 0 {
+			report.SeriesGaps = append(report.SeriesGaps, gap)
+		}
+	}
+	return report, nil
+}
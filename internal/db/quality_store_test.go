@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestGetDataQuality(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	series := func(name string, index int) (*string, *int) { return &name, &index }
+	complete := createTestBook()
+	complete.Status = model.StatusRead
+	pages := 300
+	complete.PageCount = &pages
+	complete.Series, complete.SeriesIndex = series("Dune", 1)
+	bare := createTestBook()
+	bare.OpenLibraryID, bare.ISBN, bare.CoverURL, bare.Rating, bare.Status = "OL2M", "", nil, nil, model.StatusRead
+	bare.Series, bare.SeriesIndex = series("dune", 4)
+	unread := createTestBook()
+	unread.OpenLibraryID, unread.Rating = "OL3M", nil
+	unread.Series, unread.SeriesIndex = series("Foundation", 1)
+	for _, book := range []*model.Book{complete, bare, unread} {
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+	}
+
+	report, err := store.GetDataQuality(ctx)
+	if err != nil {
+		t.Fatalf("GetDataQuality failed: %v", err)
+	}
+	want := &model.DataQuality{Books: 3, MissingISBN: 1, MissingCover: 1, MissingPageCount: 2, UnratedRead: 1,
+		SeriesGaps: []model.SeriesGap{{Series: "Dune", Books: 2, Missing: []int{2, 3}}}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("GetDataQuality() = %+v, want %+v", report, want)
+	}
+
+	// The drill-down filters find the books counted
+	for _, tt := range []struct {
+		filter BookFilter
+		want   int
+	}{
+		{BookFilter{Missing: "isbn"}, report.MissingISBN},
+		{BookFilter{Missing: "cover"}, report.MissingCover},
+		{BookFilter{Missing: "page_count"}, report.MissingPageCount},
+		{BookFilter{Status: model.StatusRead, Missing: "rating"}, report.UnratedRead},
+		{BookFilter{Series: "DUNE"}, 2},
+	} {
+		if _, total, err := store.GetBooksPage(ctx, ListOptions{Filter: tt.filter}); err != nil || total != tt.want {
+			t.Errorf("GetBooksPage(%+v) found %d books, want %d (%v)", tt.filter, total, tt.want, err)
+		}
+	}
+}
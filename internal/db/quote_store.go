@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// QuoteStore defines the database operations for the favorite passages
+// collected from books.
+type QuoteStore interface {
+	GetQuotes(ctx context.Context, bookID int64) ([]model.Quote, error)
+	AddQuote(ctx context.Context, quote *model.Quote) error
+	UpdateQuote(ctx context.Context, quote *model.Quote) error
+	DeleteQuote(ctx context.Context, bookID, quoteID int64) error
+	GetRandomQuote(ctx context.Context) (*model.RandomQuote, error)
+}
+
+const quoteColumns = `quotes.id, quotes.book_id, quotes.text, quotes.page, quotes.added_at`
+
+func scanQuote(row rowScanner, extra ...interface{}) (*model.Quote, error) {
+	var quote model.Quote
+	var page sql.NullInt64
+	if err := row.Scan(append([]interface{}{&quote.ID, &quote.BookID, &quote.Text, &page, &quote.AddedAt}, extra...)...); err != nil {
+		return nil, err
+	}
+	if page.Valid {
+		p := int(page.Int64)
+		quote.Page = &p
+	}
+	return &quote, nil
+}
+
+// GetQuotes returns the quotes of a book, in the order they were added.
+func (s *SQLiteBookStore) GetQuotes(ctx context.Context, bookID int64) ([]model.Quote, error) {
+	if _, err := s.GetBookByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "SQL: Executing GetQuotes query", "bookID", bookID)
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+quoteColumns+` FROM quotes WHERE book_id = ? ORDER BY added_at, id;`, bookID)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetQuotes query failed", "error", err)
+		return nil, fmt.Errorf("failed to query quotes: %w", err)
+	}
+	defer rows.Close()
+
+	quotes := []model.Quote{}
+	for rows.Next() {
+		quote, err := scanQuote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan quote row: %w", err)
+		}
+		quotes = append(quotes, *quote)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating quote rows: %w", err)
+	}
+	return quotes, nil
+}
+
+// AddQuote adds a quote to a book and sets its ID and when it was added.
+func (s *SQLiteBookStore) AddQuote(ctx context.Context, quote *model.Quote) error {
+	if err := quote.Validate(); err != nil {
+		return &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
+	}
+	if _, err := s.GetBookByID(ctx, quote.BookID); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing AddQuote query", "bookID", quote.BookID)
+	quote.AddedAt = time.Now().UTC()
+	if err := s.DB.QueryRowContext(ctx, `INSERT INTO quotes (book_id, text, page, added_at) VALUES (?, ?, ?, ?) RETURNING id;`,
+		quote.BookID, quote.Text, quote.Page, quote.AddedAt).Scan(&quote.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Inserting quote failed", "error", err)
+		return fmt.Errorf("failed to add quote: %w", classify(err))
+	}
+	return nil
+}
+
+// UpdateQuote replaces the text and page of a quote of a book, keeping when
+// it was added, and sets that time.
+func (s *SQLiteBookStore) UpdateQuote(ctx context.Context, quote *model.Quote) error {
+	if err := quote.Validate(); err != nil {
+		return &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
+	}
+	if _, err := s.GetBookByID(ctx, quote.BookID); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing UpdateQuote query", "bookID", quote.BookID, "quoteID", quote.ID)
+	err := s.DB.QueryRowContext(ctx, `UPDATE quotes SET text = ?, page = ? WHERE id = ? AND book_id = ? RETURNING added_at;`,
+		quote.Text, quote.Page, quote.ID, quote.BookID).Scan(&quote.AddedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("quote %d of book with ID %d %w", quote.ID, quote.BookID, ErrNotFound)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Updating quote failed", "error", err)
+		return fmt.Errorf("failed to update quote: %w", classify(err))
+	}
+	return nil
+}
+
+// DeleteQuote removes a quote from a book.
+func (s *SQLiteBookStore) DeleteQuote(ctx context.Context, bookID, quoteID int64) error {
+	if _, err := s.GetBookByID(ctx, bookID); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing DeleteQuote query", "bookID", bookID, "quoteID", quoteID)
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM quotes WHERE id = ? AND book_id = ?;`, quoteID, bookID)
+	if err != nil {
+		return fmt.Errorf("failed to delete quote: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("quote %d of book with ID %d %w", quoteID, bookID, ErrNotFound)
+	}
+	return nil
+}
+
+// GetRandomQuote picks one of the quotes of the books on the shelf at
+// random. It returns ErrNotFound when there are none.
+func (s *SQLiteBookStore) GetRandomQuote(ctx context.Context) (*model.RandomQuote, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetRandomQuote query")
+	owned, args := shelved(ctx, "books")
+	var random model.RandomQuote
+	quote, err := scanQuote(s.DB.QueryRowContext(ctx, `SELECT `+quoteColumns+`, books.title, books.author
+        FROM quotes JOIN books ON books.id = quotes.book_id WHERE `+owned+` ORDER BY RANDOM() LIMIT 1;`, args...),
+		&random.Title, &random.Author)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("quote %w", ErrNotFound)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetRandomQuote query failed", "error", err)
+		return nil, fmt.Errorf("failed to get random quote: %w", err)
+	}
+	random.Quote = *quote
+	return &random, nil
+}
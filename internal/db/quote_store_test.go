@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestQuotes(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	if _, err := store.GetRandomQuote(ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected no random quote without quotes, got %v", err)
+	}
+	book := createTestBook()
+	if _, err := store.AddBook(ctx, book); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+
+	page := 7
+	quote := &model.Quote{BookID: book.ID, Text: "  Fear is the mind-killer.  ", Page: &page}
+	if err := store.AddQuote(ctx, quote); err != nil {
+		t.Fatalf("AddQuote failed: %v", err)
+	}
+	if quote.ID == 0 || quote.Text != "Fear is the mind-killer." || quote.AddedAt.IsZero() {
+		t.Errorf("Expected a trimmed quote with its ID, got %+v", quote)
+	}
+	for _, bad := range []*model.Quote{{BookID: book.ID}, {BookID: book.ID, Text: " "}, {BookID: book.ID, Text: "x", Page: new(int)}} {
+		if err := store.AddQuote(ctx, bad); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected %+v to be invalid, got %v", bad, err)
+		}
+	}
+	if err := store.AddQuote(ctx, &model.Quote{BookID: 999, Text: "x"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a quote of a missing book to be not found, got %v", err)
+	}
+
+	random, err := store.GetRandomQuote(ctx)
+	if err != nil || random.ID != quote.ID || random.Title != book.Title || random.Author != book.Author {
+		t.Errorf("Expected the only quote with its book, got %+v, %v", random, err)
+	}
+
+	quote.Text, quote.Page = "I must not fear.", nil
+	if err := store.UpdateQuote(ctx, quote); err != nil {
+		t.Fatalf("UpdateQuote failed: %v", err)
+	}
+	quotes, err := store.GetQuotes(ctx, book.ID)
+	if err != nil || len(quotes) != 1 || quotes[0].Text != "I must not fear." || quotes[0].Page != nil {
+		t.Errorf("Expected the quote replaced, got %+v, %v", quotes, err)
+	}
+	if err := store.UpdateQuote(ctx, &model.Quote{ID: 999, BookID: book.ID, Text: "x"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a missing quote to be not found, got %v", err)
+	}
+
+	// Quotes of books in the trash aren't picked
+	if err := store.DeleteBook(ctx, book.ID); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if _, err := store.GetRandomQuote(ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected no random quote from the trash, got %v", err)
+	}
+	if err := store.RestoreBook(ctx, book.ID); err != nil {
+		t.Fatalf("RestoreBook failed: %v", err)
+	}
+
+	if err := store.DeleteQuote(ctx, book.ID, quote.ID); err != nil {
+		t.Fatalf("DeleteQuote failed: %v", err)
+	}
+	if err := store.DeleteQuote(ctx, book.ID, quote.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a deleted quote to be not found, got %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// ReadStore defines the database operations for a book's reading history.
+type ReadStore interface {
+	GetReads(ctx context.Context, bookID int64) ([]model.Read, error)
+	GetAllReads(ctx context.Context) (map[int64][]model.Read, error)
+	AddRead(ctx context.Context, read *model.Read) error
+	DeleteRead(ctx context.Context, bookID, readID int64) error
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// utcTime returns t in UTC, so stored times sort as text.
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// insertRead adds a read to the history and sets its ID.
+func insertRead(ctx context.Context, db execer, read *model.Read) error {
+	if err := db.QueryRowContext(ctx, `INSERT INTO reads (book_id, date_started, date_finished, created_at) VALUES (?, ?, ?, ?) RETURNING id;`,
+		read.BookID, utcTime(read.DateStarted), read.DateFinished.UTC(), time.Now().UTC()).Scan(&read.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Inserting read failed", "error", err)
+		return fmt.Errorf("failed to log read: %w", classify(err))
+	}
+	return nil
+}
+
+// syncBookDates sets a book's dates to those of its latest read. A book being
+// read keeps the dates of the read in progress.
+func syncBookDates(ctx context.Context, db execer, bookID int64) error {
+	_, err := db.ExecContext(ctx, `UPDATE books SET
+            date_started = (SELECT date_started FROM reads WHERE book_id = books.id ORDER BY date_finished DESC, id DESC LIMIT 1),
+            date_finished = (SELECT MAX(date_finished) FROM reads WHERE book_id = books.id),
+            updated_at = ?
+        WHERE id = ? AND status != ?;`, time.Now().UTC(), bookID, model.StatusCurrentlyReading)
+	if err != nil {
+		return fmt.Errorf("failed to update book dates: %w", err)
+	}
+	return nil
+}
+
+// GetReads returns a book's reading history, most recent first.
+func (s *SQLiteBookStore) GetReads(ctx context.Context, bookID int64) ([]model.Read, error) {
+	if _, err := s.GetBookByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "SQL: Executing GetReads query", "bookID", bookID)
+	return s.queryReads(ctx, `SELECT id, book_id, date_started, date_finished FROM reads
+        WHERE book_id = ? ORDER BY date_finished DESC, id DESC;`, bookID)
+}
+
+// GetAllReads returns the reading history of every book, keyed by book ID and
+// most recent first. Books never read are left out.
+func (s *SQLiteBookStore) GetAllReads(ctx context.Context) (map[int64][]model.Read, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetAllReads query")
+	owned, args := shelved(ctx, "")
+	reads, err := s.queryReads(ctx, `SELECT id, book_id, date_started, date_finished FROM reads
+        WHERE book_id IN (SELECT id FROM books WHERE `+owned+`) ORDER BY book_id, date_finished DESC, id DESC;`, args...)
+	if err != nil {
+		return nil, err
+	}
+	byBook := make(map[int64][]model.Read)
+	for _, r := range reads {
+		byBook[r.BookID] = append(byBook[r.BookID], r)
+	}
+	return byBook, nil
+}
+
+// queryReads runs a query selecting id, book_id, date_started and date_finished.
+func (s *SQLiteBookStore) queryReads(ctx context.Context, query string, args ...interface{}) ([]model.Read, error) {
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing read query failed", "error", err)
+		return nil, fmt.Errorf("failed to query reads: %w", err)
+	}
+	defer rows.Close()
+
+	reads := []model.Read{}
+	for rows.Next() {
+		var r model.Read
+		var started sql.NullTime
+		if err := rows.Scan(&r.ID, &r.BookID, &started, &r.DateFinished); err != nil {
+			return nil, fmt.Errorf("failed to scan read row: %w", err)
+		}
+		if started.Valid {
+			r.DateStarted = &started.Time
+		}
+		reads = append(reads, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating read rows: %w", err)
+	}
+	return reads, nil
+}
+
+// AddRead logs a completed read of a book, such as a re-read or one from
+// before the book was added, and sets its ID. When it is the latest read, the
+// book's dates are updated to match.
+func (s *SQLiteBookStore) AddRead(ctx context.Context, read *model.Read) error {
+	if err := read.Validate(); err != nil {
+		return &storeError{kind: ErrValidation, msg: err.Error(), cause: err}
+	}
+	book, err := s.GetBookByID(ctx, read.BookID)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing AddRead query", "bookID", read.BookID, "dateStarted", read.DateStarted, "dateFinished", read.DateFinished)
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertRead(ctx, tx, read); err != nil {
+		return err
+	}
+	if err := syncBookDates(ctx, tx, read.BookID); err != nil {
+		return err
+	}
+	if err := s.recordBookDates(ctx, tx, book); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit read: %w", err)
+	}
+	return nil
+}
+
+// DeleteRead removes a read from a book's history. The book's dates fall back
+// to its latest remaining read.
+func (s *SQLiteBookStore) DeleteRead(ctx context.Context, bookID, readID int64) error {
+	book, err := s.GetBookByID(ctx, bookID)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing DeleteRead query", "bookID", bookID, "readID", readID)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM reads WHERE id = ? AND book_id = ?;`, readID, bookID)
+	if err != nil {
+		return fmt.Errorf("failed to delete read: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("read %d of book with ID %d %w", readID, bookID, ErrNotFound)
+	}
+	if err := syncBookDates(ctx, tx, bookID); err != nil {
+		return err
+	}
+	if err := s.recordBookDates(ctx, tx, book); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// recordBookDates records the change a read made to the dates of book, as
+// it was before syncBookDates.
+func (s *SQLiteBookStore) recordBookDates(ctx context.Context, tx *sql.Tx, book *model.Book) error {
+	updated, err := s.getBookTx(ctx, tx, book.ID)
+	if err != nil {
+		return err
+	}
+	return recordBookUpdate(ctx, tx, book, updated)
+}
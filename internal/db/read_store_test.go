@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestReadingHistory(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	book.Status = model.StatusWantToRead
+	if _, err := store.AddBook(ctx, book); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+
+	// Starting and finishing the book stamps its dates and logs the read
+	if err := store.UpdateBookStatus(ctx, book.ID, model.StatusCurrentlyReading); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	got, _ := store.GetBookByID(ctx, book.ID)
+	if got.DateStarted == nil || got.DateFinished != nil {
+		t.Fatalf("Expected only date_started after starting, got %v / %v", got.DateStarted, got.DateFinished)
+	}
+	started := *got.DateStarted
+	if err := store.UpdateBookStatus(ctx, book.ID, model.StatusRead); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	got, _ = store.GetBookByID(ctx, book.ID)
+	if got.DateFinished == nil || got.DateStarted == nil || !got.DateStarted.Equal(started) {
+		t.Fatalf("Expected both dates after finishing, got %v / %v", got.DateStarted, got.DateFinished)
+	}
+	// Setting the same status again is not a new read
+	if err := store.UpdateBookStatus(ctx, book.ID, model.StatusRead); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	reads, err := store.GetReads(ctx, book.ID)
+	if err != nil || len(reads) != 1 {
+		t.Fatalf("Expected one read, got %+v, %v", reads, err)
+	}
+	if reads[0].DateStarted == nil || !reads[0].DateStarted.Equal(started) || !reads[0].DateFinished.Equal(*got.DateFinished) {
+		t.Errorf("Read doesn't match the book's dates: %+v", reads[0])
+	}
+
+	// Re-reading clears the finish date until the book is finished again
+	if err := store.UpdateBookStatus(ctx, book.ID, model.StatusCurrentlyReading); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	if got, _ = store.GetBookByID(ctx, book.ID); got.DateFinished != nil {
+		t.Errorf("Expected no date_finished while re-reading, got %v", got.DateFinished)
+	}
+	if err := store.UpdateBookStatus(ctx, book.ID, model.StatusWantToRead); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	if err := store.UpdateBookStatus(ctx, book.ID, model.StatusRead); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	if reads, _ = store.GetReads(ctx, book.ID); len(reads) != 2 {
+		t.Fatalf("Expected two reads, got %+v", reads)
+	}
+
+	// Logging an old read keeps the book's dates on the latest one
+	latest := reads[0]
+	finished := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	old := &model.Read{BookID: book.ID, DateFinished: finished}
+	if err := store.AddRead(ctx, old); err != nil || old.ID == 0 {
+		t.Fatalf("AddRead failed: %v", err)
+	}
+	reads, _ = store.GetReads(ctx, book.ID)
+	if len(reads) != 3 || reads[2].ID != old.ID || !reads[2].DateFinished.Equal(finished) {
+		t.Errorf("Expected the old read last, got %+v", reads)
+	}
+	if got, _ = store.GetBookByID(ctx, book.ID); !got.DateFinished.Equal(latest.DateFinished) {
+		t.Errorf("Book dates moved to an older read: %v", got.DateFinished)
+	}
+
+	// Deleting the latest read falls back to the one before
+	if err := store.DeleteRead(ctx, book.ID, latest.ID); err != nil {
+		t.Fatalf("DeleteRead failed: %v", err)
+	}
+	if got, _ = store.GetBookByID(ctx, book.ID); got.DateFinished == nil || !got.DateFinished.Equal(reads[1].DateFinished) {
+		t.Errorf("Expected the book's dates from the previous read, got %v", got.DateFinished)
+	}
+
+	start := finished.Add(time.Hour)
+	for name, err := range map[string]error{
+		"missing book":      store.AddRead(ctx, &model.Read{BookID: 999, DateFinished: finished}),
+		"unfinished":        store.AddRead(ctx, &model.Read{BookID: book.ID}),
+		"started after":     store.AddRead(ctx, &model.Read{BookID: book.ID, DateStarted: &start, DateFinished: finished}),
+		"delete missing":    store.DeleteRead(ctx, book.ID, latest.ID),
+		"list missing book": func() error { _, err := store.GetReads(ctx, 999); return err }(),
+	} {
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if err := store.AddRead(ctx, &model.Read{BookID: book.ID}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation, got %v", err)
+	}
+
+	// A book added as already read starts its history with that read
+	added := createTestBook()
+	added.OpenLibraryID, added.Status, added.DateFinished = "OL2M", model.StatusRead, &finished
+	if _, err := store.AddBook(ctx, added); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if reads, _ := store.GetReads(ctx, added.ID); len(reads) != 1 || !reads[0].DateFinished.Equal(finished) {
+		t.Errorf("Expected the added read, got %+v", reads)
+	}
+	if err := store.DeleteBook(ctx, added.ID); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	var n int
+	db.QueryRow(`SELECT COUNT(*) FROM reads WHERE book_id = ?;`, added.ID).Scan(&n)
+	if n != 1 {
+		t.Errorf("Expected a book in the trash to keep its reads, got %d", n)
+	}
+	if err := store.PurgeBook(ctx, added.ID); err != nil {
+		t.Fatalf("PurgeBook failed: %v", err)
+	}
+	db.QueryRow(`SELECT COUNT(*) FROM reads WHERE book_id = ?;`, added.ID).Scan(&n)
+	if n != 0 {
+		t.Errorf("Purging a book left %d reads", n)
+	}
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReadinessStore defines the checks of whether the database can serve
+// requests, for readiness probes. They run every few seconds, so unlike the
+// other queries they aren't logged.
+type ReadinessStore interface {
+	// Ping checks that the database can be reached.
+	Ping(ctx context.Context) error
+	// SchemaVersions returns the version of the newest migration applied to
+	// the database and that of the newest migration the server has.
+	SchemaVersions(ctx context.Context) (applied, latest int, err error)
+}
+
+// Ping checks that the database can be reached.
+func (s *SQLiteBookStore) Ping(ctx context.Context) error {
+	if err := s.DB.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
+
+// SchemaVersions returns the schema version of the database and the latest
+// one of its backend's migrations.
+func (s *SQLiteBookStore) SchemaVersions(ctx context.Context) (applied, latest int, err error) {
+	applied, err = schemaVersion(ctx, s.DB)
+	if err != nil {
+		return 0, 0, err
+	}
+	return applied, LatestSchemaVersion(s.dialect.postgres), nil
+}
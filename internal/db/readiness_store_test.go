@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReadiness(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+
+	if err := store.Ping(ctx); err != nil {
+		t.Errorf("Ping failed: %v", err)
+	}
+	applied, latest, err := store.SchemaVersions(ctx)
+	if err != nil || applied != latest || latest != LatestSchemaVersion(false) {
+		t.Errorf("SchemaVersions = %d, %d, %v; want both %d", applied, latest, err, LatestSchemaVersion(false))
+	}
+
+	teardownTestDB(db)
+	if err := store.Ping(ctx); err == nil {
+		t.Error("Expected Ping of a closed database to fail")
+	}
+	if _, _, err := store.SchemaVersions(ctx); err == nil {
+		t.Error("Expected SchemaVersions of a closed database to fail")
+	}
+}
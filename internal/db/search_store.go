@@ -0,0 +1,213 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/match"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// The books_fts table indexes the full title (with the subtitle), author and
+// comments of every book for full-text search. Its rowid is the book ID and
+// triggers keep it in sync with the books table. FTS5 is used when the SQLite
+// driver is built with it (the sqlite_fts5 build tag); otherwise the index
+// falls back to FTS4, which the default build includes. books_fts_terms lists the indexed words and is used
+// to correct misspelled search terms.
+
+// searchResultLimit caps the number of books returned by SearchBooks.
+const searchResultLimit = 50
+
+// createSearchIndex creates the full-text index and its triggers, and fills
+// the index when it is new.
+func createSearchIndex(db *sql.DB) error {
+	var existing int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'books_fts';`).Scan(&existing); err != nil {
+		return fmt.Errorf("failed to check for search index: %w", err)
+	}
+	if existing == 0 {
+		_, err := db.Exec(`CREATE VIRTUAL TABLE books_fts USING fts5(title, author, comments, tokenize = 'unicode61 remove_diacritics 2');
+            CREATE VIRTUAL TABLE books_fts_terms USING fts5vocab(books_fts, 'row');`)
+		if err != nil && strings.Contains(err.Error(), "no such module") {
+			slog.Info("FTS5 is not available, using FTS4 for the search index")
+			_, err = db.Exec(`CREATE VIRTUAL TABLE books_fts USING fts4(title, author, comments, tokenize=unicode61 "remove_diacritics=2");
+                CREATE VIRTUAL TABLE books_fts_terms USING fts4aux(books_fts);`)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create search index: %w", err)
+		}
+		if _, err := db.Exec(`INSERT INTO books_fts (rowid, title, author, comments)
+            SELECT id, ` + ftsTitle("books") + `, author, COALESCE(comments, '') FROM books;`); err != nil {
+			return fmt.Errorf("failed to fill search index: %w", err)
+		}
+	}
+
+	// The insert and update triggers are recreated because older versions
+	// indexed the title without the subtitle.
+	_, err := db.Exec(`
+    DROP TRIGGER IF EXISTS books_fts_insert;
+    CREATE TRIGGER books_fts_insert AFTER INSERT ON books BEGIN
+        INSERT INTO books_fts (rowid, title, author, comments) VALUES (new.id, ` + ftsTitle("new") + `, new.author, COALESCE(new.comments, ''));
+    END;
+    DROP TRIGGER IF EXISTS books_fts_update;
+    CREATE TRIGGER books_fts_update AFTER UPDATE OF title, subtitle, author, comments ON books BEGIN
+        DELETE FROM books_fts WHERE rowid = old.id;
+        INSERT INTO books_fts (rowid, title, author, comments) VALUES (new.id, ` + ftsTitle("new") + `, new.author, COALESCE(new.comments, ''));
+    END;
+    CREATE TRIGGER IF NOT EXISTS books_fts_delete AFTER DELETE ON books BEGIN
+        DELETE FROM books_fts WHERE rowid = old.id;
+    END;`)
+	if err != nil {
+		return fmt.Errorf("failed to create search index triggers: %w", err)
+	}
+	return nil
+}
+
+// ftsTitle is the indexed title of the books row named row: the title
+// followed by the subtitle, if any.
+func ftsTitle(row string) string {
+	return row + ".title || COALESCE(': ' || " + row + ".subtitle, '')"
+}
+
+// searchTerms splits a user query into lowercase words, dropping punctuation
+// and FTS query syntax.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchExpression builds a full-text query matching every term, the last one
+// as a prefix. A term that appears nowhere in vocabulary also matches the
+// indexed words within maxTypos of it. quote writes a single word in the
+// engine's syntax; or and and join alternatives and terms.
+func searchExpression(terms []string, vocabulary map[string]bool, quote func(term string, prefix bool) string, or, and string) string {
+	groups := make([]string, 0, len(terms))
+	for i, term := range terms {
+		alternatives := []string{quote(term, i == len(terms)-1)}
+		if typos := maxTypos(term); typos > 0 && !vocabulary[term] {
+			for word := range vocabulary {
+				if abs(len(word)-len(term)) <= typos && match.EditDistance(word, term) <= typos {
+					alternatives = append(alternatives, quote(word, false))
+				}
+			}
+		}
+		groups = append(groups, "("+strings.Join(alternatives, or)+")")
+	}
+	return strings.Join(groups, and)
+}
+
+// maxTypos is the number of edits allowed when correcting a search term.
+// Short words are left alone, since nearly everything is close to them.
+func maxTypos(term string) int {
+	switch n := len([]rune(term)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// SearchBooks finds books whose title, author or comments contain every word
+// of query. The last word may be a prefix ("tolk" finds Tolkien), and a word
+// that appears nowhere in the library also matches indexed words within a
+// couple of typos of it ("herbet" finds Herbert). Results are ranked by
+// relevance when the index supports it.
+func (s *SQLiteBookStore) SearchBooks(ctx context.Context, query string) ([]model.Book, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []model.Book{}, nil
+	}
+	slog.InfoContext(ctx, "SQL: Executing SearchBooks query", logging.Redact("query", query))
+
+	vocabulary, err := s.searchVocabulary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fts5, err := s.searchIndexIsFTS5(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	expression := searchExpression(terms, vocabulary, func(term string, prefix bool) string {
+		switch {
+		case !prefix:
+			return `"` + term + `"`
+		case fts5: // The two engines disagree on where the prefix marker goes
+			return `"` + term + `"*`
+		default:
+			return `"` + term + `*"`
+		}
+	}, " OR ", " AND ")
+
+	// FTS4 has no built-in ranking, so its matches are ordered by title alone
+	rank := "0"
+	if fts5 {
+		rank = "rank"
+	}
+
+	owned, args := shelved(ctx, "")
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+bookColumns+` FROM books
+        JOIN (SELECT rowid AS hit_id, `+rank+` AS hit_rank FROM books_fts WHERE books_fts MATCH ?) ON books.id = hit_id
+        WHERE `+owned+` ORDER BY hit_rank, title, subtitle, id LIMIT ?;`, append(append([]interface{}{expression}, args...), searchResultLimit)...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing SearchBooks query failed", "error", err)
+		return nil, fmt.Errorf("failed to search books: %w", err)
+	}
+	defer rows.Close()
+
+	books := []model.Book{}
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan book row: %w", err)
+		}
+		books = append(books, *book)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating book rows: %w", err)
+	}
+	slog.InfoContext(ctx, "SQL: Search complete", logging.Redact("query", query), "matches", len(books))
+	return books, nil
+}
+
+// searchVocabulary returns every word in the search index.
+func (s *SQLiteBookStore) searchVocabulary(ctx context.Context) (map[string]bool, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT DISTINCT term FROM books_fts_terms;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read search vocabulary: %w", err)
+	}
+	defer rows.Close()
+	vocabulary := make(map[string]bool)
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			return nil, fmt.Errorf("failed to scan search term: %w", err)
+		}
+		vocabulary[term] = true
+	}
+	return vocabulary, rows.Err()
+}
+
+// searchIndexIsFTS5 reports whether the search index was created with FTS5.
+func (s *SQLiteBookStore) searchIndexIsFTS5(ctx context.Context) (bool, error) {
+	var ddl string
+	if err := s.DB.QueryRowContext(ctx, `SELECT sql FROM sqlite_master WHERE name = 'books_fts';`).Scan(&ddl); err != nil {
+		return false, fmt.Errorf("failed to inspect search index: %w", err)
+	}
+	return strings.Contains(strings.ToLower(ddl), "using fts5"), nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
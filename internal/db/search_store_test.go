@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestSearchBooks(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	add := func(title, author, comments string) *model.Book {
+		book := createTestBook()
+		book.Title, book.Author = title, author
+		book.OpenLibraryID = "OLSEARCH" + strings.ReplaceAll(title, " ", "")
+		book.Comments = &comments
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("Failed to add %s: %v", title, err)
+		}
+		return book
+	}
+	add("Dune", "Frank Herbert", "Spice and sandworms")
+	add("The Hobbit", "J. R. R. Tolkien", "Reread every winter")
+	add("Les Misérables", "Victor Hugo", "")
+	emma := add("Emma", "Jane Austen", "Matchmaking gone wrong")
+
+	search := func(query string) string {
+		t.Helper()
+		books, err := store.SearchBooks(ctx, query)
+		if err != nil {
+			t.Fatalf("SearchBooks(%q) failed: %v", query, err)
+		}
+		var titles []string
+		for _, b := range books {
+			titles = append(titles, b.Title)
+		}
+		return strings.Join(titles, ",")
+	}
+
+	for query, want := range map[string]string{
+		"dune":              "Dune",
+		"tolk":              "The Hobbit", // Prefix of the last word
+		"herbet":            "Dune",       // Misspelled author
+		"sandworm":          "Dune",       // Partial note
+		"winter reread":     "The Hobbit", // Every word, in any order
+		"miserables":        "Les Misérables",
+		"spice hobbit":      "", // Words must all match the same book
+		"  \"AND\" OR (*) ": "", // FTS syntax is treated as text
+		"matchmaking":       "Emma",
+	} {
+		if got := search(query); got != want {
+			t.Errorf("SearchBooks(%q) = %q, want %q", query, got, want)
+		}
+	}
+
+	// The index follows updates and deletes
+	notes := "Unexpected heist"
+	if err := store.UpdateBook(ctx, emma.ID, model.BookPatch{Comments: model.Some(notes)}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+	if got := search("matchmaking"); got != "" {
+		t.Errorf("Expected old comments to be unindexed, got %q", got)
+	}
+	if got := search("heist"); got != "Emma" {
+		t.Errorf("Expected new comments to be indexed, got %q", got)
+	}
+	if err := store.DeleteBook(ctx, emma.ID); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if got := search("emma"); got != "" {
+		t.Errorf("Expected deleted book to be unindexed, got %q", got)
+	}
+
+	// Books stored before the index existed are indexed when it is created
+	if _, err := db.Exec(`DROP TABLE books_fts_terms; DROP TABLE books_fts;`); err != nil {
+		t.Fatalf("Failed to drop search index: %v", err)
+	}
+	if err := CreateSchema(db); err != nil {
+		t.Fatalf("CreateSchema failed: %v", err)
+	}
+	if got := search("hugo"); got != "Les Misérables" {
+		t.Errorf("Expected existing books to be indexed, got %q", got)
+	}
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// ShareStore manages the read-only share links to users' libraries.
+type ShareStore interface {
+	// AddShareLink stores a link of the user of ctx, identified by token, and
+	// sets its ID and creation time.
+	AddShareLink(ctx context.Context, link *model.ShareLink, token string) error
+	// GetShareLinks returns the share links, newest first.
+	GetShareLinks(ctx context.Context) ([]model.ShareLink, error)
+	// DeleteShareLink revokes a share link.
+	DeleteShareLink(ctx context.Context, id int64) error
+	// GetShareLinkByToken returns the link token is of, regardless of the
+	// user of ctx, and records that it was used. Unknown, revoked and
+	// expired links are not found.
+	GetShareLinkByToken(ctx context.Context, token string) (*model.ShareLink, error)
+	// GetSharedBooks returns a page of the books in the scope of link, by
+	// title, with the total number of them.
+	GetSharedBooks(ctx context.Context, link *model.ShareLink, limit, offset int) ([]model.SharedBook, int, error)
+}
+
+const shareLinkColumns = `id, user_id, name, prefix, status, tag, include_ratings, include_comments, expires_at, created_at, last_used_at`
+
+// scanShareLink reads a share_links row of shareLinkColumns.
+func scanShareLink(row interface{ Scan(...interface{}) error }) (model.ShareLink, error) {
+	var link model.ShareLink
+	var userID sql.NullInt64
+	var status, tag sql.NullString
+	var expiresAt, lastUsed sql.NullTime
+	if err := row.Scan(&link.ID, &userID, &link.Name, &link.Prefix, &status, &tag, &link.IncludeRatings, &link.IncludeComments,
+		&expiresAt, &link.CreatedAt, &lastUsed); err != nil {
+		return link, err
+	}
+	if userID.Valid {
+		link.UserID = &userID.Int64
+	}
+	link.Status, link.Tag = model.BookStatus(status.String), tag.String
+	if expiresAt.Valid {
+		link.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsed.Valid {
+		link.LastUsedAt = &lastUsed.Time
+	}
+	return link, nil
+}
+
+// AddShareLink stores a link of the user of ctx, identified by token, and
+// sets its ID and creation time.
+func (s *SQLiteBookStore) AddShareLink(ctx context.Context, link *model.ShareLink, token string) error {
+	if link.Name == "" {
+		return invalidf("share link name is required")
+	}
+	if link.Status != "" && !link.Status.IsValid() {
+		return invalidf("invalid status provided")
+	}
+	link.UserID = owner(ctx)
+	link.CreatedAt = time.Now().UTC()
+	slog.InfoContext(ctx, "SQL: Executing AddShareLink query", "name", link.Name, "status", link.Status, "tag", link.Tag)
+	if err := s.DB.QueryRowContext(ctx, `INSERT INTO share_links (user_id, name, prefix, token_hash, status, tag, include_ratings, include_comments, expires_at, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;`,
+		link.UserID, link.Name, link.Prefix, tokenHash(token), sql.NullString{String: string(link.Status), Valid: link.Status != ""},
+		sql.NullString{String: link.Tag, Valid: link.Tag != ""}, link.IncludeRatings, link.IncludeComments, link.ExpiresAt, link.CreatedAt).Scan(&link.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddShareLink statement failed", "error", err)
+		return fmt.Errorf("failed to add share link: %w", classify(err))
+	}
+	return nil
+}
+
+// GetShareLinks returns the share links, newest first.
+func (s *SQLiteBookStore) GetShareLinks(ctx context.Context) ([]model.ShareLink, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetShareLinks query")
+	owned, args := ownedBy(ctx, "user_id")
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+shareLinkColumns+` FROM share_links WHERE `+owned+`
+        ORDER BY created_at DESC, id DESC;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetShareLinks query failed", "error", err)
+		return nil, fmt.Errorf("failed to query share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []model.ShareLink{}
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan share link row: %w", err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating share link rows: %w", err)
+	}
+	return links, nil
+}
+
+// DeleteShareLink revokes a share link.
+func (s *SQLiteBookStore) DeleteShareLink(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "SQL: Executing DeleteShareLink query", "id", id)
+	owned, args := ownedBy(ctx, "user_id")
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM share_links WHERE id = ? AND `+owned+`;`, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete share link: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("share link with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// GetShareLinkByToken returns the link token is of, regardless of the user
+// of ctx, and records that it was used. Unknown, revoked and expired links
+// are not found.
+func (s *SQLiteBookStore) GetShareLinkByToken(ctx context.Context, token string) (*model.ShareLink, error) {
+	hash := tokenHash(token)
+	link, err := scanShareLink(s.DB.QueryRowContext(ctx, `SELECT `+shareLinkColumns+` FROM share_links WHERE token_hash = ?;`, hash))
+	if err == sql.ErrNoRows || (err == nil && link.ExpiresAt != nil && !link.ExpiresAt.After(time.Now())) {
+		return nil, fmt.Errorf("share link %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share link: %w", err)
+	}
+	if _, err := s.DB.ExecContext(ctx, `UPDATE share_links SET last_used_at = ? WHERE token_hash = ?;`, time.Now().UTC(), hash); err != nil {
+		slog.WarnContext(ctx, "Failed to record share link use", "error", err)
+	}
+	return &link, nil
+}
+
+// GetSharedBooks returns a page of the books in the scope of link, by title,
+// with the total number of them. A limit of 0 returns all of them.
+func (s *SQLiteBookStore) GetSharedBooks(ctx context.Context, link *model.ShareLink, limit, offset int) ([]model.SharedBook, int, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetSharedBooks query", "link", link.ID, "limit", limit, "offset", offset)
+	// The books are the link owner's, whoever follows it
+	if link.UserID != nil {
+		ctx = WithUser(ctx, *link.UserID)
+	}
+	books, total, err := s.GetBooksPage(ctx, ListOptions{
+		Filter: BookFilter{Status: link.Status, Tag: link.Tag},
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	shared := make([]model.SharedBook, len(books))
+	for i, book := range books {
+		shared[i] = model.NewSharedBook(book, *link)
+	}
+	return shared, total, nil
+}
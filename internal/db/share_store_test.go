@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestShareLinks(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	var users []model.User
+	for _, username := range []string{"alice", "bob"} {
+		user := model.User{Username: username, PasswordHash: "hash"}
+		if err := store.AddUser(ctx, &user); err != nil {
+			t.Fatalf("AddUser failed: %v", err)
+		}
+		users = append(users, user)
+	}
+	alice, bob := WithUser(ctx, users[0].ID), WithUser(ctx, users[1].ID)
+
+	read := createTestBook()
+	read.Status = model.StatusRead
+	for _, c := range []context.Context{alice, bob} {
+		if _, err := store.AddBook(c, read); err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+	}
+	tagged := createTestBook()
+	tagged.OpenLibraryID, tagged.Title = "OLTAGGEDM", "Tagged"
+	taggedID, err := store.AddBook(alice, tagged)
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if _, err := store.AddBookTag(alice, taggedID, "club"); err != nil {
+		t.Fatalf("AddBookTag failed: %v", err)
+	}
+
+	if err := store.AddShareLink(alice, &model.ShareLink{Name: "", Prefix: "x"}, "none"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a link without a name to be rejected, got %v", err)
+	}
+	readLink := &model.ShareLink{Name: "read", Prefix: "read", Status: model.StatusRead}
+	tagLink := &model.ShareLink{Name: "club", Prefix: "club", Tag: "club"}
+	past := time.Now().Add(-time.Minute)
+	expired := &model.ShareLink{Name: "old", Prefix: "old", ExpiresAt: &past}
+	for token, link := range map[string]*model.ShareLink{"read-token": readLink, "tag-token": tagLink, "expired-token": expired} {
+		if err := store.AddShareLink(alice, link, token); err != nil {
+			t.Fatalf("AddShareLink failed: %v", err)
+		}
+	}
+
+	// Links are found by token whoever asks, and show only their owner's books in scope
+	link, err := store.GetShareLinkByToken(bob, "read-token")
+	if err != nil || link.ID != readLink.ID || link.Status != model.StatusRead {
+		t.Fatalf("GetShareLinkByToken = %+v, %v", link, err)
+	}
+	if books, total, err := store.GetSharedBooks(bob, link, 0, 0); err != nil || total != 1 || len(books) != 1 || books[0].Title != read.Title {
+		t.Errorf("Expected alice's one Read book, got %+v, %d, %v", books, total, err)
+	}
+	link, _ = store.GetShareLinkByToken(ctx, "tag-token")
+	if books, total, err := store.GetSharedBooks(ctx, link, 0, 0); err != nil || total != 1 || books[0].ID != taggedID {
+		t.Errorf("Expected the tagged book, got %+v, %d, %v", books, total, err)
+	}
+	for _, token := range []string{"expired-token", "unknown"} {
+		if _, err := store.GetShareLinkByToken(ctx, token); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected %s not found, got %v", token, err)
+		}
+	}
+
+	links, err := store.GetShareLinks(alice)
+	if err != nil || len(links) != 3 {
+		t.Fatalf("Unexpected links %+v, %v", links, err)
+	}
+	for _, l := range links {
+		if l.ID == readLink.ID && l.LastUsedAt == nil {
+			t.Error("Expected the use of the link recorded")
+		}
+	}
+	if links, err := store.GetShareLinks(bob); err != nil || len(links) != 0 {
+		t.Errorf("Expected bob to have no links, got %+v, %v", links, err)
+	}
+	if err := store.DeleteShareLink(bob, readLink.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected bob not to revoke alice's link, got %v", err)
+	}
+	if err := store.DeleteShareLink(alice, readLink.ID); err != nil {
+		t.Fatalf("DeleteShareLink failed: %v", err)
+	}
+	if _, err := store.GetShareLinkByToken(ctx, "read-token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a revoked link not found, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/requestid"
+)
+
+// SlowQuery is a query that took at least the threshold of a SlowQueryLog.
+type SlowQuery struct {
+	// Query is the shape of the statement: its whitespace collapsed, its
+	// literals and lists of placeholders shortened to "?", so it holds no
+	// values and the same query run with different ones looks the same.
+	Query      string    `json:"query"`
+	Operation  string    `json:"operation"` // As for the query duration metric: "select", "insert", ...
+	Table      string    `json:"table"`     // The first table the statement names
+	DurationMS float64   `json:"duration_ms"`
+	Params     int       `json:"params"` // Parameters the query was run with
+	At         time.Time `json:"at"`     // When it started
+	RequestID  string    `json:"request_id,omitempty"`
+}
+
+// SlowQueryLog keeps the most recent queries that took at least its
+// threshold, and logs each as a warning. It's safe for concurrent use.
+type SlowQueryLog struct {
+	mu        sync.Mutex
+	threshold time.Duration // 0 turns the log off
+	queries   []SlowQuery   // A ring of the latest, next being the oldest once full
+	next      int
+	total     int64 // Slow queries since startup, including those no longer kept
+}
+
+// NewSlowQueryLog creates a log of queries taking at least threshold, which
+// keeps the latest size of them.
+func NewSlowQueryLog(threshold time.Duration, size int) *SlowQueryLog {
+	return &SlowQueryLog{threshold: threshold, queries: make([]SlowQuery, 0, size)}
+}
+
+// SlowQueries is the slow query log of the stores opened with InitDB and
+// InitPostgresDB, off until given a threshold.
+var SlowQueries = NewSlowQueryLog(0, 100)
+
+// Threshold returns the duration from which queries are logged, 0 when off.
+func (l *SlowQueryLog) Threshold() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.threshold
+}
+
+// SetThreshold changes the duration from which queries are logged; 0 turns
+// the log off. Queries already logged are kept.
+func (l *SlowQueryLog) SetThreshold(threshold time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.threshold = threshold
+}
+
+// Recent returns the slow queries kept, newest first, and how many there
+// have been since startup.
+func (l *SlowQueryLog) Recent() ([]SlowQuery, int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	recent := make([]SlowQuery, 0, len(l.queries))
+	for i := 1; i <= len(l.queries); i++ {
+		recent = append(recent, l.queries[(l.next-i+len(l.queries))%len(l.queries)])
+	}
+	return recent, l.total
+}
+
+// observe logs query, run with params parameters from start, if it took at
+// least the threshold.
+func (l *SlowQueryLog) observe(ctx context.Context, query string, params int, start time.Time, took time.Duration) {
+	l.mu.Lock()
+	threshold := l.threshold
+	l.mu.Unlock()
+	if threshold == 0 || took < threshold {
+		return
+	}
+
+	operation, table := queryLabels(query)
+	slow := SlowQuery{
+		Query:      queryShape(query),
+		Operation:  operation,
+		Table:      table,
+		DurationMS: float64(took) / float64(time.Millisecond),
+		Params:     params,
+		At:         start.UTC(),
+		RequestID:  requestid.FromContext(ctx),
+	}
+	slog.WarnContext(ctx, "Slow query", "query", slow.Query, "duration", took, "params", params, "threshold", threshold)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total++
+	switch {
+	case len(l.queries) < cap(l.queries):
+		l.queries = append(l.queries, slow)
+		l.next = len(l.queries) % cap(l.queries)
+	case len(l.queries) > 0:
+		l.queries[l.next] = slow
+		l.next = (l.next + 1) % len(l.queries)
+	}
+}
+
+var (
+	// queryLiteral matches the string and number literals of a statement.
+	queryLiteral = regexp.MustCompile(`'(?:[^']|'')*'|\b\d+(?:\.\d+)?\b`)
+	// queryPlaceholders matches lists of placeholders, such as those of IN.
+	queryPlaceholders = regexp.MustCompile(`\?(?:\s*,\s*\?)+`)
+)
+
+// queryShape returns query with its whitespace collapsed and its literals
+// and lists of placeholders replaced with a single "?".
+func queryShape(query string) string {
+	shape := strings.Join(strings.Fields(query), " ")
+	shape = queryLiteral.ReplaceAllString(shape, "?")
+	return queryPlaceholders.ReplaceAllString(shape, "?")
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/requestid"
+)
+
+func TestQueryShape(t *testing.T) {
+	for query, want := range map[string]string{
+		"\n    SELECT id FROM books\n    WHERE id = ?;":                 "SELECT id FROM books WHERE id = ?;",
+		"SELECT * FROM books WHERE id IN (?, ?,?) LIMIT 20":             "SELECT * FROM books WHERE id IN (?) LIMIT ?",
+		"SELECT * FROM books WHERE title = 'It''s' AND rating > 4.5":    "SELECT * FROM books WHERE title = ? AND rating > ?",
+		"INSERT INTO book_tags (book_id, tag_id) VALUES (?, ?), (?, ?)": "INSERT INTO book_tags (book_id, tag_id) VALUES (?), (?)",
+		"SELECT rowid FROM books_fts5":                                  "SELECT rowid FROM books_fts5",
+	} {
+		if got := queryShape(query); got != want {
+			t.Errorf("queryShape(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewSlowQueryLog(100*time.Millisecond, 2)
+	l.observe(ctx, "SELECT * FROM books WHERE id = ?", 1, start, 50*time.Millisecond)
+	for i, query := range []string{"SELECT * FROM tags", "DELETE FROM sessions", "UPDATE books SET title = ? WHERE id = ?"} {
+		l.observe(ctx, query, i, start.Add(time.Duration(i)*time.Second), 150*time.Millisecond)
+	}
+	queries, total := l.Recent()
+	if total != 3 || len(queries) != 2 {
+		t.Fatalf("Expected the latest 2 of 3 slow queries, got %+v of %d", queries, total)
+	}
+	if q := queries[0]; q.Query != "UPDATE books SET title = ? WHERE id = ?" || q.Operation != "update" || q.Table != "books" ||
+		q.DurationMS != 150 || q.Params != 2 || !q.At.Equal(start.Add(2*time.Second)) {
+		t.Errorf("Unexpected newest slow query: %+v", q)
+	}
+	if queries[1].Table != "sessions" {
+		t.Errorf("Expected the DELETE second, got %+v", queries[1])
+	}
+
+	l.SetThreshold(0)
+	l.observe(ctx, "SELECT * FROM tags", 0, start, time.Hour)
+	if _, total := l.Recent(); total != 3 {
+		t.Errorf("Expected nothing logged once off, got %d slow queries", total)
+	}
+}
+
+// TestSlowQueries tests that queries on a database opened with InitDB are
+// logged in SlowQueries once they take its threshold.
+func TestSlowQueries(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "books.db"))
+	if err != nil {
+		t.Fatalf("InitDB failed: %v", err)
+	}
+	defer db.Close()
+	store := NewSQLiteBookStore(db)
+
+	SlowQueries.SetThreshold(time.Nanosecond)
+	defer SlowQueries.SetThreshold(0)
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	if _, err := store.GetTags(ctx); err != nil {
+		t.Fatalf("GetTags failed: %v", err)
+	}
+	queries, _ := SlowQueries.Recent()
+	if len(queries) == 0 || queries[0].Table != "tags" || queries[0].RequestID != "req-1" {
+		t.Errorf("Expected the query logged, got %+v", queries)
+	}
+}
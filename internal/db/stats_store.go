@@ -0,0 +1,390 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// StatsStore defines the reading statistics derived from the reading history.
+// Like every reading stat, they leave reference books out, and archived books
+// unless SQLiteBookStore.ArchivedInStats is set.
+type StatsStore interface {
+	GetRereadStats(ctx context.Context, limit int) (model.RereadStats, error)
+	GetLengthStats(ctx context.Context) ([]model.LengthBucket, error)
+	GetReadingPace(ctx context.Context) (model.ReadingPace, error)
+	GetReadingStats(ctx context.Context, year int) (model.ReadingStats, error)
+	CountBooksByStatus(ctx context.Context) (map[model.BookStatus]int, error)
+}
+
+// counted returns the condition matching the books whose reads count towards
+// reading stats, with its arguments: the leisure books on the bookshelf,
+// leaving archived books out unless ArchivedInStats is set. table qualifies
+// the columns, as for shelved.
+func (s *SQLiteBookStore) counted(ctx context.Context, table string) (string, []interface{}) {
+	prefix := ""
+	if table != "" {
+		prefix = table + "."
+	}
+	owned, ownedArgs := shelved(ctx, table)
+	cond := prefix + "reading_mode = ? AND " + owned
+	args := append([]interface{}{model.ModeLeisure}, ownedArgs...)
+	if !s.ArchivedInStats {
+		cond = prefix + "archived = ? AND " + cond
+		args = append([]interface{}{false}, args...)
+	}
+	return cond, args
+}
+
+// statsAuthors is the number of authors GetReadingStats lists.
+const statsAuthors = 10
+
+// paceReads is the number of recent reads GetReadingPace measures.
+const paceReads = 10
+
+// lengthBuckets are the page ranges GetLengthStats averages over. A zero
+// max means no upper bound.
+var lengthBuckets = []struct {
+	label    string
+	min, max int
+}{
+	{"<200", 0, 200},
+	{"200-400", 200, 400},
+	{"400+", 400, 0},
+}
+
+// GetRereadStats counts first reads and re-reads per year, and lists up to
+// limit of the books read most often. A book's first read is its earliest.
+func (s *SQLiteBookStore) GetRereadStats(ctx context.Context, limit int) (model.RereadStats, error) {
+	stats := model.RereadStats{Years: []model.ReadingYear{}, MostReread: []model.RereadBook{}}
+	slog.InfoContext(ctx, "SQL: Executing GetRereadStats query", "limit", limit)
+	counted, args := s.counted(ctx, "books")
+	rows, err := s.DB.QueryContext(ctx, `SELECT books.id, books.title, books.author, reads.date_finished
+        FROM reads JOIN books ON books.id = reads.book_id
+        WHERE `+counted+`
+        ORDER BY books.id, reads.date_finished, reads.id;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetRereadStats query failed", "error", err)
+		return stats, fmt.Errorf("failed to query reads: %w", err)
+	}
+	defer rows.Close()
+
+	years := make(map[int]*model.ReadingYear)
+	var books []model.RereadBook
+	for rows.Next() {
+		var book model.RereadBook
+		var finished time.Time
+		if err := rows.Scan(&book.BookID, &book.Title, &book.Author, &finished); err != nil {
+			return stats, fmt.Errorf("failed to scan read row: %w", err)
+		}
+		year := years[finished.UTC().Year()]
+		if year == nil {
+			year = &model.ReadingYear{Year: finished.UTC().Year()}
+			years[year.Year] = year
+		}
+		// Rows come grouped by book, earliest read first
+		if n := len(books); n > 0 && books[n-1].BookID == book.BookID {
+			year.Rereads++
+			books[n-1].Reads++
+			books[n-1].LastFinished = finished
+			continue
+		}
+		year.FirstReads++
+		book.Reads, book.LastFinished = 1, finished
+		books = append(books, book)
+	}
+	if err := rows.Err(); err != nil {
+		return stats, fmt.Errorf("error iterating read rows: %w", err)
+	}
+
+	for _, year := range years {
+		stats.Years = append(stats.Years, *year)
+	}
+	sort.Slice(stats.Years, func(i, j int) bool { return stats.Years[i].Year < stats.Years[j].Year })
+	for _, book := range books {
+		if book.Reads > 1 {
+			stats.MostReread = append(stats.MostReread, book)
+		}
+	}
+	sort.SliceStable(stats.MostReread, func(i, j int) bool {
+		a, b := stats.MostReread[i], stats.MostReread[j]
+		return a.Reads > b.Reads || a.Reads == b.Reads && a.LastFinished.After(b.LastFinished)
+	})
+	if limit > 0 && len(stats.MostReread) > limit {
+		stats.MostReread = stats.MostReread[:limit]
+	}
+	return stats, nil
+}
+
+// GetLengthStats averages the days taken to finish a book per length bucket.
+// Only reads with both dates of books with a page count are counted, and a
+// re-read counts again.
+func (s *SQLiteBookStore) GetLengthStats(ctx context.Context) ([]model.LengthBucket, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetLengthStats query")
+	counted, args := s.counted(ctx, "books")
+	rows, err := s.DB.QueryContext(ctx, `SELECT books.page_count, reads.date_started, reads.date_finished
+        FROM reads JOIN books ON books.id = reads.book_id
+        WHERE books.page_count IS NOT NULL AND reads.date_started IS NOT NULL AND `+counted+`;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetLengthStats query failed", "error", err)
+		return nil, fmt.Errorf("failed to query reads: %w", err)
+	}
+	defer rows.Close()
+
+	days := make([]float64, len(lengthBuckets))
+	buckets := make([]model.LengthBucket, len(lengthBuckets))
+	for i, b := range lengthBuckets {
+		buckets[i] = model.LengthBucket{Label: b.label, MinPages: b.min}
+		if b.max > 0 {
+			max := b.max
+			buckets[i].MaxPages = &max
+		}
+	}
+	for rows.Next() {
+		var pages int
+		var started, finished time.Time
+		if err := rows.Scan(&pages, &started, &finished); err != nil {
+			return nil, fmt.Errorf("failed to scan read row: %w", err)
+		}
+		for i, b := range lengthBuckets {
+			if pages >= b.min && (b.max == 0 || pages < b.max) {
+				buckets[i].Reads++
+				days[i] += finished.Sub(started).Hours() / 24
+				break
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating read rows: %w", err)
+	}
+	for i := range buckets {
+		if buckets[i].Reads > 0 {
+			avg := math.Round(days[i]*10/float64(buckets[i].Reads)) / 10
+			buckets[i].AverageDays = &avg
+		}
+	}
+	return buckets, nil
+}
+
+// GetReadingPace measures the pages read per day over the latest reads with
+// both dates of books with a page count. Reads finished within a day count as
+// taking a day.
+func (s *SQLiteBookStore) GetReadingPace(ctx context.Context) (model.ReadingPace, error) {
+	var pace model.ReadingPace
+	slog.InfoContext(ctx, "SQL: Executing GetReadingPace query")
+	counted, args := s.counted(ctx, "books")
+	rows, err := s.DB.QueryContext(ctx, `SELECT books.page_count, reads.date_started, reads.date_finished
+        FROM reads JOIN books ON books.id = reads.book_id
+        WHERE books.page_count IS NOT NULL AND reads.date_started IS NOT NULL AND `+counted+`
+        ORDER BY reads.date_finished DESC, reads.id DESC LIMIT ?;`, append(args, paceReads)...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetReadingPace query failed", "error", err)
+		return pace, fmt.Errorf("failed to query reads: %w", err)
+	}
+	defer rows.Close()
+
+	var pages, days float64
+	for rows.Next() {
+		var count int
+		var started, finished time.Time
+		if err := rows.Scan(&count, &started, &finished); err != nil {
+			return pace, fmt.Errorf("failed to scan read row: %w", err)
+		}
+		pages += float64(count)
+		days += math.Max(finished.Sub(started).Hours()/24, 1)
+		pace.Reads++
+	}
+	if err := rows.Err(); err != nil {
+		return pace, fmt.Errorf("error iterating read rows: %w", err)
+	}
+	if days > 0 {
+		pace.PagesPerDay = math.Round(pages*10/days) / 10
+	}
+	return pace, nil
+}
+
+// GetReadingStats summarizes the reads finished in year, or every read when
+// year is 0. The counts are aggregated by the database; only the dates of
+// reads are loaded, for the streak, which always runs up to today.
+func (s *SQLiteBookStore) GetReadingStats(ctx context.Context, year int) (model.ReadingStats, error) {
+	stats := model.ReadingStats{Months: []model.PeriodCount{}, Years: []model.PeriodCount{}, Types: []model.StatCount{}, Authors: []model.StatCount{}}
+	slog.InfoContext(ctx, "SQL: Executing GetReadingStats query", "year", year)
+	where, args := s.counted(ctx, "books")
+	if year != 0 {
+		from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		where += ` AND reads.date_finished >= ? AND reads.date_finished < ?`
+		args = append(args, from, from.AddDate(1, 0, 0))
+		stats.Year = &year
+	}
+	from := ` FROM reads JOIN books ON books.id = reads.book_id WHERE ` + where
+
+	if err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*), COUNT(DISTINCT books.id), COALESCE(SUM(books.page_count), 0)`+from+`;`, args...).
+		Scan(&stats.Reads, &stats.Books, &stats.Pages); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetReadingStats query failed", "error", err)
+		return stats, fmt.Errorf("failed to count reads: %w", err)
+	}
+	var rating sql.NullFloat64
+	if err := s.DB.QueryRowContext(ctx, `SELECT AVG(rating) FROM books WHERE rating IS NOT NULL AND id IN (SELECT books.id`+from+`);`, args...).
+		Scan(&rating); err != nil {
+		return stats, fmt.Errorf("failed to average ratings: %w", err)
+	}
+	if rating.Valid {
+		avg := math.Round(rating.Float64*10) / 10
+		stats.AverageRating = &avg
+	}
+
+	for _, p := range []struct {
+		periods *[]model.PeriodCount
+		expr    string
+	}{
+		{&stats.Months, s.dialect.yearMonth("reads.date_finished")},
+		{&stats.Years, s.dialect.year("reads.date_finished")},
+	} {
+		rows, err := s.DB.QueryContext(ctx, `SELECT `+p.expr+`, COUNT(*), COALESCE(SUM(books.page_count), 0)`+from+`
+            GROUP BY `+p.expr+` ORDER BY `+p.expr+`;`, args...)
+		if err != nil {
+			return stats, fmt.Errorf("failed to count reads per period: %w", err)
+		}
+		for rows.Next() {
+			var c model.PeriodCount
+			if err := rows.Scan(&c.Period, &c.Reads, &c.Pages); err != nil {
+				rows.Close()
+				return stats, fmt.Errorf("failed to scan period row: %w", err)
+			}
+			*p.periods = append(*p.periods, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return stats, fmt.Errorf("error iterating period rows: %w", err)
+		}
+	}
+	if year != 0 {
+		stats.Months = fillMonths(year, stats.Months)
+	}
+
+	for _, c := range []struct {
+		counts *[]model.StatCount
+		query  string
+	}{
+		{&stats.Types, `SELECT books.type, COUNT(DISTINCT books.id)` + from + ` GROUP BY books.type ORDER BY 2 DESC, books.type;`},
+		{&stats.Authors, `SELECT books.author, COUNT(DISTINCT books.id)` + from + ` GROUP BY books.author ORDER BY 2 DESC, books.author LIMIT ` + strconv.Itoa(statsAuthors) + `;`},
+	} {
+		rows, err := s.DB.QueryContext(ctx, c.query, args...)
+		if err != nil {
+			return stats, fmt.Errorf("failed to count books: %w", err)
+		}
+		for rows.Next() {
+			var value string
+			var books int
+			if err := rows.Scan(&value, &books); err != nil {
+				rows.Close()
+				return stats, fmt.Errorf("failed to scan count row: %w", err)
+			}
+			*c.counts = append(*c.counts, statCount(value, books, stats.Books))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return stats, fmt.Errorf("error iterating count rows: %w", err)
+		}
+	}
+
+	streak, err := s.readingStreak(ctx)
+	if err != nil {
+		return stats, err
+	}
+	stats.Streak = streak
+	return stats, nil
+}
+
+// fillMonths returns every month of year, with the counts of months.
+func fillMonths(year int, months []model.PeriodCount) []model.PeriodCount {
+	counted := make(map[string]model.PeriodCount, len(months))
+	for _, m := range months {
+		counted[m.Period] = m
+	}
+	all := make([]model.PeriodCount, 12)
+	for i := range all {
+		period := fmt.Sprintf("%04d-%02d", year, i+1)
+		all[i] = counted[period]
+		all[i].Period = period
+	}
+	return all
+}
+
+// readingStreak finds the reading streaks from the days covered by leisure
+// reads and by the leisure books being read, leaving vacations out.
+func (s *SQLiteBookStore) readingStreak(ctx context.Context) (model.ReadingStreak, error) {
+	counted, args := s.counted(ctx, "books")
+	rows, err := s.DB.QueryContext(ctx, `SELECT reads.date_started, reads.date_finished
+        FROM reads JOIN books ON books.id = reads.book_id WHERE `+counted+`
+        UNION ALL SELECT books.date_started, NULL FROM books
+        WHERE books.status = ? AND books.date_started IS NOT NULL AND `+counted+`;`,
+		append(append(append([]interface{}{}, args...), model.StatusCurrentlyReading), args...)...)
+	if err != nil {
+		return model.ReadingStreak{}, fmt.Errorf("failed to query reading days: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now().UTC()
+	reading := make(map[string]bool)
+	for rows.Next() {
+		var started, finished *time.Time
+		if err := rows.Scan(&started, &finished); err != nil {
+			return model.ReadingStreak{}, fmt.Errorf("failed to scan reading days: %w", err)
+		}
+		end := now
+		if finished != nil {
+			end = *finished
+		}
+		day := end
+		if started != nil && started.Before(end) {
+			day = *started
+		}
+		for day = day.UTC(); day.Format(model.DateLayout) <= end.UTC().Format(model.DateLayout); day = day.AddDate(0, 0, 1) {
+			reading[day.Format(model.DateLayout)] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return model.ReadingStreak{}, fmt.Errorf("error iterating reading days: %w", err)
+	}
+	vacations, err := s.GetVacations(ctx)
+	if err != nil {
+		return model.ReadingStreak{}, err
+	}
+	return model.Streaks(reading, vacations, now), nil
+}
+
+// CountBooksByStatus returns the number of books on each shelf. Unlike the
+// reading stats it counts every book on the bookshelf; shelves without books
+// are counted as 0.
+func (s *SQLiteBookStore) CountBooksByStatus(ctx context.Context) (map[model.BookStatus]int, error) {
+	slog.InfoContext(ctx, "SQL: Executing CountBooksByStatus query")
+	owned, args := shelved(ctx, "")
+	rows, err := s.DB.QueryContext(ctx, `SELECT status, COUNT(*) FROM books WHERE `+owned+` GROUP BY status;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing CountBooksByStatus query failed", "error", err)
+		return nil, fmt.Errorf("failed to count books: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[model.BookStatus]int{model.StatusWantToRead: 0, model.StatusCurrentlyReading: 0, model.StatusRead: 0}
+	for rows.Next() {
+		var status model.BookStatus
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan book count row: %w", err)
+		}
+		counts[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating book count rows: %w", err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,279 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestRereadStats(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	day := func(year int, month time.Month) time.Time { return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC) }
+	add := func(olid, title string, mode model.ReadingMode, finished ...time.Time) int64 {
+		t.Helper()
+		book := createTestBook()
+		book.OpenLibraryID, book.Title, book.ReadingMode, book.Status = olid, title, mode, model.StatusRead
+		book.DateFinished = &finished[0]
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+		for _, f := range finished[1:] {
+			if err := store.AddRead(ctx, &model.Read{BookID: book.ID, DateFinished: f}); err != nil {
+				t.Fatalf("AddRead failed: %v", err)
+			}
+		}
+		return book.ID
+	}
+	// Reads are logged out of order; the earliest one is the first read
+	dune := add("OL1M", "Dune", model.ModeLeisure, day(2024, 5), day(2022, 3), day(2023, 8))
+	emma := add("OL2M", "Emma", model.ModeLeisure, day(2023, 1), day(2024, 2))
+	add("OL3M", "Odd", model.ModeLeisure, day(2024, 7))
+	add("OL4M", "Manual", model.ModeReference, day(2024, 9), day(2024, 10))
+
+	stats, err := store.GetRereadStats(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetRereadStats failed: %v", err)
+	}
+	want := []model.ReadingYear{{Year: 2022, FirstReads: 1}, {Year: 2023, FirstReads: 1, Rereads: 1}, {Year: 2024, FirstReads: 1, Rereads: 2}}
+	if len(stats.Years) != len(want) {
+		t.Fatalf("Expected %d years, got %+v", len(want), stats.Years)
+	}
+	for i, y := range want {
+		if stats.Years[i] != y {
+			t.Errorf("Year %d: got %+v, want %+v", i, stats.Years[i], y)
+		}
+	}
+	if len(stats.MostReread) != 2 || stats.MostReread[0].BookID != dune || stats.MostReread[0].Reads != 3 ||
+		!stats.MostReread[0].LastFinished.Equal(day(2024, 5)) || stats.MostReread[1].BookID != emma || stats.MostReread[1].Reads != 2 {
+		t.Errorf("Expected Dune then Emma as most re-read, got %+v", stats.MostReread)
+	}
+	if stats, _ := store.GetRereadStats(ctx, 1); len(stats.MostReread) != 1 || stats.MostReread[0].BookID != dune {
+		t.Errorf("Expected only Dune within the limit, got %+v", stats.MostReread)
+	}
+
+	// The list filter tells re-read books from the rest
+	yes, no := true, false
+	for _, tc := range []struct {
+		reread *bool
+		want   int
+	}{{&yes, 3}, {&no, 1}} {
+		books, total, err := store.GetBooksPage(ctx, ListOptions{Filter: BookFilter{Reread: tc.reread}})
+		if err != nil || total != tc.want || len(books) != tc.want {
+			t.Errorf("Reread=%v: expected %d books, got %d (total %d), %v", *tc.reread, tc.want, len(books), total, err)
+		}
+	}
+}
+
+func TestLengthStats(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	add := func(olid string, pages, days int, mode model.ReadingMode) {
+		t.Helper()
+		book := createTestBook()
+		book.OpenLibraryID, book.ReadingMode, book.Status = olid, mode, model.StatusRead
+		if pages > 0 {
+			book.PageCount = &pages
+		}
+		finished := start.AddDate(0, 0, days)
+		book.DateStarted, book.DateFinished = &start, &finished
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+	}
+	add("OL1M", 150, 3, model.ModeLeisure)
+	add("OL2M", 199, 4, model.ModeLeisure)
+	add("OL3M", 200, 10, model.ModeLeisure)
+	add("OL4M", 900, 30, model.ModeReference)
+	add("OL5M", 0, 50, model.ModeLeisure)
+
+	buckets, err := store.GetLengthStats(ctx)
+	if err != nil {
+		t.Fatalf("GetLengthStats failed: %v", err)
+	}
+	if len(buckets) != 3 {
+		t.Fatalf("Expected 3 buckets, got %+v", buckets)
+	}
+	for i, want := range []struct {
+		label string
+		reads int
+		avg   float64
+	}{{"<200", 2, 3.5}, {"200-400", 1, 10}, {"400+", 0, 0}} {
+		b := buckets[i]
+		if b.Label != want.label || b.Reads != want.reads || (want.reads == 0) != (b.AverageDays == nil) ||
+			b.AverageDays != nil && *b.AverageDays != want.avg {
+			t.Errorf("Bucket %d: got %+v (average %v), want %+v", i, b, b.AverageDays, want)
+		}
+	}
+	if buckets[2].MaxPages != nil || buckets[1].MaxPages == nil || *buckets[1].MaxPages != 400 {
+		t.Errorf("Unexpected bucket bounds: %+v", buckets)
+	}
+}
+
+func TestReadingPace(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	if pace, err := store.GetReadingPace(ctx); err != nil || pace.PagesPerDay != 0 || pace.Reads != 0 {
+		t.Errorf("Expected no pace without reads, got %+v, %v", pace, err)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, r := range []struct {
+		pages, days int
+		mode        model.ReadingMode
+	}{{150, 3, model.ModeLeisure}, {199, 4, model.ModeLeisure}, {200, 10, model.ModeLeisure}, {900, 1, model.ModeReference}, {0, 5, model.ModeLeisure}} {
+		book := createTestBook()
+		book.OpenLibraryID, book.ReadingMode, book.Status = fmt.Sprintf("OL%dM", i), r.mode, model.StatusRead
+		if r.pages > 0 {
+			book.PageCount = &r.pages
+		}
+		finished := start.AddDate(0, 0, r.days)
+		book.DateStarted, book.DateFinished = &start, &finished
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+	}
+
+	pace, err := store.GetReadingPace(ctx)
+	if err != nil || pace.Reads != 3 || pace.PagesPerDay != 32.3 {
+		t.Fatalf("Expected 549 pages in 17 days, got %+v, %v", pace, err)
+	}
+
+	pages := 323
+	started := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	reading := &model.Book{Status: model.StatusCurrentlyReading, PageCount: &pages, DateStarted: &started}
+	if got := pace.EstimateFinish(reading, started); got == nil || !got.Equal(time.Date(2025, 1, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected to finish on 11 January, got %v", got)
+	}
+	late := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	if got := pace.EstimateFinish(reading, late); got == nil || !got.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected a book past its estimate to finish today, got %v", got)
+	}
+	// With progress recorded, the pages left are counted from now
+	current := 258
+	reading.CurrentPage = &current
+	if got := pace.EstimateFinish(reading, late); got == nil || !got.Equal(time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected 65 pages left to finish on 3 March, got %v", got)
+	}
+	reading.Status = model.StatusRead
+	if got := pace.EstimateFinish(reading, started); got != nil {
+		t.Errorf("Expected no estimate for a finished book, got %v", got)
+	}
+}
+
+func TestReadingStats(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	add := func(olid string, mode model.ReadingMode, bookType model.BookType, pages, rating *int, started *time.Time, finished ...time.Time) {
+		t.Helper()
+		book := createTestBook()
+		book.OpenLibraryID, book.Author, book.ReadingMode, book.Type, book.PageCount, book.Rating = olid, "Ursula K. Le Guin", mode, bookType, pages, rating
+		book.Status = model.StatusCurrentlyReading
+		book.DateStarted = started
+		if len(finished) > 0 {
+			book.Status, book.DateFinished = model.StatusRead, &finished[0]
+		}
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+		for i := 1; i < len(finished); i++ {
+			if err := store.AddRead(ctx, &model.Read{BookID: book.ID, DateFinished: finished[i]}); err != nil {
+				t.Fatalf("AddRead failed: %v", err)
+			}
+		}
+	}
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 12, 0, 0, 0, time.UTC)
+	}
+	today := time.Now().UTC()
+	ago := func(days int) *time.Time { d := today.AddDate(0, 0, -days); return &d }
+	three, two, six, eight := 300, 200, 6, 8
+
+	add("OL1M", model.ModeLeisure, model.TypeBook, &three, &eight, nil, day(2024, 3, 10), day(2024, 11, 5))
+	add("OL2M", model.ModeLeisure, model.TypeAudiobook, &two, &six, nil, day(2023, 6, 1))
+	add("OL3M", model.ModeReference, model.TypeBook, &three, &six, nil, day(2024, 5, 1))
+	// A week of reading, then a vacation and the book being read now
+	add("OL4M", model.ModeLeisure, model.TypeBook, nil, nil, ago(12), *ago(6))
+	add("OL5M", model.ModeLeisure, model.TypeBook, nil, nil, ago(2))
+
+	stats, err := store.GetReadingStats(ctx, 2024)
+	if err != nil {
+		t.Fatalf("GetReadingStats failed: %v", err)
+	}
+	if stats.Year == nil || *stats.Year != 2024 || stats.Reads != 2 || stats.Books != 1 || stats.Pages != 600 ||
+		stats.AverageRating == nil || *stats.AverageRating != 8 {
+		t.Errorf("Unexpected 2024 totals: %+v", stats)
+	}
+	if len(stats.Months) != 12 || stats.Months[2] != (model.PeriodCount{Period: "2024-03", Reads: 1, Pages: 300}) ||
+		stats.Months[10].Reads != 1 || stats.Months[0] != (model.PeriodCount{Period: "2024-01"}) {
+		t.Errorf("Unexpected 2024 months: %+v", stats.Months)
+	}
+	if len(stats.Years) != 1 || stats.Years[0] != (model.PeriodCount{Period: "2024", Reads: 2, Pages: 600}) {
+		t.Errorf("Unexpected 2024 years: %+v", stats.Years)
+	}
+	if len(stats.Types) != 1 || stats.Types[0] != (model.StatCount{Value: "book", Books: 1, Percent: 100}) ||
+		len(stats.Authors) != 1 || stats.Authors[0].Value != "Ursula K. Le Guin" {
+		t.Errorf("Unexpected 2024 breakdowns: %+v, %+v", stats.Types, stats.Authors)
+	}
+
+	stats, err = store.GetReadingStats(ctx, 0)
+	if err != nil {
+		t.Fatalf("GetReadingStats failed: %v", err)
+	}
+	if stats.Year != nil || stats.Reads != 4 || stats.Books != 3 || stats.Pages != 800 || *stats.AverageRating != 7 || len(stats.Months) != 4 ||
+		len(stats.Years) != 3 || stats.Years[0].Period != "2023" || len(stats.Types) != 2 || stats.Authors[0].Books != 3 {
+		t.Errorf("Unexpected totals: %+v", stats)
+	}
+	if stats.Streak.Current != 3 || stats.Streak.Longest != 7 {
+		t.Errorf("Expected the vacation to break the streak without one, got %+v", stats.Streak)
+	}
+
+	// Over a vacation the streak carries on
+	if err := store.AddVacation(ctx, &model.Vacation{StartDate: ago(5).Format(model.DateLayout), EndDate: ago(3).Format(model.DateLayout)}); err != nil {
+		t.Fatalf("AddVacation failed: %v", err)
+	}
+	if stats, _ := store.GetReadingStats(ctx, 0); stats.Streak.Current != 10 || stats.Streak.Longest != 10 {
+		t.Errorf("Expected the streak to carry on over the vacation, got %+v", stats.Streak)
+	}
+	if stats, _ := store.GetReadingStats(ctx, 1999); stats.Reads != 0 || stats.AverageRating != nil || len(stats.Months) != 12 || stats.Streak.Current != 10 {
+		t.Errorf("Unexpected stats for a year without reads: %+v", stats)
+	}
+}
+
+func TestCountBooksByStatus(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	for i, status := range []model.BookStatus{model.StatusRead, model.StatusRead, model.StatusWantToRead} {
+		book := createTestBook()
+		book.OpenLibraryID, book.Status = fmt.Sprintf("OL%dM", i), status
+		if _, err := store.AddBook(ctx, book); err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+	}
+	counts, err := store.CountBooksByStatus(ctx)
+	if err != nil {
+		t.Fatalf("CountBooksByStatus failed: %v", err)
+	}
+	want := map[model.BookStatus]int{model.StatusRead: 2, model.StatusWantToRead: 1, model.StatusCurrentlyReading: 0}
+	if len(counts) != len(want) {
+		t.Fatalf("Unexpected counts %v", counts)
+	}
+	for status, n := range want {
+		if counts[status] != n {
+			t.Errorf("Expected %d books %s, got %d", n, status, counts[status])
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// SyncStore defines the database operations for Hardcover/Goodreads sync.
+type SyncStore interface {
+	AddSyncAccount(ctx context.Context, account *model.SyncAccount) (int64, error)
+	GetSyncAccounts(ctx context.Context) ([]model.SyncAccount, error)
+	GetSyncAccountByID(ctx context.Context, id int64) (*model.SyncAccount, error)
+	DeleteSyncAccount(ctx context.Context, id int64) error
+	UpdateSyncAccountStatus(ctx context.Context, id int64, syncedAt time.Time, syncErr *string) error
+	GetSyncLinks(ctx context.Context, accountID int64) ([]model.SyncLink, error)
+	SaveSyncLink(ctx context.Context, link model.SyncLink) error
+	AddSyncLogEntry(ctx context.Context, entry *model.SyncLogEntry) error
+	ListSyncLog(ctx context.Context, accountID int64, limit int) ([]model.SyncLogEntry, error)
+}
+
+// AddSyncAccount inserts a linked sync account. The token must already be encrypted.
+func (s *SQLiteBookStore) AddSyncAccount(ctx context.Context, account *model.SyncAccount) (int64, error) {
+	if !account.Provider.IsValid() {
+		return 0, invalidf("invalid sync provider: %s", account.Provider)
+	}
+	if account.ConflictPolicy == "" {
+		account.ConflictPolicy = model.ConflictLocalWins
+	} else if !account.ConflictPolicy.IsValid() {
+		return 0, invalidf("invalid conflict policy: %s", account.ConflictPolicy)
+	}
+	if account.RatingMapping == "" {
+		account.RatingMapping = model.MappingProportional
+	} else if !account.RatingMapping.IsValid() {
+		return 0, invalidf("invalid rating mapping: %s", account.RatingMapping)
+	}
+	if account.RatingRounding == "" {
+		account.RatingRounding = model.RoundNearest
+	} else if !account.RatingRounding.IsValid() {
+		return 0, invalidf("invalid rating rounding: %s", account.RatingRounding)
+	}
+	if account.CreatedAt.IsZero() {
+		account.CreatedAt = time.Now().UTC()
+	}
+
+	// The encrypted token is deliberately left out of the log line
+	slog.InfoContext(ctx, "SQL: Executing AddSyncAccount query", "provider", account.Provider, "remoteUser", account.RemoteUser)
+	account.UserID = owner(ctx)
+	if err := s.DB.QueryRowContext(ctx, `INSERT INTO sync_accounts (provider, remote_user, token_encrypted, conflict_policy, rating_mapping, rating_rounding,
+            enabled, created_at, user_id)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;`,
+		account.Provider, account.RemoteUser, account.EncryptedToken, account.ConflictPolicy, account.RatingMapping, account.RatingRounding,
+		account.Enabled, account.CreatedAt, account.UserID).Scan(&account.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddSyncAccount statement failed", "error", err)
+		return 0, fmt.Errorf("failed to add sync account: %w", classify(err))
+	}
+	return account.ID, nil
+}
+
+const syncAccountColumns = `id, provider, remote_user, token_encrypted, conflict_policy, rating_mapping, rating_rounding, enabled,
+        created_at, last_synced_at, last_error, user_id`
+
+func scanSyncAccount(row rowScanner) (*model.SyncAccount, error) {
+	var a model.SyncAccount
+	var lastSynced sql.NullTime
+	var lastError sql.NullString
+	var userID sql.NullInt64
+	if err := row.Scan(&a.ID, &a.Provider, &a.RemoteUser, &a.EncryptedToken, &a.ConflictPolicy, &a.RatingMapping, &a.RatingRounding, &a.Enabled,
+		&a.CreatedAt, &lastSynced, &lastError, &userID); err != nil {
+		return nil, err
+	}
+	if lastSynced.Valid {
+		a.LastSyncedAt = &lastSynced.Time
+	}
+	if lastError.Valid {
+		a.LastError = &lastError.String
+	}
+	if userID.Valid {
+		a.UserID = &userID.Int64
+	}
+	return &a, nil
+}
+
+// GetSyncAccounts returns all linked sync accounts, including their encrypted tokens.
+func (s *SQLiteBookStore) GetSyncAccounts(ctx context.Context) ([]model.SyncAccount, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetSyncAccounts query")
+	owned, args := ownedBy(ctx, "user_id")
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+syncAccountColumns+` FROM sync_accounts WHERE `+owned+` ORDER BY id;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetSyncAccounts query failed", "error", err)
+		return nil, fmt.Errorf("failed to query sync accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []model.SyncAccount{}
+	for rows.Next() {
+		a, err := scanSyncAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sync account row: %w", err)
+		}
+		accounts = append(accounts, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sync account rows: %w", err)
+	}
+	return accounts, nil
+}
+
+// GetSyncAccountByID returns a single linked sync account.
+func (s *SQLiteBookStore) GetSyncAccountByID(ctx context.Context, id int64) (*model.SyncAccount, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetSyncAccountByID query", "id", id)
+	owned, args := ownedBy(ctx, "user_id")
+	a, err := scanSyncAccount(s.DB.QueryRowContext(ctx, `SELECT `+syncAccountColumns+` FROM sync_accounts WHERE id = ? AND `+owned+`;`,
+		append([]interface{}{id}, args...)...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("sync account with ID %d %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to scan sync account row for ID %d: %w", id, err)
+	}
+	return a, nil
+}
+
+// DeleteSyncAccount removes a sync account together with its links, log and queued matches.
+func (s *SQLiteBookStore) DeleteSyncAccount(ctx context.Context, id int64) error {
+	if _, err := s.GetSyncAccountByID(ctx, id); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing DeleteSyncAccount query", "id", id)
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sync_links WHERE account_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to delete sync links: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sync_log WHERE account_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to delete sync log: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM pending_matches WHERE source = ? AND source_ref = ?;`,
+		model.SourceTrackerSync, strconv.FormatInt(id, 10)); err != nil {
+		return fmt.Errorf("failed to delete pending matches: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM sync_accounts WHERE id = ?;`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete sync account: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("sync account with ID %d %w", id, ErrNotFound)
+	}
+	return tx.Commit()
+}
+
+// UpdateSyncAccountStatus records the outcome of the last sync of an account.
+func (s *SQLiteBookStore) UpdateSyncAccountStatus(ctx context.Context, id int64, syncedAt time.Time, syncErr *string) error {
+	slog.InfoContext(ctx, "SQL: Executing UpdateSyncAccountStatus query", "id", id)
+	_, err := s.DB.ExecContext(ctx, `UPDATE sync_accounts SET last_synced_at = ?, last_error = ? WHERE id = ?;`, syncedAt, syncErr, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing UpdateSyncAccountStatus statement failed", "error", err)
+		return fmt.Errorf("failed to update sync account status: %w", classify(err))
+	}
+	return nil
+}
+
+// GetSyncLinks returns every book linked for an account.
+func (s *SQLiteBookStore) GetSyncLinks(ctx context.Context, accountID int64) ([]model.SyncLink, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetSyncLinks query", "accountID", accountID)
+	rows, err := s.DB.QueryContext(ctx, `SELECT account_id, book_id, remote_id, status, rating FROM sync_links WHERE account_id = ?;`, accountID)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetSyncLinks query failed", "error", err)
+		return nil, fmt.Errorf("failed to query sync links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []model.SyncLink{}
+	for rows.Next() {
+		var l model.SyncLink
+		var rating sql.NullInt64
+		if err := rows.Scan(&l.AccountID, &l.BookID, &l.RemoteID, &l.Status, &rating); err != nil {
+			return nil, fmt.Errorf("failed to scan sync link row: %w", err)
+		}
+		if rating.Valid {
+			r := int(rating.Int64)
+			l.Rating = &r
+		}
+		links = append(links, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sync link rows: %w", err)
+	}
+	return links, nil
+}
+
+// SaveSyncLink inserts or replaces the link for a book. A link from another
+// book to the same remote book is replaced too.
+func (s *SQLiteBookStore) SaveSyncLink(ctx context.Context, link model.SyncLink) error {
+	slog.DebugContext(ctx, "SQL: Executing SaveSyncLink query", "accountID", link.AccountID, "bookID", link.BookID)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sync_links WHERE account_id = ? AND remote_id = ? AND book_id <> ?;`,
+		link.AccountID, link.RemoteID, link.BookID); err != nil {
+		return fmt.Errorf("failed to replace sync link: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO sync_links (account_id, book_id, remote_id, status, rating) VALUES (?, ?, ?, ?, ?)
+        ON CONFLICT(account_id, book_id) DO UPDATE SET remote_id = excluded.remote_id, status = excluded.status, rating = excluded.rating;`,
+		link.AccountID, link.BookID, link.RemoteID, link.Status, link.Rating); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing SaveSyncLink statement failed", "error", err)
+		return fmt.Errorf("failed to save sync link: %w", classify(err))
+	}
+	return tx.Commit()
+}
+
+// AddSyncLogEntry appends an entry to the sync log. OccurredAt defaults to now.
+func (s *SQLiteBookStore) AddSyncLogEntry(ctx context.Context, entry *model.SyncLogEntry) error {
+	if entry.OccurredAt.IsZero() {
+		entry.OccurredAt = time.Now().UTC()
+	}
+	if err := s.DB.QueryRowContext(ctx, `INSERT INTO sync_log (account_id, book_id, direction, message, occurred_at) VALUES (?, ?, ?, ?, ?) RETURNING id;`,
+		entry.AccountID, entry.BookID, entry.Direction, entry.Message, entry.OccurredAt).Scan(&entry.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddSyncLogEntry statement failed", "error", err)
+		return fmt.Errorf("failed to add sync log entry: %w", classify(err))
+	}
+	return nil
+}
+
+// ListSyncLog returns the most recent sync log entries, newest first.
+// An accountID of 0 returns entries for all accounts.
+func (s *SQLiteBookStore) ListSyncLog(ctx context.Context, accountID int64, limit int) ([]model.SyncLogEntry, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	query := `SELECT id, account_id, book_id, direction, message, occurred_at FROM sync_log WHERE 1 = 1`
+	args := []interface{}{}
+	if userID, ok := UserFromContext(ctx); ok {
+		query += ` AND account_id IN (SELECT id FROM sync_accounts WHERE user_id = ?)`
+		args = append(args, userID)
+	}
+	if accountID != 0 {
+		query += ` AND account_id = ?`
+		args = append(args, accountID)
+	}
+	query += ` ORDER BY occurred_at DESC, id DESC LIMIT ?;`
+	args = append(args, limit)
+	slog.InfoContext(ctx, "SQL: Executing ListSyncLog query", "accountID", accountID, "limit", limit)
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing ListSyncLog query failed", "error", err)
+		return nil, fmt.Errorf("failed to query sync log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []model.SyncLogEntry{}
+	for rows.Next() {
+		var e model.SyncLogEntry
+		var bookID sql.NullInt64
+		if err := rows.Scan(&e.ID, &e.AccountID, &bookID, &e.Direction, &e.Message, &e.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan sync log row: %w", err)
+		}
+		if bookID.Valid {
+			e.BookID = &bookID.Int64
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sync log rows: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TestSyncAccounts tests sync account, link and log persistence
+func TestSyncAccounts(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	account := &model.SyncAccount{Provider: model.ProviderGoodreads, RemoteUser: "12345", Enabled: true}
+	id, err := store.AddSyncAccount(ctx, account)
+	if err != nil {
+		t.Fatalf("AddSyncAccount failed: %v", err)
+	}
+	if account.ConflictPolicy != model.ConflictLocalWins {
+		t.Errorf("Expected default conflict policy 'local', got %s", account.ConflictPolicy)
+	}
+	if _, err := store.AddSyncAccount(ctx, &model.SyncAccount{Provider: "librarything"}); err == nil {
+		t.Error("Expected error for invalid provider")
+	}
+
+	syncErr := "boom"
+	if err := store.UpdateSyncAccountStatus(ctx, id, time.Now().UTC(), &syncErr); err != nil {
+		t.Fatalf("UpdateSyncAccountStatus failed: %v", err)
+	}
+	got, err := store.GetSyncAccountByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSyncAccountByID failed: %v", err)
+	}
+	if got.LastSyncedAt == nil || got.LastError == nil || *got.LastError != "boom" {
+		t.Errorf("Sync status not recorded: %+v", got)
+	}
+
+	rating := 8
+	link := model.SyncLink{AccountID: id, BookID: 1, RemoteID: "gr-1", Status: model.StatusRead, Rating: &rating}
+	if err := store.SaveSyncLink(ctx, link); err != nil {
+		t.Fatalf("SaveSyncLink failed: %v", err)
+	}
+	link.Status = model.StatusCurrentlyReading
+	link.Rating = nil
+	if err := store.SaveSyncLink(ctx, link); err != nil {
+		t.Fatalf("SaveSyncLink (replace) failed: %v", err)
+	}
+	links, err := store.GetSyncLinks(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSyncLinks failed: %v", err)
+	}
+	if len(links) != 1 || links[0].Status != model.StatusCurrentlyReading || links[0].Rating != nil {
+		t.Errorf("Unexpected links: %+v", links)
+	}
+
+	bookID := int64(1)
+	for _, msg := range []string{"first", "second"} {
+		if err := store.AddSyncLogEntry(ctx, &model.SyncLogEntry{AccountID: id, BookID: &bookID, Direction: model.SyncPull, Message: msg}); err != nil {
+			t.Fatalf("AddSyncLogEntry failed: %v", err)
+		}
+	}
+	entries, err := store.ListSyncLog(ctx, id, 10)
+	if err != nil {
+		t.Fatalf("ListSyncLog failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Message != "second" {
+		t.Errorf("Expected newest log entry first, got %+v", entries)
+	}
+
+	if err := store.DeleteSyncAccount(ctx, id); err != nil {
+		t.Fatalf("DeleteSyncAccount failed: %v", err)
+	}
+	if links, _ := store.GetSyncLinks(ctx, id); len(links) != 0 {
+		t.Errorf("Expected links to be deleted with the account, got %d", len(links))
+	}
+	if entries, _ := store.ListSyncLog(ctx, 0, 10); len(entries) != 0 {
+		t.Errorf("Expected log to be deleted with the account, got %d", len(entries))
+	}
+	if err := store.DeleteSyncAccount(ctx, id); err == nil {
+		t.Error("Expected not found error when deleting twice")
+	}
+}
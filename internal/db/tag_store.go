@@ -0,0 +1,222 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TagStore defines the database operations for tags and the books they label.
+type TagStore interface {
+	GetTags(ctx context.Context) ([]model.Tag, error)
+	RenameTag(ctx context.Context, id int64, name string) error
+	DeleteTag(ctx context.Context, id int64) error
+	GetBookTags(ctx context.Context, bookID int64) ([]model.Tag, error)
+	GetTagNamesByBook(ctx context.Context) (map[int64][]string, error)
+	AddBookTag(ctx context.Context, bookID int64, name string) (*model.Tag, error)
+	RemoveBookTag(ctx context.Context, bookID, tagID int64) error
+}
+
+// tagName normalizes and validates a tag name.
+func tagName(name string) (string, error) {
+	name = model.NormalizeTagName(name)
+	if err := model.ValidateTagName(name); err != nil {
+		return "", &storeError{kind: ErrValidation, msg: err.Error(), cause: err}
+	}
+	return name, nil
+}
+
+// queryTags runs a query selecting id, name and book count.
+func (s *SQLiteBookStore) queryTags(ctx context.Context, query string, args ...interface{}) ([]model.Tag, error) {
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing tag query failed", "error", err)
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []model.Tag{}
+	for rows.Next() {
+		var t model.Tag
+		if err := rows.Scan(&t.ID, &t.Name, &t.BookCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag row: %w", err)
+		}
+		tags = append(tags, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag rows: %w", err)
+	}
+	return tags, nil
+}
+
+// GetTags returns every tag with the number of books it is on, in name order.
+// Tags no longer on any book are included with a count of zero; books in the
+// trash aren't counted.
+func (s *SQLiteBookStore) GetTags(ctx context.Context) ([]model.Tag, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetTags query")
+	owned, args := ownedBy(ctx, "tags.user_id")
+	return s.queryTags(ctx, `SELECT tags.id, tags.name, COUNT(books.id) FROM tags
+        LEFT JOIN book_tags ON book_tags.tag_id = tags.id
+        LEFT JOIN books ON books.id = book_tags.book_id AND books.deleted_at IS NULL
+        WHERE `+owned+` GROUP BY tags.id ORDER BY tags.name, tags.id;`, args...)
+}
+
+// RenameTag changes a tag's name. Renaming to the name of another tag is a
+// conflict; changing only the case of a name is allowed.
+func (s *SQLiteBookStore) RenameTag(ctx context.Context, id int64, name string) error {
+	name, err := tagName(name)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing RenameTag query", "id", id, "name", name)
+	owned, args := ownedBy(ctx, "user_id")
+	res, err := s.DB.ExecContext(ctx, `UPDATE tags SET name = ? WHERE id = ? AND `+owned+`;`, append([]interface{}{name, id}, args...)...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing RenameTag statement failed", "error", err)
+		return fmt.Errorf("failed to rename tag: %w", classify(err))
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("tag with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// DeleteTag removes a tag from every book and then deletes it.
+func (s *SQLiteBookStore) DeleteTag(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "SQL: Executing DeleteTag query", "id", id)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	owned, args := ownedBy(ctx, "user_id")
+	res, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id = ? AND `+owned+`;`, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("tag with ID %d %w", id, ErrNotFound)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM book_tags WHERE tag_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to untag books: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetBookTags returns the tags on a book, in name order.
+func (s *SQLiteBookStore) GetBookTags(ctx context.Context, bookID int64) ([]model.Tag, error) {
+	if _, err := s.GetBookByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "SQL: Executing GetBookTags query", "bookID", bookID)
+	return s.queryTags(ctx, `SELECT tags.id, tags.name, (SELECT COUNT(*) FROM book_tags counted WHERE counted.tag_id = tags.id)
+        FROM book_tags JOIN tags ON tags.id = book_tags.tag_id
+        WHERE book_tags.book_id = ? ORDER BY tags.name, tags.id;`, bookID)
+}
+
+// GetTagNamesByBook returns the names of the tags on every book, keyed by
+// book ID and in name order. Untagged books are left out.
+func (s *SQLiteBookStore) GetTagNamesByBook(ctx context.Context) (map[int64][]string, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetTagNamesByBook query")
+	owned, args := ownedBy(ctx, "tags.user_id")
+	rows, err := s.DB.QueryContext(ctx, `SELECT book_tags.book_id, tags.name
+        FROM book_tags JOIN tags ON tags.id = book_tags.tag_id
+        WHERE `+owned+` ORDER BY book_tags.book_id, tags.name, tags.id;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetTagNamesByBook query failed", "error", err)
+		return nil, fmt.Errorf("failed to query book tags: %w", err)
+	}
+	defer rows.Close()
+
+	names := make(map[int64][]string)
+	for rows.Next() {
+		var bookID int64
+		var name string
+		if err := rows.Scan(&bookID, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan book tag row: %w", err)
+		}
+		names[bookID] = append(names[bookID], name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating book tag rows: %w", err)
+	}
+	return names, nil
+}
+
+// AddBookTag puts the tag called name on a book, creating the tag if the
+// book's owner has no tag with that name yet (ignoring case). Tagging a book
+// twice is not an error.
+func (s *SQLiteBookStore) AddBookTag(ctx context.Context, bookID int64, name string) (*model.Tag, error) {
+	name, err := tagName(name)
+	if err != nil {
+		return nil, err
+	}
+	book, err := s.GetBookByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	var ownerID int64 // Books without an owner share tags without one
+	if book.UserID != nil {
+		ownerID = *book.UserID
+	}
+	slog.InfoContext(ctx, "SQL: Executing AddBookTag query", "bookID", bookID, "name", name)
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO tags (user_id, name, created_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING;`,
+		book.UserID, name, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("failed to add tag: %w", classify(err))
+	}
+	tag := &model.Tag{}
+	if err := tx.QueryRowContext(ctx, `SELECT id, name FROM tags WHERE name = ? AND COALESCE(user_id, 0) = ?;`,
+		name, ownerID).Scan(&tag.ID, &tag.Name); err != nil {
+		return nil, fmt.Errorf("failed to look up tag: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO book_tags (book_id, tag_id) VALUES (?, ?) ON CONFLICT DO NOTHING;`, bookID, tag.ID); err != nil {
+		return nil, fmt.Errorf("failed to tag book: %w", classify(err))
+	}
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM book_tags WHERE tag_id = ?;`, tag.ID).Scan(&tag.BookCount); err != nil {
+		return nil, fmt.Errorf("failed to count tagged books: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit tag: %w", err)
+	}
+	return tag, nil
+}
+
+// RemoveBookTag takes a tag off a book. The tag itself is kept, even when no
+// book has it any more.
+func (s *SQLiteBookStore) RemoveBookTag(ctx context.Context, bookID, tagID int64) error {
+	if _, err := s.GetBookByID(ctx, bookID); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing RemoveBookTag query", "bookID", bookID, "tagID", tagID)
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM book_tags WHERE book_id = ? AND tag_id = ?;`, bookID, tagID)
+	if err != nil {
+		return fmt.Errorf("failed to untag book: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("tag %d on book with ID %d %w", tagID, bookID, ErrNotFound)
+	}
+	return nil
+}
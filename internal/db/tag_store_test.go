@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestBookTags(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	dune := createTestBook()
+	dune.Title, dune.OpenLibraryID = "Dune", "OL1M"
+	hobbit := createTestBook()
+	hobbit.Title, hobbit.OpenLibraryID = "The Hobbit", "OL2M"
+	for _, b := range []*model.Book{dune, hobbit} {
+		if _, err := store.AddBook(ctx, b); err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+	}
+
+	sf, err := store.AddBookTag(ctx, dune.ID, "  Science   Fiction ")
+	if err != nil {
+		t.Fatalf("AddBookTag failed: %v", err)
+	}
+	if sf.Name != "Science Fiction" || sf.BookCount != 1 {
+		t.Errorf("Unexpected tag %+v", sf)
+	}
+	// Same tag in another case, and tagging twice, reuse the tag
+	if again, err := store.AddBookTag(ctx, dune.ID, "science fiction"); err != nil || again.ID != sf.ID || again.BookCount != 1 {
+		t.Errorf("Re-tagging: got %+v, %v", again, err)
+	}
+	if _, err := store.AddBookTag(ctx, hobbit.ID, "Fantasy"); err != nil {
+		t.Fatalf("AddBookTag failed: %v", err)
+	}
+	if _, err := store.AddBookTag(ctx, dune.ID, "classic"); err != nil {
+		t.Fatalf("AddBookTag failed: %v", err)
+	}
+	if _, err := store.AddBookTag(ctx, hobbit.ID, "Classic"); err != nil {
+		t.Fatalf("AddBookTag failed: %v", err)
+	}
+
+	tags, err := store.GetTags(ctx)
+	if err != nil {
+		t.Fatalf("GetTags failed: %v", err)
+	}
+	want := []model.Tag{{Name: "classic", BookCount: 2}, {Name: "Fantasy", BookCount: 1}, {Name: "Science Fiction", BookCount: 1}}
+	if len(tags) != len(want) {
+		t.Fatalf("Expected %d tags, got %+v", len(want), tags)
+	}
+	for i := range want {
+		if tags[i].Name != want[i].Name || tags[i].BookCount != want[i].BookCount {
+			t.Errorf("Tag %d: got %+v, want %+v", i, tags[i], want[i])
+		}
+	}
+
+	duneTags, err := store.GetBookTags(ctx, dune.ID)
+	if err != nil || len(duneTags) != 2 || duneTags[0].Name != "classic" || duneTags[0].BookCount != 2 {
+		t.Errorf("GetBookTags: got %+v, %v", duneTags, err)
+	}
+
+	books, _, err := store.GetBooksPage(ctx, ListOptions{Filter: BookFilter{Tag: "CLASSIC"}})
+	if err != nil || len(books) != 2 {
+		t.Errorf("Filtering by tag: got %d books, %v", len(books), err)
+	}
+	books, _, err = store.GetBooksPage(ctx, ListOptions{Filter: BookFilter{Tag: "science fiction"}})
+	if err != nil || len(books) != 1 || books[0].ID != dune.ID {
+		t.Errorf("Filtering by tag: got %+v, %v", books, err)
+	}
+
+	// Rename, including to a different case of the same name, but not onto another tag
+	if err := store.RenameTag(ctx, sf.ID, "SF"); err != nil {
+		t.Errorf("RenameTag failed: %v", err)
+	}
+	if err := store.RenameTag(ctx, sf.ID, "sf"); err != nil {
+		t.Errorf("RenameTag to a different case failed: %v", err)
+	}
+	if err := store.RenameTag(ctx, sf.ID, "Fantasy"); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Renaming onto an existing tag: expected ErrDuplicate, got %v", err)
+	}
+
+	for name, err := range map[string]error{
+		"missing book":       func() error { _, err := store.AddBookTag(ctx, 999, "x"); return err }(),
+		"empty name":         func() error { _, err := store.AddBookTag(ctx, dune.ID, "   "); return err }(),
+		"remove missing tag": store.RemoveBookTag(ctx, hobbit.ID, sf.ID),
+		"delete missing tag": store.DeleteTag(ctx, 999),
+	} {
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if err := store.RemoveBookTag(ctx, dune.ID, sf.ID); err != nil {
+		t.Errorf("RemoveBookTag failed: %v", err)
+	}
+	if err := store.DeleteBook(ctx, hobbit.ID); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	tags, _ = store.GetTags(ctx)
+	counts := map[string]int{}
+	for _, tag := range tags {
+		counts[tag.Name] = tag.BookCount
+	}
+	if counts["sf"] != 0 || counts["classic"] != 1 || counts["Fantasy"] != 0 || len(tags) != 3 {
+		t.Errorf("Unexpected counts after untagging and deleting: %v", counts)
+	}
+
+	classic := tags[0]
+	if err := store.DeleteTag(ctx, classic.ID); err != nil {
+		t.Fatalf("DeleteTag failed: %v", err)
+	}
+	if duneTags, _ := store.GetBookTags(ctx, dune.ID); len(duneTags) != 0 {
+		t.Errorf("Deleted tag still on book: %+v", duneTags)
+	}
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TitleStore defines the maintenance operations on book titles.
+type TitleStore interface {
+	SplitSubtitles(ctx context.Context) (SubtitleSplit, error)
+}
+
+// SubtitleSplit reports the outcome of splitting subtitles out of stored titles.
+type SubtitleSplit struct {
+	Checked int `json:"checked"` // Books without a subtitle
+	Split   int `json:"split"`   // Titles a subtitle was moved out of
+	// Changes lists each change in a dry run
+	Changes []BookChange `json:"changes,omitempty"`
+}
+
+// SplitSubtitles moves subtitles written into the titles of books added
+// before subtitles were kept separately, as AddBook does for new books.
+// Changed books count as updated for differential exports.
+func (s *SQLiteBookStore) SplitSubtitles(ctx context.Context) (SubtitleSplit, error) {
+	var report SubtitleSplit
+	slog.InfoContext(ctx, "SQL: Executing SplitSubtitles query")
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, title FROM books WHERE subtitle IS NULL;`)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing SplitSubtitles query failed", "error", err)
+		return report, fmt.Errorf("failed to query titles: %w", err)
+	}
+	changed := make(map[int64]model.Book)
+	titles := make(map[int64]string)
+	for rows.Next() {
+		var book model.Book
+		if err := rows.Scan(&book.ID, &book.Title); err != nil {
+			rows.Close()
+			return report, fmt.Errorf("failed to scan title row: %w", err)
+		}
+		report.Checked++
+		title := book.Title
+		if book.SplitSubtitle() {
+			changed[book.ID] = book
+			titles[book.ID] = title
+			if IsDryRun(ctx) {
+				report.Changes = append(report.Changes,
+					BookChange{BookID: book.ID, Field: "title", From: &title, To: &book.Title},
+					BookChange{BookID: book.ID, Field: "subtitle", To: book.Subtitle})
+			}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return report, fmt.Errorf("error iterating title rows: %w", err)
+	}
+	if len(changed) == 0 {
+		return report, nil
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return report, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	now := time.Now().UTC()
+	for id, book := range changed {
+		if _, err := tx.ExecContext(ctx, `UPDATE books SET title = ?, subtitle = ?, updated_at = ? WHERE id = ?;`,
+			book.Title, book.Subtitle, now, id); err != nil {
+			return report, fmt.Errorf("failed to update title of book %d: %w", id, err)
+		}
+		if err := recordBookUpdate(ctx, tx, &model.Book{ID: id, Title: titles[id]}, &book); err != nil {
+			return report, err
+		}
+	}
+	if err := commit(ctx, tx); err != nil {
+		return report, fmt.Errorf("failed to commit titles: %w", err)
+	}
+	report.Split = len(changed)
+	slog.InfoContext(ctx, "SQL: Split book subtitles", "checked", report.Checked, "split", report.Split)
+	return report, nil
+}
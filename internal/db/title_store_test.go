@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSplitSubtitles(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	book.Title = "Dune: Deluxe Edition"
+	if _, err := store.AddBook(ctx, book); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	got, _ := store.GetBookByID(ctx, book.ID)
+	if got.Title != "Dune" || got.Subtitle == nil || *got.Subtitle != "Deluxe Edition" {
+		t.Fatalf("Expected the subtitle split on add, got %q / %v", got.Title, got.Subtitle)
+	}
+
+	// Rows stored before subtitles existed are fixed by the backfill
+	legacy := createTestBook()
+	legacy.OpenLibraryID = "OL3M"
+	if _, err := store.AddBook(ctx, legacy); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE books SET title = ? WHERE id = ?;`, "Sapiens: A Brief History of Humankind", legacy.ID); err != nil {
+		t.Fatalf("Failed to store a legacy title: %v", err)
+	}
+
+	// A dry run lists the change without making it
+	report, err := store.SplitSubtitles(WithDryRun(ctx))
+	if err != nil || report.Split != 1 || len(report.Changes) != 2 || *report.Changes[0].To != "Sapiens" || *report.Changes[1].To != "A Brief History of Humankind" {
+		t.Fatalf("Expected a dry run to report the split, got %+v, %v", report, err)
+	}
+	if got, _ := store.GetBookByID(ctx, legacy.ID); got.Subtitle != nil {
+		t.Fatalf("Expected a dry run to change nothing, got subtitle %q", *got.Subtitle)
+	}
+
+	report, err = store.SplitSubtitles(ctx)
+	if err != nil {
+		t.Fatalf("SplitSubtitles failed: %v", err)
+	}
+	if report.Changes != nil {
+		t.Errorf("Expected changes to be listed only in a dry run, got %+v", report.Changes)
+	}
+	if report.Checked != 1 || report.Split != 1 {
+		t.Errorf("Expected 1 checked and 1 split, got %+v", report)
+	}
+	if got, _ := store.GetBookByID(ctx, legacy.ID); got.Title != "Sapiens" || got.Subtitle == nil || *got.Subtitle != "A Brief History of Humankind" {
+		t.Errorf("Unexpected backfilled title %q / %v", got.Title, got.Subtitle)
+	}
+	// The search index covers the subtitle
+	if found, err := store.SearchBooks(ctx, "humankind"); err != nil || len(found) != 1 || found[0].ID != legacy.ID {
+		t.Errorf("Expected the subtitle to be searchable, got %v, %v", found, err)
+	}
+
+	if report, err := store.SplitSubtitles(ctx); err != nil || report.Split != 0 {
+		t.Errorf("Splitting twice: got %+v, %v", report, err)
+	}
+
+	// An empty subtitle keeps the title whole
+	whole := createTestBook()
+	whole.OpenLibraryID = "OL4M"
+	whole.Title = "Star Wars: Thrawn"
+	empty := ""
+	whole.Subtitle = &empty
+	if _, err := store.AddBook(ctx, whole); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if got, _ := store.GetBookByID(ctx, whole.ID); got.Title != "Star Wars: Thrawn" || got.Subtitle != nil {
+		t.Errorf("Expected the title kept whole, got %q / %v", got.Title, got.Subtitle)
+	}
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TrashStore defines the database operations for the trash, where DeleteBook
+// moves books until they are restored or purged.
+type TrashStore interface {
+	GetTrash(ctx context.Context) ([]model.Book, error)
+	RestoreBook(ctx context.Context, id int64) error
+	PurgeBook(ctx context.Context, id int64) error
+}
+
+// getTrashedBook returns a book in the trash of the user ctx is scoped to.
+func getTrashedBook(ctx context.Context, db execer, id int64) (*model.Book, error) {
+	owned, args := ownedBy(ctx, "user_id")
+	book, err := scanBook(db.QueryRowContext(ctx, `SELECT `+bookColumns+` FROM books WHERE id = ? AND deleted_at IS NOT NULL AND `+owned+`;`,
+		append([]interface{}{id}, args...)...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("book with ID %d in the trash %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book: %w", err)
+	}
+	return book, nil
+}
+
+// GetTrash returns the books in the trash, most recently deleted first.
+func (s *SQLiteBookStore) GetTrash(ctx context.Context) ([]model.Book, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetTrash query")
+	owned, args := ownedBy(ctx, "user_id")
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+bookColumns+` FROM books WHERE deleted_at IS NOT NULL AND `+owned+`
+        ORDER BY deleted_at DESC, id DESC;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetTrash query failed", "error", err)
+		return nil, fmt.Errorf("failed to query trash: %w", err)
+	}
+	defer rows.Close()
+
+	books := []model.Book{}
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan book row: %w", err)
+		}
+		books = append(books, *book)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating book rows: %w", err)
+	}
+	return books, nil
+}
+
+// RestoreBook takes a book out of the trash and puts it back on the
+// bookshelf with its tags and reading history. Its tombstone is removed and
+// it counts as changed, so differential exports report it again. It is no
+// longer part of the bulk deletion that put it in the trash, if any.
+func (s *SQLiteBookStore) RestoreBook(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "SQL: Executing RestoreBook statement", "id", id)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := getTrashedBook(ctx, tx, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE books SET deleted_at = NULL, bulk_deletion_id = NULL, updated_at = ? WHERE id = ?;`, time.Now().UTC(), id); err != nil {
+		return fmt.Errorf("failed to restore book: %w", classify(err))
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM book_tombstones WHERE book_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to remove book tombstone: %w", err)
+	}
+	if err := recordBookEvent(ctx, tx, &model.BookEvent{BookID: id, Kind: model.EventRestored}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PurgeBook permanently deletes a book in the trash, with its tags and
+// reading history. Books on the bookshelf must go to the trash first.
+func (s *SQLiteBookStore) PurgeBook(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "SQL: Executing PurgeBook statement", "id", id)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	book, err := getTrashedBook(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if err := recordBookEvent(ctx, tx, &model.BookEvent{BookID: id, Kind: model.EventPurged}); err != nil {
+		return err
+	}
+	if err := deleteBook(ctx, tx, book); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
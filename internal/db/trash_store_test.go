@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTrash(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	if _, err := store.AddBook(ctx, book); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if _, err := store.AddBookTag(ctx, book.ID, "favourites"); err != nil {
+		t.Fatalf("AddBookTag failed: %v", err)
+	}
+	if err := store.DeleteBook(ctx, book.ID); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+
+	if books, _ := store.GetBooks(ctx); len(books) != 0 {
+		t.Errorf("Expected the deleted book off the bookshelf, got %+v", books)
+	}
+	if tags, _ := store.GetTags(ctx); len(tags) != 1 || tags[0].BookCount != 0 {
+		t.Errorf("Expected books in the trash not to be counted, got %+v", tags)
+	}
+	trash, err := store.GetTrash(ctx)
+	if err != nil || len(trash) != 1 || trash[0].ID != book.ID || trash[0].DeletedAt == nil {
+		t.Fatalf("Expected the book in the trash, got %+v, %v", trash, err)
+	}
+	if tombstones, _ := store.GetTombstonesSince(ctx, time.Time{}); len(tombstones) != 1 {
+		t.Errorf("Expected a tombstone for the deleted book, got %+v", tombstones)
+	}
+	if err := store.DeleteBook(ctx, book.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting a book in the trash, got %v", err)
+	}
+
+	// Adding the book again points at the trash
+	again := createTestBook()
+	again.ISBN = book.ISBN
+	var dup *DuplicateBookError
+	if _, err := store.AddBook(ctx, again); !errors.As(err, &dup) || !dup.Trashed || dup.ExistingID != book.ID {
+		t.Errorf("Expected a duplicate in the trash, got %v", err)
+	}
+
+	if err := store.RestoreBook(ctx, book.ID); err != nil {
+		t.Fatalf("RestoreBook failed: %v", err)
+	}
+	restored, err := store.GetBookByID(ctx, book.ID)
+	if err != nil || restored.DeletedAt != nil {
+		t.Fatalf("Expected the book back on the bookshelf, got %+v, %v", restored, err)
+	}
+	if tags, _ := store.GetBookTags(ctx, book.ID); len(tags) != 1 {
+		t.Errorf("Expected the restored book to keep its tags, got %+v", tags)
+	}
+	if tombstones, _ := store.GetTombstonesSince(ctx, time.Time{}); len(tombstones) != 0 {
+		t.Errorf("Expected the restored book's tombstone to be removed, got %+v", tombstones)
+	}
+	if err := store.RestoreBook(ctx, book.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound restoring a book on the bookshelf, got %v", err)
+	}
+	if err := store.PurgeBook(ctx, book.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound purging a book on the bookshelf, got %v", err)
+	}
+
+	if err := store.DeleteBook(ctx, book.ID); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if err := store.PurgeBook(ctx, book.ID); err != nil {
+		t.Fatalf("PurgeBook failed: %v", err)
+	}
+	if trash, _ := store.GetTrash(ctx); len(trash) != 0 {
+		t.Errorf("Expected the trash to be empty after purging, got %+v", trash)
+	}
+	if tombstones, _ := store.GetTombstonesSince(ctx, time.Time{}); len(tombstones) != 1 {
+		t.Errorf("Expected a tombstone for the purged book, got %+v", tombstones)
+	}
+	if err := store.RestoreBook(ctx, book.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound restoring a purged book, got %v", err)
+	}
+	if _, err := store.AddBook(ctx, again); err != nil {
+		t.Errorf("Expected a purged book to be added again, got %v", err)
+	}
+}
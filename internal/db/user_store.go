@@ -0,0 +1,310 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// UserStore defines the database operations for accounts, their login
+// sessions and their API keys.
+type UserStore interface {
+	AddUser(ctx context.Context, user *model.User) error
+	GetUserByID(ctx context.Context, id int64) (*model.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*model.User, error)
+	CountUsers(ctx context.Context) (int, error)
+	FirstUserID(ctx context.Context) (int64, error)
+	SetPublicFeed(ctx context.Context, userID int64, public bool) error
+	CreateSession(ctx context.Context, userID int64, token string, expiresAt time.Time) error
+	GetSessionUser(ctx context.Context, token string) (*model.User, error)
+	DeleteSession(ctx context.Context, token string) error
+	AddAPIKey(ctx context.Context, key *model.APIKey, secret string) error
+	GetAPIKeys(ctx context.Context) ([]model.APIKey, error)
+	GetAPIKeyUser(ctx context.Context, secret string) (*model.User, error)
+	DeleteAPIKey(ctx context.Context, id int64) error
+}
+
+type userContextKey struct{}
+
+// WithUser returns a copy of ctx that scopes store methods to the library of
+// the user with ID userID: they only see and change that user's books, tags,
+// vacations and linked accounts, and what they add belongs to the user.
+// Without a user, as in background jobs, store methods act on every library.
+func WithUser(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, userContextKey{}, userID)
+}
+
+// UserFromContext returns the ID of the user ctx is scoped to, if any.
+func UserFromContext(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(userContextKey{}).(int64)
+	return id, ok
+}
+
+// ownedBy returns a condition limiting rows to those of the user ctx is
+// scoped to, given the column holding their owner, with its arguments.
+// Without a user the condition matches every row.
+func ownedBy(ctx context.Context, column string) (string, []interface{}) {
+	if id, ok := UserFromContext(ctx); ok {
+		return column + " = ?", []interface{}{id}
+	}
+	return "1 = 1", nil
+}
+
+// owner returns the owner of rows added in ctx: its user, or nil for none.
+func owner(ctx context.Context) *int64 {
+	if id, ok := UserFromContext(ctx); ok {
+		return &id
+	}
+	return nil
+}
+
+// tokenHash returns the hash by which a session token or API key is stored,
+// so the secrets themselves are never kept.
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// AddUser stores a new user and sets its ID and creation time. The first user
+// adopts the library from before there were accounts: every book, tag,
+// vacation, linked account and import without an owner.
+func (s *SQLiteBookStore) AddUser(ctx context.Context, user *model.User) error {
+	if err := model.ValidateUsername(user.Username); err != nil {
+		return &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
+	}
+	if user.PasswordHash == "" {
+		return invalidf("a password is required")
+	}
+	slog.InfoContext(ctx, "SQL: Executing AddUser query", "username", user.Username)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	user.CreatedAt = time.Now().UTC()
+	if err := tx.QueryRowContext(ctx, `INSERT INTO users (username, password_hash, created_at) VALUES (?, ?, ?) RETURNING id;`,
+		user.Username, user.PasswordHash, user.CreatedAt).Scan(&user.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddUser statement failed", "error", err)
+		return fmt.Errorf("failed to add user: %w", classify(err))
+	}
+	var users int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM users;`).Scan(&users); err != nil {
+		return fmt.Errorf("failed to count users: %w", err)
+	}
+	if users == 1 {
+		for _, table := range []string{"books", "tags", "book_tombstones", "vacations", "sync_accounts", "crosspost_accounts", "import_batches", "book_merges", "book_events", "shelf_presets", "reading_goals", "bulk_deletions", "disposals", "collections", "webhooks", "share_links", "follows", "activities", "pending_matches", "fediverse_followers"} {
+			if _, err := tx.ExecContext(ctx, `UPDATE `+table+` SET user_id = ? WHERE user_id IS NULL;`, user.ID); err != nil {
+				return fmt.Errorf("failed to give %s to the first user: %w", table, err)
+			}
+		}
+		slog.InfoContext(ctx, "SQL: First user adopted the existing library", "id", user.ID)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user: %w", err)
+	}
+	return nil
+}
+
+// getUser returns the user the query, selecting a user's columns, finds.
+func (s *SQLiteBookStore) getUser(ctx context.Context, query string, args ...interface{}) (*model.User, error) {
+	var user model.User
+	err := s.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.CreatedAt, &user.PublicFeed)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByID returns the user with the given ID.
+func (s *SQLiteBookStore) GetUserByID(ctx context.Context, id int64) (*model.User, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetUserByID query", "id", id)
+	user, err := s.getUser(ctx, `SELECT id, username, password_hash, created_at, public_feed FROM users WHERE id = ?;`, id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user %d: %w", id, err)
+	}
+	return user, nil
+}
+
+// GetUserByUsername returns the user with the given username, ignoring case.
+func (s *SQLiteBookStore) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetUserByUsername query", "username", username)
+	user, err := s.getUser(ctx, `SELECT id, username, password_hash, created_at, public_feed FROM users WHERE username = ?;`, username)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user %q %w", username, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user %q: %w", username, err)
+	}
+	return user, nil
+}
+
+// CountUsers returns the number of users.
+func (s *SQLiteBookStore) CountUsers(ctx context.Context) (int, error) {
+	var users int
+	if err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM users;`).Scan(&users); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return users, nil
+}
+
+// FirstUserID returns the ID of the first user registered, the one that
+// adopted the library from before there were accounts.
+func (s *SQLiteBookStore) FirstUserID(ctx context.Context) (int64, error) {
+	var id sql.NullInt64
+	if err := s.DB.QueryRowContext(ctx, `SELECT MIN(id) FROM users;`).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to find the first user: %w", err)
+	}
+	if !id.Valid {
+		return 0, fmt.Errorf("first user %w", ErrNotFound)
+	}
+	return id.Int64, nil
+}
+
+// SetPublicFeed sets whether the activity feed of the user with ID userID is
+// public.
+func (s *SQLiteBookStore) SetPublicFeed(ctx context.Context, userID int64, public bool) error {
+	slog.InfoContext(ctx, "SQL: Executing SetPublicFeed query", "user", userID, "public", public)
+	res, err := s.DB.ExecContext(ctx, `UPDATE users SET public_feed = ? WHERE id = ?;`, public, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing SetPublicFeed statement failed", "error", err)
+		return fmt.Errorf("failed to set public feed: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with ID %d %w", userID, ErrNotFound)
+	}
+	return nil
+}
+
+// CreateSession starts a login session for a user, identified by token until
+// expiresAt.
+func (s *SQLiteBookStore) CreateSession(ctx context.Context, userID int64, token string, expiresAt time.Time) error {
+	slog.InfoContext(ctx, "SQL: Executing CreateSession query", "user", userID)
+	if _, err := s.DB.ExecContext(ctx, `INSERT INTO sessions (token_hash, user_id, created_at, expires_at) VALUES (?, ?, ?, ?);`,
+		tokenHash(token), userID, time.Now().UTC(), expiresAt.UTC()); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing CreateSession statement failed", "error", err)
+		return fmt.Errorf("failed to create session: %w", classify(err))
+	}
+	return nil
+}
+
+// GetSessionUser returns the user logged in with token. Unknown and expired
+// sessions are not found.
+func (s *SQLiteBookStore) GetSessionUser(ctx context.Context, token string) (*model.User, error) {
+	user, err := s.getUser(ctx, `SELECT users.id, users.username, users.password_hash, users.created_at, users.public_feed
+        FROM sessions JOIN users ON users.id = sessions.user_id WHERE sessions.token_hash = ? AND sessions.expires_at > ?;`,
+		tokenHash(token), time.Now().UTC())
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return user, nil
+}
+
+// DeleteSession ends the session identified by token, along with any expired
+// sessions.
+func (s *SQLiteBookStore) DeleteSession(ctx context.Context, token string) error {
+	slog.InfoContext(ctx, "SQL: Executing DeleteSession query")
+	if _, err := s.DB.ExecContext(ctx, `DELETE FROM sessions WHERE token_hash = ? OR expires_at <= ?;`,
+		tokenHash(token), time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// AddAPIKey stores a key for key.UserID, identified by secret, and sets its
+// ID and creation time.
+func (s *SQLiteBookStore) AddAPIKey(ctx context.Context, key *model.APIKey, secret string) error {
+	if key.Name == "" {
+		return invalidf("API key name is required")
+	}
+	slog.InfoContext(ctx, "SQL: Executing AddAPIKey query", "user", key.UserID, "name", key.Name)
+	key.CreatedAt = time.Now().UTC()
+	if err := s.DB.QueryRowContext(ctx, `INSERT INTO api_keys (user_id, name, prefix, key_hash, created_at) VALUES (?, ?, ?, ?, ?) RETURNING id;`,
+		key.UserID, key.Name, key.Prefix, tokenHash(secret), key.CreatedAt).Scan(&key.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddAPIKey statement failed", "error", err)
+		return fmt.Errorf("failed to add API key: %w", classify(err))
+	}
+	return nil
+}
+
+// GetAPIKeys returns the API keys, newest first.
+func (s *SQLiteBookStore) GetAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetAPIKeys query")
+	owned, args := ownedBy(ctx, "user_id")
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, user_id, name, prefix, created_at, last_used_at FROM api_keys WHERE `+owned+`
+        ORDER BY created_at DESC, id DESC;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetAPIKeys query failed", "error", err)
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []model.APIKey{}
+	for rows.Next() {
+		var key model.APIKey
+		var lastUsed sql.NullTime
+		if err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.CreatedAt, &lastUsed); err != nil {
+			return nil, fmt.Errorf("failed to scan API key row: %w", err)
+		}
+		if lastUsed.Valid {
+			key.LastUsedAt = &lastUsed.Time
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating API key rows: %w", err)
+	}
+	return keys, nil
+}
+
+// GetAPIKeyUser returns the user whose API key secret is, and records that
+// the key was used. Unknown and revoked keys are not found.
+func (s *SQLiteBookStore) GetAPIKeyUser(ctx context.Context, secret string) (*model.User, error) {
+	hash := tokenHash(secret)
+	user, err := s.getUser(ctx, `SELECT users.id, users.username, users.password_hash, users.created_at, users.public_feed
+        FROM api_keys JOIN users ON users.id = api_keys.user_id WHERE api_keys.key_hash = ?;`, hash)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("API key %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	if _, err := s.DB.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE key_hash = ?;`, time.Now().UTC(), hash); err != nil {
+		slog.WarnContext(ctx, "Failed to record API key use", "error", err)
+	}
+	return user, nil
+}
+
+// DeleteAPIKey revokes an API key.
+func (s *SQLiteBookStore) DeleteAPIKey(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "SQL: Executing DeleteAPIKey query", "id", id)
+	owned, args := ownedBy(ctx, "user_id")
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM api_keys WHERE id = ? AND `+owned+`;`, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete API key: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("API key with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
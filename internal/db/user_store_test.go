@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestUsersAndSessions(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	// A book from before accounts goes to the first user only
+	legacy := createTestBook()
+	if _, err := store.AddBook(ctx, legacy); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+
+	if _, err := store.FirstUserID(ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected no first user yet, got %v", err)
+	}
+	alice := model.User{Username: "alice", PasswordHash: "hash"}
+	if err := store.AddUser(ctx, &alice); err != nil || alice.ID == 0 {
+		t.Fatalf("AddUser failed: %+v, %v", alice, err)
+	}
+	bob := model.User{Username: "bob", PasswordHash: "hash"}
+	if err := store.AddUser(ctx, &bob); err != nil {
+		t.Fatalf("AddUser failed: %v", err)
+	}
+	for name, err := range map[string]error{
+		"duplicate": store.AddUser(ctx, &model.User{Username: "ALICE", PasswordHash: "hash"}),
+		"bad name":  store.AddUser(ctx, &model.User{Username: "a b", PasswordHash: "hash"}),
+		"no hash":   store.AddUser(ctx, &model.User{Username: "carol"}),
+	} {
+		want := ErrValidation
+		if name == "duplicate" {
+			want = ErrDuplicate
+		}
+		if !errors.Is(err, want) {
+			t.Errorf("%s: expected %v, got %v", name, want, err)
+		}
+	}
+	if users, err := store.CountUsers(ctx); err != nil || users != 2 {
+		t.Errorf("Expected 2 users, got %d, %v", users, err)
+	}
+	if first, err := store.FirstUserID(ctx); err != nil || first != alice.ID {
+		t.Errorf("Expected alice to be the first user, got %d, %v", first, err)
+	}
+	if found, err := store.GetUserByUsername(ctx, "Alice"); err != nil || found.ID != alice.ID {
+		t.Errorf("Expected to find alice ignoring case, got %+v, %v", found, err)
+	}
+	if _, err := store.GetUserByID(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a missing user to be not found, got %v", err)
+	}
+
+	if _, err := store.GetBookByID(WithUser(ctx, alice.ID), legacy.ID); err != nil {
+		t.Errorf("Expected the first user to adopt the existing book, got %v", err)
+	}
+	if _, err := store.GetBookByID(WithUser(ctx, bob.ID), legacy.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the second user not to see the existing book, got %v", err)
+	}
+
+	if err := store.CreateSession(ctx, alice.ID, "secret", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if err := store.CreateSession(ctx, bob.ID, "stale", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if user, err := store.GetSessionUser(ctx, "secret"); err != nil || user.ID != alice.ID {
+		t.Errorf("Expected the session to belong to alice, got %+v, %v", user, err)
+	}
+	for _, token := range []string{"stale", "unknown"} {
+		if _, err := store.GetSessionUser(ctx, token); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected the session not to be found, got %v", token, err)
+		}
+	}
+	if err := store.DeleteSession(ctx, "secret"); err != nil {
+		t.Fatalf("DeleteSession failed: %v", err)
+	}
+	if _, err := store.GetSessionUser(ctx, "secret"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the session to be gone after logging out, got %v", err)
+	}
+}
+
+func TestStoreIsScopedToUser(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	alice, bob := model.User{Username: "alice", PasswordHash: "hash"}, model.User{Username: "bob", PasswordHash: "hash"}
+	store.AddUser(ctx, &alice)
+	store.AddUser(ctx, &bob)
+	asAlice, asBob := WithUser(ctx, alice.ID), WithUser(ctx, bob.ID)
+
+	// Both may own the same edition
+	mine, theirs := createTestBook(), createTestBook()
+	if _, err := store.AddBook(asAlice, mine); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if _, err := store.AddBook(asBob, theirs); err != nil {
+		t.Fatalf("Expected a second user to add the same edition, got %v", err)
+	}
+	if _, err := store.AddBook(asBob, createTestBook()); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected the same edition twice in one library to be a duplicate, got %v", err)
+	}
+
+	if books, err := store.GetBooks(asAlice); err != nil || len(books) != 1 || books[0].ID != mine.ID {
+		t.Errorf("Expected alice to see only her book, got %+v, %v", books, err)
+	}
+	if books, total, err := store.GetBooksPage(asBob, ListOptions{}); err != nil || total != 1 || books[0].ID != theirs.ID {
+		t.Errorf("Expected bob to page through only his book, got %d, %v", total, err)
+	}
+	if books, _ := store.GetBooks(ctx); len(books) != 2 {
+		t.Errorf("Expected an unscoped store to see both books, got %d", len(books))
+	}
+	if found, _ := store.SearchBooks(asAlice, "Test Book"); len(found) != 1 || found[0].ID != mine.ID {
+		t.Errorf("Expected search to find only alice's book, got %+v", found)
+	}
+	for name, err := range map[string]error{
+		"get":     func() error { _, err := store.GetBookByID(asAlice, theirs.ID); return err }(),
+		"update":  store.UpdateBookStatus(asAlice, theirs.ID, model.StatusRead),
+		"cover":   store.UpdateBookCover(asAlice, theirs.ID, nil),
+		"delete":  store.DeleteBook(asAlice, theirs.ID),
+		"tag":     func() error { _, err := store.AddBookTag(asAlice, theirs.ID, "stolen"); return err }(),
+		"history": func() error { _, err := store.GetBookEvents(asAlice, theirs.ID); return err }(),
+	} {
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s of another user's book: expected not found, got %v", name, err)
+		}
+	}
+
+	// Tag names are per user
+	aliceTag, err := store.AddBookTag(asAlice, mine.ID, "Sci-Fi")
+	if err != nil {
+		t.Fatalf("AddBookTag failed: %v", err)
+	}
+	bobTag, err := store.AddBookTag(asBob, theirs.ID, "sci-fi")
+	if err != nil || bobTag.ID == aliceTag.ID || bobTag.BookCount != 1 {
+		t.Fatalf("Expected bob to get a tag of his own, got %+v, %v", bobTag, err)
+	}
+	if tags, _ := store.GetTags(asBob); len(tags) != 1 || tags[0].ID != bobTag.ID {
+		t.Errorf("Expected bob to see only his tag, got %+v", tags)
+	}
+	if err := store.DeleteTag(asBob, aliceTag.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected deleting another user's tag to be not found, got %v", err)
+	}
+
+	if err := store.AddVacation(asAlice, &model.Vacation{StartDate: "2025-07-01", EndDate: "2025-07-14"}); err != nil {
+		t.Fatalf("AddVacation failed: %v", err)
+	}
+	if vacations, _ := store.GetVacations(asBob); len(vacations) != 0 {
+		t.Errorf("Expected bob to have no vacations, got %+v", vacations)
+	}
+
+	if err := store.DeleteBook(asBob, theirs.ID); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if tombstones, _ := store.GetTombstonesSince(asAlice, time.Time{}); len(tombstones) != 0 {
+		t.Errorf("Expected alice's export not to report bob's deletion, got %+v", tombstones)
+	}
+	if tombstones, _ := store.GetTombstonesSince(asBob, time.Time{}); len(tombstones) != 1 {
+		t.Errorf("Expected bob's export to report his deletion, got %+v", tombstones)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	alice, bob := model.User{Username: "alice", PasswordHash: "hash"}, model.User{Username: "bob", PasswordHash: "hash"}
+	store.AddUser(ctx, &alice)
+	store.AddUser(ctx, &bob)
+
+	key := model.APIKey{UserID: alice.ID, Name: "backup", Prefix: "bks_abc"}
+	if err := store.AddAPIKey(ctx, &key, "bks_abcdef"); err != nil || key.ID == 0 {
+		t.Fatalf("AddAPIKey failed: %+v, %v", key, err)
+	}
+	if err := store.AddAPIKey(ctx, &model.APIKey{UserID: alice.ID}, "bks_other"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a key without a name to be invalid, got %v", err)
+	}
+	if user, err := store.GetAPIKeyUser(ctx, "bks_abcdef"); err != nil || user.ID != alice.ID {
+		t.Errorf("Expected the key to belong to alice, got %+v, %v", user, err)
+	}
+	if keys, err := store.GetAPIKeys(WithUser(ctx, alice.ID)); err != nil || len(keys) != 1 || keys[0].LastUsedAt == nil {
+		t.Errorf("Expected alice's key to be marked used, got %+v, %v", keys, err)
+	}
+	if keys, _ := store.GetAPIKeys(WithUser(ctx, bob.ID)); len(keys) != 0 {
+		t.Errorf("Expected bob to have no keys, got %+v", keys)
+	}
+	if err := store.DeleteAPIKey(WithUser(ctx, bob.ID), key.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected revoking another user's key to be not found, got %v", err)
+	}
+	if err := store.DeleteAPIKey(WithUser(ctx, alice.ID), key.ID); err != nil {
+		t.Fatalf("DeleteAPIKey failed: %v", err)
+	}
+	if _, err := store.GetAPIKeyUser(ctx, "bks_abcdef"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a revoked key not to be found, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// VacationStore defines the database operations for vacations, the date
+// ranges left out of streak and goal pace calculations.
+type VacationStore interface {
+	GetVacations(ctx context.Context) ([]model.Vacation, error)
+	AddVacation(ctx context.Context, vacation *model.Vacation) error
+	DeleteVacation(ctx context.Context, id int64) error
+}
+
+// GetVacations returns every vacation, earliest first.
+func (s *SQLiteBookStore) GetVacations(ctx context.Context) ([]model.Vacation, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetVacations query")
+	owned, args := ownedBy(ctx, "user_id")
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, start_date, end_date, note, created_at FROM vacations WHERE `+owned+`
+        ORDER BY start_date, id;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetVacations query failed", "error", err)
+		return nil, fmt.Errorf("failed to query vacations: %w", err)
+	}
+	defer rows.Close()
+
+	vacations := []model.Vacation{}
+	for rows.Next() {
+		var v model.Vacation
+		if err := rows.Scan(&v.ID, &v.StartDate, &v.EndDate, &v.Note, &v.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan vacation row: %w", err)
+		}
+		vacations = append(vacations, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating vacation rows: %w", err)
+	}
+	return vacations, nil
+}
+
+// AddVacation stores a vacation and sets its ID and creation time. Vacations
+// may overlap.
+func (s *SQLiteBookStore) AddVacation(ctx context.Context, vacation *model.Vacation) error {
+	if err := vacation.Validate(); err != nil {
+		return &storeError{kind: ErrValidation, msg: "validation failed: " + err.Error(), cause: err}
+	}
+	slog.InfoContext(ctx, "SQL: Executing AddVacation query", "start", vacation.StartDate, "end", vacation.EndDate)
+	vacation.CreatedAt = time.Now().UTC()
+	if err := s.DB.QueryRowContext(ctx, `INSERT INTO vacations (start_date, end_date, note, created_at, user_id) VALUES (?, ?, ?, ?, ?) RETURNING id;`,
+		vacation.StartDate, vacation.EndDate, vacation.Note, vacation.CreatedAt, owner(ctx)).Scan(&vacation.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddVacation statement failed", "error", err)
+		return fmt.Errorf("failed to add vacation: %w", classify(err))
+	}
+	return nil
+}
+
+// DeleteVacation removes a vacation, so its days count again.
+func (s *SQLiteBookStore) DeleteVacation(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "SQL: Executing DeleteVacation query", "id", id)
+	owned, args := ownedBy(ctx, "user_id")
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM vacations WHERE id = ? AND `+owned+`;`, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete vacation: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("vacation with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
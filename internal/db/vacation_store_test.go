@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestVacations(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	note := "  Hiking  "
+	trip := model.Vacation{StartDate: "2025-07-10", EndDate: "2025-07-20", Note: &note}
+	if err := store.AddVacation(ctx, &trip); err != nil || trip.ID == 0 || *trip.Note != "Hiking" {
+		t.Fatalf("AddVacation failed: %+v, %v", trip, err)
+	}
+	// Overlapping vacations are allowed
+	if err := store.AddVacation(ctx, &model.Vacation{StartDate: "2025-07-01", EndDate: "2025-07-12"}); err != nil {
+		t.Fatalf("AddVacation failed: %v", err)
+	}
+	for _, bad := range []model.Vacation{
+		{StartDate: "2025-07-10", EndDate: "2025-07-09"},
+		{StartDate: "July 1", EndDate: "2025-07-09"},
+		{StartDate: "2025-07-01"},
+	} {
+		if err := store.AddVacation(ctx, &bad); !errors.Is(err, ErrValidation) {
+			t.Errorf("AddVacation(%+v): expected a validation error, got %v", bad, err)
+		}
+	}
+
+	vacations, err := store.GetVacations(ctx)
+	if err != nil || len(vacations) != 2 || vacations[0].StartDate != "2025-07-01" {
+		t.Fatalf("Expected both vacations, earliest first, got %+v, %v", vacations, err)
+	}
+	from, to := time.Date(2025, 6, 30, 15, 0, 0, 0, time.UTC), time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	if days := model.VacationDays(vacations, from, to); days != 20 {
+		t.Errorf("Expected 20 vacation days in July, got %d", days)
+	}
+	if !vacations[1].Covers(time.Date(2025, 7, 20, 23, 59, 0, 0, time.UTC)) || vacations[1].Covers(time.Date(2025, 7, 21, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the last day to be included and the next one not")
+	}
+
+	if err := store.DeleteVacation(ctx, trip.ID); err != nil {
+		t.Fatalf("DeleteVacation failed: %v", err)
+	}
+	if err := store.DeleteVacation(ctx, trip.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found deleting twice, got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// ValueStore defines the database operations for the market value samples
+// of books.
+type ValueStore interface {
+	AddMarketValue(ctx context.Context, value *model.MarketValue) error
+	GetOwnedISBNs(ctx context.Context) ([]string, error)
+	GetBookValueHistory(ctx context.Context, bookID int64) (*model.BookValueHistory, error)
+	GetCollectionValue(ctx context.Context) (*model.CollectionValue, error)
+	GetLatestMarketValues(ctx context.Context) (map[string]model.MarketValue, error)
+}
+
+const marketValueColumns = `id, isbn, value, currency, source, sampled_at`
+
+// ownedCopies returns the condition matching the books owned, those on the
+// shelf and not archived, that have an ISBN to be valued by.
+func ownedCopies(ctx context.Context) (string, []interface{}) {
+	cond, args := shelved(ctx, "")
+	return `archived = ? AND isbn IS NOT NULL AND isbn <> '' AND ` + cond, append([]interface{}{false}, args...)
+}
+
+// AddMarketValue stores a sample of the market value of an ISBN and sets its
+// ID, and its time unless it is set.
+func (s *SQLiteBookStore) AddMarketValue(ctx context.Context, value *model.MarketValue) error {
+	if value.ISBN == "" || value.Currency == "" || value.Source == "" {
+		return invalidf("isbn, currency and source are required")
+	}
+	if value.Value < 0 {
+		return invalidf("value must not be negative")
+	}
+	if value.SampledAt.IsZero() {
+		value.SampledAt = time.Now().UTC()
+	}
+	slog.InfoContext(ctx, "SQL: Executing AddMarketValue query", "isbn", value.ISBN, "source", value.Source)
+	if err := s.DB.QueryRowContext(ctx, `INSERT INTO market_values (isbn, value, currency, source, sampled_at) VALUES (?, ?, ?, ?, ?) RETURNING id;`,
+		value.ISBN, value.Value, value.Currency, value.Source, value.SampledAt).Scan(&value.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Inserting market value failed", "error", err)
+		return fmt.Errorf("failed to add market value: %w", classify(err))
+	}
+	return nil
+}
+
+// GetOwnedISBNs returns the ISBNs of the books owned, each once, in order.
+// Without a user in ctx, it covers the books of every user, for sampling.
+func (s *SQLiteBookStore) GetOwnedISBNs(ctx context.Context) ([]string, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetOwnedISBNs query")
+	cond, args := ownedCopies(ctx)
+	rows, err := s.DB.QueryContext(ctx, `SELECT DISTINCT isbn FROM books WHERE `+cond+` ORDER BY isbn;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetOwnedISBNs query failed", "error", err)
+		return nil, fmt.Errorf("failed to query ISBNs: %w", err)
+	}
+	defer rows.Close()
+
+	isbns := []string{}
+	for rows.Next() {
+		var isbn string
+		if err := rows.Scan(&isbn); err != nil {
+			return nil, fmt.Errorf("failed to scan ISBN row: %w", err)
+		}
+		isbns = append(isbns, isbn)
+	}
+	return isbns, rows.Err()
+}
+
+// queryMarketValues returns the samples matching a condition, oldest first.
+func (s *SQLiteBookStore) queryMarketValues(ctx context.Context, cond string, args ...interface{}) ([]model.MarketValue, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+marketValueColumns+` FROM market_values WHERE `+cond+` ORDER BY sampled_at, id;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Querying market values failed", "error", err)
+		return nil, fmt.Errorf("failed to query market values: %w", err)
+	}
+	defer rows.Close()
+
+	values := []model.MarketValue{}
+	for rows.Next() {
+		var v model.MarketValue
+		if err := rows.Scan(&v.ID, &v.ISBN, &v.Value, &v.Currency, &v.Source, &v.SampledAt); err != nil {
+			return nil, fmt.Errorf("failed to scan market value row: %w", err)
+		}
+		values = append(values, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating market value rows: %w", err)
+	}
+	return values, nil
+}
+
+// GetBookValueHistory returns the market value samples of a book's ISBN,
+// oldest first.
+func (s *SQLiteBookStore) GetBookValueHistory(ctx context.Context, bookID int64) (*model.BookValueHistory, error) {
+	book, err := s.GetBookByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	history := &model.BookValueHistory{BookID: book.ID, Samples: []model.MarketValue{}}
+	if book.ISBN == "" {
+		return history, nil
+	}
+	slog.InfoContext(ctx, "SQL: Executing GetBookValueHistory query", "bookID", bookID)
+	history.ISBN = &book.ISBN
+	if history.Samples, err = s.queryMarketValues(ctx, `isbn = ?`, book.ISBN); err != nil {
+		return nil, err
+	}
+	if n := len(history.Samples); n > 0 {
+		history.Latest = &history.Samples[n-1]
+	}
+	return history, nil
+}
+
+// GetCollectionValue returns the market value of the books owned over time,
+// in each currency samples were taken in. A day's point adds up the latest
+// value of each book sampled by the end of that day.
+func (s *SQLiteBookStore) GetCollectionValue(ctx context.Context) (*model.CollectionValue, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetCollectionValue query")
+	cond, args := ownedCopies(ctx)
+	rows, err := s.DB.QueryContext(ctx, `SELECT isbn, COUNT(*) FROM books WHERE `+cond+` GROUP BY isbn;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetCollectionValue query failed", "error", err)
+		return nil, fmt.Errorf("failed to query owned books: %w", err)
+	}
+	defer rows.Close()
+	copies := map[string]int{}
+	collection := &model.CollectionValue{Series: []model.ValueSeries{}}
+	for rows.Next() {
+		var isbn string
+		var n int
+		if err := rows.Scan(&isbn, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan owned book row: %w", err)
+		}
+		copies[isbn] = n
+		collection.Books += n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating owned book rows: %w", err)
+	}
+	rows.Close()
+
+	samples, err := s.queryMarketValues(ctx, `isbn IN (SELECT isbn FROM books WHERE `+cond+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	collection.Series = valueSeries(samples, copies)
+	valued := map[string]bool{}
+	for _, v := range samples {
+		if !valued[v.ISBN] {
+			valued[v.ISBN] = true
+			collection.Valued += copies[v.ISBN]
+		}
+	}
+	return collection, nil
+}
+
+// GetLatestMarketValues returns the latest market value sample of each ISBN
+// owned, keyed by ISBN. ISBNs never sampled are left out.
+func (s *SQLiteBookStore) GetLatestMarketValues(ctx context.Context) (map[string]model.MarketValue, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetLatestMarketValues query")
+	cond, args := ownedCopies(ctx)
+	samples, err := s.queryMarketValues(ctx, `isbn IN (SELECT isbn FROM books WHERE `+cond+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[string]model.MarketValue)
+	for _, v := range samples {
+		latest[v.ISBN] = v
+	}
+	return latest, nil
+}
+
+// valueSeries turns samples, oldest first, into the value over time of the
+// copies of each ISBN owned, one series per currency.
+func valueSeries(samples []model.MarketValue, copies map[string]int) []model.ValueSeries {
+	type state struct {
+		latest map[string]float64 // Latest value of each ISBN sampled so far
+		series model.ValueSeries
+	}
+	currencies := map[string]*state{}
+	for _, v := range samples {
+		c := currencies[v.Currency]
+		if c == nil {
+			c = &state{latest: map[string]float64{}, series: model.ValueSeries{Currency: v.Currency, Points: []model.ValuePoint{}}}
+			currencies[v.Currency] = c
+		}
+		c.latest[v.ISBN] = v.Value
+		point := model.ValuePoint{Date: v.SampledAt.UTC().Format(model.DateLayout)}
+		for isbn, value := range c.latest {
+			point.Value += value * float64(copies[isbn])
+			point.Books += copies[isbn]
+		}
+		point.Value = math.Round(point.Value*100) / 100
+		// Later samples of the same day replace its point
+		if n := len(c.series.Points); n > 0 && c.series.Points[n-1].Date == point.Date {
+			c.series.Points[n-1] = point
+		} else {
+			c.series.Points = append(c.series.Points, point)
+		}
+	}
+	series := []model.ValueSeries{}
+	for _, c := range currencies {
+		series = append(series, c.series)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Currency < series[j].Currency })
+	return series
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestMarketValues(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	first := createTestBook()
+	first.ISBN = "9781111111111"
+	second := createTestBook()
+	second.OpenLibraryID, second.ISBN = "OLVALUE2M", "9782222222222"
+	bare := createTestBook()
+	bare.OpenLibraryID, bare.ISBN = "OLVALUE4M", ""
+	for _, b := range []*model.Book{first, second, bare} {
+		if _, err := store.AddBook(ctx, b); err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+	}
+	if isbns, err := store.GetOwnedISBNs(ctx); err != nil || len(isbns) != 2 || isbns[0] != "9781111111111" {
+		t.Errorf("Expected both ISBNs once, got %v, %v", isbns, err)
+	}
+
+	day := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	for _, v := range []model.MarketValue{
+		{ISBN: "9781111111111", Value: 10, Currency: "USD", Source: "test", SampledAt: day},
+		{ISBN: "9782222222222", Value: 5.5, Currency: "USD", Source: "test", SampledAt: day.Add(time.Hour)},
+		{ISBN: "9781111111111", Value: 12, Currency: "USD", Source: "test", SampledAt: day.AddDate(0, 0, 1)},
+		{ISBN: "9782222222222", Value: 4, Currency: "EUR", Source: "test", SampledAt: day.AddDate(0, 0, 1)},
+	} {
+		if err := store.AddMarketValue(ctx, &v); err != nil || v.ID == 0 {
+			t.Fatalf("AddMarketValue failed: %v", err)
+		}
+	}
+	if err := store.AddMarketValue(ctx, &model.MarketValue{ISBN: "9781111111111", Value: -1, Currency: "USD", Source: "test"}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a negative value to be invalid, got %v", err)
+	}
+
+	history, err := store.GetBookValueHistory(ctx, first.ID)
+	if err != nil || len(history.Samples) != 2 || history.Latest.Value != 12 || *history.ISBN != first.ISBN {
+		t.Errorf("Expected both samples of the book, got %+v, %v", history, err)
+	}
+	if history, err := store.GetBookValueHistory(ctx, bare.ID); err != nil || len(history.Samples) != 0 || history.ISBN != nil {
+		t.Errorf("Expected no samples without an ISBN, got %+v, %v", history, err)
+	}
+	if _, err := store.GetBookValueHistory(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a missing book to be not found, got %v", err)
+	}
+
+	collection, err := store.GetCollectionValue(ctx)
+	if err != nil {
+		t.Fatalf("GetCollectionValue failed: %v", err)
+	}
+	if collection.Books != 2 || collection.Valued != 2 || len(collection.Series) != 2 || collection.Series[0].Currency != "EUR" {
+		t.Fatalf("Expected EUR and USD series over 2 books, got %+v", collection)
+	}
+	// The second book keeps its value from the day before
+	want := []model.ValuePoint{{Date: "2025-03-01", Value: 15.5, Books: 2}, {Date: "2025-03-02", Value: 17.5, Books: 2}}
+	if usd := collection.Series[1].Points; len(usd) != 2 || usd[0] != want[0] || usd[1] != want[1] {
+		t.Errorf("Expected USD points %+v, got %+v", want, usd)
+	}
+
+	// Archived books are no longer owned
+	if err := store.UpdateBook(ctx, second.ID, model.BookPatch{Archived: model.Some(true)}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+	if collection, _ := store.GetCollectionValue(ctx); collection.Books != 1 || len(collection.Series) != 1 || collection.Series[0].Points[1].Value != 12 {
+		t.Errorf("Expected the archived book left out, got %+v", collection)
+	}
+}
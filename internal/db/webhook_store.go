@@ -0,0 +1,331 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// WebhookStore defines the database operations for webhooks and their
+// delivery log. Deliveries are queued by the changes to books themselves, in
+// the same transaction, and sent by the webhook dispatcher, which reads the
+// deliveries due of every user with an unscoped context.
+type WebhookStore interface {
+	AddWebhook(ctx context.Context, webhook *model.Webhook) (int64, error)
+	GetWebhooks(ctx context.Context) ([]model.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	GetWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]model.WebhookDelivery, error)
+	RetryWebhookDelivery(ctx context.Context, id int64) (*model.WebhookDelivery, error)
+	GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]model.WebhookDelivery, error)
+	SaveWebhookAttempt(ctx context.Context, delivery *model.WebhookDelivery) error
+}
+
+// AddWebhook inserts a webhook. The secret must already be encrypted.
+func (s *SQLiteBookStore) AddWebhook(ctx context.Context, webhook *model.Webhook) (int64, error) {
+	if webhook.URL == "" {
+		return 0, invalidf("a webhook needs a URL")
+	}
+	if len(webhook.Events) == 0 {
+		return 0, invalidf("a webhook needs at least one event")
+	}
+	events := make([]string, 0, len(webhook.Events))
+	for _, event := range webhook.Events {
+		if !event.IsValid() {
+			return 0, invalidf("invalid webhook event: %s", event)
+		}
+		events = append(events, string(event))
+	}
+	if webhook.CreatedAt.IsZero() {
+		webhook.CreatedAt = time.Now().UTC()
+	}
+
+	// The encrypted secret is deliberately left out of the log line
+	slog.InfoContext(ctx, "SQL: Executing AddWebhook query", logging.Redact("url", webhook.URL), "events", events)
+	if err := s.DB.QueryRowContext(ctx, `INSERT INTO webhooks (user_id, url, events, secret_encrypted, enabled, created_at)
+        VALUES (?, ?, ?, ?, ?, ?) RETURNING id;`,
+		owner(ctx), webhook.URL, strings.Join(events, ","), webhook.EncryptedSecret, webhook.Enabled, webhook.CreatedAt).Scan(&webhook.ID); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing AddWebhook statement failed", "error", err)
+		return 0, fmt.Errorf("failed to add webhook: %w", classify(err))
+	}
+	return webhook.ID, nil
+}
+
+// GetWebhooks returns the webhooks, including their encrypted secrets.
+func (s *SQLiteBookStore) GetWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetWebhooks query")
+	owned, args := ownedBy(ctx, "user_id")
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, url, events, secret_encrypted, enabled, created_at
+        FROM webhooks WHERE `+owned+` ORDER BY id;`, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing GetWebhooks query failed", "error", err)
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []model.Webhook{}
+	for rows.Next() {
+		var w model.Webhook
+		var events string
+		if err := rows.Scan(&w.ID, &w.URL, &events, &w.EncryptedSecret, &w.Enabled, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook row: %w", err)
+		}
+		for _, event := range strings.Split(events, ",") {
+			w.Events = append(w.Events, model.WebhookEvent(event))
+		}
+		webhooks = append(webhooks, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook rows: %w", err)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook removes a webhook with its delivery log.
+func (s *SQLiteBookStore) DeleteWebhook(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "SQL: Executing DeleteWebhook query", "id", id)
+	owned, args := ownedBy(ctx, "user_id")
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM webhooks WHERE id = ? AND `+owned+`);`,
+		append([]interface{}{id}, args...)...); err != nil {
+		return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ? AND `+owned+`;`, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook with ID %d %w", id, ErrNotFound)
+	}
+	return tx.Commit()
+}
+
+// deliveryColumns is the column list of queries loading deliveries with
+// their webhook's URL and secret, FROM deliveryTables. It must stay in sync
+// with scanDelivery.
+const deliveryColumns = `webhook_deliveries.id, webhook_deliveries.webhook_id, webhook_deliveries.event, webhook_deliveries.book_id,
+        webhook_deliveries.status, webhook_deliveries.attempts, webhook_deliveries.next_attempt_at, webhook_deliveries.response_status,
+        COALESCE(webhook_deliveries.error, ''), webhook_deliveries.created_at, webhook_deliveries.delivered_at, webhook_deliveries.payload,
+        webhooks.url, webhooks.secret_encrypted`
+
+const deliveryTables = `webhook_deliveries JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id`
+
+// scanDelivery scans a row selected with deliveryColumns.
+func scanDelivery(row rowScanner) (*model.WebhookDelivery, error) {
+	var d model.WebhookDelivery
+	var payload string
+	if err := row.Scan(&d.ID, &d.WebhookID, &d.Event, &d.BookID, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.ResponseStatus,
+		&d.Error, &d.CreatedAt, &d.DeliveredAt, &payload, &d.URL, &d.EncryptedSecret); err != nil {
+		return nil, err
+	}
+	d.Payload = json.RawMessage(payload)
+	return &d, nil
+}
+
+// queryDeliveries runs a query selecting deliveryColumns.
+func (s *SQLiteBookStore) queryDeliveries(ctx context.Context, query string, args ...interface{}) ([]model.WebhookDelivery, error) {
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing webhook delivery query failed", "error", err)
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []model.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery row: %w", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook delivery rows: %w", err)
+	}
+	return deliveries, nil
+}
+
+// GetWebhookDeliveries returns the delivery log of a webhook, newest first,
+// at most limit entries.
+func (s *SQLiteBookStore) GetWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]model.WebhookDelivery, error) {
+	slog.InfoContext(ctx, "SQL: Executing GetWebhookDeliveries query", "webhookID", webhookID, "limit", limit)
+	owned, args := ownedBy(ctx, "user_id")
+	var exists bool
+	if err := s.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM webhooks WHERE id = ? AND `+owned+`);`,
+		append([]interface{}{webhookID}, args...)...).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up webhook: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("webhook with ID %d %w", webhookID, ErrNotFound)
+	}
+	return s.queryDeliveries(ctx, `SELECT `+deliveryColumns+` FROM `+deliveryTables+`
+        WHERE webhook_deliveries.webhook_id = ? ORDER BY webhook_deliveries.id DESC LIMIT ?;`, webhookID, limit)
+}
+
+// RetryWebhookDelivery queues a delivery again, to be sent right away with
+// a fresh set of retries, whether it failed or was delivered.
+func (s *SQLiteBookStore) RetryWebhookDelivery(ctx context.Context, id int64) (*model.WebhookDelivery, error) {
+	slog.InfoContext(ctx, "SQL: Executing RetryWebhookDelivery query", "id", id)
+	owned, args := ownedBy(ctx, "user_id")
+	res, err := s.DB.ExecContext(ctx, `UPDATE webhook_deliveries SET status = ?, attempts = 0, next_attempt_at = ?
+        WHERE id = ? AND webhook_id IN (SELECT id FROM webhooks WHERE `+owned+`);`,
+		append([]interface{}{model.DeliveryPending, time.Now().UTC(), id}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue webhook delivery: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, fmt.Errorf("webhook delivery with ID %d %w", id, ErrNotFound)
+	}
+	delivery, err := scanDelivery(s.DB.QueryRowContext(ctx, `SELECT `+deliveryColumns+` FROM `+deliveryTables+` WHERE webhook_deliveries.id = ?;`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// GetDueWebhookDeliveries returns the pending deliveries to be attempted by
+// now, oldest first, at most limit of them. Those of disabled webhooks wait
+// for them to be enabled again.
+func (s *SQLiteBookStore) GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]model.WebhookDelivery, error) {
+	// Polled every few seconds, so it isn't logged at the info level
+	slog.DebugContext(ctx, "SQL: Executing GetDueWebhookDeliveries query", "limit", limit)
+	owned, args := ownedBy(ctx, "webhooks.user_id")
+	return s.queryDeliveries(ctx, `SELECT `+deliveryColumns+` FROM `+deliveryTables+`
+        WHERE webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ? AND webhooks.enabled = ? AND `+owned+`
+        ORDER BY webhook_deliveries.next_attempt_at, webhook_deliveries.id LIMIT ?;`,
+		append(append([]interface{}{model.DeliveryPending, now.UTC(), true}, args...), limit)...)
+}
+
+// SaveWebhookAttempt records the outcome of an attempt to send a delivery:
+// its status, attempts, next attempt, response and error, and when it was
+// delivered.
+func (s *SQLiteBookStore) SaveWebhookAttempt(ctx context.Context, delivery *model.WebhookDelivery) error {
+	slog.InfoContext(ctx, "SQL: Executing SaveWebhookAttempt query", "id", delivery.ID, "status", delivery.Status, "attempts", delivery.Attempts)
+	var deliveryErr interface{}
+	if delivery.Error != "" {
+		deliveryErr = delivery.Error
+	}
+	res, err := s.DB.ExecContext(ctx, `UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, response_status = ?,
+            error = ?, delivered_at = ?
+        WHERE id = ?;`,
+		delivery.Status, delivery.Attempts, utcTime(delivery.NextAttemptAt), delivery.ResponseStatus, deliveryErr, utcTime(delivery.DeliveredAt), delivery.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing SaveWebhookAttempt statement failed", "error", err)
+		return fmt.Errorf("failed to save webhook attempt: %w", classify(err))
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook delivery with ID %d %w", delivery.ID, ErrNotFound)
+	}
+	return nil
+}
+
+// webhookEvents returns the webhook events a book event is sent as, with the
+// book's previous status for status changes. book is the book after the
+// event.
+func webhookEvents(event *model.BookEvent, book *model.Book) ([]model.WebhookEvent, model.BookStatus) {
+	switch event.Kind {
+	case model.EventCreated:
+		return []model.WebhookEvent{model.WebhookBookCreated}, ""
+	case model.EventDeleted:
+		return []model.WebhookEvent{model.WebhookBookDeleted}, ""
+	case model.EventPurged:
+		// Books purged from the trash were sent as deleted when they went there
+		if book.DeletedAt == nil {
+			return []model.WebhookEvent{model.WebhookBookDeleted}, ""
+		}
+	case model.EventUpdated:
+		for _, change := range event.Changes {
+			var previous model.BookStatus
+			if change.Field != "status" || json.Unmarshal(change.From, &previous) != nil {
+				continue
+			}
+			events := []model.WebhookEvent{model.WebhookBookStatusChanged}
+			if book.Status == model.StatusRead {
+				events = append(events, model.WebhookBookFinished)
+			}
+			return events, previous
+		}
+	}
+	return nil, ""
+}
+
+// queueWebhookDeliveries queues the deliveries of a book event, just
+// recorded by recordBookEvent in tx, to the enabled webhooks of the book's
+// owner that subscribe to it. The payload has the book as it is in tx.
+func queueWebhookDeliveries(ctx context.Context, tx execer, event *model.BookEvent) error {
+	rows, err := tx.QueryContext(ctx, `SELECT webhooks.id, webhooks.events FROM webhooks
+        JOIN books ON COALESCE(books.user_id, 0) = COALESCE(webhooks.user_id, 0)
+        WHERE books.id = ? AND webhooks.enabled = ?;`, event.BookID, true)
+	if err != nil {
+		return fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	subscribed := map[model.WebhookEvent][]int64{}
+	for rows.Next() {
+		var id int64
+		var events string
+		if err := rows.Scan(&id, &events); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan webhook row: %w", err)
+		}
+		for _, e := range strings.Split(events, ",") {
+			subscribed[model.WebhookEvent(e)] = append(subscribed[model.WebhookEvent(e)], id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating webhook rows: %w", err)
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	book, err := scanBook(tx.QueryRowContext(ctx, `SELECT `+bookColumns+` FROM books WHERE id = ?;`, event.BookID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get book for webhooks: %w", err)
+	}
+	events, previous := webhookEvents(event, book)
+	for _, kind := range events {
+		if len(subscribed[kind]) == 0 {
+			continue
+		}
+		payload, err := json.Marshal(model.WebhookPayload{Event: kind, OccurredAt: event.OccurredAt, Book: *book, PreviousStatus: previous})
+		if err != nil {
+			return fmt.Errorf("failed to encode webhook payload: %w", err)
+		}
+		for _, webhookID := range subscribed[kind] {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO webhook_deliveries (webhook_id, event, book_id, payload, status, attempts, next_attempt_at, created_at)
+                VALUES (?, ?, ?, ?, ?, 0, ?, ?);`,
+				webhookID, kind, book.ID, string(payload), model.DeliveryPending, event.OccurredAt, event.OccurredAt); err != nil {
+				return fmt.Errorf("failed to queue webhook delivery: %w", classify(err))
+			}
+		}
+		slog.InfoContext(ctx, "SQL: Queued webhook deliveries", "event", kind, "bookID", book.ID, "webhooks", len(subscribed[kind]))
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestWebhookDeliveries(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	if _, err := store.AddWebhook(ctx, &model.Webhook{URL: "https://example.com/hook", Events: []model.WebhookEvent{"book.read"}}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected an unknown event to be rejected, got %v", err)
+	}
+	all := &model.Webhook{URL: "https://example.com/all", Events: model.WebhookEvents, Enabled: true, EncryptedSecret: "sealed"}
+	finished := &model.Webhook{URL: "https://example.com/finished", Events: []model.WebhookEvent{model.WebhookBookFinished}, Enabled: true}
+	disabled := &model.Webhook{URL: "https://example.com/off", Events: model.WebhookEvents}
+	for _, w := range []*model.Webhook{all, finished, disabled} {
+		if _, err := store.AddWebhook(ctx, w); err != nil {
+			t.Fatalf("AddWebhook failed: %v", err)
+		}
+	}
+	if webhooks, err := store.GetWebhooks(ctx); err != nil || len(webhooks) != 3 || len(webhooks[0].Events) != 4 || webhooks[1].Events[0] != model.WebhookBookFinished {
+		t.Fatalf("Unexpected webhooks %+v, %v", webhooks, err)
+	}
+
+	id, err := store.AddBook(ctx, createTestBook())
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if err := store.UpdateBookStatus(ctx, id, model.StatusCurrentlyReading); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	if err := store.UpdateBook(ctx, id, model.BookPatch{Title: model.Some("Renamed")}); err != nil {
+		t.Fatalf("UpdateBook failed: %v", err)
+	}
+	if err := store.UpdateBookStatus(ctx, id, model.StatusRead); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	if err := store.DeleteBook(ctx, id); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	// A dry run queues nothing
+	dryRun := createTestBook()
+	dryRun.OpenLibraryID = "OLDRYRUNM"
+	if _, err := store.AddBook(WithDryRun(ctx), dryRun); err != nil {
+		t.Fatalf("Dry run AddBook failed: %v", err)
+	}
+
+	deliveries, err := store.GetWebhookDeliveries(ctx, all.ID, 10)
+	if err != nil {
+		t.Fatalf("GetWebhookDeliveries failed: %v", err)
+	}
+	var events []model.WebhookEvent
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		events = append(events, deliveries[i].Event)
+	}
+	want := []model.WebhookEvent{model.WebhookBookCreated, model.WebhookBookStatusChanged, model.WebhookBookStatusChanged,
+		model.WebhookBookFinished, model.WebhookBookDeleted}
+	if len(events) != len(want) {
+		t.Fatalf("Expected deliveries %v, got %v", want, events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("Delivery %d: got %s, want %s", i, events[i], want[i])
+		}
+	}
+	var payload model.WebhookPayload
+	if err := json.Unmarshal(deliveries[1].Payload, &payload); err != nil || payload.Event != model.WebhookBookFinished ||
+		payload.Book.Title != "Renamed" || payload.Book.Status != model.StatusRead || payload.PreviousStatus != model.StatusCurrentlyReading {
+		t.Errorf("Unexpected finished payload %+v, %v", payload, err)
+	}
+	if deliveries[0].Status != model.DeliveryPending || deliveries[0].URL != all.URL || deliveries[0].EncryptedSecret != "sealed" {
+		t.Errorf("Unexpected delivery %+v", deliveries[0])
+	}
+	if d, err := store.GetWebhookDeliveries(ctx, finished.ID, 10); err != nil || len(d) != 1 {
+		t.Errorf("Expected only book.finished sent to its subscriber, got %d, %v", len(d), err)
+	}
+	if d, err := store.GetWebhookDeliveries(ctx, disabled.ID, 10); err != nil || len(d) != 0 {
+		t.Errorf("Expected nothing queued for a disabled webhook, got %d, %v", len(d), err)
+	}
+
+	due, err := store.GetDueWebhookDeliveries(ctx, time.Now().Add(time.Second), 100)
+	if err != nil || len(due) != 6 {
+		t.Fatalf("Expected 6 deliveries due, got %d, %v", len(due), err)
+	}
+	now := time.Now().UTC()
+	status := 200
+	delivered := due[0]
+	delivered.Status, delivered.Attempts, delivered.NextAttemptAt, delivered.ResponseStatus, delivered.DeliveredAt = model.DeliveryDelivered, 1, nil, &status, &now
+	if err := store.SaveWebhookAttempt(ctx, &delivered); err != nil {
+		t.Fatalf("SaveWebhookAttempt failed: %v", err)
+	}
+	later := now.Add(time.Hour)
+	retried := due[1]
+	retried.Attempts, retried.NextAttemptAt, retried.Error = 1, &later, "connection refused"
+	if err := store.SaveWebhookAttempt(ctx, &retried); err != nil {
+		t.Fatalf("SaveWebhookAttempt failed: %v", err)
+	}
+	if due, err := store.GetDueWebhookDeliveries(ctx, time.Now().Add(time.Second), 100); err != nil || len(due) != 4 {
+		t.Errorf("Expected the delivered and postponed deliveries not due, got %d, %v", len(due), err)
+	}
+
+	again, err := store.RetryWebhookDelivery(ctx, delivered.ID)
+	if err != nil || again.Status != model.DeliveryPending || again.Attempts != 0 || again.ResponseStatus == nil || *again.ResponseStatus != 200 {
+		t.Errorf("Unexpected retried delivery %+v, %v", again, err)
+	}
+	if _, err := store.RetryWebhookDelivery(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found retrying a missing delivery, got %v", err)
+	}
+
+	if err := store.DeleteWebhook(ctx, all.ID); err != nil {
+		t.Fatalf("DeleteWebhook failed: %v", err)
+	}
+	if _, err := store.GetWebhookDeliveries(ctx, all.ID, 10); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected deleted webhook not found, got %v", err)
+	}
+	if due, err := store.GetDueWebhookDeliveries(ctx, time.Now().Add(time.Second), 100); err != nil || len(due) != 1 {
+		t.Errorf("Expected the deleted webhook's deliveries gone, got %d, %v", len(due), err)
+	}
+}
+
+func TestWebhooksScopedToUser(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	var users []model.User
+	for _, username := range []string{"alice", "bob"} {
+		user := model.User{Username: username, PasswordHash: "hash"}
+		if err := store.AddUser(ctx, &user); err != nil {
+			t.Fatalf("AddUser failed: %v", err)
+		}
+		users = append(users, user)
+	}
+	alice, bob := WithUser(ctx, users[0].ID), WithUser(ctx, users[1].ID)
+
+	webhook := &model.Webhook{URL: "https://example.com/alice", Events: model.WebhookEvents, Enabled: true}
+	if _, err := store.AddWebhook(alice, webhook); err != nil {
+		t.Fatalf("AddWebhook failed: %v", err)
+	}
+	if _, err := store.AddBook(bob, createTestBook()); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if _, err := store.AddBook(alice, createTestBook()); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if d, err := store.GetWebhookDeliveries(alice, webhook.ID, 10); err != nil || len(d) != 1 {
+		t.Errorf("Expected only alice's book sent to her webhook, got %d, %v", len(d), err)
+	}
+	if webhooks, err := store.GetWebhooks(bob); err != nil || len(webhooks) != 0 {
+		t.Errorf("Expected bob to have no webhooks, got %+v, %v", webhooks, err)
+	}
+	if _, err := store.GetWebhookDeliveries(bob, webhook.ID, 10); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected alice's webhook hidden from bob, got %v", err)
+	}
+	if err := store.DeleteWebhook(bob, webhook.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected bob not to delete alice's webhook, got %v", err)
+	}
+}
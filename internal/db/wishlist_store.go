@@ -0,0 +1,311 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// WishlistStore defines the database operations for sharing a wishlist, the
+// Want to Read shelf, with household members, and for the gift claims they
+// coordinate on it. Each operation acts for the user of ctx: as the owner
+// sharing their wishlist, or as a member of someone else's.
+type WishlistStore interface {
+	ShareWishlist(ctx context.Context, username string) (*model.WishlistShare, error)
+	GetWishlistMembers(ctx context.Context) ([]model.WishlistShare, error)
+	UnshareWishlist(ctx context.Context, memberID int64) error
+	GetSharedWishlists(ctx context.Context) ([]model.WishlistShare, error)
+	GetSharedWishlist(ctx context.Context, ownerID int64) ([]model.WishlistItem, error)
+	ClaimWishlistBook(ctx context.Context, ownerID, bookID int64, purchased bool) (*model.WishlistItem, error)
+	UnclaimWishlistBook(ctx context.Context, ownerID, bookID int64) error
+}
+
+// wishlistUser returns the user of ctx. Wishlists are shared between users,
+// so without one there is nothing to share.
+func wishlistUser(ctx context.Context) (int64, error) {
+	id, ok := UserFromContext(ctx)
+	if !ok {
+		return 0, &storeError{kind: ErrValidation, msg: "validation failed: wishlists are shared between users, log in first"}
+	}
+	return id, nil
+}
+
+// queryWishlistShares runs a query selecting a user's ID and username and a
+// share's time.
+func (s *SQLiteBookStore) queryWishlistShares(ctx context.Context, query string, args ...interface{}) ([]model.WishlistShare, error) {
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing wishlist query failed", "error", err)
+		return nil, fmt.Errorf("failed to query wishlist shares: %w", err)
+	}
+	defer rows.Close()
+
+	shares := []model.WishlistShare{}
+	for rows.Next() {
+		var share model.WishlistShare
+		if err := rows.Scan(&share.UserID, &share.Username, &share.SharedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan wishlist share row: %w", err)
+		}
+		shares = append(shares, share)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating wishlist share rows: %w", err)
+	}
+	return shares, nil
+}
+
+// ShareWishlist shares the wishlist of the user of ctx with another user, who
+// can then see it and claim books on it. Sharing with a member twice is not
+// an error.
+func (s *SQLiteBookStore) ShareWishlist(ctx context.Context, username string) (*model.WishlistShare, error) {
+	ownerID, err := wishlistUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	member, err := s.GetUserByUsername(ctx, strings.TrimSpace(username))
+	if err != nil {
+		return nil, err
+	}
+	if member.ID == ownerID {
+		return nil, &storeError{kind: ErrValidation, msg: "validation failed: a wishlist can't be shared with its owner"}
+	}
+	slog.InfoContext(ctx, "SQL: Executing ShareWishlist query", "ownerID", ownerID, "memberID", member.ID)
+	if _, err := s.DB.ExecContext(ctx, `INSERT INTO wishlist_members (owner_id, member_id, created_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING;`,
+		ownerID, member.ID, time.Now().UTC()); err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing ShareWishlist statement failed", "error", err)
+		return nil, fmt.Errorf("failed to share wishlist: %w", classify(err))
+	}
+	shares, err := s.queryWishlistShares(ctx, `SELECT users.id, users.username, wishlist_members.created_at
+        FROM wishlist_members JOIN users ON users.id = wishlist_members.member_id
+        WHERE wishlist_members.owner_id = ? AND wishlist_members.member_id = ?;`, ownerID, member.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("user %q %w", username, ErrNotFound)
+	}
+	return &shares[0], nil
+}
+
+// GetWishlistMembers returns the users the wishlist of the user of ctx is
+// shared with, by username.
+func (s *SQLiteBookStore) GetWishlistMembers(ctx context.Context) ([]model.WishlistShare, error) {
+	ownerID, err := wishlistUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "SQL: Executing GetWishlistMembers query", "ownerID", ownerID)
+	return s.queryWishlistShares(ctx, `SELECT users.id, users.username, wishlist_members.created_at
+        FROM wishlist_members JOIN users ON users.id = wishlist_members.member_id
+        WHERE wishlist_members.owner_id = ? ORDER BY users.username;`, ownerID)
+}
+
+// UnshareWishlist stops sharing the wishlist of the user of ctx with a
+// member, dropping the member's claims on it.
+func (s *SQLiteBookStore) UnshareWishlist(ctx context.Context, memberID int64) error {
+	ownerID, err := wishlistUser(ctx)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing UnshareWishlist query", "ownerID", ownerID, "memberID", memberID)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM wishlist_members WHERE owner_id = ? AND member_id = ?;`, ownerID, memberID)
+	if err != nil {
+		return fmt.Errorf("failed to unshare wishlist: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("wishlist member with ID %d %w", memberID, ErrNotFound)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM gift_claims WHERE member_id = ? AND book_id IN (SELECT id FROM books WHERE user_id = ?);`,
+		memberID, ownerID); err != nil {
+		return fmt.Errorf("failed to drop gift claims: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetSharedWishlists returns the owners of the wishlists shared with the
+// user of ctx, by username.
+func (s *SQLiteBookStore) GetSharedWishlists(ctx context.Context) ([]model.WishlistShare, error) {
+	memberID, err := wishlistUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "SQL: Executing GetSharedWishlists query", "memberID", memberID)
+	return s.queryWishlistShares(ctx, `SELECT users.id, users.username, wishlist_members.created_at
+        FROM wishlist_members JOIN users ON users.id = wishlist_members.owner_id
+        WHERE wishlist_members.member_id = ? ORDER BY users.username;`, memberID)
+}
+
+// sharedWishlistMember returns the user of ctx if the wishlist of ownerID is
+// shared with them. An owner isn't a member of their own wishlist, so they
+// can't see its claims.
+func (s *SQLiteBookStore) sharedWishlistMember(ctx context.Context, ownerID int64) (int64, error) {
+	memberID, err := wishlistUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var shared int
+	if err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM wishlist_members WHERE owner_id = ? AND member_id = ?;`,
+		ownerID, memberID).Scan(&shared); err != nil {
+		return 0, fmt.Errorf("failed to check wishlist member: %w", err)
+	}
+	if shared == 0 {
+		return 0, fmt.Errorf("wishlist of user %d %w", ownerID, ErrNotFound)
+	}
+	return memberID, nil
+}
+
+// giftClaim is a member's claim on a book to give.
+type giftClaim struct {
+	memberID  int64
+	purchased bool
+	claimedAt time.Time
+}
+
+// getGiftClaims returns the claims on the books of ownerID by book ID.
+func (s *SQLiteBookStore) getGiftClaims(ctx context.Context, ownerID int64) (map[int64]giftClaim, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT book_id, member_id, purchased, claimed_at FROM gift_claims
+        WHERE book_id IN (SELECT id FROM books WHERE user_id = ?);`, ownerID)
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing gift claims query failed", "error", err)
+		return nil, fmt.Errorf("failed to query gift claims: %w", err)
+	}
+	defer rows.Close()
+
+	claims := make(map[int64]giftClaim)
+	for rows.Next() {
+		var bookID int64
+		var claim giftClaim
+		if err := rows.Scan(&bookID, &claim.memberID, &claim.purchased, &claim.claimedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan gift claim row: %w", err)
+		}
+		claims[bookID] = claim
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating gift claim rows: %w", err)
+	}
+	return claims, nil
+}
+
+// newWishlistItem describes a book of a shared wishlist to memberID.
+func newWishlistItem(book model.Book, claim giftClaim, claimed bool, memberID int64) model.WishlistItem {
+	item := model.WishlistItem{BookID: book.ID, Title: book.FullTitle(), Author: book.Author, ISBN: book.ISBN, PublishYear: book.PublishYear}
+	if claimed {
+		claimedAt := claim.claimedAt
+		item.Claimed, item.ClaimedByMe, item.Purchased, item.ClaimedAt = true, claim.memberID == memberID, claim.purchased, &claimedAt
+	}
+	return item
+}
+
+// GetSharedWishlist returns the wishlist of ownerID as the user of ctx, a
+// member of it, sees it: the books on the owner's Want to Read shelf, not
+// archived, by title, with their claims.
+func (s *SQLiteBookStore) GetSharedWishlist(ctx context.Context, ownerID int64) ([]model.WishlistItem, error) {
+	memberID, err := s.sharedWishlistMember(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "SQL: Executing GetSharedWishlist query", "ownerID", ownerID, "memberID", memberID)
+	books, _, err := s.GetBooksPage(WithUser(ctx, ownerID), ListOptions{Filter: BookFilter{Status: model.StatusWantToRead}})
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.getGiftClaims(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]model.WishlistItem, len(books))
+	for i, book := range books {
+		claim, claimed := claims[book.ID]
+		items[i] = newWishlistItem(book, claim, claimed, memberID)
+	}
+	return items, nil
+}
+
+// wishlistBook returns a book on the wishlist of ownerID.
+func (s *SQLiteBookStore) wishlistBook(ctx context.Context, ownerID, bookID int64) (*model.Book, error) {
+	book, err := s.GetBookByID(WithUser(ctx, ownerID), bookID)
+	if errors.Is(err, ErrNotFound) || (err == nil && (book.Status != model.StatusWantToRead || book.Archived)) {
+		return nil, fmt.Errorf("book with ID %d on the wishlist %w", bookID, ErrNotFound)
+	}
+	return book, err
+}
+
+// ClaimWishlistBook claims a book on the wishlist of ownerID for the user of
+// ctx to give, or updates whether they purchased it. A book claimed by
+// another member returns ErrConflict.
+func (s *SQLiteBookStore) ClaimWishlistBook(ctx context.Context, ownerID, bookID int64, purchased bool) (*model.WishlistItem, error) {
+	memberID, err := s.sharedWishlistMember(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	book, err := s.wishlistBook(ctx, ownerID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "SQL: Executing ClaimWishlistBook query", "ownerID", ownerID, "bookID", bookID, "memberID", memberID, "purchased", purchased)
+	res, err := s.DB.ExecContext(ctx, `INSERT INTO gift_claims (book_id, member_id, purchased, claimed_at) VALUES (?, ?, ?, ?)
+        ON CONFLICT (book_id) DO UPDATE SET purchased = excluded.purchased WHERE gift_claims.member_id = excluded.member_id;`,
+		bookID, memberID, purchased, time.Now().UTC())
+	if err != nil {
+		slog.ErrorContext(ctx, "SQL Error: Executing ClaimWishlistBook statement failed", "error", err)
+		return nil, fmt.Errorf("failed to claim book: %w", classify(err))
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, &storeError{kind: ErrConflict, msg: fmt.Sprintf("book with ID %d is already claimed by another member", bookID)}
+	}
+
+	var claim giftClaim
+	if err := s.DB.QueryRowContext(ctx, `SELECT member_id, purchased, claimed_at FROM gift_claims WHERE book_id = ?;`, bookID).
+		Scan(&claim.memberID, &claim.purchased, &claim.claimedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("claim on book with ID %d %w", bookID, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get gift claim: %w", err)
+	}
+	item := newWishlistItem(*book, claim, true, memberID)
+	return &item, nil
+}
+
+// UnclaimWishlistBook withdraws the claim of the user of ctx on a book on
+// the wishlist of ownerID. Only the member who claimed a book can withdraw
+// the claim.
+func (s *SQLiteBookStore) UnclaimWishlistBook(ctx context.Context, ownerID, bookID int64) error {
+	memberID, err := s.sharedWishlistMember(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SQL: Executing UnclaimWishlistBook query", "ownerID", ownerID, "bookID", bookID, "memberID", memberID)
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM gift_claims WHERE book_id = ? AND member_id = ?
+        AND book_id IN (SELECT id FROM books WHERE user_id = ?);`, bookID, memberID, ownerID)
+	if err != nil {
+		return fmt.Errorf("failed to unclaim book: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("claim of yours on book with ID %d %w", bookID, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestWishlistGiftClaims(t *testing.T) {
+	ctx := context.Background()
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	var users []model.User
+	for _, username := range []string{"alice", "bob", "carol"} {
+		user := model.User{Username: username, PasswordHash: "hash"}
+		if err := store.AddUser(ctx, &user); err != nil {
+			t.Fatalf("AddUser failed: %v", err)
+		}
+		users = append(users, user)
+	}
+	alice, bob, carol := WithUser(ctx, users[0].ID), WithUser(ctx, users[1].ID), WithUser(ctx, users[2].ID)
+	aliceID := users[0].ID
+
+	var ids []int64
+	for i, status := range []model.BookStatus{model.StatusWantToRead, model.StatusWantToRead, model.StatusRead} {
+		book := createTestBook()
+		book.OpenLibraryID = "OL" + string(rune('A'+i)) + "M"
+		book.Status = status
+		id, err := store.AddBook(alice, book)
+		if err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if _, err := store.ShareWishlist(alice, "alice"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected sharing with oneself to be rejected, got %v", err)
+	}
+	if _, err := store.ShareWishlist(alice, "dave"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found for a missing user, got %v", err)
+	}
+	if _, err := store.ShareWishlist(ctx, "bob"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected sharing without a user to be rejected, got %v", err)
+	}
+	for _, username := range []string{"bob", "carol", "bob"} {
+		if share, err := store.ShareWishlist(alice, username); err != nil || share.Username != username {
+			t.Fatalf("ShareWishlist failed: %+v, %v", share, err)
+		}
+	}
+	if members, err := store.GetWishlistMembers(alice); err != nil || len(members) != 2 || members[0].Username != "bob" {
+		t.Errorf("Expected bob and carol as members, got %+v, %v", members, err)
+	}
+	if owners, err := store.GetSharedWishlists(bob); err != nil || len(owners) != 1 || owners[0].UserID != aliceID {
+		t.Errorf("Expected alice's wishlist shared with bob, got %+v, %v", owners, err)
+	}
+
+	// The owner can't see the claims on her own wishlist
+	if _, err := store.GetSharedWishlist(alice, aliceID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the owner to be kept from the claims, got %v", err)
+	}
+	if _, err := store.GetSharedWishlist(bob, users[2].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a wishlist not shared to be hidden, got %v", err)
+	}
+
+	item, err := store.ClaimWishlistBook(bob, aliceID, ids[0], false)
+	if err != nil || !item.Claimed || !item.ClaimedByMe || item.Purchased {
+		t.Fatalf("ClaimWishlistBook failed: %+v, %v", item, err)
+	}
+	if _, err := store.ClaimWishlistBook(carol, aliceID, ids[0], false); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected another member's claim to conflict, got %v", err)
+	}
+	if item, err := store.ClaimWishlistBook(bob, aliceID, ids[0], true); err != nil || !item.Purchased {
+		t.Errorf("Expected the claim marked purchased, got %+v, %v", item, err)
+	}
+	if _, err := store.ClaimWishlistBook(bob, aliceID, ids[2], false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a book off the wishlist to be unclaimable, got %v", err)
+	}
+
+	wishlist, err := store.GetSharedWishlist(carol, aliceID)
+	if err != nil || len(wishlist) != 2 {
+		t.Fatalf("Expected the two books on the wishlist, got %+v, %v", wishlist, err)
+	}
+	for _, item := range wishlist {
+		if claimed := item.BookID == ids[0]; item.Claimed != claimed || item.Purchased != claimed || item.ClaimedByMe {
+			t.Errorf("Unexpected claim seen by carol: %+v", item)
+		}
+	}
+
+	if err := store.UnclaimWishlistBook(carol, aliceID, ids[0]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected only the claimer to withdraw a claim, got %v", err)
+	}
+	if err := store.UnclaimWishlistBook(bob, aliceID, ids[0]); err != nil {
+		t.Fatalf("UnclaimWishlistBook failed: %v", err)
+	}
+	if _, err := store.ClaimWishlistBook(carol, aliceID, ids[0], false); err != nil {
+		t.Errorf("Expected the withdrawn book free to claim, got %v", err)
+	}
+
+	// Unsharing drops the member's claims
+	if err := store.UnshareWishlist(alice, users[2].ID); err != nil {
+		t.Fatalf("UnshareWishlist failed: %v", err)
+	}
+	if err := store.UnshareWishlist(alice, users[2].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found unsharing twice, got %v", err)
+	}
+	if wishlist, _ := store.GetSharedWishlist(bob, aliceID); len(wishlist) != 2 || wishlist[0].Claimed || wishlist[1].Claimed {
+		t.Errorf("Expected carol's claim dropped, got %+v", wishlist)
+	}
+}
@@ -0,0 +1,152 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Destination receives finished export files.
+type Destination interface {
+	Put(ctx context.Context, name, contentType string, data []byte) error
+}
+
+// Lister is implemented by destinations that can enumerate and delete their
+// exports, which is required for retention. Webhooks hand files off and
+// therefore leave retention to the receiver.
+type Lister interface {
+	List(ctx context.Context) ([]string, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// ParseDestination builds a destination from its configured location:
+//   - s3://bucket/prefix uploads to S3 (credentials from the AWS_* environment)
+//   - http:// or https:// URLs receive each export as a POST
+//   - anything else (optionally prefixed with file://) is a local directory
+func ParseDestination(location string, client *http.Client) (Destination, error) {
+	switch {
+	case location == "":
+		return nil, fmt.Errorf("export destination is required")
+	case strings.HasPrefix(location, "s3://"):
+		return NewS3FromEnv(location, client)
+	case strings.HasPrefix(location, "http://"), strings.HasPrefix(location, "https://"):
+		if _, err := url.ParseRequestURI(location); err != nil {
+			return nil, fmt.Errorf("invalid webhook URL: %w", err)
+		}
+		return &Webhook{URL: location, HTTPClient: client}, nil
+	default:
+		return &LocalDir{Dir: strings.TrimPrefix(location, "file://")}, nil
+	}
+}
+
+// LocalDir writes exports into a directory on disk.
+type LocalDir struct {
+	Dir string
+}
+
+// Put writes the export atomically so a crash never leaves a truncated backup.
+func (d *LocalDir) Put(ctx context.Context, name, contentType string, data []byte) error {
+	if err := os.MkdirAll(d.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(d.Dir, ".export-*")
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	return os.Rename(tmp.Name(), filepath.Join(d.Dir, name))
+}
+
+// List returns the export files in the directory.
+func (d *LocalDir) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(d.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read export directory: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), filePrefix) {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+// Delete removes an export file.
+func (d *LocalDir) Delete(ctx context.Context, name string) error {
+	return os.Remove(filepath.Join(d.Dir, name))
+}
+
+// Webhook POSTs each export to a URL. The file name is sent in the
+// Content-Disposition header.
+type Webhook struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// Put delivers the export to the webhook.
+func (wh *Webhook) Put(ctx context.Context, name, contentType string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	req.Header.Set("User-Agent", "BookshelfApp/1.0 (github.com/ericdahl/bookshelf)")
+
+	resp, err := wh.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver export: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+// prune deletes all but the newest keep exports with the given extension.
+func prune(ctx context.Context, lister Lister, ext string, keep int) (int, error) {
+	names, err := lister.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var matching []string
+	for _, name := range names {
+		// Names end in the export time, so "Z.csv" does not match "Z.goodreads.csv"
+		if strings.HasSuffix(name, "Z."+ext) {
+			matching = append(matching, name)
+		}
+	}
+	if len(matching) <= keep {
+		return 0, nil
+	}
+	// Names embed the export time, so lexical order is chronological
+	sort.Strings(matching)
+	deleted := 0
+	for _, name := range matching[:len(matching)-keep] {
+		if err := lister.Delete(ctx, name); err != nil {
+			return deleted, fmt.Errorf("failed to delete old export %s: %w", name, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
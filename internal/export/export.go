@@ -0,0 +1,282 @@
+// Package export renders the bookshelf as CSV, Goodreads CSV, JSON or Markdown and delivers
+// the result to a destination (local directory, S3 bucket or webhook) on a
+// schedule, pruning old exports so backups do not grow without bound.
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// Format identifies an export file format.
+type Format string
+
+const (
+	FormatCSV       Format = "csv"
+	FormatGoodreads Format = "goodreads" // CSV in the column layout of a Goodreads library export
+	FormatJSON      Format = "json"
+	FormatMarkdown  Format = "markdown"
+)
+
+// IsValid checks if the format is one of the supported formats.
+func (f Format) IsValid() bool {
+	switch f {
+	case FormatCSV, FormatGoodreads, FormatJSON, FormatMarkdown:
+		return true
+	default:
+		return false
+	}
+}
+
+// Extension returns the file extension used for the format.
+func (f Format) Extension() string {
+	switch f {
+	case FormatGoodreads:
+		return "goodreads.csv"
+	case FormatMarkdown:
+		return "md"
+	default:
+		return string(f)
+	}
+}
+
+// ContentType returns the MIME type used when delivering the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV, FormatGoodreads:
+		return "text/csv"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	default:
+		return "application/json"
+	}
+}
+
+// filePrefix starts the name of every export file, so retention only ever
+// touches files written by the exporter.
+const filePrefix = "bookshelf-"
+
+// Filename returns the name of an export taken at t. Names sort chronologically.
+func Filename(t time.Time, format Format) string {
+	return filePrefix + t.UTC().Format("20060102T150405Z") + "." + format.Extension()
+}
+
+// Snapshot is the data written by one export. Differential snapshots hold
+// only the books changed since Since, plus tombstones for deleted books.
+type Snapshot struct {
+	ExportID   int64
+	ExportedAt time.Time
+	Since      *time.Time
+	Books      []model.Book
+	Tags       map[int64][]string     // Tag names by book ID
+	Reads      map[int64][]model.Read // Reading history by book ID, most recent first
+	Deleted    []model.BookTombstone
+}
+
+// Take loads a snapshot and records it as an export run. A nil since takes a
+// full snapshot; otherwise only changes after since are included.
+func Take(ctx context.Context, store db.BookStore, format Format, since *time.Time, now time.Time) (*Snapshot, error) {
+	snap := &Snapshot{ExportedAt: now.UTC(), Since: since, Deleted: []model.BookTombstone{}}
+	var err error
+	if since == nil {
+		snap.Books, err = store.GetBooks(ctx)
+	} else {
+		if snap.Books, err = store.GetBooksChangedSince(ctx, *since); err == nil {
+			snap.Deleted, err = store.GetTombstonesSince(ctx, *since)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load books: %w", err)
+	}
+	if snap.Tags, err = store.GetTagNamesByBook(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load tags: %w", err)
+	}
+	if snap.Reads, err = store.GetAllReads(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load reads: %w", err)
+	}
+
+	run := &model.ExportRun{Format: string(format), Since: since, ExportedAt: snap.ExportedAt,
+		Books: len(snap.Books), Deleted: len(snap.Deleted)}
+	if err := store.RecordExport(ctx, run); err != nil {
+		return nil, err
+	}
+	snap.ExportID = run.ID
+	return snap, nil
+}
+
+// Document is the JSON export file.
+type Document struct {
+	ExportID   int64                 `json:"export_id"`
+	ExportedAt time.Time             `json:"exported_at"`
+	Since      *time.Time            `json:"since,omitempty"` // Set for differential exports
+	Books      []BookRecord          `json:"books"`
+	Deleted    []model.BookTombstone `json:"deleted"` // Books deleted since Since; always empty for full exports
+}
+
+// BookRecord is a book in the JSON export, with its tags and reading history.
+type BookRecord struct {
+	model.Book
+	Tags  []string     `json:"tags"`
+	Reads []model.Read `json:"reads"` // Most recent first
+}
+
+// csvHeader lists the CSV columns in order.
+var csvHeader = []string{
+	"id", "title", "subtitle", "author", "open_library_id", "isbn", "status", "type", "rating", "comments", "description",
+	"series", "series_index", "publish_year", "edition", "page_count", "publisher", "language", "course_code", "semester", "reading_mode", "translated", "cover_url",
+	"date_started", "date_finished", "updated_at", "tags", "read_count", "deleted_at",
+}
+
+// csvTagSeparator joins a book's tags in the tags column. Tag names can
+// contain commas, so a semicolon is used instead.
+const csvTagSeparator = "; "
+
+// Write renders snap in format to w.
+func Write(w io.Writer, snap *Snapshot, format Format) error {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, snap)
+	case FormatGoodreads:
+		return writeGoodreads(w, snap)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		deleted := snap.Deleted
+		if deleted == nil {
+			deleted = []model.BookTombstone{}
+		}
+		books := make([]BookRecord, len(snap.Books))
+		for i, b := range snap.Books {
+			books[i] = BookRecord{Book: b, Tags: snap.Tags[b.ID], Reads: snap.Reads[b.ID]}
+			if books[i].Tags == nil {
+				books[i].Tags = []string{}
+			}
+			if books[i].Reads == nil {
+				books[i].Reads = []model.Read{}
+			}
+		}
+		return enc.Encode(Document{ExportID: snap.ExportID, ExportedAt: snap.ExportedAt.UTC(), Since: snap.Since,
+			Books: books, Deleted: deleted})
+	case FormatMarkdown:
+		return writeMarkdown(w, snap)
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// writeCSV writes one row per book. Deleted books follow as rows carrying only
+// their ID, title, Open Library ID and deleted_at.
+func writeCSV(w io.Writer, snap *Snapshot) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, b := range snap.Books {
+		record := []string{
+			strconv.FormatInt(b.ID, 10), b.Title, optString(b.Subtitle), b.Author, b.OpenLibraryID, b.ISBN, string(b.Status), string(b.Type),
+			optInt(b.Rating), optString(b.Comments), optString(b.Description), optString(b.Series), optInt(b.SeriesIndex), optInt(b.PublishYear),
+			optInt(b.Edition), optInt(b.PageCount), optString(b.Publisher), optString(b.Language), optString(b.CourseCode), optString(b.Semester), string(b.ReadingMode), strconv.FormatBool(b.Translated), optString(b.CoverURL),
+			optTime(b.DateStarted), optTime(b.DateFinished), optTime(b.UpdatedAt),
+			strings.Join(snap.Tags[b.ID], csvTagSeparator), strconv.Itoa(len(snap.Reads[b.ID])), "",
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	for _, t := range snap.Deleted {
+		record := make([]string, len(csvHeader))
+		record[0], record[1], record[4] = strconv.FormatInt(t.BookID, 10), t.Title, t.OpenLibraryID
+		record[len(record)-1] = t.DeletedAt.UTC().Format(time.RFC3339)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// markdownShelves is the order shelves appear in a Markdown export.
+var markdownShelves = []model.BookStatus{model.StatusCurrentlyReading, model.StatusWantToRead, model.StatusRead}
+
+func writeMarkdown(w io.Writer, snap *Snapshot) error {
+	byShelf := make(map[model.BookStatus][]model.Book)
+	for _, b := range snap.Books {
+		byShelf[b.Status] = append(byShelf[b.Status], b)
+	}
+
+	header := fmt.Sprintf("# Bookshelf\n\nExported %s.\n", snap.ExportedAt.UTC().Format(time.RFC1123))
+	if snap.Since != nil {
+		header = fmt.Sprintf("# Bookshelf changes\n\nChanges from %s to %s.\n",
+			snap.Since.UTC().Format(time.RFC1123), snap.ExportedAt.UTC().Format(time.RFC1123))
+	}
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	for _, shelf := range markdownShelves {
+		shelfBooks := byShelf[shelf]
+		if len(shelfBooks) == 0 {
+			continue
+		}
+		sort.SliceStable(shelfBooks, func(i, j int) bool { return shelfBooks[i].FullTitle() < shelfBooks[j].FullTitle() })
+		if _, err := fmt.Fprintf(w, "\n## %s (%d)\n\n", shelf, len(shelfBooks)); err != nil {
+			return err
+		}
+		for _, b := range shelfBooks {
+			line := fmt.Sprintf("- **%s**", b.FullTitle())
+			if b.Author != "" {
+				line += " by " + b.Author
+			}
+			if b.Rating != nil {
+				line += fmt.Sprintf(" — %d/10", *b.Rating)
+			}
+			if b.Comments != nil && *b.Comments != "" && !b.CommentsSpoiler {
+				line += "\n  > " + strings.ReplaceAll(*b.Comments, "\n", " ")
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	if len(snap.Deleted) > 0 {
+		if _, err := fmt.Fprintf(w, "\n## Removed (%d)\n\n", len(snap.Deleted)); err != nil {
+			return err
+		}
+		for _, t := range snap.Deleted {
+			if _, err := fmt.Fprintf(w, "- ~~%s~~\n", t.Title); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func optInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+func optTime(v *time.Time) string {
+	if v == nil {
+		return ""
+	}
+	return v.UTC().Format(time.RFC3339)
+}
+
+func optString(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
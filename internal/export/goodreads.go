@@ -0,0 +1,118 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// goodreadsHeader is the column layout of a Goodreads library export, which
+// Goodreads and most other trackers can import.
+var goodreadsHeader = []string{
+	"Book Id", "Title", "Author", "Author l-f", "Additional Authors", "ISBN", "ISBN13", "My Rating",
+	"Average Rating", "Publisher", "Binding", "Number of Pages", "Year Published", "Original Publication Year",
+	"Date Read", "Date Added", "Bookshelves", "Bookshelves with positions", "Exclusive Shelf", "My Review",
+	"Spoiler", "Private Notes", "Read Count", "Owned Copies",
+}
+
+// goodreadsShelves maps statuses to the Goodreads exclusive shelves.
+var goodreadsShelves = map[model.BookStatus]string{
+	model.StatusWantToRead:       "to-read",
+	model.StatusCurrentlyReading: "currently-reading",
+	model.StatusRead:             "read",
+}
+
+// writeGoodreads writes one row per book in the Goodreads column layout.
+// Goodreads IDs, average ratings and the date a book was added are not
+// stored and are left empty. Ratings are halved to Goodreads' five stars,
+// rounding up, and tags become shelves. Deleted books cannot be
+// expressed and are left out.
+func writeGoodreads(w io.Writer, snap *Snapshot) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(goodreadsHeader); err != nil {
+		return err
+	}
+	for _, b := range snap.Books {
+		authors := model.AuthorNames(b.Author)
+		var author, authorLF string
+		if len(authors) > 0 {
+			author, authorLF = authors[0], lastFirst(authors[0])
+			authors = authors[1:]
+		}
+		isbn, isbn13 := "", ""
+		if digits := strings.ReplaceAll(b.ISBN, "-", ""); len(digits) == 13 {
+			isbn13 = goodreadsText(digits)
+		} else if digits != "" {
+			isbn = goodreadsText(digits)
+		}
+		rating := "0"
+		if b.Rating != nil {
+			rating = strconv.Itoa((*b.Rating + 1) / 2)
+		}
+		binding := ""
+		if b.Type == model.TypeAudiobook {
+			binding = "Audiobook"
+		}
+		dateRead := ""
+		if b.DateFinished != nil {
+			dateRead = b.DateFinished.UTC().Format("2006/01/02")
+		}
+		shelves := make([]string, 0, len(snap.Tags[b.ID]))
+		for _, tag := range snap.Tags[b.ID] {
+			shelves = append(shelves, goodreadsShelf(tag))
+		}
+		spoiler := ""
+		if b.CommentsSpoiler {
+			spoiler = "true"
+		}
+		record := []string{
+			"", b.FullTitle(), author, authorLF, strings.Join(authors, ", "), isbn, isbn13, rating,
+			"", optString(b.Publisher), binding, optInt(b.PageCount), "", optInt(b.PublishYear),
+			dateRead, "", strings.Join(shelves, ", "), "", goodreadsShelves[b.Status], optString(b.Comments),
+			spoiler, "", strconv.Itoa(len(snap.Reads[b.ID])), "0",
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// goodreadsText wraps a value as a spreadsheet text formula, the way
+// Goodreads writes ISBNs so leading zeros survive.
+func goodreadsText(s string) string {
+	return `="` + s + `"`
+}
+
+// lastFirst turns "Frank Herbert" into "Herbert, Frank".
+func lastFirst(name string) string {
+	i := strings.LastIndex(name, " ")
+	if i < 0 {
+		return name
+	}
+	return name[i+1:] + ", " + name[:i]
+}
+
+// goodreadsShelf turns a tag name into a Goodreads shelf name: lower case,
+// with runs of anything but letters and digits replaced by a hyphen.
+func goodreadsShelf(tag string) string {
+	var sb strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(tag) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	return sb.String()
+}
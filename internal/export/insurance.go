@@ -0,0 +1,211 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// InsuranceFilename returns the name of an insurance archive taken at t.
+func InsuranceFilename(t time.Time) string {
+	return filePrefix + "insurance-" + t.UTC().Format("20060102T150405Z") + ".zip"
+}
+
+// Inventory is what an insurance archive documents: the books owned, on the
+// shelf and not archived, each with its latest estimated value.
+type Inventory struct {
+	TakenAt time.Time
+	Items   []InventoryItem
+}
+
+// InventoryItem is a book owned with its estimated value, nil when its ISBN
+// has no market value sample.
+type InventoryItem struct {
+	Book  model.Book
+	Value *model.MarketValue
+}
+
+// TakeInventory loads the books owned and their latest market values.
+func TakeInventory(ctx context.Context, store db.BookStore, now time.Time) (*Inventory, error) {
+	books, _, err := store.GetBooksPage(ctx, db.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load books: %w", err)
+	}
+	values, err := store.GetLatestMarketValues(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load market values: %w", err)
+	}
+	inv := &Inventory{TakenAt: now.UTC(), Items: make([]InventoryItem, len(books))}
+	for i, b := range books {
+		inv.Items[i].Book = b
+		if v, ok := values[b.ISBN]; ok && b.ISBN != "" {
+			inv.Items[i].Value = &v
+		}
+	}
+	return inv, nil
+}
+
+// Covers opens cached cover images, as covers.Cache does.
+type Covers interface {
+	Open(ctx context.Context, hash string) (*os.File, *model.CoverImage, error)
+}
+
+// coverExtensions maps the content types of cached covers to file extensions.
+var coverExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// insuranceHeader lists the columns of inventory.csv in order.
+var insuranceHeader = []string{
+	"id", "title", "author", "isbn", "edition", "publish_year", "type", "added_at",
+	"estimated_value", "currency", "valued_at", "value_source", "cover",
+}
+
+// WriteInsurance writes inv to w as a ZIP archive for insurance
+// documentation: inventory.csv with a row per book, inventory.md with the
+// same listing and the totals for printing, and the cached cover of each
+// book under covers/. With nil covers, or for covers not cached, the archive
+// has no image of the book.
+func WriteInsurance(ctx context.Context, w io.Writer, inv *Inventory, covers Covers) error {
+	zw := zip.NewWriter(w)
+	coverFiles := make(map[int64]string)
+	if covers != nil {
+		for _, item := range inv.Items {
+			if item.Book.CoverHash == nil {
+				continue
+			}
+			name, err := writeCover(ctx, zw, covers, item.Book, inv.TakenAt)
+			if err != nil {
+				// A cover gone missing shouldn't cost the whole inventory
+				slog.Warn("Failed to add cover to insurance export", "id", item.Book.ID, "error", err)
+				continue
+			}
+			coverFiles[item.Book.ID] = name
+		}
+	}
+
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: "inventory.csv", Method: zip.Deflate, Modified: inv.TakenAt})
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	if err := cw.Write(insuranceHeader); err != nil {
+		return err
+	}
+	for _, item := range inv.Items {
+		b := item.Book
+		value, currency, valuedAt, source := "", "", "", ""
+		if v := item.Value; v != nil {
+			value, currency, valuedAt, source = strconv.FormatFloat(v.Value, 'f', 2, 64), v.Currency, v.SampledAt.UTC().Format(time.RFC3339), v.Source
+		}
+		if err := cw.Write([]string{
+			strconv.FormatInt(b.ID, 10), b.FullTitle(), b.Author, b.ISBN, optInt(b.Edition), optInt(b.PublishYear), string(b.Type), optTime(b.AddedAt),
+			value, currency, valuedAt, source, coverFiles[b.ID],
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+
+	f, err = zw.CreateHeader(&zip.FileHeader{Name: "inventory.md", Method: zip.Deflate, Modified: inv.TakenAt})
+	if err != nil {
+		return err
+	}
+	if err := writeInventoryMarkdown(f, inv, coverFiles); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// writeCover copies the cached cover of book into the archive and returns
+// its name there.
+func writeCover(ctx context.Context, zw *zip.Writer, covers Covers, book model.Book, modified time.Time) (string, error) {
+	f, image, err := covers.Open(ctx, *book.CoverHash)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	ext, ok := coverExtensions[image.ContentType]
+	if !ok {
+		ext = "img"
+	}
+	name := fmt.Sprintf("covers/%d.%s", book.ID, ext)
+	// Images are compressed already
+	dst, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: modified})
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(dst, f); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// writeInventoryMarkdown lists the books owned with their estimated values,
+// after the total value in each currency.
+func writeInventoryMarkdown(w io.Writer, inv *Inventory, coverFiles map[int64]string) error {
+	totals := make(map[string]float64)
+	valued := 0
+	for _, item := range inv.Items {
+		if item.Value != nil {
+			totals[item.Value.Currency] += item.Value.Value
+			valued++
+		}
+	}
+	if _, err := fmt.Fprintf(w, "# Bookshelf inventory\n\nTaken %s. %d books, %d with an estimated value.\n",
+		inv.TakenAt.UTC().Format(time.RFC1123), len(inv.Items), valued); err != nil {
+		return err
+	}
+	currencies := make([]string, 0, len(totals))
+	for currency := range totals {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	if len(currencies) > 0 {
+		if _, err := io.WriteString(w, "\n## Estimated value\n\n"); err != nil {
+			return err
+		}
+		for _, currency := range currencies {
+			if _, err := fmt.Fprintf(w, "- %s %.2f\n", currency, totals[currency]); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := io.WriteString(w, "\n## Books\n\n| Title | Author | ISBN | Value | Cover |\n| --- | --- | --- | --- | --- |\n"); err != nil {
+		return err
+	}
+	cell := strings.NewReplacer("|", `\|`, "\n", " ")
+	for _, item := range inv.Items {
+		b := item.Book
+		value := ""
+		if v := item.Value; v != nil {
+			value = fmt.Sprintf("%s %.2f (%s)", v.Currency, v.Value, v.SampledAt.UTC().Format(model.DateLayout))
+		}
+		cover := ""
+		if name, ok := coverFiles[b.ID]; ok {
+			cover = fmt.Sprintf("[%s](%s)", name, name)
+		}
+		if _, err := fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n",
+			cell.Replace(b.FullTitle()), cell.Replace(b.Author), b.ISBN, value, cover); err != nil {
+			return err
+		}
+	}
+	return nil
+}
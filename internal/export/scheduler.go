@@ -0,0 +1,156 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+)
+
+// Schedule is how often a scheduled export runs.
+type Schedule string
+
+const (
+	ScheduleNightly Schedule = "nightly"
+	ScheduleWeekly  Schedule = "weekly"
+)
+
+// Period returns the time between runs, or 0 for an unknown schedule.
+func (s Schedule) Period() time.Duration {
+	switch s {
+	case ScheduleNightly:
+		return 24 * time.Hour
+	case ScheduleWeekly:
+		return 7 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// lastRunSetting is the settings key holding the time of the last successful
+// export, so restarts do not reset the schedule.
+const lastRunSetting = "export_last_run"
+
+// Exporter runs exports of the whole bookshelf to a destination.
+type Exporter struct {
+	Store       db.BookStore
+	Format      Format
+	Destination Destination
+	Keep        int  // Number of exports retained at the destination; 0 keeps all
+	Changes     bool // Export only changes since the previous scheduled export
+
+	now func() time.Time // Overridable for tests
+}
+
+// NewExporter creates an Exporter.
+func NewExporter(store db.BookStore, format Format, dest Destination, keep int) (*Exporter, error) {
+	if !format.IsValid() {
+		return nil, fmt.Errorf("invalid export format: %s", format)
+	}
+	if keep < 0 {
+		return nil, fmt.Errorf("export retention must not be negative")
+	}
+	return &Exporter{Store: store, Format: format, Destination: dest, Keep: keep}, nil
+}
+
+func (e *Exporter) clock() time.Time {
+	if e.now != nil {
+		return e.now()
+	}
+	return time.Now()
+}
+
+// lastRun returns the time of the last successful scheduled export.
+func (e *Exporter) lastRun(ctx context.Context) (time.Time, bool) {
+	value, ok, err := e.Store.GetSetting(ctx, lastRunSetting)
+	if err != nil || !ok {
+		return time.Time{}, false
+	}
+	last, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return last, true
+}
+
+// RunOnce exports the bookshelf, applies retention and records the run.
+// Returns the name of the export file.
+func (e *Exporter) RunOnce(ctx context.Context) (string, error) {
+	var since *time.Time
+	if last, ok := e.lastRun(ctx); ok && e.Changes {
+		since = &last
+	}
+
+	now := e.clock().UTC()
+	snap, err := Take(ctx, e.Store, e.Format, since, now)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, snap, e.Format); err != nil {
+		return "", fmt.Errorf("failed to render export: %w", err)
+	}
+	name := Filename(now, e.Format)
+	if err := e.Destination.Put(ctx, name, e.Format.ContentType(), buf.Bytes()); err != nil {
+		return "", err
+	}
+	slog.Info("Export written", "name", name, "exportID", snap.ExportID, "books", len(snap.Books),
+		"deleted", len(snap.Deleted), "bytes", buf.Len())
+
+	if err := e.Store.SetSetting(ctx, lastRunSetting, now.Format(time.RFC3339Nano)); err != nil {
+		slog.Warn("Failed to record export time", "error", err)
+	}
+
+	// Differential exports depend on every earlier one, so they are never pruned
+	if lister, ok := e.Destination.(Lister); ok && e.Keep > 0 && !e.Changes {
+		deleted, err := prune(ctx, lister, e.Format.Extension(), e.Keep)
+		if err != nil {
+			// The new export is safe; old ones will be pruned next time
+			slog.Warn("Failed to apply export retention", "error", err)
+		} else if deleted > 0 {
+			slog.Info("Pruned old exports", "deleted", deleted, "keep", e.Keep)
+		}
+	}
+	return name, nil
+}
+
+// nextRun returns how long to wait before the next export. Exports overdue
+// according to the last recorded run (or never run) are due immediately.
+func (e *Exporter) nextRun(ctx context.Context, period time.Duration) time.Duration {
+	last, ok := e.lastRun(ctx)
+	if !ok {
+		return 0
+	}
+	wait := last.Add(period).Sub(e.clock())
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// Run exports on schedule until ctx is cancelled. Failed exports are retried
+// after an hour rather than waiting for the next scheduled run.
+func (e *Exporter) Run(ctx context.Context, schedule Schedule) {
+	period := schedule.Period()
+	slog.Info("Starting scheduled export", "schedule", schedule, "format", e.Format, "keep", e.Keep, "changesOnly", e.Changes)
+
+	timer := time.NewTimer(e.nextRun(ctx, period))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping scheduled export")
+			return
+		case <-timer.C:
+			wait := period
+			if _, err := e.RunOnce(ctx); err != nil {
+				slog.Error("Scheduled export failed", "error", err)
+				wait = min(time.Hour, period)
+			}
+			timer.Reset(wait)
+		}
+	}
+}
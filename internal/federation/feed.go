@@ -0,0 +1,190 @@
+// Package federation lets bookshelf instances follow each other. Each instance
+// publishes its activity timeline as a JSON Feed (https://jsonfeed.org), and
+// followed feeds (JSON Feed or RSS 2.0) are polled and cached locally.
+package federation
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// JSONFeedVersion is the JSON Feed version this package produces.
+const JSONFeedVersion = "https://jsonfeed.org/version/1.1"
+
+// JSONFeed is a JSON Feed 1.1 document.
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	FeedURL     string         `json:"feed_url,omitempty"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+// JSONFeedItem is a single JSON Feed item. The _bookshelf extension carries
+// structured data so other bookshelf instances don't have to parse the text.
+type JSONFeedItem struct {
+	ID            string             `json:"id"`
+	URL           string             `json:"url,omitempty"`
+	Title         string             `json:"title,omitempty"`
+	ContentText   string             `json:"content_text"`
+	DatePublished time.Time          `json:"date_published"`
+	Bookshelf     *BookshelfFeedData `json:"_bookshelf,omitempty"`
+}
+
+// BookshelfFeedData is the bookshelf-specific JSON Feed extension.
+type BookshelfFeedData struct {
+	Kind   model.ActivityKind `json:"kind"`
+	Title  string             `json:"book_title"`
+	Author string             `json:"author,omitempty"`
+	Status model.BookStatus   `json:"status,omitempty"`
+}
+
+// BuildFeed renders local activities as a JSON Feed.
+func BuildFeed(title, homeURL, feedURL string, activities []model.Activity) JSONFeed {
+	feed := JSONFeed{
+		Version:     JSONFeedVersion,
+		Title:       title,
+		HomePageURL: homeURL,
+		FeedURL:     feedURL,
+		Items:       []JSONFeedItem{},
+	}
+	for _, a := range activities {
+		feed.Items = append(feed.Items, JSONFeedItem{
+			ID:            fmt.Sprintf("activity-%d", a.ID),
+			URL:           homeURL,
+			Title:         a.Title,
+			ContentText:   a.Summary,
+			DatePublished: a.OccurredAt,
+			Bookshelf: &BookshelfFeedData{
+				Kind:   a.Kind,
+				Title:  a.Title,
+				Author: a.Author,
+				Status: a.Status,
+			},
+		})
+	}
+	return feed
+}
+
+// rssDocument maps the subset of RSS 2.0 used for activity feeds.
+type rssDocument struct {
+	Channel struct {
+		Items []struct {
+			GUID        string `xml:"guid"`
+			Title       string `xml:"title"`
+			Link        string `xml:"link"`
+			Description string `xml:"description"`
+			PubDate     string `xml:"pubDate"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// ParseFeed parses a JSON Feed or RSS 2.0 document into activities ready to be
+// cached. The caller is responsible for setting FollowID.
+func ParseFeed(body []byte) ([]model.Activity, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("feed is empty")
+	}
+	switch trimmed[0] {
+	case '{':
+		return parseJSONFeed(trimmed)
+	case '<':
+		return parseRSS(trimmed)
+	default:
+		return nil, fmt.Errorf("unrecognized feed format")
+	}
+}
+
+func parseJSONFeed(body []byte) ([]model.Activity, error) {
+	var feed JSONFeed
+	if err := json.Unmarshal(body, &feed); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON feed: %w", err)
+	}
+	if !strings.HasPrefix(feed.Version, "https://jsonfeed.org/version/") {
+		return nil, fmt.Errorf("not a JSON feed (version %q)", feed.Version)
+	}
+
+	activities := make([]model.Activity, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		if item.ID == "" {
+			continue
+		}
+		id := item.ID
+		a := model.Activity{
+			RemoteID:   &id,
+			Kind:       model.ActivityStatusChanged,
+			Title:      item.Title,
+			Summary:    item.ContentText,
+			OccurredAt: item.DatePublished,
+		}
+		if item.URL != "" {
+			u := item.URL
+			a.URL = &u
+		}
+		if ext := item.Bookshelf; ext != nil {
+			if ext.Kind.IsValid() {
+				a.Kind = ext.Kind
+			}
+			if ext.Title != "" {
+				a.Title = ext.Title
+			}
+			a.Author = ext.Author
+			a.Status = ext.Status
+		}
+		if a.Title == "" {
+			a.Title = a.Summary
+		}
+		if a.OccurredAt.IsZero() {
+			a.OccurredAt = time.Now().UTC()
+		}
+		activities = append(activities, a)
+	}
+	return activities, nil
+}
+
+func parseRSS(body []byte) ([]model.Activity, error) {
+	var doc rssDocument
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode RSS feed: %w", err)
+	}
+
+	activities := make([]model.Activity, 0, len(doc.Channel.Items))
+	for _, item := range doc.Channel.Items {
+		id := item.GUID
+		if id == "" {
+			id = item.Link
+		}
+		if id == "" {
+			continue
+		}
+		a := model.Activity{
+			RemoteID: &id,
+			Kind:     model.ActivityStatusChanged,
+			Title:    item.Title,
+			Summary:  item.Description,
+		}
+		if a.Summary == "" {
+			a.Summary = item.Title
+		}
+		if item.Link != "" {
+			link := item.Link
+			a.URL = &link
+		}
+		if t, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil {
+			a.OccurredAt = t.UTC()
+		} else if t, err := time.Parse(time.RFC1123, item.PubDate); err == nil {
+			a.OccurredAt = t.UTC()
+		} else {
+			a.OccurredAt = time.Now().UTC()
+		}
+		activities = append(activities, a)
+	}
+	return activities, nil
+}
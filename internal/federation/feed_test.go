@@ -0,0 +1,100 @@
+package federation
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestBuildAndParseFeedRoundTrip(t *testing.T) {
+	occurred := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	bookID := int64(7)
+	activities := []model.Activity{
+		{
+			ID:         42,
+			BookID:     &bookID,
+			Kind:       model.ActivityBookFinished,
+			Title:      "Dune",
+			Author:     "Frank Herbert",
+			Status:     model.StatusRead,
+			Summary:    `Finished reading "Dune"`,
+			OccurredAt: occurred,
+		},
+	}
+
+	feed := BuildFeed("Test shelf", "http://example.com/", "http://example.com/api/feed.json", activities)
+	if feed.Version != JSONFeedVersion {
+		t.Errorf("Expected version %s, got %s", JSONFeedVersion, feed.Version)
+	}
+
+	body, err := json.Marshal(feed)
+	if err != nil {
+		t.Fatalf("Failed to marshal feed: %v", err)
+	}
+
+	parsed, err := ParseFeed(body)
+	if err != nil {
+		t.Fatalf("ParseFeed failed: %v", err)
+	}
+	if len(parsed) != 1 {
+		t.Fatalf("Expected 1 activity, got %d", len(parsed))
+	}
+
+	got := parsed[0]
+	if got.RemoteID == nil || *got.RemoteID != "activity-42" {
+		t.Errorf("Expected remote ID activity-42, got %v", got.RemoteID)
+	}
+	if got.Kind != model.ActivityBookFinished {
+		t.Errorf("Expected kind %s, got %s", model.ActivityBookFinished, got.Kind)
+	}
+	if got.Title != "Dune" || got.Author != "Frank Herbert" || got.Status != model.StatusRead {
+		t.Errorf("Unexpected book data: %+v", got)
+	}
+	if !got.OccurredAt.Equal(occurred) {
+		t.Errorf("Expected occurred_at %v, got %v", occurred, got.OccurredAt)
+	}
+}
+
+func TestParseFeedRSS(t *testing.T) {
+	rss := `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Shelf</title>
+  <item><guid>post-1</guid><title>Finished Dune</title><link>http://example.com/1</link>
+    <description>Loved it</description><pubDate>Sun, 01 Jun 2025 12:00:00 +0000</pubDate></item>
+  <item><title>No id or link</title></item>
+</channel></rss>`
+
+	parsed, err := ParseFeed([]byte(rss))
+	if err != nil {
+		t.Fatalf("ParseFeed failed: %v", err)
+	}
+	if len(parsed) != 1 {
+		t.Fatalf("Expected 1 activity (items without guid/link are skipped), got %d", len(parsed))
+	}
+	if parsed[0].Summary != "Loved it" {
+		t.Errorf("Expected summary 'Loved it', got %q", parsed[0].Summary)
+	}
+	if parsed[0].OccurredAt.Year() != 2025 {
+		t.Errorf("Expected pubDate to be parsed, got %v", parsed[0].OccurredAt)
+	}
+}
+
+func TestParseFeedInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty", ""},
+		{"plain text", "hello"},
+		{"json without version", `{"items": []}`},
+		{"malformed xml", "<rss><channel>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseFeed([]byte(tt.body)); err == nil {
+				t.Errorf("Expected error for %s feed", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/safehttp"
+)
+
+// maxFeedSize bounds how much of a remote feed is read.
+const maxFeedSize = 2 * 1024 * 1024 // 2 MB
+
+// Fetcher polls followed feeds and caches their activity.
+type Fetcher struct {
+	Store      db.FollowStore
+	HTTPClient *http.Client
+}
+
+// NewFetcher creates a Fetcher with a sensible HTTP timeout. Feeds are
+// fetched on behalf of whoever follows them, so it can't reach the server's
+// own networks.
+func NewFetcher(store db.FollowStore) *Fetcher {
+	return &Fetcher{
+		Store:      store,
+		HTTPClient: safehttp.NewClient(15 * time.Second),
+	}
+}
+
+// RefreshFollow fetches a single followed feed and stores any new activity.
+// The fetch outcome is recorded on the follow either way. Returns the number of new entries.
+func (f *Fetcher) RefreshFollow(ctx context.Context, follow model.Follow) (int, error) {
+	inserted, fetchErr := f.fetch(ctx, follow)
+
+	var errMsg *string
+	if fetchErr != nil {
+		msg := fetchErr.Error()
+		errMsg = &msg
+		slog.Warn("Failed to refresh followed feed", logging.Redact("feedURL", follow.FeedURL), "error", fetchErr)
+	} else {
+		slog.Info("Refreshed followed feed", logging.Redact("feedURL", follow.FeedURL), "newItems", inserted)
+	}
+	if err := f.Store.UpdateFollowFetchStatus(ctx, follow.ID, time.Now().UTC(), errMsg); err != nil {
+		slog.Error("Failed to record follow fetch status", "id", follow.ID, "error", err)
+	}
+	return inserted, fetchErr
+}
+
+func (f *Fetcher) fetch(ctx context.Context, follow model.Follow) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, follow.FeedURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create feed request: %w", err)
+	}
+	req.Header.Set("Accept", "application/feed+json, application/json, application/rss+xml;q=0.9")
+	req.Header.Set("User-Agent", "BookshelfApp/1.0 (github.com/ericdahl/bookshelf)")
+
+	resp, err := f.HTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("feed returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read feed: %w", err)
+	}
+
+	activities, err := ParseFeed(body)
+	if err != nil {
+		return 0, err
+	}
+	return f.Store.SaveRemoteActivities(ctx, follow.ID, activities)
+}
+
+// RefreshAll refreshes every followed feed. Errors are recorded per follow and logged.
+func (f *Fetcher) RefreshAll(ctx context.Context) {
+	follows, err := f.Store.GetFollows(ctx)
+	if err != nil {
+		slog.Error("Failed to list followed feeds", "error", err)
+		return
+	}
+	for _, follow := range follows {
+		if ctx.Err() != nil {
+			return
+		}
+		f.RefreshFollow(ctx, follow)
+	}
+}
+
+// Run refreshes all followed feeds every interval until ctx is cancelled.
+func (f *Fetcher) Run(ctx context.Context, interval time.Duration) {
+	slog.Info("Starting followed feed poller", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	f.RefreshAll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping followed feed poller")
+			return
+		case <-ticker.C:
+			f.RefreshAll(ctx)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package federation
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/safehttp"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTestStore(t *testing.T) *db.SQLiteBookStore {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := db.CreateSchema(database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	return db.NewSQLiteBookStore(database)
+}
+
+func TestFetcherRefreshFollow(t *testing.T) {
+	ctx := context.Background()
+	remote := BuildFeed("Remote", "http://remote/", "http://remote/api/feed.json", []model.Activity{
+		{ID: 1, Kind: model.ActivityBookAdded, Title: "Remote Book", Summary: "Added", OccurredAt: time.Now().UTC()},
+		{ID: 2, Kind: model.ActivityBookFinished, Title: "Other Book", Summary: "Finished", OccurredAt: time.Now().UTC()},
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(remote)
+	}))
+	defer server.Close()
+
+	store := setupTestStore(t)
+	follow := model.Follow{FeedURL: server.URL, Name: "Friend"}
+	if _, err := store.AddFollow(ctx, &follow); err != nil {
+		t.Fatalf("AddFollow failed: %v", err)
+	}
+
+	fetcher := NewFetcher(store)
+	fetcher.HTTPClient = server.Client() // The test server is on loopback
+	inserted, err := fetcher.RefreshFollow(ctx, follow)
+	if err != nil {
+		t.Fatalf("RefreshFollow failed: %v", err)
+	}
+	if inserted != 2 {
+		t.Errorf("Expected 2 new items, got %d", inserted)
+	}
+
+	// A second refresh must not duplicate cached items
+	inserted, err = fetcher.RefreshFollow(ctx, follow)
+	if err != nil {
+		t.Fatalf("Second RefreshFollow failed: %v", err)
+	}
+	if inserted != 0 {
+		t.Errorf("Expected 0 new items on second refresh, got %d", inserted)
+	}
+
+	timeline, err := store.ListActivities(ctx, 10, false)
+	if err != nil {
+		t.Fatalf("ListActivities failed: %v", err)
+	}
+	if len(timeline) != 2 {
+		t.Fatalf("Expected 2 timeline entries, got %d", len(timeline))
+	}
+	if timeline[0].Source != "Friend" {
+		t.Errorf("Expected source Friend, got %s", timeline[0].Source)
+	}
+
+	updated, err := store.GetFollowByID(ctx, follow.ID)
+	if err != nil {
+		t.Fatalf("GetFollowByID failed: %v", err)
+	}
+	if updated.LastFetchedAt == nil || updated.LastError != nil {
+		t.Errorf("Expected successful fetch status, got %+v", updated)
+	}
+}
+
+func TestFetcherRefreshFollowRecordsError(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	}))
+	defer server.Close()
+
+	store := setupTestStore(t)
+	follow := model.Follow{FeedURL: server.URL, Name: "Broken"}
+	if _, err := store.AddFollow(ctx, &follow); err != nil {
+		t.Fatalf("AddFollow failed: %v", err)
+	}
+
+	fetcher := NewFetcher(store)
+	fetcher.HTTPClient = server.Client()
+	if _, err := fetcher.RefreshFollow(ctx, follow); err == nil {
+		t.Fatal("Expected error for failing feed")
+	}
+
+	updated, err := store.GetFollowByID(ctx, follow.ID)
+	if err != nil {
+		t.Fatalf("GetFollowByID failed: %v", err)
+	}
+	if updated.LastError == nil {
+		t.Error("Expected last_error to be recorded")
+	}
+}
+
+func TestFetcherRefusesOwnNetworks(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(BuildFeed("Internal", "http://internal/", "", []model.Activity{
+			{ID: 1, Kind: model.ActivityBookAdded, Title: "Internal Book", Summary: "Added"},
+		}))
+	}))
+	defer server.Close()
+
+	store := setupTestStore(t)
+	follow := model.Follow{FeedURL: server.URL, Name: "Internal"}
+	if _, err := store.AddFollow(ctx, &follow); err != nil {
+		t.Fatalf("AddFollow failed: %v", err)
+	}
+
+	_, err := NewFetcher(store).RefreshFollow(ctx, follow)
+	if !errors.Is(err, safehttp.ErrForbiddenAddress) {
+		t.Fatalf("Expected a feed on loopback to be refused, got %v", err)
+	}
+	timeline, err := store.ListActivities(ctx, 10, false)
+	if err != nil {
+		t.Fatalf("ListActivities failed: %v", err)
+	}
+	if len(timeline) != 0 {
+		t.Errorf("Expected nothing mirrored from the refused feed, got %+v", timeline)
+	}
+}
@@ -0,0 +1,297 @@
+// Package health keeps rolling statistics of the outbound requests the server
+// makes to metadata providers and other integrations, so an operator can see
+// which upstream is slow or failing. Clients are instrumented by wrapping
+// their transport; each request is attributed to a provider by host.
+package health
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/metrics"
+)
+
+// Window is how far back the statistics reach.
+const Window = 15 * time.Minute
+
+// bucketSize is the granularity of the rolling window.
+const bucketSize = time.Minute
+
+// latencySamples is how many recent latencies are kept per provider for percentiles.
+const latencySamples = 200
+
+// Thresholds for the reported status.
+const (
+	degradedErrorRate = 0.1
+	downErrorRate     = 0.5
+	slowLatency       = 5 * time.Second
+	minRequests       = 3 // Fewer requests than this are too few to call a provider down
+)
+
+// Status summarizes how a provider is doing.
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusDegraded Status = "degraded" // Some errors, or slow
+	StatusDown     Status = "down"     // Mostly failing
+	StatusIdle     Status = "idle"     // No requests in the window
+)
+
+// DefaultHosts attributes requests to well-known providers by host (matched
+// with subdomains), whichever client makes them.
+var DefaultHosts = map[string]string{
+	"openlibrary.org": "openlibrary",
+	"googleapis.com":  "google_books",
+	"hardcover.app":   "hardcover",
+	"goodreads.com":   "goodreads",
+	"bsky.social":     "bluesky",
+	"amazonaws.com":   "s3",
+}
+
+// ProviderStats is the health of one provider over the window.
+type ProviderStats struct {
+	Name          string     `json:"name"`
+	Status        Status     `json:"status"`
+	Requests      int        `json:"requests"`
+	Errors        int        `json:"errors"` // Transport failures, 429s and 5xx responses
+	ErrorRate     float64    `json:"error_rate"`
+	AvgLatencyMS  int64      `json:"avg_latency_ms"`
+	P95LatencyMS  int64      `json:"p95_latency_ms"`
+	MaxLatencyMS  int64      `json:"max_latency_ms"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+}
+
+type bucket struct {
+	start    time.Time
+	requests int
+	errors   int
+	latency  time.Duration
+	max      time.Duration
+}
+
+type sample struct {
+	at      time.Time
+	latency time.Duration
+}
+
+// provider holds the raw statistics of one provider.
+type provider struct {
+	buckets     []bucket
+	samples     []sample // Ring of recent latencies
+	next        int
+	lastSuccess time.Time
+	lastError   time.Time
+	lastErrMsg  string
+}
+
+// Monitor collects request statistics per provider. It is safe for concurrent use.
+type Monitor struct {
+	Hosts map[string]string // Host suffix to provider name; see DefaultHosts
+
+	mu        sync.Mutex
+	providers map[string]*provider
+	now       func() time.Time
+}
+
+// NewMonitor creates a monitor attributing requests with DefaultHosts.
+func NewMonitor() *Monitor {
+	return &Monitor{Hosts: DefaultHosts, providers: make(map[string]*provider), now: time.Now}
+}
+
+// Instrument wraps client's transport so its requests are recorded. Requests
+// to a host in m.Hosts count towards that provider; the rest count towards
+// name. It returns client for convenience.
+func (m *Monitor) Instrument(client *http.Client, name string) *http.Client {
+	client.Transport = &transport{monitor: m, name: name, base: client.Transport}
+	return client
+}
+
+// providerFor returns the provider a request to host is attributed to.
+func (m *Monitor) providerFor(host, fallback string) string {
+	host = strings.ToLower(host)
+	for suffix, provider := range m.Hosts {
+		if host == suffix || strings.HasSuffix(host, "."+suffix) {
+			return provider
+		}
+	}
+	return fallback
+}
+
+// Record adds the outcome of one request to the provider's statistics. A nil
+// errMsg is a success.
+func (m *Monitor) Record(name string, latency time.Duration, errMsg *string) {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.providers[name]
+	if !ok {
+		p = &provider{}
+		m.providers[name] = p
+	}
+	start := now.Truncate(bucketSize)
+	if n := len(p.buckets); n == 0 || p.buckets[n-1].start.Before(start) {
+		p.buckets = append(p.buckets, bucket{start: start})
+	}
+	p.prune(now)
+	b := &p.buckets[len(p.buckets)-1]
+	b.requests++
+	b.latency += latency
+	b.max = max(b.max, latency)
+
+	if len(p.samples) < latencySamples {
+		p.samples = append(p.samples, sample{now, latency})
+	} else {
+		p.samples[p.next] = sample{now, latency}
+		p.next = (p.next + 1) % latencySamples
+	}
+
+	if errMsg != nil {
+		b.errors++
+		p.lastError = now
+		p.lastErrMsg = *errMsg
+	} else {
+		p.lastSuccess = now
+	}
+}
+
+// prune drops buckets that have left the window.
+func (p *provider) prune(now time.Time) {
+	cutoff := now.Add(-Window)
+	i := 0
+	for i < len(p.buckets) && !p.buckets[i].start.After(cutoff) {
+		i++
+	}
+	p.buckets = p.buckets[i:]
+}
+
+// Stats returns the health of every provider contacted since startup, sorted
+// by name. Providers with no requests in the window are reported as idle.
+func (m *Monitor) Stats() []ProviderStats {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]ProviderStats, 0, len(m.providers))
+	for name, p := range m.providers {
+		p.prune(now)
+		s := ProviderStats{Name: name}
+		var total time.Duration
+		var maxLatency time.Duration
+		for _, b := range p.buckets {
+			s.Requests += b.requests
+			s.Errors += b.errors
+			total += b.latency
+			maxLatency = max(maxLatency, b.max)
+		}
+		if s.Requests > 0 {
+			s.ErrorRate = float64(s.Errors) / float64(s.Requests)
+			s.AvgLatencyMS = (total / time.Duration(s.Requests)).Milliseconds()
+			s.MaxLatencyMS = maxLatency.Milliseconds()
+		}
+		p95 := p.percentile(now, 0.95)
+		s.P95LatencyMS = p95.Milliseconds()
+		if !p.lastSuccess.IsZero() {
+			t := p.lastSuccess.UTC()
+			s.LastSuccessAt = &t
+		}
+		if !p.lastError.IsZero() {
+			t := p.lastError.UTC()
+			s.LastErrorAt = &t
+			s.LastError = p.lastErrMsg
+		}
+		switch {
+		case s.Requests == 0:
+			s.Status = StatusIdle
+		case s.Requests >= minRequests && s.ErrorRate >= downErrorRate:
+			s.Status = StatusDown
+		case s.ErrorRate >= degradedErrorRate || p95 >= slowLatency:
+			s.Status = StatusDegraded
+		default:
+			s.Status = StatusOK
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// percentile returns the q-th latency percentile of the samples in the window.
+func (p *provider) percentile(now time.Time, q float64) time.Duration {
+	cutoff := now.Add(-Window)
+	var latencies []time.Duration
+	for _, s := range p.samples {
+		if s.at.After(cutoff) {
+			latencies = append(latencies, s.latency)
+		}
+	}
+	if len(latencies) == 0 {
+		return 0
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	idx := int(q*float64(len(latencies))+0.5) - 1
+	return latencies[max(0, min(idx, len(latencies)-1))]
+}
+
+// transport records the outcome of each request it sends.
+type transport struct {
+	monitor *Monitor
+	name    string
+	base    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	name := t.monitor.providerFor(req.URL.Hostname(), t.name)
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	latency := time.Since(start)
+
+	var errMsg *string
+	switch {
+	case err != nil:
+		msg := err.Error()
+		errMsg = &msg
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		msg := req.Method + " " + req.URL.Host + ": " + resp.Status
+		errMsg = &msg
+	}
+	t.monitor.Record(name, latency, errMsg)
+	providerRequests.Inc(name, outcome(resp, err))
+	providerDuration.Observe(latency.Seconds(), name)
+	return resp, err
+}
+
+// Outbound requests for /metrics, which unlike the statistics above count
+// from startup.
+var (
+	providerRequests = metrics.NewCounter("bookshelf_provider_requests_total",
+		"Outbound requests to metadata providers and other integrations, by outcome.", "provider", "outcome")
+	providerDuration = metrics.NewHistogram("bookshelf_provider_request_duration_seconds",
+		"Duration of outbound requests to metadata providers and other integrations.", metrics.DefBuckets, "provider")
+)
+
+// outcome classifies a request's response for providerRequests.
+func outcome(resp *http.Response, err error) string {
+	switch {
+	case err != nil:
+		return "transport_error"
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return "rate_limited"
+	case resp.StatusCode >= 500:
+		return "server_error"
+	case resp.StatusCode >= 400:
+		return "client_error"
+	}
+	return "success"
+}
@@ -0,0 +1,103 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMonitorStatus(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	m := NewMonitor()
+	m.now = func() time.Time { return now }
+	fail := "boom"
+
+	for i := 0; i < 9; i++ {
+		m.Record("steady", 100*time.Millisecond, nil)
+	}
+	m.Record("steady", 300*time.Millisecond, nil)
+	m.Record("flaky", time.Millisecond, nil)
+	m.Record("flaky", time.Millisecond, &fail)
+	for i := 0; i < 3; i++ {
+		m.Record("failing", time.Millisecond, &fail)
+	}
+	m.Record("slow", 6*time.Second, nil)
+	m.Record("once", time.Millisecond, &fail) // One failure is too few to call it down
+
+	want := map[string]Status{"failing": StatusDown, "flaky": StatusDegraded, "once": StatusDegraded, "slow": StatusDegraded, "steady": StatusOK}
+	stats := m.Stats()
+	if len(stats) != len(want) {
+		t.Fatalf("Expected %d providers, got %+v", len(want), stats)
+	}
+	for i, s := range stats {
+		if i > 0 && stats[i-1].Name >= s.Name {
+			t.Errorf("Providers not sorted by name: %q before %q", stats[i-1].Name, s.Name)
+		}
+		if s.Status != want[s.Name] {
+			t.Errorf("%s: got status %s, want %s", s.Name, s.Status, want[s.Name])
+		}
+	}
+	steady := stats[len(stats)-1]
+	if steady.Requests != 10 || steady.Errors != 0 || steady.AvgLatencyMS != 120 || steady.P95LatencyMS != 300 || steady.MaxLatencyMS != 300 {
+		t.Errorf("Unexpected steady stats %+v", steady)
+	}
+	if steady.LastSuccessAt == nil || !steady.LastSuccessAt.Equal(now) || steady.LastErrorAt != nil {
+		t.Errorf("Unexpected steady timestamps %+v", steady)
+	}
+	if flaky := stats[1]; flaky.ErrorRate != 0.5 || flaky.LastError != fail {
+		t.Errorf("Unexpected flaky stats %+v", flaky)
+	}
+
+	// Once the window has passed, providers are still listed but idle
+	now = now.Add(Window + time.Minute)
+	for _, s := range m.Stats() {
+		if s.Status != StatusIdle || s.Requests != 0 || s.P95LatencyMS != 0 {
+			t.Errorf("%s: expected idle after the window, got %+v", s.Name, s)
+		}
+		if s.LastSuccessAt == nil && s.LastErrorAt == nil {
+			t.Errorf("%s: lost its last request time", s.Name)
+		}
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/busy" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound) // Client errors are the caller's fault, not the provider's
+	}))
+	defer server.Close()
+
+	m := NewMonitor()
+	client := m.Instrument(&http.Client{}, "feeds")
+	for _, path := range []string{"/missing", "/busy"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+	if _, err := client.Get("http://127.0.0.1:1/unreachable"); err == nil {
+		t.Fatal("Expected an error from an unreachable host")
+	}
+
+	stats := m.Stats()
+	if len(stats) != 1 || stats[0].Name != "feeds" || stats[0].Requests != 3 || stats[0].Errors != 2 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+	for _, outcome := range []string{"client_error", "rate_limited", "transport_error"} {
+		if got := providerRequests.Value("feeds", outcome); got != 1 {
+			t.Errorf("Expected one %s request counted, got %v", outcome, got)
+		}
+	}
+
+	m.Hosts = map[string]string{"example.org": "example"}
+	for host, want := range map[string]string{"example.org": "example", "covers.EXAMPLE.org": "example", "notexample.org": "feeds"} {
+		if got := m.providerFor(host, "feeds"); got != want {
+			t.Errorf("providerFor(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+// Package htmltext turns the HTML that metadata providers put in book
+// descriptions into clean Markdown-flavored text: tags are dropped, the ones
+// that carry meaning (paragraphs, line breaks, lists, emphasis and links)
+// become their Markdown equivalents, and entities are decoded.
+package htmltext
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// markup matches the start of an HTML tag or comment. Text without any is
+// treated as plain text, keeping its line breaks.
+var markup = regexp.MustCompile(`(?i)<(/?[a-z][a-z0-9]*[\s/>]|!--)`)
+
+// hrefAttr extracts a link target from a tag's attributes.
+var hrefAttr = regexp.MustCompile(`(?i)\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+// blankLines matches runs of blank lines.
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// skipped are elements whose content is never text.
+var skipped = map[string]bool{"script": true, "style": true, "head": true, "template": true, "noscript": true}
+
+// blocks are elements that start a new paragraph.
+var blocks = map[string]bool{
+	"p": true, "div": true, "blockquote": true, "section": true, "article": true, "header": true, "footer": true,
+	"table": true, "tr": true, "dl": true, "dd": true, "dt": true, "pre": true, "figure": true,
+}
+
+// emphasis maps inline elements to their Markdown markers.
+var emphasis = map[string]string{"b": "**", "strong": "**", "i": "_", "em": "_", "cite": "_"}
+
+// Clean converts an HTML description to Markdown text. It is safe to apply to
+// text that is already clean: plain text only has its entities decoded and
+// its whitespace tidied.
+func Clean(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	if !markup.MatchString(s) {
+		lines := strings.Split(strings.ReplaceAll(html.UnescapeString(s), "\u00a0", " "), "\n")
+		for i, line := range lines {
+			indent := line[:len(line)-len(strings.TrimLeft(line, " "))] // Nested list items
+			lines[i] = indent + strings.Join(strings.Fields(line), " ")
+		}
+		return normalize(strings.Join(lines, "\n"))
+	}
+
+	w := &writer{}
+	for s != "" {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			w.text(s)
+			break
+		}
+		w.text(s[:i])
+		s = s[i:]
+		if strings.HasPrefix(s, "<!--") {
+			end := strings.Index(s, "-->")
+			if end < 0 {
+				break
+			}
+			s = s[end+3:]
+			continue
+		}
+		loc := markup.FindStringIndex(s)
+		if loc == nil || loc[0] != 0 {
+			w.text("<")
+			s = s[1:]
+			continue
+		}
+		end := tagEnd(s)
+		if end < 0 {
+			break // A tag cut off at the end of the text
+		}
+		name, closing, attrs := parseTag(s[1:end])
+		s = s[end+1:]
+		if skipped[name] && !closing {
+			if idx := strings.Index(strings.ToLower(s), "</"+name); idx >= 0 {
+				s = s[idx:]
+			} else {
+				s = ""
+			}
+			continue
+		}
+		w.tag(name, closing, attrs)
+	}
+	return normalize(w.String())
+}
+
+// tagEnd returns the index of the '>' that ends the tag at the start of s,
+// skipping quoted attribute values, or -1.
+func tagEnd(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i
+		}
+	}
+	return -1
+}
+
+// parseTag splits the inside of a tag into its lowercased name, whether it
+// closes an element, and the rest.
+func parseTag(tag string) (name string, closing bool, attrs string) {
+	if strings.HasPrefix(tag, "/") {
+		closing, tag = true, tag[1:]
+	}
+	tag = strings.TrimSuffix(tag, "/")
+	name, attrs, _ = strings.Cut(tag, " ")
+	if i := strings.IndexAny(name, "\t\n/"); i >= 0 {
+		name, attrs = name[:i], name[i:]+" "+attrs
+	}
+	return strings.ToLower(name), closing, attrs
+}
+
+// normalize trims trailing whitespace from lines and collapses blank lines.
+func normalize(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	s = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(s)
+}
+
+// list is an open ul or ol element.
+type list struct {
+	ordered bool
+	n       int
+}
+
+// writer accumulates Markdown output.
+type writer struct {
+	buf     []byte
+	pending string   // Opening markers waiting for the text they apply to
+	lists   []list   // Open lists, innermost last
+	links   []string // Targets of open links; "" for links that are dropped
+}
+
+func (w *writer) String() string { return string(w.buf) }
+
+// atLineStart reports whether the output is empty or ends a line.
+func (w *writer) atLineStart() bool {
+	return len(w.buf) == 0 || w.buf[len(w.buf)-1] == '\n'
+}
+
+// trimSpace drops trailing spaces, reporting whether there were any.
+func (w *writer) trimSpace() bool {
+	n := len(w.buf)
+	for n > 0 && w.buf[n-1] == ' ' {
+		n--
+	}
+	trimmed := n < len(w.buf)
+	w.buf = w.buf[:n]
+	return trimmed
+}
+
+// text writes character data, collapsing whitespace the way a browser does.
+func (w *writer) text(s string) {
+	if s == "" {
+		return
+	}
+	s = strings.ReplaceAll(html.UnescapeString(s), "\u00a0", " ")
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		if !w.atLineStart() && w.buf[len(w.buf)-1] != ' ' {
+			w.buf = append(w.buf, ' ')
+		}
+		return
+	}
+	if isSpace(s[0]) && !w.atLineStart() && w.buf[len(w.buf)-1] != ' ' {
+		w.buf = append(w.buf, ' ')
+	}
+	w.buf = append(w.buf, w.pending...)
+	w.pending = ""
+	w.buf = append(w.buf, strings.Join(words, " ")...)
+	if isSpace(s[len(s)-1]) {
+		w.buf = append(w.buf, ' ')
+	}
+}
+
+// breakLines ends the current line and adds blank lines until n newlines end the output.
+func (w *writer) breakLines(n int) {
+	w.trimSpace()
+	if len(w.buf) == 0 {
+		return
+	}
+	have := 0
+	for have < len(w.buf) && w.buf[len(w.buf)-1-have] == '\n' {
+		have++
+	}
+	for ; have < n; have++ {
+		w.buf = append(w.buf, '\n')
+	}
+}
+
+// open writes an opening marker once text follows it.
+func (w *writer) open(marker string) {
+	w.pending += marker
+}
+
+// close writes a closing marker, or drops the opening one if nothing was written in between.
+func (w *writer) close(opening, closing string) {
+	if strings.HasSuffix(w.pending, opening) {
+		w.pending = strings.TrimSuffix(w.pending, opening)
+		return
+	}
+	spaced := w.trimSpace()
+	w.buf = append(w.buf, closing...)
+	if spaced {
+		w.buf = append(w.buf, ' ')
+	}
+}
+
+func (w *writer) tag(name string, closing bool, attrs string) {
+	switch {
+	case name == "br":
+		w.trimSpace()
+		w.buf = append(w.buf, '\n')
+	case name == "hr":
+		w.breakLines(2)
+		w.buf = append(w.buf, "---"...)
+		w.breakLines(2)
+	case blocks[name]:
+		if closing {
+			w.pending = "" // Markers opened in an empty block have nothing to apply to
+		}
+		w.breakLines(2)
+	case len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6':
+		if closing {
+			w.pending = ""
+		}
+		w.breakLines(2)
+		if !closing {
+			w.open(strings.Repeat("#", int(name[1]-'0')) + " ")
+		}
+	case name == "ul" || name == "ol":
+		if closing {
+			if len(w.lists) > 0 {
+				w.lists = w.lists[:len(w.lists)-1]
+			}
+		} else {
+			w.lists = append(w.lists, list{ordered: name == "ol"})
+		}
+		if len(w.lists) == 0 {
+			w.breakLines(2)
+		} else {
+			w.breakLines(1)
+		}
+	case name == "li":
+		w.breakLines(1)
+		if closing {
+			w.pending = ""
+			return
+		}
+		depth := max(len(w.lists), 1)
+		marker := "- "
+		if depth <= len(w.lists) && w.lists[depth-1].ordered {
+			w.lists[depth-1].n++
+			marker = strconv.Itoa(w.lists[depth-1].n) + ". "
+		}
+		w.open(strings.Repeat("  ", depth-1) + marker)
+	case emphasis[name] != "":
+		if closing {
+			w.close(emphasis[name], emphasis[name])
+		} else {
+			w.open(emphasis[name])
+		}
+	case name == "a":
+		if closing {
+			if n := len(w.links); n > 0 {
+				href := w.links[n-1]
+				w.links = w.links[:n-1]
+				if href != "" {
+					w.close("[", "]("+href+")")
+				}
+			}
+			return
+		}
+		href := ""
+		if m := hrefAttr.FindStringSubmatch(attrs); m != nil {
+			href = strings.TrimSpace(html.UnescapeString(m[1] + m[2] + m[3]))
+			lower := strings.ToLower(href)
+			if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+				href = "" // Relative and script links mean nothing outside the provider's site
+			}
+		}
+		w.links = append(w.links, href)
+		if href != "" {
+			w.open("[")
+		}
+	}
+}
+
+// isSpace reports whether c is HTML whitespace.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,34 @@
+package htmltext
+
+import "testing"
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain text keeps paragraphs", "First line.\r\n\r\n\r\nSecond   line &amp; more.  ", "First line.\n\nSecond line & more."},
+		{"not a tag", "a < b and 3<4", "a < b and 3<4"},
+		{"paragraphs", "<p>One\n  two</p><p>Three</p>", "One two\n\nThree"},
+		{"line breaks", "Line one<br>Line two<BR/>Line three", "Line one\nLine two\nLine three"},
+		{"entities", "<p>Caf&eacute; &#8212; &quot;quoted&quot;&nbsp;text</p>", "Café — \"quoted\" text"},
+		{"emphasis", "A <b>bold</b> and <i> italic </i> word", "A **bold** and _italic_ word"},
+		{"empty emphasis", "<p><strong></strong>Text</p>", "Text"},
+		{"links", `See <a href="https://example.com/a?b=1&amp;c=2">the site</a> or <a href="/relative">here</a>.`, "See [the site](https://example.com/a?b=1&c=2) or here."},
+		{"unsafe link", `<a href="javascript:alert(1)">click</a>`, "click"},
+		{"lists", "<ul><li>One</li><li>Two<ol><li>A</li><li>B</li></ol></li></ul><p>After</p>", "- One\n- Two\n  1. A\n  2. B\n\nAfter"},
+		{"headings", "<h2>Praise</h2><p>Great.</p>", "## Praise\n\nGreat."},
+		{"skipped content", "<style>p{color:red}</style><script>alert('<b>')</script>Text<!-- note -->", "Text"},
+		{"quoted attributes", `<span title="a > b">Text</span>`, "Text"},
+		{"unknown tags", "<div><span class=\"x\">Hello</span> <font>world</font></div>", "Hello world"},
+		{"truncated tag", "Text <a href=", "Text"},
+		{"rule", "Above<hr>Below", "Above\n\n---\n\nBelow"},
+	}
+	for _, tt := range tests {
+		if got := Clean(tt.in); got != tt.want {
+			t.Errorf("%s: Clean(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+		if got := Clean(tt.want); got != tt.want {
+			t.Errorf("%s: cleaning the output again changed it to %q", tt.name, got)
+		}
+	}
+}
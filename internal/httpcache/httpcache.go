@@ -0,0 +1,186 @@
+// Package httpcache keeps the JSON answers of an upstream API, such as Open
+// Library searches and edition lookups, for a while, so repeated requests are
+// served without contacting the upstream. Identical requests made while one
+// is in flight wait for it and share its answer instead of being sent again.
+// Entries live in memory and, optionally, in a directory so they survive a
+// restart.
+package httpcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultMaxEntries is how many responses a cache keeps in memory unless
+// MaxEntries says otherwise.
+const DefaultMaxEntries = 1000
+
+// maxEntrySize caps the body of a response that is cached.
+const maxEntrySize = 1 << 20
+
+// entry is a cached response.
+type entry struct {
+	Key      string      `json:"key"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	StoredAt time.Time   `json:"stored_at"`
+
+	element *list.Element // Position in the cache's recency list
+}
+
+// Cache holds responses for TTL. The zero value is not usable; create one
+// with New.
+type Cache struct {
+	TTL        time.Duration
+	MaxEntries int    // Responses kept in memory; the least recently used go first
+	Dir        string // Directory the responses are also written to; "" keeps them in memory only
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	recency *list.List         // Keys, most recently used first
+	flights map[string]*flight // Requests on their way upstream, by key
+	now     func() time.Time
+}
+
+// New creates a cache keeping responses for ttl, in memory and, when dir is
+// not empty, in dir, which is created if needed.
+func New(ttl time.Duration, dir string) (*Cache, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create cache directory: %w", err)
+		}
+	}
+	return &Cache{
+		TTL:        ttl,
+		MaxEntries: DefaultMaxEntries,
+		Dir:        dir,
+		entries:    make(map[string]*entry),
+		recency:    list.New(),
+		flights:    make(map[string]*flight),
+		now:        time.Now,
+	}, nil
+}
+
+// get returns the fresh response stored under key, looking on disk when it
+// is not in memory.
+func (c *Cache) get(key string) (*entry, bool) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok && c.fresh(e) {
+		c.recency.MoveToFront(e.element)
+		c.mu.Unlock()
+		return e, true
+	}
+	if ok {
+		c.remove(e)
+	}
+	c.mu.Unlock()
+
+	if c.Dir == "" {
+		return nil, false
+	}
+	e, err := c.readFile(key)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("Failed to read cached response", "key", key, "error", err)
+		}
+		return nil, false
+	}
+	if !c.fresh(e) {
+		os.Remove(c.path(key))
+		return nil, false
+	}
+	c.mu.Lock()
+	c.store(e)
+	c.mu.Unlock()
+	return e, true
+}
+
+// put stores a response under its key.
+func (c *Cache) put(e *entry) {
+	c.mu.Lock()
+	if old, ok := c.entries[e.Key]; ok {
+		c.remove(old)
+	}
+	c.store(e)
+	c.mu.Unlock()
+
+	if c.Dir != "" {
+		if err := c.writeFile(e); err != nil {
+			slog.Warn("Failed to write cached response", "key", e.Key, "error", err)
+		}
+	}
+}
+
+func (c *Cache) fresh(e *entry) bool {
+	return c.now().Sub(e.StoredAt) < c.TTL
+}
+
+// store adds e to memory, evicting the least recently used entries over the
+// limit. c.mu must be held.
+func (c *Cache) store(e *entry) {
+	e.element = c.recency.PushFront(e.Key)
+	c.entries[e.Key] = e
+	for c.MaxEntries > 0 && len(c.entries) > c.MaxEntries {
+		c.remove(c.entries[c.recency.Back().Value.(string)])
+	}
+}
+
+// remove drops e from memory. c.mu must be held.
+func (c *Cache) remove(e *entry) {
+	c.recency.Remove(e.element)
+	delete(c.entries, e.Key)
+}
+
+// path is the file the response stored under key is kept in.
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *Cache) readFile(key string) (*entry, error) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, err
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	if e.Key != key {
+		return nil, os.ErrNotExist // A hash collision; treat as a miss
+	}
+	return &e, nil
+}
+
+// writeFile writes e through a temporary file, so a reader never sees half
+// an entry.
+func (c *Cache) writeFile(e *entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.Dir, "entry-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(e.Key))
+}
@@ -0,0 +1,137 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newUpstream returns a server answering every request with JSON, after
+// release is closed when it is not nil, and counting the requests.
+func newUpstream(t *testing.T, release chan struct{}) (*httptest.Server, *atomic.Int32) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if release != nil {
+			<-release
+		}
+		if r.URL.Path == "/cover.jpg" {
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write([]byte("jpeg"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"q":"` + r.URL.Query().Get("q") + `"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func get(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestTransportCachesJSON(t *testing.T) {
+	server, hits := newUpstream(t, nil)
+	cache, _ := New(time.Hour, "")
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	client := Wrap(&http.Client{}, cache, "127.0.0.1")
+
+	for i := 0; i < 3; i++ {
+		if body := get(t, client, server.URL+"/search.json?q=dune"); body != `{"q":"dune"}` {
+			t.Fatalf("Unexpected body %s", body)
+		}
+	}
+	get(t, client, server.URL+"/search.json?q=emma")
+	if n := hits.Load(); n != 2 {
+		t.Errorf("Expected one upstream request per query, got %d", n)
+	}
+
+	get(t, client, server.URL+"/cover.jpg")
+	get(t, client, server.URL+"/cover.jpg")
+	if n := hits.Load(); n != 4 {
+		t.Errorf("Expected images not to be cached, got %d upstream requests", n)
+	}
+
+	now = now.Add(time.Hour)
+	get(t, client, server.URL+"/search.json?q=dune")
+	if n := hits.Load(); n != 5 {
+		t.Errorf("Expected an expired response to be fetched again, got %d upstream requests", n)
+	}
+
+	uncached := Wrap(&http.Client{}, cache, "openlibrary.org")
+	get(t, uncached, server.URL+"/search.json?q=emma")
+	if n := hits.Load(); n != 6 {
+		t.Errorf("Expected other hosts to pass through, got %d upstream requests", n)
+	}
+}
+
+func TestTransportCoalescesRequests(t *testing.T) {
+	release := make(chan struct{})
+	server, hits := newUpstream(t, release)
+	cache, _ := New(time.Hour, "")
+	client := Wrap(&http.Client{}, cache, "127.0.0.1")
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 5)
+	for i := range bodies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bodies[i] = get(t, client, server.URL+"/search.json?q=dune")
+		}()
+	}
+	// Let the requests pile up behind the first before it is answered
+	for deadline := time.Now().Add(time.Second); hits.Load() == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := hits.Load(); n != 1 {
+		t.Errorf("Expected concurrent identical requests to be sent once, got %d", n)
+	}
+	for i, body := range bodies {
+		if body != `{"q":"dune"}` {
+			t.Errorf("Request %d got %q", i, body)
+		}
+	}
+}
+
+func TestCacheOnDisk(t *testing.T) {
+	server, hits := newUpstream(t, nil)
+	dir := t.TempDir()
+	cache, err := New(time.Hour, dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	get(t, Wrap(&http.Client{}, cache, "127.0.0.1"), server.URL+"/search.json?q=dune")
+
+	// A new cache over the same directory, as after a restart
+	restarted, _ := New(time.Hour, dir)
+	if body := get(t, Wrap(&http.Client{}, restarted, "127.0.0.1"), server.URL+"/search.json?q=dune"); body != `{"q":"dune"}` {
+		t.Errorf("Unexpected body %s", body)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("Expected the response to be read from disk, got %d upstream requests", n)
+	}
+
+	restarted.MaxEntries = 1
+	get(t, Wrap(&http.Client{}, restarted, "127.0.0.1"), server.URL+"/search.json?q=emma")
+	if len(restarted.entries) != 1 {
+		t.Errorf("Expected the least recently used entry to be evicted from memory, got %d entries", len(restarted.entries))
+	}
+}
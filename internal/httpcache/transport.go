@@ -0,0 +1,151 @@
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Transport serves GET requests to the cached hosts from the cache, and sends
+// identical requests made at the same time, by any client sharing the cache,
+// upstream only once. Successful JSON responses are cached; anything else,
+// such as cover images or errors, is passed on but not kept. Requests to
+// other hosts, and requests other than GET, pass straight through.
+type Transport struct {
+	Cache *Cache
+	Hosts []string          // Hosts to cache, matched with their subdomains
+	Base  http.RoundTripper // nil uses http.DefaultTransport
+}
+
+// flight is a request on its way upstream, which identical requests wait for.
+type flight struct {
+	done chan struct{}
+	resp *entry // Set when the response was read in full
+	err  error
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" || !t.caches(req.URL.Hostname()) {
+		return base.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	for {
+		if e, ok := t.Cache.get(key); ok {
+			slog.Debug("Serving cached response", "url", key)
+			return e.response(req), nil
+		}
+
+		c := t.Cache
+		c.mu.Lock()
+		if f, ok := c.flights[key]; ok {
+			c.mu.Unlock()
+			select {
+			case <-f.done:
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+			// A request given up by its own caller says nothing about this one
+			if errors.Is(f.err, context.Canceled) || errors.Is(f.err, context.DeadlineExceeded) {
+				continue
+			}
+			if f.err != nil {
+				return nil, f.err
+			}
+			return f.resp.response(req), nil
+		}
+		f := &flight{done: make(chan struct{})}
+		c.flights[key] = f
+		c.mu.Unlock()
+
+		resp, err := t.fetch(base, req, key, f)
+		c.mu.Lock()
+		delete(c.flights, key)
+		c.mu.Unlock()
+		close(f.done)
+		return resp, err
+	}
+}
+
+// fetch sends req upstream, reading the response into f so requests waiting
+// on it get a copy, and caches it if it may be.
+func (t *Transport) fetch(base http.RoundTripper, req *http.Request, key string, f *flight) (*http.Response, error) {
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		f.err = err
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		f.err = err
+		return nil, err
+	}
+	f.resp = &entry{Key: key, Status: resp.StatusCode, Header: resp.Header.Clone(), Body: body, StoredAt: t.Cache.now()}
+	f.resp.Header.Del("Set-Cookie")
+	if cacheable(resp, body) {
+		t.Cache.put(f.resp)
+	}
+	return f.resp.response(req), nil
+}
+
+// cacheable reports whether a response is a successful JSON answer small
+// enough to keep.
+func cacheable(resp *http.Response, body []byte) bool {
+	if resp.StatusCode != http.StatusOK || len(body) > maxEntrySize {
+		return false
+	}
+	if strings.Contains(resp.Header.Get("Cache-Control"), "no-store") {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// response builds a fresh response to req from e, so every caller can read
+// the body.
+func (e *entry) response(req *http.Request) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Content-Length", strconv.Itoa(len(e.Body)))
+	return &http.Response{
+		Status:        strconv.Itoa(e.Status) + " " + http.StatusText(e.Status),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+func (t *Transport) caches(host string) bool {
+	host = strings.ToLower(host)
+	for _, h := range t.Hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// Wrap makes client answer GET requests to hosts from cache where it can. It
+// returns client for convenience. Wrap a client after limiting and
+// instrumenting it, so cached answers neither wait for the limiter nor count
+// as upstream requests.
+func Wrap(client *http.Client, cache *Cache, hosts ...string) *http.Client {
+	client.Transport = &Transport{Cache: cache, Hosts: hosts, Base: client.Transport}
+	return client
+}
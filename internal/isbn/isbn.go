@@ -0,0 +1,102 @@
+// Package isbn validates International Standard Book Numbers and converts
+// between their 10- and 13-digit forms. ISBN-10s are the ISBN-13s of the 978
+// prefix without it, under a check digit of their own.
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalid is returned for a code that isn't a valid ISBN: the wrong
+// length, characters other than digits (and a final X for ISBN-10), or a
+// wrong check digit.
+var ErrInvalid = errors.New("invalid ISBN")
+
+// Normalize returns s without hyphens and spaces, with an ISBN-10's check
+// digit X in upper case, after verifying its check digit.
+func Normalize(s string) (string, error) {
+	code := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(s)))
+	switch len(code) {
+	case 10:
+		if !digits(code[:9]) || !(digits(code[9:]) || code[9] == 'X') || checkDigit10(code[:9]) != code[9] {
+			return "", ErrInvalid
+		}
+	case 13:
+		if !digits(code) || checkDigit13(code[:12]) != code[12] {
+			return "", ErrInvalid
+		}
+	default:
+		return "", ErrInvalid
+	}
+	return code, nil
+}
+
+// Valid reports whether s is a valid ISBN-10 or ISBN-13.
+func Valid(s string) bool {
+	_, err := Normalize(s)
+	return err == nil
+}
+
+// To13 returns the ISBN-13 of a valid ISBN, which is s itself, normalized,
+// when it is one already.
+func To13(s string) (string, error) {
+	code, err := Normalize(s)
+	if err != nil || len(code) == 13 {
+		return code, err
+	}
+	prefix := "978" + code[:9]
+	return prefix + string(checkDigit13(prefix)), nil
+}
+
+// To10 returns the ISBN-10 of a valid ISBN. Only ISBN-13s with the 978
+// prefix have one; others return ErrInvalid.
+func To10(s string) (string, error) {
+	code, err := Normalize(s)
+	if err != nil || len(code) == 10 {
+		return code, err
+	}
+	if !strings.HasPrefix(code, "978") {
+		return "", ErrInvalid
+	}
+	return code[3:12] + string(checkDigit10(code[3:12])), nil
+}
+
+func digits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDigit10 returns the check digit of the first nine digits of an
+// ISBN-10: the weighted sum 10, 9, ..., 2 plus it is divisible by 11, with
+// X standing for 10.
+func checkDigit10(first9 string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(first9[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+// checkDigit13 returns the check digit of the first twelve digits of an
+// ISBN-13: the sum with weights alternating 1 and 3 plus it is divisible by
+// 10.
+func checkDigit13(first12 string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(first12[i]-'0') * weight
+	}
+	return byte('0' + (10-sum%10)%10)
+}
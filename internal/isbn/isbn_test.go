@@ -0,0 +1,58 @@
+package isbn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for input, want := range map[string]string{
+		"978-0-14-143951-8": "9780141439518",
+		" 0 14 143951 3 ":   "0141439513",
+		"080442957x":        "080442957X",
+		"9780306406157":     "9780306406157",
+		"978-0-14-143951-9": "", // Wrong check digit
+		"0141439514":        "",
+		"014143951":         "", // Too short
+		"97801414395180":    "",
+		"X141439513":        "", // X only as an ISBN-10 check digit
+		"978014143951X":     "",
+		"abcdefghij":        "",
+		"":                  "",
+	} {
+		got, err := Normalize(input)
+		if want == "" {
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("Normalize(%q) = %q, %v; want ErrInvalid", input, got, err)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	for isbn10, isbn13 := range map[string]string{
+		"0141439513": "9780141439518",
+		"080442957X": "9780804429573",
+		"0306406152": "9780306406157",
+	} {
+		if got, err := To13(isbn10); err != nil || got != isbn13 {
+			t.Errorf("To13(%q) = %q, %v; want %q", isbn10, got, err, isbn13)
+		}
+		if got, err := To10(isbn13); err != nil || got != isbn10 {
+			t.Errorf("To10(%q) = %q, %v; want %q", isbn13, got, err, isbn10)
+		}
+		if got, _ := To13(isbn13); got != isbn13 {
+			t.Errorf("To13(%q) = %q; want it unchanged", isbn13, got)
+		}
+	}
+	if _, err := To10("9791032305690"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected a 979 ISBN to have no ISBN-10, got %v", err)
+	}
+	if _, err := To13("0141439514"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected an invalid ISBN to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,363 @@
+// Package logging is the server's slog handler, whose level and format can
+// be changed while it runs, such as from the admin API. Levels can also be
+// set by subsystem, the package a record is logged from, so the chatty SQL
+// lines of the db package can be quietened without losing the rest, or one
+// integration looked at closely without turning on debug logging everywhere.
+//
+// Below the debug level, values marked with Redact are left out, and SQL
+// lines are sampled: busy queries log a few lines a second rather than one
+// for each time they run. At the debug level, whether set for
+// everything or for a subsystem, records are logged in full.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Format is how records are written: as text or as JSON objects, one to a
+// line.
+type Format string
+
+const (
+	Text Format = "text"
+	JSON Format = "json"
+)
+
+// Settings are what a Controller can change.
+type Settings struct {
+	Level  slog.Level `json:"level"`
+	Format Format     `json:"format"`
+	// Subsystems are levels overriding Level for the records of some
+	// packages, by package name, such as "db" or "webhook".
+	Subsystems map[string]slog.Level `json:"subsystems"`
+	// Sample is how many SQL lines below the warning level each call site
+	// logs a second, the rest being counted and left out; 0 logs them all.
+	Sample int `json:"sample"`
+}
+
+// Validate returns an error unless the settings can be used.
+func (s Settings) Validate() error {
+	if s.Format != Text && s.Format != JSON {
+		return fmt.Errorf("log format must be either 'json' or 'text', got '%s'", s.Format)
+	}
+	if s.Sample < 0 {
+		return fmt.Errorf("log sample must not be negative, got %d", s.Sample)
+	}
+	for name := range s.Subsystems {
+		if name == "" || strings.ContainsAny(name, "./= ") {
+			return fmt.Errorf("invalid subsystem '%s': expected a package name, such as 'db'", name)
+		}
+	}
+	return nil
+}
+
+// ParseSubsystems parses comma-separated subsystem levels, such as
+// "db=warn,webhook=debug".
+func ParseSubsystems(list string) (map[string]slog.Level, error) {
+	levels := map[string]slog.Level{}
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected 'subsystem=level', got '%s'", item)
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+			return nil, fmt.Errorf("invalid level of subsystem '%s': %v", name, err)
+		}
+		levels[strings.TrimSpace(name)] = level
+	}
+	return levels, nil
+}
+
+// FormatSubsystems formats subsystem levels as ParseSubsystems parses them.
+func FormatSubsystems(levels map[string]slog.Level) string {
+	var items []string
+	for name, level := range levels {
+		items = append(items, name+"="+strings.ToLower(level.String()))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+// Redact returns an attribute whose value is left out of records below the
+// debug level, for what the log shouldn't keep, such as the comments people
+// write about their books. Pointers are logged as what they point to.
+func Redact(key string, value any) slog.Attr {
+	return slog.Any(key, redacted{value})
+}
+
+// redacted is a value of Redact. Logged by other handlers, it's left out.
+type redacted struct {
+	value any
+}
+
+func (v redacted) LogValue() slog.Value {
+	if v.reveal().Any() == nil {
+		return slog.AnyValue(nil) // There's nothing to hide
+	}
+	return slog.StringValue("[redacted]")
+}
+
+// reveal returns the value itself.
+func (v redacted) reveal() slog.Value {
+	value := reflect.ValueOf(v.value)
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return slog.AnyValue(nil)
+		}
+		return slog.AnyValue(value.Elem().Interface())
+	}
+	return slog.AnyValue(v.value)
+}
+
+// sampledPrefix starts the messages of the records that are sampled: the SQL
+// lines the db package logs for every query.
+const sampledPrefix = "SQL: "
+
+// site is what a sampler knows of the records logged from one call site.
+type site struct {
+	second  time.Time // Of the latest record
+	logged  int       // Records logged in that second
+	dropped int       // Records left out since the last one logged
+}
+
+// sampler samples records by the call site they're logged from.
+type sampler struct {
+	mu    sync.Mutex
+	sites map[uintptr]*site
+}
+
+// take reports whether a record of the call site pc at t is logged, being one
+// of the first perSecond that second, and if so how many were left out since
+// the last one that was.
+func (s *sampler) take(pc uintptr, t time.Time, perSecond int) (dropped int, ok bool) {
+	second := t.Truncate(time.Second)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sites == nil {
+		s.sites = map[uintptr]*site{}
+	}
+	at := s.sites[pc]
+	if at == nil {
+		at = &site{}
+		s.sites[pc] = at
+	}
+	if !at.second.Equal(second) {
+		at.second, at.logged = second, 0
+	}
+	if at.logged >= perSecond {
+		at.dropped++
+		return 0, false
+	}
+	at.logged++
+	dropped, at.dropped = at.dropped, 0
+	return dropped, true
+}
+
+// Controller holds the settings of the handlers created from it. It's safe
+// for concurrent use.
+type Controller struct {
+	mu       sync.RWMutex
+	settings Settings
+	min      slog.Level // Lowest of the levels, below which nothing is logged
+	sampler  sampler
+}
+
+// NewController returns a controller logging text at the info level, with
+// every record sampled in.
+func NewController() *Controller {
+	return &Controller{settings: Settings{Level: slog.LevelInfo, Format: Text}, min: slog.LevelInfo}
+}
+
+// Settings returns the settings in effect.
+func (c *Controller) Settings() Settings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s := c.settings
+	s.Subsystems = make(map[string]slog.Level, len(c.settings.Subsystems))
+	for name, level := range c.settings.Subsystems {
+		s.Subsystems[name] = level
+	}
+	return s
+}
+
+// Set replaces the settings, or returns an error having changed nothing.
+func (c *Controller) Set(s Settings) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	subsystems := make(map[string]slog.Level, len(s.Subsystems))
+	min := s.Level
+	for name, level := range s.Subsystems {
+		subsystems[name] = level
+		if level < min {
+			min = level
+		}
+	}
+	s.Subsystems = subsystems
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.settings, c.min = s, min
+	return nil
+}
+
+// level returns the level of the records logged from the function at pc.
+func (c *Controller) level(pc uintptr) slog.Level {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.settings.Subsystems) > 0 {
+		if level, ok := c.settings.Subsystems[subsystem(pc)]; ok {
+			return level
+		}
+	}
+	return c.settings.Level
+}
+
+// subsystems caches the package names of the functions records are logged
+// from, by program counter.
+var subsystems sync.Map
+
+// subsystem returns the name of the package of the function at pc, such as
+// "db" for github.com/ericdahl/bookshelf/internal/db, or "" when unknown.
+func subsystem(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+	if name, ok := subsystems.Load(pc); ok {
+		return name.(string)
+	}
+	frames := runtime.CallersFrames([]uintptr{pc})
+	frame, _ := frames.Next()
+	name := frame.Function[strings.LastIndex(frame.Function, "/")+1:]
+	name, _, _ = strings.Cut(name, ".")
+	subsystems.Store(pc, name)
+	return name
+}
+
+// handler writes records with the text or JSON handler, as its Controller
+// says. Both are kept with the same attributes and groups, so a logger made
+// With some attributes keeps them across a change of format.
+type handler struct {
+	c    *Controller
+	text slog.Handler
+	json slog.Handler
+}
+
+// NewHandler returns a handler writing to w as c says.
+func NewHandler(w io.Writer, c *Controller) slog.Handler {
+	// The handlers themselves let everything through; handler filters
+	opts := &slog.HandlerOptions{Level: slog.Level(-1 << 10)}
+	return &handler{c: c, text: slog.NewTextHandler(w, opts), json: slog.NewJSONHandler(w, opts)}
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	h.c.mu.RLock()
+	defer h.c.mu.RUnlock()
+	return level >= h.c.min
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	level := h.c.level(r.PC)
+	if r.Level < level {
+		return nil
+	}
+	h.c.mu.RLock()
+	format, sample := h.c.settings.Format, h.c.settings.Sample
+	h.c.mu.RUnlock()
+
+	if level <= slog.LevelDebug {
+		r = reveal(r)
+	} else if sample > 0 && r.Level < slog.LevelWarn && strings.HasPrefix(r.Message, sampledPrefix) {
+		t := r.Time
+		if t.IsZero() {
+			t = time.Now()
+		}
+		dropped, ok := h.c.sampler.take(r.PC, t, sample)
+		if !ok {
+			return nil
+		}
+		if dropped > 0 {
+			// The line summarizes those left out since the last one
+			r = r.Clone()
+			r.AddAttrs(slog.Int("sampled_out", dropped))
+		}
+	}
+	if format == JSON {
+		return h.json.Handle(ctx, r)
+	}
+	return h.text.Handle(ctx, r)
+}
+
+// reveal returns r with the values of its Redact attributes in place of
+// "[redacted]".
+func reveal(r slog.Record) slog.Record {
+	found := false
+	r.Attrs(func(a slog.Attr) bool {
+		found = isRedacted(a)
+		return !found
+	})
+	if !found {
+		return r
+	}
+	revealed := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		revealed.AddAttrs(revealAttr(a))
+		return true
+	})
+	return revealed
+}
+
+// isRedacted reports whether a, or an attribute of its group, is of Redact.
+func isRedacted(a slog.Attr) bool {
+	switch a.Value.Kind() {
+	case slog.KindLogValuer:
+		_, ok := a.Value.LogValuer().(redacted)
+		return ok
+	case slog.KindGroup:
+		for _, attr := range a.Value.Group() {
+			if isRedacted(attr) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// revealAttr returns a with the values of Redact in place of "[redacted]".
+func revealAttr(a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindLogValuer:
+		if v, ok := a.Value.LogValuer().(redacted); ok {
+			a.Value = v.reveal()
+		}
+	case slog.KindGroup:
+		group := a.Value.Group()
+		attrs := make([]slog.Attr, len(group))
+		for i, attr := range group {
+			attrs[i] = revealAttr(attr)
+		}
+		a.Value = slog.GroupValue(attrs...)
+	}
+	return a
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{c: h.c, text: h.text.WithAttrs(attrs), json: h.json.WithAttrs(attrs)}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{c: h.c, text: h.text.WithGroup(name), json: h.json.WithGroup(name)}
+}
@@ -0,0 +1,133 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	var out bytes.Buffer
+	c := NewController()
+	logger := slog.New(NewHandler(&out, c)).With("component", "test")
+
+	logger.Debug("hidden")
+	logger.Info("shown")
+	if strings.Contains(out.String(), "hidden") || !strings.Contains(out.String(), "msg=shown component=test") {
+		t.Errorf("At the info level, got %q", out.String())
+	}
+
+	// The level and format change for loggers already made
+	out.Reset()
+	if err := c.Set(Settings{Level: slog.LevelDebug, Format: JSON}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	logger.Debug("details")
+	if got := out.String(); !strings.Contains(got, `"msg":"details","component":"test"`) {
+		t.Errorf("At the debug level in JSON, got %q", got)
+	}
+
+	// Records are filtered by the package they're logged from, this one
+	out.Reset()
+	if err := c.Set(Settings{Level: slog.LevelDebug, Format: Text, Subsystems: map[string]slog.Level{"logging": slog.LevelWarn}}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	logger.Info("quiet")
+	logger.Warn("loud")
+	if got := out.String(); strings.Contains(got, "quiet") || !strings.Contains(got, "msg=loud") {
+		t.Errorf("With the subsystem at warn, got %q", got)
+	}
+	out.Reset()
+	if err := c.Set(Settings{Level: slog.LevelError, Format: Text, Subsystems: map[string]slog.Level{"logging": slog.LevelDebug}}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if logger.Debug("close look"); !strings.Contains(out.String(), "close look") {
+		t.Errorf("With the subsystem below the level, got %q", out.String())
+	}
+
+	// Invalid settings change nothing
+	if err := c.Set(Settings{Level: slog.LevelInfo, Format: "xml"}); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+	if err := c.Set(Settings{Level: slog.LevelInfo, Format: Text, Subsystems: map[string]slog.Level{"internal/db": slog.LevelWarn}}); err == nil {
+		t.Error("Expected a path as a subsystem to be rejected")
+	}
+	if s := c.Settings(); s.Level != slog.LevelError || s.Subsystems["logging"] != slog.LevelDebug {
+		t.Errorf("Expected the settings to be kept, got %+v", s)
+	}
+}
+
+func TestParseSubsystems(t *testing.T) {
+	levels, err := ParseSubsystems(" db=warn, webhook=DEBUG ,,covers=info+2")
+	want := map[string]slog.Level{"db": slog.LevelWarn, "webhook": slog.LevelDebug, "covers": slog.LevelInfo + 2}
+	if err != nil || !reflect.DeepEqual(levels, want) {
+		t.Errorf("ParseSubsystems = %v, %v; want %v", levels, err, want)
+	}
+	if got := FormatSubsystems(levels); got != "covers=info+2,db=warn,webhook=debug" {
+		t.Errorf("FormatSubsystems = %q", got)
+	}
+	for _, bad := range []string{"db", "db=loud"} {
+		if _, err := ParseSubsystems(bad); err == nil {
+			t.Errorf("ParseSubsystems(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestRedactAndSample(t *testing.T) {
+	var out bytes.Buffer
+	c := NewController()
+	logger := slog.New(NewHandler(&out, c))
+	comments, none := "Loved it", (*string)(nil)
+
+	logger.Info("SQL: Executing AddBook query", "title", "Dune", Redact("comments", &comments), Redact("description", none))
+	if got := out.String(); !strings.Contains(got, "title=Dune comments=[redacted] description=<nil>") {
+		t.Errorf("At the info level, got %q", got)
+	}
+	out.Reset()
+	if err := c.Set(Settings{Level: slog.LevelInfo, Format: JSON, Subsystems: map[string]slog.Level{"logging": slog.LevelDebug}}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	logger.Info("SQL: Executing AddBook query", slog.Group("book", Redact("comments", &comments)))
+	if got := out.String(); !strings.Contains(got, `"book":{"comments":"Loved it"}`) {
+		t.Errorf("At the debug level, got %q", got)
+	}
+
+	// Three lines a second of each call site, with the count of those left out
+	if err := c.Set(Settings{Level: slog.LevelInfo, Format: Text, Sample: 3}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	out.Reset()
+	for i := 0; i < 10; i++ {
+		logger.Info("SQL: Executing GetBookByID query")
+		logger.Info("Not a query")
+	}
+	if got := strings.Count(out.String(), "GetBookByID"); got < 3 || got > 6 { // Up to 6 across two seconds
+		t.Errorf("Expected 3 sampled lines, got %d: %q", got, out.String())
+	}
+	if got := strings.Count(out.String(), "Not a query"); got != 10 {
+		t.Errorf("Expected every other line, got %d", got)
+	}
+	logger.Error("SQL: Executing GetBookByID query failed")
+	if !strings.Contains(out.String(), "query failed") {
+		t.Error("Expected errors not to be sampled")
+	}
+}
+
+func TestSampler(t *testing.T) {
+	var s sampler
+	second := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	for i, want := range []bool{true, true, false, false} {
+		if _, ok := s.take(1, second.Add(time.Duration(i)*time.Millisecond), 2); ok != want {
+			t.Errorf("Record %d: got %v, want %v", i, ok, want)
+		}
+	}
+	if _, ok := s.take(2, second, 2); !ok {
+		t.Error("Expected call sites to be sampled apart")
+	}
+	if dropped, ok := s.take(1, second.Add(time.Second), 2); !ok || dropped != 2 {
+		t.Errorf("The next second: got %d, %v; want 2 left out", dropped, ok)
+	}
+}
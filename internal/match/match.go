@@ -0,0 +1,430 @@
+// Package match scores how likely an incoming book (an import row, a remote
+// shelf entry) refers to a book already on the bookshelf. Identifiers decide
+// outright; otherwise normalized title, author and publication year are
+// compared and the score is bucketed by configurable thresholds.
+package match
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// Outcome is the verdict for a candidate.
+type Outcome string
+
+const (
+	Matched Outcome = "matched" // Confident enough to link automatically
+	Review  Outcome = "review"  // Plausible, but a person should confirm
+	NoMatch Outcome = "none"
+)
+
+// Thresholds control how scores map to outcomes. Scores range from 0 to 1.
+type Thresholds struct {
+	Accept float64 // Scores at or above this are Matched
+	Review float64 // Scores at or above this (but below Accept) need review
+}
+
+// DefaultThresholds are tuned so that small typos and subtitle differences still
+// match while same-titled books by different authors go to review.
+var DefaultThresholds = Thresholds{Accept: 0.9, Review: 0.7}
+
+// Validate checks that the thresholds are ordered and within range.
+func (t Thresholds) Validate() error {
+	if t.Review <= 0 || t.Review > t.Accept || t.Accept > 1 {
+		return fmt.Errorf("match thresholds must satisfy 0 < review (%.2f) <= accept (%.2f) <= 1", t.Review, t.Accept)
+	}
+	return nil
+}
+
+// ambiguityMargin is how close the runner-up may score to the best candidate
+// before an otherwise confident match is sent to review.
+const ambiguityMargin = 0.05
+
+// Candidate describes the incoming book to be matched.
+type Candidate struct {
+	OpenLibraryID string
+	ISBNs         []string
+	Title         string
+	Author        string
+	Year          *int
+}
+
+// Scored is a local book with its score against a candidate.
+type Scored struct {
+	Book  model.Book `json:"book"`
+	Score float64    `json:"score"`
+}
+
+// Result is the outcome of matching one candidate.
+type Result struct {
+	Outcome    Outcome  `json:"outcome"`
+	Best       *Scored  `json:"best,omitempty"`
+	Candidates []Scored `json:"candidates"` // Every book at or above the review threshold, best first
+}
+
+// entry caches the normalized form of a local book.
+type entry struct {
+	book   model.Book
+	title  string
+	main   string // Title without subtitle
+	author string
+}
+
+// Index is a set of local books prepared for repeated matching.
+type Index struct {
+	Thresholds Thresholds
+
+	entries []entry
+	byISBN  map[string][]int
+	byOLID  map[string]int
+	byID    map[int64]int
+	byToken map[string][]int // Title token -> entries containing it
+}
+
+// NewIndex prepares books for matching with the default thresholds.
+func NewIndex(books []model.Book) *Index {
+	idx := &Index{
+		Thresholds: DefaultThresholds,
+		byISBN:     make(map[string][]int),
+		byOLID:     make(map[string]int),
+		byID:       make(map[int64]int),
+		byToken:    make(map[string][]int),
+	}
+	for _, b := range books {
+		idx.Add(b)
+	}
+	return idx
+}
+
+// Add inserts a book into the index, e.g. after it was imported.
+func (idx *Index) Add(b model.Book) {
+	i := len(idx.entries)
+	// Imports and provider results carry the subtitle in the title
+	title, main := normalizeTitle(b.FullTitle())
+	idx.entries = append(idx.entries, entry{book: b, title: title, main: main, author: normalizeAuthor(b.Author)})
+	idx.byID[b.ID] = i
+	if b.OpenLibraryID != "" {
+		idx.byOLID[b.OpenLibraryID] = i
+	}
+	if isbn := normalizeISBN(b.ISBN); isbn != "" {
+		idx.byISBN[isbn] = append(idx.byISBN[isbn], i)
+	}
+	seen := make(map[string]bool)
+	for _, tok := range strings.Fields(title) {
+		if !seen[tok] {
+			seen[tok] = true
+			idx.byToken[tok] = append(idx.byToken[tok], i)
+		}
+	}
+}
+
+// Match scores c against every plausible book in the index.
+func (idx *Index) Match(c Candidate) Result {
+	// Identifiers are authoritative
+	if c.OpenLibraryID != "" {
+		if i, ok := idx.byOLID[c.OpenLibraryID]; ok {
+			return idx.exact(i)
+		}
+	}
+	for _, isbn := range c.ISBNs {
+		if hits := idx.byISBN[normalizeISBN(isbn)]; len(hits) == 1 {
+			return idx.exact(hits[0])
+		}
+	}
+
+	title, main := normalizeTitle(c.Title)
+	author := normalizeAuthor(c.Author)
+
+	// Only score books sharing at least one title word
+	considered := make(map[int]bool)
+	for _, tok := range strings.Fields(title) {
+		for _, i := range idx.byToken[tok] {
+			considered[i] = true
+		}
+	}
+
+	result := Result{Outcome: NoMatch, Candidates: []Scored{}}
+	for i := range considered {
+		e := idx.entries[i]
+		score := scoreEntry(e, title, main, author, c.Year)
+		if score >= idx.Thresholds.Review {
+			result.Candidates = append(result.Candidates, Scored{Book: e.book, Score: score})
+		}
+	}
+	if len(result.Candidates) == 0 {
+		return result
+	}
+
+	sort.Slice(result.Candidates, func(a, b int) bool {
+		return result.Candidates[a].Score > result.Candidates[b].Score
+	})
+	best := result.Candidates[0]
+	result.Best = &best
+	result.Outcome = Review
+	if best.Score >= idx.Thresholds.Accept {
+		ambiguous := len(result.Candidates) > 1 && best.Score-result.Candidates[1].Score < ambiguityMargin
+		if !ambiguous {
+			result.Outcome = Matched
+		}
+	}
+	return result
+}
+
+// PendingCandidates converts the candidates to the form stored in the review queue.
+func (r Result) PendingCandidates() []model.PendingCandidate {
+	out := make([]model.PendingCandidate, 0, len(r.Candidates))
+	for _, c := range r.Candidates {
+		out = append(out, model.PendingCandidate{BookID: c.Book.ID, Title: c.Book.FullTitle(), Author: c.Book.Author, Score: c.Score})
+	}
+	return out
+}
+
+func (idx *Index) exact(i int) Result {
+	s := Scored{Book: idx.entries[i].book, Score: 1}
+	return Result{Outcome: Matched, Best: &s, Candidates: []Scored{s}}
+}
+
+// Duplicates returns groups of books in the index that look like the same book.
+// Each group starts with a book followed by the later books resembling it, best
+// match first, so every pair is reported once.
+func (idx *Index) Duplicates() [][]Scored {
+	groups := [][]Scored{}
+	for i, e := range idx.entries {
+		group := []Scored{{Book: e.book, Score: 1}}
+		seen := map[int]bool{i: true}
+		if isbn := normalizeISBN(e.book.ISBN); isbn != "" {
+			for _, j := range idx.byISBN[isbn] {
+				if j > i && !seen[j] {
+					seen[j] = true
+					group = append(group, Scored{Book: idx.entries[j].book, Score: 1})
+				}
+			}
+		}
+		result := idx.Match(Candidate{Title: e.book.FullTitle(), Author: e.book.Author, Year: e.book.PublishYear})
+		for _, s := range result.Candidates {
+			if j := idx.byID[s.Book.ID]; j > i && !seen[j] {
+				seen[j] = true
+				group = append(group, s)
+			}
+		}
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// Score compares a candidate with a single book. It is exposed for callers that
+// need a similarity without building an index.
+func Score(c Candidate, b model.Book) float64 {
+	for _, isbn := range c.ISBNs {
+		if isbn = normalizeISBN(isbn); isbn != "" && isbn == normalizeISBN(b.ISBN) {
+			return 1
+		}
+	}
+	title, main := normalizeTitle(c.Title)
+	bTitle, bMain := normalizeTitle(b.FullTitle())
+	e := entry{book: b, title: bTitle, main: bMain, author: normalizeAuthor(b.Author)}
+	return scoreEntry(e, title, main, normalizeAuthor(c.Author), c.Year)
+}
+
+// Weights of each signal in the combined score.
+const (
+	titleWeight  = 0.65
+	authorWeight = 0.25
+	yearWeight   = 0.10
+	// titleOnlyFactor caps matches where one side has no author below the
+	// default accept threshold, so they always go to review.
+	titleOnlyFactor = 0.85
+	// Below differentAuthor similarity the authors are treated as different people.
+	differentAuthor       = 0.5
+	differentAuthorFactor = 0.8
+)
+
+func scoreEntry(e entry, title, main, author string, year *int) float64 {
+	titleScore := similarity(title, e.title)
+	if main != "" || e.main != "" {
+		// "Dune" vs "Dune: Deluxe Edition" should still be close
+		if s := similarity(orElse(main, title), orElse(e.main, e.title)) * 0.95; s > titleScore {
+			titleScore = s
+		}
+	}
+
+	if author == "" || e.author == "" {
+		return titleScore * titleOnlyFactor
+	}
+	authorScore := authorSimilarity(author, e.author)
+
+	var score float64
+	if year == nil || e.book.PublishYear == nil {
+		score = (titleScore*titleWeight + authorScore*authorWeight) / (titleWeight + authorWeight)
+	} else {
+		score = titleScore*titleWeight + authorScore*authorWeight + yearScore(*year, *e.book.PublishYear)*yearWeight
+	}
+	if authorScore < differentAuthor {
+		// Identical titles by clearly different authors are usually different books
+		score *= differentAuthorFactor
+	}
+	return score
+}
+
+func yearScore(a, b int) float64 {
+	switch diff := a - b; {
+	case diff == 0:
+		return 1
+	case diff == 1 || diff == -1:
+		return 0.5 // Off-by-one between editions and first publication is common
+	default:
+		return 0
+	}
+}
+
+// authorSimilarity compares authors regardless of name order ("Herbert, Frank").
+func authorSimilarity(a, b string) float64 {
+	ta, tb := strings.Fields(a), strings.Fields(b)
+	sort.Strings(ta)
+	sort.Strings(tb)
+	s := similarity(strings.Join(ta, " "), strings.Join(tb, " "))
+	// The same surname with different initials ("J Tolkien" vs "J R R Tolkien")
+	if s < 0.9 && longest(ta) != "" && longest(ta) == longest(tb) {
+		s = 0.9
+	}
+	return s
+}
+
+func longest(tokens []string) string {
+	var l string
+	for _, t := range tokens {
+		if len(t) > len(l) {
+			l = t
+		}
+	}
+	return l
+}
+
+var leadingArticles = []string{"the ", "a ", "an "}
+
+// normalizeTitle returns the normalized full title and the title before any subtitle.
+func normalizeTitle(s string) (string, string) {
+	s = stripParenthetical(s)
+	main := s
+	if i := strings.IndexAny(s, ":;"); i > 0 {
+		main = s[:i]
+	}
+	full, main := normalizeText(s), normalizeText(main)
+	for _, article := range leadingArticles {
+		full = strings.TrimPrefix(full, article)
+		main = strings.TrimPrefix(main, article)
+	}
+	if main == full {
+		main = ""
+	}
+	return full, main
+}
+
+// stripParenthetical drops "(...)" segments such as series info in "Dune (Dune #1)".
+func stripParenthetical(s string) string {
+	var sb strings.Builder
+	depth := 0
+	for _, r := range s {
+		switch {
+		case r == '(' || r == '[':
+			depth++
+		case (r == ')' || r == ']') && depth > 0:
+			depth--
+		case depth == 0:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func orElse(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// normalizeAuthor normalizes an author name; placeholders count as no author.
+func normalizeAuthor(s string) string {
+	s = normalizeText(s)
+	if s == "unknown" || s == "unknown author" {
+		return ""
+	}
+	return s
+}
+
+// normalizeText lowercases s, maps "&" to "and" and collapses everything that
+// is not a letter or digit into single spaces.
+func normalizeText(s string) string {
+	s = strings.ReplaceAll(strings.ToLower(s), "&", " and ")
+	var sb strings.Builder
+	space := true
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+			space = false
+		} else if r == '\'' || r == '’' {
+			continue // "Ender's" == "Enders"
+		} else if !space {
+			sb.WriteByte(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func normalizeISBN(s string) string {
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) || r == 'x' || r == 'X' {
+			return r
+		}
+		return -1
+	}, s))
+}
+
+// similarity returns 1 - levenshtein(a, b) / max(len(a), len(b)).
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// EditDistance returns the Levenshtein distance between a and b, counted in runes.
+func EditDistance(a, b string) int {
+	return levenshtein([]rune(a), []rune(b))
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
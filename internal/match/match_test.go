@@ -0,0 +1,90 @@
+package match
+
+import (
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func intPtr(i int) *int { return &i }
+
+func testBooks() []model.Book {
+	return []model.Book{
+		{ID: 1, Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL1W", ISBN: "978-0441013593", PublishYear: intPtr(1965)},
+		{ID: 2, Title: "The Left Hand of Darkness", Author: "Ursula K. Le Guin", OpenLibraryID: "OL2W"},
+		{ID: 3, Title: "Emma", Author: "Jane Austen", OpenLibraryID: "OL3W"},
+		{ID: 4, Title: "Emma", Author: "Alexander McCall Smith", OpenLibraryID: "OL4W"},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	idx := NewIndex(testBooks())
+
+	tests := []struct {
+		name      string
+		candidate Candidate
+		outcome   Outcome
+		bookID    int64
+	}{
+		{"open library ID", Candidate{OpenLibraryID: "OL2W", Title: "Something else"}, Matched, 2},
+		{"ISBN ignores punctuation", Candidate{ISBNs: []string{"9780441013593"}, Title: "Unrelated"}, Matched, 1},
+		{"case and punctuation", Candidate{Title: "DUNE!", Author: "frank herbert"}, Matched, 1},
+		{"series suffix and subtitle", Candidate{Title: "Dune (Dune Chronicles #1): Deluxe Edition", Author: "Frank Herbert"}, Matched, 1},
+		{"leading article and author order", Candidate{Title: "Left Hand of Darkness", Author: "Le Guin, Ursula K."}, Matched, 2},
+		{"typo", Candidate{Title: "The Left Hand of Darknes", Author: "Ursula K. Le Guin"}, Matched, 2},
+		{"same title, matching author", Candidate{Title: "Emma", Author: "Jane Austen"}, Matched, 3},
+		{"same title, no author", Candidate{Title: "Emma"}, Review, 0},
+		{"year mismatch lowers score", Candidate{Title: "Dune", Author: "Brian Herbert", Year: intPtr(2020)}, Review, 1},
+		{"unrelated", Candidate{Title: "Neuromancer", Author: "William Gibson"}, NoMatch, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := idx.Match(tt.candidate)
+			if result.Outcome != tt.outcome {
+				t.Fatalf("Expected outcome %s, got %s (%+v)", tt.outcome, result.Outcome, result.Best)
+			}
+			if tt.bookID != 0 && (result.Best == nil || result.Best.Book.ID != tt.bookID) {
+				t.Errorf("Expected best match to be book %d, got %+v", tt.bookID, result.Best)
+			}
+		})
+	}
+}
+
+func TestMatchThresholds(t *testing.T) {
+	idx := NewIndex(testBooks())
+	candidate := Candidate{Title: "The Left Hand of Darknes", Author: "Ursula K. Le Guin"}
+
+	idx.Thresholds = Thresholds{Accept: 0.999, Review: 0.9}
+	if result := idx.Match(candidate); result.Outcome != Review {
+		t.Errorf("Expected a strict accept threshold to send the typo to review, got %s", result.Outcome)
+	}
+	idx.Thresholds = Thresholds{Accept: 1, Review: 0.999}
+	if result := idx.Match(candidate); result.Outcome != NoMatch {
+		t.Errorf("Expected a strict review threshold to reject the typo, got %s", result.Outcome)
+	}
+
+	if err := (Thresholds{Accept: 0.5, Review: 0.8}).Validate(); err == nil {
+		t.Error("Expected error when review exceeds accept")
+	}
+	if err := DefaultThresholds.Validate(); err != nil {
+		t.Errorf("Default thresholds should be valid: %v", err)
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	books := append(testBooks(),
+		model.Book{ID: 5, Title: "Dune: Deluxe Edition", Author: "Herbert, Frank", OpenLibraryID: "OL5W"},
+		model.Book{ID: 6, Title: "A Different Title", Author: "Someone", OpenLibraryID: "OL6W", ISBN: "9780441013593"},
+	)
+	groups := NewIndex(books).Duplicates()
+	if len(groups) != 1 {
+		t.Fatalf("Expected 1 duplicate group, got %d: %+v", len(groups), groups)
+	}
+	ids := map[int64]bool{}
+	for _, s := range groups[0] {
+		ids[s.Book.ID] = true
+	}
+	if groups[0][0].Book.ID != 1 || !ids[5] || !ids[6] || len(ids) != 3 {
+		t.Errorf("Unexpected duplicate group: %+v", groups[0])
+	}
+}
@@ -0,0 +1,140 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GoogleBooksName is the name of the Google Books provider.
+const GoogleBooksName = "googlebooks"
+
+// GoogleBooksIDPrefix marks the IDs of Google Books volumes, which are stored
+// alongside Open Library keys.
+const GoogleBooksIDPrefix = "gbooks:"
+
+// GoogleBooks searches Google Books, which covers many newer and non-English
+// titles Open Library lacks. Its IDs are volume IDs with GoogleBooksIDPrefix.
+type GoogleBooks struct {
+	BaseURL    string // e.g. https://www.googleapis.com/books/v1
+	APIKey     string // Optional; raises the unauthenticated quota
+	HTTPClient *http.Client
+}
+
+// NewGoogleBooks creates a Google Books provider sending requests with client.
+func NewGoogleBooks(client *http.Client) *GoogleBooks {
+	return &GoogleBooks{BaseURL: "https://www.googleapis.com/books/v1", HTTPClient: client}
+}
+
+// googleBooksResponse is the part of a Google Books volume search we use.
+// See: https://developers.google.com/books/docs/v1/reference/volumes/list
+type googleBooksResponse struct {
+	Items []struct {
+		ID         string `json:"id"`
+		VolumeInfo struct {
+			Title               string   `json:"title"`
+			Subtitle            string   `json:"subtitle"`
+			Authors             []string `json:"authors"`
+			PublishedDate       string   `json:"publishedDate"` // "2011", "2011-10" or "2011-10-04"
+			PageCount           int      `json:"pageCount"`
+			Publisher           string   `json:"publisher"`
+			Language            string   `json:"language"` // ISO 639-1, e.g. "en"
+			IndustryIdentifiers []struct {
+				Type       string `json:"type"` // ISBN_10, ISBN_13 or OTHER
+				Identifier string `json:"identifier"`
+			} `json:"industryIdentifiers"`
+			ImageLinks struct {
+				Thumbnail string `json:"thumbnail"`
+			} `json:"imageLinks"`
+		} `json:"volumeInfo"`
+	} `json:"items"`
+}
+
+// Name returns "googlebooks".
+func (g *GoogleBooks) Name() string { return GoogleBooksName }
+
+// Search searches volumes by title, author and other text.
+func (g *GoogleBooks) Search(ctx context.Context, query string) ([]Book, error) {
+	return g.search(ctx, query)
+}
+
+// LookupISBN finds the volumes with isbn.
+func (g *GoogleBooks) LookupISBN(ctx context.Context, isbn string) ([]Book, error) {
+	return g.search(ctx, "isbn:"+isbn)
+}
+
+func (g *GoogleBooks) search(ctx context.Context, q string) ([]Book, error) {
+	params := url.Values{"q": {q}, "maxResults": {"20"}, "printType": {"books"}}
+	apiURL := g.BaseURL + "/volumes?" + params.Encode()
+	slog.Info("Querying Google Books", "url", apiURL)
+	if g.APIKey != "" {
+		params.Set("key", g.APIKey) // Added after logging, so the key stays out of the logs
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.BaseURL+"/volumes?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Google Books request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+
+	start := time.Now()
+	resp, err := g.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to contact Google Books API: %w", err)
+	}
+	defer resp.Body.Close()
+	slog.Info("Google Books API response",
+		"url", apiURL,
+		"status", resp.StatusCode,
+		"responseTime", time.Since(start))
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return nil, fmt.Errorf("Google Books API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var gbResponse googleBooksResponse
+	if err := json.NewDecoder(resp.Body).Decode(&gbResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode Google Books response: %w", err)
+	}
+
+	books := []Book{}
+	for _, item := range gbResponse.Items {
+		if item.ID == "" {
+			continue
+		}
+		info := item.VolumeInfo
+		var isbns []string
+		for _, id := range info.IndustryIdentifiers {
+			if id.Type == "ISBN_13" || id.Type == "ISBN_10" {
+				isbns = append(isbns, id.Identifier)
+			}
+		}
+		book := Book{
+			Provider:  GoogleBooksName,
+			ID:        GoogleBooksIDPrefix + item.ID,
+			Title:     info.Title,
+			Subtitle:  info.Subtitle,
+			Authors:   info.Authors,
+			ISBN:      preferISBN13(isbns),
+			PageCount: info.PageCount,
+			Publisher: info.Publisher,
+			Language:  info.Language,
+			// Google returns http links that redirect; ask for https directly
+			CoverURL: strings.Replace(info.ImageLinks.Thumbnail, "http://", "https://", 1),
+		}
+		if len(info.PublishedDate) >= 4 {
+			book.PublishYear, _ = strconv.Atoi(info.PublishedDate[:4])
+		}
+		books = append(books, book)
+	}
+	return books, nil
+}
@@ -0,0 +1,155 @@
+// Package metadata looks books up in online catalogues such as Open Library
+// and Google Books. Each catalogue is a Provider; a Chain asks them in turn,
+// so a book one catalogue lacks can still be found in the next.
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// userAgent identifies the bookshelf to catalogues.
+const userAgent = "BookshelfApp/1.0 (github.com/ericdahl/bookshelf; contact@example.com)"
+
+// Book is a book as a provider describes it.
+type Book struct {
+	Provider    string // Name of the provider that found the book
+	ID          string // Identifier to store the book under; see Provider
+	Title       string
+	Subtitle    string
+	Authors     []string
+	ISBN        string // ISBN-13 when known, otherwise ISBN-10; "" when unknown
+	CoverURL    string // Medium-sized cover; "" when there is none
+	PublishYear int    // Year of first publication; 0 when unknown
+	PageCount   int    // 0 when unknown
+	Publisher   string // Publisher of the edition; "" when unknown
+	Language    string // Language of the edition as a code, e.g. "en" or "eng"; "" when unknown
+}
+
+// Provider searches one catalogue. Books it finds carry IDs that tell the
+// catalogues apart: Open Library keys such as OL45883W as they are, other
+// providers' IDs with a prefix, such as "gbooks:".
+type Provider interface {
+	Name() string
+	Search(ctx context.Context, query string) ([]Book, error)
+	LookupISBN(ctx context.Context, isbn string) ([]Book, error)
+}
+
+// Searcher finds books for a query, as a Chain does.
+type Searcher interface {
+	Search(ctx context.Context, query string) ([]Book, error)
+}
+
+// Chain asks its providers in order until one finds something.
+type Chain []Provider
+
+// Search looks books up by ISBN when the query is one, and by title and
+// author otherwise. A provider that fails or finds nothing is passed over for
+// the next; the error is only returned when every provider failed.
+func (c Chain) Search(ctx context.Context, query string) ([]Book, error) {
+	isbn, byISBN := NormalizeISBN(query)
+	var lastErr error
+	failed := 0
+	for _, p := range c {
+		var books []Book
+		var err error
+		if byISBN {
+			books, err = p.LookupISBN(ctx, isbn)
+		} else {
+			books, err = p.Search(ctx, query)
+		}
+		if err != nil {
+			slog.Warn("Metadata provider failed, trying the next", "provider", p.Name(), "error", err)
+			lastErr = err
+			failed++
+			continue
+		}
+		if len(books) > 0 {
+			return books, nil
+		}
+	}
+	if failed > 0 && failed == len(c) {
+		return nil, lastErr
+	}
+	return []Book{}, nil
+}
+
+// Reloadable is a Chain that can be replaced while it's in use, such as when
+// the configuration is reloaded. Searches already under way finish with the
+// chain they started with.
+type Reloadable struct {
+	chain atomic.Pointer[Chain]
+}
+
+// NewReloadable returns a Reloadable asking chain until it's replaced.
+func NewReloadable(chain Chain) *Reloadable {
+	r := &Reloadable{}
+	r.Set(chain)
+	return r
+}
+
+// Set replaces the chain later searches ask.
+func (r *Reloadable) Set(chain Chain) {
+	r.chain.Store(&chain)
+}
+
+// Search searches with the current chain.
+func (r *Reloadable) Search(ctx context.Context, query string) ([]Book, error) {
+	return r.chain.Load().Search(ctx, query)
+}
+
+// NewChain returns the providers with the given names, in that order. Names
+// are "openlibrary" and "googlebooks"; googleKey is the optional Google Books
+// API key. Every provider sends its requests with client.
+func NewChain(names []string, client *http.Client, googleKey string) (Chain, error) {
+	var chain Chain
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case OpenLibraryName:
+			chain = append(chain, NewOpenLibrary(client))
+		case GoogleBooksName:
+			gb := NewGoogleBooks(client)
+			gb.APIKey = googleKey
+			chain = append(chain, gb)
+		default:
+			return nil, fmt.Errorf("unknown metadata provider %q, expected %q or %q", name, OpenLibraryName, GoogleBooksName)
+		}
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("at least one metadata provider is required")
+	}
+	return chain, nil
+}
+
+// NormalizeISBN returns s without hyphens and spaces, and whether it is then
+// shaped like an ISBN-10 or ISBN-13. Check digits are not verified.
+func NormalizeISBN(s string) (string, bool) {
+	isbn := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(s)))
+	if len(isbn) != 10 && len(isbn) != 13 {
+		return "", false
+	}
+	for i, c := range isbn {
+		if c >= '0' && c <= '9' || c == 'X' && len(isbn) == 10 && i == 9 {
+			continue
+		}
+		return "", false
+	}
+	return isbn, true
+}
+
+// preferISBN13 returns the first ISBN-13 of isbns, or else the first entry.
+func preferISBN13(isbns []string) string {
+	for _, code := range isbns {
+		if len(code) == 13 {
+			return code
+		}
+	}
+	if len(isbns) > 0 {
+		return isbns[0]
+	}
+	return ""
+}
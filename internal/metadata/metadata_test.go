@@ -0,0 +1,181 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// stubProvider returns fixed results, recording what it was asked.
+type stubProvider struct {
+	name  string
+	books []Book
+	err   error
+	asked []string
+}
+
+func (s *stubProvider) Name() string { return s.name }
+
+func (s *stubProvider) Search(ctx context.Context, query string) ([]Book, error) {
+	s.asked = append(s.asked, "search:"+query)
+	return s.books, s.err
+}
+
+func (s *stubProvider) LookupISBN(ctx context.Context, isbn string) ([]Book, error) {
+	s.asked = append(s.asked, "isbn:"+isbn)
+	return s.books, s.err
+}
+
+func TestChainFallsBack(t *testing.T) {
+	ctx := context.Background()
+	down := &stubProvider{name: "down", err: errors.New("unavailable")}
+	empty := &stubProvider{name: "empty"}
+	found := &stubProvider{name: "found", books: []Book{{ID: "gbooks:abc", Title: "Kallocain"}}}
+	unused := &stubProvider{name: "unused", books: []Book{{ID: "OL1W"}}}
+
+	books, err := Chain{down, empty, found, unused}.Search(ctx, "978-91-0-012345-6")
+	if err != nil || len(books) != 1 || books[0].ID != "gbooks:abc" {
+		t.Fatalf("Expected the third provider's book, got %+v, %v", books, err)
+	}
+	if len(empty.asked) != 1 || empty.asked[0] != "isbn:9789100123456" {
+		t.Errorf("Expected an ISBN query to be looked up as a normalized ISBN, got %v", empty.asked)
+	}
+	if len(unused.asked) != 0 {
+		t.Errorf("Expected providers after a match not to be asked, got %v", unused.asked)
+	}
+
+	empty.asked = nil
+	if books, err := (Chain{down, empty}).Search(ctx, "Kallocain Boye"); err != nil || len(books) != 0 {
+		t.Errorf("Expected no books and no error when only some providers failed, got %+v, %v", books, err)
+	}
+	if len(empty.asked) != 1 || empty.asked[0] != "search:Kallocain Boye" {
+		t.Errorf("Expected a title query to be searched, got %v", empty.asked)
+	}
+	if _, err := (Chain{down, down}).Search(ctx, "Kallocain"); err == nil {
+		t.Error("Expected an error when every provider failed")
+	}
+}
+
+func TestNormalizeISBN(t *testing.T) {
+	for input, want := range map[string]string{
+		"978-0-441-17271-9": "9780441172719",
+		" 0 441 17271 7 ":   "0441172717",
+		"080442957x":        "080442957X",
+		"Dune":              "",
+		"97804411727X":      "",
+		"12345":             "",
+	} {
+		got, ok := NormalizeISBN(input)
+		if got != want || ok != (want != "") {
+			t.Errorf("NormalizeISBN(%q) = %q, %v; want %q", input, got, ok, want)
+		}
+	}
+}
+
+func TestNewChain(t *testing.T) {
+	chain, err := NewChain([]string{"googlebooks", " openlibrary"}, http.DefaultClient, "key")
+	if err != nil || len(chain) != 2 || chain[0].Name() != GoogleBooksName || chain[1].Name() != OpenLibraryName {
+		t.Fatalf("Expected Google Books then Open Library, got %v, %v", chain, err)
+	}
+	if chain[0].(*GoogleBooks).APIKey != "key" {
+		t.Error("Expected the Google Books provider to get the API key")
+	}
+	for _, names := range [][]string{{"openlibrary", "amazon"}, {}} {
+		if _, err := NewChain(names, http.DefaultClient, ""); err == nil {
+			t.Errorf("%v: expected an error", names)
+		}
+	}
+}
+
+func TestOpenLibrary(t *testing.T) {
+	editionFound := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/isbn/0441172717.json" && editionFound:
+			w.Write([]byte(`{"isbn_13":["9780441172719"],"publishers":["Ace Books"],"languages":[{"key":"/languages/eng"}],"number_of_pages":535}`))
+		case r.URL.Path == "/isbn/0441172717.json":
+			http.NotFound(w, r)
+		case r.URL.Path == "/search.json" && r.URL.Query().Get("isbn") == "0441172717":
+			w.Write([]byte(`{"numFound":1,"docs":[{"key":"/works/OL893415W","title":"Dune","author_name":["Frank Herbert"],
+				"isbn":["0441172717","9780441172719"],"cover_i":11481354,"first_publish_year":1965,"number_of_pages_median":528}]}`))
+		default:
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+	}))
+	defer server.Close()
+
+	ol := NewOpenLibrary(server.Client())
+	ol.BaseURL, ol.CoversURL = server.URL, "https://covers.example"
+	books, err := ol.LookupISBN(context.Background(), "0441172717")
+	if err != nil || len(books) != 1 {
+		t.Fatalf("Expected one book, got %+v, %v", books, err)
+	}
+	want := Book{Provider: OpenLibraryName, ID: "OL893415W", Title: "Dune", Authors: []string{"Frank Herbert"}, ISBN: "9780441172719",
+		CoverURL: "https://covers.example/b/id/11481354-M.jpg", PublishYear: 1965, PageCount: 535, Publisher: "Ace Books", Language: "eng"}
+	if !reflect.DeepEqual(books[0], want) {
+		t.Errorf("Expected %+v, got %+v", want, books[0])
+	}
+
+	// Without the edition record, the work is returned as found
+	editionFound = false
+	books, err = ol.LookupISBN(context.Background(), "0441172717")
+	want.PageCount, want.Publisher, want.Language = 528, "", ""
+	if err != nil || len(books) != 1 || !reflect.DeepEqual(books[0], want) {
+		t.Errorf("Expected %+v, got %+v, %v", want, books, err)
+	}
+}
+
+func TestGoogleBooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/volumes" || r.URL.Query().Get("q") != "isbn:9789100123456" || r.URL.Query().Get("key") != "secret" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"items":[{"id":"zyTCAlFPjgYC","volumeInfo":{"title":"Kallocain","subtitle":"Roman",
+			"authors":["Karin Boye"],"publisher":"Bonnier","publishedDate":"2013-05-02","pageCount":212,"language":"sv",
+			"industryIdentifiers":[{"type":"ISBN_10","identifier":"9100123456"},{"type":"ISBN_13","identifier":"9789100123456"}],
+			"imageLinks":{"thumbnail":"http://books.google.com/books/content?id=zyTCAlFPjgYC"}}}]}`))
+	}))
+	defer server.Close()
+
+	gb := NewGoogleBooks(server.Client())
+	gb.BaseURL, gb.APIKey = server.URL, "secret"
+	books, err := gb.LookupISBN(context.Background(), "9789100123456")
+	if err != nil || len(books) != 1 {
+		t.Fatalf("Expected one book, got %+v, %v", books, err)
+	}
+	want := Book{Provider: GoogleBooksName, ID: "gbooks:zyTCAlFPjgYC", Title: "Kallocain", Subtitle: "Roman", Authors: []string{"Karin Boye"}, ISBN: "9789100123456",
+		CoverURL: "https://books.google.com/books/content?id=zyTCAlFPjgYC", PublishYear: 2013, PageCount: 212, Publisher: "Bonnier", Language: "sv"}
+	if !reflect.DeepEqual(books[0], want) {
+		t.Errorf("Expected %+v, got %+v", want, books[0])
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer failing.Close()
+	gb.BaseURL = failing.URL
+	if _, err := gb.Search(context.Background(), "Kallocain"); err == nil {
+		t.Error("Expected an error when Google Books refuses the request")
+	}
+}
+
+func TestReloadable(t *testing.T) {
+	ctx := context.Background()
+	first := &stubProvider{name: "first", books: []Book{{ID: "OL1W"}}}
+	second := &stubProvider{name: "second", books: []Book{{ID: "gbooks:abc"}}}
+
+	r := NewReloadable(Chain{first})
+	if books, err := r.Search(ctx, "dune"); err != nil || len(books) != 1 || books[0].ID != "OL1W" {
+		t.Fatalf("Search = %v, %v; want the first chain's book", books, err)
+	}
+	r.Set(Chain{second})
+	if books, err := r.Search(ctx, "dune"); err != nil || len(books) != 1 || books[0].ID != "gbooks:abc" {
+		t.Fatalf("Search after Set = %v, %v; want the second chain's book", books, err)
+	}
+	if len(first.asked) != 1 {
+		t.Errorf("Expected the replaced chain not to be asked again, got %v", first.asked)
+	}
+}
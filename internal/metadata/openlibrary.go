@@ -0,0 +1,166 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OpenLibraryName is the name of the Open Library provider.
+const OpenLibraryName = "openlibrary"
+
+// searchFields are the fields asked of the Open Library search API.
+const searchFields = "key,title,subtitle,author_name,isbn,cover_i,author_key,first_publish_year,number_of_pages_median"
+
+// OpenLibrary searches Open Library. Its IDs are work or edition keys such as
+// OL7353617M.
+type OpenLibrary struct {
+	BaseURL    string // e.g. https://openlibrary.org
+	CoversURL  string // e.g. https://covers.openlibrary.org
+	HTTPClient *http.Client
+}
+
+// NewOpenLibrary creates an Open Library provider sending requests with client.
+func NewOpenLibrary(client *http.Client) *OpenLibrary {
+	return &OpenLibrary{
+		BaseURL:    "https://openlibrary.org",
+		CoversURL:  "https://covers.openlibrary.org",
+		HTTPClient: client,
+	}
+}
+
+// openLibrarySearchResponse is the structure matching the Open Library Search API JSON response.
+// We only map the fields we need. See: https://openlibrary.org/dev/docs/api/search
+type openLibrarySearchResponse struct {
+	NumFound int `json:"numFound"`
+	Docs     []struct {
+		Key              string   `json:"key"` // e.g., "/works/OL7353617M"
+		Title            string   `json:"title"`
+		Subtitle         string   `json:"subtitle"`
+		AuthorName       []string `json:"author_name"` // Array of author names
+		ISBN             []string `json:"isbn"`        // Array of ISBNs (10 and 13)
+		CoverI           int      `json:"cover_i"`     // Cover ID (integer)
+		AuthorKey        []string `json:"author_key"`  // Array of author IDs
+		FirstPublishYear int      `json:"first_publish_year"`
+		PageCountMedian  int      `json:"number_of_pages_median"`
+	} `json:"docs"`
+}
+
+// Name returns "openlibrary".
+func (o *OpenLibrary) Name() string { return OpenLibraryName }
+
+// Search searches works by title, author and other text.
+func (o *OpenLibrary) Search(ctx context.Context, query string) ([]Book, error) {
+	return o.search(ctx, url.Values{"q": {query}})
+}
+
+// openLibraryEdition is the part of an edition record we use.
+// See: https://openlibrary.org/dev/docs/api/books
+type openLibraryEdition struct {
+	ISBN13        []string `json:"isbn_13"`
+	Publishers    []string `json:"publishers"`
+	NumberOfPages int      `json:"number_of_pages"`
+	Languages     []struct {
+		Key string `json:"key"` // e.g. "/languages/eng"
+	} `json:"languages"`
+}
+
+// LookupISBN finds the works with an edition of isbn, with the ISBN,
+// publisher, language and page count of that edition. When the edition record can't be
+// had, the works are returned as search finds them.
+func (o *OpenLibrary) LookupISBN(ctx context.Context, isbn string) ([]Book, error) {
+	books, err := o.search(ctx, url.Values{"isbn": {isbn}})
+	if err != nil || len(books) == 0 {
+		return books, err
+	}
+	var edition openLibraryEdition
+	if err := o.getJSON(ctx, o.BaseURL+"/isbn/"+url.PathEscape(isbn)+".json", &edition); err != nil {
+		slog.Warn("Open Library edition lookup failed", "isbn", isbn, "error", err)
+		return books, nil
+	}
+	for i := range books {
+		books[i].ISBN = preferISBN13(append(edition.ISBN13, isbn))
+		if len(edition.Publishers) > 0 {
+			books[i].Publisher = strings.TrimSpace(edition.Publishers[0])
+		}
+		if len(edition.Languages) > 0 {
+			books[i].Language = strings.TrimPrefix(edition.Languages[0].Key, "/languages/")
+		}
+		if edition.NumberOfPages > 0 {
+			books[i].PageCount = edition.NumberOfPages
+		}
+	}
+	return books, nil
+}
+
+func (o *OpenLibrary) search(ctx context.Context, params url.Values) ([]Book, error) {
+	// Using the works search endpoint as it often has better consolidated data
+	params.Set("fields", searchFields)
+	params.Set("limit", "20")
+	var olResponse openLibrarySearchResponse
+	if err := o.getJSON(ctx, o.BaseURL+"/search.json?"+params.Encode(), &olResponse); err != nil {
+		return nil, err
+	}
+
+	books := []Book{}
+	for _, doc := range olResponse.Docs {
+		// Extract OpenLibrary ID from the key (e.g., "/works/OL7353617M" -> "OL7353617M")
+		parts := strings.Split(doc.Key, "/")
+		olid := parts[len(parts)-1]
+		if olid == "" {
+			continue // Skip if we can't get an ID
+		}
+		book := Book{
+			Provider:    OpenLibraryName,
+			ID:          olid,
+			Title:       doc.Title,
+			Subtitle:    doc.Subtitle,
+			Authors:     doc.AuthorName,
+			ISBN:        preferISBN13(doc.ISBN),
+			PublishYear: doc.FirstPublishYear,
+			PageCount:   doc.PageCountMedian,
+		}
+		if doc.CoverI > 0 {
+			book.CoverURL = fmt.Sprintf("%s/b/id/%d-M.jpg", o.CoversURL, doc.CoverI)
+		}
+		books = append(books, book)
+	}
+	return books, nil
+}
+
+// getJSON fetches an Open Library API URL and decodes its JSON response into out.
+func (o *OpenLibrary) getJSON(ctx context.Context, apiURL string, out interface{}) error {
+	slog.Info("Querying Open Library", "url", apiURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create Open Library request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent) // Be a good API citizen
+
+	start := time.Now()
+	resp, err := o.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to contact Open Library API: %w", err)
+	}
+	defer resp.Body.Close()
+	slog.Info("OpenLibrary API response",
+		"url", apiURL,
+		"status", resp.StatusCode,
+		"responseTime", time.Since(start))
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10)) // Read body for context, ignore error
+		return fmt.Errorf("Open Library API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode Open Library response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+// Package metrics exposes the server's metrics to Prometheus, in its text
+// exposition format (version 0.0.4). It implements the three kinds the server
+// needs, counters, histograms and gauges read when scraped, rather than
+// depending on the Prometheus client library. Like expvar, metrics are
+// registered globally when created, and created once, as package variables.
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets are the default histogram buckets, in seconds: those of the
+// Prometheus client libraries, for latencies from 5ms to 10s.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Collector writes a metric family in the exposition format.
+type Collector interface {
+	// Name returns the name of the metric family.
+	Name() string
+	// Collect writes the family.
+	Collect(ctx context.Context, w io.Writer) error
+}
+
+var (
+	mu         sync.Mutex
+	registered = map[string]Collector{}
+)
+
+// Register adds a collector to those written by Handler. It panics if one
+// with the same name is registered already.
+func Register(c Collector) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := registered[c.Name()]; ok {
+		panic("metrics: " + c.Name() + " is already registered")
+	}
+	registered[c.Name()] = c
+}
+
+// Write writes every registered collector, by name, and then extra.
+func Write(ctx context.Context, w io.Writer, extra ...Collector) error {
+	mu.Lock()
+	collectors := make([]Collector, 0, len(registered)+len(extra))
+	for _, c := range registered {
+		collectors = append(collectors, c)
+	}
+	mu.Unlock()
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].Name() < collectors[j].Name() })
+
+	b := bufio.NewWriter(w)
+	for _, c := range append(collectors, extra...) {
+		if err := c.Collect(ctx, b); err != nil {
+			return fmt.Errorf("failed to collect %s: %w", c.Name(), err)
+		}
+	}
+	return b.Flush()
+}
+
+// Handler serves every registered collector, and extra, to Prometheus.
+func Handler(extra ...Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body bytes.Buffer
+		if err := Write(r.Context(), &body, extra...); err != nil {
+			slog.Error("Failed to collect metrics", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", ContentType)
+		w.Write(body.Bytes())
+	})
+}
+
+// family holds what metrics of every kind have: a name, help text and label
+// names. Series are keyed by their label values, joined.
+type family struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (f *family) Name() string { return f.name }
+
+// key returns the series key of labelValues, panicking when their number is
+// wrong, as that is a programming error.
+func (f *family) key(labelValues []string) string {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+// header writes the HELP and TYPE lines of the family.
+func (f *family) header(w io.Writer, kind string) {
+	help := strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(f.help)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, help, f.name, kind)
+}
+
+// sample writes one sample of the family's series with labelValues, and
+// extra label pairs.
+func (f *family) sample(w io.Writer, suffix string, labelValues []string, value float64, extra ...string) {
+	var pairs []string
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	for i, name := range f.labels {
+		pairs = append(pairs, name+`="`+escape.Replace(labelValues[i])+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escape.Replace(extra[i+1])+`"`)
+	}
+	labels := ""
+	if len(pairs) > 0 {
+		labels = "{" + strings.Join(pairs, ",") + "}"
+	}
+	fmt.Fprintf(w, "%s%s%s %s\n", f.name, suffix, labels, formatFloat(value))
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// sortedKeys returns the keys of series in order, for stable output.
+func sortedKeys[V any](series map[string]V) []string {
+	keys := make([]string, 0, len(series))
+	for k := range series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splitKey returns the label values of a series key.
+func splitKey(key string, labels int) []string {
+	if labels == 0 {
+		return nil
+	}
+	return strings.Split(key, "\xff")
+}
+
+// Counter is a family of counters that only go up.
+type Counter struct {
+	family
+	mu     sync.Mutex
+	series map[string]float64
+}
+
+// NewCounter registers a counter family with the label names.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{family: family{name, help, labels}, series: map[string]float64{}}
+	Register(c)
+	return c
+}
+
+// Inc adds one to the counter with labelValues.
+func (c *Counter) Inc(labelValues ...string) { c.Add(1, labelValues...) }
+
+// Add adds v, which must not be negative, to the counter with labelValues.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: counters can't decrease")
+	}
+	key := c.key(labelValues)
+	c.mu.Lock()
+	c.series[key] += v
+	c.mu.Unlock()
+}
+
+// Value returns the counter with labelValues.
+func (c *Counter) Value(labelValues ...string) float64 {
+	key := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.series[key]
+}
+
+func (c *Counter) Collect(ctx context.Context, w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header(w, "counter")
+	for _, key := range sortedKeys(c.series) {
+		c.sample(w, "", splitKey(key, len(c.labels)), c.series[key])
+	}
+	return nil
+}
+
+// Histogram is a family of histograms, counting observations in buckets.
+type Histogram struct {
+	family
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64 // Per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram family with the upper bounds of its
+// buckets, in increasing order, and the label names.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{family: family{name, help, labels}, buckets: buckets, series: map[string]*histogramSeries{}}
+	Register(h)
+	return h
+}
+
+// Observe adds an observation to the histogram with labelValues.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+// Count returns the number of observations of the histogram with labelValues.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[key]; ok {
+		return s.count
+	}
+	return 0
+}
+
+func (h *Histogram) Collect(ctx context.Context, w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(w, "histogram")
+	for _, key := range sortedKeys(h.series) {
+		s, values := h.series[key], splitKey(key, len(h.labels))
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			h.sample(w, "_bucket", values, float64(cumulative), "le", formatFloat(bound))
+		}
+		h.sample(w, "_bucket", values, float64(s.count), "le", "+Inf")
+		h.sample(w, "_sum", values, s.sum)
+		h.sample(w, "_count", values, float64(s.count))
+	}
+	return nil
+}
+
+// Sample is the value of one series of a GaugeFunc.
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// GaugeFunc is a family of gauges read when they are scraped, such as from
+// the database.
+type GaugeFunc struct {
+	family
+	read func(ctx context.Context) ([]Sample, error)
+}
+
+// NewGaugeFunc returns a gauge family with the label names that read reads.
+// It isn't registered, as what it reads usually belongs to something that
+// isn't global; pass it to Handler instead.
+func NewGaugeFunc(name, help string, read func(ctx context.Context) ([]Sample, error), labels ...string) *GaugeFunc {
+	return &GaugeFunc{family: family{name, help, labels}, read: read}
+}
+
+func (g *GaugeFunc) Collect(ctx context.Context, w io.Writer) error {
+	samples, err := g.read(ctx)
+	if err != nil {
+		return err
+	}
+	g.header(w, "gauge")
+	for _, s := range samples {
+		g.key(s.LabelValues) // Checks their number
+		g.sample(w, "", s.LabelValues, s.Value)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExposition(t *testing.T) {
+	requests := NewCounter("test_requests_total", "Requests\nserved.", "path")
+	requests.Inc(`/a"b`)
+	requests.Add(2, "/")
+	latency := NewHistogram("test_latency_seconds", "Latency.", []float64{0.1, 1})
+	for _, v := range []float64{0.05, 0.1, 0.5, 3} {
+		latency.Observe(v)
+	}
+	books := NewGaugeFunc("test_books", "Books.", func(ctx context.Context) ([]Sample, error) {
+		return []Sample{{LabelValues: []string{"Read"}, Value: 3}}, nil
+	}, "status")
+
+	rr := httptest.NewRecorder()
+	Handler(books).ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != ContentType {
+		t.Fatalf("Got status %d, headers %v", rr.Code, rr.Header())
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		"# HELP test_latency_seconds Latency.\n# TYPE test_latency_seconds histogram\n" +
+			"test_latency_seconds_bucket{le=\"0.1\"} 2\ntest_latency_seconds_bucket{le=\"1\"} 3\ntest_latency_seconds_bucket{le=\"+Inf\"} 4\n" +
+			"test_latency_seconds_sum 3.65\ntest_latency_seconds_count 4\n",
+		"# HELP test_requests_total Requests\\nserved.\n# TYPE test_requests_total counter\n" +
+			"test_requests_total{path=\"/\"} 2\ntest_requests_total{path=\"/a\\\"b\"} 1\n",
+		"# TYPE test_books gauge\ntest_books{status=\"Read\"} 3\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in:\n%s", want, body)
+		}
+	}
+	// Registered families come by name, before the extra ones
+	if strings.Index(body, "test_latency") > strings.Index(body, "test_requests") || strings.Index(body, "test_requests") > strings.Index(body, "test_books") {
+		t.Errorf("Unexpected order:\n%s", body)
+	}
+
+	failing := NewGaugeFunc("test_failing", "Fails.", func(ctx context.Context) ([]Sample, error) {
+		return nil, errors.New("database is locked")
+	})
+	rr = httptest.NewRecorder()
+	Handler(failing).ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected a failed collection to fail the scrape, got %d", rr.Code)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a name twice to panic")
+		}
+	}()
+	NewCounter("test_requests_total", "Again.")
+}
@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// ActivityKind describes what happened in an activity entry.
+type ActivityKind string
+
+const (
+	ActivityBookAdded     ActivityKind = "book_added"
+	ActivityStatusChanged ActivityKind = "status_changed"
+	ActivityBookFinished  ActivityKind = "book_finished"
+)
+
+// IsValid checks if the kind is one of the predefined activity kinds.
+func (k ActivityKind) IsValid() bool {
+	switch k {
+	case ActivityBookAdded, ActivityStatusChanged, ActivityBookFinished:
+		return true
+	default:
+		return false
+	}
+}
+
+// Activity is a single entry in the activity timeline. Local activities are
+// recorded as books change; remote activities are cached from followed feeds.
+type Activity struct {
+	ID         int64        `json:"id"`
+	FollowID   *int64       `json:"follow_id,omitempty"` // Nil for local activity
+	RemoteID   *string      `json:"remote_id,omitempty"` // Item ID from the remote feed
+	BookID     *int64       `json:"book_id,omitempty"`   // Local book, if any
+	Kind       ActivityKind `json:"kind"`
+	Title      string       `json:"title"`
+	Author     string       `json:"author,omitempty"`
+	Status     BookStatus   `json:"status,omitempty"`
+	URL        *string      `json:"url,omitempty"`
+	Summary    string       `json:"summary"`
+	Source     string       `json:"source"` // "local" or the name of the followed feed
+	OccurredAt time.Time    `json:"occurred_at"`
+}
+
+// Follow is a remote bookshelf feed whose activity is mirrored into the local timeline.
+type Follow struct {
+	ID            int64      `json:"id"`
+	FeedURL       string     `json:"feed_url"`
+	Name          string     `json:"name"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastFetchedAt *time.Time `json:"last_fetched_at,omitempty"`
+	LastError     *string    `json:"last_error,omitempty"`
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// AuthorGender is the gender of an author, as entered by the user.
+type AuthorGender string
+
+const (
+	GenderUnknown   AuthorGender = ""
+	GenderWoman     AuthorGender = "woman"
+	GenderMan       AuthorGender = "man"
+	GenderNonbinary AuthorGender = "nonbinary"
+)
+
+// IsValid checks if the gender is one of the allowed values. Unknown is allowed.
+func (g AuthorGender) IsValid() bool {
+	switch g {
+	case GenderUnknown, GenderWoman, GenderMan, GenderNonbinary:
+		return true
+	default:
+		return false
+	}
+}
+
+// AuthorProfile is an author with optional facts about them, used only for
+// reading diversity stats. Every author a book names has one, found by name
+// ignoring case; the author has a profile once a gender or nationality is
+// set.
+type AuthorProfile struct {
+	ID          int64        `json:"id,omitempty"`
+	Name        string       `json:"name"`
+	Gender      AuthorGender `json:"gender,omitempty"`
+	Nationality *string      `json:"nationality,omitempty"` // e.g. "Nigeria"
+	UpdatedAt   *time.Time   `json:"updated_at,omitempty"`
+	BookCount   int          `json:"book_count"` // Books naming the author
+}
+
+// Validate checks the profile and trims its fields.
+func (a *AuthorProfile) Validate() error {
+	a.Name = strings.Join(strings.Fields(a.Name), " ")
+	if a.Name == "" {
+		return &ValidationError{"author name is required"}
+	}
+	if !a.Gender.IsValid() {
+		return &ValidationError{"invalid gender provided, must be 'woman', 'man', 'nonbinary' or empty"}
+	}
+	if a.Nationality != nil {
+		nationality := strings.TrimSpace(*a.Nationality)
+		a.Nationality = &nationality
+		if nationality == "" {
+			a.Nationality = nil
+		}
+	}
+	return nil
+}
+
+// AuthorNames splits a book's author field, such as "Neil Gaiman, Terry
+// Pratchett", into the names of its authors.
+func AuthorNames(author string) []string {
+	var names []string
+	for _, name := range strings.Split(author, ",") {
+		if name = strings.Join(strings.Fields(name), " "); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// StatCount is the number of books in one bucket of a stat, and their share
+// of all the books counted.
+type StatCount struct {
+	Value   string  `json:"value"`
+	Books   int     `json:"books"`
+	Percent float64 `json:"percent"`
+}
+
+// DiversityStats describes the authors of the leisure books finished in a
+// year. A book counts once in every bucket one of its authors falls in, so
+// co-written books can make the buckets add up to more than Books. Authors
+// without a profile count as unknown.
+type DiversityStats struct {
+	Year        int         `json:"year"`
+	Books       int         `json:"books"`
+	Gender      []StatCount `json:"gender"`      // Every gender, then "unknown"
+	Nationality []StatCount `json:"nationality"` // Most read first, then "unknown"
+	Translated  StatCount   `json:"translated"`  // Books read in translation
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // BookStatus represents the reading status of a book.
 type BookStatus string
 
@@ -37,24 +39,115 @@ func (t BookType) IsValid() bool {
 	}
 }
 
+// ReadingMode distinguishes leisure reading from reference material (e.g., textbooks).
+type ReadingMode string
+
+const (
+	ModeLeisure   ReadingMode = "leisure"
+	ModeReference ReadingMode = "reference"
+)
+
+// IsValid checks if the reading mode is one of the predefined valid modes.
+func (m ReadingMode) IsValid() bool {
+	switch m {
+	case ModeLeisure, ModeReference:
+		return true
+	default:
+		return false
+	}
+}
+
+// BookSource records how a book came to be on the bookshelf.
+type BookSource string
+
+const (
+	BookSourceManual     BookSource = "manual"           // Added by hand in the web UI
+	BookSourceAPI        BookSource = "api"              // Added through the API by another client
+	BookSourceISBNScan   BookSource = "isbn_scan"        // Added by scanning a barcode
+	BookSourceGoodreads  BookSource = "goodreads_import" // Imported from Goodreads
+	BookSourceListImport BookSource = "list_import"      // Imported from a shared list file
+)
+
+// IsValid checks if the source is one of the predefined book sources.
+func (s BookSource) IsValid() bool {
+	switch s {
+	case BookSourceManual, BookSourceAPI, BookSourceISBNScan, BookSourceGoodreads, BookSourceListImport:
+		return true
+	default:
+		return false
+	}
+}
+
 // Book represents a book entry in the bookshelf.
 type Book struct {
-	ID            int64      `json:"id"`
-	Title         string     `json:"title"`
-	Author        string     `json:"author"`
-	OpenLibraryID string     `json:"open_library_id"` // e.g., OL7353617M
-	ISBN          string     `json:"isbn,omitempty"`  // Optional, but useful
-	Status        BookStatus `json:"status"`
-	Type          BookType   `json:"type"`            // "book" or "audiobook"
-	Rating        *int       `json:"rating,omitempty"`   // Pointer to allow null, 1-10
-	Comments      *string    `json:"comments,omitempty"` // Pointer to allow null
-	CoverURL      *string    `json:"cover_url,omitempty"` // URL for the book cover image
-	Series        *string    `json:"series,omitempty"`    // Name of the series (optional)
-	SeriesIndex   *int       `json:"series_index,omitempty"` // Position in the series (optional)
+	ID              int64       `json:"id"`
+	UserID          *int64      `json:"-"` // Owner of the book; nil for books from before accounts
+	Title           string      `json:"title"`
+	Subtitle        *string     `json:"subtitle,omitempty"` // Part of the title after the colon, e.g. "A Novel"
+	Author          string      `json:"author"`
+	OpenLibraryID   string      `json:"open_library_id"` // e.g., OL7353617M
+	ISBN            string      `json:"isbn,omitempty"`  // Optional, but useful
+	Status          BookStatus  `json:"status"`
+	Type            BookType    `json:"type"`                       // "book" or "audiobook"
+	Rating          *int        `json:"rating,omitempty"`           // Pointer to allow null, 1-10
+	Comments        *string     `json:"comments,omitempty"`         // Pointer to allow null
+	Description     *string     `json:"description,omitempty"`      // Publisher's description, cleaned to Markdown
+	CoverURL        *string     `json:"cover_url,omitempty"`        // URL for the book cover image
+	CoverHash       *string     `json:"cover_hash,omitempty"`       // Cached copy of the cover, served from /api/covers/{hash}
+	CoverBlurhash   string      `json:"cover_blurhash,omitempty"`   // Placeholder for the cached cover, see https://blurha.sh
+	CoverLQIP       string      `json:"cover_lqip,omitempty"`       // Tiny inline placeholder for the cached cover (data: URI)
+	Series          *string     `json:"series,omitempty"`           // Name of the series (optional)
+	SeriesIndex     *int        `json:"series_index,omitempty"`     // Position in the series (optional)
+	PublishYear     *int        `json:"publish_year,omitempty"`     // Year of first publication, used for matching imports
+	Edition         *int        `json:"edition,omitempty"`          // Edition number, mostly for textbooks
+	PageCount       *int        `json:"page_count,omitempty"`       // Number of pages, used for length stats
+	Publisher       *string     `json:"publisher,omitempty"`        // Publisher of the edition, e.g. "Ace Books"
+	Language        *string     `json:"language,omitempty"`         // Language of the edition as a lowercase code, e.g. "en" or "eng"
+	CourseCode      *string     `json:"course_code,omitempty"`      // e.g., "CS 101"
+	Semester        *string     `json:"semester,omitempty"`         // e.g., "Fall 2025"
+	ReadingMode     ReadingMode `json:"reading_mode"`               // "leisure" or "reference"; reference books are excluded from reading stats
+	PublishOptOut   bool        `json:"publish_opt_out"`            // Never publish activity about this book to the fediverse
+	CommentsSpoiler bool        `json:"comments_spoiler"`           // Comments contain spoilers and must be hidden behind a content warning
+	Translated      bool        `json:"translated"`                 // Read in translation; only used for diversity stats
+	Favorite        bool        `json:"favorite"`                   // Pinned as a favorite, which is independent of the rating
+	Label           *string     `json:"label,omitempty"`            // An emoji or #rrggbb color for grouping books at a glance
+	Archived        bool        `json:"archived"`                   // No longer owned, e.g. sold or given away; hidden from lists and stats, but its history is kept
+	NoteCount       int         `json:"note_count"`                 // Notes and highlights taken on the book, see Note
+	LentOut         bool        `json:"lent_out"`                   // Lent to someone and not yet returned, see Loan
+	CurrentPage     *int        `json:"current_page,omitempty"`     // Page reached in the current read, see ProgressUpdate
+	ProgressPercent *float64    `json:"progress_percent,omitempty"` // How far through the current read, from 0 to 100
+	UpdatedAt       *time.Time  `json:"updated_at,omitempty"`       // Last time the book was added or changed; nil for unset legacy rows
+	DateStarted     *time.Time  `json:"date_started,omitempty"`     // When the current or latest read began
+	DateFinished    *time.Time  `json:"date_finished,omitempty"`    // When the latest read ended; nil while reading
+	// SourceRating is the rating as last imported from a tracker, on the
+	// tracker's own scale; Rating holds it mapped to 1-10.
+	SourceRating *SourceRating `json:"source_rating,omitempty"`
+	// Source and AddedAt record where and when the book was added; both are
+	// unset for books added before they were tracked.
+	Source  BookSource `json:"source,omitempty"`
+	AddedAt *time.Time `json:"added_at,omitempty"`
+	// ImportBatchID is the import that added the book, if any.
+	ImportBatchID *int64 `json:"import_batch_id,omitempty"`
+	// DeletedAt is when the book was moved to the trash; nil for books on the bookshelf.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// StudyInfo groups the textbook-related fields of a book so they can be updated together.
+type StudyInfo struct {
+	Edition     *int        `json:"edition"`
+	CourseCode  *string     `json:"course_code"`
+	Semester    *string     `json:"semester"`
+	ReadingMode ReadingMode `json:"reading_mode"`
+}
+
+// SharingSettings groups the per-book publishing preferences.
+type SharingSettings struct {
+	PublishOptOut   bool `json:"publish_opt_out"`
+	CommentsSpoiler bool `json:"comments_spoiler"`
 }
 
 // Validate checks the book data for validity.
-// Checks Rating range, Status and Type values.
+// Checks Rating range, Status, Type and ReadingMode values.
 func (b *Book) Validate() error {
 	if b.Rating != nil && (*b.Rating < 1 || *b.Rating > 10) {
 		// Consider using a custom error type or fmt.Errorf
@@ -69,6 +162,37 @@ func (b *Book) Validate() error {
 	} else if !b.Type.IsValid() {
 		return &ValidationError{"invalid type provided, must be 'book' or 'audiobook'"}
 	}
+	if b.ReadingMode == "" {
+		b.ReadingMode = ModeLeisure
+	} else if !b.ReadingMode.IsValid() {
+		return &ValidationError{"invalid reading mode provided, must be 'leisure' or 'reference'"}
+	}
+	if b.Source != "" && !b.Source.IsValid() {
+		return &ValidationError{"invalid source provided, must be 'manual', 'api', 'isbn_scan', 'goodreads_import' or 'list_import'"}
+	}
+	if b.Edition != nil && *b.Edition <= 0 {
+		return &ValidationError{"edition must be greater than 0"}
+	}
+	if b.PageCount != nil && *b.PageCount <= 0 {
+		return &ValidationError{"page_count must be greater than 0"}
+	}
+	b.Publisher, b.Language = NormalizePublisher(b.Publisher), NormalizeLanguage(b.Language)
+	if err := ValidatePublication(b.Publisher, b.Language); err != nil {
+		return err
+	}
+	if b.Label != nil {
+		label := NormalizeLabel(*b.Label)
+		if label == "" {
+			b.Label = nil
+		} else if err := ValidateLabel(label); err != nil {
+			return err
+		} else {
+			b.Label = &label
+		}
+	}
+	if b.DateStarted != nil && b.DateFinished != nil && b.DateStarted.After(*b.DateFinished) {
+		return &ValidationError{"date_started must not be after date_finished"}
+	}
 	// Add other validations as needed (e.g., Title required)
 	return nil
 }
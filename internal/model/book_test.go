@@ -46,6 +46,27 @@ func TestBookStatus_IsValid(t *testing.T) {
 	}
 }
 
+func TestReadingMode_IsValid(t *testing.T) {
+	tests := []struct {
+		name string
+		mode ReadingMode
+		want bool
+	}{
+		{name: "Leisure mode", mode: ModeLeisure, want: true},
+		{name: "Reference mode", mode: ModeReference, want: true},
+		{name: "Invalid mode", mode: "skimming", want: false},
+		{name: "Empty mode", mode: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mode.IsValid(); got != tt.want {
+				t.Errorf("ReadingMode.IsValid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestBook_Validate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -114,6 +135,28 @@ func TestBook_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "invalid status provided",
 		},
+		{
+			name: "Book with invalid reading mode",
+			book: Book{
+				Title:       "Test Book",
+				Author:      "Test Author",
+				Status:      StatusRead,
+				ReadingMode: "skimming",
+			},
+			wantErr: true,
+			errMsg:  "invalid reading mode provided, must be 'leisure' or 'reference'",
+		},
+		{
+			name: "Book with invalid edition",
+			book: Book{
+				Title:   "Test Book",
+				Author:  "Test Author",
+				Status:  StatusRead,
+				Edition: intPtr(0),
+			},
+			wantErr: true,
+			errMsg:  "edition must be greater than 0",
+		},
 	}
 
 	for _, tt := range tests {
@@ -163,4 +206,37 @@ func TestValidationError_Error(t *testing.T) {
 // Helper function to get pointer to int
 func intPtr(i int) *int {
 	return &i
-}
\ No newline at end of file
+}
+
+func TestSplitTitle(t *testing.T) {
+	tests := []struct {
+		name         string
+		in           string
+		wantTitle    string
+		wantSubtitle string
+	}{
+		{name: "Subtitle", in: "Dune: Deluxe Edition", wantTitle: "Dune", wantSubtitle: "Deluxe Edition"},
+		{name: "Only the first colon splits", in: "Sapiens: A Brief History: Illustrated", wantTitle: "Sapiens", wantSubtitle: "A Brief History: Illustrated"},
+		{name: "No subtitle", in: "Dune Messiah", wantTitle: "Dune Messiah"},
+		{name: "Colon without a space", in: "10:04", wantTitle: "10:04"},
+		{name: "Nothing after the colon", in: "Dune: ", wantTitle: "Dune:"},
+		{name: "Surrounding space", in: "  Dune :  Deluxe ", wantTitle: "Dune", wantSubtitle: "Deluxe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, subtitle := SplitTitle(tt.in)
+			if title != tt.wantTitle || subtitle != tt.wantSubtitle {
+				t.Errorf("SplitTitle(%q) = %q, %q, want %q, %q", tt.in, title, subtitle, tt.wantTitle, tt.wantSubtitle)
+			}
+		})
+	}
+
+	book := Book{Title: "Dune: Deluxe Edition"}
+	if !book.SplitSubtitle() || book.Title != "Dune" || book.FullTitle() != "Dune: Deluxe Edition" {
+		t.Errorf("Unexpected split book %q / %v", book.Title, book.Subtitle)
+	}
+	if book.SplitSubtitle() {
+		t.Error("Expected a book with a subtitle to be left alone")
+	}
+}
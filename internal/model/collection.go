@@ -0,0 +1,49 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxCollectionNameLength is the longest collection name accepted, in
+// characters, and MaxCollectionDescriptionLength the longest description.
+const (
+	MaxCollectionNameLength        = 100
+	MaxCollectionDescriptionLength = 1000
+)
+
+// Collection is a user-defined shelf, such as "2025 TBR" or "Beach reads",
+// holding any number of books whatever their status. Names are unique per
+// user.
+type Collection struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description"`
+	BookCount   int       `json:"book_count"` // Number of books in the collection, not counting the trash
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Validate trims the collection's name and description and checks them. An
+// empty description is cleared.
+func (c *Collection) Validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return &ValidationError{"name is required"}
+	}
+	if utf8.RuneCountInString(c.Name) > MaxCollectionNameLength {
+		return &ValidationError{"name must be at most 100 characters"}
+	}
+	if c.Description != nil {
+		description := strings.TrimSpace(*c.Description)
+		if utf8.RuneCountInString(description) > MaxCollectionDescriptionLength {
+			return &ValidationError{"description must be at most 1000 characters"}
+		}
+		c.Description = &description
+		if description == "" {
+			c.Description = nil
+		}
+	}
+	return nil
+}
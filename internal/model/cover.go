@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// CoverImage is a cover stored in the local cover cache. Images are keyed by
+// the SHA-256 of their content, so books sharing a cover share one file.
+type CoverImage struct {
+	Hash        string    `json:"hash"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width,omitempty"`    // 0 when the image could not be decoded
+	Height      int       `json:"height,omitempty"`   // 0 when the image could not be decoded
+	Blurhash    string    `json:"blurhash,omitempty"` // Placeholder, see https://blurha.sh
+	LQIP        string    `json:"lqip,omitempty"`     // Tiny inline placeholder as a data: URI
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CoverCacheStats describes how much the cover cache holds.
+type CoverCacheStats struct {
+	Images     int   `json:"images"`     // Distinct images stored
+	Bytes      int64 `json:"bytes"`      // Total size of the stored images
+	References int   `json:"references"` // Books pointing at a cached image
+}
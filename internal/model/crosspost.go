@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// CrosspostProvider identifies a social network that finished books can be cross-posted to.
+type CrosspostProvider string
+
+const (
+	ProviderMastodon CrosspostProvider = "mastodon"
+	ProviderBluesky  CrosspostProvider = "bluesky"
+)
+
+// IsValid checks if the provider is one of the supported cross-posting providers.
+func (p CrosspostProvider) IsValid() bool {
+	switch p {
+	case ProviderMastodon, ProviderBluesky:
+		return true
+	default:
+		return false
+	}
+}
+
+// DefaultCrosspostTemplate is used when an account has no custom template.
+const DefaultCrosspostTemplate = `Finished reading "{{.Title}}" by {{.Author}}{{if .Stars}} {{.Stars}}{{end}}`
+
+// CrosspostAccount is a configured cross-posting destination.
+// The credential is stored encrypted and never serialized.
+type CrosspostAccount struct {
+	ID             int64             `json:"id"`
+	Provider       CrosspostProvider `json:"provider"`
+	InstanceURL    string            `json:"instance_url"` // e.g., https://mastodon.social or https://bsky.social
+	Handle         string            `json:"handle"`       // Account handle; used as the Bluesky login identifier
+	Template       string            `json:"template"`     // text/template for the post body
+	Enabled        bool              `json:"enabled"`
+	CreatedAt      time.Time         `json:"created_at"`
+	EncryptedToken string            `json:"-"`
+}
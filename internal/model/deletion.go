@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// BulkDeletion is a set of books moved to the trash together by a delete
+// by filter, such as a bad import or every book added before some year.
+// Undoing it restores those still in the trash as one.
+type BulkDeletion struct {
+	ID        int64      `json:"id"`
+	Filter    string     `json:"filter"` // The query that selected the books, e.g. "added_before=2020-01-01"
+	CreatedAt time.Time  `json:"created_at"`
+	UndoneAt  *time.Time `json:"undone_at,omitempty"`
+	BookCount int        `json:"book_count"`         // Books the deletion moved to the trash
+	InTrash   int        `json:"in_trash"`           // Of those, the books still in the trash, which undoing restores
+	Restored  int        `json:"restored,omitempty"` // The books an undo just restored
+}
+
+// DeletedBook is a book selected by a bulk deletion.
+type DeletedBook struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+// DeletionPreview is what a delete by filter would move to the trash. Its
+// token confirms the deletion, and only while the same books, unchanged,
+// still match the filter.
+type DeletionPreview struct {
+	Count int           `json:"count"`
+	Books []DeletedBook `json:"books"`
+	Token string        `json:"token"`
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxDisposalTextLength is the longest recipient or reason accepted, in
+// characters.
+const MaxDisposalTextLength = 500
+
+// DisposalKind is how a book left the collection for good.
+type DisposalKind string
+
+const (
+	DisposalGifted    DisposalKind = "gifted"
+	DisposalDonated   DisposalKind = "donated"
+	DisposalSold      DisposalKind = "sold"
+	DisposalDiscarded DisposalKind = "discarded"
+	DisposalLost      DisposalKind = "lost"
+)
+
+// DisposalKinds lists every disposal kind, in the order reports show them.
+var DisposalKinds = []DisposalKind{DisposalGifted, DisposalDonated, DisposalSold, DisposalDiscarded, DisposalLost}
+
+// IsValid reports whether k is a known disposal kind.
+func (k DisposalKind) IsValid() bool {
+	for _, kind := range DisposalKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Disposal records a book leaving the collection: given to whom, when and
+// why. The book's title and author are kept with it, so the ledger still
+// reads right once the book itself is purged.
+type Disposal struct {
+	ID         int64        `json:"id"`
+	BookID     *int64       `json:"book_id"` // nil once the book is purged
+	Title      string       `json:"title"`
+	Author     string       `json:"author"`
+	Kind       DisposalKind `json:"kind"`
+	Recipient  *string      `json:"recipient,omitempty"`
+	Reason     *string      `json:"reason,omitempty"`
+	DisposedOn string       `json:"disposed_on"` // YYYY-MM-DD
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// Validate checks the disposal's kind and date, defaulting the date to today
+// in UTC, and trims its recipient and reason. Empty ones are cleared.
+func (d *Disposal) Validate() error {
+	if !d.Kind.IsValid() {
+		return &ValidationError{"kind must be one of 'gifted', 'donated', 'sold', 'discarded' or 'lost'"}
+	}
+	if d.DisposedOn == "" {
+		d.DisposedOn = time.Now().UTC().Format(DateLayout)
+	}
+	if _, err := time.Parse(DateLayout, d.DisposedOn); err != nil {
+		return &ValidationError{"disposed_on must be a date formatted as YYYY-MM-DD"}
+	}
+	for _, field := range []struct {
+		name  string
+		value **string
+	}{{"recipient", &d.Recipient}, {"reason", &d.Reason}} {
+		if *field.value == nil {
+			continue
+		}
+		text := strings.TrimSpace(**field.value)
+		switch {
+		case text == "":
+			*field.value = nil
+		case utf8.RuneCountInString(text) > MaxDisposalTextLength:
+			return &ValidationError{field.name + " must be at most 500 characters"}
+		default:
+			*field.value = &text
+		}
+	}
+	return nil
+}
+
+// DisposalReport sums up the books that left the collection in a year, or
+// ever: how many, how, and to whom.
+type DisposalReport struct {
+	Year       *int        `json:"year,omitempty"`
+	Total      int         `json:"total"`
+	Kinds      []StatCount `json:"kinds"`      // Every kind, in the order of DisposalKinds
+	Recipients []StatCount `json:"recipients"` // Most books first
+	Disposals  []Disposal  `json:"disposals"`  // Newest first
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// BookEventKind describes what happened to a book in its history.
+type BookEventKind string
+
+const (
+	EventCreated  BookEventKind = "created"
+	EventUpdated  BookEventKind = "updated"
+	EventDeleted  BookEventKind = "deleted"  // Moved to the trash
+	EventRestored BookEventKind = "restored" // Taken out of the trash
+	EventPurged   BookEventKind = "purged"   // Deleted for good
+	EventMerged   BookEventKind = "merged"   // Took in, or was merged into, RelatedBookID
+	EventUnmerged BookEventKind = "unmerged" // A merge with RelatedBookID was undone
+)
+
+// FieldChange is one field of a book changed by an event. From and To are
+// the field's JSON values, null when unset.
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
+
+// BookEvent is an entry in the audit log of a book: who changed it, when and
+// how. Events are kept after the book is purged.
+type BookEvent struct {
+	ID            int64         `json:"id"`
+	BookID        int64         `json:"book_id"`
+	Kind          BookEventKind `json:"kind"`
+	UserID        *int64        `json:"user_id,omitempty"`  // Who made the change; nil before accounts
+	Username      string        `json:"username,omitempty"` // Of UserID
+	RelatedBookID *int64        `json:"related_book_id,omitempty"`
+	Changes       []FieldChange `json:"changes,omitempty"` // Field by field, for updates and merges
+	OccurredAt    time.Time     `json:"occurred_at"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// BookTombstone records a deleted book so differential exports can report it.
+type BookTombstone struct {
+	BookID        int64     `json:"id"`
+	OpenLibraryID string    `json:"open_library_id"`
+	Title         string    `json:"title"`
+	DeletedAt     time.Time `json:"deleted_at"`
+}
+
+// ExportRun records an export so later differential exports can start from it.
+type ExportRun struct {
+	ID         int64      `json:"id"`
+	Format     string     `json:"format"`
+	Since      *time.Time `json:"since,omitempty"` // Set for differential exports
+	ExportedAt time.Time  `json:"exported_at"`
+	Books      int        `json:"books"`   // Number of book records written
+	Deleted    int        `json:"deleted"` // Number of tombstones written
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// MaxGoalTarget is the most books a reading goal may aim for.
+const MaxGoalTarget = 10000
+
+// ReadingGoal is the number of books to finish in a year, as in a reading
+// challenge. Re-reads count, being books finished too.
+type ReadingGoal struct {
+	Year      int       `json:"year"`
+	Target    int       `json:"target"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks the goal's year and target.
+func (g ReadingGoal) Validate() error {
+	if g.Year < 1 || g.Year > 9999 {
+		return &ValidationError{"year must be between 1 and 9999"}
+	}
+	if g.Target < 1 || g.Target > MaxGoalTarget {
+		return &ValidationError{"target must be between 1 and 10000"}
+	}
+	return nil
+}
+
+// GoalProgress is how far a reading goal has come, and whether it is on pace
+// to be met by the end of the year.
+type GoalProgress struct {
+	ReadingGoal
+	Finished  int     `json:"finished"`  // Books finished in the year
+	Remaining int     `json:"remaining"` // Books still to finish; 0 once the goal is met
+	Percent   float64 `json:"percent"`   // Finished share of the target, up to 100
+	Expected  float64 `json:"expected"`  // Books that should be finished by now to stay on pace
+	OnTrack   bool    `json:"on_track"`
+	Behind    int     `json:"behind"`    // Books short of the pace; 0 when on track
+	DaysLeft  int     `json:"days_left"` // Days of the year after today, vacation days left out
+	Completed bool    `json:"completed"` // The goal has been met
+}
+
+// Progress returns the goal's progress with finished books as of now. The
+// pace leaves the vacation days out, so a break doesn't put a goal behind.
+func (g ReadingGoal) Progress(finished int, vacations []Vacation, now time.Time) GoalProgress {
+	p := GoalProgress{ReadingGoal: g, Finished: finished, Completed: finished >= g.Target}
+	if !p.Completed {
+		p.Remaining = g.Target - finished
+	}
+	p.Percent = math.Min(math.Round(float64(finished)*1000/float64(g.Target))/10, 100)
+
+	start := time.Date(g.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	// Today counts as elapsed once it is over, so the pace doesn't jump at midnight
+	today := time.Date(now.UTC().Year(), now.UTC().Month(), now.UTC().Day(), 0, 0, 0, 0, time.UTC)
+	elapsedTo := today
+	if elapsedTo.Before(start) {
+		elapsedTo = start
+	} else if elapsedTo.After(end) {
+		elapsedTo = end
+	}
+	days := int(end.Sub(start).Hours()/24) - VacationDays(vacations, start, end)
+	elapsed := int(elapsedTo.Sub(start).Hours()/24) - VacationDays(vacations, start, elapsedTo)
+	if today.Before(end) {
+		from := today.AddDate(0, 0, 1)
+		if from.Before(start) {
+			from = start
+		}
+		p.DaysLeft = int(end.Sub(from).Hours()/24) - VacationDays(vacations, from, end)
+	}
+	if days > 0 {
+		p.Expected = math.Round(float64(g.Target)*float64(elapsed)*10/float64(days)) / 10
+	} else if !today.Before(end) {
+		p.Expected = float64(g.Target)
+	}
+	behind := int(math.Floor(p.Expected)) - finished
+	p.OnTrack = behind <= 0 || p.Completed
+	if !p.OnTrack {
+		p.Behind = behind
+	}
+	return p
+}
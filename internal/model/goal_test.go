@@ -0,0 +1,32 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGoalProgress(t *testing.T) {
+	goal := ReadingGoal{Year: 2025, Target: 52}
+	july := time.Date(2025, 7, 2, 15, 0, 0, 0, time.UTC)
+	june := []Vacation{{StartDate: "2025-06-01", EndDate: "2025-06-30"}}
+	for _, tt := range []struct {
+		name      string
+		finished  int
+		vacations []Vacation
+		now       time.Time
+		want      GoalProgress
+	}{
+		{"behind", 20, nil, july, GoalProgress{Finished: 20, Remaining: 32, Percent: 38.5, Expected: 25.9, Behind: 5, DaysLeft: 182}},
+		{"vacation", 20, june, july, GoalProgress{Finished: 20, Remaining: 32, Percent: 38.5, Expected: 23.6, Behind: 3, DaysLeft: 182}},
+		{"ahead", 30, nil, july, GoalProgress{Finished: 30, Remaining: 22, Percent: 57.7, Expected: 25.9, OnTrack: true, DaysLeft: 182}},
+		{"met", 60, nil, time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), GoalProgress{Finished: 60, Percent: 100, Expected: 52, OnTrack: true, Completed: true}},
+		{"missed", 40, nil, time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), GoalProgress{Finished: 40, Remaining: 12, Percent: 76.9, Expected: 52, Behind: 12}},
+		{"ahead of the year", 0, nil, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), GoalProgress{Remaining: 52, OnTrack: true, DaysLeft: 365}},
+	} {
+		got := goal.Progress(tt.finished, tt.vacations, tt.now)
+		tt.want.ReadingGoal = goal
+		if got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
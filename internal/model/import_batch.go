@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// ImportBatch is one import, such as a batch of books from a Goodreads
+// export or a shared list, whose books can be rolled back together.
+type ImportBatch struct {
+	ID           int64      `json:"id"`
+	Source       BookSource `json:"source"`
+	Name         string     `json:"name,omitempty"` // e.g. the name of an imported list
+	CreatedAt    time.Time  `json:"created_at"`
+	RolledBackAt *time.Time `json:"rolled_back_at,omitempty"`
+	BookCount    int        `json:"book_count"` // Books of the batch still on the bookshelf
+}
+
+// RolledBackBook is a book considered by a rollback.
+type RolledBackBook struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+// ImportRollback is the outcome of rolling back an import batch. Books
+// changed since the import are kept unless the rollback is forced, since
+// deleting them would throw the changes away.
+type ImportRollback struct {
+	Batch   ImportBatch      `json:"batch"`
+	Deleted []RolledBackBook `json:"deleted"`
+	Kept    []RolledBackBook `json:"kept"` // Changed, tagged or read since the import
+}
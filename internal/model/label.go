@@ -0,0 +1,59 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MaxLabelLength is the longest emoji label accepted, in bytes. It leaves
+// room for emoji made of several code points, such as flags and families.
+const MaxLabelLength = 32
+
+// labelColor matches a label that is a color, once normalized.
+var labelColor = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// NormalizeLabel trims a book label and lowercases a color, expanding the
+// short form, so " #FA0" and "#ffaa00" are the same label.
+func NormalizeLabel(label string) string {
+	label = strings.TrimSpace(label)
+	if !strings.HasPrefix(label, "#") {
+		return label
+	}
+	label = strings.ToLower(label)
+	if len(label) == 4 {
+		label = string([]byte{'#', label[1], label[1], label[2], label[2], label[3], label[3]})
+	}
+	return label
+}
+
+// ValidateLabel checks a normalized book label. A label is either a color,
+// as #rrggbb, or a single emoji; it is distinct from tags in that a book has
+// at most one, for telling books apart at a glance.
+func ValidateLabel(label string) error {
+	if strings.HasPrefix(label, "#") {
+		if !labelColor.MatchString(label) {
+			return &ValidationError{"label color must be given as #rrggbb"}
+		}
+		return nil
+	}
+	if len(label) > MaxLabelLength {
+		return &ValidationError{"label emoji is too long"}
+	}
+	invalid := &ValidationError{"label must be an emoji or a color (#rrggbb)"}
+	symbol := false
+	for _, r := range label {
+		switch {
+		case unicode.Is(unicode.So, r):
+			symbol = true
+		case unicode.In(r, unicode.Sk, unicode.Mn, unicode.Me, unicode.Cf):
+			// Skin tones, variation selectors, keycaps and joiners
+		default:
+			return invalid
+		}
+	}
+	if !symbol {
+		return invalid
+	}
+	return nil
+}
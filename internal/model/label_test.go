@@ -0,0 +1,36 @@
+package model
+
+import "testing"
+
+func TestValidateLabel(t *testing.T) {
+	tests := []struct {
+		label string
+		want  string // Normalized, or "" if invalid
+	}{
+		{" #FA0 ", "#ffaa00"},
+		{"#1E90FF", "#1e90ff"},
+		{"📚", "📚"},
+		{"❤️", "❤️"},
+		{"👍🏽", "👍🏽"},
+		{"🇫🇷", "🇫🇷"},
+		{"👩‍🚀", "👩‍🚀"},
+		{"#blue", ""},
+		{"#12345", ""},
+		{"red", ""},
+		{"📚 sci-fi", ""},
+		{"‍", ""},
+	}
+	for _, tt := range tests {
+		label := NormalizeLabel(tt.label)
+		err := ValidateLabel(label)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("Expected %q to be an invalid label, got %q", tt.label, label)
+			}
+			continue
+		}
+		if err != nil || label != tt.want {
+			t.Errorf("NormalizeLabel(%q) = %q (%v), want %q", tt.label, label, err, tt.want)
+		}
+	}
+}
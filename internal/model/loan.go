@@ -0,0 +1,65 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxBorrowerLength is the longest borrower name accepted, in characters.
+const MaxBorrowerLength = 200
+
+// Loan is a physical book lent to someone: to whom, when, and when it is due
+// back. A book has at most one outstanding loan, the one not yet returned.
+type Loan struct {
+	ID         int64     `json:"id"`
+	BookID     int64     `json:"book_id"`
+	Borrower   string    `json:"borrower"`
+	LentOn     string    `json:"lent_on"`               // YYYY-MM-DD
+	DueOn      *string   `json:"due_on,omitempty"`      // YYYY-MM-DD
+	ReturnedOn *string   `json:"returned_on,omitempty"` // YYYY-MM-DD; nil while lent out
+	CreatedAt  time.Time `json:"created_at"`
+	// Overdue is set on outstanding loans past their due date.
+	Overdue bool `json:"overdue"`
+}
+
+// Validate trims the borrower and checks the loan's dates, defaulting the
+// day it was lent to today in UTC. The due date must not be before it.
+func (l *Loan) Validate() error {
+	l.Borrower = strings.TrimSpace(l.Borrower)
+	if l.Borrower == "" {
+		return &ValidationError{"borrower is required"}
+	}
+	if utf8.RuneCountInString(l.Borrower) > MaxBorrowerLength {
+		return &ValidationError{"borrower must be at most 200 characters"}
+	}
+	if l.LentOn == "" {
+		l.LentOn = time.Now().UTC().Format(DateLayout)
+	}
+	if _, err := time.Parse(DateLayout, l.LentOn); err != nil {
+		return &ValidationError{"lent_on must be a date formatted as YYYY-MM-DD"}
+	}
+	if l.DueOn != nil {
+		if _, err := time.Parse(DateLayout, *l.DueOn); err != nil {
+			return &ValidationError{"due_on must be a date formatted as YYYY-MM-DD"}
+		}
+		if *l.DueOn < l.LentOn {
+			return &ValidationError{"due_on must not be before lent_on"}
+		}
+	}
+	return nil
+}
+
+// IsOverdue reports whether the loan is still outstanding after its due
+// date, as of today in UTC.
+func (l Loan) IsOverdue(today time.Time) bool {
+	return l.ReturnedOn == nil && l.DueOn != nil && *l.DueOn < today.UTC().Format(DateLayout)
+}
+
+// OutstandingLoan is a book currently lent out, with the title and author of
+// the book.
+type OutstandingLoan struct {
+	Loan
+	Title  string `json:"title"`
+	Author string `json:"author"`
+}
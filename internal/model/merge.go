@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// BookMerge records a duplicate merged into a book, with both records as
+// they were before the merge, so a wrong merge can be undone.
+type BookMerge struct {
+	ID          int64      `json:"id"`
+	BookID      int64      `json:"book_id"`      // The book kept
+	DuplicateID int64      `json:"duplicate_id"` // The book merged into it and deleted
+	Before      Book       `json:"before"`       // The kept book before the merge
+	Duplicate   Book       `json:"duplicate"`
+	Filled      []string   `json:"filled"` // Fields of the kept book taken from the duplicate, by JSON name
+	MergedAt    time.Time  `json:"merged_at"`
+	UnmergedAt  *time.Time `json:"unmerged_at,omitempty"`
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxNoteLength is the longest note accepted, in characters.
+const MaxNoteLength = 10000
+
+// MaxNoteLocationLength is the longest note location accepted, in characters.
+const MaxNoteLocationLength = 100
+
+// NoteKind tells a reader's own notes from passages highlighted in the book.
+type NoteKind string
+
+const (
+	NoteKindNote      NoteKind = "note"
+	NoteKindHighlight NoteKind = "highlight" // The body quotes the book
+)
+
+// IsValid reports whether k is a known note kind.
+func (k NoteKind) IsValid() bool {
+	return k == NoteKindNote || k == NoteKindHighlight
+}
+
+// Note is a timestamped note or highlight taken on a book, one of any number.
+// Where it applies in the book is optional: a page, or a location for books
+// without fixed pages, such as "Loc 1234" or "Chapter 3".
+type Note struct {
+	ID        int64     `json:"id"`
+	BookID    int64     `json:"book_id"`
+	Kind      NoteKind  `json:"kind"`
+	Body      string    `json:"body"` // Markdown
+	Page      *int      `json:"page,omitempty"`
+	Location  *string   `json:"location,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate trims the note and checks it has a body, a valid kind (defaulting
+// to a note) and a valid place in the book. An empty location is cleared.
+func (n *Note) Validate() error {
+	n.Body = strings.TrimSpace(n.Body)
+	if n.Body == "" {
+		return &ValidationError{"body is required"}
+	}
+	if utf8.RuneCountInString(n.Body) > MaxNoteLength {
+		return &ValidationError{"body must be at most 10000 characters"}
+	}
+	if n.Kind == "" {
+		n.Kind = NoteKindNote
+	}
+	if !n.Kind.IsValid() {
+		return &ValidationError{"kind must be 'note' or 'highlight'"}
+	}
+	if n.Page != nil && *n.Page <= 0 {
+		return &ValidationError{"page must be a positive number"}
+	}
+	if n.Location != nil {
+		location := strings.TrimSpace(*n.Location)
+		switch {
+		case location == "":
+			n.Location = nil
+		case utf8.RuneCountInString(location) > MaxNoteLocationLength:
+			return &ValidationError{"location must be at most 100 characters"}
+		default:
+			n.Location = &location
+		}
+	}
+	return nil
+}
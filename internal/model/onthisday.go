@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// Memory is something that happened to a book on the same day of an earlier year.
+type Memory struct {
+	Book     Book
+	Date     time.Time // When it happened
+	YearsAgo int
+}
+
+// OnThisDay lists the books finished and added on a day of the year in
+// earlier years, most recent first.
+type OnThisDay struct {
+	Finished []Memory
+	Added    []Memory
+}
@@ -0,0 +1,260 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Optional is one field of a partial update. Set reports whether the field
+// was given at all; a set field with a nil Value clears the stored value. In
+// JSON, an omitted field is unset and null clears it.
+type Optional[T any] struct {
+	Set   bool
+	Value *T
+}
+
+// Some returns a set field holding v.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{Set: true, Value: &v}
+}
+
+// Null returns a set field that clears the stored value.
+func Null[T any]() Optional[T] {
+	return Optional[T]{Set: true}
+}
+
+// Of returns a set field holding *v, or clearing the value if v is nil.
+func Of[T any](v *T) Optional[T] {
+	return Optional[T]{Set: true, Value: v}
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It is only called for fields
+// present in the document.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	o.Value = &v
+	return nil
+}
+
+// BookPatch is a partial update of a book: only the fields that are set are
+// changed. Identifiers, cover caching and reading dates are managed elsewhere.
+type BookPatch struct {
+	Title           Optional[string]      `json:"title"`
+	Subtitle        Optional[string]      `json:"subtitle"`
+	Author          Optional[string]      `json:"author"`
+	ISBN            Optional[string]      `json:"isbn"`
+	Status          Optional[BookStatus]  `json:"status"`
+	Type            Optional[BookType]    `json:"type"`
+	Rating          Optional[int]         `json:"rating"`
+	Comments        Optional[string]      `json:"comments"`
+	Description     Optional[string]      `json:"description"`
+	CoverURL        Optional[string]      `json:"cover_url"`
+	Series          Optional[string]      `json:"series"`
+	SeriesIndex     Optional[int]         `json:"series_index"`
+	PublishYear     Optional[int]         `json:"publish_year"`
+	Edition         Optional[int]         `json:"edition"`
+	PageCount       Optional[int]         `json:"page_count"`
+	Publisher       Optional[string]      `json:"publisher"`
+	Language        Optional[string]      `json:"language"`
+	CourseCode      Optional[string]      `json:"course_code"`
+	Semester        Optional[string]      `json:"semester"`
+	ReadingMode     Optional[ReadingMode] `json:"reading_mode"`
+	PublishOptOut   Optional[bool]        `json:"publish_opt_out"`
+	CommentsSpoiler Optional[bool]        `json:"comments_spoiler"`
+	Translated      Optional[bool]        `json:"translated"`
+	Favorite        Optional[bool]        `json:"favorite"`
+	Label           Optional[string]      `json:"label"`
+	Archived        Optional[bool]        `json:"archived"`
+	// SourceRating is only set by imports, never by clients
+	SourceRating Optional[SourceRating] `json:"-"`
+}
+
+// IsEmpty reports whether the patch changes nothing.
+func (p *BookPatch) IsEmpty() bool {
+	return !(p.Title.Set || p.Subtitle.Set || p.Author.Set || p.ISBN.Set || p.Status.Set || p.Type.Set || p.Rating.Set ||
+		p.Comments.Set || p.Description.Set || p.CoverURL.Set || p.Series.Set || p.SeriesIndex.Set ||
+		p.PublishYear.Set || p.Edition.Set || p.PageCount.Set || p.Publisher.Set || p.Language.Set || p.CourseCode.Set || p.Semester.Set || p.ReadingMode.Set ||
+		p.PublishOptOut.Set || p.CommentsSpoiler.Set || p.Translated.Set || p.Favorite.Set || p.Label.Set || p.Archived.Set || p.SourceRating.Set)
+}
+
+// Validate checks the values the patch sets. Fields that every book has
+// cannot be cleared.
+func (p *BookPatch) Validate() error {
+	for _, f := range []struct {
+		name    string
+		cleared bool
+	}{
+		{"title", p.Title.Set && (p.Title.Value == nil || strings.TrimSpace(*p.Title.Value) == "")},
+		{"author", p.Author.Set && p.Author.Value == nil},
+		{"status", p.Status.Set && p.Status.Value == nil},
+		{"type", p.Type.Set && p.Type.Value == nil},
+		{"reading_mode", p.ReadingMode.Set && p.ReadingMode.Value == nil},
+		{"publish_opt_out", p.PublishOptOut.Set && p.PublishOptOut.Value == nil},
+		{"comments_spoiler", p.CommentsSpoiler.Set && p.CommentsSpoiler.Value == nil},
+		{"translated", p.Translated.Set && p.Translated.Value == nil},
+		{"favorite", p.Favorite.Set && p.Favorite.Value == nil},
+		{"archived", p.Archived.Set && p.Archived.Value == nil},
+	} {
+		if f.cleared {
+			return &ValidationError{f.name + " cannot be cleared"}
+		}
+	}
+	if p.Status.Value != nil && !p.Status.Value.IsValid() {
+		return &ValidationError{"invalid status provided"}
+	}
+	if p.Type.Value != nil && !p.Type.Value.IsValid() {
+		return &ValidationError{"invalid type provided, must be 'book' or 'audiobook'"}
+	}
+	if p.ReadingMode.Value != nil && !p.ReadingMode.Value.IsValid() {
+		return &ValidationError{"invalid reading mode provided, must be 'leisure' or 'reference'"}
+	}
+	if p.Rating.Value != nil && (*p.Rating.Value < 1 || *p.Rating.Value > 10) {
+		return &ValidationError{"rating must be between 1 and 10"}
+	}
+	if p.SeriesIndex.Value != nil && *p.SeriesIndex.Value <= 0 {
+		return &ValidationError{"series_index must be greater than 0"}
+	}
+	if p.Edition.Value != nil && *p.Edition.Value <= 0 {
+		return &ValidationError{"edition must be greater than 0"}
+	}
+	if p.PageCount.Value != nil && *p.PageCount.Value <= 0 {
+		return &ValidationError{"page_count must be greater than 0"}
+	}
+	if err := ValidatePublication(NormalizePublisher(p.Publisher.Value), NormalizeLanguage(p.Language.Value)); err != nil {
+		return err
+	}
+	if p.Label.Value != nil {
+		if label := NormalizeLabel(*p.Label.Value); label != "" {
+			if err := ValidateLabel(label); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Apply changes book by the fields the patch sets, after validating them.
+// A new title with a subtitle in it is split unless the patch sets the
+// subtitle too, and an empty subtitle counts as none. Clearing the series also clears the position in it, an empty series name
+// counts as none, and a position needs a series, set by the patch or already
+// on the book.
+func (p *BookPatch) Apply(book *Book) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if p.Title.Set {
+		book.Title = strings.TrimSpace(*p.Title.Value)
+		if !p.Subtitle.Set {
+			if title, subtitle := SplitTitle(book.Title); subtitle != "" {
+				book.Title, book.Subtitle = title, &subtitle
+			}
+		}
+	}
+	if p.Subtitle.Set {
+		book.Subtitle = p.Subtitle.Value
+		if book.Subtitle != nil && strings.TrimSpace(*book.Subtitle) == "" {
+			book.Subtitle = nil
+		}
+	}
+	if p.Author.Set {
+		book.Author = *p.Author.Value
+	}
+	if p.ISBN.Set {
+		book.ISBN = ""
+		if p.ISBN.Value != nil {
+			book.ISBN = *p.ISBN.Value
+		}
+	}
+	if p.Status.Set {
+		book.Status = *p.Status.Value
+	}
+	if p.Type.Set {
+		book.Type = *p.Type.Value
+	}
+	if p.Rating.Set {
+		book.Rating = p.Rating.Value
+	}
+	if p.Comments.Set {
+		book.Comments = p.Comments.Value
+	}
+	if p.Description.Set {
+		book.Description = p.Description.Value
+	}
+	if p.CoverURL.Set {
+		book.CoverURL = p.CoverURL.Value
+	}
+	if p.Series.Set {
+		book.Series = p.Series.Value
+		if book.Series != nil && *book.Series == "" {
+			book.Series = nil
+		}
+		if book.Series == nil && !p.SeriesIndex.Set {
+			book.SeriesIndex = nil
+		}
+	}
+	if p.SeriesIndex.Set {
+		book.SeriesIndex = p.SeriesIndex.Value
+	}
+	if p.PublishYear.Set {
+		book.PublishYear = p.PublishYear.Value
+	}
+	if p.Edition.Set {
+		book.Edition = p.Edition.Value
+	}
+	if p.PageCount.Set {
+		book.PageCount = p.PageCount.Value
+	}
+	if p.Publisher.Set {
+		book.Publisher = NormalizePublisher(p.Publisher.Value)
+	}
+	if p.Language.Set {
+		book.Language = NormalizeLanguage(p.Language.Value)
+	}
+	if p.CourseCode.Set {
+		book.CourseCode = p.CourseCode.Value
+	}
+	if p.Semester.Set {
+		book.Semester = p.Semester.Value
+	}
+	if p.ReadingMode.Set {
+		book.ReadingMode = *p.ReadingMode.Value
+	}
+	if p.PublishOptOut.Set {
+		book.PublishOptOut = *p.PublishOptOut.Value
+	}
+	if p.CommentsSpoiler.Set {
+		book.CommentsSpoiler = *p.CommentsSpoiler.Value
+	}
+	if p.Translated.Set {
+		book.Translated = *p.Translated.Value
+	}
+	if p.Favorite.Set {
+		book.Favorite = *p.Favorite.Value
+	}
+	if p.Archived.Set {
+		book.Archived = *p.Archived.Value
+	}
+	if p.Label.Set {
+		book.Label = nil
+		if p.Label.Value != nil {
+			if label := NormalizeLabel(*p.Label.Value); label != "" {
+				book.Label = &label
+			}
+		}
+	}
+	if p.SourceRating.Set {
+		book.SourceRating = p.SourceRating.Value
+	}
+	if (p.Series.Set || p.SeriesIndex.Set) && book.SeriesIndex != nil && book.Series == nil {
+		return &ValidationError{"series_index requires a series"}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package model
+
+import "time"
+
+// PendingMatchSource identifies what produced an ambiguous match.
+type PendingMatchSource string
+
+const (
+	SourceListImport  PendingMatchSource = "list_import"
+	SourceTrackerSync PendingMatchSource = "tracker_sync"
+	SourceBackfill    PendingMatchSource = "backfill"   // Provider metadata for a book missing fields
+	SourceShelfScan   PendingMatchSource = "shelf_scan" // A book spine read off a photo of a shelf
+)
+
+// PendingMatchStatus is the review state of a pending match.
+type PendingMatchStatus string
+
+const (
+	MatchPending PendingMatchStatus = "pending"
+	MatchLinked  PendingMatchStatus = "linked"  // Confirmed as an existing book
+	MatchCreated PendingMatchStatus = "created" // Added as a new book
+	MatchSkipped PendingMatchStatus = "skipped" // Dismissed; will not be queued again
+)
+
+// IsValid checks if the status is one of the predefined pending match statuses.
+func (s PendingMatchStatus) IsValid() bool {
+	switch s {
+	case MatchPending, MatchLinked, MatchCreated, MatchSkipped:
+		return true
+	default:
+		return false
+	}
+}
+
+// PendingItem is the incoming book that could not be matched with confidence.
+type PendingItem struct {
+	Title         string     `json:"title"`
+	Author        string     `json:"author,omitempty"`
+	ISBN          string     `json:"isbn,omitempty"`
+	OpenLibraryID string     `json:"open_library_id,omitempty"`
+	Year          *int       `json:"year,omitempty"`
+	CoverURL      *string    `json:"cover_url,omitempty"`
+	PageCount     *int       `json:"page_count,omitempty"`
+	Notes         *string    `json:"notes,omitempty"`
+	Status        BookStatus `json:"status,omitempty"`    // Shelf to use if the item is added as a new book
+	RemoteID      string     `json:"remote_id,omitempty"` // Entry ID on the sync provider
+}
+
+// PendingCandidate is an existing book the item might refer to.
+type PendingCandidate struct {
+	BookID int64   `json:"book_id"`
+	Title  string  `json:"title"`
+	Author string  `json:"author"`
+	Score  float64 `json:"score"`
+}
+
+// PendingMatch is an entry in the manual review queue.
+type PendingMatch struct {
+	ID         int64              `json:"id"`
+	Source     PendingMatchSource `json:"source"`
+	SourceRef  string             `json:"source_ref"` // List name, or the sync account ID
+	ItemKey    string             `json:"-"`          // Identifies the item within the source so it is queued once
+	Item       PendingItem        `json:"item"`
+	Candidates []PendingCandidate `json:"candidates"`
+	Status     PendingMatchStatus `json:"status"`
+	BookID     *int64             `json:"book_id,omitempty"` // Book linked or created on resolution
+	CreatedAt  time.Time          `json:"created_at"`
+	ResolvedAt *time.Time         `json:"resolved_at,omitempty"`
+}
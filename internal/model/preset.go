@@ -0,0 +1,47 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxPresetNameLength is the longest shelf preset name accepted, in characters.
+const MaxPresetNameLength = 100
+
+// ShelfPreset is a named view of the book list: the filters and sort order
+// of GET /api/books and the fields to show. Presets are kept on the server,
+// so every device shows the same views.
+type ShelfPreset struct {
+	ID        int64             `json:"id"`
+	Name      string            `json:"name"`
+	Filters   map[string]string `json:"filters"`         // Book list query parameters, such as {"status": "read"}
+	Sort      string            `json:"sort,omitempty"`  // Book list sort field
+	Order     string            `json:"order,omitempty"` // "asc" or "desc"
+	Fields    []string          `json:"fields"`          // Fields to show; empty shows them all
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Validate trims the preset's name and checks it and the order. The filters,
+// sort field and fields are checked against the book list by the API.
+func (p *ShelfPreset) Validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return &ValidationError{"name is required"}
+	}
+	if utf8.RuneCountInString(p.Name) > MaxPresetNameLength {
+		return &ValidationError{"name must be at most 100 characters"}
+	}
+	p.Order = strings.ToLower(p.Order)
+	if p.Order != "" && p.Order != "asc" && p.Order != "desc" {
+		return &ValidationError{"order must be asc or desc"}
+	}
+	if p.Filters == nil {
+		p.Filters = map[string]string{}
+	}
+	if p.Fields == nil {
+		p.Fields = []string{}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// ProgressUpdate is how far through a book being read the reader is, as
+// either the page reached or a percentage, for books without pages to count,
+// such as ebooks.
+type ProgressUpdate struct {
+	Page    *int     `json:"current_page,omitempty"`
+	Percent *float64 `json:"percent,omitempty"`
+}
+
+// Resolve validates the update against the page count of the book and
+// returns both the page and the percentage. With a page count either is
+// derived from the other; without one the page is taken as given and the
+// percentage is unknown, or only the percentage is known.
+func (u ProgressUpdate) Resolve(pageCount *int) (*int, *float64, error) {
+	switch {
+	case u.Page != nil && u.Percent != nil:
+		return nil, nil, &ValidationError{"give either current_page or percent, not both"}
+	case u.Page != nil:
+		page := *u.Page
+		if page < 0 {
+			return nil, nil, &ValidationError{"current_page must not be negative"}
+		}
+		if pageCount == nil {
+			return &page, nil, nil
+		}
+		if page > *pageCount {
+			return nil, nil, &ValidationError{"current_page is past the last page of the book"}
+		}
+		percent := roundPercent(float64(page) / float64(*pageCount) * 100)
+		return &page, &percent, nil
+	case u.Percent != nil:
+		percent := *u.Percent
+		if math.IsNaN(percent) || percent < 0 || percent > 100 {
+			return nil, nil, &ValidationError{"percent must be between 0 and 100"}
+		}
+		percent = roundPercent(percent)
+		if pageCount == nil {
+			return nil, &percent, nil
+		}
+		page := int(math.Round(percent / 100 * float64(*pageCount)))
+		return &page, &percent, nil
+	default:
+		return nil, nil, &ValidationError{"current_page or percent is required"}
+	}
+}
+
+func roundPercent(percent float64) float64 {
+	return math.Round(percent*10) / 10
+}
+
+// ProgressEntry is a progress update recorded during a read.
+type ProgressEntry struct {
+	ID          int64     `json:"id"`
+	Page        *int      `json:"current_page,omitempty"`
+	Percent     *float64  `json:"percent,omitempty"`
+	RecordedAt  time.Time `json:"recorded_at"`
+	PagesPerDay *float64  `json:"pages_per_day,omitempty"` // Since the previous entry with a page, when both have one
+}
+
+// ReadingProgress is the progress history of the current read of a book,
+// oldest first, with the pace it shows.
+type ReadingProgress struct {
+	BookID      int64           `json:"book_id"`
+	PagesPerDay *float64        `json:"pages_per_day,omitempty"` // From the first entry with a page to the last; nil until there are two a day apart
+	Entries     []ProgressEntry `json:"entries"`
+}
+
+// MeasurePace sets the pace of each entry since the one before it, and of
+// the whole history, from the pages read. Entries less than an hour apart
+// are too close to tell a pace from, so they get none.
+func (p *ReadingProgress) MeasurePace() {
+	var first, previous *ProgressEntry
+	for i := range p.Entries {
+		entry := &p.Entries[i]
+		if entry.Page == nil {
+			continue
+		}
+		if previous != nil {
+			entry.PagesPerDay = pagesPerDay(previous, entry)
+		}
+		if first == nil {
+			first = entry
+		}
+		previous = entry
+	}
+	p.PagesPerDay = nil
+	if first != nil && previous.RecordedAt.Sub(first.RecordedAt) >= 24*time.Hour {
+		p.PagesPerDay = pagesPerDay(first, previous)
+	}
+}
+
+func pagesPerDay(from, to *ProgressEntry) *float64 {
+	elapsed := to.RecordedAt.Sub(from.RecordedAt)
+	if elapsed < time.Hour {
+		return nil
+	}
+	pace := math.Round(float64(*to.Page-*from.Page)/elapsed.Hours()*24*10) / 10
+	return &pace
+}
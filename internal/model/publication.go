@@ -0,0 +1,53 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxPublisherLength is the longest publisher name accepted, in characters.
+const MaxPublisherLength = 200
+
+// languageCode matches a language code once normalized: an ISO 639 code of
+// two or three letters, as Google Books ("en") and Open Library ("eng") give
+// them, optionally followed by subtags such as a region ("pt-br").
+var languageCode = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{1,8})*$`)
+
+// NormalizePublisher trims a publisher name, collapsing runs of spaces. An
+// empty name is none.
+func NormalizePublisher(publisher *string) *string {
+	if publisher == nil {
+		return nil
+	}
+	name := strings.Join(strings.Fields(*publisher), " ")
+	if name == "" {
+		return nil
+	}
+	return &name
+}
+
+// NormalizeLanguage trims and lowercases a language code, so "EN" and "en"
+// are the same language. An empty code is none.
+func NormalizeLanguage(language *string) *string {
+	if language == nil {
+		return nil
+	}
+	code := strings.ToLower(strings.TrimSpace(*language))
+	if code == "" {
+		return nil
+	}
+	return &code
+}
+
+// ValidatePublication checks a normalized publisher and language, either of
+// which may be nil.
+func ValidatePublication(publisher, language *string) error {
+	if publisher != nil && utf8.RuneCountInString(*publisher) > MaxPublisherLength {
+		return &ValidationError{"publisher must be at most 200 characters"}
+	}
+	if language != nil && !languageCode.MatchString(*language) {
+		return &ValidationError{"language must be a language code such as en or eng"}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidatePublication(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string // Normalized, or "" if invalid
+	}{
+		{" EN ", "en"},
+		{"eng", "eng"},
+		{"pt-BR", "pt-br"},
+		{"English", ""},
+		{"e", ""},
+		{"en_US", ""},
+	}
+	for _, tt := range tests {
+		language := NormalizeLanguage(&tt.language)
+		err := ValidatePublication(nil, language)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("Expected %q to be an invalid language, got %q", tt.language, *language)
+			}
+			continue
+		}
+		if err != nil || *language != tt.want {
+			t.Errorf("NormalizeLanguage(%q) = %q (%v), want %q", tt.language, *language, err, tt.want)
+		}
+	}
+
+	publisher := "  Ace   Books "
+	if got := NormalizePublisher(&publisher); got == nil || *got != "Ace Books" {
+		t.Errorf("Expected the publisher trimmed, got %v", got)
+	}
+	if blank := " "; NormalizePublisher(&blank) != nil || NormalizeLanguage(&blank) != nil {
+		t.Error("Expected a blank publisher and language to be none")
+	}
+	long := strings.Repeat("x", MaxPublisherLength+1)
+	if err := ValidatePublication(&long, nil); err == nil {
+		t.Error("Expected a publisher over the limit to be invalid")
+	}
+}
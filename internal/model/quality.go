@@ -0,0 +1,20 @@
+package model
+
+// DataQuality counts the books whose records are incomplete, to guide
+// cleanup sessions.
+type DataQuality struct {
+	Books            int         `json:"books"`
+	MissingISBN      int         `json:"missing_isbn"`
+	MissingCover     int         `json:"missing_cover"`
+	MissingPageCount int         `json:"missing_page_count"`
+	UnratedRead      int         `json:"unrated_read"` // Books on the Read shelf without a rating
+	SeriesGaps       []SeriesGap `json:"series_gaps"`
+}
+
+// SeriesGap is a series with positions missing from the bookshelf, such as
+// a series with books 1 and 3 but not 2.
+type SeriesGap struct {
+	Series  string `json:"series"`
+	Books   int    `json:"books"`   // Books of the series with a position
+	Missing []int  `json:"missing"` // Positions from 1 to the highest one not on the bookshelf
+}
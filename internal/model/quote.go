@@ -0,0 +1,43 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxQuoteLength is the longest quote accepted, in characters.
+const MaxQuoteLength = 5000
+
+// Quote is a favorite passage of a book, one of any number, with the page it
+// is on when known.
+type Quote struct {
+	ID      int64     `json:"id"`
+	BookID  int64     `json:"book_id"`
+	Text    string    `json:"text"`
+	Page    *int      `json:"page,omitempty"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// Validate trims the quote and checks it has a text and a valid page.
+func (q *Quote) Validate() error {
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Text == "" {
+		return &ValidationError{"text is required"}
+	}
+	if utf8.RuneCountInString(q.Text) > MaxQuoteLength {
+		return &ValidationError{"text must be at most 5000 characters"}
+	}
+	if q.Page != nil && *q.Page <= 0 {
+		return &ValidationError{"page must be a positive number"}
+	}
+	return nil
+}
+
+// RandomQuote is a quote picked at random, with the title and author of its
+// book to show beside it.
+type RandomQuote struct {
+	Quote
+	Title  string `json:"title"`
+	Author string `json:"author"`
+}
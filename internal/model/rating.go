@@ -0,0 +1,81 @@
+package model
+
+import "math"
+
+// RatingScale is the range and granularity of a rating scale, such as
+// Goodreads' one to five stars.
+type RatingScale struct {
+	Min, Max, Step float64
+}
+
+// LocalRatingScale is the bookshelf's own scale of 1 to 10.
+var LocalRatingScale = RatingScale{Min: 1, Max: 10, Step: 1}
+
+// snap rounds v to a step of the scale, clamped to its range.
+func (s RatingScale) snap(v float64, rounding RatingRounding) float64 {
+	// Leave room for floating point error, so 5.5000000001 is still halfway
+	const epsilon = 1e-9
+	steps := (v - s.Min) / s.Step
+	switch rounding {
+	case RoundUp:
+		steps = math.Ceil(steps - epsilon)
+	case RoundDown:
+		steps = math.Floor(steps + epsilon)
+	default:
+		steps = math.Floor(steps + 0.5 + epsilon)
+	}
+	return math.Max(s.Min, math.Min(s.Max, s.Min+steps*s.Step))
+}
+
+// RatingMapping decides how a rating is carried from one scale to another.
+type RatingMapping string
+
+const (
+	// MappingProportional keeps the rating's share of the top of the scale:
+	// 4 of 5 stars is 8 of 10.
+	MappingProportional RatingMapping = "proportional"
+	// MappingLinear maps the bottom of one scale to the bottom of the other
+	// and the top to the top: 1 of 5 stars is 1 of 10 and 3 is 5.5.
+	MappingLinear RatingMapping = "linear"
+)
+
+// IsValid checks if the mapping is one of the predefined rating mappings.
+func (m RatingMapping) IsValid() bool {
+	return m == MappingProportional || m == MappingLinear
+}
+
+// RatingRounding decides where a mapped rating that falls between two steps
+// of the destination scale lands.
+type RatingRounding string
+
+const (
+	RoundNearest RatingRounding = "nearest" // Halfway rounds up
+	RoundUp      RatingRounding = "up"
+	RoundDown    RatingRounding = "down"
+)
+
+// IsValid checks if the rounding is one of the predefined rounding rules.
+func (r RatingRounding) IsValid() bool {
+	return r == RoundNearest || r == RoundUp || r == RoundDown
+}
+
+// ConvertRating carries value from one scale to another with mapping, rounding
+// it to a step of the destination scale.
+func ConvertRating(value float64, from, to RatingScale, mapping RatingMapping, rounding RatingRounding) float64 {
+	var v float64
+	switch mapping {
+	case MappingLinear:
+		v = to.Min + (value-from.Min)*(to.Max-to.Min)/(from.Max-from.Min)
+	default:
+		v = value * to.Max / from.Max
+	}
+	return to.snap(v, rounding)
+}
+
+// SourceRating is a rating as it was given on the provider it was imported
+// from, kept because mapping it to the bookshelf's scale may lose detail.
+type SourceRating struct {
+	Provider SyncProvider `json:"provider"`
+	Value    float64      `json:"value"`
+	Max      float64      `json:"max"` // Top of the provider's scale
+}
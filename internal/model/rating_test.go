@@ -0,0 +1,35 @@
+package model
+
+import "testing"
+
+func TestConvertRating(t *testing.T) {
+	goodreads := ProviderGoodreads.RatingScale()
+	hardcover := ProviderHardcover.RatingScale()
+	for _, tc := range []struct {
+		value    float64
+		from, to RatingScale
+		mapping  RatingMapping
+		rounding RatingRounding
+		want     float64
+	}{
+		{4, goodreads, LocalRatingScale, MappingProportional, RoundNearest, 8},
+		{1, goodreads, LocalRatingScale, MappingProportional, RoundNearest, 2},
+		{3.5, hardcover, LocalRatingScale, MappingProportional, RoundNearest, 7},
+		{1, goodreads, LocalRatingScale, MappingLinear, RoundNearest, 1},
+		{3, goodreads, LocalRatingScale, MappingLinear, RoundNearest, 6},
+		{3, goodreads, LocalRatingScale, MappingLinear, RoundDown, 5},
+		{2, goodreads, LocalRatingScale, MappingLinear, RoundUp, 4},
+		{5, goodreads, LocalRatingScale, MappingLinear, RoundDown, 10},
+		{7, LocalRatingScale, goodreads, MappingProportional, RoundNearest, 4},
+		{7, LocalRatingScale, goodreads, MappingProportional, RoundDown, 3},
+		{7, LocalRatingScale, hardcover, MappingProportional, RoundNearest, 3.5},
+		{1, LocalRatingScale, hardcover, MappingProportional, RoundNearest, 0.5},
+		{6, LocalRatingScale, hardcover, MappingLinear, RoundNearest, 3},
+		// Unset rules are proportional and round to the nearest step
+		{3, goodreads, LocalRatingScale, "", "", 6},
+	} {
+		if got := ConvertRating(tc.value, tc.from, tc.to, tc.mapping, tc.rounding); got != tc.want {
+			t.Errorf("%v on %+v to %+v (%s, %s): got %v, want %v", tc.value, tc.from, tc.to, tc.mapping, tc.rounding, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Read is one completed reading of a book. A book read more than once has a
+// read for each time through; the book's own dates follow the latest one.
+type Read struct {
+	ID           int64      `json:"id"`
+	BookID       int64      `json:"book_id"`
+	DateStarted  *time.Time `json:"date_started,omitempty"` // Unknown for reads logged after the fact
+	DateFinished time.Time  `json:"date_finished"`
+}
+
+// Validate checks that the read has finished, and did not finish before it started.
+func (r *Read) Validate() error {
+	if r.DateFinished.IsZero() {
+		return &ValidationError{"date_finished is required"}
+	}
+	if r.DateStarted != nil && r.DateStarted.After(r.DateFinished) {
+		return &ValidationError{"date_started must not be after date_finished"}
+	}
+	return nil
+}
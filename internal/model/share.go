@@ -0,0 +1,73 @@
+package model
+
+import "time"
+
+// ShareLink is a read-only link to a filtered view of a user's library, for
+// showing it to people without an account. Anyone with the link's token sees
+// the books in its scope, and only them: those on Status and with Tag, when
+// set, never archived ones or the trash. Ratings and comments are hidden
+// unless the link includes them. The token itself is only shown once, when
+// the link is created; Prefix, its first characters, tells links apart
+// afterwards.
+type ShareLink struct {
+	ID              int64      `json:"id"`
+	UserID          *int64     `json:"-"`
+	Name            string     `json:"name"`
+	Prefix          string     `json:"prefix"`
+	Status          BookStatus `json:"status,omitempty"` // Empty for every shelf
+	Tag             string     `json:"tag,omitempty"`    // Empty for any tag
+	IncludeRatings  bool       `json:"include_ratings"`
+	IncludeComments bool       `json:"include_comments"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"` // Nil for a link that never expires
+	CreatedAt       time.Time  `json:"created_at"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
+}
+
+// SharedBook is a book as a share link shows it: what it is and where it is
+// on the bookshelf, without the owner's private details.
+type SharedBook struct {
+	ID              int64      `json:"id"`
+	Title           string     `json:"title"`
+	Subtitle        *string    `json:"subtitle,omitempty"`
+	Author          string     `json:"author"`
+	ISBN            string     `json:"isbn,omitempty"`
+	Status          BookStatus `json:"status"`
+	Type            BookType   `json:"type"`
+	CoverURL        *string    `json:"cover_url,omitempty"`
+	CoverHash       *string    `json:"-"` // Cached cover; served publicly from /api/covers/{hash}
+	Series          *string    `json:"series,omitempty"`
+	SeriesIndex     *int       `json:"series_index,omitempty"`
+	PublishYear     *int       `json:"publish_year,omitempty"`
+	PageCount       *int       `json:"page_count,omitempty"`
+	DateFinished    *time.Time `json:"date_finished,omitempty"`
+	Rating          *int       `json:"rating,omitempty"`   // Only when the link includes ratings
+	Comments        *string    `json:"comments,omitempty"` // Only when the link includes comments
+	CommentsSpoiler bool       `json:"comments_spoiler,omitempty"`
+}
+
+// NewSharedBook returns book as link shows it.
+func NewSharedBook(book Book, link ShareLink) SharedBook {
+	shared := SharedBook{
+		ID:           book.ID,
+		Title:        book.Title,
+		Subtitle:     book.Subtitle,
+		Author:       book.Author,
+		ISBN:         book.ISBN,
+		Status:       book.Status,
+		Type:         book.Type,
+		CoverURL:     book.CoverURL,
+		CoverHash:    book.CoverHash,
+		Series:       book.Series,
+		SeriesIndex:  book.SeriesIndex,
+		PublishYear:  book.PublishYear,
+		PageCount:    book.PageCount,
+		DateFinished: book.DateFinished,
+	}
+	if link.IncludeRatings {
+		shared.Rating = book.Rating
+	}
+	if link.IncludeComments && book.Comments != nil {
+		shared.Comments, shared.CommentsSpoiler = book.Comments, book.CommentsSpoiler
+	}
+	return shared
+}
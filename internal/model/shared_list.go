@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// SharedListFormat identifies files produced by the list export endpoint.
+const SharedListFormat = "bookshelf-list"
+
+// SharedListVersion is the current version of the shared list file format.
+const SharedListVersion = 1
+
+// SharedList is a compact, portable representation of a reading list that can be
+// exported from one bookshelf instance and imported into another.
+type SharedList struct {
+	Format     string           `json:"format"`  // Always SharedListFormat
+	Version    int              `json:"version"` // File format version
+	Name       string           `json:"name"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Books      []SharedListItem `json:"books"`
+}
+
+// SharedListItem is a single book within a SharedList.
+type SharedListItem struct {
+	Title         string  `json:"title"`
+	Author        string  `json:"author,omitempty"`
+	ISBN          string  `json:"isbn,omitempty"`
+	OpenLibraryID string  `json:"open_library_id,omitempty"`
+	CoverURL      *string `json:"cover_url,omitempty"`
+	Year          *int    `json:"year,omitempty"` // Year of first publication
+	Notes         *string `json:"notes,omitempty"`
+}
+
+// Validate checks that the shared list header is one this version understands.
+func (l *SharedList) Validate() error {
+	if l.Format != SharedListFormat {
+		return &ValidationError{"unrecognized list format, expected '" + SharedListFormat + "'"}
+	}
+	if l.Version < 1 || l.Version > SharedListVersion {
+		return &ValidationError{"unsupported list version"}
+	}
+	return nil
+}
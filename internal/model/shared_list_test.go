@@ -0,0 +1,25 @@
+package model
+
+import "testing"
+
+func TestSharedList_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    SharedList
+		wantErr bool
+	}{
+		{name: "Current version", list: SharedList{Format: SharedListFormat, Version: SharedListVersion}, wantErr: false},
+		{name: "Wrong format", list: SharedList{Format: "goodreads", Version: 1}, wantErr: true},
+		{name: "Missing version", list: SharedList{Format: SharedListFormat}, wantErr: true},
+		{name: "Future version", list: SharedList{Format: SharedListFormat, Version: SharedListVersion + 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.list.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SharedList.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
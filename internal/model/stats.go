@@ -0,0 +1,138 @@
+package model
+
+import "time"
+
+// ReadingYear counts the reads finished in a year, split into books read for
+// the first time and re-reads of books finished before.
+type ReadingYear struct {
+	Year       int `json:"year"`
+	FirstReads int `json:"first_reads"`
+	Rereads    int `json:"rereads"`
+}
+
+// RereadBook is a book that was read more than once.
+type RereadBook struct {
+	BookID       int64     `json:"book_id"`
+	Title        string    `json:"title"`
+	Author       string    `json:"author"`
+	Reads        int       `json:"reads"`
+	LastFinished time.Time `json:"last_finished"`
+}
+
+// RereadStats compares new books with re-reads.
+type RereadStats struct {
+	Years      []ReadingYear `json:"years"`       // Oldest first; years without reads are left out
+	MostReread []RereadBook  `json:"most_reread"` // Most reads first
+}
+
+// LengthBucket is the average time to finish the books of a range of lengths.
+type LengthBucket struct {
+	Label       string   `json:"label"` // "<200", "200-400" or "400+"
+	MinPages    int      `json:"min_pages"`
+	MaxPages    *int     `json:"max_pages,omitempty"` // Exclusive; nil for the longest books
+	Reads       int      `json:"reads"`
+	AverageDays *float64 `json:"average_days"` // Nil when no read falls in the bucket
+}
+
+// ReadingPace is how fast recent books were read, for estimating when the
+// books being read will be finished.
+type ReadingPace struct {
+	PagesPerDay float64 `json:"pages_per_day"` // Zero when no recent read can be measured
+	Reads       int     `json:"reads"`         // Recent reads the pace is based on
+}
+
+// EstimateFinish returns the day a Currently Reading book should be finished
+// at this pace: the pages left past the page reached, counted from now, or,
+// while no progress is recorded, its page count after the day it started. A
+// book that has taken longer than that is estimated to finish today. It
+// returns nil for other books, books without a page count or start date, and
+// an unknown pace.
+func (p ReadingPace) EstimateFinish(book *Book, now time.Time) *time.Time {
+	if book.Status != StatusCurrentlyReading || book.PageCount == nil || book.DateStarted == nil || p.PagesPerDay <= 0 {
+		return nil
+	}
+	pages, from := *book.PageCount, book.DateStarted.UTC()
+	if book.CurrentPage != nil {
+		pages, from = max(*book.PageCount-*book.CurrentPage, 0), now.UTC()
+	}
+	days := time.Duration(float64(pages) / p.PagesPerDay * float64(24*time.Hour))
+	estimate := from.Add(days)
+	if today := now.UTC(); estimate.Before(today) {
+		estimate = today
+	}
+	day := time.Date(estimate.Year(), estimate.Month(), estimate.Day(), 0, 0, 0, 0, time.UTC)
+	return &day
+}
+
+// PeriodCount is the reads finished in a month ("2025-03") or a year
+// ("2025") and the pages they covered.
+type PeriodCount struct {
+	Period string `json:"period"`
+	Reads  int    `json:"reads"`
+	Pages  int    `json:"pages"`
+}
+
+// ReadingStreak counts the days in a row with reading, as days covered by a
+// read or by a book being read. Vacation days neither count nor break a
+// streak, and a streak isn't broken before the end of today.
+type ReadingStreak struct {
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+}
+
+// ReadingStats summarizes the leisure reads finished in a year, or ever.
+type ReadingStats struct {
+	Year          *int          `json:"year,omitempty"` // Nil for every year
+	Books         int           `json:"books"`          // Books with a read finished
+	Reads         int           `json:"reads"`          // Reads finished, re-reads included
+	Pages         int           `json:"pages"`          // Pages of the reads finished, counted again for re-reads
+	AverageRating *float64      `json:"average_rating"` // Of the rated books; nil when there are none
+	Months        []PeriodCount `json:"months"`         // Oldest first; every month of the year when there is one
+	Years         []PeriodCount `json:"years"`          // Oldest first; years without reads are left out
+	Types         []StatCount   `json:"types"`          // Books per type
+	Authors       []StatCount   `json:"authors"`        // Most read first, up to 10
+	Streak        ReadingStreak `json:"streak"`         // Up to today, whatever the year
+}
+
+// Streaks returns the current and longest runs of days in reading, which
+// holds the reading days formatted with DateLayout, up to today in UTC.
+func Streaks(reading map[string]bool, vacations []Vacation, today time.Time) ReadingStreak {
+	var streak ReadingStreak
+	if len(reading) == 0 {
+		return streak
+	}
+	first := ""
+	for day := range reading {
+		if first == "" || day < first {
+			first = day
+		}
+	}
+	start, _ := time.Parse(DateLayout, first)
+	today = time.Date(today.UTC().Year(), today.UTC().Month(), today.UTC().Day(), 0, 0, 0, 0, time.UTC)
+	run := 0
+	for day := start; !day.After(today); day = day.AddDate(0, 0, 1) {
+		switch {
+		case reading[day.Format(DateLayout)]:
+			run++
+		case day.Equal(today):
+			// Today can still be read
+		case vacationDay(vacations, day):
+		default:
+			run = 0
+		}
+		if run > streak.Longest {
+			streak.Longest = run
+		}
+	}
+	streak.Current = run
+	return streak
+}
+
+func vacationDay(vacations []Vacation, day time.Time) bool {
+	for _, v := range vacations {
+		if v.Covers(day) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package model
+
+import "time"
+
+// SyncProvider identifies an external reading tracker that the bookshelf can sync with.
+type SyncProvider string
+
+const (
+	ProviderHardcover SyncProvider = "hardcover"
+	ProviderGoodreads SyncProvider = "goodreads"
+)
+
+// IsValid checks if the provider is one of the supported sync providers.
+func (p SyncProvider) IsValid() bool {
+	switch p {
+	case ProviderHardcover, ProviderGoodreads:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanPush reports whether local changes can be written back to the provider.
+// Goodreads retired its public API, so it is pulled from shelf RSS feeds only.
+func (p SyncProvider) CanPush() bool {
+	return p == ProviderHardcover
+}
+
+// RatingScale returns the scale the provider rates books on: whole stars from
+// one to five on Goodreads, and half stars on Hardcover.
+func (p SyncProvider) RatingScale() RatingScale {
+	if p == ProviderHardcover {
+		return RatingScale{Min: 0.5, Max: 5, Step: 0.5}
+	}
+	return RatingScale{Min: 1, Max: 5, Step: 1}
+}
+
+// ConflictPolicy decides which side wins when a book changed both locally and
+// remotely since the last sync.
+type ConflictPolicy string
+
+const (
+	ConflictLocalWins  ConflictPolicy = "local"
+	ConflictRemoteWins ConflictPolicy = "remote"
+)
+
+// IsValid checks if the policy is one of the predefined conflict policies.
+func (c ConflictPolicy) IsValid() bool {
+	return c == ConflictLocalWins || c == ConflictRemoteWins
+}
+
+// SyncAccount is a linked Hardcover or Goodreads account.
+// The credential is stored encrypted and never serialized.
+type SyncAccount struct {
+	ID             int64          `json:"id"`
+	Provider       SyncProvider   `json:"provider"`
+	RemoteUser     string         `json:"remote_user"` // Goodreads user ID; informational for Hardcover
+	ConflictPolicy ConflictPolicy `json:"conflict_policy"`
+	RatingMapping  RatingMapping  `json:"rating_mapping"`  // How ratings are carried between the provider's scale and 1-10
+	RatingRounding RatingRounding `json:"rating_rounding"` // Where mapped ratings between two steps land
+	Enabled        bool           `json:"enabled"`
+	CreatedAt      time.Time      `json:"created_at"`
+	LastSyncedAt   *time.Time     `json:"last_synced_at,omitempty"`
+	LastError      *string        `json:"last_error,omitempty"`
+	EncryptedToken string         `json:"-"`
+	UserID         *int64         `json:"-"` // Owner whose library is synced; nil for accounts from before accounts
+}
+
+// SyncLink ties a local book to its counterpart on a sync provider and records
+// the state both sides agreed on after the last sync.
+type SyncLink struct {
+	AccountID int64
+	BookID    int64
+	RemoteID  string
+	Status    BookStatus
+	Rating    *int
+}
+
+// SyncDirection describes what a sync log entry records.
+type SyncDirection string
+
+const (
+	SyncPush     SyncDirection = "push"     // Local change written to the provider
+	SyncPull     SyncDirection = "pull"     // Remote change applied locally
+	SyncConflict SyncDirection = "conflict" // Both sides changed; resolved by the account's policy
+	SyncReview   SyncDirection = "review"   // Weak match added to the review queue
+	SyncError    SyncDirection = "error"
+)
+
+// SyncLogEntry is a single line in the sync log.
+type SyncLogEntry struct {
+	ID         int64         `json:"id"`
+	AccountID  int64         `json:"account_id"`
+	BookID     *int64        `json:"book_id,omitempty"`
+	Direction  SyncDirection `json:"direction"`
+	Message    string        `json:"message"`
+	OccurredAt time.Time     `json:"occurred_at"`
+}
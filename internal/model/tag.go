@@ -0,0 +1,34 @@
+package model
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxTagLength is the longest tag name accepted, in characters.
+const MaxTagLength = 50
+
+// Tag is a user-defined label, such as a genre, that can be given to any
+// number of books. Names are unique regardless of case.
+type Tag struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	BookCount int    `json:"book_count"` // Number of books with the tag
+}
+
+// NormalizeTagName trims a tag name and collapses inner runs of whitespace, so
+// "  science   fiction " and "science fiction" are the same tag.
+func NormalizeTagName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// ValidateTagName checks a normalized tag name.
+func ValidateTagName(name string) error {
+	if name == "" {
+		return &ValidationError{"tag name is required"}
+	}
+	if utf8.RuneCountInString(name) > MaxTagLength {
+		return &ValidationError{"tag name must be at most 50 characters"}
+	}
+	return nil
+}
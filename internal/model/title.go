@@ -0,0 +1,39 @@
+package model
+
+import "strings"
+
+// SplitTitle splits a title such as "Dune: Deluxe Edition" into the title
+// and its subtitle at the first colon followed by a space, so titles like
+// "10:04" stay whole. Titles without a subtitle, or with nothing on one side
+// of the colon, are returned whole with an empty subtitle.
+func SplitTitle(s string) (title, subtitle string) {
+	s = strings.TrimSpace(s)
+	before, after, ok := strings.Cut(s, ": ")
+	before, after = strings.TrimSpace(before), strings.TrimSpace(after)
+	if !ok || before == "" || after == "" {
+		return s, ""
+	}
+	return before, after
+}
+
+// FullTitle returns the title with its subtitle, as in "Dune: Deluxe Edition".
+func (b *Book) FullTitle() string {
+	if b.Subtitle == nil || *b.Subtitle == "" {
+		return b.Title
+	}
+	return b.Title + ": " + *b.Subtitle
+}
+
+// SplitSubtitle moves a subtitle written into the title to the subtitle
+// field, unless the book already has one. It reports whether it did.
+func (b *Book) SplitSubtitle() bool {
+	if b.Subtitle != nil {
+		return false
+	}
+	title, subtitle := SplitTitle(b.Title)
+	if subtitle == "" {
+		return false
+	}
+	b.Title, b.Subtitle = title, &subtitle
+	return true
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"regexp"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the shortest password accepted, in bytes. Passwords
+// may be at most 72 bytes, the most bcrypt hashes.
+const MinPasswordLength = 8
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.@-]{1,64}$`)
+
+// User is an account with a library of its own. Usernames are unique
+// regardless of case.
+type User struct {
+	ID           int64     `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	// PublicFeed is whether the user's activity feed may be read by anyone,
+	// such as other instances following it.
+	PublicFeed bool `json:"public_feed"`
+}
+
+// ValidateUsername checks that a username is 1-64 letters, digits, dots,
+// dashes, underscores or at signs, so email addresses can be usernames.
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return &ValidationError{"username must be 1-64 letters, digits, '.', '-', '_' or '@'"}
+	}
+	return nil
+}
+
+// SetPassword validates password and stores its hash on the user.
+func (u *User) SetPassword(password string) error {
+	if len(password) < MinPasswordLength || len(password) > 72 {
+		return &ValidationError{"password must be between 8 and 72 bytes"}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = string(hash)
+	return nil
+}
+
+// CheckPassword reports whether password is the user's.
+func (u *User) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
+
+// APIKey is a named key a script uses to act as a user, sent as
+// "Authorization: Bearer <key>". The key itself is only shown once, when it is
+// created; Prefix, its first characters, tells keys apart afterwards.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// Passkey is a WebAuthn credential a user logs in with instead of a password.
+// CredentialID is the authenticator's ID for it, base64url encoded; the
+// public key and signature counter are only needed to verify logins.
+type Passkey struct {
+	ID           int64      `json:"id"`
+	UserID       int64      `json:"-"`
+	Name         string     `json:"name"`
+	CredentialID string     `json:"credential_id"`
+	PublicKey    []byte     `json:"-"`
+	SignCount    uint32     `json:"-"`
+	Transports   []string   `json:"transports"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// DateLayout is the layout of calendar dates, such as vacation days.
+const DateLayout = "2006-01-02"
+
+// Vacation is a range of days, first and last included, on which reading is
+// paused. Streaks don't break and goal pace doesn't fall behind over them.
+type Vacation struct {
+	ID        int64     `json:"id"`
+	StartDate string    `json:"start_date"` // YYYY-MM-DD
+	EndDate   string    `json:"end_date"`   // YYYY-MM-DD
+	Note      *string   `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks the vacation's dates and trims its note.
+func (v *Vacation) Validate() error {
+	start, err := time.Parse(DateLayout, v.StartDate)
+	if err != nil {
+		return &ValidationError{"start_date must be a date formatted as YYYY-MM-DD"}
+	}
+	end, err := time.Parse(DateLayout, v.EndDate)
+	if err != nil {
+		return &ValidationError{"end_date must be a date formatted as YYYY-MM-DD"}
+	}
+	if end.Before(start) {
+		return &ValidationError{"end_date must not be before start_date"}
+	}
+	if v.Note != nil {
+		note := strings.TrimSpace(*v.Note)
+		v.Note = &note
+		if note == "" {
+			v.Note = nil
+		}
+	}
+	return nil
+}
+
+// Covers reports whether day, in UTC, is one of the vacation's days.
+func (v Vacation) Covers(day time.Time) bool {
+	d := day.UTC().Format(DateLayout)
+	return d >= v.StartDate && d <= v.EndDate
+}
+
+// VacationDays counts the days from from up to but not including to, in UTC,
+// that fall on any of vacations. Overlapping vacations count each day once.
+func VacationDays(vacations []Vacation, from, to time.Time) int {
+	from = time.Date(from.UTC().Year(), from.UTC().Month(), from.UTC().Day(), 0, 0, 0, 0, time.UTC)
+	days := 0
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, v := range vacations {
+			if v.Covers(day) {
+				days++
+				break
+			}
+		}
+	}
+	return days
+}
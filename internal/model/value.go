@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// MarketValue is what a book was worth on the market when it was sampled,
+// by ISBN, as a value source reported it.
+type MarketValue struct {
+	ID        int64     `json:"id"`
+	ISBN      string    `json:"isbn"`
+	Value     float64   `json:"value"`
+	Currency  string    `json:"currency"` // ISO 4217, e.g. "USD"
+	Source    string    `json:"source"`
+	SampledAt time.Time `json:"sampled_at"`
+}
+
+// BookValueHistory is the market value of a book over time, oldest sample
+// first. Books without an ISBN have no samples.
+type BookValueHistory struct {
+	BookID  int64         `json:"book_id"`
+	ISBN    *string       `json:"isbn,omitempty"`
+	Latest  *MarketValue  `json:"latest,omitempty"`
+	Samples []MarketValue `json:"samples"`
+}
+
+// ValuePoint is the value of the collection on a day: the sum of the latest
+// value of each book sampled by then.
+type ValuePoint struct {
+	Date  string  `json:"date"` // YYYY-MM-DD, in UTC
+	Value float64 `json:"value"`
+	Books int     `json:"books"` // Books with a value by then
+}
+
+// ValueSeries is the value of the collection over time in one currency, with
+// a point for each day a sample was taken on.
+type ValueSeries struct {
+	Currency string       `json:"currency"`
+	Points   []ValuePoint `json:"points"`
+}
+
+// CollectionValue is the market value over time of the books owned: those on
+// the shelf and not archived.
+type CollectionValue struct {
+	Books  int           `json:"books"`  // Books owned with an ISBN
+	Valued int           `json:"valued"` // Of which have been sampled
+	Series []ValueSeries `json:"series"` // One per currency, by currency
+}
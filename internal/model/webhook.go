@@ -0,0 +1,77 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookEvent is a book lifecycle event webhooks can subscribe to.
+type WebhookEvent string
+
+const (
+	WebhookBookCreated       WebhookEvent = "book.created"
+	WebhookBookStatusChanged WebhookEvent = "book.status_changed"
+	WebhookBookFinished      WebhookEvent = "book.finished" // Moved to Read; sent along with book.status_changed
+	WebhookBookDeleted       WebhookEvent = "book.deleted"  // Moved to the trash, or deleted for good without going there
+)
+
+// WebhookEvents are the events a webhook can subscribe to.
+var WebhookEvents = []WebhookEvent{WebhookBookCreated, WebhookBookStatusChanged, WebhookBookFinished, WebhookBookDeleted}
+
+// IsValid checks if the event is one of the predefined webhook events.
+func (e WebhookEvent) IsValid() bool {
+	for _, event := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook is an endpoint sent the events it subscribes to. The secret its
+// payloads are signed with is stored encrypted and never serialized.
+type Webhook struct {
+	ID              int64          `json:"id"`
+	URL             string         `json:"url"`
+	Events          []WebhookEvent `json:"events"`
+	Enabled         bool           `json:"enabled"`
+	CreatedAt       time.Time      `json:"created_at"`
+	EncryptedSecret string         `json:"-"`
+}
+
+// WebhookDeliveryStatus is the state of a delivery of an event to a webhook.
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "pending"   // Not yet attempted, or to be retried at NextAttemptAt
+	DeliveryDelivered WebhookDeliveryStatus = "delivered" // The endpoint answered with a 2xx status
+	DeliveryFailed    WebhookDeliveryStatus = "failed"    // Given up on after the last retry
+)
+
+// WebhookPayload is the JSON body posted to a webhook.
+type WebhookPayload struct {
+	Event          WebhookEvent `json:"event"`
+	OccurredAt     time.Time    `json:"occurred_at"`
+	Book           Book         `json:"book"`                      // As it was after the change
+	PreviousStatus BookStatus   `json:"previous_status,omitempty"` // For status changes
+}
+
+// WebhookDelivery is an event queued for, or sent to, a webhook: an entry of
+// its delivery log.
+type WebhookDelivery struct {
+	ID             int64                 `json:"id"`
+	WebhookID      int64                 `json:"webhook_id"`
+	Event          WebhookEvent          `json:"event"`
+	BookID         int64                 `json:"book_id"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at,omitempty"` // While pending
+	ResponseStatus *int                  `json:"response_status,omitempty"` // Of the last attempt; nil when it got no response
+	Error          string                `json:"error,omitempty"`           // Of the last failed attempt
+	CreatedAt      time.Time             `json:"created_at"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage       `json:"payload"`
+	// The webhook's URL and secret, for sending it
+	URL             string `json:"-"`
+	EncryptedSecret string `json:"-"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// WishlistShare is a user a wishlist is shared with, or, for a member, the
+// owner of a wishlist shared with them.
+type WishlistShare struct {
+	UserID   int64     `json:"user_id"`
+	Username string    `json:"username"`
+	SharedAt time.Time `json:"shared_at"`
+}
+
+// WishlistItem is a book of a shared wishlist as its members see it: on the
+// owner's Want to Read shelf, with whether a member claimed it to give. The
+// owner never sees claims, so a gift stays a surprise.
+type WishlistItem struct {
+	BookID      int64      `json:"book_id"`
+	Title       string     `json:"title"`
+	Author      string     `json:"author"`
+	ISBN        string     `json:"isbn,omitempty"`
+	PublishYear *int       `json:"publish_year,omitempty"`
+	Claimed     bool       `json:"claimed"`
+	ClaimedByMe bool       `json:"claimed_by_me"`
+	Purchased   bool       `json:"purchased"` // The claimer bought it already
+	ClaimedAt   *time.Time `json:"claimed_at,omitempty"`
+}
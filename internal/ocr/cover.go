@@ -0,0 +1,125 @@
+package ocr
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Cover is what the text of a cover says about its book. Either field may
+// be empty when it couldn't be told apart.
+type Cover struct {
+	Title  string
+	Author string
+}
+
+// Query returns a search query for the book on the cover.
+func (c Cover) Query() string {
+	return strings.TrimSpace(c.Title + " " + c.Author)
+}
+
+// blurbs mark cover lines that are neither the title nor the author.
+var blurbs = []string{
+	"bestseller", "best seller", "best-seller", "author of", "a novel", "winner of", "now a major", "edition",
+	"introduction by", "foreword by", "translated by", "illustrated by", "praise for", "million copies",
+}
+
+// nameWord matches a word of a personal name: a capitalized word, an
+// initial or a particle such as "van".
+var nameWord = regexp.MustCompile(`^(?:[A-Z][A-Za-z'’-]*\.?|[A-Z]\.(?:[A-Z]\.)*|de|van|von|der|le|la|du|da|di)$`)
+
+// maxTitleLines is the most lines a title is taken to span.
+const maxTitleLines = 2
+
+// ParseCover picks the title and author out of the text of a cover. Lines
+// of stray characters and blurbs such as "New York Times bestseller" are
+// passed over. The author is the line starting with "by", or else the last
+// line shaped like a name; the title is the first lines left, up to two.
+func ParseCover(text string) Cover {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Trim(strings.Join(strings.Fields(line), " "), " |_~-—=*•·")
+		if !wordy(line) || isBlurb(line) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	var cover Cover
+	author := -1
+	for i, line := range lines {
+		if len(line) > 3 && strings.EqualFold(line[:3], "by ") {
+			cover.Author, author = strings.TrimSpace(line[3:]), i
+			break
+		}
+	}
+	if author < 0 && len(lines) > 1 {
+		for i := len(lines) - 1; i >= 0; i-- {
+			if nameLike(lines[i]) {
+				cover.Author, author = lines[i], i
+				break
+			}
+		}
+	}
+
+	var title []string
+	for i, line := range lines {
+		if i == author {
+			if len(title) > 0 {
+				break // The title came before the author
+			}
+			continue
+		}
+		title = append(title, line)
+		if len(title) == maxTitleLines {
+			break
+		}
+	}
+	cover.Title = strings.Join(title, " ")
+	return cover
+}
+
+// wordy reports whether a line is mostly letters rather than the specks OCR
+// makes of pictures.
+func wordy(line string) bool {
+	letters, others := 0, 0
+	for _, r := range line {
+		switch {
+		case unicode.IsLetter(r):
+			letters++
+		case !unicode.IsSpace(r):
+			others++
+		}
+	}
+	return letters >= 2 && letters >= 2*others
+}
+
+func isBlurb(line string) bool {
+	lower := strings.ToLower(line)
+	for _, blurb := range blurbs {
+		if strings.Contains(lower, blurb) {
+			return true
+		}
+	}
+	return false
+}
+
+// nameLike reports whether a line is shaped like a personal name, such as
+// "Ursula K. Le Guin" or "ANDY WEIR": two to four name words, not starting
+// with an article.
+func nameLike(line string) bool {
+	words := strings.Fields(line)
+	if len(words) < 2 || len(words) > 4 {
+		return false
+	}
+	switch strings.ToLower(words[0]) {
+	case "the", "a", "an":
+		return false
+	}
+	for _, word := range words {
+		if !nameWord.MatchString(word) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+// Package ocr reads the text on photos of book covers through a pluggable
+// Engine, and picks the title and author out of it, for adding books whose
+// barcode is missing or unreadable. Engines are Tesseract, run as a command,
+// or an OCR service spoken to over HTTP. Photos of whole shelves are split
+// into spines by a Segmenter first, for cataloging a physical library.
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// Engine recognizes the text in an image.
+type Engine interface {
+	Name() string
+	// Recognize returns the text of image, line by line.
+	Recognize(ctx context.Context, image []byte, contentType string) (string, error)
+}
+
+// TesseractName is the name of the Tesseract engine.
+const TesseractName = "tesseract"
+
+// Tesseract runs the tesseract command line tool, which must be on the PATH.
+type Tesseract struct {
+	Command   string // Defaults to "tesseract"
+	Languages string // Tesseract's -l, e.g. "eng+deu"; empty for its default
+}
+
+// Name returns "tesseract".
+func (t *Tesseract) Name() string { return TesseractName }
+
+// Recognize pipes image through tesseract.
+func (t *Tesseract) Recognize(ctx context.Context, image []byte, contentType string) (string, error) {
+	command := t.Command
+	if command == "" {
+		command = TesseractName
+	}
+	args := []string{"stdin", "stdout"}
+	if t.Languages != "" {
+		args = append(args, "-l", t.Languages)
+	}
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Stdin = bytes.NewReader(image)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w: %s", command, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return string(output), nil
+}
+
+// HTTPName is the name of the HTTP engine.
+const HTTPName = "http"
+
+// HTTPEngine posts images to an OCR service, such as a small adapter in
+// front of a cloud vision API. The service receives the image as the request
+// body with its content type and answers with {"text": "..."}.
+type HTTPEngine struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// Name returns "http".
+func (h *HTTPEngine) Name() string { return HTTPName }
+
+// Recognize sends image to the service.
+func (h *HTTPEngine) Recognize(ctx context.Context, image []byte, contentType string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(image))
+	if err != nil {
+		return "", fmt.Errorf("failed to create OCR request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	resp, err := h.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("OCR request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("OCR service returned %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode OCR response: %w", err)
+	}
+	return result.Text, nil
+}
+
+// NewEngine returns the engine named by spec: "tesseract", or the http(s)
+// URL of an OCR service, which is sent requests with client.
+func NewEngine(spec string, client *http.Client) (Engine, error) {
+	switch {
+	case spec == TesseractName:
+		if _, err := exec.LookPath(TesseractName); err != nil {
+			return nil, fmt.Errorf("the tesseract OCR engine requires tesseract to be installed")
+		}
+		return &Tesseract{}, nil
+	case strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://"):
+		return &HTTPEngine{URL: spec, HTTPClient: client}, nil
+	}
+	return nil, fmt.Errorf("unknown OCR engine %q, expected %q or the URL of an OCR service", spec, TesseractName)
+}
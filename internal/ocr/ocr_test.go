@@ -0,0 +1,52 @@
+package ocr
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseCover(t *testing.T) {
+	for text, want := range map[string]Cover{
+		"THE NEW YORK TIMES BESTSELLER\nPROJECT\nHAIL MARY\n\nANDY WEIR\nAuthor of The Martian\n": {Title: "PROJECT HAIL MARY", Author: "ANDY WEIR"},
+		"Ursula K. Le Guin\n\nThe Left Hand\nof Darkness\n":                                       {Title: "The Left Hand of Darkness", Author: "Ursula K. Le Guin"},
+		"Pride and Prejudice\nby Jane Austen\n":                                                   {Title: "Pride and Prejudice", Author: "Jane Austen"},
+		"~ ;; |\nDUNE\n' .\nFRANK HERBERT\n":                                                      {Title: "DUNE", Author: "FRANK HERBERT"},
+		"Middlemarch\n":                                                                           {Title: "Middlemarch"},
+		"":                                                                                        {},
+	} {
+		if got := ParseCover(text); got != want {
+			t.Errorf("ParseCover(%q) = %+v, want %+v", text, got, want)
+		}
+	}
+}
+
+func TestHTTPEngine(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "image/jpeg" || string(body) != "jpeg" {
+			http.Error(w, "unexpected image", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"text": "DUNE\nFRANK HERBERT"})
+	}))
+	defer server.Close()
+
+	engine, err := NewEngine(server.URL, server.Client())
+	if err != nil || engine.Name() != HTTPName {
+		t.Fatalf("NewEngine = %v, %v", engine, err)
+	}
+	text, err := engine.Recognize(context.Background(), []byte("jpeg"), "image/jpeg")
+	if err != nil || text != "DUNE\nFRANK HERBERT" {
+		t.Errorf("Recognize = %q, %v", text, err)
+	}
+	if _, err := engine.Recognize(context.Background(), []byte("png"), "image/png"); err == nil {
+		t.Error("Expected an error response to fail")
+	}
+	if _, err := NewEngine("magic", nil); err == nil {
+		t.Error("Expected an unknown engine to be rejected")
+	}
+}
@@ -0,0 +1,124 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"net/http"
+
+	_ "image/gif"  // Decode GIF photos
+	_ "image/jpeg" // Decode JPEG photos
+)
+
+// Spine is a book spine found on a photo of a shelf: where it is, and its
+// text when the segmenter read it too.
+type Spine struct {
+	Box  image.Rectangle
+	Text string // "" when the spine is left for an Engine to read
+}
+
+// Segmenter finds the spines of the books on a photo of a shelf, such as a
+// vision service.
+type Segmenter interface {
+	Name() string
+	Segment(ctx context.Context, photo []byte, contentType string) ([]Spine, error)
+}
+
+// HTTPSegmenter posts photos to a vision service, such as a small adapter in
+// front of a cloud object detection API. The service receives the photo as
+// the request body with its content type and answers with
+// {"spines": [{"x": 10, "y": 0, "width": 40, "height": 300, "text": "..."}]},
+// boxes in pixels from the top left; "text" is optional.
+type HTTPSegmenter struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// Name returns "http".
+func (h *HTTPSegmenter) Name() string { return HTTPName }
+
+// Segment sends photo to the service.
+func (h *HTTPSegmenter) Segment(ctx context.Context, photo []byte, contentType string) ([]Spine, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(photo))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vision request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	resp, err := h.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vision request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("vision service returned %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	var result struct {
+		Spines []struct {
+			X      int    `json:"x"`
+			Y      int    `json:"y"`
+			Width  int    `json:"width"`
+			Height int    `json:"height"`
+			Text   string `json:"text"`
+		} `json:"spines"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode vision response: %w", err)
+	}
+	spines := make([]Spine, 0, len(result.Spines))
+	for _, s := range result.Spines {
+		spines = append(spines, Spine{Box: image.Rect(s.X, s.Y, s.X+s.Width, s.Y+s.Height), Text: s.Text})
+	}
+	return spines, nil
+}
+
+// CropSpines cuts the spines out of photo as PNGs for an Engine to read.
+// Spines taller than wide are turned a quarter counterclockwise, so text
+// running down them reads left to right. Boxes are clipped to the photo; a
+// spine with nothing left of it has no image.
+func CropSpines(photo []byte, spines []Spine) ([][]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(photo))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode photo: %w", err)
+	}
+	crops := make([][]byte, len(spines))
+	for i, spine := range spines {
+		box := spine.Box.Intersect(img.Bounds())
+		if box.Empty() {
+			continue
+		}
+		var crop image.Image = cropImage(img, box)
+		if box.Dy() > box.Dx() {
+			crop = rotateCounterclockwise(crop)
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, crop); err != nil {
+			return nil, fmt.Errorf("failed to encode spine: %w", err)
+		}
+		crops[i] = buf.Bytes()
+	}
+	return crops, nil
+}
+
+func cropImage(img image.Image, box image.Rectangle) *image.RGBA {
+	crop := image.NewRGBA(image.Rect(0, 0, box.Dx(), box.Dy()))
+	draw.Draw(crop, crop.Bounds(), img, box.Min, draw.Src)
+	return crop
+}
+
+func rotateCounterclockwise(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	rotated := image.NewRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			rotated.Set(y-b.Min.Y, b.Max.X-1-x, img.At(x, y))
+		}
+	}
+	return rotated
+}
@@ -0,0 +1,67 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPSegmenter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"spines": [{"x": 10, "y": 0, "width": 40, "height": 300, "text": "DUNE HERBERT"}, {"x": 50, "y": 5, "width": 30, "height": 290}]}`))
+	}))
+	defer server.Close()
+
+	segmenter := &HTTPSegmenter{URL: server.URL, HTTPClient: server.Client()}
+	spines, err := segmenter.Segment(context.Background(), []byte("jpeg"), "image/jpeg")
+	if err != nil || len(spines) != 2 {
+		t.Fatalf("Segment = %+v, %v", spines, err)
+	}
+	if spines[0].Box != image.Rect(10, 0, 50, 300) || spines[0].Text != "DUNE HERBERT" || spines[1].Text != "" {
+		t.Errorf("Unexpected spines %+v", spines)
+	}
+}
+
+func TestCropSpines(t *testing.T) {
+	// A 4x3 photo whose pixels are told apart by their red value
+	photo := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			photo.Set(x, y, color.RGBA{R: uint8(10*y + x), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, photo)
+
+	crops, err := CropSpines(buf.Bytes(), []Spine{{Box: image.Rect(1, 0, 2, 3)}, {Box: image.Rect(0, 1, 4, 2)}, {Box: image.Rect(9, 9, 12, 12)}})
+	if err != nil || len(crops) != 3 {
+		t.Fatalf("CropSpines = %d crops, %v", len(crops), err)
+	}
+	if crops[2] != nil {
+		t.Error("Expected a spine off the photo to have no image")
+	}
+
+	// The tall spine is turned so its top is on the left
+	spine, err := png.Decode(bytes.NewReader(crops[0]))
+	if err != nil || spine.Bounds() != image.Rect(0, 0, 3, 1) {
+		t.Fatalf("Expected a 3x1 spine, got %v, %v", spine.Bounds(), err)
+	}
+	for x, want := range []uint8{1, 11, 21} {
+		if r, _, _, _ := spine.At(x, 0).RGBA(); uint8(r>>8) != want {
+			t.Errorf("Pixel %d of the turned spine is %d, want %d", x, r>>8, want)
+		}
+	}
+	wide, err := png.Decode(bytes.NewReader(crops[1]))
+	if err != nil || wide.Bounds() != image.Rect(0, 0, 4, 1) {
+		t.Errorf("Expected the wide spine kept as it is, got %v, %v", wide.Bounds(), err)
+	}
+
+	if _, err := CropSpines([]byte("not an image"), nil); err == nil {
+		t.Error("Expected an undecodable photo to fail")
+	}
+}
@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Buckets are token buckets of their own for each client, such as an API key
+// or an address, limiting inbound requests. Unlike a Limiter they don't
+// queue: a request finding its bucket empty is refused. The zero value is not
+// usable; create one with NewBuckets.
+type Buckets struct {
+	rate  float64 // Tokens added per second
+	burst float64 // Bucket capacity
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweepAt int // Number of buckets at which full ones are dropped
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Quota is the state of a client's bucket after taking a token from it.
+type Quota struct {
+	Allowed   bool          // Whether there was a token to take
+	Limit     int           // Bucket capacity
+	Remaining int           // Tokens left
+	Reset     time.Duration // Until the bucket is full again
+	// RetryAfter is how long until the next token, when refused.
+	RetryAfter time.Duration
+}
+
+// NewBuckets creates buckets allowing each client rate requests per second on
+// average, with bursts of up to burst requests. Buckets start full.
+func NewBuckets(rate float64, burst int) *Buckets {
+	return NewBucketsWithClock(rate, burst, time.Now)
+}
+
+// NewBucketsWithClock is NewBuckets telling the time with now, such as a
+// fixed clock in tests.
+func NewBucketsWithClock(rate float64, burst int, now func() time.Time) *Buckets {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Buckets{rate: rate, burst: float64(burst), buckets: map[string]*bucket{}, sweepAt: 1024, now: now}
+}
+
+// Take takes a token from the bucket of key, if it has one.
+func (b *Buckets) Take(key string) Quota {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	bk, ok := b.buckets[key]
+	if !ok {
+		if len(b.buckets) >= b.sweepAt {
+			b.sweep(now)
+		}
+		bk = &bucket{tokens: b.burst, last: now}
+		b.buckets[key] = bk
+	}
+	b.refill(bk, now)
+
+	q := Quota{Limit: int(b.burst)}
+	if bk.tokens >= 1 {
+		bk.tokens--
+		q.Allowed = true
+	} else {
+		q.RetryAfter = b.until(1 - bk.tokens)
+	}
+	q.Remaining = int(math.Floor(bk.tokens))
+	q.Reset = b.until(b.burst - bk.tokens)
+	return q
+}
+
+// refill adds the tokens bk earned since it was last used.
+func (b *Buckets) refill(bk *bucket, now time.Time) {
+	if elapsed := now.Sub(bk.last).Seconds(); elapsed > 0 {
+		bk.tokens = min(b.burst, bk.tokens+elapsed*b.rate)
+	}
+	bk.last = now
+}
+
+// until returns how long the given number of tokens takes to earn.
+func (b *Buckets) until(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / b.rate * float64(time.Second))
+}
+
+// sweep drops the buckets that have refilled, as new ones start full anyway,
+// so clients that come and go don't pile up. Callers hold b.mu.
+func (b *Buckets) sweep(now time.Time) {
+	for key, bk := range b.buckets {
+		if b.refill(bk, now); bk.tokens >= b.burst {
+			delete(b.buckets, key)
+		}
+	}
+	b.sweepAt = max(1024, 2*len(b.buckets))
+}
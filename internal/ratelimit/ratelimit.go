@@ -0,0 +1,204 @@
+// Package ratelimit paces outbound requests to a shared upstream with a token
+// bucket. Requests that find the bucket empty wait in a queue; interactive
+// requests (a user waiting on a search) are always served before background
+// jobs such as cover repair, so a long-running job can use the spare capacity
+// without ever starving the UI or pushing the instance over the upstream's limit.
+// Buckets limit inbound requests instead, per client.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Priority orders waiting requests. Higher priorities are served first.
+type Priority int
+
+const (
+	Background  Priority = iota // Bulk and scheduled jobs
+	Interactive                 // Requests made on behalf of a waiting user
+)
+
+// ErrQueueFull is returned when too many requests of a priority are already waiting.
+var ErrQueueFull = errors.New("rate limit queue is full")
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose requests are queued at priority p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom returns the priority set on ctx, defaulting to Interactive.
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return Interactive
+}
+
+// waiter is a request queued for a token.
+type waiter struct {
+	ready chan struct{} // Closed when the waiter has been given a token
+}
+
+// Limiter is a token bucket shared by every caller of an upstream. The zero
+// value is not usable; create one with New.
+type Limiter struct {
+	rate     float64 // Tokens added per second
+	burst    float64 // Bucket capacity
+	MaxQueue int     // Waiting requests allowed per priority; 0 for no limit
+
+	mu          sync.Mutex
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+	queues      [Interactive + 1][]*waiter
+	timer       *time.Timer
+}
+
+// New creates a limiter allowing rate requests per second on average, with
+// bursts of up to burst requests. The bucket starts full.
+func New(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Wait blocks until a token is available for a request at priority p, or ctx
+// is done.
+func (l *Limiter) Wait(ctx context.Context, p Priority) error {
+	if p < Background || p > Interactive {
+		p = Interactive
+	}
+	l.mu.Lock()
+	l.refill(time.Now())
+	if l.tokens >= 1 && !l.paused() && l.queued(p) == 0 {
+		l.tokens--
+		l.mu.Unlock()
+		return nil
+	}
+	if l.MaxQueue > 0 && len(l.queues[p]) >= l.MaxQueue {
+		l.mu.Unlock()
+		return ErrQueueFull
+	}
+	w := &waiter{ready: make(chan struct{})}
+	l.queues[p] = append(l.queues[p], w)
+	l.schedule()
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		select {
+		case <-w.ready:
+			// Granted while giving up; hand the token to the next waiter
+			l.tokens++
+			l.dispatch()
+		default:
+			l.remove(p, w)
+		}
+		return ctx.Err()
+	}
+}
+
+// PauseUntil stops handing out tokens until t, for example when the upstream
+// answers with Retry-After. Earlier pauses are extended, never shortened.
+func (l *Limiter) PauseUntil(t time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t.After(l.pausedUntil) {
+		l.pausedUntil = t
+		l.tokens = 0
+	}
+	l.schedule()
+}
+
+// Waiting returns the number of queued requests at each priority.
+func (l *Limiter) Waiting() (interactive, background int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.queues[Interactive]), len(l.queues[Background])
+}
+
+// refill adds the tokens earned since the last refill.
+func (l *Limiter) refill(now time.Time) {
+	if now.Before(l.pausedUntil) {
+		l.last = now
+		return
+	}
+	from := l.last
+	if from.Before(l.pausedUntil) {
+		from = l.pausedUntil
+	}
+	if elapsed := now.Sub(from).Seconds(); elapsed > 0 {
+		l.tokens = min(l.burst, l.tokens+elapsed*l.rate)
+	}
+	l.last = now
+}
+
+func (l *Limiter) paused() bool {
+	return time.Now().Before(l.pausedUntil)
+}
+
+// queued returns the number of requests waiting at priority p or higher.
+func (l *Limiter) queued(p Priority) int {
+	n := 0
+	for q := p; q <= Interactive; q++ {
+		n += len(l.queues[q])
+	}
+	return n
+}
+
+// dispatch hands available tokens to waiters, highest priority first, and
+// schedules the next dispatch if any remain. Callers hold l.mu.
+func (l *Limiter) dispatch() {
+	l.refill(time.Now())
+	for p := Interactive; p >= Background; p-- {
+		for len(l.queues[p]) > 0 && l.tokens >= 1 && !l.paused() {
+			w := l.queues[p][0]
+			l.queues[p] = l.queues[p][1:]
+			l.tokens--
+			close(w.ready)
+		}
+	}
+	l.schedule()
+}
+
+// schedule arranges for dispatch to run when the next token is due, if anyone
+// is waiting. Callers hold l.mu.
+func (l *Limiter) schedule() {
+	if l.timer != nil || l.queued(Background) == 0 {
+		return
+	}
+	var delay time.Duration
+	if l.paused() {
+		delay = time.Until(l.pausedUntil)
+	}
+	if l.tokens < 1 && l.rate > 0 {
+		delay += time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	}
+	l.timer = time.AfterFunc(delay, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.timer = nil
+		l.dispatch()
+	})
+}
+
+// remove drops w from the queue for priority p. Callers hold l.mu.
+func (l *Limiter) remove(p Priority, w *waiter) {
+	queue := l.queues[p]
+	for i := range queue {
+		if queue[i] == w {
+			l.queues[p] = append(queue[:i:i], queue[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiterBurstThenRate(t *testing.T) {
+	l := New(20, 3)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(ctx, Background); err != nil {
+			t.Fatalf("Wait failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("Burst of 3 took %v, want no waiting", elapsed)
+	}
+	if err := l.Wait(ctx, Background); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Fourth request after %v, want it to wait for a token (~50ms)", elapsed)
+	}
+}
+
+func TestLimiterInteractiveFirst(t *testing.T) {
+	l := New(20, 1)
+	ctx := context.Background()
+	l.Wait(ctx, Interactive) // Empty the bucket
+
+	order := make(chan string, 4)
+	for i, name := range []string{"bg1", "bg2"} {
+		go func() {
+			l.Wait(ctx, Background)
+			order <- name
+		}()
+		waitForQueue(t, l, 0, i+1)
+	}
+	go func() {
+		l.Wait(ctx, Interactive)
+		order <- "ui"
+	}()
+	waitForQueue(t, l, 1, 2)
+
+	if first := <-order; first != "ui" {
+		t.Errorf("First request served was %s, want the interactive one", first)
+	}
+	if second, third := <-order, <-order; second != "bg1" || third != "bg2" {
+		t.Errorf("Background requests served as %s, %s; want them in arrival order", second, third)
+	}
+}
+
+// waitForQueue waits until the limiter has the given numbers of waiters.
+func waitForQueue(t *testing.T, l *Limiter, interactive, background int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		ui, bg := l.Waiting()
+		if ui == interactive && bg == background {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %d interactive and %d background waiters", interactive, background)
+}
+
+func TestLimiterCancelAndQueueLimit(t *testing.T) {
+	l := New(1, 1)
+	l.MaxQueue = 1
+	l.Wait(context.Background(), Interactive)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- l.Wait(ctx, Background) }()
+	waitForQueue(t, l, 0, 1)
+
+	if err := l.Wait(context.Background(), Background); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the cancelled wait to fail, got %v", err)
+	}
+	if ui, bg := l.Waiting(); ui != 0 || bg != 0 {
+		t.Errorf("Cancelled waiter still queued: %d interactive, %d background", ui, bg)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	l := New(100, 10)
+	client := Limit(&http.Client{}, l, "127.0.0.1")
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// The 429 paused the limiter, so the next request has to wait it out
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(WithPriority(ctx, Background), http.MethodGet, server.URL, nil)
+	if _, err := client.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the request to wait for Retry-After, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Upstream called %d times during the pause, want 1", calls)
+	}
+
+	tr := client.Transport.(*Transport)
+	for host, want := range map[string]bool{"127.0.0.1": true, "openlibrary.org": false} {
+		if got := tr.limits(host); got != want {
+			t.Errorf("limits(%q) = %v, want %v", host, got, want)
+		}
+	}
+	tr.Hosts = []string{"openlibrary.org"}
+	for host, want := range map[string]bool{"covers.openlibrary.org": true, "OpenLibrary.org": true, "notopenlibrary.org": false} {
+		if got := tr.limits(host); got != want {
+			t.Errorf("limits(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"30", 30 * time.Second, true},
+		{now.Add(2 * time.Minute).Format(http.TimeFormat), 2 * time.Minute, true},
+		{"86400", maxPause, true},
+		{"0", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	b := NewBuckets(0.5, 2) // A token every 2 seconds
+	now := time.Unix(1000, 0)
+	b.now = func() time.Time { return now }
+
+	for i, want := range []Quota{
+		{Allowed: true, Limit: 2, Remaining: 1, Reset: 2 * time.Second},
+		{Allowed: true, Limit: 2, Remaining: 0, Reset: 4 * time.Second},
+		{Allowed: false, Limit: 2, Remaining: 0, Reset: 4 * time.Second, RetryAfter: 2 * time.Second},
+	} {
+		if got := b.Take("alice"); got != want {
+			t.Errorf("Take %d = %+v, want %+v", i, got, want)
+		}
+	}
+	// Clients have buckets of their own
+	if q := b.Take("bob"); !q.Allowed || q.Remaining != 1 {
+		t.Errorf("Expected bob to have a full bucket, got %+v", q)
+	}
+	now = now.Add(3 * time.Second)
+	if q := b.Take("alice"); !q.Allowed || q.Remaining != 0 {
+		t.Errorf("Expected alice to have earned a token, got %+v", q)
+	}
+
+	// Refilled buckets are dropped as clients pile up
+	b.sweepAt = 2
+	now = now.Add(time.Minute)
+	b.Take("carol")
+	if len(b.buckets) != 1 {
+		t.Errorf("Expected only carol's bucket after a sweep, got %d", len(b.buckets))
+	}
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPause caps how long a Retry-After header can stop all requests.
+const maxPause = 10 * time.Minute
+
+// Transport waits for the limiter before sending requests to the limited
+// hosts; requests to any other host pass straight through. The priority is
+// taken from the request context (see WithPriority). When a limited host
+// answers 429 or 503 with Retry-After, the whole limiter pauses, since the
+// upstream is telling every caller to slow down, not just this one.
+type Transport struct {
+	Limiter *Limiter
+	Hosts   []string          // Hosts to limit, matched with their subdomains
+	Base    http.RoundTripper // nil uses http.DefaultTransport
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if !t.limits(req.URL.Hostname()) {
+		return base.RoundTrip(req)
+	}
+	if err := t.Limiter.Wait(req.Context(), PriorityFrom(req.Context())); err != nil {
+		return nil, err
+	}
+	resp, err := base.RoundTrip(req)
+	if err == nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		if wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			slog.Warn("Upstream asked to slow down", "host", req.URL.Hostname(), "status", resp.StatusCode, "retryAfter", wait)
+			t.Limiter.PauseUntil(time.Now().Add(wait))
+		}
+	}
+	return resp, err
+}
+
+func (t *Transport) limits(host string) bool {
+	host = strings.ToLower(host)
+	for _, h := range t.Hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After value in seconds or as an HTTP date.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	var wait time.Duration
+	if secs, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(secs) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		wait = at.Sub(now)
+	} else {
+		return 0, false
+	}
+	if wait <= 0 {
+		return 0, false
+	}
+	return min(wait, maxPause), true
+}
+
+// Limit makes client wait for limiter before requests to hosts. It returns
+// client for convenience.
+func Limit(client *http.Client, limiter *Limiter, hosts ...string) *http.Client {
+	client.Transport = &Transport{Limiter: limiter, Hosts: hosts, Base: client.Transport}
+	return client
+}
@@ -0,0 +1,76 @@
+// Package requestid tells the requests the server handles apart in its logs.
+// Each request gets an ID, carried in its context, and log records made with
+// that context (slog.InfoContext and the like) are tagged with it, so the
+// SQL queries and errors of one request can be picked out from the rest.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"regexp"
+)
+
+// Header is the header request IDs are read from and sent back in.
+const Header = "X-Request-ID"
+
+// Key is the attribute log records carry the request ID in.
+const Key = "request_id"
+
+// valid matches the IDs accepted from clients and proxies: short, and without
+// characters that could pass for other fields in a text log.
+var valid = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type contextKey struct{}
+
+// New returns a random request ID.
+func New() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Valid reports whether id can be used as a request ID, as when a reverse
+// proxy has already assigned one.
+func Valid(id string) bool {
+	return valid.MatchString(id)
+}
+
+// NewContext returns a copy of ctx carrying the request ID id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID ctx carries, or "".
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// handler adds the request ID of their context to log records.
+type handler struct {
+	slog.Handler
+}
+
+// NewHandler returns a slog handler tagging the records made with a
+// request's context with its ID, as request_id, before passing them to h.
+func NewHandler(h slog.Handler) slog.Handler {
+	return handler{h}
+}
+
+func (h handler) Handle(ctx context.Context, r slog.Record) error {
+	if id := FromContext(ctx); id != "" {
+		r = r.Clone()
+		r.AddAttrs(slog.String(Key, id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return handler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h handler) WithGroup(name string) slog.Handler {
+	return handler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,47 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewTextHandler(&out, nil))).With("component", "db")
+
+	ctx := NewContext(context.Background(), "abc123")
+	if got := FromContext(ctx); got != "abc123" {
+		t.Errorf("FromContext = %q, want abc123", got)
+	}
+	logger.InfoContext(ctx, "SQL: Executing GetBooks query")
+	if !strings.Contains(out.String(), "component=db request_id=abc123") {
+		t.Errorf("Expected the record to carry the request ID, got %q", out.String())
+	}
+
+	out.Reset()
+	logger.InfoContext(context.Background(), "Database connection successful")
+	if strings.Contains(out.String(), Key) {
+		t.Errorf("Expected no request ID outside a request, got %q", out.String())
+	}
+}
+
+func TestValid(t *testing.T) {
+	if id := New(); len(id) != 16 || !Valid(id) || id == New() {
+		t.Errorf("New returned %q", id)
+	}
+	for id, want := range map[string]bool{
+		"f3a9-41c2":                            true,
+		"9b2f3c1e-8a4d-4e5f-b6a7-1c2d3e4f5a6b": true,
+		"":                                     false,
+		"two words":                            false,
+		"line\nbreak":                          false,
+		strings.Repeat("a", 129):               false,
+	} {
+		if got := Valid(id); got != want {
+			t.Errorf("Valid(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Package safehttp makes HTTP clients for the URLs users hand the server, such
+// as followed feeds, webhooks and remote actors, which refuse to connect to
+// the networks the server itself is on: loopback, private, link-local (such
+// as a cloud's metadata service at 169.254.169.254) and unspecified addresses.
+// Addresses are checked as connections are made, once host names are
+// resolved, so neither a name resolving to them nor a redirect to them gets
+// through.
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned, wrapped, for connections refused because
+// they were to one of the server's own networks.
+var ErrForbiddenAddress = errors.New("address is not publicly routable")
+
+// forbidden are the ranges refused besides those netip classifies: addresses
+// shared by carrier-grade NATs, which Tailscale uses too, and "this network".
+var forbidden = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("0.0.0.0/8"),
+}
+
+// NewClient returns an HTTP client with timeout that refuses to connect to
+// the server's own networks.
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: NewTransport()}
+}
+
+// NewTransport returns a transport like http.DefaultTransport that refuses to
+// connect to the server's own networks. It uses no proxy, since the proxy
+// rather than the host would be connected to and checked.
+func NewTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: control}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// control refuses connections to forbidden addresses, for net.Dialer.
+func control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("unexpected address %q: %w", address, err)
+	}
+	if !Allowed(addrPort.Addr()) {
+		return fmt.Errorf("connecting to %s: %w", addrPort.Addr(), ErrForbiddenAddress)
+	}
+	return nil
+}
+
+// Allowed reports whether addr may be connected to: whether it is a public
+// unicast address.
+func Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range forbidden {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+// Package secrets encrypts credentials (OAuth tokens, app passwords) before
+// they are written to the database.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNoKey is returned when a Box is used without a configured secret key.
+var ErrNoKey = errors.New("no secret key configured")
+
+// Box seals and opens secrets with AES-256-GCM using a key derived from a passphrase.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// NewBox derives an encryption key from passphrase. An empty passphrase yields
+// a Box whose Seal and Open return ErrNoKey.
+func NewBox(passphrase string) (*Box, error) {
+	if passphrase == "" {
+		return &Box{}, nil
+	}
+	key := sha256.Sum256([]byte(passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Enabled reports whether the Box has a key.
+func (b *Box) Enabled() bool { return b != nil && b.aead != nil }
+
+// Seal encrypts plaintext and returns base64(nonce || ciphertext).
+func (b *Box) Seal(plaintext string) (string, error) {
+	if !b.Enabled() {
+		return "", ErrNoKey
+	}
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal.
+func (b *Box) Open(encoded string) (string, error) {
+	if !b.Enabled() {
+		return "", ErrNoKey
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid sealed value: %w", err)
+	}
+	if len(data) < b.aead.NonceSize() {
+		return "", errors.New("sealed value too short")
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt sealed value (wrong secret key?)")
+	}
+	return string(plaintext), nil
+}
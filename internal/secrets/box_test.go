@@ -0,0 +1,47 @@
+package secrets
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBoxRoundTrip(t *testing.T) {
+	box, err := NewBox("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("NewBox failed: %v", err)
+	}
+
+	sealed, err := box.Seal("my-token")
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	if sealed == "my-token" {
+		t.Fatal("Sealed value must not equal plaintext")
+	}
+
+	opened, err := box.Open(sealed)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if opened != "my-token" {
+		t.Errorf("Expected my-token, got %s", opened)
+	}
+
+	other, _ := NewBox("another key")
+	if _, err := other.Open(sealed); err == nil {
+		t.Error("Expected error opening with the wrong key")
+	}
+}
+
+func TestBoxWithoutKey(t *testing.T) {
+	box, err := NewBox("")
+	if err != nil {
+		t.Fatalf("NewBox failed: %v", err)
+	}
+	if box.Enabled() {
+		t.Error("Expected box without key to be disabled")
+	}
+	if _, err := box.Seal("x"); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected ErrNoKey, got %v", err)
+	}
+}
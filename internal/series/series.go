@@ -0,0 +1,88 @@
+// Package series detects the series a book belongs to, and its position in
+// it, from the way titles and provider metadata commonly spell it out:
+// "Dune Messiah (Dune, #2)", "The Stormlight Archive, Book 1: The Way of
+// Kings", or an Open Library edition's "Dune chronicles ; 2".
+package series
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Match is a detected series and position.
+type Match struct {
+	Series string `json:"series"`
+	Index  int    `json:"series_index"`
+	Title  string `json:"title,omitempty"` // The title without the series part, when parsed from a title
+}
+
+// marker is the word or sign that introduces a position in a series.
+const marker = `(?:#|no\.?|nr\.?|bk\.?|book|vol\.?|volume|part|tome)`
+
+// number is a position: digits, or a spelled-out number up to twelve.
+const number = `(\d{1,4}|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve)`
+
+var (
+	// trailing matches a series in parentheses or brackets after the title:
+	// "Dune Messiah (Dune, #2)", "Emma [Austen Novels Book 4]".
+	trailing = regexp.MustCompile(`(?i)^(.*\S)\s*[(\[]\s*([^()\[\]]*?[^\s,;(\[])\s*[,;]?\s*` + marker + `\s*` + number + `\s*[)\]]$`)
+	// leading matches a series named before the title:
+	// "The Stormlight Archive, Book 1: The Way of Kings".
+	leading = regexp.MustCompile(`(?i)^(.*?[^\s,;])\s*[,;]?\s+` + marker + `\s*` + number + `\s*[:\-–—]\s+(\S.*)$`)
+	// provider matches series strings from catalog records:
+	// "Dune chronicles ; 2", "Wheel of Time, #3", "Discworld ; bk. 5".
+	provider = regexp.MustCompile(`(?i)^(.*?[^\s,;])\s*(?:[,;]\s*` + marker + `?|\s+` + marker + `)\s*` + number + `\.?$`)
+)
+
+var numberWords = map[string]int{
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+}
+
+// Parse detects a series in a book title. Fractional positions such as
+// novellas numbered "#2.5" are not matched, since a series index is a whole
+// number.
+func Parse(title string) (Match, bool) {
+	title = strings.TrimSpace(title)
+	if m := trailing.FindStringSubmatch(title); m != nil {
+		return newMatch(m[2], m[3], m[1])
+	}
+	if m := leading.FindStringSubmatch(title); m != nil {
+		return newMatch(m[1], m[2], m[3])
+	}
+	return Match{}, false
+}
+
+// ParseProvider detects a series in the series field of a provider record,
+// which names the series and usually, but not always, the position.
+func ParseProvider(s string) (Match, bool) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(s, "("), ")"))
+	if m := provider.FindStringSubmatch(s); m != nil {
+		return newMatch(m[1], m[2], "")
+	}
+	return Match{}, false
+}
+
+func newMatch(name, position, title string) (Match, bool) {
+	index, err := strconv.Atoi(position)
+	if err != nil {
+		index = numberWords[strings.ToLower(position)]
+	}
+	name = cleanName(name)
+	if index <= 0 || name == "" {
+		return Match{}, false
+	}
+	return Match{Series: name, Index: index, Title: strings.TrimSpace(title)}, true
+}
+
+// cleanName tidies a series name: whitespace is collapsed and a trailing
+// "series" is dropped, so "Discworld Series" and "Discworld" agree.
+func cleanName(name string) string {
+	words := strings.Fields(strings.Trim(name, " ,;:"))
+	if n := len(words); n > 1 && strings.EqualFold(words[n-1], "series") {
+		words = words[:n-1]
+	}
+	return strings.Join(words, " ")
+}
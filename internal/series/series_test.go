@@ -0,0 +1,142 @@
+package series
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		title string
+		want  Match
+		ok    bool
+	}{
+		{"Dune Messiah (Dune, #2)", Match{"Dune", 2, "Dune Messiah"}, true},
+		{"The Way of Kings (The Stormlight Archive #1)", Match{"The Stormlight Archive", 1, "The Way of Kings"}, true},
+		{"Guards! Guards! (Discworld Series, Book 8)", Match{"Discworld", 8, "Guards! Guards!"}, true},
+		{"Emma [Austen Novels; Vol. 4]", Match{"Austen Novels", 4, "Emma"}, true},
+		{"The Dragon Reborn (The Wheel of Time, Book Three)", Match{"The Wheel of Time", 3, "The Dragon Reborn"}, true},
+		{"The Stormlight Archive, Book 1: The Way of Kings", Match{"The Stormlight Archive", 1, "The Way of Kings"}, true},
+		{"Dune Chronicles #3 - Children of Dune", Match{"Dune Chronicles", 3, "Children of Dune"}, true},
+		{"Edgedancer (The Stormlight Archive, #2.5)", Match{}, false},
+		{"Catch-22", Match{}, false},
+		{"Nineteen Eighty-Four (Penguin Modern Classics)", Match{}, false},
+		{"The Book of Three", Match{}, false},
+		{"Something (Book 1)", Match{}, false},
+		{"Foo (Bar, #0)", Match{}, false},
+	} {
+		got, ok := Parse(tt.title)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v, %v", tt.title, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseProvider(t *testing.T) {
+	for _, tt := range []struct {
+		field string
+		want  Match
+		ok    bool
+	}{
+		{"Dune chronicles ; 2", Match{Series: "Dune chronicles", Index: 2}, true},
+		{"Wheel of Time, #3", Match{Series: "Wheel of Time", Index: 3}, true},
+		{"(Discworld series ; bk. 5)", Match{Series: "Discworld", Index: 5}, true},
+		{"Harry Potter book 1.", Match{Series: "Harry Potter", Index: 1}, true},
+		{"Foundation series", Match{}, false},
+		{"Penguin classics", Match{}, false},
+	} {
+		got, ok := ParseProvider(tt.field)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseProvider(%q) = %+v, %v; want %+v, %v", tt.field, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSuggesterRun(t *testing.T) {
+	ctx := context.Background()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+	if err := db.CreateSchema(database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	store := db.NewSQLiteBookStore(database)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/books/OLEDITIONM.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"series":["Dune chronicles ; 1"]}`)
+	})
+	mux.HandleFunc("/isbn/9780000000003.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"series":["Penguin classics"]}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	dune := "Dune"
+	two := 2
+	books := []*model.Book{
+		{Title: "Dune Messiah (Dune, #2)", Author: "Frank Herbert", OpenLibraryID: "OLMESSIAHW"},
+		{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OLEDITIONM"},
+		{Title: "Emma", Author: "Jane Austen", OpenLibraryID: "OLEMMAW", ISBN: "9780000000003"},
+		{Title: "Children of Dune (Dune, #3)", Author: "Frank Herbert", OpenLibraryID: "OLCHILDRENW", Series: &dune},
+		{Title: "God Emperor (Dune, #4)", Author: "Frank Herbert", OpenLibraryID: "OLGODW", Series: &dune, SeriesIndex: &two},
+	}
+	for _, b := range books {
+		b.Status = model.StatusRead
+		if _, err := store.AddBook(ctx, b); err != nil {
+			t.Fatalf("Failed to add book: %v", err)
+		}
+	}
+
+	suggester := NewSuggester(store)
+	suggester.OpenLibraryURL = server.URL
+	report, err := suggester.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Running || report.FinishedAt == nil || report.Checked != 4 || report.Error != "" {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	got := make(map[int64]Suggestion)
+	for _, s := range report.Suggestions {
+		got[s.BookID] = s
+	}
+	want := map[int64]Suggestion{
+		books[0].ID: {BookID: books[0].ID, Title: books[0].Title, Series: "Dune", SeriesIndex: 2, Source: SourceTitle},
+		books[1].ID: {BookID: books[1].ID, Title: "Dune", Series: "Dune chronicles", SeriesIndex: 1, Source: SourceOpenLibrary},
+		books[3].ID: {BookID: books[3].ID, Title: books[3].Title, Series: "Dune", SeriesIndex: 3, Source: SourceTitle, CurrentSeries: &dune},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d suggestions, got %+v", len(want), report.Suggestions)
+	}
+	for id, w := range want {
+		g := got[id]
+		if g.Title != w.Title || g.Series != w.Series || g.SeriesIndex != w.SeriesIndex || g.Source != w.Source ||
+			(w.CurrentSeries == nil) != (g.CurrentSeries == nil) {
+			t.Errorf("Book %d: got %+v, want %+v", id, g, w)
+		}
+	}
+
+	// Suggestions are only proposals
+	book, _ := store.GetBookByID(ctx, books[0].ID)
+	if book.Series != nil || book.SeriesIndex != nil {
+		t.Errorf("Run changed the book's series: %v, %v", book.Series, book.SeriesIndex)
+	}
+
+	// A book that already has the detected series gets no suggestion
+	three := 3
+	books[3].SeriesIndex = &three
+	if s, ok := suggester.Suggest(ctx, *books[3]); ok {
+		t.Errorf("Expected no suggestion for a book already in its series, got %+v", s)
+	}
+}
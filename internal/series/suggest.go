@@ -0,0 +1,227 @@
+package series
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/ratelimit"
+)
+
+// ErrRunning is returned when a suggestion run is started while another is in progress.
+var ErrRunning = fmt.Errorf("series suggestion is already running")
+
+// Source says where a suggestion was detected.
+type Source string
+
+const (
+	SourceTitle       Source = "title"       // Parsed from the book's title
+	SourceOpenLibrary Source = "openlibrary" // From the series field of the Open Library edition
+)
+
+// Suggestion proposes series fields for a book. Nothing is changed until the
+// suggestion is applied with the book details endpoint.
+type Suggestion struct {
+	BookID        int64   `json:"book_id"`
+	Title         string  `json:"title"`
+	Series        string  `json:"series"`
+	SeriesIndex   int     `json:"series_index"`
+	Source        Source  `json:"source"`
+	CurrentSeries *string `json:"current_series,omitempty"`
+}
+
+// Report summarizes a suggestion run.
+type Report struct {
+	Running     bool         `json:"running"`
+	StartedAt   *time.Time   `json:"started_at,omitempty"`
+	FinishedAt  *time.Time   `json:"finished_at,omitempty"`
+	Checked     int          `json:"checked"` // Books without a series position
+	Suggestions []Suggestion `json:"suggestions"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// Suggester detects series for the books in the library. Titles are parsed
+// first; when that finds nothing, the Open Library edition is consulted.
+// Only one run happens at a time; the report of the latest run is kept.
+type Suggester struct {
+	Store      db.BookStore
+	HTTPClient *http.Client
+
+	// OpenLibraryURL is the provider endpoint, overridable for tests. Empty
+	// disables provider lookups.
+	OpenLibraryURL string
+
+	mu     sync.Mutex
+	report Report
+}
+
+// NewSuggester creates a Suggester using the public Open Library endpoint.
+func NewSuggester(store db.BookStore) *Suggester {
+	return &Suggester{
+		Store:          store,
+		HTTPClient:     &http.Client{Timeout: 15 * time.Second},
+		OpenLibraryURL: "https://openlibrary.org",
+		report:         Report{Suggestions: []Suggestion{}},
+	}
+}
+
+// Suggest returns the series detected for book, if any. A suggestion that
+// matches what the book already has is not returned.
+func (s *Suggester) Suggest(ctx context.Context, book model.Book) (Suggestion, bool) {
+	source := SourceTitle
+	m, ok := Parse(book.Title)
+	if !ok {
+		source = SourceOpenLibrary
+		m, ok = s.lookup(ctx, book)
+	}
+	if !ok {
+		return Suggestion{}, false
+	}
+	if book.Series != nil && strings.EqualFold(*book.Series, m.Series) && book.SeriesIndex != nil && *book.SeriesIndex == m.Index {
+		return Suggestion{}, false
+	}
+	return Suggestion{
+		BookID:        book.ID,
+		Title:         book.Title,
+		Series:        m.Series,
+		SeriesIndex:   m.Index,
+		Source:        source,
+		CurrentSeries: book.Series,
+	}, true
+}
+
+// Report returns the progress or outcome of the latest run.
+func (s *Suggester) Report() Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report := s.report
+	report.Suggestions = append([]Suggestion{}, s.report.Suggestions...)
+	return report
+}
+
+// Start begins a suggestion run over the library in the background. It
+// returns ErrRunning if a run is already in progress.
+func (s *Suggester) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.report.Running {
+		return ErrRunning
+	}
+	now := time.Now().UTC()
+	s.report = Report{Running: true, StartedAt: &now, Suggestions: []Suggestion{}}
+	go s.run(ctx)
+	return nil
+}
+
+// Run makes suggestions for the library and waits for the result.
+func (s *Suggester) Run(ctx context.Context) (Report, error) {
+	if err := s.Start(ctx); err != nil {
+		return Report{}, err
+	}
+	for {
+		report := s.Report()
+		if !report.Running {
+			return report, nil
+		}
+		select {
+		case <-ctx.Done():
+			return report, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func (s *Suggester) run(ctx context.Context) {
+	ctx = ratelimit.WithPriority(ctx, ratelimit.Background)
+	books, err := s.Store.GetBooks(ctx)
+	if err != nil {
+		s.finish(fmt.Errorf("failed to load books: %w", err))
+		return
+	}
+	slog.Info("Starting series suggestion", "books", len(books))
+	for _, book := range books {
+		if ctx.Err() != nil {
+			break
+		}
+		if book.Series != nil && *book.Series != "" && book.SeriesIndex != nil {
+			continue // Series already set by hand or by an earlier suggestion
+		}
+		suggestion, ok := s.Suggest(ctx, book)
+		s.mu.Lock()
+		s.report.Checked++
+		if ok {
+			s.report.Suggestions = append(s.report.Suggestions, suggestion)
+		}
+		s.mu.Unlock()
+	}
+	s.finish(ctx.Err())
+}
+
+func (s *Suggester) finish(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	s.report.Running = false
+	s.report.FinishedAt = &now
+	if err != nil {
+		s.report.Error = err.Error()
+	}
+	slog.Info("Series suggestion finished", "checked", s.report.Checked, "suggestions", len(s.report.Suggestions), "error", err)
+}
+
+// lookup reads the series of the book's Open Library edition, found by its
+// edition ID or ISBN.
+func (s *Suggester) lookup(ctx context.Context, book model.Book) (Match, bool) {
+	var target string
+	switch {
+	case s.OpenLibraryURL == "":
+		return Match{}, false
+	case strings.HasSuffix(book.OpenLibraryID, "M"):
+		target = fmt.Sprintf("%s/books/%s.json", s.OpenLibraryURL, url.PathEscape(book.OpenLibraryID))
+	case book.ISBN != "":
+		target = fmt.Sprintf("%s/isbn/%s.json", s.OpenLibraryURL, url.PathEscape(book.ISBN))
+	default:
+		return Match{}, false
+	}
+
+	var edition struct {
+		Series []string `json:"series"`
+	}
+	if err := s.getJSON(ctx, target, &edition); err != nil {
+		slog.Debug("Open Library edition lookup failed", "id", book.ID, "url", target, "error", err)
+		return Match{}, false
+	}
+	for _, field := range edition.Series {
+		if m, ok := ParseProvider(field); ok {
+			return m, true
+		}
+	}
+	return Match{}, false
+}
+
+func (s *Suggester) getJSON(ctx context.Context, target string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "BookshelfApp/1.0 (github.com/ericdahl/bookshelf)")
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("returned status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
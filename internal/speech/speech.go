@@ -0,0 +1,171 @@
+// Package speech transcribes short voice clips through a pluggable
+// Transcriber, for adding books hands-free ("add Project Hail Mary by Andy
+// Weir"). Transcribers are OpenAI's transcription API, or any service
+// compatible with it such as a local Whisper server, and plain speech-to-text
+// services spoken to over HTTP.
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// Transcriber turns speech into text.
+type Transcriber interface {
+	Name() string
+	// Transcribe returns what is said in audio.
+	Transcribe(ctx context.Context, audio []byte, contentType string) (string, error)
+}
+
+// OpenAIName is the name of the OpenAI transcriber.
+const OpenAIName = "openai"
+
+// OpenAIURL is the endpoint of OpenAI's transcription API.
+const OpenAIURL = "https://api.openai.com/v1/audio/transcriptions"
+
+// OpenAI uploads clips to an OpenAI-compatible transcription endpoint as
+// multipart forms with the model to use.
+type OpenAI struct {
+	URL        string // Defaults to OpenAIURL
+	APIKey     string // Sent as a bearer token; optional for local servers
+	Model      string // Defaults to "whisper-1"
+	HTTPClient *http.Client
+}
+
+// Name returns "openai".
+func (o *OpenAI) Name() string { return OpenAIName }
+
+// Transcribe uploads audio to the endpoint.
+func (o *OpenAI) Transcribe(ctx context.Context, audio []byte, contentType string) (string, error) {
+	endpoint, model := o.URL, o.Model
+	if endpoint == "" {
+		endpoint = OpenAIURL
+	}
+	if model == "" {
+		model = "whisper-1"
+	}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("model", model)
+	form.WriteField("response_format", "json")
+	// The endpoint tells formats apart by the file name's extension
+	part, err := form.CreateFormFile("file", "clip"+extension(contentType))
+	if err != nil {
+		return "", fmt.Errorf("failed to create transcription request: %w", err)
+	}
+	part.Write(audio)
+	form.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create transcription request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if o.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+	return decodeText(o.HTTPClient, req)
+}
+
+// extensions are the file name extensions of the clip formats browsers and
+// phones record, which the system's MIME tables don't always know.
+var extensions = map[string]string{
+	"audio/webm":      ".webm",
+	"video/webm":      ".webm",
+	"audio/ogg":       ".ogg",
+	"application/ogg": ".ogg",
+	"audio/mpeg":      ".mp3",
+	"audio/mp4":       ".m4a",
+	"audio/x-m4a":     ".m4a",
+	"audio/wav":       ".wav",
+	"audio/x-wav":     ".wav",
+	"audio/wave":      ".wav",
+	"audio/flac":      ".flac",
+}
+
+// extension returns the file name extension of clips of contentType.
+func extension(contentType string) string {
+	if ext, ok := extensions[contentType]; ok {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// HTTPName is the name of the HTTP transcriber.
+const HTTPName = "http"
+
+// HTTPTranscriber posts clips to a speech-to-text service, such as a small
+// adapter in front of a cloud speech API. The service receives the clip as
+// the request body with its content type and answers with {"text": "..."}.
+type HTTPTranscriber struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// Name returns "http".
+func (h *HTTPTranscriber) Name() string { return HTTPName }
+
+// Transcribe sends audio to the service.
+func (h *HTTPTranscriber) Transcribe(ctx context.Context, audio []byte, contentType string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(audio))
+	if err != nil {
+		return "", fmt.Errorf("failed to create transcription request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	return decodeText(h.HTTPClient, req)
+}
+
+// decodeText sends a transcription request and returns the "text" of the
+// JSON response.
+func decodeText(client *http.Client, req *http.Request) (string, error) {
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("transcription request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("speech-to-text service returned %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode transcription: %w", err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+// NewTranscriber returns the transcriber named by spec: "openai", which
+// needs apiKey, "openai:" followed by the URL of a compatible endpoint, or
+// the http(s) URL of a plain speech-to-text service. Requests are sent with
+// client.
+func NewTranscriber(spec, apiKey string, client *http.Client) (Transcriber, error) {
+	switch {
+	case spec == OpenAIName:
+		if apiKey == "" {
+			return nil, fmt.Errorf("the openai speech-to-text provider requires an API key")
+		}
+		return &OpenAI{APIKey: apiKey, HTTPClient: client}, nil
+	case strings.HasPrefix(spec, OpenAIName+":"):
+		endpoint := strings.TrimPrefix(spec, OpenAIName+":")
+		if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+			return nil, fmt.Errorf("expected openai:<url> with an http(s) URL, got %q", spec)
+		}
+		return &OpenAI{URL: endpoint, APIKey: apiKey, HTTPClient: client}, nil
+	case strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://"):
+		return &HTTPTranscriber{URL: spec, HTTPClient: client}, nil
+	}
+	return nil, fmt.Errorf("unknown speech-to-text provider %q, expected %q, openai:<url> or the URL of a speech-to-text service", spec, OpenAIName)
+}
@@ -0,0 +1,73 @@
+package speech
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk-test" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		audio, _ := io.ReadAll(file)
+		if r.FormValue("model") != "whisper-1" || string(audio) != "webm" || !strings.HasSuffix(header.Filename, ".webm") {
+			http.Error(w, "unexpected clip", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"text": " Add Project Hail Mary by Andy Weir. "})
+	}))
+	defer server.Close()
+
+	transcriber, err := NewTranscriber("openai:"+server.URL, "sk-test", server.Client())
+	if err != nil || transcriber.Name() != OpenAIName {
+		t.Fatalf("NewTranscriber = %v, %v", transcriber, err)
+	}
+	text, err := transcriber.Transcribe(context.Background(), []byte("webm"), "audio/webm")
+	if err != nil || text != "Add Project Hail Mary by Andy Weir." {
+		t.Errorf("Transcribe = %q, %v", text, err)
+	}
+	if _, err := transcriber.Transcribe(context.Background(), []byte("ogg"), "audio/ogg"); err == nil {
+		t.Error("Expected an error response to fail")
+	}
+	if _, err := NewTranscriber(OpenAIName, "", nil); err == nil {
+		t.Error("Expected openai without an API key to be rejected")
+	}
+}
+
+func TestHTTPTranscriber(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "audio/wav" || string(body) != "wav" {
+			http.Error(w, "unexpected clip", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"text": "Dune by Frank Herbert"})
+	}))
+	defer server.Close()
+
+	transcriber, err := NewTranscriber(server.URL, "", server.Client())
+	if err != nil || transcriber.Name() != HTTPName {
+		t.Fatalf("NewTranscriber = %v, %v", transcriber, err)
+	}
+	text, err := transcriber.Transcribe(context.Background(), []byte("wav"), "audio/wav")
+	if err != nil || text != "Dune by Frank Herbert" {
+		t.Errorf("Transcribe = %q, %v", text, err)
+	}
+	for _, spec := range []string{"magic", "openai:ftp://example.com"} {
+		if _, err := NewTranscriber(spec, "key", nil); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package tracker
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// goodreadsMaxPages bounds how many RSS pages (100 books each) are read per sync.
+const goodreadsMaxPages = 50
+
+// Goodreads reads a user's shelves from the public shelf RSS feed. Goodreads
+// no longer issues API keys, so pushing changes is not possible.
+type Goodreads struct {
+	BaseURL    string
+	UserID     string
+	HTTPClient *http.Client
+}
+
+type goodreadsRSS struct {
+	Channel struct {
+		Items []struct {
+			BookID      string `xml:"book_id"`
+			Title       string `xml:"title"`
+			AuthorName  string `xml:"author_name"`
+			ISBN        string `xml:"isbn"`
+			ISBN13      string `xml:"isbn13"`
+			UserRating  int    `xml:"user_rating"` // 0 when unrated, otherwise 1-5
+			UserShelves string `xml:"user_shelves"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// goodreadsStatus maps the comma-separated user_shelves field to a status.
+// Books on the "read" shelf report an empty shelf list.
+func goodreadsStatus(shelves string) model.BookStatus {
+	for _, shelf := range strings.Split(shelves, ",") {
+		switch strings.TrimSpace(shelf) {
+		case "currently-reading":
+			return model.StatusCurrentlyReading
+		case "to-read":
+			return model.StatusWantToRead
+		}
+	}
+	return model.StatusRead
+}
+
+func (g *Goodreads) page(ctx context.Context, n int) (*goodreadsRSS, error) {
+	u := fmt.Sprintf("%s/review/list_rss/%s?shelf=%s&page=%d", g.BaseURL, url.PathEscape(g.UserID), url.QueryEscape("#ALL#"), n)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "BookshelfApp/1.0 (github.com/ericdahl/bookshelf)")
+
+	resp, err := g.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("goodreads returned status %d", resp.StatusCode)
+	}
+
+	var doc goodreadsRSS
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 5*1024*1024)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse goodreads feed: %w", err)
+	}
+	return &doc, nil
+}
+
+// List returns the books on all of the user's Goodreads shelves.
+func (g *Goodreads) List(ctx context.Context) ([]RemoteBook, error) {
+	if _, err := strconv.ParseInt(g.UserID, 10, 64); err != nil {
+		return nil, fmt.Errorf("goodreads user ID must be numeric, got %q", g.UserID)
+	}
+
+	books := []RemoteBook{}
+	for n := 1; n <= goodreadsMaxPages; n++ {
+		doc, err := g.page(ctx, n)
+		if err != nil {
+			return nil, err
+		}
+		if len(doc.Channel.Items) == 0 {
+			break
+		}
+		for _, item := range doc.Channel.Items {
+			rb := RemoteBook{
+				RemoteID: item.BookID,
+				Title:    strings.TrimSpace(item.Title),
+				Author:   strings.TrimSpace(item.AuthorName),
+				Status:   goodreadsStatus(item.UserShelves),
+			}
+			for _, isbn := range []string{item.ISBN13, item.ISBN} {
+				if isbn = strings.TrimSpace(isbn); isbn != "" {
+					rb.ISBNs = append(rb.ISBNs, isbn)
+				}
+			}
+			if item.UserRating > 0 {
+				r := float64(item.UserRating)
+				rb.Rating = &r
+			}
+			books = append(books, rb)
+		}
+	}
+	return books, nil
+}
+
+// Push always fails; Goodreads has no write API available to new applications.
+func (g *Goodreads) Push(ctx context.Context, remoteID string, status model.BookStatus, rating *float64) error {
+	return ErrReadOnly
+}
@@ -0,0 +1,177 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// Hardcover talks to the Hardcover GraphQL API using a personal API token.
+type Hardcover struct {
+	URL        string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// Hardcover user_book status IDs. Other statuses (paused, did not finish) are not synced.
+var hardcoverStatuses = map[int]model.BookStatus{
+	1: model.StatusWantToRead,
+	2: model.StatusCurrentlyReading,
+	3: model.StatusRead,
+}
+
+func hardcoverStatusID(status model.BookStatus) (int, bool) {
+	for id, s := range hardcoverStatuses {
+		if s == status {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+func (h *Hardcover) query(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Tokens are shown on the Hardcover settings page with the "Bearer " prefix included
+	req.Header.Set("Authorization", "Bearer "+strings.TrimPrefix(h.Token, "Bearer "))
+
+	resp, err := h.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("hardcover returned status %d: %s", resp.StatusCode, msg)
+	}
+
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphQLError  `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to decode hardcover response: %w", err)
+	}
+	if len(envelope.Errors) > 0 {
+		return fmt.Errorf("hardcover error: %s", envelope.Errors[0].Message)
+	}
+	return json.Unmarshal(envelope.Data, out)
+}
+
+const hardcoverListQuery = `query {
+  me {
+    user_books {
+      id
+      status_id
+      rating
+      book { title contributions { author { name } } }
+      edition { isbn_10 isbn_13 }
+    }
+  }
+}`
+
+// List returns the books on the user's Hardcover shelves.
+func (h *Hardcover) List(ctx context.Context) ([]RemoteBook, error) {
+	var data struct {
+		Me []struct {
+			UserBooks []struct {
+				ID       int      `json:"id"`
+				StatusID int      `json:"status_id"`
+				Rating   *float64 `json:"rating"` // 0.5-5 in half steps
+				Book     struct {
+					Title         string `json:"title"`
+					Contributions []struct {
+						Author struct {
+							Name string `json:"name"`
+						} `json:"author"`
+					} `json:"contributions"`
+				} `json:"book"`
+				Edition *struct {
+					ISBN10 *string `json:"isbn_10"`
+					ISBN13 *string `json:"isbn_13"`
+				} `json:"edition"`
+			} `json:"user_books"`
+		} `json:"me"`
+	}
+	if err := h.query(ctx, hardcoverListQuery, nil, &data); err != nil {
+		return nil, err
+	}
+	if len(data.Me) == 0 {
+		return nil, fmt.Errorf("hardcover token is not associated with a user")
+	}
+
+	books := []RemoteBook{}
+	for _, ub := range data.Me[0].UserBooks {
+		status, ok := hardcoverStatuses[ub.StatusID]
+		if !ok {
+			continue
+		}
+		rb := RemoteBook{RemoteID: strconv.Itoa(ub.ID), Title: ub.Book.Title, Status: status}
+		if len(ub.Book.Contributions) > 0 {
+			rb.Author = ub.Book.Contributions[0].Author.Name
+		}
+		if ub.Edition != nil {
+			for _, isbn := range []*string{ub.Edition.ISBN13, ub.Edition.ISBN10} {
+				if isbn != nil && *isbn != "" {
+					rb.ISBNs = append(rb.ISBNs, *isbn)
+				}
+			}
+		}
+		if ub.Rating != nil && *ub.Rating > 0 {
+			rb.Rating = ub.Rating
+		}
+		books = append(books, rb)
+	}
+	return books, nil
+}
+
+const hardcoverUpdateMutation = `mutation($id: Int!, $object: UserBookUpdateInput!) {
+  update_user_book(id: $id, object: $object) { error }
+}`
+
+// Push updates the status and rating of an entry on the user's Hardcover shelves.
+func (h *Hardcover) Push(ctx context.Context, remoteID string, status model.BookStatus, rating *float64) error {
+	id, err := strconv.Atoi(remoteID)
+	if err != nil {
+		return fmt.Errorf("invalid hardcover user book ID %q", remoteID)
+	}
+	statusID, ok := hardcoverStatusID(status)
+	if !ok {
+		return fmt.Errorf("status %s has no hardcover equivalent", status)
+	}
+	object := map[string]interface{}{"status_id": statusID}
+	if rating != nil {
+		object["rating"] = *rating
+	}
+
+	var data struct {
+		UpdateUserBook struct {
+			Error *string `json:"error"`
+		} `json:"update_user_book"`
+	}
+	if err := h.query(ctx, hardcoverUpdateMutation, map[string]interface{}{"id": id, "object": object}, &data); err != nil {
+		return err
+	}
+	if data.UpdateUserBook.Error != nil && *data.UpdateUserBook.Error != "" {
+		return fmt.Errorf("hardcover error: %s", *data.UpdateUserBook.Error)
+	}
+	return nil
+}
@@ -0,0 +1,375 @@
+// Package tracker keeps the bookshelf in sync with external reading trackers
+// (Hardcover and Goodreads). Each sync is a three-way merge between the local
+// book, the remote entry and the state both agreed on after the previous sync;
+// when both sides changed, the account's conflict policy decides the winner.
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/match"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/secrets"
+)
+
+// ErrReadOnly is returned by clients whose provider does not accept writes.
+var ErrReadOnly = errors.New("provider does not support pushing changes")
+
+// RemoteBook is a book on a provider's shelves.
+type RemoteBook struct {
+	RemoteID string
+	Title    string
+	Author   string
+	ISBNs    []string
+	Status   model.BookStatus
+	Rating   *float64 // On the provider's RatingScale; nil when unrated
+}
+
+// Client reads and writes a single provider account. Ratings are on the
+// provider's own scale; the Syncer maps them to and from the bookshelf's.
+type Client interface {
+	List(ctx context.Context) ([]RemoteBook, error)
+	Push(ctx context.Context, remoteID string, status model.BookStatus, rating *float64) error
+}
+
+// Result summarizes a single account sync.
+type Result struct {
+	Pulled    int `json:"pulled"`
+	Pushed    int `json:"pushed"`
+	Conflicts int `json:"conflicts"`
+	Unmatched int `json:"unmatched"` // Remote books with no local counterpart
+	Queued    int `json:"queued"`    // Remote books waiting in the review queue for a weak match
+}
+
+// Syncer runs syncs for linked accounts.
+type Syncer struct {
+	Store      db.BookStore
+	Box        *secrets.Box // Decrypts provider tokens; nil when no secret key is configured
+	HTTPClient *http.Client
+	Thresholds match.Thresholds // Confidence required to link a remote book to a local one
+
+	// Provider endpoints, overridable for tests.
+	HardcoverURL string
+	GoodreadsURL string
+}
+
+// NewSyncer creates a Syncer using the public provider endpoints.
+func NewSyncer(store db.BookStore, box *secrets.Box) *Syncer {
+	return &Syncer{
+		Store:        store,
+		Box:          box,
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+		Thresholds:   match.DefaultThresholds,
+		HardcoverURL: "https://api.hardcover.app/v1/graphql",
+		GoodreadsURL: "https://www.goodreads.com",
+	}
+}
+
+// clientFor builds the provider client for account.
+func (s *Syncer) clientFor(account model.SyncAccount) (Client, error) {
+	switch account.Provider {
+	case model.ProviderHardcover:
+		token, err := s.Box.Open(account.EncryptedToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt token: %w", err)
+		}
+		return &Hardcover{URL: s.HardcoverURL, Token: token, HTTPClient: s.HTTPClient}, nil
+	case model.ProviderGoodreads:
+		return &Goodreads{BaseURL: s.GoodreadsURL, UserID: account.RemoteUser, HTTPClient: s.HTTPClient}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider %s", account.Provider)
+	}
+}
+
+// bookState is the subset of a book that is synced.
+type bookState struct {
+	Status model.BookStatus
+	Rating *int
+}
+
+func (a bookState) equal(b bookState) bool {
+	if a.Status != b.Status {
+		return false
+	}
+	if a.Rating == nil || b.Rating == nil {
+		return a.Rating == nil && b.Rating == nil
+	}
+	return *a.Rating == *b.Rating
+}
+
+// localRating maps a rating on the provider's scale to the bookshelf's by the
+// account's rules.
+func localRating(account model.SyncAccount, rating *float64) *int {
+	if rating == nil {
+		return nil
+	}
+	r := int(model.ConvertRating(*rating, account.Provider.RatingScale(), model.LocalRatingScale, account.RatingMapping, account.RatingRounding))
+	return &r
+}
+
+// remoteRating maps a rating on the bookshelf's scale to the provider's by
+// the account's rules.
+func remoteRating(account model.SyncAccount, rating *int) *float64 {
+	if rating == nil {
+		return nil
+	}
+	r := model.ConvertRating(float64(*rating), model.LocalRatingScale, account.Provider.RatingScale(), account.RatingMapping, account.RatingRounding)
+	return &r
+}
+
+// merge returns winner's state, keeping other's rating when winner is unrated
+// so that a missing rating never erases one on the other side.
+func merge(winner, other bookState) bookState {
+	if winner.Rating == nil {
+		winner.Rating = other.Rating
+	}
+	return winner
+}
+
+// SyncAccount syncs one account with its owner's library. The outcome is
+// recorded on the account and each change is written to the sync log.
+func (s *Syncer) SyncAccount(ctx context.Context, account model.SyncAccount) (Result, error) {
+	if account.UserID != nil {
+		ctx = db.WithUser(ctx, *account.UserID)
+	}
+	result, syncErr := s.sync(ctx, account)
+	// The outcome is recorded even when the sync was cut short by cancellation
+	ctx = context.WithoutCancel(ctx)
+
+	var errMsg *string
+	if syncErr != nil {
+		msg := syncErr.Error()
+		errMsg = &msg
+		s.log(ctx, account.ID, nil, model.SyncError, msg)
+		slog.Warn("Sync failed", "provider", account.Provider, "account", account.ID, "error", syncErr)
+	} else {
+		slog.Info("Sync complete", "provider", account.Provider, "account", account.ID,
+			"pulled", result.Pulled, "pushed", result.Pushed, "conflicts", result.Conflicts)
+	}
+	if err := s.Store.UpdateSyncAccountStatus(ctx, account.ID, time.Now().UTC(), errMsg); err != nil {
+		slog.Error("Failed to record sync status", "account", account.ID, "error", err)
+	}
+	return result, syncErr
+}
+
+func (s *Syncer) log(ctx context.Context, accountID int64, bookID *int64, direction model.SyncDirection, message string) {
+	entry := &model.SyncLogEntry{AccountID: accountID, BookID: bookID, Direction: direction, Message: message}
+	if err := s.Store.AddSyncLogEntry(ctx, entry); err != nil {
+		slog.Warn("Failed to write sync log", "account", accountID, "error", err)
+	}
+}
+
+func (s *Syncer) sync(ctx context.Context, account model.SyncAccount) (Result, error) {
+	var result Result
+
+	client, err := s.clientFor(account)
+	if err != nil {
+		return result, err
+	}
+	remoteBooks, err := client.List(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to list remote books: %w", err)
+	}
+
+	books, err := s.Store.GetBooks(ctx)
+	if err != nil {
+		return result, err
+	}
+	links, err := s.Store.GetSyncLinks(ctx, account.ID)
+	if err != nil {
+		return result, err
+	}
+
+	byID := make(map[int64]*model.Book)
+	for i := range books {
+		byID[books[i].ID] = &books[i]
+	}
+	index := match.NewIndex(books)
+	index.Thresholds = s.Thresholds
+	linkByRemote := make(map[string]model.SyncLink)
+	linkedBooks := make(map[int64]bool)
+	for _, l := range links {
+		linkByRemote[l.RemoteID] = l
+		linkedBooks[l.BookID] = true
+	}
+
+	for _, remote := range remoteBooks {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		link, linked := linkByRemote[remote.RemoteID]
+		var book *model.Book
+		if linked {
+			book = byID[link.BookID]
+		} else {
+			m := index.Match(match.Candidate{ISBNs: remote.ISBNs, Title: remote.Title, Author: remote.Author})
+			switch {
+			case m.Outcome == match.Review:
+				s.queueForReview(ctx, account, remote, m)
+				result.Queued++
+				continue
+			case m.Outcome == match.Matched && !linkedBooks[m.Best.Book.ID]:
+				book = byID[m.Best.Book.ID]
+			}
+		}
+		if book == nil {
+			result.Unmatched++
+			continue
+		}
+
+		local := bookState{book.Status, book.Rating}
+		theirs := bookState{remote.Status, localRating(account, remote.Rating)}
+		agreed := merge(local, theirs)
+
+		if !local.equal(theirs) {
+			base := bookState{link.Status, link.Rating}
+			localChanged := !linked || !local.equal(base)
+			remoteChanged := !linked || !theirs.equal(base)
+
+			pull := remoteChanged && !localChanged
+			if localChanged && remoteChanged {
+				result.Conflicts++
+				pull = account.ConflictPolicy == model.ConflictRemoteWins
+				winner := "local"
+				if pull {
+					winner = "remote"
+				}
+				s.log(ctx, account.ID, &book.ID, model.SyncConflict, fmt.Sprintf("%q changed on both sides (local %s, remote %s); keeping %s",
+					book.Title, local.Status, theirs.Status, winner))
+			}
+
+			if pull {
+				agreed = merge(theirs, local)
+			}
+
+			if !local.equal(agreed) {
+				if err := s.applyLocal(ctx, book, agreed); err != nil {
+					s.log(ctx, account.ID, &book.ID, model.SyncError, fmt.Sprintf("Failed to update %q: %v", book.Title, err))
+					continue
+				}
+				result.Pulled++
+				s.log(ctx, account.ID, &book.ID, model.SyncPull, fmt.Sprintf("Updated %q to %s", book.Title, agreed.Status))
+			}
+
+			if !agreed.equal(theirs) {
+				if account.Provider.CanPush() {
+					if err := client.Push(ctx, remote.RemoteID, agreed.Status, remoteRating(account, agreed.Rating)); err != nil {
+						s.log(ctx, account.ID, &book.ID, model.SyncError, fmt.Sprintf("Failed to push %q: %v", book.Title, err))
+						continue
+					}
+					result.Pushed++
+					s.log(ctx, account.ID, &book.ID, model.SyncPush, fmt.Sprintf("Pushed %q as %s", book.Title, agreed.Status))
+				} else {
+					// Read-only provider: remember the remote state so only its future changes are pulled
+					agreed = theirs
+				}
+			}
+		}
+
+		// Keep the rating as the provider gave it when the book took it, since
+		// mapping it to 1-10 may lose detail
+		if remote.Rating != nil && agreed.Rating != nil && *agreed.Rating == *theirs.Rating {
+			source := model.SourceRating{Provider: account.Provider, Value: *remote.Rating, Max: account.Provider.RatingScale().Max}
+			if book.SourceRating == nil || *book.SourceRating != source {
+				if err := s.Store.UpdateBook(ctx, book.ID, model.BookPatch{SourceRating: model.Some(source)}); err != nil {
+					s.log(ctx, account.ID, &book.ID, model.SyncError, fmt.Sprintf("Failed to record the %s rating of %q: %v", account.Provider, book.Title, err))
+				}
+			}
+		}
+
+		if err := s.Store.SaveSyncLink(ctx, model.SyncLink{
+			AccountID: account.ID, BookID: book.ID, RemoteID: remote.RemoteID, Status: agreed.Status, Rating: agreed.Rating,
+		}); err != nil {
+			return result, err
+		}
+		linkedBooks[book.ID] = true
+	}
+	return result, nil
+}
+
+// queueForReview adds a weakly matched remote book to the review queue. Books
+// already queued (or dismissed) for this account are left alone.
+func (s *Syncer) queueForReview(ctx context.Context, account model.SyncAccount, remote RemoteBook, m match.Result) {
+	pending := &model.PendingMatch{
+		Source:    model.SourceTrackerSync,
+		SourceRef: strconv.FormatInt(account.ID, 10),
+		ItemKey:   remote.RemoteID,
+		Item: model.PendingItem{
+			Title:    remote.Title,
+			Author:   remote.Author,
+			Status:   remote.Status,
+			RemoteID: remote.RemoteID,
+		},
+		Candidates: m.PendingCandidates(),
+	}
+	if len(remote.ISBNs) > 0 {
+		pending.Item.ISBN = remote.ISBNs[0]
+	}
+	queued, err := s.Store.AddPendingMatch(ctx, pending)
+	if err != nil {
+		slog.Warn("Failed to queue match for review", "account", account.ID, "remoteID", remote.RemoteID, "error", err)
+		return
+	}
+	if queued {
+		s.log(ctx, account.ID, &m.Best.Book.ID, model.SyncReview, fmt.Sprintf("%q may be %q (score %.2f); queued for review",
+			remote.Title, m.Best.Book.Title, m.Best.Score))
+	}
+}
+
+// applyLocal writes state to the local book, touching only the fields that differ.
+func (s *Syncer) applyLocal(ctx context.Context, book *model.Book, state bookState) error {
+	if book.Status != state.Status {
+		if err := s.Store.UpdateBookStatus(ctx, book.ID, state.Status); err != nil {
+			return err
+		}
+	}
+	if state.Rating != nil && (book.Rating == nil || *book.Rating != *state.Rating) {
+		if err := s.Store.UpdateBook(ctx, book.ID, model.BookPatch{Rating: model.Of(state.Rating)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncAll syncs every enabled account.
+func (s *Syncer) SyncAll(ctx context.Context) {
+	accounts, err := s.Store.GetSyncAccounts(ctx)
+	if err != nil {
+		slog.Error("Failed to list sync accounts", "error", err)
+		return
+	}
+	for _, account := range accounts {
+		if ctx.Err() != nil {
+			return
+		}
+		if account.Enabled {
+			s.SyncAccount(ctx, account)
+		}
+	}
+}
+
+// Run syncs all accounts every interval until ctx is cancelled.
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) {
+	slog.Info("Starting tracker sync", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.SyncAll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping tracker sync")
+			return
+		case <-ticker.C:
+			s.SyncAll(ctx)
+		}
+	}
+}
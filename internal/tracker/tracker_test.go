@@ -0,0 +1,271 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/secrets"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTestStore(t *testing.T) *db.SQLiteBookStore {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := db.CreateSchema(database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	return db.NewSQLiteBookStore(database)
+}
+
+func addBook(t *testing.T, store *db.SQLiteBookStore, title, isbn string, status model.BookStatus) *model.Book {
+	t.Helper()
+	book := &model.Book{Title: title, Author: "Author", OpenLibraryID: "OL-" + title, ISBN: isbn, Status: status}
+	if _, err := store.AddBook(context.Background(), book); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	return book
+}
+
+// fakeHardcover serves a mutable set of user books and records updates.
+type fakeHardcover struct {
+	mu      sync.Mutex
+	books   map[int]map[string]interface{}
+	updates []map[string]interface{}
+}
+
+func (f *fakeHardcover) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer hc-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if strings.HasPrefix(req.Query, "mutation") {
+		id := int(req.Variables["id"].(float64))
+		object := req.Variables["object"].(map[string]interface{})
+		f.updates = append(f.updates, object)
+		f.books[id]["status_id"] = object["status_id"]
+		if rating, ok := object["rating"]; ok {
+			f.books[id]["rating"] = rating
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"update_user_book": map[string]interface{}{"error": nil}}})
+		return
+	}
+	userBooks := []interface{}{}
+	for _, b := range f.books {
+		userBooks = append(userBooks, b)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]interface{}{"me": []interface{}{map[string]interface{}{"user_books": userBooks}}},
+	})
+}
+
+func hardcoverBook(id int, title, isbn string, statusID int, rating interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"id":        id,
+		"status_id": statusID,
+		"rating":    rating,
+		"book":      map[string]interface{}{"title": title, "contributions": []interface{}{map[string]interface{}{"author": map[string]string{"name": "Author"}}}},
+		"edition":   map[string]interface{}{"isbn_13": isbn},
+	}
+}
+
+func TestSyncHardcover(t *testing.T) {
+	ctx := context.Background()
+	store := setupTestStore(t)
+	pulled := addBook(t, store, "Pulled", "111", model.StatusWantToRead)
+	pushed := addBook(t, store, "Pushed", "222", model.StatusWantToRead)
+	conflicted := addBook(t, store, "Conflicted", "", model.StatusWantToRead)
+
+	fake := &fakeHardcover{books: map[int]map[string]interface{}{
+		1: hardcoverBook(1, "Pulled", "111", 1, nil),
+		2: hardcoverBook(2, "Pushed", "222", 1, nil),
+		3: hardcoverBook(3, "conflicted", "", 1, nil), // Matched by title
+		4: hardcoverBook(4, "Only Remote", "999", 1, nil),
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	box, _ := secrets.NewBox("key")
+	sealed, _ := box.Seal("hc-token")
+	account := &model.SyncAccount{Provider: model.ProviderHardcover, EncryptedToken: sealed, ConflictPolicy: model.ConflictRemoteWins, Enabled: true}
+	if _, err := store.AddSyncAccount(ctx, account); err != nil {
+		t.Fatalf("AddSyncAccount failed: %v", err)
+	}
+
+	syncer := NewSyncer(store, box)
+	syncer.HardcoverURL = server.URL
+	syncer.HTTPClient = server.Client()
+
+	// First sync links matching books; everything already agrees
+	result, err := syncer.SyncAccount(ctx, *account)
+	if err != nil {
+		t.Fatalf("SyncAccount failed: %v", err)
+	}
+	if result.Unmatched != 1 || result.Pulled+result.Pushed+result.Conflicts != 0 {
+		t.Errorf("Unexpected first sync result: %+v", result)
+	}
+
+	// Change each book: remote only, local only, and both sides
+	fake.books[1]["status_id"] = 3
+	fake.books[1]["rating"] = 4.5
+	store.UpdateBookStatus(ctx, pushed.ID, model.StatusCurrentlyReading)
+	fake.books[3]["status_id"] = 2
+	store.UpdateBookStatus(ctx, conflicted.ID, model.StatusRead)
+
+	result, err = syncer.SyncAccount(ctx, *account)
+	if err != nil {
+		t.Fatalf("SyncAccount failed: %v", err)
+	}
+	if result.Pulled != 2 || result.Pushed != 1 || result.Conflicts != 1 {
+		t.Errorf("Unexpected second sync result: %+v", result)
+	}
+
+	if b, _ := store.GetBookByID(ctx, pulled.ID); b.Status != model.StatusRead || b.Rating == nil || *b.Rating != 9 {
+		t.Errorf("Remote change not pulled: %+v", b)
+	}
+	if len(fake.updates) != 1 || fake.updates[0]["status_id"] != float64(2) {
+		t.Errorf("Local change not pushed: %+v", fake.updates)
+	}
+	if b, _ := store.GetBookByID(ctx, conflicted.ID); b.Status != model.StatusCurrentlyReading {
+		t.Errorf("Expected remote to win the conflict, got %s", b.Status)
+	}
+
+	// A third sync has nothing to do
+	result, err = syncer.SyncAccount(ctx, *account)
+	if err != nil {
+		t.Fatalf("SyncAccount failed: %v", err)
+	}
+	if result.Pulled+result.Pushed+result.Conflicts != 0 {
+		t.Errorf("Expected no changes on an idle sync, got %+v", result)
+	}
+
+	entries, err := store.ListSyncLog(ctx, account.ID, 10)
+	if err != nil {
+		t.Fatalf("ListSyncLog failed: %v", err)
+	}
+	counts := map[model.SyncDirection]int{}
+	for _, e := range entries {
+		counts[e.Direction]++
+	}
+	if counts[model.SyncPull] != 2 || counts[model.SyncPush] != 1 || counts[model.SyncConflict] != 1 {
+		t.Errorf("Unexpected sync log: %+v", entries)
+	}
+}
+
+func TestSyncGoodreadsIsPullOnly(t *testing.T) {
+	ctx := context.Background()
+	store := setupTestStore(t)
+	book := addBook(t, store, "Dune", "9780441013593", model.StatusWantToRead)
+	other := addBook(t, store, "Emma", "", model.StatusRead)
+
+	shelves := "to-read"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/review/list_rss/42" || r.URL.Query().Get("shelf") != "#ALL#" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("page") != "1" {
+			fmt.Fprint(w, `<rss><channel></channel></rss>`)
+			return
+		}
+		fmt.Fprintf(w, `<rss><channel>
+<item><book_id>7</book_id><title>Dune</title><author_name>Author</author_name><isbn>0441013597</isbn><isbn13>9780441013593</isbn13><user_rating>5</user_rating><user_shelves>%s</user_shelves></item>
+<item><book_id>8</book_id><title>Emma</title><author_name>Author</author_name><user_rating>0</user_rating><user_shelves>to-read</user_shelves></item>
+</channel></rss>`, shelves)
+	}))
+	defer server.Close()
+
+	account := &model.SyncAccount{Provider: model.ProviderGoodreads, RemoteUser: "42", ConflictPolicy: model.ConflictLocalWins, Enabled: true}
+	if _, err := store.AddSyncAccount(ctx, account); err != nil {
+		t.Fatalf("AddSyncAccount failed: %v", err)
+	}
+	syncer := NewSyncer(store, nil)
+	syncer.GoodreadsURL = server.URL
+	syncer.HTTPClient = server.Client()
+
+	// First sync: Dune differs only in rating and Emma differs in status; local wins both
+	if _, err := syncer.SyncAccount(ctx, *account); err != nil {
+		t.Fatalf("SyncAccount failed: %v", err)
+	}
+	if b, _ := store.GetBookByID(ctx, other.ID); b.Status != model.StatusRead {
+		t.Errorf("Local status should win the initial conflict, got %s", b.Status)
+	}
+
+	// Finishing the book on Goodreads is pulled on the next sync
+	shelves = ""
+	result, err := syncer.SyncAccount(ctx, *account)
+	if err != nil {
+		t.Fatalf("SyncAccount failed: %v", err)
+	}
+	if result.Pulled != 1 || result.Pushed != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if b, _ := store.GetBookByID(ctx, book.ID); b.Status != model.StatusRead || b.Rating == nil || *b.Rating != 10 {
+		t.Errorf("Goodreads change not pulled: %+v", b)
+	}
+}
+
+func TestGoodreadsPushIsReadOnly(t *testing.T) {
+	g := &Goodreads{}
+	if err := g.Push(context.Background(), "1", model.StatusRead, nil); err != ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestSyncMapsRatingsByAccount(t *testing.T) {
+	ctx := context.Background()
+	store := setupTestStore(t)
+	book := addBook(t, store, "Dune", "9780441013593", model.StatusRead)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "1" {
+			fmt.Fprint(w, `<rss><channel></channel></rss>`)
+			return
+		}
+		fmt.Fprint(w, `<rss><channel>
+<item><book_id>7</book_id><title>Dune</title><author_name>Author</author_name><isbn13>9780441013593</isbn13><user_rating>3</user_rating><user_shelves></user_shelves></item>
+</channel></rss>`)
+	}))
+	defer server.Close()
+
+	account := &model.SyncAccount{Provider: model.ProviderGoodreads, RemoteUser: "42", RatingMapping: model.MappingLinear, RatingRounding: model.RoundDown, Enabled: true}
+	if _, err := store.AddSyncAccount(ctx, account); err != nil {
+		t.Fatalf("AddSyncAccount failed: %v", err)
+	}
+	syncer := NewSyncer(store, nil)
+	syncer.GoodreadsURL = server.URL
+	syncer.HTTPClient = server.Client()
+	if _, err := syncer.SyncAccount(ctx, *account); err != nil {
+		t.Fatalf("SyncAccount failed: %v", err)
+	}
+
+	// Three stars fall halfway, at 5.5 of 10, and the account rounds down
+	b, _ := store.GetBookByID(ctx, book.ID)
+	if b.Rating == nil || *b.Rating != 5 {
+		t.Errorf("Expected 3 of 5 stars to map to 5, got %v", b.Rating)
+	}
+	want := model.SourceRating{Provider: model.ProviderGoodreads, Value: 3, Max: 5}
+	if b.SourceRating == nil || *b.SourceRating != want {
+		t.Errorf("Expected the Goodreads rating to be kept as %+v, got %+v", want, b.SourceRating)
+	}
+}
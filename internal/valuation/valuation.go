@@ -0,0 +1,174 @@
+// Package valuation samples the market value of the books owned, by ISBN,
+// from a pluggable Source, building up a price history for collectors. A
+// Sampler asks the source for every ISBN periodically and stores each answer
+// as a sample; the store turns the samples into series per book and for the
+// whole collection.
+package valuation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// ErrNoValue is returned by sources that know no market value for an ISBN.
+var ErrNoValue = errors.New("no market value known")
+
+// Price is a market value as a source reports it.
+type Price struct {
+	Value    float64
+	Currency string // ISO 4217, e.g. "USD"
+}
+
+// Source looks up what books are worth on the market, by ISBN.
+type Source interface {
+	Name() string
+	// Price returns the current market value of isbn, or ErrNoValue.
+	Price(ctx context.Context, isbn string) (Price, error)
+}
+
+// HTTPName is the name of the HTTP source.
+const HTTPName = "http"
+
+// HTTPSource asks a price service over HTTP, such as a small adapter in front
+// of a marketplace API. URL holds an {isbn} placeholder; the service answers
+// with {"value": 12.5, "currency": "USD"}, or 404 Not Found when it knows no
+// value.
+type HTTPSource struct {
+	URL        string // e.g. https://prices.example.com/isbn/{isbn}
+	Currency   string // Assumed when a response names none
+	HTTPClient *http.Client
+}
+
+// NewHTTPSource creates a source asking the service at url, which must hold
+// an {isbn} placeholder.
+func NewHTTPSource(url string, client *http.Client) (*HTTPSource, error) {
+	if !strings.Contains(url, "{isbn}") {
+		return nil, fmt.Errorf("value source URL %q has no {isbn} placeholder", url)
+	}
+	return &HTTPSource{URL: url, Currency: "USD", HTTPClient: client}, nil
+}
+
+// Name returns "http".
+func (h *HTTPSource) Name() string { return HTTPName }
+
+// Price asks the service for the value of isbn.
+func (h *HTTPSource) Price(ctx context.Context, isbn string) (Price, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.ReplaceAll(h.URL, "{isbn}", isbn), nil)
+	if err != nil {
+		return Price{}, fmt.Errorf("failed to create value request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := h.HTTPClient.Do(req)
+	if err != nil {
+		return Price{}, fmt.Errorf("value request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return Price{}, ErrNoValue
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Price{}, fmt.Errorf("value source returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var payload struct {
+		Value    *float64 `json:"value"`
+		Currency string   `json:"currency"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return Price{}, fmt.Errorf("failed to decode value response: %w", err)
+	}
+	if payload.Value == nil {
+		return Price{}, ErrNoValue
+	}
+	if *payload.Value < 0 {
+		return Price{}, fmt.Errorf("value source returned a negative value for %s", isbn)
+	}
+	price := Price{Value: *payload.Value, Currency: strings.ToUpper(strings.TrimSpace(payload.Currency))}
+	if price.Currency == "" {
+		price.Currency = h.Currency
+	}
+	return price, nil
+}
+
+// Result summarizes a sampling run.
+type Result struct {
+	Checked int `json:"checked"` // ISBNs asked for
+	Sampled int `json:"sampled"` // Of which a value was stored for
+	Missing int `json:"missing"` // Of which the source knew no value for
+	Failed  int `json:"failed"`
+}
+
+// Sampler stores the market value of every ISBN owned, as Source reports it.
+type Sampler struct {
+	Store  db.BookStore
+	Source Source
+}
+
+// NewSampler creates a Sampler asking source.
+func NewSampler(store db.BookStore, source Source) *Sampler {
+	return &Sampler{Store: store, Source: source}
+}
+
+// SampleAll takes a sample of the value of every ISBN owned by any user.
+// An ISBN the source fails on is skipped until the next run.
+func (s *Sampler) SampleAll(ctx context.Context) (Result, error) {
+	var result Result
+	isbns, err := s.Store.GetOwnedISBNs(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to list ISBNs: %w", err)
+	}
+	for _, isbn := range isbns {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		result.Checked++
+		price, err := s.Source.Price(ctx, isbn)
+		if errors.Is(err, ErrNoValue) {
+			result.Missing++
+			continue
+		}
+		if err == nil {
+			err = s.Store.AddMarketValue(ctx, &model.MarketValue{ISBN: isbn, Value: price.Value, Currency: price.Currency, Source: s.Source.Name()})
+		}
+		if err != nil {
+			slog.Warn("Failed to sample market value", "isbn", isbn, "source", s.Source.Name(), "error", err)
+			result.Failed++
+			continue
+		}
+		result.Sampled++
+	}
+	return result, nil
+}
+
+// Run samples every interval until ctx is cancelled.
+func (s *Sampler) Run(ctx context.Context, interval time.Duration) {
+	slog.Info("Starting market value sampling", "interval", interval, "source", s.Source.Name())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := s.SampleAll(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Market value sampling failed", "error", err)
+		} else {
+			slog.Info("Market values sampled", "checked", result.Checked, "sampled", result.Sampled,
+				"missing", result.Missing, "failed", result.Failed)
+		}
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping market value sampling")
+			return
+		case <-ticker.C:
+		}
+	}
+}
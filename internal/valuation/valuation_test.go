@@ -0,0 +1,110 @@
+package valuation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// stubSource knows the prices listed under an ISBN, and fails on the rest.
+type stubSource map[string]Price
+
+func (s stubSource) Name() string { return "stub" }
+
+func (s stubSource) Price(ctx context.Context, isbn string) (Price, error) {
+	if isbn == "9780000000000" {
+		return Price{}, ErrNoValue
+	}
+	price, ok := s[isbn]
+	if !ok {
+		return Price{}, errors.New("source unavailable")
+	}
+	return price, nil
+}
+
+func TestSamplerSampleAll(t *testing.T) {
+	ctx := context.Background()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+	if err := db.CreateSchema(database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	store := db.NewSQLiteBookStore(database)
+
+	books := []*model.Book{
+		{Title: "Valued", Author: "A", OpenLibraryID: "OL1M", ISBN: "9781111111111"},
+		{Title: "Unknown", Author: "B", OpenLibraryID: "OL2M", ISBN: "9780000000000"},
+		{Title: "Failing", Author: "C", OpenLibraryID: "OL3M", ISBN: "9782222222222"},
+		{Title: "No ISBN", Author: "D", OpenLibraryID: "OL4M"},
+		{Title: "Sold", Author: "E", OpenLibraryID: "OL5M", ISBN: "9783333333333"},
+	}
+	for _, b := range books {
+		b.Status = model.StatusRead
+		if _, err := store.AddBook(ctx, b); err != nil {
+			t.Fatalf("Failed to add book: %v", err)
+		}
+	}
+	if err := store.UpdateBook(ctx, books[4].ID, model.BookPatch{Archived: model.Some(true)}); err != nil {
+		t.Fatalf("Failed to archive book: %v", err)
+	}
+
+	sampler := NewSampler(store, stubSource{"9781111111111": {Value: 12.5, Currency: "USD"}, "9783333333333": {Value: 99, Currency: "USD"}})
+	result, err := sampler.SampleAll(ctx)
+	if err != nil {
+		t.Fatalf("SampleAll failed: %v", err)
+	}
+	if want := (Result{Checked: 3, Sampled: 1, Missing: 1, Failed: 1}); result != want {
+		t.Errorf("Expected %+v, got %+v", want, result)
+	}
+	history, err := store.GetBookValueHistory(ctx, books[0].ID)
+	if err != nil || len(history.Samples) != 1 || history.Latest.Value != 12.5 || history.Latest.Source != "stub" {
+		t.Errorf("Expected one sample from the stub, got %+v, %v", history, err)
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/isbn/9781111111111":
+			w.Write([]byte(`{"value": 8.25, "currency": "eur"}`))
+		case "/isbn/9782222222222":
+			w.Write([]byte(`{"value": 3}`))
+		case "/isbn/9783333333333":
+			http.Error(w, "boom", http.StatusBadGateway)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	if _, err := NewHTTPSource(server.URL+"/isbn", server.Client()); err == nil {
+		t.Error("Expected a URL without {isbn} to be rejected")
+	}
+	source, err := NewHTTPSource(server.URL+"/isbn/{isbn}", server.Client())
+	if err != nil {
+		t.Fatalf("NewHTTPSource failed: %v", err)
+	}
+	ctx := context.Background()
+	if price, err := source.Price(ctx, "9781111111111"); err != nil || price != (Price{Value: 8.25, Currency: "EUR"}) {
+		t.Errorf("Expected 8.25 EUR, got %+v, %v", price, err)
+	}
+	if price, err := source.Price(ctx, "9782222222222"); err != nil || price.Currency != "USD" {
+		t.Errorf("Expected the default currency, got %+v, %v", price, err)
+	}
+	if _, err := source.Price(ctx, "9780000000000"); !errors.Is(err, ErrNoValue) {
+		t.Errorf("Expected no value for an unknown ISBN, got %v", err)
+	}
+	if _, err := source.Price(ctx, "9783333333333"); err == nil || errors.Is(err, ErrNoValue) {
+		t.Errorf("Expected a failing source to fail, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithms (RFC 9053) a passkey may sign with, in order of
+// preference.
+const (
+	AlgES256 = -7   // ECDSA with P-256 and SHA-256, what most authenticators use
+	AlgEdDSA = -8   // Ed25519
+	AlgRS256 = -257 // RSASSA-PKCS1-v1_5 with SHA-256, for Windows Hello
+)
+
+// Algorithms are the algorithms passkeys are accepted with.
+var Algorithms = []int{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE key parameters (RFC 9052) and key types.
+const (
+	coseKty    = 1
+	coseAlg    = 3
+	coseCrv    = -1 // Or the modulus n of RSA keys
+	coseX      = -2 // Or the exponent e of RSA keys
+	coseY      = -3
+	ktyOKP     = 1
+	ktyEC2     = 2
+	ktyRSA     = 3
+	crvP256    = 1
+	crvEd25519 = 6
+)
+
+// publicKey is a passkey's public key with the algorithm it signs with.
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// parsePublicKey decodes a public key in the COSE_Key format authenticators
+// hand out.
+func parsePublicKey(cose []byte) (*publicKey, error) {
+	item, _, err := decodeCBOR(cose)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	m, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("invalid public key: not a COSE key")
+	}
+	kty, _ := m[int64(coseKty)].(int64)
+	alg, _ := m[int64(coseAlg)].(int64)
+	crv, _ := m[int64(coseCrv)].(int64)
+	x, _ := m[int64(coseX)].([]byte)
+
+	switch {
+	case kty == ktyEC2 && alg == AlgES256:
+		y, _ := m[int64(coseY)].([]byte)
+		if crv != crvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid public key: expected a P-256 point")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("invalid public key: point is not on P-256")
+		}
+		return &publicKey{alg: alg, key: key}, nil
+	case kty == ktyOKP && alg == AlgEdDSA:
+		if crv != crvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid public key: expected an Ed25519 key")
+		}
+		return &publicKey{alg: alg, key: ed25519.PublicKey(x)}, nil
+	case kty == ktyRSA && alg == AlgRS256:
+		n, _ := m[int64(coseCrv)].([]byte)
+		e := new(big.Int).SetBytes(x)
+		if len(n) < 256 || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid public key: expected an RSA key of at least 2048 bits")
+		}
+		return &publicKey{alg: alg, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(e.Int64())}}, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %d with algorithm %d", kty, alg)
+}
+
+// verify checks that signature is the key's signature of data.
+func (k *publicKey) verify(data, signature []byte) error {
+	digest := sha256.Sum256(data)
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		if ecdsa.VerifyASN1(key, digest[:], signature) {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if ed25519.Verify(key, data, signature) {
+			return nil
+		}
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+			return nil
+		}
+	}
+	return errors.New("invalid signature")
+}
@@ -0,0 +1,367 @@
+// Package webauthn implements the relying party side of WebAuthn (Level 2)
+// for logging in with passkeys: the options browsers are sent to create and
+// use a passkey, and the verification of what they send back. Attestation
+// statements aren't verified, as for the "none" attestation the server asks
+// for: any authenticator the user trusts with their account is accepted.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Timeout is how long browsers are given to create or use a passkey, in
+// milliseconds.
+const Timeout = 5 * 60 * 1000
+
+// Authenticator data flags.
+const (
+	flagUserPresent = 0x01
+	flagAttested    = 0x40
+)
+
+// ErrVerification is wrapped by the errors of responses that fail
+// verification, as opposed to malformed ones.
+var ErrVerification = errors.New("passkey verification failed")
+
+// RelyingParty is the site passkeys are created for: the origin of its web
+// UI, and that origin's host as the RP ID passkeys are bound to.
+type RelyingParty struct {
+	ID     string
+	Name   string
+	Origin string
+}
+
+// NewRelyingParty returns the relying party of the web UI at origin, such
+// as "https://books.example.com".
+func NewRelyingParty(origin, name string) (RelyingParty, error) {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+		return RelyingParty{}, fmt.Errorf("expected an origin such as https://books.example.com, got %q", origin)
+	}
+	return RelyingParty{ID: u.Hostname(), Name: name, Origin: u.Scheme + "://" + u.Host}, nil
+}
+
+// NewChallenge returns a random challenge for a ceremony, base64url encoded.
+func NewChallenge() (string, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(challenge), nil
+}
+
+// CredentialDescriptor identifies a passkey in options.
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"` // base64url
+	Transports []string `json:"transports,omitempty"`
+}
+
+// CreationOptions are the PublicKeyCredentialCreationOptions browsers create
+// a passkey with, binary values base64url encoded.
+type CreationOptions struct {
+	Challenge string `json:"challenge"`
+	RP        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	PubKeyCredParams []struct {
+		Type string `json:"type"`
+		Alg  int    `json:"alg"`
+	} `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout"`
+	Attestation            string                 `json:"attestation"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection struct {
+		ResidentKey        string `json:"residentKey"`
+		RequireResidentKey bool   `json:"requireResidentKey"`
+		UserVerification   string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+}
+
+// CreationOptions returns the options for creating a passkey of the user
+// with handle userHandle, excluding the passkeys they already have. Passkeys
+// are discoverable, so logging in needs no username.
+func (rp RelyingParty) CreationOptions(challenge string, userHandle []byte, username string, exclude []CredentialDescriptor) CreationOptions {
+	var o CreationOptions
+	o.Challenge = challenge
+	o.RP.ID, o.RP.Name = rp.ID, rp.Name
+	o.User.ID, o.User.Name, o.User.DisplayName = base64.RawURLEncoding.EncodeToString(userHandle), username, username
+	for _, alg := range Algorithms {
+		o.PubKeyCredParams = append(o.PubKeyCredParams, struct {
+			Type string `json:"type"`
+			Alg  int    `json:"alg"`
+		}{"public-key", alg})
+	}
+	o.Timeout = Timeout
+	o.Attestation = "none"
+	o.ExcludeCredentials = exclude
+	if o.ExcludeCredentials == nil {
+		o.ExcludeCredentials = []CredentialDescriptor{}
+	}
+	o.AuthenticatorSelection.ResidentKey = "preferred"
+	o.AuthenticatorSelection.UserVerification = "preferred"
+	return o
+}
+
+// RequestOptions are the PublicKeyCredentialRequestOptions browsers log in
+// with, binary values base64url encoded.
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int                    `json:"timeout"`
+	UserVerification string                 `json:"userVerification"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+}
+
+// RequestOptions returns the options for logging in with one of allow, or
+// with any passkey of the site when allow is empty.
+func (rp RelyingParty) RequestOptions(challenge string, allow []CredentialDescriptor) RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+	return RequestOptions{Challenge: challenge, RPID: rp.ID, Timeout: Timeout, UserVerification: "preferred", AllowCredentials: allow}
+}
+
+// AttestationResponse is the PublicKeyCredential a browser created, as
+// JSON with binary values base64url encoded (its toJSON()).
+type AttestationResponse struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the PublicKeyCredential a browser logged in with, as
+// JSON with binary values base64url encoded.
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// Credential is a verified new passkey.
+type Credential struct {
+	ID         []byte
+	PublicKey  []byte // COSE_Key
+	SignCount  uint32
+	Transports []string
+}
+
+// clientData is the part of the client data JSON that is checked.
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// Challenge returns the challenge a response's client data JSON answers, so
+// the ceremony it belongs to can be looked up.
+func Challenge(clientDataJSON string) (string, error) {
+	raw, err := decode(clientDataJSON)
+	if err != nil {
+		return "", fmt.Errorf("invalid clientDataJSON: %w", err)
+	}
+	var c clientData
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return "", fmt.Errorf("invalid clientDataJSON: %w", err)
+	}
+	return c.Challenge, nil
+}
+
+// checkClientData checks that raw is client data of a ceremony of type
+// answering challenge on the relying party's origin.
+func (rp RelyingParty) checkClientData(raw []byte, ceremony, challenge string) error {
+	var c clientData
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return fmt.Errorf("invalid clientDataJSON: %w", err)
+	}
+	switch {
+	case c.Type != ceremony:
+		return fmt.Errorf("%w: expected a %s ceremony, got %q", ErrVerification, ceremony, c.Type)
+	case c.Challenge != challenge:
+		return fmt.Errorf("%w: challenge doesn't match", ErrVerification)
+	case c.Origin != rp.Origin:
+		return fmt.Errorf("%w: origin %q isn't %s", ErrVerification, c.Origin, rp.Origin)
+	}
+	return nil
+}
+
+// authenticatorData is parsed authenticator data.
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte // COSE_Key, for the data of a new passkey
+}
+
+// parseAuthenticatorData parses authenticator data, with the attested
+// credential data of a new passkey when its flag is set.
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("invalid authenticator data: too short")
+	}
+	a := &authenticatorData{rpIDHash: data[:32], flags: data[32], signCount: binary.BigEndian.Uint32(data[33:37])}
+	if a.flags&flagAttested == 0 {
+		return a, nil
+	}
+	rest := data[37:]
+	if len(rest) < 18 {
+		return nil, errors.New("invalid authenticator data: truncated credential data")
+	}
+	idLength := int(binary.BigEndian.Uint16(rest[16:18])) // After the 16-byte AAGUID
+	rest = rest[18:]
+	if idLength == 0 || idLength > 1023 || len(rest) < idLength {
+		return nil, errors.New("invalid authenticator data: bad credential ID length")
+	}
+	a.credentialID, rest = rest[:idLength], rest[idLength:]
+	// The key is followed by extension data, if any
+	_, after, err := decodeCBOR(rest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid authenticator data: %w", err)
+	}
+	a.publicKey = rest[:len(rest)-len(after)]
+	return a, nil
+}
+
+// checkAuthenticatorData checks that a is for the relying party and that the
+// user was present.
+func (rp RelyingParty) checkAuthenticatorData(a *authenticatorData) error {
+	hash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(a.rpIDHash, hash[:]) {
+		return fmt.Errorf("%w: passkey is for another site", ErrVerification)
+	}
+	if a.flags&flagUserPresent == 0 {
+		return fmt.Errorf("%w: user wasn't present", ErrVerification)
+	}
+	return nil
+}
+
+// VerifyRegistration verifies a new passkey created in answer to
+// challenge and returns it.
+func (rp RelyingParty) VerifyRegistration(resp AttestationResponse, challenge string) (*Credential, error) {
+	if resp.Type != "public-key" {
+		return nil, fmt.Errorf("expected a public-key credential, got %q", resp.Type)
+	}
+	rawClientData, err := decode(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clientDataJSON: %w", err)
+	}
+	if err := rp.checkClientData(rawClientData, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+	rawAttestation, err := decode(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestationObject: %w", err)
+	}
+	item, _, err := decodeCBOR(rawAttestation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestationObject: %w", err)
+	}
+	attestation, _ := item.(map[interface{}]interface{})
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, errors.New("invalid attestationObject: no authData")
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.checkAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+	if authData.credentialID == nil {
+		return nil, errors.New("invalid attestationObject: no credential data")
+	}
+	if _, err := parsePublicKey(authData.publicKey); err != nil {
+		return nil, err
+	}
+	return &Credential{
+		ID:         authData.credentialID,
+		PublicKey:  authData.publicKey,
+		SignCount:  authData.signCount,
+		Transports: resp.Response.Transports,
+	}, nil
+}
+
+// CredentialID returns the ID of the passkey a response is from.
+func CredentialID(id string) ([]byte, error) {
+	raw, err := decode(id)
+	if err != nil || len(raw) == 0 {
+		return nil, errors.New("invalid credential ID")
+	}
+	return raw, nil
+}
+
+// VerifyLogin verifies a login with the passkey with publicKey, whose
+// signature counter was signCount, in answer to challenge, and returns its
+// new signature counter. A counter that didn't increase, when the
+// authenticator keeps one, means the passkey may have been cloned.
+func (rp RelyingParty) VerifyLogin(resp AssertionResponse, challenge string, publicKey []byte, signCount uint32) (uint32, error) {
+	if resp.Type != "public-key" {
+		return 0, fmt.Errorf("expected a public-key credential, got %q", resp.Type)
+	}
+	rawClientData, err := decode(resp.Response.ClientDataJSON)
+	if err != nil {
+		return 0, fmt.Errorf("invalid clientDataJSON: %w", err)
+	}
+	if err := rp.checkClientData(rawClientData, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+	rawAuthData, err := decode(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, fmt.Errorf("invalid authenticatorData: %w", err)
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+	if err := rp.checkAuthenticatorData(authData); err != nil {
+		return 0, err
+	}
+	signature, err := decode(resp.Response.Signature)
+	if err != nil {
+		return 0, fmt.Errorf("invalid signature: %w", err)
+	}
+	key, err := parsePublicKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(rawClientData)
+	signed := append(append([]byte(nil), rawAuthData...), clientDataHash[:]...)
+	if err := key.verify(signed, signature); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrVerification, err)
+	}
+	if (authData.signCount != 0 || signCount != 0) && authData.signCount <= signCount {
+		return 0, fmt.Errorf("%w: signature counter went from %d to %d, the passkey may have been cloned", ErrVerification, signCount, authData.signCount)
+	}
+	return authData.signCount, nil
+}
+
+// decode decodes base64url, with or without padding.
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
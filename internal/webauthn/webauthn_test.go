@@ -0,0 +1,204 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// cborHead encodes the head of a CBOR item of major type major.
+func cborHead(major byte, n int) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 256:
+		return []byte{major<<5 | 24, byte(n)}
+	}
+	return []byte{major<<5 | 25, byte(n >> 8), byte(n)}
+}
+
+// cborInt encodes an integer.
+func cborInt(n int) []byte {
+	if n < 0 {
+		return cborHead(1, -1-n)
+	}
+	return cborHead(0, n)
+}
+
+// cborBytes encodes a byte string.
+func cborBytes(b []byte) []byte { return append(cborHead(2, len(b)), b...) }
+
+// cborText encodes a text string.
+func cborText(s string) []byte { return append(cborHead(3, len(s)), s...) }
+
+// cborMap encodes a map of the given encoded keys and values, in order.
+func cborMap(pairs ...[]byte) []byte {
+	out := cborHead(5, len(pairs)/2)
+	for _, p := range pairs {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// authenticator is a software authenticator holding one passkey.
+type authenticator struct {
+	rpID      string
+	origin    string
+	id        []byte
+	key       *ecdsa.PrivateKey
+	signCount uint32
+}
+
+func newAuthenticator(t *testing.T, rpID, origin string) *authenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	return &authenticator{rpID: rpID, origin: origin, id: []byte("credential-1"), key: key}
+}
+
+func (a *authenticator) coseKey() []byte {
+	x, y := make([]byte, 32), make([]byte, 32)
+	a.key.X.FillBytes(x)
+	a.key.Y.FillBytes(y)
+	return cborMap(cborInt(1), cborInt(2), cborInt(3), cborInt(-7), cborInt(-1), cborInt(1), cborInt(-2), cborBytes(x), cborInt(-3), cborBytes(y))
+}
+
+func (a *authenticator) authData(flags byte, attested []byte) []byte {
+	hash := sha256.Sum256([]byte(a.rpID))
+	data := append(hash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func (a *authenticator) clientData(ceremony, challenge string) []byte {
+	data, _ := json.Marshal(map[string]interface{}{"type": ceremony, "challenge": challenge, "origin": a.origin, "crossOrigin": false})
+	return data
+}
+
+func encode(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+// create makes the passkey in answer to challenge.
+func (a *authenticator) create(challenge string) AttestationResponse {
+	attested := make([]byte, 16) // AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.id)))
+	attested = append(append(attested, a.id...), a.coseKey()...)
+	object := cborMap(cborText("fmt"), cborText("none"), cborText("attStmt"), cborMap(), cborText("authData"), cborBytes(a.authData(0x45, attested)))
+	var resp AttestationResponse
+	resp.ID, resp.Type = encode(a.id), "public-key"
+	resp.Response.ClientDataJSON = encode(a.clientData("webauthn.create", challenge))
+	resp.Response.AttestationObject = encode(object)
+	resp.Response.Transports = []string{"internal"}
+	return resp
+}
+
+// get logs in with the passkey in answer to challenge.
+func (a *authenticator) get(t *testing.T, challenge string) AssertionResponse {
+	a.signCount++
+	authData := a.authData(0x05, nil)
+	clientData := a.clientData("webauthn.get", challenge)
+	hash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), hash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("SignASN1 failed: %v", err)
+	}
+	var resp AssertionResponse
+	resp.ID, resp.Type = encode(a.id), "public-key"
+	resp.Response.ClientDataJSON = encode(clientData)
+	resp.Response.AuthenticatorData = encode(authData)
+	resp.Response.Signature = encode(signature)
+	return resp
+}
+
+func TestRegistrationAndLogin(t *testing.T) {
+	rp, err := NewRelyingParty("https://books.example.com", "Bookshelf")
+	if err != nil || rp.ID != "books.example.com" {
+		t.Fatalf("NewRelyingParty = %+v, %v", rp, err)
+	}
+	a := newAuthenticator(t, rp.ID, rp.Origin)
+
+	resp := a.create("challenge-1")
+	if challenge, err := Challenge(resp.Response.ClientDataJSON); err != nil || challenge != "challenge-1" {
+		t.Errorf("Challenge = %q, %v", challenge, err)
+	}
+	if _, err := rp.VerifyRegistration(resp, "challenge-2"); !errors.Is(err, ErrVerification) {
+		t.Errorf("Expected another challenge to fail verification, got %v", err)
+	}
+	phished := newAuthenticator(t, "books.example.evil", "https://books.example.evil")
+	if _, err := rp.VerifyRegistration(phished.create("challenge-1"), "challenge-1"); !errors.Is(err, ErrVerification) {
+		t.Errorf("Expected another origin to fail verification, got %v", err)
+	}
+	credential, err := rp.VerifyRegistration(resp, "challenge-1")
+	if err != nil {
+		t.Fatalf("VerifyRegistration failed: %v", err)
+	}
+	if string(credential.ID) != "credential-1" || credential.SignCount != 0 || credential.Transports[0] != "internal" {
+		t.Errorf("Unexpected credential %+v", credential)
+	}
+	if id, err := CredentialID(resp.ID); err != nil || string(id) != "credential-1" {
+		t.Errorf("CredentialID = %q, %v", id, err)
+	}
+
+	login := a.get(t, "challenge-3")
+	count, err := rp.VerifyLogin(login, "challenge-3", credential.PublicKey, credential.SignCount)
+	if err != nil || count != 1 {
+		t.Fatalf("VerifyLogin = %d, %v", count, err)
+	}
+	// A replayed login has a counter that didn't move
+	if _, err := rp.VerifyLogin(login, "challenge-3", credential.PublicKey, count); !errors.Is(err, ErrVerification) {
+		t.Errorf("Expected a replay to fail verification, got %v", err)
+	}
+	forged := a.get(t, "challenge-4")
+	forged.Response.Signature = a.get(t, "challenge-5").Response.Signature
+	if _, err := rp.VerifyLogin(forged, "challenge-4", credential.PublicKey, count); !errors.Is(err, ErrVerification) {
+		t.Errorf("Expected a bad signature to fail verification, got %v", err)
+	}
+	if _, err := rp.VerifyLogin(a.get(t, "challenge-6"), "challenge-6", credential.PublicKey, count); err != nil {
+		t.Errorf("VerifyLogin failed: %v", err)
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	key, err := parsePublicKey(cborMap(cborInt(1), cborInt(1), cborInt(3), cborInt(-8), cborInt(-1), cborInt(6), cborInt(-2), cborBytes(pub)))
+	if err != nil {
+		t.Fatalf("parsePublicKey failed: %v", err)
+	}
+	if err := key.verify([]byte("data"), ed25519.Sign(priv, []byte("data"))); err != nil {
+		t.Errorf("verify failed: %v", err)
+	}
+	for _, cose := range [][]byte{
+		cborMap(cborInt(1), cborInt(2), cborInt(3), cborInt(-7), cborInt(-1), cborInt(1), cborInt(-2), cborBytes(make([]byte, 32)), cborInt(-3), cborBytes(make([]byte, 32))),
+		cborMap(cborInt(1), cborInt(2), cborInt(3), cborInt(-36)),
+		{0xbf},
+		cborText("key"),
+	} {
+		if _, err := parsePublicKey(cose); err == nil {
+			t.Errorf("Expected %x to be rejected", cose)
+		}
+	}
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	value, rest, err := decodeCBOR([]byte{0x82, 0x39, 0x01, 0x00, 0xa1, 0x61, 'a', 0xf5, 0x00})
+	if err != nil || len(rest) != 1 {
+		t.Fatalf("decodeCBOR = %v, %x, %v", value, rest, err)
+	}
+	items := value.([]interface{})
+	if items[0] != int64(-257) || items[1].(map[interface{}]interface{})["a"] != true {
+		t.Errorf("Unexpected value %v", value)
+	}
+	for _, data := range [][]byte{{}, {0x5a, 0xff, 0xff, 0xff, 0xff}, {0x9f}, {0x81}, {0xa1, 0x80, 0x00}} {
+		if _, _, err := decodeCBOR(data); err == nil {
+			t.Errorf("Expected %x to be rejected", data)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Package webhook sends book lifecycle events to the webhooks users
+// register. The store queues a delivery for each subscribed webhook in the
+// same transaction as the change to the book, so no event is lost or sent
+// for a change that was rolled back; the Dispatcher posts the queued
+// deliveries, signed with the webhook's secret, and retries failed ones with
+// backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/safehttp"
+	"github.com/ericdahl/bookshelf/internal/secrets"
+)
+
+// Headers sent with every delivery.
+const (
+	EventHeader     = "X-Bookshelf-Event"     // e.g. book.finished
+	DeliveryHeader  = "X-Bookshelf-Delivery"  // The delivery's ID, the same for every attempt
+	TimestampHeader = "X-Bookshelf-Timestamp" // Unix seconds the attempt was signed at
+	SignatureHeader = "X-Bookshelf-Signature" // "sha256=" and the hex HMAC; see Sign
+)
+
+// DefaultBackoff are the waits before each retry of a failed delivery.
+var DefaultBackoff = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour, 12 * time.Hour}
+
+// batchSize caps the deliveries sent per poll; the rest wait for the next.
+const batchSize = 50
+
+// Sign returns the signature of a payload sent at timestamp: the hex
+// HMAC-SHA256 under secret of the timestamp, a dot and the payload, prefixed
+// with "sha256=". Receivers recompute it to check that a delivery came from
+// this server, and reject old timestamps to stop replays.
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher sends the queued deliveries of every user's webhooks.
+type Dispatcher struct {
+	Store      db.WebhookStore
+	Box        *secrets.Box // Opens the webhooks' secrets
+	HTTPClient *http.Client
+	// Backoff are the waits before each retry; a delivery that fails once
+	// more than it has waits is given up on.
+	Backoff []time.Duration
+}
+
+// NewDispatcher creates a dispatcher with the default backoff. Its client
+// can't reach the server's own networks, and doesn't follow redirects, which
+// would send events somewhere the user didn't register.
+func NewDispatcher(store db.WebhookStore, box *secrets.Box) *Dispatcher {
+	client := safehttp.NewClient(10 * time.Second)
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return &Dispatcher{
+		Store:      store,
+		Box:        box,
+		HTTPClient: client,
+		Backoff:    DefaultBackoff,
+	}
+}
+
+// Run sends the deliveries due every interval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	slog.Info("Starting webhook dispatcher", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		d.DeliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping webhook dispatcher")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue sends the deliveries due now, up to batchSize of them, and
+// returns how many were delivered.
+func (d *Dispatcher) DeliverDue(ctx context.Context) int {
+	due, err := d.Store.GetDueWebhookDeliveries(ctx, time.Now(), batchSize)
+	if err != nil {
+		slog.Error("Failed to list due webhook deliveries", "error", err)
+		return 0
+	}
+	delivered := 0
+	for i := range due {
+		if d.attempt(ctx, &due[i]) {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// attempt sends a delivery once and records the outcome, scheduling the next
+// retry when it fails. It reports whether the delivery succeeded.
+func (d *Dispatcher) attempt(ctx context.Context, delivery *model.WebhookDelivery) bool {
+	delivery.Attempts++
+	status, err := d.send(ctx, delivery)
+	delivery.ResponseStatus, delivery.Error = status, ""
+	now := time.Now().UTC()
+	switch {
+	case err == nil:
+		delivery.Status, delivery.NextAttemptAt, delivery.DeliveredAt = model.DeliveryDelivered, nil, &now
+	case delivery.Attempts > len(d.Backoff):
+		delivery.Status, delivery.NextAttemptAt, delivery.Error = model.DeliveryFailed, nil, err.Error()
+	default:
+		next := now.Add(d.Backoff[delivery.Attempts-1])
+		delivery.NextAttemptAt, delivery.Error = &next, err.Error()
+	}
+	if err != nil {
+		slog.Warn("Webhook delivery failed", "delivery", delivery.ID, logging.Redact("url", delivery.URL), "attempts", delivery.Attempts,
+			"status", delivery.Status, "error", err)
+	} else {
+		slog.Info("Delivered webhook", "delivery", delivery.ID, "event", delivery.Event, logging.Redact("url", delivery.URL))
+	}
+	if err := d.Store.SaveWebhookAttempt(ctx, delivery); err != nil {
+		slog.Error("Failed to save webhook attempt", "delivery", delivery.ID, "error", err)
+	}
+	return err == nil
+}
+
+// send posts a delivery's payload, returning the response status, if any.
+// Any status but 2xx is an error.
+func (d *Dispatcher) send(ctx context.Context, delivery *model.WebhookDelivery) (*int, error) {
+	secret, err := d.Box.Open(delivery.EncryptedSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt webhook secret: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bookshelf-webhooks")
+	req.Header.Set(EventHeader, string(delivery.Event))
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(secret, timestamp, delivery.Payload))
+	resp, err := d.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	status := resp.StatusCode
+	if status < 200 || status > 299 {
+		// Only the status is kept: the body is the receiver's, and could be anything
+		return &status, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return &status, nil
+}
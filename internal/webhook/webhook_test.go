@@ -0,0 +1,182 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/safehttp"
+	"github.com/ericdahl/bookshelf/internal/secrets"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{"event":"book.finished"}' | openssl dgst -sha256 -hmac secret
+	if got := Sign("secret", 1700000000, []byte(`{"event":"book.finished"}`)); got != "sha256=b251509c70891b8937ecfa956cacf85caa27b43b8f88f71abf6655b882d1d99f" {
+		t.Errorf("Unexpected signature %q", got)
+	}
+	if Sign("secret", 1, []byte("a")) == Sign("other", 1, []byte("a")) || Sign("secret", 1, []byte("a")) == Sign("secret", 2, []byte("a")) {
+		t.Error("Expected the signature to depend on the secret and timestamp")
+	}
+}
+
+func TestDispatcherDeliverDue(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var received []*http.Request
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		if r.Header.Get(SignatureHeader) != Sign("s3cret", timestamp, body) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r)
+		if fail {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	defer database.Close()
+	if err := db.CreateSchema(database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	store := db.NewSQLiteBookStore(database)
+	box, err := secrets.NewBox("passphrase")
+	if err != nil {
+		t.Fatalf("NewBox failed: %v", err)
+	}
+	sealed, err := box.Seal("s3cret")
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	webhook := &model.Webhook{URL: server.URL, Events: []model.WebhookEvent{model.WebhookBookFinished}, Enabled: true, EncryptedSecret: sealed}
+	if _, err := store.AddWebhook(ctx, webhook); err != nil {
+		t.Fatalf("AddWebhook failed: %v", err)
+	}
+	id, err := store.AddBook(ctx, &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL1M", Status: model.StatusCurrentlyReading})
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if err := store.UpdateBookStatus(ctx, id, model.StatusRead); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+
+	d := NewDispatcher(store, box)
+	d.HTTPClient.Transport = server.Client().Transport // The test server is on loopback
+	d.Backoff = []time.Duration{0}
+
+	// The first attempt fails and is retried right away by the next round
+	if n := d.DeliverDue(ctx); n != 0 || len(received) != 1 {
+		t.Fatalf("Expected a failed attempt, got %d delivered, %d received", n, len(received))
+	}
+	deliveries, _ := store.GetWebhookDeliveries(ctx, webhook.ID, 10)
+	if len(deliveries) != 1 || deliveries[0].Status != model.DeliveryPending || deliveries[0].Attempts != 1 ||
+		*deliveries[0].ResponseStatus != http.StatusServiceUnavailable || deliveries[0].Error != "webhook returned 503 Service Unavailable" {
+		t.Fatalf("Expected the delivery to be retried, got %+v", deliveries)
+	}
+
+	// The retry fails too, and there are no more
+	d.DeliverDue(ctx)
+	deliveries, _ = store.GetWebhookDeliveries(ctx, webhook.ID, 10)
+	if deliveries[0].Status != model.DeliveryFailed || deliveries[0].Attempts != 2 || deliveries[0].NextAttemptAt != nil {
+		t.Fatalf("Expected the delivery given up on, got %+v", deliveries[0])
+	}
+	if n := d.DeliverDue(ctx); n != 0 || len(received) != 2 {
+		t.Errorf("Expected a failed delivery not to be sent again, got %d received", len(received))
+	}
+
+	// Retried by hand, it gets through
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	if _, err := store.RetryWebhookDelivery(ctx, deliveries[0].ID); err != nil {
+		t.Fatalf("RetryWebhookDelivery failed: %v", err)
+	}
+	if n := d.DeliverDue(ctx); n != 1 {
+		t.Fatalf("Expected the delivery delivered, got %d", n)
+	}
+	deliveries, _ = store.GetWebhookDeliveries(ctx, webhook.ID, 10)
+	if deliveries[0].Status != model.DeliveryDelivered || deliveries[0].DeliveredAt == nil || deliveries[0].Error != "" {
+		t.Errorf("Unexpected delivery %+v", deliveries[0])
+	}
+	last := received[len(received)-1]
+	if last.Header.Get(EventHeader) != "book.finished" || last.Header.Get(DeliveryHeader) != strconv.FormatInt(deliveries[0].ID, 10) {
+		t.Errorf("Unexpected headers %v", last.Header)
+	}
+	var payload model.WebhookPayload
+	if err := json.Unmarshal(deliveries[0].Payload, &payload); err != nil || payload.Book.Title != "Dune" || payload.PreviousStatus != model.StatusCurrentlyReading {
+		t.Errorf("Unexpected payload %+v, %v", payload, err)
+	}
+}
+
+func TestDispatcherStaysWhereSent(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		http.Redirect(w, r, "/elsewhere", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	defer database.Close()
+	if err := db.CreateSchema(database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	store := db.NewSQLiteBookStore(database)
+	box, _ := secrets.NewBox("passphrase")
+	sealed, _ := box.Seal("s3cret")
+	webhook := &model.Webhook{URL: server.URL + "/hook", Events: []model.WebhookEvent{model.WebhookBookCreated}, Enabled: true, EncryptedSecret: sealed}
+	if _, err := store.AddWebhook(ctx, webhook); err != nil {
+		t.Fatalf("AddWebhook failed: %v", err)
+	}
+	if _, err := store.AddBook(ctx, &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL1M", Status: model.StatusRead}); err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+
+	// The server's own networks are refused
+	d := NewDispatcher(store, box)
+	d.Backoff = []time.Duration{0, 0}
+	d.DeliverDue(ctx)
+	deliveries, _ := store.GetWebhookDeliveries(ctx, webhook.ID, 10)
+	if len(deliveries) != 1 || deliveries[0].ResponseStatus != nil || !strings.Contains(deliveries[0].Error, safehttp.ErrForbiddenAddress.Error()) || len(paths) != 0 {
+		t.Fatalf("Expected the delivery to loopback to be refused, got %+v and %v received", deliveries, paths)
+	}
+
+	// Redirects aren't followed
+	d.HTTPClient.Transport = server.Client().Transport
+	d.DeliverDue(ctx)
+	deliveries, _ = store.GetWebhookDeliveries(ctx, webhook.ID, 10)
+	if deliveries[0].ResponseStatus == nil || *deliveries[0].ResponseStatus != http.StatusTemporaryRedirect ||
+		deliveries[0].Error != "webhook returned 307 Temporary Redirect" {
+		t.Errorf("Expected the redirect recorded as a failure, got %+v", deliveries[0])
+	}
+	if len(paths) != 1 || paths[0] != "/hook" {
+		t.Errorf("Expected only the webhook's URL to be posted to, got %v", paths)
+	}
+}
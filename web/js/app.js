@@ -1,11 +1,19 @@
 document.addEventListener('DOMContentLoaded', function() {
     // API endpoints
     const API = {
-        BOOKS: '/api/books',
-        SEARCH: '/api/books/search',
-        BOOK_STATUS: (id) => `/api/books/${id}`,
-        BOOK_DETAILS: (id) => `/api/books/${id}/details`,
-        DELETE_BOOK: (id) => `/api/books/${id}`
+        BOOKS: '/api/v1/books',
+        SEARCH: '/api/v1/books/search',
+        BOOK_STATUS: (id) => `/api/v1/books/${id}`,
+        BOOK_DETAILS: (id) => `/api/v1/books/${id}/details`,
+        DELETE_BOOK: (id) => `/api/v1/books/${id}`,
+        CURRENT_USER: '/api/v1/users/me',
+        LOGIN: '/api/v1/users/login',
+        REGISTER: '/api/v1/users/register',
+        LOGOUT: '/api/v1/users/logout',
+        PASSKEY_REGISTER_BEGIN: '/api/v1/users/passkeys/register/begin',
+        PASSKEY_REGISTER_FINISH: '/api/v1/users/passkeys/register/finish',
+        PASSKEY_LOGIN_BEGIN: '/api/v1/users/passkeys/login/begin',
+        PASSKEY_LOGIN_FINISH: '/api/v1/users/passkeys/login/finish'
     };
 
     // DOM Elements
@@ -23,7 +31,17 @@ document.addEventListener('DOMContentLoaded', function() {
     const ratingStars = document.querySelectorAll('.stars i');
     const fullViewButton = document.getElementById('full-view');
     const compactViewButton = document.getElementById('compact-view');
+    const subtitleToggleButton = document.getElementById('subtitle-toggle');
     const shelvesContainer = document.querySelector('.shelves-container');
+    const loginForm = document.getElementById('login-form');
+    const loginUsername = document.getElementById('login-username');
+    const loginPassword = document.getElementById('login-password');
+    const registerButton = document.getElementById('register-button');
+    const accountInfo = document.getElementById('account-info');
+    const accountName = document.getElementById('account-name');
+    const logoutButton = document.getElementById('logout-button');
+    const passkeyLoginButton = document.getElementById('passkey-login-button');
+    const addPasskeyButton = document.getElementById('add-passkey-button');
 
     // Current book being viewed/edited
     let currentBook = null;
@@ -34,6 +52,9 @@ document.addEventListener('DOMContentLoaded', function() {
 
     // Initialize the application
     function initApp() {
+        // Show who is logged in, or the login form
+        loadAccount();
+
         // Load all books from the server
         loadBooks();
 
@@ -74,11 +95,168 @@ document.addEventListener('DOMContentLoaded', function() {
         sortShelfBooks(status, sortBy, sortDirection);
     }
 
+    // Show the user who is logged in, or the login form when nobody is.
+    // The server keeps the session in a cookie, so requests carry it.
+    function loadAccount() {
+        fetch(API.CURRENT_USER)
+            .then(response => response.ok ? response.json() : null)
+            .then(user => {
+                loginForm.classList.toggle('hidden', !!user);
+                accountInfo.classList.toggle('hidden', !user);
+                accountName.textContent = user ? user.username : '';
+            })
+            .catch(error => console.error('Error loading account:', error));
+    }
+
+    // Log in with the username and password of the login form
+    function logIn() {
+        const credentials = JSON.stringify({
+            username: loginUsername.value.trim(),
+            password: loginPassword.value
+        });
+        return fetch(API.LOGIN, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: credentials
+        })
+        .then(response => {
+            if (!response.ok) {
+                throw new Error('Invalid username or password');
+            }
+            loginPassword.value = '';
+            loadAccount();
+            loadBooks();
+        });
+    }
+
+    // Create an account from the login form, then log in with it
+    function register() {
+        fetch(API.REGISTER, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify({
+                username: loginUsername.value.trim(),
+                password: loginPassword.value
+            })
+        })
+        .then(response => {
+            if (!response.ok) {
+                return response.json().then(body => { throw new Error(body.error || 'Failed to create account'); });
+            }
+            return logIn();
+        })
+        .catch(error => alert(error.message));
+    }
+
+    // Passkeys need the browser's WebAuthn API
+    const passkeysSupported = !!(window.PublicKeyCredential && navigator.credentials);
+
+    // Convert between base64url, as the API sends binary values, and bytes
+    function fromBase64url(value) {
+        const base64 = value.replace(/-/g, '+').replace(/_/g, '/');
+        return Uint8Array.from(atob(base64 + '='.repeat((4 - base64.length % 4) % 4)), c => c.charCodeAt(0));
+    }
+
+    function toBase64url(buffer) {
+        return btoa(String.fromCharCode(...new Uint8Array(buffer))).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+    }
+
+    // POST body as JSON, returning the JSON response or throwing its error
+    function postJSON(url, body) {
+        return fetch(url, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify(body)
+        })
+        .then(response => response.json().then(result => {
+            if (!response.ok) {
+                throw new Error(result.error || 'Request failed');
+            }
+            return result;
+        }));
+    }
+
+    // Add a passkey to the account that is logged in
+    function addPasskey() {
+        postJSON(API.PASSKEY_REGISTER_BEGIN, {})
+            .then(({ publicKey }) => {
+                publicKey.challenge = fromBase64url(publicKey.challenge);
+                publicKey.user.id = fromBase64url(publicKey.user.id);
+                publicKey.excludeCredentials.forEach(c => { c.id = fromBase64url(c.id); });
+                return navigator.credentials.create({ publicKey });
+            })
+            .then(credential => {
+                const name = prompt('Name this passkey', 'Passkey');
+                return postJSON(API.PASSKEY_REGISTER_FINISH, {
+                    name: name || '',
+                    credential: {
+                        id: credential.id,
+                        type: credential.type,
+                        response: {
+                            clientDataJSON: toBase64url(credential.response.clientDataJSON),
+                            attestationObject: toBase64url(credential.response.attestationObject),
+                            transports: credential.response.getTransports ? credential.response.getTransports() : []
+                        }
+                    }
+                });
+            })
+            .then(() => alert('Passkey added'))
+            .catch(error => alert(error.message));
+    }
+
+    // Log in with a passkey, of the user named in the login form if any
+    function logInWithPasskey() {
+        postJSON(API.PASSKEY_LOGIN_BEGIN, { username: loginUsername.value.trim() })
+            .then(({ publicKey }) => {
+                publicKey.challenge = fromBase64url(publicKey.challenge);
+                publicKey.allowCredentials.forEach(c => { c.id = fromBase64url(c.id); });
+                return navigator.credentials.get({ publicKey });
+            })
+            .then(credential => postJSON(API.PASSKEY_LOGIN_FINISH, {
+                id: credential.id,
+                type: credential.type,
+                response: {
+                    clientDataJSON: toBase64url(credential.response.clientDataJSON),
+                    authenticatorData: toBase64url(credential.response.authenticatorData),
+                    signature: toBase64url(credential.response.signature),
+                    userHandle: credential.response.userHandle ? toBase64url(credential.response.userHandle) : ''
+                }
+            }))
+            .then(() => {
+                loginPassword.value = '';
+                loadAccount();
+                loadBooks();
+            })
+            .catch(error => alert(error.message));
+    }
+
+    // Log out and show the login form
+    function logOut() {
+        fetch(API.LOGOUT, { method: 'POST' })
+            .catch(error => console.error('Error logging out:', error))
+            .then(() => {
+                loadAccount();
+                loadBooks();
+            });
+    }
+
     // Load all books from the server
     function loadBooks() {
         showLoading();
         fetch(API.BOOKS)
-            .then(response => response.json())
+            .then(response => {
+                // The shelves stay empty until someone logs in
+                if (response.status === 401) {
+                    return [];
+                }
+                return response.json();
+            })
             .then(books => {
                 // Clear existing books from shelves
                 document.querySelectorAll('.books-container').forEach(shelf => {
@@ -235,6 +413,20 @@ document.addEventListener('DOMContentLoaded', function() {
 
     // Set up event listeners
     function setupEventListeners() {
+        // Account
+        loginForm.addEventListener('submit', e => {
+            e.preventDefault();
+            logIn().catch(error => alert(error.message));
+        });
+        registerButton.addEventListener('click', register);
+        logoutButton.addEventListener('click', logOut);
+        if (passkeysSupported) {
+            passkeyLoginButton.classList.remove('hidden');
+            addPasskeyButton.classList.remove('hidden');
+            passkeyLoginButton.addEventListener('click', logInWithPasskey);
+            addPasskeyButton.addEventListener('click', addPasskey);
+        }
+
         // Search
         searchButton.addEventListener('click', searchBooks);
         searchInput.addEventListener('keypress', e => {
@@ -289,8 +481,21 @@ document.addEventListener('DOMContentLoaded', function() {
             setViewMode('compact');
         });
         
+        // Subtitles are hidden on cards unless switched on
+        subtitleToggleButton.addEventListener('click', () => {
+            setShowSubtitles(!shelvesContainer.classList.contains('show-subtitles'));
+        });
+        
         // Load saved view preference
         loadViewPreference();
+        setShowSubtitles(localStorage.getItem('bookshelfShowSubtitles') === 'true');
+    }
+    
+    // Show or hide subtitles on book cards
+    function setShowSubtitles(show) {
+        shelvesContainer.classList.toggle('show-subtitles', show);
+        subtitleToggleButton.classList.toggle('active', show);
+        localStorage.setItem('bookshelfShowSubtitles', show ? 'true' : 'false');
     }
     
     // Set the view mode (full or compact)
@@ -552,13 +757,96 @@ document.addEventListener('DOMContentLoaded', function() {
         }
     }
 
+    // Cover image for a shelf book; the API points at the cached copy when there is one
+    function bookCoverUrl(book) {
+        return book.cover_image_url || 'https://via.placeholder.com/150x200?text=No+Cover';
+    }
+
+    const BLURHASH_DIGITS = '0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~';
+
+    function decode83(str) {
+        let value = 0;
+        for (const c of str) {
+            value = value * 83 + BLURHASH_DIGITS.indexOf(c);
+        }
+        return value;
+    }
+
+    function srgbToLinear(value) {
+        const v = value / 255;
+        return v <= 0.04045 ? v / 12.92 : Math.pow((v + 0.055) / 1.055, 2.4);
+    }
+
+    function linearToSrgb(value) {
+        const v = Math.max(0, Math.min(1, value));
+        return Math.round(v <= 0.0031308 ? v * 12.92 * 255 : (1.055 * Math.pow(v, 1 / 2.4) - 0.055) * 255);
+    }
+
+    // Render a blurhash to a small data URL, or return null if it cannot be decoded
+    function blurhashDataUrl(hash, width = 16, height = 24) {
+        if (!hash || hash.length < 6) {
+            return null;
+        }
+        const sizeFlag = decode83(hash[0]);
+        const numX = (sizeFlag % 9) + 1;
+        const numY = Math.floor(sizeFlag / 9) + 1;
+        if (hash.length !== 4 + 2 * numX * numY) {
+            return null;
+        }
+        const maxValue = (decode83(hash[1]) + 1) / 166;
+
+        const colors = [];
+        const dc = decode83(hash.substring(2, 6));
+        colors.push([srgbToLinear(dc >> 16), srgbToLinear((dc >> 8) & 255), srgbToLinear(dc & 255)]);
+        for (let i = 1; i < numX * numY; i++) {
+            const ac = decode83(hash.substring(4 + i * 2, 6 + i * 2));
+            const quant = [Math.floor(ac / (19 * 19)), Math.floor(ac / 19) % 19, ac % 19];
+            colors.push(quant.map(q => {
+                const v = (q - 9) / 9;
+                return Math.sign(v) * v * v * maxValue;
+            }));
+        }
+
+        const canvas = document.createElement('canvas');
+        canvas.width = width;
+        canvas.height = height;
+        const ctx = canvas.getContext('2d');
+        const pixels = ctx.createImageData(width, height);
+        for (let y = 0; y < height; y++) {
+            for (let x = 0; x < width; x++) {
+                let r = 0, g = 0, b = 0;
+                for (let j = 0; j < numY; j++) {
+                    for (let i = 0; i < numX; i++) {
+                        const basis = Math.cos(Math.PI * x * i / width) * Math.cos(Math.PI * y * j / height);
+                        const color = colors[i + j * numX];
+                        r += color[0] * basis;
+                        g += color[1] * basis;
+                        b += color[2] * basis;
+                    }
+                }
+                const offset = 4 * (x + y * width);
+                pixels.data[offset] = linearToSrgb(r);
+                pixels.data[offset + 1] = linearToSrgb(g);
+                pixels.data[offset + 2] = linearToSrgb(b);
+                pixels.data[offset + 3] = 255;
+            }
+        }
+        ctx.putImageData(pixels, 0, 0);
+        return canvas.toDataURL();
+    }
+
+    // Placeholder shown behind a cover until the image loads
+    function coverPlaceholder(book) {
+        return book.cover_lqip || blurhashDataUrl(book.cover_blurhash);
+    }
+
     // Create a book card element
     function createBookCard(book) {
         const card = document.createElement('div');
         card.className = 'book-card';
         card.dataset.id = book.id;
         
-        const coverUrl = book.cover_url || 'https://via.placeholder.com/150x200?text=No+Cover';
+        const coverUrl = bookCoverUrl(book);
         const ratingHtml = book.rating ? `<p class="book-rating">Rating: ${book.rating}/10</p>` : '';
         
         // Prepare series info display if available
@@ -571,6 +859,7 @@ document.addEventListener('DOMContentLoaded', function() {
         
         // Show book type if it's an audiobook (default type "book" isn't shown to keep UI clean)
         const typeHtml = book.type === 'audiobook' ? `<p class="book-type"><i class="fas fa-headphones"></i> Audiobook</p>` : '';
+        const subtitleHtml = book.subtitle ? `<p class="book-subtitle">${book.subtitle}</p>` : '';
         
         card.innerHTML = `
             <div class="book-cover">
@@ -578,6 +867,7 @@ document.addEventListener('DOMContentLoaded', function() {
             </div>
             <div class="book-info">
                 <h3 class="book-title">${book.title}</h3>
+                ${subtitleHtml}
                 <p class="book-author">${book.author}</p>
                 ${seriesHtml}
                 ${typeHtml}
@@ -585,6 +875,20 @@ document.addEventListener('DOMContentLoaded', function() {
             </div>
         `;
         
+        const placeholder = coverPlaceholder(book);
+        if (placeholder) {
+            const cover = card.querySelector('.book-cover');
+            const img = cover.querySelector('img');
+            cover.classList.add('has-placeholder');
+            cover.style.backgroundImage = `url("${placeholder}")`;
+            if (img.complete) {
+                img.classList.add('loaded');
+            } else {
+                img.addEventListener('load', () => img.classList.add('loaded'), { once: true });
+                img.addEventListener('error', () => img.classList.add('loaded'), { once: true });
+            }
+        }
+        
         // Add click event to open book details
         card.addEventListener('click', () => {
             showBookDetails(book);
@@ -642,12 +946,18 @@ document.addEventListener('DOMContentLoaded', function() {
         
         const newBook = {
             title: book.title,
+            subtitle: book.subtitle || undefined, // Left out, the server splits "Title: Subtitle" itself
             author: book.author,
             open_library_id: book.open_library_id,
             isbn: book.isbn || '',
             status: 'Want to Read',
             type: 'book', // Set default type to "book"
-            cover_url: book.cover_url || null
+            cover_url: book.cover_url || null,
+            publish_year: book.publish_year || null,
+            page_count: book.page_count || null,
+            publisher: book.publisher || null,
+            language: book.language || null,
+            source: 'manual'
         };
         
         fetch(API.BOOKS, {
@@ -716,8 +1026,9 @@ document.addEventListener('DOMContentLoaded', function() {
         
         // Update the UI with book details
         document.getElementById('detail-title').textContent = book.title;
+        document.getElementById('detail-subtitle').textContent = book.subtitle || '';
         document.getElementById('detail-author').textContent = book.author;
-        document.getElementById('detail-cover').src = book.cover_url || 'https://via.placeholder.com/150x200?text=No+Cover';
+        document.getElementById('detail-cover').src = bookCoverUrl(book);
         
         // Update OpenLibrary link
         const openLibraryLink = document.getElementById('detail-openlibrary-link').querySelector('a');
@@ -732,7 +1043,10 @@ document.addEventListener('DOMContentLoaded', function() {
             
             // Set the URL based on the format of the ID
             let url;
-            if (olid.startsWith('OL') && olid.endsWith('M')) {
+            if (olid.startsWith('gbooks:')) {
+                // It's a Google Books volume, found when Open Library had no match
+                url = `https://books.google.com/books?id=${encodeURIComponent(olid.slice('gbooks:'.length))}`;
+            } else if (olid.startsWith('OL') && olid.endsWith('M')) {
                 // It's an edition ID (starts with OL and ends with M)
                 url = `https://openlibrary.org/books/${olid}`;
             } else if (olid.startsWith('OL') && olid.endsWith('W')) {
@@ -744,6 +1058,7 @@ document.addEventListener('DOMContentLoaded', function() {
             }
             
             openLibraryLink.href = url;
+            openLibraryLink.firstChild.textContent = olid.startsWith('gbooks:') ? 'View on Google Books ' : 'View on OpenLibrary ';
             openLibraryLink.parentElement.style.display = 'block';
         } else {
             openLibraryLink.parentElement.style.display = 'none';